@@ -0,0 +1,141 @@
+// Package analysis is a workshop on static analysis: custom vet checks built with golang.org/x/tools/go/analysis
+// for pitfalls taught in other modules.
+//
+// Exercises are in this file, not in test files: analyzers are run by cmd/workshopvet,
+// so they have to be part of a regular package.
+package analysis
+
+import (
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// go vet is a set of analyzers: small programs that get a type-checked package and report suspicious code.
+// golang.org/x/tools/go/analysis is the framework behind them, and it's open for custom checks.
+// An analyzer declares what it needs, like the result of the inspect pass, a shared index of the syntax trees,
+// and its Run function gets a *analysis.Pass with the files, the types of every expression, and Reportf.
+//
+// analysistest runs an analyzer on packages in testdata/src and compares its diagnostics with
+// // want "regexp" comments on the lines where they are expected. Diagnostics without a comment
+// and comments without a diagnostic both fail the test.
+//
+// cmd/workshopvet runs the analyzers of this package like go vet does:
+//
+//	go install ./analysis/cmd/workshopvet
+//	go vet -vettool=$(which workshopvet) ./...
+
+// 1. time.After in a loop.
+// time.After creates a timer on every call, and before Go 1.23 the timer wasn't collected until it fired.
+// A select with time.After in a loop that handles thousands of messages a second keeps thousands of timers alive,
+// and even with Go 1.23 it allocates a timer per iteration. The fix is a time.Timer created once and reset,
+// or a context with a deadline.
+//
+// TimeAfter reports every call that looks like time.After, but it has to report only calls inside loops.
+// Keep a stack of nodes with inspector.WithStack and look for a for or range statement around the call;
+// a function literal in between starts a new function, the loop doesn't run its body.
+// The name of the package isn't enough either: it can be imported as another name, and a variable named time
+// may have a method After. Resolve the called function with pass.TypesInfo, typeutil.Callee does that.
+
+// TimeAfter reports calls to time.After inside loops.
+var TimeAfter = &analysis.Analyzer{
+	Name:     "timeafter",
+	Doc:      "report calls to time.After inside loops, each of them creates a timer",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runTimeAfter,
+}
+
+func runTimeAfter(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		sel, ok := n.(*ast.CallExpr).Fun.(*ast.SelectorExpr)
+		if !ok {
+			return
+		}
+
+		if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "time" && sel.Sel.Name == "After" {
+			pass.Reportf(n.Pos(), "time.After in a loop creates a timer on every iteration, reuse a time.Timer")
+		}
+	})
+
+	return nil, nil
+}
+
+// 2. A shadowed err.
+// The shadowing module hunts bugs where err := in a nested block declares a new variable instead of assigning
+// the one outside: the error is checked in the block and lost after it. go vet doesn't report shadowing,
+// and the shadow analyzer reports every shadowed name. Let's write a check for err alone.
+//
+// ShadowedErr reports every err := inside a nested block, but most of them are fine: if _, err := f(); err != nil
+// declares err for the if statement, and nothing outside needs it. It must report a declaration only when
+// it shadows an err of an enclosing scope of the same function, and that err is used after the block ends:
+// the value assigned in the block was meant for it. The type checker knows scopes: pass.TypesInfo.Defs gives
+// the variable declared by an identifier, nil when := only assigns an existing one, types.Object.Parent
+// is the scope of the variable, and Scope.LookupParent finds what the name refers to outside of it.
+// An err of the package is not a variable of the function, leave it alone. Report the line of the shadowed err,
+// like "err shadows err declared at line 10", pass.Fset.Position turns a token.Pos into a file and line.
+
+// ShadowedErr reports declarations of err that shadow an err used after them.
+var ShadowedErr = &analysis.Analyzer{
+	Name:     "shadowederr",
+	Doc:      "report err declared with := in a nested block when it shadows an err that is used after the block",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runShadowedErr,
+}
+
+func runShadowedErr(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.WithStack([]ast.Node{(*ast.AssignStmt)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		assign := n.(*ast.AssignStmt)
+		if !push || assign.Tok != token.DEFINE {
+			return true
+		}
+
+		// Declarations directly in the function body are in the scope of the function.
+		if _, ok := stack[len(stack)-2].(*ast.BlockStmt); ok {
+			if _, ok := stack[len(stack)-3].(*ast.FuncDecl); ok {
+				return true
+			}
+		}
+
+		for _, lhs := range assign.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && id.Name == "err" {
+				pass.Reportf(id.Pos(), "err shadows err of an enclosing scope")
+			}
+		}
+
+		return true
+	})
+
+	return nil, nil
+}
+
+// 3. Advanced: a lost cancel.
+// context.WithCancel, WithTimeout, and WithDeadline return a cancel function that releases the resources
+// of the context: its timer and its place in the parent. Without a call to cancel they are held until
+// the parent is cancelled, for a background context, forever. go vet has the lostcancel check for that,
+// let's write a simpler one.
+//
+// LostCancel must report a cancel function that is assigned to _ or never used in the function
+// that created it: not called, deferred, returned, or passed anywhere. The compiler rejects unused variables,
+// so the usual way to lose it is _ = cancel, which doesn't count as a use.
+// pass.TypesInfo.ObjectOf gives the variable of an identifier, and pass.TypesInfo.Uses has every identifier
+// that refers to a variable.
+// Functions of the context package are recognized by the type checker like in TimeAfter.
+
+// LostCancel reports cancel functions of contexts that are never used.
+var LostCancel = &analysis.Analyzer{
+	Name:     "lostcancel",
+	Doc:      "report cancel functions returned by context.WithCancel, WithTimeout, and WithDeadline that are never called",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runLostCancel,
+}
+
+func runLostCancel(pass *analysis.Pass) (any, error) {
+	return nil, nil
+}
@@ -0,0 +1,19 @@
+package analysis
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestTimeAfter(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), TimeAfter, "timeafter")
+}
+
+func TestShadowedErr(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), ShadowedErr, "shadowederr")
+}
+
+func TestLostCancel(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), LostCancel, "lostcancel")
+}
@@ -0,0 +1,14 @@
+// Command workshopvet runs the analyzers of the analysis workshop as a vet tool:
+//
+//	go vet -vettool=$(which workshopvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+
+	"github.com/ksysoev/go-workshops/analysis"
+)
+
+func main() {
+	multichecker.Main(analysis.TimeAfter, analysis.ShadowedErr, analysis.LostCancel)
+}
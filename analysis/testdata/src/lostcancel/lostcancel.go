@@ -0,0 +1,66 @@
+package lostcancel
+
+import (
+	"context"
+	"time"
+)
+
+func deferred(parent context.Context) error {
+	ctx, cancel := context.WithTimeout(parent, time.Second)
+	defer cancel()
+
+	return ctx.Err()
+}
+
+func discarded(parent context.Context) error {
+	ctx, _ := context.WithCancel(parent) // want "cancel function"
+
+	return ctx.Err()
+}
+
+func unused(parent context.Context) error {
+	ctx, cancel := context.WithDeadline(parent, time.Now().Add(time.Second)) // want "cancel function"
+	// Silences the compiler, not the leak.
+	_ = cancel
+
+	return ctx.Err()
+}
+
+func returned(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	return ctx, cancel
+}
+
+func assigned(parent context.Context) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	ctx, cancel = context.WithCancel(parent)
+	go func() {
+		<-ctx.Done()
+	}()
+	cancel()
+}
+
+func shadowed(parent context.Context) {
+	ctx, cancel := context.WithCancel(parent) // want "cancel function"
+	_ = cancel
+
+	if ctx.Err() == nil {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		<-ctx.Done()
+	}
+}
+
+func notContext() {
+	_, _ = withCancel()
+}
+
+func withCancel() (context.Context, func()) {
+	return context.WithCancel(context.Background())
+}
@@ -0,0 +1,91 @@
+package shadowederr
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+func load(name string) (int, error) {
+	data, err := os.ReadFile(name)
+	if err == nil {
+		n, err := strconv.Atoi(string(data)) // want "err shadows err declared at line 10"
+		if err == nil {
+			return n, nil
+		}
+	}
+
+	return 0, err
+}
+
+func loadNamed(name string) (n int, err error) {
+	if name != "" {
+		data, err := os.ReadFile(name) // want "err shadows err declared at line 21"
+		n = len(data)
+		_ = err
+	}
+
+	return n, err
+}
+
+func checked(name string) error {
+	err := os.Remove(name)
+	if err != nil {
+		return err
+	}
+
+	// The outer err is never used after the block, the inner one is a new variable on purpose.
+	if _, err := os.Stat(name); err == nil {
+		return errors.New("still exists")
+	}
+
+	return nil
+}
+
+func nested(names []string) error {
+	for _, name := range names {
+		if _, err := os.Stat(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func reassigned(name string) error {
+	data, err := os.ReadFile(name)
+	if err == nil {
+		// err is assigned, not declared: the outer variable gets the result.
+		_, err = strconv.Atoi(string(data))
+	}
+
+	return err
+}
+
+func redeclared(name string) error {
+	data, err := os.ReadFile(name)
+	n, err := strconv.Atoi(string(data))
+	_ = n
+
+	return err
+}
+
+func other(name string) error {
+	res, err := os.ReadFile(name)
+	if err == nil {
+		res, err2 := strconv.Atoi(string(res))
+		_, _ = res, err2
+	}
+
+	return err
+}
+
+var err = errors.New("package level")
+
+func packageLevel(name string) error {
+	if _, err := os.Stat(name); err != nil {
+		return err
+	}
+
+	return err
+}
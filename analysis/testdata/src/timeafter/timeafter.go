@@ -0,0 +1,58 @@
+package timeafter
+
+import (
+	"time"
+	tm "time"
+)
+
+func consume(messages <-chan string, handle func(string)) {
+	for {
+		select {
+		case msg := <-messages:
+			handle(msg)
+		case <-time.After(time.Second): // want "time.After in a loop"
+			return
+		}
+	}
+}
+
+func consumeRange(batches [][]string, out chan<- string) {
+	for _, batch := range batches {
+		for _, msg := range batch {
+			select {
+			case out <- msg:
+			case <-tm.After(time.Second): // want "time.After in a loop"
+			}
+		}
+	}
+}
+
+func waitOnce(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func startWorkers(n int, work func()) {
+	for range n {
+		go func() {
+			select {
+			case <-time.After(time.Second):
+				work()
+			}
+		}()
+	}
+}
+
+type clock struct{}
+
+func (clock) After(d time.Duration) <-chan time.Time { return nil }
+
+func poll(time clock, check func() bool) {
+	for !check() {
+		<-time.After(0)
+	}
+}
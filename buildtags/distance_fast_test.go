@@ -0,0 +1,10 @@
+//go:build fast
+
+package buildtags
+
+const fastBuild = true
+
+// Distance returns the Levenshtein distance between a and b.
+func Distance(a, b string) int {
+	return 0
+}
@@ -0,0 +1,27 @@
+//go:build !fast
+
+package buildtags
+
+const fastBuild = false
+
+// Distance returns the Levenshtein distance between a and b.
+func Distance(a, b string) int {
+	return distance([]rune(a), []rune(b))
+}
+
+func distance(a, b []rune) int {
+	switch {
+	case len(a) == 0:
+		return len(b)
+	case len(b) == 0:
+		return len(a)
+	case a[0] == b[0]:
+		return distance(a[1:], b[1:])
+	}
+
+	return 1 + min(
+		distance(a[1:], b),     // delete a[0]
+		distance(a, b[1:]),     // insert b[0]
+		distance(a[1:], b[1:]), // replace a[0] with b[0]
+	)
+}
@@ -0,0 +1,80 @@
+package buildtags
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 3. Advanced: a test matrix.
+// Build tags also swap a simple reference implementation for an optimized one: assembly for some architectures
+// (crypto and math/big do that, with a purego tag to opt out), a cgo binding, or code that relies on unsafe.
+// Both versions must behave the same, so the same conformance tests run against each of them,
+// and CI runs the matrix: every combination of tags and platforms the package supports.
+//
+//	go test ./buildtags              // distance_ref_test.go
+//	go test -tags=fast ./buildtags   // distance_fast_test.go
+//
+// Distance returns the Levenshtein distance between two strings: the minimal number of inserted,
+// deleted, and replaced characters that turns one into the other. The reference implementation tries all
+// of the options recursively, it's obviously correct and exponentially slow.
+// Implement Distance in distance_fast_test.go with dynamic programming: keep a row of distances between
+// a prefix of a and all prefixes of b, two rows are enough. Distances are counted in runes, not bytes.
+// TestFastDistance runs the conformance suite with -tags=fast, and checks that long strings are fast.
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"go", "", 2},
+		{"", "gopher", 6},
+		{"gopher", "gopher", 0},
+		{"gopher", "gophers", 1},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"sunday", "saturday", 3},
+		{"intention", "execution", 5},
+		{"привет", "привед", 1},
+		{"日本語", "日本", 1},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Expected Distance(%q, %q) to be %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+
+		if got := Distance(tt.b, tt.a); got != tt.expected {
+			t.Errorf("Expected Distance(%q, %q) to be %d, got %d", tt.b, tt.a, tt.expected, got)
+		}
+	}
+}
+
+func TestFastDistance(t *testing.T) {
+	if !fastBuild {
+		runWithTags(t, "fast", "TestDistance|TestFastDistance")
+		return
+	}
+
+	a := strings.Repeat("abcde", 400)
+	b := strings.Repeat("abdce", 400)
+
+	start := time.Now()
+	got := Distance(a, b)
+	elapsed := time.Since(start)
+
+	if got != 800 {
+		t.Errorf("Expected a distance of 800 between long strings, got %d", got)
+	}
+
+	if elapsed > time.Second {
+		t.Errorf("Expected Distance of 2000 characters to take less than a second, took %v", elapsed)
+	}
+}
+
+func BenchmarkDistance(b *testing.B) {
+	for range b.N {
+		Distance("intention", "execution")
+	}
+}
@@ -0,0 +1,70 @@
+package buildtags
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The go command decides which files of a package to compile with build constraints:
+// - a file name suffix: _linux.go, _windows_amd64.go, _test.go. Only GOOS and GOARCH values work in names,
+// - a //go:build line before the package clause with a boolean expression of tags:
+//   //go:build linux && (amd64 || arm64), //go:build !windows, //go:build debug.
+// Tags are GOOS and GOARCH values, "unix" for all Unix-like systems, "cgo", go1.N versions, and custom tags
+// passed with go build -tags. A file is compiled when both its name and its //go:build line match.
+//
+// The classic way to provide a function on every platform is a file per platform plus a fallback
+// with the negation of all others. Every platform must get exactly one implementation:
+// none is an "undefined" error, two are a "redeclared" error, and only on that platform, not where you build.
+//
+// GOOS=windows go vet ./... checks another platform without a Windows machine, and go/build answers
+// the same question in code, which is what the test below does.
+
+// 1. Files per platform.
+// testdata/paths has three implementations of configDir: for Unix-like systems, Windows, and everything else.
+// The test checks which of them are compiled for every platform, and two platforms get two of them.
+// Fix the constraints, without renaming the files: "unix" is a valid tag, but not a valid file name suffix.
+
+func TestPlatformFiles(t *testing.T) {
+	expected := map[string]string{
+		"linux/amd64":   "path_unix.go",
+		"darwin/arm64":  "path_unix.go",
+		"freebsd/amd64": "path_unix.go",
+		"windows/amd64": "path_windows.go",
+		"js/wasm":       "path_other.go",
+		"wasip1/wasm":   "path_other.go",
+		"plan9/386":     "path_other.go",
+	}
+
+	dir := filepath.Join("testdata", "paths")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for platform, file := range expected {
+		ctx := build.Default
+		ctx.GOOS, ctx.GOARCH, _ = strings.Cut(platform, "/")
+		ctx.CgoEnabled = false
+
+		var matched []string
+
+		for _, entry := range entries {
+			ok, err := ctx.MatchFile(dir, entry.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok {
+				matched = append(matched, entry.Name())
+			}
+		}
+
+		if len(matched) != 1 || matched[0] != file {
+			t.Errorf("Expected only %s to be compiled for %s, got %v", file, platform, matched)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+//go:build debug
+
+package buildtags
+
+import "sync"
+
+const debugBuild = true
+
+// NewPool returns a pool that panics when buffers are misused.
+func NewPool() Pool {
+	return &debugPool{}
+}
+
+// debugPool keeps free buffers in a slice, so every Get and Put can check them.
+type debugPool struct {
+	mu   sync.Mutex
+	free []*Buffer
+}
+
+func (p *debugPool) Get() *Buffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.free) == 0 {
+		return &Buffer{B: make([]byte, 0, 512)}
+	}
+
+	b := p.free[len(p.free)-1]
+	p.free = p.free[:len(p.free)-1]
+
+	return b
+}
+
+func (p *debugPool) Put(b *Buffer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b.B = b.B[:0]
+	p.free = append(p.free, b)
+}
@@ -0,0 +1,111 @@
+package buildtags
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 2. A debug build.
+// Custom tags select between implementations of the same interface at compile time, with no cost at runtime:
+// the release build doesn't contain a single instruction of the debug one.
+//
+//	go test ./buildtags               // pool_release_test.go
+//	go test -tags=debug ./buildtags   // pool_debug_test.go
+//
+// Buffer pools are fast and dangerous: a buffer put back twice is handed out to two owners,
+// and an owner that keeps writing to a buffer after Put corrupts the data of the next one.
+// Both bugs show up far away from their cause. The release build uses sync.Pool, which checks nothing,
+// so the debug build should catch them where they happen.
+//
+// Implement the debug pool in pool_debug_test.go:
+// - Put panics if the buffer is already in the pool,
+// - Put fills the whole buffer up to its capacity with poison bytes,
+//   and Get panics if a buffer was modified after Put.
+// TestPool runs itself with -tags=debug, both builds must pass.
+
+// Buffer is a reusable byte buffer.
+type Buffer struct {
+	B []byte
+}
+
+// Pool hands out buffers and takes them back for reuse.
+type Pool interface {
+	Get() *Buffer
+	Put(b *Buffer)
+}
+
+// syncPool is the release implementation of Pool.
+type syncPool struct {
+	pool sync.Pool
+}
+
+func (p *syncPool) Get() *Buffer {
+	if b, ok := p.pool.Get().(*Buffer); ok {
+		return b
+	}
+
+	return &Buffer{B: make([]byte, 0, 512)}
+}
+
+func (p *syncPool) Put(b *Buffer) {
+	b.B = b.B[:0]
+	p.pool.Put(b)
+}
+
+func TestPool(t *testing.T) {
+	p := NewPool()
+
+	b := p.Get()
+	b.B = append(b.B, "hello"...)
+	p.Put(b)
+
+	if b := p.Get(); len(b.B) != 0 {
+		t.Errorf("Expected an empty buffer from the pool, got %q", b.B)
+	}
+
+	if !debugBuild {
+		runWithTags(t, "debug", "TestPool")
+		return
+	}
+
+	expectPanic(t, "putting a buffer twice", func() {
+		b := p.Get()
+		p.Put(b)
+		p.Put(b)
+	})
+
+	p = NewPool()
+
+	expectPanic(t, "writing to a buffer after Put", func() {
+		b := p.Get()
+		b.B = append(b.B, "request 1"...)
+		p.Put(b)
+
+		b.B = append(b.B, "late write"...)
+		p.Get()
+	})
+}
+
+func expectPanic(t *testing.T, what string, fn func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected %s to panic in the debug build", what)
+		}
+	}()
+
+	fn()
+}
+
+// runWithTags runs the test in a separate build of the package with the tags.
+func runWithTags(t *testing.T, tags, test string) {
+	t.Helper()
+
+	out, err := exec.Command("go", "test", "-count=1", "-tags", tags, "-run", "^"+test+"$", ".").CombinedOutput()
+	if err != nil {
+		t.Errorf("Expected %s to pass with -tags=%s, got %v:\n%s", test, tags, err, strings.TrimSpace(string(out)))
+	}
+}
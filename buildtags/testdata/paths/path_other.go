@@ -0,0 +1,8 @@
+//go:build !windows
+
+package paths
+
+// configDir is unknown on platforms without a home directory, like js/wasm and plan9.
+func configDir() string {
+	return ""
+}
@@ -0,0 +1,14 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".config")
+}
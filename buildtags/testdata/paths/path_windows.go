@@ -0,0 +1,7 @@
+package paths
+
+import "os"
+
+func configDir() string {
+	return os.Getenv("AppData")
+}
@@ -0,0 +1,193 @@
+package capstone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/capstone/repo"
+	"github.com/ksysoev/go-workshops/capstone/service"
+	"github.com/ksysoev/go-workshops/capstone/task"
+	"github.com/ksysoev/go-workshops/capstone/transport"
+)
+
+// The capstone puts the earlier workshops together in one small service, an API of tasks, laid out in layers:
+// - task: the domain, types and rules that don't depend on anything,
+// - repo: storage, Memory and Postgres behind the service.Repository interface,
+// - service: the business logic, it knows nothing about HTTP or SQL,
+// - transport: HTTP handlers and the server,
+// - cmd/tasks: main, it reads flags, wires the layers together, and handles signals.
+// Dependencies point inwards: transport uses service, service uses task and an interface that repo satisfies.
+//
+// The service works on a happy path and breaks everywhere else. Exercises are in the code of the service,
+// not in test files: the tests here use it from the outside, like a client and an operator would.
+// Run it with:
+//
+//	go run ./capstone/cmd/tasks
+//	curl -d '{"title":"Write the capstone"}' localhost:8080/tasks
+
+// api serves the service on the repository for the test.
+func api(t *testing.T, r service.Repository) (*httptest.Server, *bytes.Buffer) {
+	t.Helper()
+
+	var logs bytes.Buffer
+
+	svc := service.New(r)
+	svc.Now = func() time.Time { return time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC) }
+
+	server := httptest.NewServer(transport.NewHandler(svc, slog.New(slog.NewTextHandler(&logs, nil))))
+	t.Cleanup(server.Close)
+
+	return server, &logs
+}
+
+// call sends a request with the JSON body, if it's not nil, and returns the status and the body of the response.
+func call(t *testing.T, method, url string, body any) (int, string) {
+	t.Helper()
+
+	var r io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		r = bytes.NewReader(data)
+	}
+
+	req, _ := http.NewRequest(method, url, r)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, strings.TrimSpace(string(data))
+}
+
+// brokenRepo is a repository whose database is down.
+type brokenRepo struct {
+	*repo.Memory
+}
+
+func (brokenRepo) Get(context.Context, int64) (task.Task, error) {
+	return task.Task{}, errors.New("dial tcp 10.0.0.5:5432: connect: connection refused, user=tasks password=s3cr3t")
+}
+
+// 1. Errors across layers.
+// An error travels from the repository through the service to the handler, and every layer adds context:
+// "complete task: get task 42: task not found". The handler must still tell what happened, to pick the status:
+// task.ErrNotFound is 404, a *task.ValidationError is 400 with its message, and anything else is 500.
+// A 500 is our fault: it's logged with the details, and the client gets a generic message,
+// error strings of a database driver have no business in a response.
+//
+// The chain is broken in three places. The memory repository returns errors that don't wrap task.ErrNotFound,
+// the service adds context with %v, which flattens errors into strings, and writeError in the transport
+// responds 500 with the text of any error. Fix them, errors.Is and errors.As see through wrapped errors.
+
+func TestErrorsAcrossLayers(t *testing.T) {
+	svc := service.New(repo.NewMemory())
+
+	if _, err := svc.Complete(context.Background(), 42); !errors.Is(err, task.ErrNotFound) {
+		t.Errorf("Expected the service to return an error wrapping task.ErrNotFound, got %v", err)
+	} else if !strings.Contains(err.Error(), "42") {
+		t.Errorf("Expected the error to mention the ID of the task, got %q", err)
+	}
+
+	var verr *task.ValidationError
+	if _, err := svc.Create(context.Background(), ""); !errors.As(err, &verr) || verr.Field != "title" {
+		t.Errorf("Expected the service to return a *task.ValidationError for the title, got %v", err)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	server, _ := api(t, repo.NewMemory())
+
+	tests := []struct {
+		method, path string
+		body         any
+		status       int
+		response     string
+	}{
+		{"POST", "/tasks", map[string]string{"title": "Buy milk"}, http.StatusCreated,
+			`{"id":1,"title":"Buy milk","done":false,"created_at":"2024-05-01T10:00:00Z"}`},
+		{"POST", "/tasks/1/complete", nil, http.StatusOK,
+			`{"id":1,"title":"Buy milk","done":true,"created_at":"2024-05-01T10:00:00Z"}`},
+		{"GET", "/tasks/2", nil, http.StatusNotFound, `{"error":"task not found"}`},
+		{"POST", "/tasks/2/complete", nil, http.StatusNotFound, `{"error":"task not found"}`},
+		{"POST", "/tasks", map[string]string{"title": ""}, http.StatusBadRequest, `{"error":"title: is required"}`},
+		{"POST", "/tasks", map[string]string{"title": strings.Repeat("a", 201)}, http.StatusBadRequest,
+			`{"error":"title: is too long"}`},
+	}
+
+	for _, tt := range tests {
+		status, body := call(t, tt.method, server.URL+tt.path, tt.body)
+		if status != tt.status || body != tt.response {
+			t.Errorf("Expected %s %s to respond %d %s, got %d %s", tt.method, tt.path, tt.status, tt.response, status, body)
+		}
+	}
+}
+
+func TestInternalErrors(t *testing.T) {
+	server, logs := api(t, brokenRepo{repo.NewMemory()})
+
+	status, body := call(t, "GET", server.URL+"/tasks/1", nil)
+	if status != http.StatusInternalServerError || body != `{"error":"internal error"}` {
+		t.Errorf(`Expected 500 {"error":"internal error"}, got %d %s`, status, body)
+	}
+
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("Expected the error to be logged, got logs %q", logs.String())
+	}
+}
+
+// 2. Context propagation.
+// The context of a request is cancelled when the client goes away or its deadline passes. Passed down through
+// the service to the repository, it stops the work nobody waits for anymore, and frees the connection to the database.
+// Every function on the path of a request takes a context as the first argument and passes it on.
+//
+// The handlers start from context.Background(), so the context of the request is lost right away, and the memory
+// repository sleeps through its latency whatever happens to the context. Pass the context of the request,
+// make Memory stop waiting when the context is done, and respond 503 Service Unavailable to requests
+// that ran out of time: errors of cancelled contexts wrap context.Canceled or context.DeadlineExceeded.
+
+func TestContextPropagation(t *testing.T) {
+	r := repo.NewMemory()
+	r.Latency = 5 * time.Second
+
+	h := transport.NewHandler(service.New(r), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequestWithContext(ctx, "GET", "/tasks", nil)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		h.ServeHTTP(rec, req)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler to stop when the deadline of the request passed, it's still running")
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for a request that ran out of time, got %d", rec.Code)
+	}
+
+	if _, err := r.Get(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the repository to return the error of the context, got %v", err)
+	}
+}
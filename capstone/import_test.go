@@ -0,0 +1,132 @@
+package capstone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/capstone/repo"
+	"github.com/ksysoev/go-workshops/capstone/service"
+	"github.com/ksysoev/go-workshops/capstone/task"
+)
+
+// 3. A worker pool.
+// Import creates tasks one by one, and with 20ms per round trip to the database, a list of 20 tasks takes 400ms.
+// Make it concurrent with at most Workers tasks in flight: the database has a limited pool of connections,
+// and an import must not take all of them. Results keep the order of titles.
+// The first error stops the import: workers don't start new tasks and Import returns the error.
+// errgroup.WithContext and SetLimit from golang.org/x/sync do most of it, or use a channel of jobs.
+
+// countingRepo tracks how many calls to Create are in flight.
+type countingRepo struct {
+	*repo.Memory
+
+	mu                    sync.Mutex
+	inFlight, maxInFlight int
+	created               int
+}
+
+func (r *countingRepo) Create(ctx context.Context, t task.Task) (task.Task, error) {
+	r.mu.Lock()
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.created++
+		r.mu.Unlock()
+	}()
+
+	return r.Memory.Create(ctx, t)
+}
+
+func newCountingRepo(latency time.Duration) *countingRepo {
+	m := repo.NewMemory()
+	m.Latency = latency
+
+	return &countingRepo{Memory: m}
+}
+
+func TestImport(t *testing.T) {
+	r := newCountingRepo(20 * time.Millisecond)
+	svc := service.New(r)
+
+	titles := make([]string, 20)
+	for i := range titles {
+		titles[i] = fmt.Sprintf("Task %d", i)
+	}
+
+	start := time.Now()
+
+	tasks, err := svc.Import(context.Background(), titles)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	elapsed := time.Since(start)
+
+	if len(tasks) != len(titles) {
+		t.Fatalf("Expected %d tasks, got %d", len(titles), len(tasks))
+	}
+
+	ids := make(map[int64]bool)
+
+	for i, task := range tasks {
+		if task.Title != titles[i] || task.ID == 0 || ids[task.ID] {
+			t.Fatalf("Expected task %d to be %q with a unique ID, got %+v", i, titles[i], task)
+		}
+
+		ids[task.ID] = true
+	}
+
+	if r.maxInFlight > svc.Workers || r.maxInFlight < 2 {
+		t.Errorf("Expected up to %d tasks in flight, and more than 1, got %d", svc.Workers, r.maxInFlight)
+	}
+
+	if elapsed > 200*time.Millisecond {
+		t.Errorf("Expected 20 tasks to be imported concurrently in less than 200ms, took %v", elapsed)
+	}
+}
+
+func TestImportError(t *testing.T) {
+	r := newCountingRepo(10 * time.Millisecond)
+	svc := service.New(r)
+
+	titles := make([]string, 40)
+	for i := range titles {
+		titles[i] = fmt.Sprintf("Task %d", i)
+	}
+
+	titles[5] = ""
+
+	tasks, err := svc.Import(context.Background(), titles)
+
+	var verr *task.ValidationError
+	if !errors.As(err, &verr) || tasks != nil {
+		t.Errorf("Expected a *task.ValidationError and no tasks, got %v, %d tasks", err, len(tasks))
+	}
+
+	if r.created > 5+svc.Workers {
+		t.Errorf("Expected the import to stop at the first error, %d tasks were created", r.created)
+	}
+
+	// Cancelling the context stops the import as well.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	titles[5] = "Task 5"
+	start := time.Now()
+
+	if _, err := svc.Import(ctx, titles); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the import to return the error of the context, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected the import to stop right after the deadline, took %v", elapsed)
+	}
+}
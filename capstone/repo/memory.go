@@ -0,0 +1,107 @@
+// Package repo implements storage of tasks: Memory for tests and local runs, and Postgres for production.
+// Both satisfy service.Repository, the interface is declared by the service that consumes it.
+package repo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ksysoev/go-workshops/capstone/task"
+)
+
+// Memory stores tasks in a map.
+type Memory struct {
+	// Latency, if set, is added to every call to behave like a database over the network.
+	Latency time.Duration
+
+	mu     sync.Mutex
+	tasks  map[int64]task.Task
+	nextID int64
+}
+
+// NewMemory creates an empty repository.
+func NewMemory() *Memory {
+	return &Memory{tasks: make(map[int64]task.Task)}
+}
+
+// wait simulates a round trip to the database.
+func (m *Memory) wait(ctx context.Context) error {
+	if m.Latency > 0 {
+		time.Sleep(m.Latency)
+	}
+
+	return nil
+}
+
+// Create stores a new task and returns it with the ID assigned.
+func (m *Memory) Create(ctx context.Context, t task.Task) (task.Task, error) {
+	if err := m.wait(ctx); err != nil {
+		return task.Task{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	t.ID = m.nextID
+	m.tasks[t.ID] = t
+
+	return t, nil
+}
+
+// Get returns the task with the ID.
+func (m *Memory) Get(ctx context.Context, id int64) (task.Task, error) {
+	if err := m.wait(ctx); err != nil {
+		return task.Task{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.tasks[id]
+	if !ok {
+		return task.Task{}, fmt.Errorf("task %d not found", id)
+	}
+
+	return t, nil
+}
+
+// Update replaces the stored task with the same ID.
+func (m *Memory) Update(ctx context.Context, t task.Task) error {
+	if err := m.wait(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.tasks[t.ID]; !ok {
+		return fmt.Errorf("task %d not found", t.ID)
+	}
+
+	m.tasks[t.ID] = t
+
+	return nil
+}
+
+// List returns all tasks ordered by ID.
+func (m *Memory) List(ctx context.Context) ([]task.Task, error) {
+	if err := m.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := make([]task.Task, 0, len(m.tasks))
+	for _, t := range m.tasks {
+		tasks = append(tasks, t)
+	}
+
+	slices.SortFunc(tasks, func(a, b task.Task) int { return int(a.ID - b.ID) })
+
+	return tasks, nil
+}
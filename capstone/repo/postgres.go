@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ksysoev/go-workshops/capstone/task"
+)
+
+// Schema creates the table of tasks.
+const Schema = `CREATE TABLE IF NOT EXISTS tasks (
+	id         BIGSERIAL PRIMARY KEY,
+	title      TEXT NOT NULL,
+	done       BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL
+)`
+
+// Postgres stores tasks in PostgreSQL. The database is opened by the caller,
+// with the pgx driver registered by importing github.com/jackc/pgx/v5/stdlib.
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres creates a repository on the database.
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Migrate creates the schema if it doesn't exist.
+func (p *Postgres) Migrate(ctx context.Context) error {
+	if _, err := p.db.ExecContext(ctx, Schema); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	return nil
+}
+
+// Create stores a new task and returns it with the ID assigned.
+func (p *Postgres) Create(ctx context.Context, t task.Task) (task.Task, error) {
+	err := p.db.QueryRowContext(ctx,
+		`INSERT INTO tasks (title, done, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		t.Title, t.Done, t.CreatedAt,
+	).Scan(&t.ID)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("insert task: %w", err)
+	}
+
+	return t, nil
+}
+
+// Get returns the task with the ID.
+func (p *Postgres) Get(ctx context.Context, id int64) (task.Task, error) {
+	var t task.Task
+
+	err := p.db.QueryRowContext(ctx,
+		`SELECT id, title, done, created_at FROM tasks WHERE id = $1`, id,
+	).Scan(&t.ID, &t.Title, &t.Done, &t.CreatedAt)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("select task %d: %w", id, err)
+	}
+
+	return t, nil
+}
+
+// Update replaces the stored task with the same ID.
+func (p *Postgres) Update(ctx context.Context, t task.Task) error {
+	_, err := p.db.ExecContext(ctx, `UPDATE tasks SET title = $2, done = $3 WHERE id = $1`, t.ID, t.Title, t.Done)
+	if err != nil {
+		return fmt.Errorf("update task %d: %w", t.ID, err)
+	}
+
+	return nil
+}
+
+// List returns all tasks ordered by ID.
+func (p *Postgres) List(ctx context.Context) ([]task.Task, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT id, title, done, created_at FROM tasks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []task.Task
+
+	for rows.Next() {
+		var t task.Task
+		if err := rows.Scan(&t.ID, &t.Title, &t.Done, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("select tasks: %w", err)
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,103 @@
+// Package service is the business logic of the capstone: it validates input, applies the rules of tasks,
+// and talks to storage through Repository. It knows nothing about HTTP or SQL.
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ksysoev/go-workshops/capstone/task"
+)
+
+// DefaultWorkers is the number of tasks Import creates concurrently.
+const DefaultWorkers = 4
+
+// Repository stores tasks. Get and Update return an error wrapping task.ErrNotFound for tasks that don't exist.
+type Repository interface {
+	Create(ctx context.Context, t task.Task) (task.Task, error)
+	Get(ctx context.Context, id int64) (task.Task, error)
+	Update(ctx context.Context, t task.Task) error
+	List(ctx context.Context) ([]task.Task, error)
+}
+
+// Service manages tasks.
+type Service struct {
+	repo Repository
+	// Workers limits how many tasks Import creates at the same time.
+	Workers int
+	// Now returns the current time, tests replace it.
+	Now func() time.Time
+}
+
+// New creates a service on the repository.
+func New(repo Repository) *Service {
+	return &Service{repo: repo, Workers: DefaultWorkers, Now: time.Now}
+}
+
+// Create validates and stores a new task.
+func (s *Service) Create(ctx context.Context, title string) (task.Task, error) {
+	t := task.Task{Title: title, CreatedAt: s.Now().UTC()}
+	if err := t.Validate(); err != nil {
+		return task.Task{}, fmt.Errorf("invalid task: %v", err)
+	}
+
+	t, err := s.repo.Create(ctx, t)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("create task: %v", err)
+	}
+
+	return t, nil
+}
+
+// Get returns the task with the ID.
+func (s *Service) Get(ctx context.Context, id int64) (task.Task, error) {
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("get task: %v", err)
+	}
+
+	return t, nil
+}
+
+// Complete marks the task as done.
+func (s *Service) Complete(ctx context.Context, id int64) (task.Task, error) {
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return task.Task{}, fmt.Errorf("complete task: %v", err)
+	}
+
+	t.Done = true
+	if err := s.repo.Update(ctx, t); err != nil {
+		return task.Task{}, fmt.Errorf("complete task: %v", err)
+	}
+
+	return t, nil
+}
+
+// List returns all tasks.
+func (s *Service) List(ctx context.Context) ([]task.Task, error) {
+	tasks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list tasks: %v", err)
+	}
+
+	return tasks, nil
+}
+
+// Import creates tasks with the titles and returns them in the order of titles.
+// It stops at the first error and returns it.
+func (s *Service) Import(ctx context.Context, titles []string) ([]task.Task, error) {
+	tasks := make([]task.Task, len(titles))
+
+	for i, title := range titles {
+		t, err := s.Create(ctx, title)
+		if err != nil {
+			return nil, fmt.Errorf("import task %d: %w", i, err)
+		}
+
+		tasks[i] = t
+	}
+
+	return tasks, nil
+}
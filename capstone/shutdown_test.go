@@ -0,0 +1,141 @@
+package capstone
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/capstone/repo"
+	"github.com/ksysoev/go-workshops/capstone/service"
+	"github.com/ksysoev/go-workshops/capstone/task"
+	"github.com/ksysoev/go-workshops/capstone/transport"
+)
+
+// 4. Graceful shutdown.
+// On deploy, the orchestrator sends SIGTERM to the old instance, and kills it after a grace period.
+// main turns the signal into a cancelled context with signal.NotifyContext, and transport.Run must stop gracefully:
+// stop accepting connections, let requests in flight finish, and return. Requests that don't finish
+// within shutdownTimeout are cut off, and Run reports that the shutdown wasn't clean.
+//
+// Run closes the server, which drops requests in flight. Use Shutdown with a deadline instead, Close only when
+// the deadline passes. Mind that Serve returns as soon as Shutdown is called, Run must wait for Shutdown to return.
+
+// startedRepo reports when a call to List starts.
+type startedRepo struct {
+	*repo.Memory
+	started chan struct{}
+}
+
+func (r startedRepo) List(ctx context.Context) ([]task.Task, error) {
+	r.started <- struct{}{}
+	return r.Memory.List(ctx)
+}
+
+// serve runs the service with the repository latency until the returned cancel is called.
+func serve(t *testing.T, latency, shutdownTimeout time.Duration) (addr string, started chan struct{}, cancel func(), stopped chan error) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m := repo.NewMemory()
+	m.Latency = latency
+	started = make(chan struct{}, 1)
+
+	h := transport.NewHandler(service.New(startedRepo{m, started}), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	stopped = make(chan error, 1)
+
+	go func() {
+		stopped <- transport.Run(ctx, l, h, shutdownTimeout)
+	}()
+
+	return l.Addr().String(), started, cancel, stopped
+}
+
+// get requests the list of tasks in the background.
+func get(url string) chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				err = errors.New(resp.Status)
+			}
+		}
+
+		done <- err
+	}()
+
+	return done
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	addr, started, shutdown, stopped := serve(t, 200*time.Millisecond, 5*time.Second)
+
+	response := get("http://" + addr + "/tasks")
+	<-started
+	shutdown()
+
+	if err := <-response; err != nil {
+		t.Errorf("Expected the request in flight to complete, got %v", err)
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return after the last request")
+	}
+
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("Expected new connections to be refused after shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	addr, started, shutdown, stopped := serve(t, 10*time.Second, 100*time.Millisecond)
+
+	response := get("http://" + addr + "/tasks")
+	<-started
+
+	start := time.Now()
+	shutdown()
+
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected Run to report that the shutdown timed out, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("Expected Run to wait for the request in flight for 100ms, returned after %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Run to return when the shutdown timeout passed")
+	}
+
+	select {
+	case err := <-response:
+		if err == nil {
+			t.Error("Expected the request that didn't finish in time to be cut off")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the request that didn't finish in time to be cut off, it's still waiting")
+	}
+}
@@ -0,0 +1,45 @@
+// Package task is the domain of the capstone service: tasks, their rules, and the errors of the domain.
+// It depends on nothing else in the service, every other layer depends on it.
+package task
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the maximum length of a title in characters.
+const MaxTitleLength = 200
+
+// ErrNotFound is returned for tasks that don't exist.
+var ErrNotFound = errors.New("task not found")
+
+// Task is a task of the to-do list.
+type Task struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Done      bool      `json:"done"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidationError is an invalid field of a task.
+type ValidationError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// Validate checks the fields a user sets.
+func (t Task) Validate() error {
+	switch {
+	case t.Title == "":
+		return &ValidationError{Field: "title", Msg: "is required"}
+	case utf8.RuneCountInString(t.Title) > MaxTitleLength:
+		return &ValidationError{Field: "title", Msg: "is too long"}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package transport exposes the service over HTTP: it decodes requests, calls the service,
+// and maps results and errors of the domain to responses.
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ksysoev/go-workshops/capstone/service"
+)
+
+// maxBodySize limits request bodies.
+const maxBodySize = 1 << 20
+
+// Handler serves the API of tasks:
+//
+//	GET  /tasks                list tasks
+//	POST /tasks                create a task from {"title": "..."}
+//	GET  /tasks/{id}           get a task
+//	POST /tasks/{id}/complete  mark a task as done
+//	POST /tasks/import         create tasks from {"titles": ["...", ...]}
+type Handler struct {
+	svc    *service.Service
+	logger *slog.Logger
+	mux    *http.ServeMux
+}
+
+// NewHandler creates a handler for the service, errors that are not the client's fault are logged to the logger.
+func NewHandler(svc *service.Service, logger *slog.Logger) *Handler {
+	h := &Handler{svc: svc, logger: logger, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /tasks", h.list)
+	h.mux.HandleFunc("POST /tasks", h.create)
+	h.mux.HandleFunc("GET /tasks/{id}", h.get)
+	h.mux.HandleFunc("POST /tasks/{id}/complete", h.complete)
+	h.mux.HandleFunc("POST /tasks/import", h.importTasks)
+
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.svc.List(context.Background())
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title string `json:"title"`
+	}
+
+	if !decode(w, r, &req) {
+		return
+	}
+
+	t, err := h.svc.Create(context.Background(), req.Title)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := h.svc.Get(context.Background(), id)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) complete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	t, err := h.svc.Complete(context.Background(), id)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *Handler) importTasks(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Titles []string `json:"titles"`
+	}
+
+	if !decode(w, r, &req) {
+		return
+	}
+
+	tasks, err := h.svc.Import(context.Background(), req.Titles)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, tasks)
+}
+
+// writeError responds with the status and the message for the error.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request body"})
+		return false
+	}
+
+	return true
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid task ID"})
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,26 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Run serves the handler on the listener until the context is cancelled. Then it stops accepting connections,
+// waits up to shutdownTimeout for requests in flight, and returns.
+func Run(ctx context.Context, l net.Listener, h http.Handler, shutdownTimeout time.Duration) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package cgointerop
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+static long gcd(long a, long b) {
+	while (b != 0) {
+		long t = a % b;
+		a = b;
+		b = t;
+	}
+	return a < 0 ? -a : a;
+}
+
+// count_vowels counts vowels in a NUL-terminated string.
+static size_t count_vowels(const char *s) {
+	size_t n = 0;
+	for (; *s; s++) {
+		switch (*s) {
+		case 'a': case 'e': case 'i': case 'o': case 'u':
+			n++;
+		}
+	}
+	return n;
+}
+
+// count_vowels_n counts vowels in the first len bytes of s, s may be NULL when len is 0.
+static size_t count_vowels_n(const char *s, size_t len) {
+	size_t n = 0;
+	for (size_t i = 0; i < len; i++) {
+		switch (s[i]) {
+		case 'a': case 'e': case 'i': case 'o': case 'u':
+			n++;
+		}
+	}
+	return n;
+}
+
+static int64_t sum_i32(const int32_t *xs, size_t len) {
+	int64_t sum = 0;
+	for (size_t i = 0; i < len; i++) {
+		sum += xs[i];
+	}
+	return sum;
+}
+
+static double scale_one(double x, double k) {
+	return x * k;
+}
+
+static void scale_all(double *xs, size_t len, double k) {
+	for (size_t i = 0; i < len; i++) {
+		xs[i] *= k;
+	}
+}
+*/
+import "C"
+
+import "unsafe"
+
+// The comment right above import "C" is the preamble: C code compiled with the package.
+// Its functions, types, and macros are available in Go as C.name, and C types have Go names too:
+// C.long, C.int, C.size_t, C.double, C.int32_t, *C.char. Conversions between Go and C types are explicit.
+//
+// Exercises of this module are in this file, cgo can't be used in _test.go files.
+
+// 1. Calling C.
+// Gcd returns 0. Make it call gcd from the preamble, convert the arguments to C.long and the result back to int.
+
+// Gcd returns the greatest common divisor of a and b.
+func Gcd(a, b int) int {
+	return 0
+}
+
+// 2. Passing strings.
+// C strings end with a NUL byte, Go strings have a length and may contain NUL bytes.
+// C.CString copies a Go string to memory allocated with malloc, which the Go GC doesn't know about,
+// so it must be released with C.free. CountVowels leaks it on every call, and stops counting at a NUL byte.
+//
+// Go memory can be passed to C for the duration of a call, as long as it contains no Go pointers.
+// Rewrite CountVowels with count_vowels_n: pass a pointer to the bytes of the string and its length,
+// no copy and nothing to free. unsafe.StringData from the unsafe workshop returns the pointer.
+
+// CountVowels returns the number of lowercase vowels in s.
+func CountVowels(s string) int {
+	return int(C.count_vowels(C.CString(s)))
+}
+
+// 3. Passing slices.
+// A slice is passed as a pointer to its first element and a length. &xs[0] panics for an empty slice,
+// unsafe.SliceData returns nil for it instead. C must not keep the pointer after the call returns:
+// the GC may move or free Go memory once no Go code references it, GODEBUG=cgocheck=1 catches some violations.
+// Fix Sum for empty slices.
+
+// Sum returns the sum of xs computed in C.
+func Sum(xs []int32) int64 {
+	return int64(C.sum_i32((*C.int32_t)(unsafe.Pointer(&xs[0])), C.size_t(len(xs))))
+}
+
+// Advanced: the cost of a call.
+// A cgo call is not a function call: the goroutine switches to the system stack, tells the scheduler it may block,
+// and restores everything on return. It costs tens of nanoseconds, more than most small C functions take,
+// so calling C in a loop for every element is often slower than the same loop in Go.
+// Compare a trivial function in C and in Go:
+//
+//	go test -run '^$' -bench Gcd ./cgointerop
+//
+// Scale calls C for every element. Make a single call to scale_all for the whole slice,
+// so the overhead is paid once, it must be at most 2 times slower than the pure Go version.
+
+// Scale multiplies every element of xs by k in place.
+func Scale(xs []float64, k float64) {
+	for i, x := range xs {
+		xs[i] = float64(C.scale_one(C.double(x), C.double(k)))
+	}
+}
@@ -0,0 +1,129 @@
+//go:build cgo
+
+package cgointerop
+
+import (
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+func TestGcd(t *testing.T) {
+	tests := []struct{ a, b, expected int }{
+		{12, 18, 6},
+		{17, 5, 1},
+		{0, 7, 7},
+		{-24, 36, 12},
+	}
+
+	for _, tt := range tests {
+		if got := Gcd(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Expected Gcd(%d, %d) to be %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestCountVowels(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected int
+	}{
+		{"gopher", 2},
+		{"", 0},
+		{"rhythm", 0},
+		{"null\x00byte inside", 5},
+	}
+
+	for _, tt := range tests {
+		if got := CountVowels(tt.s); got != tt.expected {
+			t.Errorf("Expected %d vowels in %q, got %d", tt.expected, tt.s, got)
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Unexpected panic: %v", r)
+		}
+	}()
+
+	if got := Sum([]int32{1, 2, 3, -10, 1 << 30, 1 << 30}); got != 1<<31-4 {
+		t.Errorf("Expected sum %d, got %d", 1<<31-4, got)
+	}
+
+	if got := Sum(nil); got != 0 {
+		t.Errorf("Expected sum of no numbers to be 0, got %d", got)
+	}
+
+	if got := Sum([]int32{}); got != 0 {
+		t.Errorf("Expected sum of an empty slice to be 0, got %d", got)
+	}
+}
+
+// gcdGo is gcd from the preamble in Go.
+func gcdGo(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	if a < 0 {
+		return -a
+	}
+
+	return a
+}
+
+func scaleGo(xs []float64, k float64) {
+	for i := range xs {
+		xs[i] *= k
+	}
+}
+
+func TestScale(t *testing.T) {
+	xs := []float64{1, -2, 0.5}
+	Scale(xs, 4)
+
+	if xs[0] != 4 || xs[1] != -8 || xs[2] != 2 {
+		t.Errorf("Expected [4 -8 2], got %v", xs)
+	}
+
+	Scale(nil, 2)
+
+	testutil.SkipWithRaceDetector(t)
+
+	c := testing.Benchmark(BenchmarkScale)
+	goScale := testing.Benchmark(BenchmarkScaleGo)
+
+	if c.NsPerOp() > 2*goScale.NsPerOp() {
+		t.Errorf("Expected Scale to be at most 2 times slower than Go, got %d ns/op vs %d ns/op", c.NsPerOp(), goScale.NsPerOp())
+	}
+}
+
+var benchSink int
+
+func BenchmarkGcd(b *testing.B) {
+	for i := range b.N {
+		benchSink = Gcd(i, 48)
+	}
+}
+
+func BenchmarkGcdGo(b *testing.B) {
+	for i := range b.N {
+		benchSink = gcdGo(i, 48)
+	}
+}
+
+var scaleInput = make([]float64, 4096)
+
+func BenchmarkScale(b *testing.B) {
+	for range b.N {
+		Scale(scaleInput, 1.0001)
+	}
+}
+
+func BenchmarkScaleGo(b *testing.B) {
+	for range b.N {
+		scaleGo(scaleInput, 1.0001)
+	}
+}
@@ -0,0 +1,5 @@
+// Package cgointerop contains exercises on calling C from Go with cgo.
+//
+// Functions that call C are in cgointerop.go, files that import "C" are built only when cgo is enabled.
+// Without a C compiler, or with CGO_ENABLED=0, tests of the package are skipped.
+package cgointerop
@@ -0,0 +1,12 @@
+//go:build !cgo
+
+package cgointerop
+
+import "testing"
+
+// Tests of the module need cgo, these stubs keep exercise names valid and explain why they don't run.
+
+func TestGcd(t *testing.T)         { t.Skip("cgo is disabled") }
+func TestCountVowels(t *testing.T) { t.Skip("cgo is disabled") }
+func TestSum(t *testing.T)         { t.Skip("cgo is disabled") }
+func TestScale(t *testing.T)       { t.Skip("cgo is disabled") }
@@ -0,0 +1,12 @@
+// Command wc counts lines, words, and bytes, it's built in the clibasics workshop.
+package main
+
+import (
+	"os"
+
+	"github.com/ksysoev/go-workshops/clibasics"
+)
+
+func main() {
+	os.Exit(clibasics.WordCount(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
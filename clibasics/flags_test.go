@@ -0,0 +1,215 @@
+package clibasics
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The flag package parses command line flags: -name value, -name=value, and -bool for boolean flags.
+// Parsing stops at the first argument that is not a flag, the rest is available with FlagSet.Args.
+//
+// Besides the built-in types, a flag can be of any type that implements flag.Value:
+//
+//	type Value interface {
+//		String() string
+//		Set(string) error
+//	}
+//
+// Set is called for every occurrence of the flag on the command line, and an error from Set is reported as an invalid value.
+
+// StringList is a flag that can be repeated, e.g. -tag web -tag api.
+// Fix Set, so every occurrence is kept.
+type StringList []string
+
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *StringList) Set(v string) error {
+	*l = StringList{v}
+	return nil
+}
+
+func TestStringList(t *testing.T) {
+	var tags StringList
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&tags, "tag", "tag to add, can be repeated")
+
+	if err := fs.Parse([]string{"-tag", "web", "-tag=api", "-tag", "beta"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := []string{"web", "api", "beta"}; !slices.Equal(tags, expected) {
+		t.Errorf("Expected tags to be %v, got %v", expected, tags)
+	}
+}
+
+// ByteSize is a size in bytes written with an optional unit: 512, 64KB, 10MB, or 1GB.
+// Units are powers of 1024. Implement Set, so invalid sizes are rejected.
+type ByteSize int64
+
+func (s *ByteSize) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *ByteSize) Set(v string) error {
+	return nil
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected ByteSize
+	}{
+		{arg: "512", expected: 512},
+		{arg: "64KB", expected: 64 << 10},
+		{arg: "10MB", expected: 10 << 20},
+		{arg: "1GB", expected: 1 << 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			size := ByteSize(1)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.Var(&size, "max", "max size")
+
+			if err := fs.Parse([]string{"-max", tt.arg}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if size != tt.expected {
+				t.Errorf("Expected size to be %d, got %d", tt.expected, size)
+			}
+		})
+	}
+
+	for _, arg := range []string{"", "KB", "10XB", "-5MB", "1.5GB"} {
+		size := ByteSize(1)
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Var(&size, "max", "max size")
+
+		if err := fs.Parse([]string{"-max", arg}); err == nil {
+			t.Errorf("Expected error for %q, got size %d", arg, size)
+		}
+	}
+}
+
+// Tools like go and git have subcommands, each with its own flags: go test -run, git commit -m.
+// A subcommand gets its own flag.FlagSet, and the program dispatches on the first argument.
+//
+// Global state makes a command hard to test: flag.CommandLine, os.Args, os.Stdout, os.Exit.
+// Instead, Run gets everything as arguments and returns an exit code, main is just:
+//
+//	func main() {
+//		os.Exit(Run(os.Args[1:], os.Stdout, os.Stderr))
+//	}
+//
+// Fix Run, so it writes only to the given streams and follows the conventions:
+// exit code 0 on success and for -h, 2 for usage errors, and help goes to stderr.
+
+const usage = `usage: tool <command> [flags] [args]
+
+commands:
+  greet  print a greeting
+  sum    print the sum of numbers
+`
+
+// Run runs the tool with arguments without the program name and returns the exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "greet":
+		fs := flag.NewFlagSet("greet", flag.ContinueOnError)
+		name := fs.String("name", "World", "who to greet")
+		shout := fs.Bool("shout", false, "print in upper case")
+
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+
+		greeting := fmt.Sprintf("Hello, %s!", *name)
+		if *shout {
+			greeting = strings.ToUpper(greeting)
+		}
+
+		fmt.Println(greeting)
+	case "sum":
+		fs := flag.NewFlagSet("sum", flag.ContinueOnError)
+
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+
+		total := 0
+
+		for _, arg := range fs.Args() {
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Printf("sum: invalid number %q\n", arg)
+				return 2
+			}
+
+			total += n
+		}
+
+		fmt.Println(total)
+	default:
+		fmt.Printf("unknown command %q\n\n%s", cmd, usage)
+		return 2
+	}
+
+	return 0
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{name: "greet", args: []string{"greet"}, stdout: "Hello, World!\n"},
+		{name: "greet with flags", args: []string{"greet", "-name", "Ann", "-shout"}, stdout: "HELLO, ANN!\n"},
+		{name: "sum", args: []string{"sum", "1", "2", "39"}, stdout: "42\n"},
+		{name: "help", args: []string{"greet", "-h"}, stderr: "Usage of greet:"},
+		{name: "no command", args: nil, code: 2, stderr: "usage: tool <command>"},
+		{name: "unknown command", args: []string{"deploy"}, code: 2, stderr: `unknown command "deploy"`},
+		{name: "unknown flag", args: []string{"greet", "-loud"}, code: 2, stderr: "flag provided but not defined: -loud"},
+		{name: "invalid number", args: []string{"sum", "1", "two"}, code: 2, stderr: `sum: invalid number "two"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+
+			code := Run(tt.args, &stdout, &stderr)
+
+			if code != tt.code {
+				t.Errorf("Expected exit code %d, got %d", tt.code, code)
+			}
+
+			if stdout.String() != tt.stdout {
+				t.Errorf("Expected stdout to be %q, got %q", tt.stdout, stdout.String())
+			}
+
+			if tt.stderr == "" && stderr.Len() > 0 || !strings.Contains(stderr.String(), tt.stderr) {
+				t.Errorf("Expected stderr to contain %q, got %q", tt.stderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package clibasics
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"testing"
+)
+
+// Advanced: flags after arguments.
+//
+// flag.FlagSet.Parse stops at the first argument that is not a flag, so `tool build -v ./pkg` leaves -v unparsed.
+// Users of git, docker, and kubectl expect flags anywhere on the command line though,
+// and many tools implement it on top of the flag package instead of bringing a bigger library.
+//
+// Let's implement ParseInterspersed: it parses flags placed before, between, and after positional arguments
+// and returns the positional arguments in their order. Everything after "--" is positional, even if it looks like a flag.
+// Hint: call Parse in a loop, every call consumes flags until the next positional argument, see FlagSet.Args.
+
+// ParseInterspersed parses flags anywhere in args and returns positional arguments.
+func ParseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return fs.Args(), nil
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("tool", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	verbose := fs.Bool("v", false, "verbose output")
+	output := fs.String("o", "", "output file")
+
+	args, err := ParseInterspersed(fs, []string{"build", "-v", "./cmd", "-o", "bin/tool", "--", "-not-a-flag", "./pkg"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := []string{"build", "./cmd", "-not-a-flag", "./pkg"}; !slices.Equal(args, expected) {
+		t.Errorf("Expected positional arguments %q, got %q", expected, args)
+	}
+
+	if !*verbose || *output != "bin/tool" {
+		t.Errorf("Expected -v and -o bin/tool to be parsed, got %v and %q", *verbose, *output)
+	}
+
+	if _, err := ParseInterspersed(fs, []string{"build", "-unknown"}); err == nil {
+		t.Error("Expected error for unknown flag after an argument")
+	}
+}
@@ -0,0 +1,81 @@
+// Package clibasics is a workshop on command line tools: flags, subcommands, and standard streams.
+package clibasics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// Counts are the counts reported by wc.
+type Counts struct {
+	Lines int
+	Words int
+	Bytes int
+}
+
+// Add adds the other counts, it's used for the total.
+func (c *Counts) Add(other Counts) {
+	c.Lines += other.Lines
+	c.Words += other.Words
+	c.Bytes += other.Bytes
+}
+
+// Count counts newlines, words separated by white space, and bytes in r.
+func Count(r io.Reader) (Counts, error) {
+	var (
+		c      Counts
+		inWord bool
+	)
+
+	br := bufio.NewReader(r)
+
+	for {
+		ch, size, err := br.ReadRune()
+		if err == io.EOF {
+			return c, nil
+		} else if err != nil {
+			return c, err
+		}
+
+		c.Bytes += size
+
+		if ch == '\n' {
+			c.Lines++
+		}
+
+		if unicode.IsSpace(ch) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			c.Words++
+		}
+	}
+}
+
+// WordCount is a simplified version of the wc utility:
+//
+//	usage: wc [-l] [-w] [-c] [file ...]
+//
+// It prints the selected counts for each file in the order lines, words, bytes, every count is right aligned to 8 characters
+// and followed by the file name. Without flags all counts are printed. Without files it reads stdin and prints no name.
+// For more than one file, it prints a line with the total at the end.
+//
+// A file that can't be read doesn't stop the tool: the error is reported to stderr as "wc: <file>: no such file"
+// for missing files or "wc: <error>" otherwise, and the exit code is 1. Usage errors have exit code 2.
+//
+// It's implemented only partially, it always reads stdin. Finish it, cmd/wc runs it as a real program:
+//
+//	go run ./clibasics/cmd/wc -l clibasics/testdata/input/*.txt
+func WordCount(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c, err := Count(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "wc: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%8d%8d%8d\n", c.Lines, c.Words, c.Bytes)
+
+	return 0
+}
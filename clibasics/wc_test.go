@@ -0,0 +1,81 @@
+package clibasics
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites golden files with the current output: go test ./clibasics -run WordCount -update
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCount(t *testing.T) {
+	c, err := Count(strings.NewReader("Hello, 世界!\nsecond  line"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := (Counts{Lines: 1, Words: 4, Bytes: 27}); c != expected {
+		t.Errorf("Expected counts to be %+v, got %+v", expected, c)
+	}
+}
+
+// A command line tool is tested end to end by running it with arguments and comparing its output with golden files
+// in testdata/golden. When the output changes on purpose, run the test with -update flag and review the diff.
+func TestWordCount(t *testing.T) {
+	poem, err := os.ReadFile("testdata/input/poem.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+	}{
+		{name: "stdin", stdin: string(poem)},
+		{name: "stdin-lines", args: []string{"-l"}, stdin: string(poem)},
+		{name: "file", args: []string{"testdata/input/poem.txt"}},
+		{name: "files", args: []string{"testdata/input/poem.txt", "testdata/input/empty.txt", "testdata/input/no-newline.txt"}},
+		{name: "words-and-bytes", args: []string{"-w", "-c", "testdata/input/poem.txt", "testdata/input/no-newline.txt"}},
+		{name: "missing-file", args: []string{"testdata/input/poem.txt", "testdata/input/missing.txt", "testdata/input/empty.txt"}},
+		{name: "unknown-flag", args: []string{"-x", "testdata/input/poem.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			code := WordCount(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+			cmd := strings.Join(append([]string{"wc"}, tt.args...), " ")
+			got := fmt.Sprintf("$ %s\nexit code: %d\n--- stdout\n%s--- stderr\n%s", cmd, code, &stdout, &stderr)
+			assertGolden(t, tt.name+".golden", []byte(got))
+		})
+	}
+}
+
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name)
+
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Output doesn't match %s, expected:\n%s\ngot:\n%s", path, expected, got)
+	}
+}
@@ -0,0 +1,194 @@
+package closures
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// A closure is a function value that references variables from outside its body.
+// The function may access and assign to the referenced variables; in this sense the function is "bound" to the variables.
+// Closures capture variables, not values. It means that if the variable changes after the closure was created,
+// the closure will see the new value.
+
+// NewCounter returns a function that returns the next number on every call.
+// Every counter has its own state, because every call of NewCounter creates a new count variable.
+func NewCounter() func() int {
+	count := 0
+
+	return func() int {
+		count++
+		return count
+	}
+}
+
+func ExampleNewCounter() {
+	c1 := NewCounter()
+	c2 := NewCounter()
+
+	fmt.Println(c1(), c1(), c1())
+	fmt.Println(c2())
+
+	// Output:
+	// 1 2 3
+	// 1
+}
+
+// Since Go 1.22 each iteration of a for loop has its own variable.
+// The same code that was broken in legacy_loop_test.go works as expected here,
+// because this file uses the language version from go.mod.
+// Compare TestLoopVariableCapture with TestLoopVariableCaptureLegacy.
+
+// CollectIDs starts n goroutines and collects the IDs they observed.
+func CollectIDs(n int) []int {
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	ids := make([]int, 0, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			mu.Lock()
+			ids = append(ids, i)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	sort.Ints(ids)
+
+	return ids
+}
+
+func TestLoopVariableCapture(t *testing.T) {
+	ids := CollectIDs(5)
+
+	for i, id := range ids {
+		if id != i {
+			t.Fatalf("Expected to collect ids [0 1 2 3 4], got %v", ids)
+		}
+	}
+}
+
+// But new loop semantics don't save us from all capture bugs.
+// Only variables declared by the for statement itself are per-iteration.
+// Any variable declared outside of the loop is still shared by all closures created inside of it.
+// Let's try to fix the code below, so every goroutine sends its own greeting.
+// Try to run the test with -race flag, what does the race detector say about this code?
+
+// Greetings returns greeting for every name in the list.
+func Greetings(names []string) []string {
+	var greeting string
+
+	results := make(chan string)
+
+	for _, name := range names {
+		greeting = "Hello, " + name + "!"
+
+		go func() {
+			results <- greeting
+		}()
+	}
+
+	greetings := make([]string, 0, len(names))
+	for range names {
+		greetings = append(greetings, <-results)
+	}
+
+	sort.Strings(greetings)
+
+	return greetings
+}
+
+func TestCaptureByReference(t *testing.T) {
+	greetings := Greetings([]string{"Alice", "Bob", "Eve"})
+	expected := []string{"Hello, Alice!", "Hello, Bob!", "Hello, Eve!"}
+
+	for i := range expected {
+		if greetings[i] != expected[i] {
+			t.Fatalf("Expected greetings to be %v, got %v", expected, greetings)
+		}
+	}
+}
+
+// Closures keep alive everything they capture.
+// As long as the closure is reachable, garbage collector can't free the captured variables,
+// even if the closure uses only a tiny part of them.
+// It could lead to memory leaks, when closures are stored for a long time, for example as callbacks or in caches.
+
+const payloadSize = 64 << 20 // 64 MiB
+
+// loadPayload simulates loading of a large object, for example a file or a response body.
+func loadPayload() []byte {
+	payload := make([]byte, payloadSize)
+	copy(payload, "HEADER: workshop")
+
+	return payload
+}
+
+// heapAlloc runs garbage collection and returns the number of bytes allocated on the heap.
+func heapAlloc() int64 {
+	runtime.GC()
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return int64(m.HeapAlloc)
+}
+
+// NewSizeReporter returns a function that reports the size of the payload.
+// Let's try to fix it, so the payload can be garbage collected, while the reporter is still in use.
+func NewSizeReporter(payload []byte) func() int {
+	return func() int {
+		return len(payload)
+	}
+}
+
+func TestClosureRetention(t *testing.T) {
+	before := heapAlloc()
+
+	report := NewSizeReporter(loadPayload())
+
+	if retained := heapAlloc() - before; retained > payloadSize/2 {
+		t.Errorf("Expected payload to be garbage collected, but %d bytes are still retained", retained)
+	}
+
+	if report() != payloadSize {
+		t.Errorf("Expected reporter to return %d, got %d", payloadSize, report())
+	}
+
+	runtime.KeepAlive(report)
+}
+
+// Sub-slicing doesn't copy the data, new slice shares the backing array with the original one.
+// If closure captures a small sub-slice, it still retains the whole backing array.
+// Let's try to fix the code below, so only the header is kept in memory.
+
+// NewHeaderReader returns a function that returns the first 16 bytes of the payload.
+func NewHeaderReader(payload []byte) func() string {
+	header := payload[:16]
+
+	return func() string {
+		return string(header)
+	}
+}
+
+func TestSubSliceRetention(t *testing.T) {
+	before := heapAlloc()
+
+	read := NewHeaderReader(loadPayload())
+
+	if retained := heapAlloc() - before; retained > payloadSize/2 {
+		t.Errorf("Expected payload to be garbage collected, but %d bytes are still retained", retained)
+	}
+
+	if read() != "HEADER: workshop" {
+		t.Errorf("Expected header to be 'HEADER: workshop', got '%s'", read())
+	}
+
+	runtime.KeepAlive(read)
+}
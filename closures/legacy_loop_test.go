@@ -0,0 +1,80 @@
+//go:build go1.21
+
+// The build constraint above downgrades the language version of this file to Go 1.21.
+// It means that all loops in this file use the old semantics, where a loop variable is declared once
+// and shared by all iterations. This is the way Go worked before version 1.22.
+
+package closures
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+// Before Go 1.22, the loop variable was a single variable that was updated on every iteration.
+// Every closure created inside the loop captured the same variable, not its value at the moment.
+// When goroutines started inside the loop read the variable, they most likely saw the last value.
+// This bug was so common that it has its own entry in the Go FAQ https://go.dev/doc/faq#closures_and_goroutines
+// and `go vet` has the loopclosure check for it. Run `go vet ./closures` to see what it reports for this file.
+//
+// Let's try to fix the code below, so every goroutine gets its own copy of the loop variable.
+// Hint: there are two classic fixes: shadow the variable with `i := i` or pass it as an argument to the goroutine.
+
+// CollectIDsLegacy starts n goroutines and collects the IDs they observed.
+func CollectIDsLegacy(n int) []int {
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	ids := make([]int, 0, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			mu.Lock()
+			ids = append(ids, i)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	sort.Ints(ids)
+
+	return ids
+}
+
+func TestLoopVariableCaptureLegacy(t *testing.T) {
+	ids := CollectIDsLegacy(5)
+
+	for i, id := range ids {
+		if id != i {
+			t.Fatalf("Expected to collect ids [0 1 2 3 4], got %v", ids)
+		}
+	}
+}
+
+// The same problem appears without goroutines at all, when closures are stored and called later.
+
+// MakeMultipliersLegacy returns functions that multiply their argument by 1, 2, ..., n.
+func MakeMultipliersLegacy(n int) []func(int) int {
+	var fns []func(int) int
+
+	for i := 1; i <= n; i++ {
+		fns = append(fns, func(x int) int {
+			return x * i
+		})
+	}
+
+	return fns
+}
+
+func TestDeferredClosuresLegacy(t *testing.T) {
+	fns := MakeMultipliersLegacy(3)
+
+	for i, fn := range fns {
+		if got := fn(10); got != 10*(i+1) {
+			t.Errorf("Expected multiplier %d to return %d, got %d", i+1, 10*(i+1), got)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package closures
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Advanced: closures that own state shared by goroutines.
+//
+// A closure can hide a cache from its callers, Memoize wraps a function and remembers its results.
+// The returned function is called from many goroutines, so the captured cache must be safe for concurrent use.
+// A mutex around the map is not enough: goroutines that miss the cache at the same time all call fn,
+// and an expensive function, like a request to another service, is executed several times for the same key.
+//
+// Let's implement Memoize so fn is called once per key, and goroutines asking for a key that is being computed
+// wait for the result. Computations of different keys should not wait for each other.
+// Hint: cache an entry with sync.Once, or a channel closed when the value is ready, instead of the value itself.
+
+// Memoize returns a function that calls fn once per key and returns the cached result on later calls.
+func Memoize[K comparable, V any](fn func(K) V) func(K) V {
+	return fn
+}
+
+func TestMemoize(t *testing.T) {
+	var calls atomic.Int32
+
+	upper := Memoize(func(s string) string {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+
+		return strings.ToUpper(s)
+	})
+
+	var wg sync.WaitGroup
+
+	start := time.Now()
+
+	for range 10 {
+		for _, key := range []string{"a", "b"} {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if got := upper(key); got != strings.ToUpper(key) {
+					t.Errorf("Expected %q, got %q", strings.ToUpper(key), got)
+				}
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected fn to be called once per key, got %d calls", n)
+	}
+
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("Expected keys to be computed concurrently, took %v", elapsed)
+	}
+
+	if upper("a") != "A" || calls.Load() != 2 {
+		t.Error("Expected the cached result on later calls")
+	}
+}
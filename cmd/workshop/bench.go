@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/bench"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+// defaultBenchCount is the number of runs of every benchmark, benchstat needs at least 6 for confidence intervals.
+const defaultBenchCount = 6
+
+// baselineDirName is the directory of baselines in the progress directory, a baseline per module.
+const baselineDirName = "bench"
+
+// baseline is the recorded result of benchmarks of a module before the learner optimized its code.
+type baseline struct {
+	Time       time.Time     `json:"time"`
+	Benchmarks bench.Results `json:"benchmarks"`
+}
+
+// runBench runs benchmarks of the selected modules and compares them with baselines. The first run of a module
+// records its baseline, the next ones check optimization exercises for the speedup or the reduction of allocations
+// they require.
+func runBench(ctx context.Context, e *env, args []string) error {
+	var (
+		count   int
+		record  bool
+		verbose bool
+	)
+
+	r := runner.New(e.manifest)
+
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	flags.IntVar(&count, "count", defaultBenchCount, "run every benchmark n times")
+	flags.StringVar(&r.BenchTime, "benchtime", "", "run every benchmark for the duration or the number of iterations, like 100ms or 1000x")
+	flags.BoolVar(&record, "baseline", false, "record a new baseline instead of comparing with the old one")
+	flags.BoolVar(&verbose, "v", false, "print output of failed benchmarks")
+	flags.DurationVar(&r.Timeout, "timeout", 0, "limit benchmark runs of each module (default 10m)")
+	track := trackFlag(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if count < 1 {
+		return fmt.Errorf("invalid count %d", count)
+	}
+
+	targets, err := e.manifest.SelectTrack(*track, flags.Args()...)
+	if err != nil {
+		return err
+	}
+
+	failed := false
+
+	for _, group := range byModule(targets) {
+		mod := group[0].Module
+
+		fmt.Fprintf(e.stdout, "=== %s\n", mod.Name)
+
+		res, err := r.Bench(ctx, mod, count, nil)
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		switch {
+		case res.Skipped != "":
+			fmt.Fprintf(e.stdout, "SKIP %s (%s)\n", mod.Name, res.Skipped)
+			continue
+		case !res.Passed:
+			fmt.Fprintf(e.stdout, "FAIL %s (benchmarks failed) %.2fs\n", mod.Name, res.Duration.Seconds())
+
+			if verbose {
+				e.stdout.Write(res.Output)
+			}
+
+			failed = true
+
+			continue
+		case len(res.Results) == 0:
+			fmt.Fprintf(e.stdout, "no benchmarks in %s\n", mod.Name)
+			continue
+		}
+
+		path := baselinePath(e.manifest, mod)
+
+		base, err := loadBaseline(path)
+		if err != nil {
+			return err
+		}
+
+		if base == nil || record {
+			if err := saveBaseline(path, baseline{Time: time.Now(), Benchmarks: res.Results}); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(e.stdout, "Baseline of %d benchmarks recorded, optimize the code and run bench again\n", len(res.Results))
+
+			continue
+		}
+
+		fmt.Fprintf(e.stdout, "Compared with the baseline of %s\n", base.Time.Local().Format(time.DateTime))
+		printComparison(e, base.Benchmarks, res.Results)
+
+		for _, t := range group {
+			if t.Exercise.Bench == nil {
+				continue
+			}
+
+			ok, note := checkBench(t.Exercise.Bench, base.Benchmarks, res.Results)
+
+			status := "PASS"
+			if !ok {
+				status, failed = "FAIL", true
+			}
+
+			fmt.Fprintf(e.stdout, "%s %s (%s)\n", status, t.ID(), note)
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+
+	return nil
+}
+
+// byModule groups targets by their modules, in the order of the first target of each module.
+func byModule(targets []manifest.Target) [][]manifest.Target {
+	var groups [][]manifest.Target
+
+	index := make(map[*manifest.Module]int)
+
+	for _, t := range targets {
+		i, ok := index[t.Module]
+		if !ok {
+			i = len(groups)
+			index[t.Module] = i
+			groups = append(groups, nil)
+		}
+
+		groups[i] = append(groups[i], t)
+	}
+
+	return groups
+}
+
+// checkBench checks that every benchmark of the exercise improved on the baseline as much as the exercise requires.
+// It returns a note on the improvement, or on the first benchmark that falls short of it.
+func checkBench(b *manifest.Bench, base, current bench.Results) (bool, string) {
+	var notes []string
+
+	for _, name := range b.Benchmarks {
+		switch {
+		case len(base[name]) == 0:
+			return false, name + " is not in the baseline, record a new one with -baseline"
+		case len(current[name]) == 0:
+			return false, name + " didn't run"
+		}
+
+		if b.Speedup > 0 {
+			c := bench.Compare(base.Values(name, bench.SecPerOp), current.Values(name, bench.SecPerOp))
+
+			// Noise is no speedup at all.
+			speedup := 1.0
+			if c.Significant() && c.New.Center > 0 {
+				speedup = c.Old.Center / c.New.Center
+			}
+
+			note := fmt.Sprintf("%s is %.2fx faster", name, speedup)
+			if speedup < b.Speedup {
+				return false, fmt.Sprintf("%s, %gx required", note, b.Speedup)
+			}
+
+			notes = append(notes, note)
+		}
+
+		if b.AllocReduction > 0 {
+			c := bench.Compare(base.Values(name, bench.AllocsPerOp), current.Values(name, bench.AllocsPerOp))
+
+			// More allocations are no reduction, and they don't print as -0%.
+			reduction := max(0, -100*c.Delta())
+
+			note := fmt.Sprintf("%s allocates %.0f%% less", name, reduction)
+			if reduction < b.AllocReduction {
+				return false, fmt.Sprintf("%s, %g%% required", note, b.AllocReduction)
+			}
+
+			notes = append(notes, note)
+		}
+	}
+
+	return true, strings.Join(notes, ", ")
+}
+
+// printComparison prints a table of benchmarks like benchstat does: medians of the baseline and the current run
+// with their confidence intervals, and the change with its p-value, or ~ for changes that may be noise.
+func printComparison(e *env, base, current bench.Results) {
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "\t\tbaseline\tcurrent")
+
+	for _, name := range current.Names() {
+		if len(base[name]) == 0 {
+			fmt.Fprintf(w, "%s\t\t-\tnew\n", name)
+			continue
+		}
+
+		label := name
+
+		for _, u := range bench.Units {
+			c := bench.Compare(base.Values(name, u), current.Values(name, u))
+
+			// Units benchmarks don't report, like allocations without -benchmem, are zeros.
+			if c.Old.Center == 0 && c.New.Center == 0 && u != bench.SecPerOp {
+				continue
+			}
+
+			delta := "~"
+			if c.Significant() {
+				delta = fmt.Sprintf("%+.2f%%", 100*c.Delta())
+			}
+
+			n := fmt.Sprint(c.NewN)
+			if c.OldN != c.NewN {
+				n = fmt.Sprintf("%d+%d", c.OldN, c.NewN)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s (p=%.3f n=%s)\n", label, u, formatSummary(c.Old), formatSummary(c.New), delta, c.P, n)
+
+			label = ""
+		}
+	}
+
+	w.Flush()
+}
+
+// formatSummary formats the median with the spread of its confidence interval, like 12.3µ ± 2%.
+func formatSummary(s bench.Summary) string {
+	if !s.Confident {
+		return formatValue(s.Center) + " ± ∞"
+	}
+
+	return fmt.Sprintf("%s ± %.0f%%", formatValue(s.Center), 100*s.Spread())
+}
+
+// formatValue formats the value with 4 significant digits and a metric prefix.
+func formatValue(v float64) string {
+	for _, p := range []struct {
+		scale  float64
+		prefix string
+	}{{1e9, "G"}, {1e6, "M"}, {1e3, "k"}, {1, ""}, {1e-3, "m"}, {1e-6, "µ"}, {1e-9, "n"}} {
+		if v >= p.scale {
+			return fmt.Sprintf("%.4g%s", v/p.scale, p.prefix)
+		}
+	}
+
+	return fmt.Sprintf("%.4g", v)
+}
+
+// needsBench is the note of an optimization exercise whose tests pass before its module has a baseline.
+const needsBench = "needs bench, record the baseline with workshop bench"
+
+// benchGate checks optimization exercises whose tests pass like bench does: their benchmarks run and are compared
+// with the baseline of the module, so an exercise passes only when the code is as much faster as the exercise requires.
+func benchGate(ctx context.Context, m *manifest.Manifest, r *runner.Runner, res *runner.Result) error {
+	b := res.Target.Exercise.Bench
+	if b == nil || !res.Passed {
+		return nil
+	}
+
+	base, err := loadBaseline(baselinePath(m, res.Target.Module))
+	if err != nil {
+		return err
+	}
+
+	if base == nil {
+		res.Passed, res.Bench = false, needsBench
+		return nil
+	}
+
+	current, err := r.BenchExercise(ctx, res.Target, defaultBenchCount)
+	if err != nil {
+		return err
+	}
+
+	res.Duration += current.Duration
+	res.Output = append(res.Output, current.Output...)
+
+	if !current.Passed {
+		res.Passed, res.Bench = false, "benchmarks failed"
+		return nil
+	}
+
+	res.Passed, res.Bench = checkBench(b, base.Benchmarks, current.Results)
+
+	return nil
+}
+
+func baselinePath(m *manifest.Manifest, mod *manifest.Module) string {
+	return filepath.Join(m.ProgressDir(), baselineDirName, mod.Name+".json")
+}
+
+// loadBaseline reads the baseline of a module, it returns nil when the baseline is not recorded yet.
+func loadBaseline(path string) (*baseline, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var b baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %w", path, err)
+	}
+
+	return &b, nil
+}
+
+func saveBaseline(path string, b baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save baseline: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/classroom"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+const (
+	classroomTokenEnv = "WORKSHOP_CLASSROOM_TOKEN"
+	classroomURLEnv   = "WORKSHOP_CLASSROOM_URL"
+
+	// learnerKeyFile keeps the key of the checkout, deleting it gives up the name on the leaderboard.
+	learnerKeyFile = "classroom.key"
+)
+
+func serve(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	enabled := flags.Bool("classroom", false, "collect progress of learners and show the leaderboard")
+	addr := flags.String("addr", ":8080", "address to listen on")
+	token := flags.String("token", os.Getenv(classroomTokenEnv), "classroom token learners sign submissions with, generated when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if !*enabled {
+		return errors.New("classroom mode is opt-in, run workshop serve -classroom")
+	}
+
+	if *token == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("failed to generate token: %w", err)
+		}
+
+		*token = hex.EncodeToString(b)
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("failed to start classroom server: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           classroom.NewServer(*token, classroom.NewStore()).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(e.stdout, "Leaderboard: http://%s/\n", ln.Addr())
+	fmt.Fprintf(e.stdout, "Learners submit progress with:\n  %s=%s go run ./cmd/workshop submit -server http://<this host>:%d -name <name>\n",
+		classroomTokenEnv, *token, ln.Addr().(*net.TCPAddr).Port)
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("classroom server failed: %w", err)
+	}
+
+	return nil
+}
+
+func submit(ctx context.Context, e *env, args []string) error {
+	var (
+		client classroom.Client
+		name   string
+	)
+
+	opts, err := parseRunOptions(e, "submit", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&client.URL, "server", os.Getenv(classroomURLEnv), "URL of the classroom server")
+		flags.StringVar(&client.Token, "token", os.Getenv(classroomTokenEnv), "classroom token from the instructor")
+		flags.StringVar(&name, "name", os.Getenv("USER"), "your name on the leaderboard")
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case client.URL == "":
+		return fmt.Errorf("classroom server is required, use -server or %s", classroomURLEnv)
+	case client.Token == "":
+		return fmt.Errorf("classroom token is required, use -token or %s", classroomTokenEnv)
+	}
+
+	results, err := runTargets(ctx, e, opts)
+	if err != nil {
+		return err
+	}
+
+	key, err := learnerKey(e.manifest)
+	if err != nil {
+		return err
+	}
+
+	sub := newSubmission(name, key, time.Now(), results)
+	if err := sub.Validate(); err != nil {
+		return err
+	}
+
+	if err := client.Submit(ctx, sub); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Submitted to %s as %s\n", client.URL, name)
+
+	return summary(e, results)
+}
+
+func newSubmission(name, key string, now time.Time, results []runner.Result) classroom.Submission {
+	sub := classroom.Submission{Learner: name, Key: key, Time: now}
+
+	for _, res := range results {
+		sub.Exercises = append(sub.Exercises, classroom.Exercise{ID: res.Target.ID(), Passed: res.Passed})
+	}
+
+	return sub
+}
+
+// learnerKey returns the random key of the checkout that binds the learner name on the classroom server,
+// it's generated on the first submission and kept in the progress directory.
+func learnerKey(m *manifest.Manifest) (string, error) {
+	path := filepath.Join(m.ProgressDir(), learnerKeyFile)
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read learner key: %w", err)
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate learner key: %w", err)
+	}
+
+	key := hex.EncodeToString(b)
+
+	if err := os.MkdirAll(m.ProgressDir(), 0o755); err != nil {
+		return "", fmt.Errorf("failed to save learner key: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(key+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("failed to save learner key: %w", err)
+	}
+
+	return key, nil
+}
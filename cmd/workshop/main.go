@@ -0,0 +1,210 @@
+// Command workshop runs workshop exercises and reports their status.
+//
+// Usage:
+//
+//	workshop [-lang code] <command> [flags] [module | module/exercise ...]
+//
+// Commands:
+//
+//	workshop start [-track level] [-race] [-strict] [-timeout d] [module | module/exercise ...]
+//	workshop list [-track level] [module | module/exercise ...]
+//	workshop show [-track level] [module | module/exercise ...]
+//	workshop verify [-track level] [-race] [-strict] [-timeout d] [-stress n] [-v] [module | module/exercise ...]
+//	workshop watch [-track level] [-race] [-strict] [-timeout d] [-interval d] [-v] [module | module/exercise ...]
+//	workshop report [-track level] [-race] [-strict] [-timeout d] [-v] [-o file] [module | module/exercise ...]
+//	workshop mutate [-track level] [-race] [-strict] [-timeout d] [-o file] [module | module/exercise ...]
+//	workshop bench [-track level] [-count n] [-benchtime d] [-baseline] [-timeout d] [-v] [module | module/exercise ...]
+//	workshop tui [-track level] [-race] [-strict] [-timeout d] [module | module/exercise ...]
+//	workshop replay [-all] [module | module/exercise ...]
+//	workshop submit [-track level] [-race] [-strict] [-timeout d] [-server url] [-token token] [-name name] [module | module/exercise ...]
+//	workshop serve -classroom [-addr addr] [-token token]
+//	workshop play [-template name] [-edit=false] [-keep] [-race] [-timeout d]
+//	workshop new-module [-title title] -exercises a,b name
+//	workshop update [-check] [-endpoint url] [-binary=false] [-content=false]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ksysoev/go-workshops/internal/content"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+// errFailed is returned when some of the exercises are not solved yet.
+var errFailed = errors.New("some exercises failed")
+
+const usage = `Usage: workshop [-lang code] <command> [flags] [module | module/exercise ...]
+
+The -lang flag, or WORKSHOP_LANG, selects the language of exercise narratives, like -lang ru.
+Commands that select exercises accept -track beginner, intermediate, or advanced, advanced exercises
+are included only in the advanced track or when they are named explicitly.
+
+Commands:
+  start       start the workshop on a track in the interactive terminal UI, like start -track advanced
+  list        list modules and exercises
+  show        print narratives of exercises in the selected language
+  verify      run exercises, report their status, and record the run for replay
+  watch       run exercises like verify, and run exercises of a module again on every save
+  replay      step through recorded runs, or attempts of the given exercises with diffs between them
+  tui         browse exercises, run them, and read hints in an interactive terminal UI
+  report      run exercises like verify and write a JSON report with failed tests, assertions, and hints
+  mutate      run tests of solved exercises against mutants of their code and report the mutants tests miss
+  bench       run benchmarks, record a baseline, and check optimization exercises against it
+  submit      run exercises like verify and submit the progress to the classroom server
+  serve       run the classroom server with the leaderboard of learners, with -classroom
+  play        run a scratch program from a template on every save, outside of exercise packages
+  new-module  create a new module with exercise stubs, hints, and a manifest entry
+  update      update the workshop binary and sync new exercises, keeping your solutions
+`
+
+// env is the environment of a command.
+type env struct {
+	manifest *manifest.Manifest
+	lang     string
+	stdin    io.Reader
+	stdout   io.Writer
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if !errors.Is(err, errFailed) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("workshop", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.Usage = func() { fmt.Fprint(stdout, usage) }
+	lang := flags.String("lang", os.Getenv("WORKSHOP_LANG"), "language of exercise narratives")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if args = flags.Args(); len(args) == 0 {
+		fmt.Fprint(stdout, usage)
+		return errors.New("command is required")
+	}
+
+	path, err := manifest.Find(".")
+	if err != nil {
+		return err
+	}
+
+	m, err := manifest.Load(path)
+	if err != nil {
+		return err
+	}
+
+	e := &env{manifest: m, lang: *lang, stdin: stdin, stdout: stdout}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "list":
+		return list(e, args)
+	case "show":
+		return show(e, args)
+	case "verify":
+		return verify(ctx, e, args)
+	case "watch":
+		return watch(ctx, e, args)
+	case "replay":
+		return replay(e, args)
+	case "start", "tui":
+		return runTUI(ctx, e, cmd, args)
+	case "report":
+		return runReport(ctx, e, args)
+	case "mutate":
+		return mutate(ctx, e, args)
+	case "bench":
+		return runBench(ctx, e, args)
+	case "submit":
+		return submit(ctx, e, args)
+	case "serve":
+		return serve(ctx, e, args)
+	case "play":
+		return play(ctx, e, args)
+	case "new-module":
+		return newModule(e, args)
+	case "update":
+		return runUpdate(ctx, e, args)
+	default:
+		fmt.Fprint(stdout, usage)
+		return fmt.Errorf("unknown command %s", cmd)
+	}
+}
+
+func list(e *env, args []string) error {
+	targets, err := selectTrack(e, "list", args)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		fmt.Fprintln(e.stdout, t.ID())
+	}
+
+	return nil
+}
+
+// selectTrack parses the -track flag of commands that don't run exercises and selects them.
+func selectTrack(e *env, name string, args []string) ([]manifest.Target, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	track := trackFlag(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return e.manifest.SelectTrack(*track, flags.Args()...)
+}
+
+// show prints narratives of the exercises, noting when a narrative is not translated.
+func show(e *env, args []string) error {
+	targets, err := selectTrack(e, "show", args)
+	if err != nil {
+		return err
+	}
+
+	lang := content.ParseLang(e.lang)
+
+	for i, t := range targets {
+		n, err := content.Load(e.manifest.Dir, t, lang)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Fprintln(e.stdout)
+		}
+
+		// Comments have no title of their own.
+		if n.Source == content.SourceComments {
+			fmt.Fprintf(e.stdout, "=== %s\n", t.ID())
+		} else {
+			fmt.Fprintf(e.stdout, "=== %s: %s\n", t.ID(), n.Title)
+		}
+
+		if lang != "" && n.Lang != lang {
+			fmt.Fprintf(e.stdout, "(not translated to %s yet, shown in %s)\n", lang, n.Lang)
+		}
+
+		fmt.Fprintf(e.stdout, "\n%s\n", n.Body)
+	}
+
+	return nil
+}
@@ -0,0 +1,999 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/internal/bench"
+	"github.com/ksysoev/go-workshops/internal/classroom"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/progress"
+	"github.com/ksysoev/go-workshops/internal/runner"
+	"github.com/ksysoev/go-workshops/internal/session"
+)
+
+// testEnv creates a manifest with a single module in a temporary directory.
+func testEnv(t *testing.T, stdin string) (*env, *bytes.Buffer) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, "mod", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"mod/mod_test.go", "mod/sub/sub.go", "mod/README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("package mod\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &manifest.Manifest{
+		Dir: dir,
+		Modules: []manifest.Module{
+			{Name: "mod", Path: "./mod", Exercises: []manifest.Exercise{
+				{Name: "first", Tests: []string{"TestFirst"}},
+				{Name: "second", Tests: []string{"TestSecond"}},
+			}},
+		},
+	}
+
+	out := &bytes.Buffer{}
+
+	return &env{manifest: m, stdin: strings.NewReader(stdin), stdout: out}, out
+}
+
+func TestList(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	if err := list(e, []string{"mod"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if out.String() != "mod/first\nmod/second\n" {
+		t.Errorf("Expected to list exercises of the module, got %q", out.String())
+	}
+}
+
+func TestListTrack(t *testing.T) {
+	e, out := testEnv(t, "")
+	e.manifest.Modules[0].Exercises[1].Level = manifest.LevelAdvanced
+
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"mod"}, "mod/first\n"},
+		{[]string{"-track", "advanced", "mod"}, "mod/first\nmod/second\n"},
+		{[]string{"-track", "beginner"}, ""},
+		{[]string{"-track", "beginner", "mod/second"}, "mod/second\n"},
+	} {
+		out.Reset()
+
+		if err := list(e, tt.args); err != nil {
+			t.Fatalf("list %v: unexpected error: %v", tt.args, err)
+		}
+
+		if out.String() != tt.expected {
+			t.Errorf("list %v: expected %q, got %q", tt.args, tt.expected, out.String())
+		}
+	}
+
+	if err := list(e, []string{"-track", "expert"}); err == nil || !strings.Contains(err.Error(), "unknown level") {
+		t.Errorf("Expected error for unknown track, got %v", err)
+	}
+}
+
+func TestShow(t *testing.T) {
+	e, out := testEnv(t, "")
+	e.lang = "ru_RU.UTF-8"
+
+	dir := filepath.Join(e.manifest.Dir, "mod", "content")
+	for path, text := range map[string]string{"en/first.md": "# First\n\nFix it.\n", "ru/second.md": "# Второе\n\nПочините.\n"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := show(e, []string{"mod"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "=== mod/first: First\n(not translated to ru yet, shown in en)\n\nFix it.\n\n" +
+		"=== mod/second: Второе\n\nПочините.\n"
+	if out.String() != expected {
+		t.Errorf("Expected narratives in the language, got %q", out.String())
+	}
+}
+
+func TestModuleFiles(t *testing.T) {
+	e, _ := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := moduleFiles(e.manifest, targets)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if strings.Join(files, ",") != "mod/mod_test.go,mod/sub/sub.go" {
+		t.Errorf("Expected Go files of the module, got %v", files)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	e, out := testEnv(t, "\nq\n")
+	rec := session.NewRecorder(sessionDir(e.manifest))
+
+	for i, passed := range []bool{false, false, true} {
+		if i > 0 {
+			content := "package mod\n\n// attempt " + string(rune('0'+i)) + "\n"
+			if err := os.WriteFile(filepath.Join(e.manifest.Dir, "mod", "mod_test.go"), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		entry := session.Entry{
+			Time:    time.Date(2024, 1, 1, 10, i, 0, 0, time.UTC),
+			Command: []string{"verify", "mod/first"},
+			Results: []session.Result{{Exercise: "mod/first", Passed: passed, Output: "attempt output\n"}},
+		}
+
+		if err := rec.Record(entry, e.manifest.Dir, []string{"mod/mod_test.go"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := replay(e, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"=== Run 1/3 at 2024-01-01 10:00:00\n$ workshop verify mod/first",
+		"FAIL mod/first 0.00s\nattempt output\n",
+		"=== Run 2/3 at 2024-01-01 10:01:00",
+		"+// attempt 1",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected replay to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "Run 3/3") {
+		t.Errorf("Expected replay to stop when q is entered, got:\n%s", out.String())
+	}
+}
+
+func TestReplayExercise(t *testing.T) {
+	e, out := testEnv(t, "")
+	rec := session.NewRecorder(sessionDir(e.manifest))
+
+	runs := []struct {
+		exercise string
+		content  string
+	}{
+		{"mod/first", "package mod\n"},
+		{"mod/second", "package mod\n\n// second\n"},
+		{"mod/first", "package mod\n\n// second\n\n// first\n"},
+	}
+
+	for i, run := range runs {
+		if err := os.WriteFile(filepath.Join(e.manifest.Dir, "mod", "mod_test.go"), []byte(run.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		entry := session.Entry{
+			Time:    time.Date(2024, 1, 1, 10, i, 0, 0, time.UTC),
+			Command: []string{"verify", run.exercise},
+			Results: []session.Result{{Exercise: run.exercise, Output: "output of " + run.exercise}},
+		}
+
+		if err := rec.Record(entry, e.manifest.Dir, []string{"mod/mod_test.go"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := replay(e, []string{"-all", "mod/first"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"=== Run 1/2 at 2024-01-01 10:00:00", "=== Run 2/2 at 2024-01-01 10:02:00", "+// second\n", "+// first\n", "output of mod/first"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected replay to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "mod/second") {
+		t.Errorf("Expected runs of other exercises to be skipped, got:\n%s", out.String())
+	}
+
+	if err := replay(e, []string{"mod/missing"}); err == nil {
+		t.Error("Expected error for unknown exercise")
+	}
+}
+
+func TestPrintResult(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	printResult(e, runner.Result{Target: targets[0], Passed: true, Race: true, Duration: time.Second})
+	printResult(e, runner.Result{Target: targets[1], Race: true, TimedOut: true, Duration: 30 * time.Second})
+	printResult(e, runner.Result{Target: targets[1], Panicked: true})
+	printResult(e, runner.Result{Target: targets[1], Skipped: "cgo is disabled"})
+
+	expected := "PASS mod/first (race) 1.00s\nFAIL mod/second (timed out) 30.00s\nFAIL mod/second (panic) 0.00s\n" +
+		"SKIP mod/second (cgo is disabled) 0.00s\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestPrintResultCoverage(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := "package mod\n\nfunc Sign(n int) int {\n\tif n < 0 {\n\t\treturn -1\n\t}\n\n\treturn 1\n}\n"
+	if err := os.WriteFile(filepath.Join(e.manifest.Dir, "mod", "sign.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	printResult(e, runner.Result{Target: targets[0], Passed: true, Coverage: &runner.Coverage{Percent: 90, Required: 80}})
+	printResult(e, runner.Result{Target: targets[1], Coverage: &runner.Coverage{
+		Percent:   66.66,
+		Required:  80,
+		Uncovered: []runner.Block{{File: "mod/sign.go", StartLine: 5, EndLine: 6}},
+	}})
+
+	expected := "PASS mod/first (coverage 90.0%) 0.00s\n" +
+		"FAIL mod/second (coverage 66.7% of 80%) 0.00s\n" +
+		"    not covered: mod/sign.go:5\n" +
+		"                return -1\n" +
+		"            }\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestPrintResultStress(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod/first")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := []string{"TestFirst", "TestFirst/sub", "TestOther", "TestFirst", "TestOther", "TestOther", "TestOther"}
+	printResult(e, runner.Result{Target: targets[0], Race: true, Runs: 4, FailedTests: failed, Duration: time.Second})
+	printResult(e, runner.Result{Target: targets[0], Race: true, Runs: 4, Passed: true, Duration: time.Second})
+
+	expected := "FAIL mod/first (flaky) 1.00s\n    TestFirst failed 2/4 runs (50%)\n    TestOther failed 4/4 runs (100%)\n" +
+		"PASS mod/first (race) 1.00s\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestSummarySkipped(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []runner.Result{{Target: targets[0], Passed: true}, {Target: targets[1], Skipped: "cgo is disabled"}}
+
+	if err := summary(e, results); err != nil {
+		t.Errorf("Expected skipped exercises not to fail the run, got %v", err)
+	}
+
+	if expected := "\n1/1 exercises passed, 1 skipped\n"; out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	e, _ := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	results := []runner.Result{
+		{Target: targets[0], Passed: true, Duration: time.Second},
+		{
+			Target:      targets[1],
+			Duration:    2 * time.Second,
+			TimedOut:    true,
+			FailedTests: []string{"TestSecond"},
+			Assertions: []grader.Assertion{
+				{Test: "TestSecond", Name: "a", Message: "failed", Hint: "try harder"},
+				{Test: "TestSecond", Name: "b", Message: "failed", Hint: "try harder"},
+				{Test: "TestSecond", Name: "c", Message: "failed"},
+			},
+		},
+	}
+
+	data, err := json.Marshal(newReport(start, results))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"time":"2024-01-01T10:00:00Z","passed":1,"total":2,"exercises":[` +
+		`{"exercise":"mod/first","passed":true,"duration":1000000000},` +
+		`{"exercise":"mod/second","passed":false,"duration":2000000000,"timed_out":true,"failed_tests":["TestSecond"],"failed_assertions":[` +
+		`{"test":"TestSecond","name":"a","message":"failed","hint":"try harder"},` +
+		`{"test":"TestSecond","name":"b","message":"failed","hint":"try harder"},` +
+		`{"test":"TestSecond","name":"c","message":"failed"}],"hints_used":["try harder"]}]}`
+
+	if string(data) != expected {
+		t.Errorf("Expected report:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	src := `package mod
+
+import "testing"
+
+func Abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func TestFirst(t *testing.T) {
+	if Abs(-2) != 2 {
+		t.Error("Expected 2")
+	}
+}
+
+func Max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func TestSecond(t *testing.T) {
+	if Max(1, 1) != 1 {
+		t.Error("Expected 1")
+	}
+}
+`
+
+	files := map[string]string{"go.mod": "module example.com/workshop\n\ngo 1.23\n", "mod/mod_test.go": src}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(e.manifest.Dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output := filepath.Join(t.TempDir(), "mutants.json")
+
+	if err := mutate(context.Background(), e, []string{"-o", output, "mod"}); !errors.Is(err, errFailed) {
+		t.Errorf("Expected errFailed for a survived mutant, got %v", err)
+	}
+
+	for _, line := range []string{
+		"killed    mod/mod_test.go:6:7 flip-comparison",
+		"survived  mod/mod_test.go:20:7 flip-comparison\n          - if a > b {\n          + if a <= b {",
+		"1/2 mutants killed",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rep mutationReport
+	if err := json.Unmarshal(data, &rep); err != nil {
+		t.Fatal(err)
+	}
+
+	if rep.Killed != 1 || rep.Total != 2 || len(rep.Exercises) != 2 || rep.Exercises[1].Mutants[0].Status != mutantSurvived {
+		t.Errorf("Expected the report of 2 mutants with 1 killed, got %+v", rep)
+	}
+
+	if got, _ := os.ReadFile(filepath.Join(e.manifest.Dir, "mod/mod_test.go")); string(got) != src {
+		t.Errorf("Expected the source to stay unchanged, got:\n%s", got)
+	}
+
+	// Mutants of failing exercises say nothing, the exercise is skipped.
+	failing := strings.Replace(src, "return -n", "return n", 1)
+	if err := os.WriteFile(filepath.Join(e.manifest.Dir, "mod/mod_test.go"), []byte(failing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+
+	if err := mutate(context.Background(), e, []string{"mod/first"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "SKIP mod/first (tests fail, solve the exercise first)") {
+		t.Errorf("Expected the failing exercise to be skipped, got:\n%s", out.String())
+	}
+}
+
+func TestBench(t *testing.T) {
+	e, out := testEnv(t, "")
+	e.manifest.Modules[0].Exercises[0].Bench = &manifest.Bench{Benchmarks: []string{"BenchmarkJoin"}, AllocReduction: 100}
+
+	src := `package mod
+
+import (
+	"strings"
+	"testing"
+)
+
+var words = []string{"a", "b", "c"}
+
+func BenchmarkJoin(b *testing.B) {
+	for range b.N {
+		_ = strings.Join(words, ",")
+	}
+}
+`
+
+	files := map[string]string{"go.mod": "module example.com/workshop\n\ngo 1.23\n", "mod/mod_test.go": src}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(e.manifest.Dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := []string{"-benchtime", "100x", "mod"}
+
+	// verify checks the exercise against the baseline once its tests pass.
+	r := runner.New(e.manifest)
+	r.BenchTime = "100x"
+
+	gate := func() runner.Result {
+		t.Helper()
+
+		res := runner.Result{Target: manifest.Target{Module: &e.manifest.Modules[0], Exercise: &e.manifest.Modules[0].Exercises[0]}, Passed: true}
+		if err := benchGate(context.Background(), e.manifest, r, &res); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return res
+	}
+
+	if res := gate(); res.Passed || res.Bench != needsBench {
+		t.Errorf("Expected the exercise to need a baseline, got %t, %q", res.Passed, res.Bench)
+	}
+
+	if err := runBench(context.Background(), e, args); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res := gate(); res.Passed || res.Bench != "BenchmarkJoin allocates 0% less, 100% required" {
+		t.Errorf("Expected the exercise to fail without optimization, got %t, %q", res.Passed, res.Bench)
+	}
+
+	if !strings.Contains(out.String(), "Baseline of 1 benchmarks recorded") {
+		t.Errorf("Expected the first run to record the baseline, got:\n%s", out.String())
+	}
+
+	out.Reset()
+
+	if err := runBench(context.Background(), e, args); !errors.Is(err, errFailed) {
+		t.Errorf("Expected errFailed without optimization, got %v", err)
+	}
+
+	if !strings.Contains(out.String(), "FAIL mod/first (BenchmarkJoin allocates 0% less, 100% required)") {
+		t.Errorf("Expected the exercise to fail, got:\n%s", out.String())
+	}
+
+	optimized := strings.Replace(src, `_ = strings.Join(words, ",")`, `_ = strings.Compare(words[0], words[1])`, 1)
+	if err := os.WriteFile(filepath.Join(e.manifest.Dir, "mod/mod_test.go"), []byte(optimized), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+
+	if err := runBench(context.Background(), e, args); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, line := range []string{"allocs/op", "-100.00% (p=0.001 n=6)", "PASS mod/first (BenchmarkJoin allocates 100% less)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+
+	if res := gate(); !res.Passed || res.Bench != "BenchmarkJoin allocates 100% less" {
+		t.Errorf("Expected the optimized exercise to pass, got %t, %q", res.Passed, res.Bench)
+	}
+}
+
+func TestCheckBench(t *testing.T) {
+	base := bench.Results{"BenchmarkA": make([]bench.Run, 6)}
+	fast := bench.Results{"BenchmarkA": make([]bench.Run, 6)}
+	noisy := bench.Results{"BenchmarkA": make([]bench.Run, 6)}
+
+	for i := range 6 {
+		base["BenchmarkA"][i] = bench.Run{NsPerOp: float64(1000 + i), AllocsPerOp: 4}
+		fast["BenchmarkA"][i] = bench.Run{NsPerOp: float64(200 + i), AllocsPerOp: 1}
+		noisy["BenchmarkA"][i] = bench.Run{NsPerOp: float64(500 + 1000*(i%2)), AllocsPerOp: 4}
+	}
+
+	tests := []struct {
+		name    string
+		bench   manifest.Bench
+		current bench.Results
+		ok      bool
+		note    string
+	}{
+		{"speedup", manifest.Bench{Benchmarks: []string{"BenchmarkA"}, Speedup: 3}, fast, true, "BenchmarkA is 4.95x faster"},
+		{"not enough", manifest.Bench{Benchmarks: []string{"BenchmarkA"}, Speedup: 10}, fast, false, "BenchmarkA is 4.95x faster, 10x required"},
+		{"noise", manifest.Bench{Benchmarks: []string{"BenchmarkA"}, Speedup: 1.5}, noisy, false, "BenchmarkA is 1.00x faster, 1.5x required"},
+		{"allocations", manifest.Bench{Benchmarks: []string{"BenchmarkA"}, AllocReduction: 50}, fast, true, "BenchmarkA allocates 75% less"},
+		{"missing", manifest.Bench{Benchmarks: []string{"BenchmarkB"}, Speedup: 2}, fast, false, "BenchmarkB is not in the baseline, record a new one with -baseline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, note := checkBench(&tt.bench, base, tt.current)
+			if ok != tt.ok || note != tt.note {
+				t.Errorf("Expected %t, %q, got %t, %q", tt.ok, tt.note, ok, note)
+			}
+		})
+	}
+}
+
+// lockedBuffer is a buffer safe for concurrent writes of commands and reads of the test.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestPlay(t *testing.T) {
+	e, _ := testEnv(t, "")
+	out := &lockedBuffer{}
+	e.stdout = out
+
+	if err := play(context.Background(), e, []string{"-template", "unknown"}); err == nil {
+		t.Error("Expected error for unknown template")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- play(ctx, e, []string{"-edit=false", "-keep", "-template", "channels"}) }()
+
+	for deadline := time.Now().Add(time.Minute); !strings.Contains(out.String(), "--- done in"); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the playground to run, got:\n%s", out.String())
+		}
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "1\n4\n9\n16\n") {
+		t.Errorf("Expected output of the template, got:\n%s", out.String())
+	}
+
+	_, dir, ok := strings.Cut(out.String(), "Playground is kept in ")
+	if !ok {
+		t.Fatalf("Expected kept directory to be reported, got:\n%s", out.String())
+	}
+
+	dir = strings.TrimSpace(dir)
+	defer os.RemoveAll(dir)
+
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		t.Errorf("Expected playground to be kept, got %v", err)
+	}
+}
+
+func TestNewSubmission(t *testing.T) {
+	e, _ := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	sub := newSubmission("ann", "0123456789abcdef", now, []runner.Result{{Target: targets[0], Passed: true}, {Target: targets[1]}})
+
+	expected := []classroom.Exercise{{ID: "mod/first", Passed: true}, {ID: "mod/second"}}
+	if sub.Learner != "ann" || sub.Key != "0123456789abcdef" || !sub.Time.Equal(now) || !slices.Equal(sub.Exercises, expected) {
+		t.Errorf("Expected submission of results, got %+v", sub)
+	}
+}
+
+func TestLearnerKey(t *testing.T) {
+	e, _ := testEnv(t, "")
+
+	key, err := learnerKey(e.manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(key) != 32 {
+		t.Errorf("Expected a key of 32 hex characters, got %q", key)
+	}
+
+	if again, err := learnerKey(e.manifest); err != nil || again != key {
+		t.Errorf("Expected the saved key %q to be reused, got %q, %v", key, again, err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	e, _ := testEnv(t, "")
+	out := &lockedBuffer{}
+	e.stdout = out
+
+	if err := serve(context.Background(), e, nil); err == nil {
+		t.Error("Expected error without -classroom")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- serve(ctx, e, []string{"-classroom", "-addr", "127.0.0.1:0", "-token", "secret"}) }()
+
+	var url string
+
+	for deadline := time.Now().Add(5 * time.Second); url == ""; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected server to start, got:\n%s", out.String())
+		}
+
+		if _, rest, ok := strings.Cut(out.String(), "Leaderboard: "); ok {
+			url, _, _ = strings.Cut(rest, "\n")
+		}
+	}
+
+	sub := classroom.Submission{Learner: "ann", Key: "0123456789abcdef", Time: time.Now(), Exercises: []classroom.Exercise{{ID: "mod/first", Passed: true}}}
+	if err := (&classroom.Client{URL: url, Token: "secret"}).Submit(ctx, sub); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "WORKSHOP_CLASSROOM_TOKEN=secret go run ./cmd/workshop submit") {
+		t.Errorf("Expected instructions for learners, got:\n%s", out.String())
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestNewModule(t *testing.T) {
+	e, out := testEnv(t, "")
+	dir := e.manifest.Dir
+
+	readme := "# Workshops\n\n- [Mod](./mod/README.md)\n\n## Utilities\n"
+	for name, content := range map[string]string{"go.mod": "module example.com/workshops\n\ngo 1.23\n", "README.md": readme} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := newModule(e, []string{"-title", "Channel Patterns", "-exercises", "fan-in,pipeline", "channels"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, file := range []string{"channels/doc.go", "channels/channels_test.go", "channels/README.md", "channels/HINTS.md"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("Expected %s to be created, got %v", file, err)
+		}
+
+		if !strings.Contains(out.String(), "created  "+file) {
+			t.Errorf("Expected %s to be reported, got:\n%s", file, out.String())
+		}
+	}
+
+	m, err := manifest.Load(filepath.Join(dir, manifest.FileName))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	targets, err := m.Select("channels")
+	if err != nil {
+		t.Fatalf("Expected new module in the manifest, got %v", err)
+	}
+
+	if len(targets) != 2 || targets[1].ID() != "channels/pipeline" {
+		t.Errorf("Expected exercises of the new module, got %v", targets)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# Workshops\n\n- [Mod](./mod/README.md)\n- [Channel Patterns](./channels/README.md)\n\n## Utilities\n"
+	if string(data) != expected {
+		t.Errorf("Expected link after the last module in README, got:\n%s", data)
+	}
+
+	if err := newModule(e, []string{"-exercises", "again", "channels"}); err == nil {
+		t.Error("Expected error for existing module")
+	}
+
+	if err := newModule(e, []string{"-exercises", "first", "mod"}); err == nil {
+		t.Error("Expected error for module in the manifest")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	e, out := testEnv(t, "")
+
+	archive := &bytes.Buffer{}
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+	content := "package mod\n\nfunc New() {}\n"
+
+	for name, data := range map[string]string{"workshop.json": "{}\n", "mod/new.go": content} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(archive.Bytes())
+	mux := http.NewServeMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/release.json", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"version": "v2", "content": {"url": %q, "sha256": %q}}`, srv.URL+"/content.tar.gz", hex.EncodeToString(sum[:]))
+	})
+	mux.HandleFunc("/content.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive.Bytes())
+	})
+
+	e.manifest.UpdateURL = srv.URL + "/release.json"
+
+	if err := runUpdate(context.Background(), e, []string{"-binary=false"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "  added    mod/new.go") {
+		t.Errorf("Expected new file to be reported, got:\n%s", out.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(e.manifest.Dir, "mod", "new.go"))
+	if err != nil || string(data) != content {
+		t.Errorf("Expected new file to be synced, got %q, %v", data, err)
+	}
+
+	out.Reset()
+
+	if err := runUpdate(context.Background(), e, []string{"-binary=false"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Content is up to date") {
+		t.Errorf("Expected content to be up to date, got:\n%s", out.String())
+	}
+}
+
+type passRunner struct{}
+
+func (passRunner) Stream(_ context.Context, t manifest.Target, _ io.Writer) (runner.Result, error) {
+	return runner.Result{Target: t, Passed: t.Exercise.Name == "first"}, nil
+}
+
+func TestAutosave(t *testing.T) {
+	e, _ := testEnv(t, "")
+
+	targets, err := e.manifest.Select("mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, stop, err := autosave(context.Background(), e)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := progressRunner{Runner: passRunner{}, store: store}
+	for _, target := range targets {
+		if _, err := r.Stream(context.Background(), target, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	saved, err := progress.Open(progressPath(e.manifest))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ex, _ := saved.Get("mod/first"); !ex.Passed || ex.Attempts != 1 {
+		t.Errorf("Expected mod/first to be saved as passed, got %+v", ex)
+	}
+
+	if ex, ok := saved.Get("mod/second"); !ok || ex.Passed {
+		t.Errorf("Expected mod/second to be saved as failed, got %+v", ex)
+	}
+
+	// A run interrupted with Ctrl+C fails, it must not overwrite the solved exercise.
+	store, stop, err = autosave(context.Background(), e)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r = progressRunner{Runner: failRunner{}, store: store}
+	if _, err := r.Stream(ctx, targets[0], nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if saved, err = progress.Open(progressPath(e.manifest)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ex, _ := saved.Get("mod/first"); !ex.Passed || ex.Attempts != 1 {
+		t.Errorf("Expected the interrupted run of mod/first not to be recorded, got %+v", ex)
+	}
+}
+
+type failRunner struct{}
+
+func (failRunner) Stream(_ context.Context, t manifest.Target, _ io.Writer) (runner.Result, error) {
+	return runner.Result{Target: t}, nil
+}
+
+func TestWatch(t *testing.T) {
+	e, _ := testEnv(t, "")
+	out := &lockedBuffer{}
+	e.stdout = out
+
+	src := `package mod
+
+import "testing"
+
+func TestFirst(t *testing.T) {}
+
+func TestSecond(t *testing.T) {
+	t.Error("not solved")
+}
+`
+
+	files := map[string]string{"go.mod": "module example.com/workshop\n\ngo 1.23\n", "mod/mod_test.go": src}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(e.manifest.Dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() { done <- watch(ctx, e, []string{"-interval", "50ms", "mod"}) }()
+
+	waitFor := func(runs int) {
+		t.Helper()
+
+		for deadline := time.Now().Add(time.Minute); strings.Count(out.String(), "Watching for changes") < runs; time.Sleep(20 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d runs, got:\n%s", runs, out.String())
+			}
+		}
+	}
+
+	waitFor(1)
+
+	// The learner solves the second exercise and saves the file.
+	solved := strings.Replace(src, `t.Error("not solved")`, "", 1)
+	if err := os.WriteFile(filepath.Join(e.manifest.Dir, "mod/mod_test.go"), []byte(solved), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(2)
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "FAIL mod/second") || !strings.Contains(out.String(), "PASS mod/second") {
+		t.Errorf("Expected mod/second to fail and pass after the change, got:\n%s", out.String())
+	}
+
+	saved, err := progress.Open(progressPath(e.manifest))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ex, _ := saved.Get("mod/second"); !ex.Passed || ex.Attempts != 2 {
+		t.Errorf("Expected mod/second to be saved as passed after 2 attempts, got %+v", ex)
+	}
+}
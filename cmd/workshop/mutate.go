@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/mutation"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+// Statuses of mutants after their tests ran.
+const (
+	mutantKilled   = "killed"
+	mutantTimedOut = "timed out"
+	mutantSurvived = "survived"
+	mutantInvalid  = "invalid"
+)
+
+// mutantTimeoutFactor limits the run of a mutant by the duration of the run of the original code,
+// a mutant that turns a loop into an infinite one is killed by the timeout.
+const mutantTimeoutFactor = 10
+
+// minMutantTimeout limits the run of a mutant when the original code runs fast.
+const minMutantTimeout = 10 * time.Second
+
+// mutationReport is the report of a mutate run.
+type mutationReport struct {
+	Time      time.Time         `json:"time"`
+	Killed    int               `json:"killed"`
+	Total     int               `json:"total"`
+	Exercises []exerciseMutants `json:"exercises"`
+}
+
+// exerciseMutants are mutants of an exercise in the report.
+type exerciseMutants struct {
+	Exercise string         `json:"exercise"`
+	Mutants  []mutantReport `json:"mutants,omitempty"`
+	Skipped  string         `json:"skipped,omitempty"`
+}
+
+// mutantReport is the outcome of a mutant in the report.
+type mutantReport struct {
+	File     string            `json:"file"`
+	Line     int               `json:"line"`
+	Column   int               `json:"column"`
+	Operator mutation.Operator `json:"operator"`
+	Original string            `json:"original"`
+	Mutated  string            `json:"mutated"`
+	Status   string            `json:"status"`
+}
+
+// mutate runs tests of the exercises against mutants of their code and reports the mutants tests don't notice.
+// Mutants are applied with go test -overlay, files of the learner are never changed.
+func mutate(ctx context.Context, e *env, args []string) error {
+	var output string
+
+	opts, err := parseRunOptions(e, "mutate", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&output, "o", "", "also write a JSON report of mutants to the file, - for stdout")
+	})
+	if err != nil {
+		return err
+	}
+
+	// Status lines would break JSON on stdout.
+	progress := e
+	if output == "-" {
+		progress = &env{manifest: e.manifest, stdin: e.stdin, stdout: io.Discard}
+	}
+
+	tmp, err := os.MkdirTemp("", "workshop-mutate-*")
+	if err != nil {
+		return fmt.Errorf("failed to create a directory for mutants: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	rep := mutationReport{Time: time.Now(), Exercises: make([]exerciseMutants, 0, len(opts.targets))}
+
+	for _, t := range opts.targets {
+		ex, err := mutateTarget(ctx, progress, opts.runner, tmp, t)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range ex.Mutants {
+			switch m.Status {
+			case mutantKilled, mutantTimedOut:
+				rep.Killed++
+				rep.Total++
+			case mutantSurvived:
+				rep.Total++
+			}
+		}
+
+		rep.Exercises = append(rep.Exercises, ex)
+	}
+
+	if output != "" {
+		if err := writeMutationReport(e, output, rep); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(progress.stdout, "\n%d/%d mutants killed\n", rep.Killed, rep.Total)
+
+	if rep.Killed < rep.Total {
+		return errFailed
+	}
+
+	return nil
+}
+
+// mutateTarget runs tests of the exercise against every mutant of its code.
+// Mutants are only meaningful when tests pass on the original code, otherwise the exercise is skipped.
+func mutateTarget(ctx context.Context, e *env, base *runner.Runner, tmp string, t manifest.Target) (exerciseMutants, error) {
+	ex := exerciseMutants{Exercise: t.ID()}
+
+	res, err := base.Run(ctx, t)
+	if err != nil {
+		return ex, err
+	}
+
+	switch {
+	case res.Skipped != "":
+		ex.Skipped = res.Skipped
+	case !res.Passed:
+		ex.Skipped = "tests fail, solve the exercise first"
+	}
+
+	if ex.Skipped != "" {
+		fmt.Fprintf(e.stdout, "SKIP %s (%s)\n", t.ID(), ex.Skipped)
+		return ex, nil
+	}
+
+	dir, err := filepath.Abs(filepath.Join(e.manifest.Dir, t.Module.Path))
+	if err != nil {
+		return ex, err
+	}
+
+	mutants, err := mutation.ForExercise(dir, t.Exercise.Tests)
+	if err != nil {
+		return ex, err
+	}
+
+	fmt.Fprintf(e.stdout, "=== %s: %d mutants\n", t.ID(), len(mutants))
+
+	// Coverage of a mutant changes with its control flow, only failed tests kill it.
+	r := *base
+	r.FailFast = true
+	r.SkipCoverage = true
+
+	if r.Timeout == 0 {
+		r.Timeout = max(mutantTimeoutFactor*res.Duration, minMutantTimeout)
+	}
+
+	for _, m := range mutants {
+		status, err := runMutant(ctx, &r, tmp, t, m)
+		if err != nil {
+			return ex, err
+		}
+
+		file, err := filepath.Rel(e.manifest.Dir, m.File)
+		if err != nil {
+			file = m.File
+		}
+
+		ex.Mutants = append(ex.Mutants, mutantReport{
+			File:     filepath.ToSlash(file),
+			Line:     m.Line,
+			Column:   m.Column,
+			Operator: m.Operator,
+			Original: m.Original,
+			Mutated:  m.Mutated,
+			Status:   status,
+		})
+
+		fmt.Fprintf(e.stdout, "%-9s %s:%d:%d %s\n", status, filepath.ToSlash(file), m.Line, m.Column, m.Operator)
+
+		// Survivors are what the learner needs to look at: the change their tests don't notice.
+		if status == mutantSurvived {
+			fmt.Fprintf(e.stdout, "          - %s\n          + %s\n", m.Original, m.Mutated)
+		}
+	}
+
+	return ex, nil
+}
+
+// runMutant runs tests of the exercise with the file of the mutant replaced by the mutated copy.
+func runMutant(ctx context.Context, r *runner.Runner, tmp string, t manifest.Target, m mutation.Mutant) (string, error) {
+	src, err := os.ReadFile(m.File)
+	if err != nil {
+		return "", err
+	}
+
+	mutated, err := mutation.Apply(src, m)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp(tmp, "mutant-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create a directory for mutants: %w", err)
+	}
+
+	// The copy keeps the name of the file, so build errors point at a familiar name.
+	file := filepath.Join(dir, filepath.Base(m.File))
+	if err := os.WriteFile(file, mutated, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write mutant: %w", err)
+	}
+
+	overlay, err := json.Marshal(map[string]map[string]string{"Replace": {m.File: file}})
+	if err != nil {
+		return "", err
+	}
+
+	r.Overlay = filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(r.Overlay, overlay, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write mutant: %w", err)
+	}
+
+	res, err := r.Run(ctx, t)
+	if err != nil {
+		return "", err
+	}
+
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	switch {
+	case res.Passed:
+		return mutantSurvived, nil
+	case bytes.Contains(res.Output, []byte("[build failed]")):
+		// Mutants that don't compile or fail vet say nothing about tests.
+		return mutantInvalid, nil
+	case res.TimedOut:
+		return mutantTimedOut, nil
+	default:
+		return mutantKilled, nil
+	}
+}
+
+func writeMutationReport(e *env, output string, rep mutationReport) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	if output == "-" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	fmt.Fprintf(e.stdout, "Report written to %s\n", output)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/scaffold"
+)
+
+// readmeLinkRe matches links to module READMEs in the content list of the repository README.
+var readmeLinkRe = regexp.MustCompile(`^- \[.+\]\(\./[^)]+/README\.md\)$`)
+
+func newModule(e *env, args []string) error {
+	flags := flag.NewFlagSet("new-module", flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	title := flags.String("title", "", "title of the module, derived from the name when empty")
+	exercises := flags.String("exercises", "", "comma separated exercise names, like fan-in,pipeline")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("module name is required: workshop new-module -exercises a,b name")
+	}
+
+	name := flags.Arg(0)
+
+	if e.manifest.Module(name) != nil {
+		return fmt.Errorf("module %s already exists", name)
+	}
+
+	importPath, err := goModulePath(e.manifest.Dir)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if *exercises != "" {
+		names = strings.Split(*exercises, ",")
+	}
+
+	mod, err := scaffold.New(name, *title, importPath, names)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(e.manifest.Dir, name)
+	if _, err := os.Stat(dir); !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("directory %s already exists", dir)
+	}
+
+	files, err := mod.Files()
+	if err != nil {
+		return err
+	}
+
+	e.manifest.Modules = append(e.manifest.Modules, mod.Manifest())
+	if err := e.manifest.Validate(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create module: %w", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		if err := os.WriteFile(filepath.Join(dir, path), files[path], 0o644); err != nil {
+			return fmt.Errorf("failed to create module: %w", err)
+		}
+
+		fmt.Fprintf(e.stdout, "  created  %s/%s\n", name, path)
+	}
+
+	if err := manifest.Save(filepath.Join(e.manifest.Dir, manifest.FileName), e.manifest); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "  updated  %s\n", manifest.FileName)
+
+	added, err := addReadmeLink(filepath.Join(e.manifest.Dir, "README.md"), mod.Title, name)
+	if err != nil {
+		return err
+	}
+
+	if added {
+		fmt.Fprintln(e.stdout, "  updated  README.md")
+	}
+
+	fmt.Fprintf(e.stdout, "\nFill in the TODOs, then check that all exercises fail: go run ./cmd/workshop verify %s\n", name)
+
+	return nil
+}
+
+// goModulePath returns the module path from go.mod in dir.
+func goModulePath(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(path), `"`), nil
+		}
+	}
+
+	return "", errors.New("module path not found in go.mod")
+}
+
+// addReadmeLink adds a link to the module README after the last module in the content list.
+// It reports false if the README has no such list.
+func addReadmeLink(path, title, name string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to read README: %w", err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	last := -1
+
+	for i, line := range lines {
+		if readmeLinkRe.MatchString(strings.TrimRight(line, "\r\n")) {
+			last = i
+		}
+	}
+
+	if last < 0 {
+		return false, nil
+	}
+
+	link := fmt.Sprintf("- [%s](./%s/README.md)\n", title, name)
+	lines = slices.Insert(lines, last+1, link)
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o644); err != nil {
+		return false, fmt.Errorf("failed to update README: %w", err)
+	}
+
+	return true, nil
+}
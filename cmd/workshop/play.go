@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/playground"
+)
+
+func play(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("play", flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	preset := flags.String("template", playground.DefaultPreset, "template of main.go: "+strings.Join(playground.Presets(), ", "))
+	edit := flags.Bool("edit", true, "open main.go in $EDITOR, with -edit=false edit the printed file in any editor")
+	keep := flags.Bool("keep", false, "keep the playground directory after exit")
+	race := flags.Bool("race", false, "run the program with the race detector")
+	timeout := flags.Duration("timeout", 30*time.Second, "stop a run that takes longer")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dir, err := playground.Create(*preset)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if *keep {
+			fmt.Fprintf(e.stdout, "Playground is kept in %s\n", dir)
+			return
+		}
+
+		os.RemoveAll(dir)
+	}()
+
+	file := filepath.Join(dir, "main.go")
+	session := &playground.Session{
+		Dir:      dir,
+		Race:     *race,
+		Timeout:  *timeout,
+		Interval: 300 * time.Millisecond,
+		Out:      e.stdout,
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if !*edit || len(editor) == 0 {
+		fmt.Fprintf(e.stdout, "Edit %s, it runs on every save. Press Ctrl+C to stop.\n", file)
+		return session.Watch(ctx)
+	}
+
+	cmd := exec.CommandContext(ctx, editor[0], append(editor[1:], file)...)
+	cmd.Stdin = e.stdin
+	cmd.Stdout = e.stdout
+	cmd.Stderr = e.stdout
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open editor: %w", err)
+	}
+
+	// The playground runs while the editor is open, GUI editors need a flag to wait for the file to be closed, like code --wait.
+	watchCtx, stop := context.WithCancel(ctx)
+	watched := make(chan error, 1)
+
+	go func() { watched <- session.Watch(watchCtx) }()
+
+	err = cmd.Wait()
+
+	stop()
+
+	if err != nil && ctx.Err() == nil {
+		err = fmt.Errorf("editor failed: %w", err)
+	} else {
+		err = nil
+	}
+
+	return errors.Join(err, <-watched)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/progress"
+	"github.com/ksysoev/go-workshops/internal/runner"
+	"github.com/ksysoev/go-workshops/internal/tui"
+)
+
+func progressPath(m *manifest.Manifest) string {
+	return filepath.Join(m.ProgressDir(), progress.FileName)
+}
+
+// autosave opens the progress store and flushes it periodically and on SIGINT or SIGTERM until stop is called.
+// Results recorded in the store are kept when the learner interrupts a long session, stop flushes the rest.
+func autosave(ctx context.Context, e *env) (store *progress.Store, stop func() error, err error) {
+	store, err = progress.Open(progressPath(e.manifest))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+
+	go func() {
+		done <- store.Autosave(ctx, progress.DefaultInterval, signals, func(err error) {
+			fmt.Fprintln(e.stdout, "Error:", err)
+		})
+	}()
+
+	stop = func() error {
+		cancel()
+		signal.Stop(signals)
+
+		return <-done
+	}
+
+	return store, stop, nil
+}
+
+// progressRunner records results of exercises run in the TUI to the progress store, except interrupted runs.
+// With bench set, optimization exercises are checked against the benchmark baseline like verify does.
+type progressRunner struct {
+	tui.Runner
+	store *progress.Store
+
+	manifest *manifest.Manifest
+	bench    *runner.Runner
+}
+
+func (r progressRunner) Stream(ctx context.Context, t manifest.Target, w io.Writer) (runner.Result, error) {
+	res, err := r.Runner.Stream(ctx, t, w)
+	if err == nil && r.bench != nil {
+		err = benchGate(ctx, r.manifest, r.bench, &res)
+	}
+
+	if err == nil && ctx.Err() == nil && res.Skipped == "" {
+		r.store.Record(t.ID(), res.Passed, time.Now())
+	}
+
+	return res, err
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/runner"
+	"github.com/ksysoev/go-workshops/internal/session"
+)
+
+func sessionDir(m *manifest.Manifest) string {
+	return filepath.Join(m.ProgressDir(), "session")
+}
+
+func recordSession(m *manifest.Manifest, opts *runOptions, start time.Time, results []runner.Result) error {
+	files, err := moduleFiles(m, opts.targets)
+	if err != nil {
+		return err
+	}
+
+	entry := session.Entry{
+		Time:    start,
+		Command: opts.command,
+	}
+
+	for _, res := range results {
+		entry.Results = append(entry.Results, session.Result{
+			Exercise: res.Target.ID(),
+			Passed:   res.Passed,
+			Output:   string(res.Output),
+			Duration: res.Duration,
+		})
+	}
+
+	if err := session.NewRecorder(sessionDir(m)).Record(entry, m.Dir, files); err != nil {
+		return fmt.Errorf("failed to record session: %w", err)
+	}
+
+	return nil
+}
+
+// moduleFiles returns Go files of modules of the targets, relative to the manifest directory.
+func moduleFiles(m *manifest.Manifest, targets []manifest.Target) ([]string, error) {
+	var files []string
+
+	seen := make(map[string]bool)
+
+	for _, t := range targets {
+		if seen[t.Module.Name] {
+			continue
+		}
+
+		seen[t.Module.Name] = true
+
+		err := filepath.WalkDir(filepath.Join(m.Dir, t.Module.Path), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".go" {
+				return err
+			}
+
+			rel, err := filepath.Rel(m.Dir, path)
+			if err != nil {
+				return err
+			}
+
+			files = append(files, filepath.ToSlash(rel))
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files of module %s: %w", t.Module.Name, err)
+		}
+	}
+
+	return files, nil
+}
+
+// replay steps through recorded runs. With exercises or modules as arguments, it shows only runs of them,
+// and diffs of their module files between consecutive attempts.
+func replay(e *env, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	all := flags.Bool("all", false, "print all runs without pausing")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	dir := sessionDir(e.manifest)
+
+	entries, err := session.Load(dir)
+	if err != nil {
+		return err
+	}
+
+	var (
+		exercises map[string]bool
+		modules   []string
+	)
+
+	if flags.NArg() > 0 {
+		targets, err := e.manifest.Select(flags.Args()...)
+		if err != nil {
+			return err
+		}
+
+		exercises = make(map[string]bool)
+
+		for _, t := range targets {
+			exercises[t.ID()] = true
+
+			if prefix := path.Clean(t.Module.Path) + "/"; !slices.Contains(modules, prefix) {
+				modules = append(modules, prefix)
+			}
+		}
+	}
+
+	selected := func(res session.Result) bool {
+		return exercises == nil || exercises[res.Exercise]
+	}
+
+	includeFile := func(file string) bool {
+		return modules == nil || slices.ContainsFunc(modules, func(prefix string) bool { return strings.HasPrefix(file, prefix) })
+	}
+
+	var runs []session.Entry
+
+	for _, entry := range entries {
+		if slices.ContainsFunc(entry.Results, selected) {
+			runs = append(runs, entry)
+		}
+	}
+
+	if len(runs) == 0 {
+		return errors.New("no recorded runs of the selected exercises")
+	}
+
+	rec := session.NewRecorder(dir)
+	input := bufio.NewScanner(e.stdin)
+
+	for i, entry := range runs {
+		fmt.Fprintf(e.stdout, "=== Run %d/%d at %s\n$ workshop %s\n\n", i+1, len(runs), entry.Time.Format(time.DateTime), strings.Join(entry.Command, " "))
+
+		var prev *session.Entry
+		if i > 0 {
+			prev = &runs[i-1]
+		}
+
+		diffs, err := rec.Diffs(prev, entry, includeFile)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range diffs {
+			fmt.Fprintln(e.stdout, d.Diff)
+		}
+
+		for _, res := range entry.Results {
+			if !selected(res) {
+				continue
+			}
+
+			status := "PASS"
+			if !res.Passed {
+				status = "FAIL"
+			}
+
+			fmt.Fprintf(e.stdout, "%s %s %.2fs\n", status, res.Exercise, res.Duration.Seconds())
+
+			if !res.Passed {
+				fmt.Fprintln(e.stdout, strings.TrimRight(res.Output, "\n"))
+			}
+		}
+
+		if *all || i == len(runs)-1 {
+			fmt.Fprintln(e.stdout)
+			continue
+		}
+
+		fmt.Fprint(e.stdout, "\nPress Enter for the next run, q to quit: ")
+
+		if !input.Scan() || strings.TrimSpace(input.Text()) == "q" {
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+// report is the grading report of a run.
+type report struct {
+	Time      time.Time        `json:"time"`
+	Passed    int              `json:"passed"`
+	Total     int              `json:"total"`
+	Exercises []exerciseReport `json:"exercises"`
+}
+
+// exerciseReport is the outcome of an exercise in the report.
+type exerciseReport struct {
+	Exercise         string             `json:"exercise"`
+	Passed           bool               `json:"passed"`
+	Duration         time.Duration      `json:"duration"`
+	TimedOut         bool               `json:"timed_out,omitempty"`
+	Panicked         bool               `json:"panicked,omitempty"`
+	FailedTests      []string           `json:"failed_tests,omitempty"`
+	FailedAssertions []grader.Assertion `json:"failed_assertions,omitempty"`
+	HintsUsed        []string           `json:"hints_used,omitempty"`
+	Skipped          string             `json:"skipped,omitempty"`
+	Coverage         *coverageReport    `json:"coverage,omitempty"`
+	Bench            string             `json:"bench,omitempty"`
+}
+
+// coverageReport is the coverage of an exercise with a coverage gate in the report.
+type coverageReport struct {
+	Percent  float64 `json:"percent"`
+	Required float64 `json:"required"`
+	// Uncovered are ranges of lines the tests never ran, like "module/file.go:10-12".
+	Uncovered []string `json:"uncovered,omitempty"`
+}
+
+func reportPath(e *env) string {
+	return filepath.Join(e.manifest.ProgressDir(), "report.json")
+}
+
+func runReport(ctx context.Context, e *env, args []string) error {
+	var output string
+
+	opts, err := parseRunOptions(e, "report", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&output, "o", reportPath(e), "write the report to the file, - for stdout")
+	})
+	if err != nil {
+		return err
+	}
+
+	// Status lines would break JSON on stdout.
+	progress := e
+	if output == "-" {
+		progress = &env{manifest: e.manifest, stdin: e.stdin, stdout: io.Discard}
+	}
+
+	start := time.Now()
+
+	results, err := runTargets(ctx, progress, opts)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(newReport(start, results), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	if output == "-" {
+		if _, err := e.stdout.Write(data); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+
+		fmt.Fprintf(e.stdout, "Report written to %s\n", output)
+	}
+
+	return summary(progress, results)
+}
+
+func newReport(start time.Time, results []runner.Result) report {
+	r := report{Time: start, Total: len(results), Exercises: make([]exerciseReport, 0, len(results))}
+
+	for _, res := range results {
+		ex := exerciseReport{
+			Exercise:         res.Target.ID(),
+			Passed:           res.Passed,
+			Duration:         res.Duration,
+			TimedOut:         res.TimedOut,
+			Panicked:         res.Panicked,
+			FailedTests:      res.FailedTests,
+			FailedAssertions: res.Assertions,
+			Skipped:          res.Skipped,
+			Bench:            res.Bench,
+		}
+
+		if cov := res.Coverage; cov != nil {
+			ex.Coverage = &coverageReport{Percent: cov.Percent, Required: cov.Required}
+
+			for _, b := range cov.Uncovered {
+				ex.Coverage.Uncovered = append(ex.Coverage.Uncovered, fmt.Sprintf("%s:%d-%d", b.File, b.StartLine, b.EndLine))
+			}
+		}
+
+		// Exercises that could not run on this machine are not graded.
+		if res.Skipped != "" {
+			r.Total--
+		}
+
+		for _, a := range res.Assertions {
+			if a.Hint != "" && !slices.Contains(ex.HintsUsed, a.Hint) {
+				ex.HintsUsed = append(ex.HintsUsed, a.Hint)
+			}
+		}
+
+		if res.Passed {
+			r.Passed++
+		}
+
+		r.Exercises = append(r.Exercises, ex)
+	}
+
+	return r
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ksysoev/go-workshops/internal/tui"
+)
+
+func runTUI(ctx context.Context, e *env, name string, args []string) error {
+	opts, err := parseRunOptions(e, name, args)
+	if err != nil {
+		return err
+	}
+
+	store, stop, err := autosave(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	err = tui.New(e.manifest, opts.targets, progressRunner{Runner: opts.runner, store: store, manifest: e.manifest, bench: opts.runner}, e.lang).Run(ctx)
+	if stopErr := stop(); err == nil {
+		err = stopErr
+	}
+
+	return err
+}
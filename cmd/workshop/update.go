@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/update"
+)
+
+// version is the version of the workshop binary, it's set at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.0" ./cmd/workshop
+var version = "dev"
+
+// updateURLEnv overrides the release endpoint from the manifest.
+const updateURLEnv = "WORKSHOP_UPDATE_URL"
+
+func updateDir(m *manifest.Manifest) string {
+	return filepath.Join(m.ProgressDir(), "update")
+}
+
+func runUpdate(ctx context.Context, e *env, args []string) error {
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	endpoint := flags.String("endpoint", "", "release endpoint, defaults to $"+updateURLEnv+" or update_url from "+manifest.FileName)
+	check := flags.Bool("check", false, "only check for a new release")
+	binary := flags.Bool("binary", true, "update the workshop binary")
+	content := flags.Bool("content", true, "sync exercise modules")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	url := *endpoint
+	if url == "" {
+		url = os.Getenv(updateURLEnv)
+	}
+
+	if url == "" {
+		url = e.manifest.UpdateURL
+	}
+
+	if url == "" {
+		return fmt.Errorf("release endpoint is not configured, set update_url in %s or pass -endpoint", manifest.FileName)
+	}
+
+	client := update.NewClient(url)
+
+	release, err := client.Latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	syncer := update.NewSyncer(e.manifest.Dir, updateDir(e.manifest))
+
+	synced, err := syncer.Version()
+	if err != nil {
+		return err
+	}
+
+	if synced == "" {
+		synced = "never synced"
+	}
+
+	fmt.Fprintf(e.stdout, "Latest release: %s\nBinary: %s\nContent: %s\n", release.Version, version, synced)
+
+	if *check {
+		return nil
+	}
+
+	if *binary {
+		if err := updateBinary(ctx, e, client, release); err != nil {
+			return err
+		}
+	}
+
+	if *content {
+		return updateContent(ctx, e, client, syncer, release)
+	}
+
+	return nil
+}
+
+func updateBinary(ctx context.Context, e *env, client *update.Client, release *update.Release) error {
+	switch {
+	case version == release.Version:
+		fmt.Fprintln(e.stdout, "\nBinary is up to date")
+		return nil
+	case version == "dev":
+		// Binaries built from source, e.g. with go run, are updated with the content sync.
+		fmt.Fprintln(e.stdout, "\nBinary is built from source, it's updated with the content")
+		return nil
+	}
+
+	asset, ok := release.Binary()
+	if !ok {
+		fmt.Fprintf(e.stdout, "\nRelease %s has no binary for this platform, build it from source: go build ./cmd/workshop\n", release.Version)
+		return nil
+	}
+
+	data, err := client.Download(ctx, asset)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate workshop binary: %w", err)
+	}
+
+	if err := update.ReplaceBinary(exe, data); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "\nBinary updated: %s -> %s\n", version, release.Version)
+
+	return nil
+}
+
+func updateContent(ctx context.Context, e *env, client *update.Client, syncer *update.Syncer, release *update.Release) error {
+	synced, err := syncer.Version()
+	if err != nil {
+		return err
+	}
+
+	if synced == release.Version {
+		fmt.Fprintln(e.stdout, "\nContent is up to date")
+		return nil
+	}
+
+	archive, err := client.Download(ctx, release.Content)
+	if err != nil {
+		return err
+	}
+
+	report, err := syncer.Sync(release.Version, archive)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "\nContent synced to %s\n", release.Version)
+
+	for _, group := range []struct {
+		status string
+		files  []string
+	}{
+		{"added", report.Added},
+		{"updated", report.Updated},
+		{"merged", report.Merged},
+		{"removed", report.Removed},
+		{"kept", report.Kept},
+		{"conflict", report.Conflicts},
+	} {
+		for _, f := range group.files {
+			fmt.Fprintf(e.stdout, "  %-8s %s\n", group.status, f)
+		}
+	}
+
+	if len(report.Kept) > 0 {
+		fmt.Fprintln(e.stdout, "\nKept files differ from the release, but the checkout has no git history to merge them with")
+	}
+
+	if len(report.Conflicts) > 0 {
+		return errors.New("your changes conflict with the update, resolve conflict markers in the files above and run workshop verify")
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+// runOptions are flags shared by commands that run exercises.
+type runOptions struct {
+	runner  *runner.Runner
+	verbose bool
+	targets []manifest.Target
+
+	// command is recorded in the session along with results.
+	command []string
+}
+
+// trackFlag defines the -track flag that limits selected exercises by difficulty.
+func trackFlag(flags *flag.FlagSet) *manifest.Level {
+	track := manifest.DefaultTrack
+
+	flags.Func("track", "include exercises up to the level: beginner, intermediate, or advanced (default intermediate)", func(s string) error {
+		l, err := manifest.ParseLevel(s)
+		track = l
+
+		return err
+	})
+
+	return &track
+}
+
+// parseRunOptions parses shared flags, define adds flags specific to the command.
+func parseRunOptions(e *env, name string, args []string, define ...func(*flag.FlagSet)) (*runOptions, error) {
+	opts := &runOptions{runner: runner.New(e.manifest), command: append([]string{name}, args...)}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stdout)
+	flags.BoolVar(&opts.runner.Race, "race", false, "run all exercises with the race detector")
+	flags.BoolVar(&opts.verbose, "v", false, "print test output for failed exercises")
+	flags.BoolVar(&opts.runner.Strict, "strict", false, "also run hidden tests that check solutions more strictly")
+	flags.DurationVar(&opts.runner.Timeout, "timeout", 0, "limit each exercise run, overriding timeouts from the manifest")
+	track := trackFlag(flags)
+
+	for _, fn := range define {
+		fn(flags)
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	targets, err := e.manifest.SelectTrack(*track, flags.Args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.targets = targets
+
+	return opts, nil
+}
+
+func verify(ctx context.Context, e *env, args []string) error {
+	var stress int
+
+	opts, err := parseRunOptions(e, "verify", args, func(flags *flag.FlagSet) {
+		flags.IntVar(&stress, "stress", 0, "run tests of every exercise n times in random order with the race detector to reveal flaky tests")
+	})
+	if err != nil {
+		return err
+	}
+
+	if stress < 0 {
+		return fmt.Errorf("invalid stress %d", stress)
+	}
+
+	opts.runner.Stress = stress
+
+	results, err := runTargets(ctx, e, opts)
+	if err != nil {
+		return err
+	}
+
+	return summary(e, results)
+}
+
+// runTargets runs selected exercises one by one and prints their status.
+// The run is recorded in the session with snapshots of module files, so it can be replayed later.
+// Progress is saved after each exercise, so results of exercises finished before an interrupt are kept,
+// and neither the interrupted exercise nor the session are recorded.
+func runTargets(ctx context.Context, e *env, opts *runOptions) (_ []runner.Result, err error) {
+	store, stop, err := autosave(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if stopErr := stop(); err == nil {
+			err = stopErr
+		}
+	}()
+
+	start := time.Now()
+	results := make([]runner.Result, 0, len(opts.targets))
+
+	for _, t := range opts.targets {
+		res, err := opts.runner.Run(ctx, t)
+		if err == nil {
+			err = benchGate(ctx, e.manifest, opts.runner, &res)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// An interrupted run fails, recording it would overwrite the result of an exercise the learner has solved.
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		results = append(results, res)
+
+		if res.Skipped == "" {
+			store.Record(t.ID(), res.Passed, time.Now())
+		}
+
+		printResult(e, res)
+
+		if !res.Passed && opts.verbose {
+			e.stdout.Write(res.Output)
+		}
+	}
+
+	if err := recordSession(e.manifest, opts, start, results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func printResult(e *env, res runner.Result) {
+	status := "PASS"
+	if !res.Passed {
+		status = "FAIL"
+	}
+
+	note := ""
+
+	switch {
+	case res.Skipped != "":
+		status, note = "SKIP", " ("+res.Skipped+")"
+	case res.TimedOut:
+		note = " (timed out)"
+	case res.Panicked:
+		note = " (panic)"
+	case res.Flaky():
+		note = " (flaky)"
+	case res.Bench != "":
+		note = " (" + res.Bench + ")"
+	case res.Coverage != nil && !res.Coverage.Met():
+		note = fmt.Sprintf(" (coverage %.1f%% of %g%%)", res.Coverage.Percent, res.Coverage.Required)
+	case res.Coverage != nil:
+		note = fmt.Sprintf(" (coverage %.1f%%)", res.Coverage.Percent)
+	case res.Race:
+		note = " (race)"
+	}
+
+	fmt.Fprintf(e.stdout, "%s %s%s %.2fs\n", status, res.Target.ID(), note, res.Duration.Seconds())
+
+	if res.Coverage != nil && !res.Coverage.Met() {
+		printUncovered(e, res.Coverage)
+	}
+
+	if res.Runs > 1 && !res.Passed {
+		printFailedRuns(e, res)
+	}
+}
+
+// printFailedRuns prints how many runs of every test failed, a test that fails only sometimes is flaky.
+func printFailedRuns(e *env, res runner.Result) {
+	failed := res.FailedRuns()
+
+	for _, name := range slices.Sorted(maps.Keys(failed)) {
+		n := failed[name]
+		fmt.Fprintf(e.stdout, "    %s failed %d/%d runs (%.0f%%)\n", name, n, res.Runs, 100*float64(n)/float64(res.Runs))
+	}
+}
+
+// maxUncoveredLines limits the code printed for a block the tests never ran.
+const maxUncoveredLines = 5
+
+// printUncovered prints code the tests never ran, it shows which cases are left to test.
+func printUncovered(e *env, cov *runner.Coverage) {
+	files := make(map[string][]string)
+
+	for _, b := range cov.Uncovered {
+		lines, ok := files[b.File]
+		if !ok {
+			data, _ := os.ReadFile(filepath.Join(e.manifest.Dir, b.File))
+			lines = strings.Split(string(data), "\n")
+			files[b.File] = lines
+		}
+
+		fmt.Fprintf(e.stdout, "    not covered: %s:%d\n", b.File, b.StartLine)
+
+		for n := b.StartLine; n <= b.EndLine && n <= len(lines); n++ {
+			if n-b.StartLine == maxUncoveredLines {
+				fmt.Fprintln(e.stdout, "        ...")
+				break
+			}
+
+			fmt.Fprintf(e.stdout, "        %s\n", strings.ReplaceAll(lines[n-1], "\t", "    "))
+		}
+	}
+}
+
+// summary prints the number of passed exercises and returns errFailed if some of them failed.
+// Skipped exercises are not counted as failed.
+func summary(e *env, results []runner.Result) error {
+	passed, skipped := 0, 0
+
+	for _, res := range results {
+		switch {
+		case res.Skipped != "":
+			skipped++
+		case res.Passed:
+			passed++
+		}
+	}
+
+	total := len(results) - skipped
+
+	if skipped > 0 {
+		fmt.Fprintf(e.stdout, "\n%d/%d exercises passed, %d skipped\n", passed, total, skipped)
+	} else {
+		fmt.Fprintf(e.stdout, "\n%d/%d exercises passed\n", passed, total)
+	}
+
+	if passed < total {
+		return errFailed
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+// watch runs the exercises, and runs exercises of a module again after every change of its files until interrupted.
+// Each run is a verify run: progress is saved after every exercise and on Ctrl+C, and the run is recorded for replay.
+func watch(ctx context.Context, e *env, args []string) error {
+	var interval time.Duration
+
+	opts, err := parseRunOptions(e, "watch", args, func(flags *flag.FlagSet) {
+		flags.DurationVar(&interval, "interval", 500*time.Millisecond, "how often module files are checked for changes")
+	})
+	if err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s", interval)
+	}
+
+	var modules []*manifest.Module
+
+	byModule := make(map[*manifest.Module][]manifest.Target)
+
+	for _, t := range opts.targets {
+		if _, ok := byModule[t.Module]; !ok {
+			modules = append(modules, t.Module)
+		}
+
+		byModule[t.Module] = append(byModule[t.Module], t)
+	}
+
+	states := make(map[*manifest.Module][]byte, len(modules))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		changed := false
+
+		for _, m := range modules {
+			state, err := moduleState(filepath.Join(e.manifest.Dir, m.Path))
+			if err != nil {
+				return err
+			}
+
+			if bytes.Equal(state, states[m]) {
+				continue
+			}
+
+			states[m], changed = state, true
+
+			moduleOpts := *opts
+			moduleOpts.targets = byModule[m]
+
+			fmt.Fprintf(e.stdout, "--- %s at %s\n", m.Name, time.Now().Format(time.TimeOnly))
+
+			if _, err := runTargets(ctx, e, &moduleOpts); ctx.Err() != nil {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if changed {
+			fmt.Fprintln(e.stdout, "Watching for changes, press Ctrl+C to stop.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// moduleState returns names, sizes, and modification times of Go files in the module, it changes when a file is saved.
+func moduleState(dir string) ([]byte, error) {
+	var state bytes.Buffer
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Editors replace files on save, the file is seen on the next check.
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".go" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&state, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module files: %w", err)
+	}
+
+	return state.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+// Command enumstring generates String methods for enum types, it's meant to be run by go generate:
+//
+//	//go:generate enumstring -type=Status
+//
+// It writes the method of the type Status to status_string.go in the current directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ksysoev/go-workshops/codegen"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the enum type")
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*typeName); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(typeName string) error {
+	e, err := codegen.Load(".", typeName)
+	if err != nil {
+		return err
+	}
+
+	src, err := codegen.Generate(e)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(strings.ToLower(typeName)+"_string.go", src, 0o644)
+}
@@ -0,0 +1,136 @@
+// Package codegen is a workshop on go generate: enumstring, a generator of String methods for enum types.
+//
+// Exercises are in this file and in testdata, not in test files: the generator is a command
+// that go generate runs, so its code has to be part of a regular package.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Go has no enums, a named type with a block of constants and iota plays their role:
+//
+//	type Status int
+//
+//	const (
+//		StatusPending Status = iota
+//		StatusPaid
+//	)
+//
+// Printing such a value shows a number, and writing a String method for every type by hand is tedious
+// and gets out of date when a constant is added. Tools like stringer generate it instead.
+// A generator is an ordinary program: it parses the package with go/parser, finds what it needs in the syntax tree,
+// renders Go source, and formats it with go/format. A //go:generate comment in the package runs it:
+//
+//	//go:generate stringer -type=Status
+//
+// go generate isn't part of go build, it runs only when asked, and generated files are committed,
+// so users of the package don't need the generator.
+
+// 1. Finding constants.
+// Load parses the package and collects the constants of the enum type, but it finds only StatusPending in testdata/shop.
+// In a const block, a spec without a type and a value repeats the type and the expression of the previous one,
+// that's how iota works. A spec with a value and without a type is untyped and doesn't belong to the enum.
+// Walk the specs of a block keeping the type of the last one.
+
+// Enum is a named type with constants.
+type Enum struct {
+	Package string
+	Type    string
+	// Names of the constants of the type in the order of declaration.
+	Names []string
+}
+
+// Load parses Go files of the package in dir, except tests, and returns the enum of the type.
+func Load(dir, typeName string) (*Enum, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	e := &Enum{Type: typeName}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Package = f.Name.Name
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+
+				if ident, ok := vs.Type.(*ast.Ident); ok && ident.Name == typeName {
+					for _, n := range vs.Names {
+						e.Names = append(e.Names, n.Name)
+					}
+				}
+			}
+		}
+	}
+
+	if len(e.Names) == 0 {
+		return nil, fmt.Errorf("no constants of type %s in %s", typeName, dir)
+	}
+
+	return e, nil
+}
+
+// 2. Running the generator.
+// cmd/enumstring is the generator: go generate runs it in the directory of the package,
+// and it writes status_string.go next to status.go. Install it, so go generate finds it in PATH:
+//
+//	go install ./codegen/cmd/enumstring
+//
+// Add the //go:generate directive to testdata/shop/status.go, and mark the output of Generate as generated:
+// tools recognize generated files by a line matching `^// Code generated .* DO NOT EDIT\.$` before the package clause.
+// Linters skip them, and code review tools collapse them.
+
+// Generate returns formatted source of the String method of the enum.
+func Generate(e *Enum) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "package %s\n\n", e.Package)
+	fmt.Fprintf(&buf, "import \"strconv\"\n\n")
+	fmt.Fprintf(&buf, "func (v %s) String() string {\n", e.Type)
+	fmt.Fprintf(&buf, "switch v {\n")
+
+	for _, name := range e.Names {
+		fmt.Fprintf(&buf, "case %s:\nreturn %q\n", name, name)
+	}
+
+	fmt.Fprintf(&buf, "}\n\n")
+	fmt.Fprintf(&buf, "return \"%s(\" + strconv.FormatInt(int64(v), 10) + \")\"\n", e.Type)
+	fmt.Fprintf(&buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// 3. Advanced: type-checked constants.
+// The syntax tree knows names, not values. In testdata/perm, Perm is a set of flags with an alias and a blank constant:
+// Default has the same value as Read, so the generated switch has a duplicate case and doesn't compile,
+// and `case _:` isn't valid either.
+// Only the type checker knows that 1 << iota in the first spec is 1. Type-check the package with go/types,
+// take the constants of the type from the scope of the package, sorted by position,
+// skip blank ones and keep only the first name of every value.
@@ -0,0 +1,96 @@
+package codegen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	e, err := Load(filepath.Join("testdata", "shop"), "Status")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"StatusPending", "StatusPaid", "StatusShipped", "StatusCancelled"}
+
+	if e.Package != "shop" || !slices.Equal(e.Names, expected) {
+		t.Errorf("Expected constants %v of package shop, got %v of package %s", expected, e.Names, e.Package)
+	}
+
+	if _, err := Load(filepath.Join("testdata", "shop"), "Order"); err == nil {
+		t.Error("Expected an error for a type without constants")
+	}
+}
+
+var generatedHeader = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+func TestGoGenerate(t *testing.T) {
+	dir := generate(t, "shop")
+
+	src, err := os.ReadFile(filepath.Join(dir, "status_string.go"))
+	if err != nil {
+		t.Fatalf("Expected go generate to write status_string.go, got %v", err)
+	}
+
+	if !generatedHeader.Match(src) {
+		t.Errorf("Expected status_string.go to be marked as generated, got:\n%s", src)
+	}
+
+	goCommand(t, dir, "test", "-count=1", ".")
+}
+
+func TestLoadTypeChecked(t *testing.T) {
+	e, err := Load(filepath.Join("testdata", "perm"), "Perm")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"Read", "Write", "Exec", "Admin", "All"}
+
+	if !slices.Equal(e.Names, expected) {
+		t.Errorf("Expected a constant per value %v, got %v", expected, e.Names)
+	}
+
+	dir := generate(t, "perm")
+	goCommand(t, dir, "test", "-count=1", ".")
+}
+
+// generate copies the package from testdata to a temporary directory and runs go generate in it,
+// with enumstring built from cmd/enumstring in PATH. It returns the directory.
+func generate(t *testing.T, pkg string) string {
+	t.Helper()
+
+	bin := t.TempDir()
+	goCommand(t, ".", "build", "-o", bin, "./cmd/enumstring")
+
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Join("testdata", pkg))); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "generate", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go generate failed: %v\n%s", err, out)
+	}
+
+	return dir
+}
+
+func goCommand(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
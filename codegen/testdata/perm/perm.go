@@ -0,0 +1,17 @@
+package perm
+
+//go:generate enumstring -type=Perm
+
+// Perm is a set of permissions.
+type Perm uint8
+
+const (
+	Read Perm = 1 << iota
+	Write
+	Exec
+	_
+	Admin
+)
+
+// Default is the permission of new users.
+const Default Perm = 1
@@ -0,0 +1,27 @@
+package perm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPermString(t *testing.T) {
+	tests := []struct {
+		perm     Perm
+		expected string
+	}{
+		{Read, "Read"},
+		{Write, "Write"},
+		{Exec, "Exec"},
+		{Admin, "Admin"},
+		{Default, "Read"},
+		{All, "All"},
+		{Perm(8), "Perm(8)"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.perm); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
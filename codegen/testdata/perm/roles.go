@@ -0,0 +1,4 @@
+package perm
+
+// All is every permission except Admin.
+const All = Read | Write | Exec
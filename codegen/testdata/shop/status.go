@@ -0,0 +1,21 @@
+package shop
+
+// Status is a status of an order.
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusPaid
+	StatusShipped
+	StatusCancelled
+)
+
+// maxRetries is untyped, it isn't a Status.
+const maxRetries = 3
+
+// Order is an order of the shop.
+type Order struct {
+	ID      int
+	Status  Status
+	Retries int
+}
@@ -0,0 +1,25 @@
+package shop
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStatusString(t *testing.T) {
+	tests := []struct {
+		status   Status
+		expected string
+	}{
+		{StatusPending, "StatusPending"},
+		{StatusPaid, "StatusPaid"},
+		{StatusShipped, "StatusShipped"},
+		{StatusCancelled, "StatusCancelled"},
+		{Status(42), "Status(42)"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.status); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package composition
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Go has no inheritance. A struct embeds another type instead: its fields and methods are promoted,
+// so they can be used as if they were declared by the outer struct, and the outer struct implements
+// the interfaces of the embedded type. Embedding an interface is the cheapest decorator in Go:
+// the struct implements the whole interface by delegating to the value inside it, and declares only
+// the methods it changes.
+
+// 1. Decorators.
+// ResponseTime reports how long the handler took in the X-Response-Time header, but it sets the header
+// after the handler returns, and headers are sent with the first WriteHeader or Write: too late.
+// The header has to be set right before it's sent, and only the ResponseWriter knows when that is.
+//
+// Wrap the ResponseWriter in a struct that embeds it, and set the header in WriteHeader. That's not enough:
+// a handler that calls only Write never calls WriteHeader of your struct, the promoted Write calls
+// WriteHeader of the writer inside it. And a handler may not write anything at all, then the server
+// sends 200 OK after the handler returns.
+
+// ResponseTimeHeader is the header with the time the handler took to respond.
+const ResponseTimeHeader = "X-Response-Time"
+
+// ResponseTime is middleware that reports how long the handler took to write the response header.
+func ResponseTime(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		next.ServeHTTP(w, r)
+
+		w.Header().Set(ResponseTimeHeader, time.Since(start).String())
+	})
+}
+
+func TestResponseTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"write header", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":1}`)
+		}, http.StatusCreated},
+		{"write only", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprint(w, "hello")
+		}, http.StatusOK},
+		{"nothing written", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+		}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ResponseTime(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			// Result returns headers as they were sent.
+			resp := rec.Result()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+
+			d, err := time.ParseDuration(resp.Header.Get(ResponseTimeHeader))
+			if err != nil || d < 10*time.Millisecond {
+				t.Errorf("Expected the response time of at least 10ms in the sent headers, got %q", resp.Header.Get(ResponseTimeHeader))
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package composition
+
+import (
+	"strings"
+	"testing"
+)
+
+// 3. Embedding is not inheritance.
+// A promoted method is still a method of the embedded type: its receiver is the embedded value,
+// which knows nothing about the struct around it. When Notifier.Send calls n.Format, it calls Format
+// of Notifier, always. UrgentNotifier declares its own Format, and it's called only when someone calls it
+// on an UrgentNotifier: there are no virtual methods that Send could dispatch to.
+//
+// UrgentNotifier was written as if there were. Make urgent notifications formatted as urgent, keeping
+// the delivery in one place. Behavior that varies goes in as a value: an interface or a func field of Notifier,
+// which NewUrgentNotifier sets up.
+
+// Notifier sends notifications to a channel.
+type Notifier struct {
+	Channel string
+	sent    []string
+}
+
+// Format formats the message of a notification.
+func (n *Notifier) Format(msg string) string {
+	return "[" + n.Channel + "] " + msg
+}
+
+// Send formats and sends the message.
+func (n *Notifier) Send(msg string) {
+	n.sent = append(n.sent, n.Format(msg))
+}
+
+// Sent returns all sent notifications.
+func (n *Notifier) Sent() []string {
+	return n.sent
+}
+
+// UrgentNotifier sends notifications that must not be missed.
+type UrgentNotifier struct {
+	Notifier
+}
+
+// Format formats the message in capitals, with a siren.
+func (u *UrgentNotifier) Format(msg string) string {
+	return "🚨 " + u.Notifier.Format(strings.ToUpper(msg))
+}
+
+// NewUrgentNotifier creates an urgent notifier for the channel.
+func NewUrgentNotifier(channel string) *UrgentNotifier {
+	return &UrgentNotifier{Notifier: Notifier{Channel: channel}}
+}
+
+func TestUrgentNotifier(t *testing.T) {
+	n := NewUrgentNotifier("ops")
+
+	n.Send("disk is full")
+
+	if got := n.Sent(); len(got) != 1 || got[0] != "🚨 [ops] DISK IS FULL" {
+		t.Errorf("Expected an urgent notification %q, got %q", "🚨 [ops] DISK IS FULL", got)
+	}
+
+	plain := &Notifier{Channel: "dev"}
+	plain.Send("build passed")
+
+	if got := plain.Sent(); len(got) != 1 || got[0] != "[dev] build passed" {
+		t.Errorf("Expected a plain notification %q, got %q", "[dev] build passed", got)
+	}
+}
@@ -0,0 +1,100 @@
+package composition
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// 2. Promotion and ambiguity.
+// Promoted names follow two rules. A name declared at a shallower depth wins: a field of the struct itself
+// hides fields and methods of the same name in embedded types. And two names at the same depth cancel out:
+// neither is promoted. Using an ambiguous name is a compile error, but nothing reports names that are
+// only looked up at run time:
+// - encoding/json leaves out fields that conflict at the same depth, without an error,
+// - fmt looks for a String method, and a struct embedding two fmt.Stringers isn't one.
+//
+// Order embeds Entity and Audit, and both have an ID. Fix the JSON of Order without renaming fields
+// of the embedded types, other code uses them, and make PriceTag print as "Gopher plush: 15.00 EUR".
+
+// Entity is the base of everything stored in the database.
+type Entity struct {
+	ID        int
+	CreatedAt time.Time
+}
+
+// Audit records who changed an entity last.
+type Audit struct {
+	ID string
+	By string
+}
+
+// Order is an order of a customer.
+type Order struct {
+	Entity
+	Audit
+	Customer string
+}
+
+// Money is an amount in cents of a currency.
+type Money struct {
+	Cents    int
+	Currency string
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%d.%02d %s", m.Cents/100, m.Cents%100, m.Currency)
+}
+
+// Label is the name of a product.
+type Label struct {
+	Name string
+}
+
+func (l Label) String() string {
+	return l.Name
+}
+
+// PriceTag is the label of a product with its price.
+type PriceTag struct {
+	Label
+	Money
+}
+
+func TestOrderJSON(t *testing.T) {
+	order := Order{
+		Entity:   Entity{ID: 42, CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		Audit:    Audit{ID: "change-7", By: "alice"},
+		Customer: "bob",
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got map[string]any
+	json.Unmarshal(data, &got)
+
+	if got["ID"] != float64(42) {
+		t.Errorf("Expected the ID of the order 42 in JSON, got %s", data)
+	}
+
+	if got["By"] != "alice" || got["Customer"] != "bob" || got["CreatedAt"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("Expected other fields to stay as they are, got %s", data)
+	}
+
+	// Order stays an Entity and an Audit for the code that uses them.
+	if order.Entity.ID != 42 || order.Audit.ID != "change-7" {
+		t.Errorf("Expected IDs of embedded types to keep their values, got %d and %q", order.Entity.ID, order.Audit.ID)
+	}
+}
+
+func TestPriceTag(t *testing.T) {
+	tag := PriceTag{Label: Label{Name: "Gopher plush"}, Money: Money{Cents: 1500, Currency: "EUR"}}
+
+	if got := fmt.Sprint(tag); got != "Gopher plush: 15.00 EUR" {
+		t.Errorf("Expected the price tag to print as %q, got %q", "Gopher plush: 15.00 EUR", got)
+	}
+}
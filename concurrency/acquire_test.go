@@ -0,0 +1,131 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// EatPasta deadlocks, because philosophers take the cutlery in different order, and each of them holds one item forever.
+// Instead of taking locks one by one, we can acquire all of them at once or none of them.
+// sync.Mutex has TryLock method, it acquires the lock only if it's free and never blocks.
+// With TryLock we can implement AcquireAll:
+// 1. Sort locks in the same order for all callers (deadlock-free ordering).
+// 2. Try to lock them one by one.
+// 3. If some lock is busy, roll back: unlock everything acquired so far, wait a bit, and try again.
+// 4. Give up when the context is done.
+//
+// Let's implement AcquireAll and use it to fix the dining philosophers below.
+// Once you are done, compare your implementation with syncutil.AcquireAll, it's exported for other exercises to use.
+
+// TryLocker is a lock that can be acquired without blocking, sync.Mutex and sync.RWMutex implement it.
+type TryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// AcquireAll acquires all locks or none of them.
+// It returns a function that releases all acquired locks,
+// or an error if locks can't be acquired before the context is done.
+func AcquireAll(ctx context.Context, locks ...TryLocker) (release func(), err error) {
+	for _, l := range locks {
+		l.Lock()
+	}
+
+	return func() {
+		for _, l := range locks {
+			l.Unlock()
+		}
+	}, nil
+}
+
+func TestAcquireAllRollback(t *testing.T) {
+	fork := &sync.Mutex{}
+	spoon := &sync.Mutex{}
+
+	spoon.Lock()
+	defer spoon.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	errs := make(chan error, 1)
+
+	go func() {
+		_, err := AcquireAll(ctx, fork, spoon)
+		errs <- err
+	}()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected error to be %v, got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Expected AcquireAll to give up when context is done")
+	}
+
+	if !fork.TryLock() {
+		t.Error("Expected fork to be released after failed attempt")
+	}
+}
+
+// Cutlery is a lock that takes some time to pick up, like in EatPasta.
+type Cutlery struct {
+	sync.Mutex
+}
+
+func (c *Cutlery) Lock() {
+	c.Mutex.Lock()
+	time.Sleep(time.Microsecond)
+}
+
+// EatPastaPolitely acquires all the cutlery at once for every meal, so philosophers never deadlock.
+func EatPastaPolitely(ctx context.Context, name string, meals int, result chan<- string, cutlery ...TryLocker) {
+	for i := 0; i < meals; i++ {
+		release, err := AcquireAll(ctx, cutlery...)
+		if err != nil {
+			result <- name + " is still hungry: " + err.Error()
+			return
+		}
+
+		time.Sleep(time.Microsecond)
+		release()
+	}
+
+	result <- name + " is done eating pasta"
+}
+
+func TestDiningPhilosophers(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	names := []string{"Plato", "Socrates", "Aristotle", "Pythagoras", "Epicurus"}
+	cutlery := make([]TryLocker, len(names))
+
+	for i := range cutlery {
+		cutlery[i] = &Cutlery{}
+	}
+
+	results := make(chan string)
+	deadlock := testutil.WithDeadlockReport(t, 1*time.Second)
+
+	// Philosophers sit at a round table, everyone takes the left item first and the right one second.
+	// With plain Lock calls it's a classic circular wait.
+	for i, name := range names {
+		go EatPastaPolitely(ctx, name, 100, results, cutlery[i], cutlery[(i+1)%len(cutlery)])
+	}
+
+	for range names {
+		select {
+		case res := <-results:
+			t.Log(res)
+		case <-deadlock:
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package concurrency
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// TestAtomacity shows that counter++ is not atomic, it's a read, an increment, and a write.
+// There are three common ways to make it safe for concurrent use, let's implement all of them
+// and compare their performance:
+//
+//	go test -run '^$' -bench Counter ./concurrency
+//
+// workshop bench records the benchmarks of the module as a baseline on the first run and compares later runs with it,
+// so run it before you start to see how every counter changes.
+
+// Counter is a counter that is safe for concurrent use.
+type Counter interface {
+	Inc()
+	Value() int
+}
+
+// 1. MutexCounter should protect the value with the mutex.
+
+// MutexCounter is a counter protected by a mutex.
+type MutexCounter struct {
+	mu    sync.Mutex
+	value int
+}
+
+func (c *MutexCounter) Inc() {
+	c.value++
+}
+
+func (c *MutexCounter) Value() int {
+	return c.value
+}
+
+// 2. AtomicCounter should use atomic operations, for example atomic.Int64 from sync/atomic package.
+
+// AtomicCounter is a lock-free counter.
+type AtomicCounter struct {
+	value int
+}
+
+func (c *AtomicCounter) Inc() {
+	c.value++
+}
+
+func (c *AtomicCounter) Value() int {
+	return c.value
+}
+
+// 3. ChannelCounter should own the value in a single aggregator goroutine.
+// Other goroutines send increments and read requests to the aggregator through channels.
+// "Don't communicate by sharing memory; share memory by communicating."
+
+// ChannelCounter is a counter owned by a single aggregator goroutine.
+type ChannelCounter struct {
+	value int
+	incs  chan struct{}
+	reads chan chan int
+	done  chan struct{}
+}
+
+func NewChannelCounter() *ChannelCounter {
+	c := &ChannelCounter{
+		incs:  make(chan struct{}),
+		reads: make(chan chan int),
+		done:  make(chan struct{}),
+	}
+
+	go c.aggregate()
+
+	return c
+}
+
+// aggregate is the only goroutine that is allowed to access the value.
+func (c *ChannelCounter) aggregate() {
+	<-c.done
+}
+
+func (c *ChannelCounter) Inc() {
+	c.value++
+}
+
+func (c *ChannelCounter) Value() int {
+	return c.value
+}
+
+// Close stops the aggregator goroutine.
+func (c *ChannelCounter) Close() {
+	close(c.done)
+}
+
+// 4. RequestStats counts requests of a busy server, every request increments it from its own goroutine.
+// It's correct, but all goroutines contend for the same lock. Record the baseline first:
+//
+//	go run ./cmd/workshop bench concurrency
+//
+// Then make Inc at least 1.5 times faster with the counter that fits the hot path best, and run bench again.
+
+// RequestStats is a counter of served requests.
+type RequestStats struct {
+	mu     sync.Mutex
+	served int
+}
+
+func (s *RequestStats) Inc() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.served++
+}
+
+func (s *RequestStats) Value() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.served
+}
+
+func testCounter(t *testing.T, c Counter) {
+	t.Helper()
+	testutil.RequireRaceDetector(t)
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc()
+		}()
+	}
+
+	wg.Wait()
+
+	if c.Value() != 1000 {
+		t.Errorf("Expected counter to be 1000, got %d", c.Value())
+	}
+}
+
+func TestAtomicityMutex(t *testing.T) {
+	testCounter(t, &MutexCounter{})
+}
+
+func TestAtomicityAtomic(t *testing.T) {
+	testCounter(t, &AtomicCounter{})
+}
+
+func TestAtomicityChannel(t *testing.T) {
+	c := NewChannelCounter()
+	defer c.Close()
+
+	testCounter(t, c)
+}
+
+func TestRequestStats(t *testing.T) {
+	testCounter(t, &RequestStats{})
+}
+
+func benchmarkCounter(b *testing.B, c Counter) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc()
+		}
+	})
+}
+
+func BenchmarkCounterMutex(b *testing.B) {
+	benchmarkCounter(b, &MutexCounter{})
+}
+
+func BenchmarkCounterAtomic(b *testing.B) {
+	benchmarkCounter(b, &AtomicCounter{})
+}
+
+func BenchmarkCounterChannel(b *testing.B) {
+	c := NewChannelCounter()
+	defer c.Close()
+
+	benchmarkCounter(b, c)
+}
+
+func BenchmarkRequestStats(b *testing.B) {
+	benchmarkCounter(b, &RequestStats{})
+}
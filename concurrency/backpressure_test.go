@@ -0,0 +1,473 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// When a producer is faster than its consumer, values pile up between them. An unbounded queue only delays
+// the problem until the process runs out of memory, so the queue must be bounded, and the producer
+// must be told what happens when it's full. That's backpressure, and there are a few policies for it:
+//   - Block: Push waits for room, the producer slows down to the pace of the consumer. Nothing is lost,
+//     but a stuck consumer stops the producer, so Push must respect ctx. A buffered channel is such a queue.
+//   - Drop newest: Push never waits, a value that doesn't fit is discarded. The consumer sees the oldest values,
+//     like a server rejecting requests when it's overloaded.
+//   - Drop oldest: Push never waits, a value that doesn't fit evicts the oldest one. It's a ring buffer,
+//     the consumer sees the freshest values, like a log tail.
+//   - Sample: Push never waits, when the queue is full only every n-th value gets in, evicting the oldest one.
+//     The consumer sees a thinned out stream of fresh values, like metrics under load.
+//
+// Let's implement all four behind the Queue interface:
+//   - Pop waits for a value or until ctx is done.
+//   - Close wakes up everybody waiting: Push returns ErrQueueClosed, Pop returns values that are still
+//     in the queue and then ErrQueueClosed. No goroutine stays blocked after Close. Close can be called many times.
+//   - Dropped counts values discarded by the policy.
+//
+// Hint: the blocking queue is a buffered channel with a done channel, Send and Recv from sendrecv_test.go
+// are close to what it needs. The others need a mutex, a slice used as a ring buffer, and a way to wake up Pop,
+// like a channel with capacity of 1 that Push notifies without blocking.
+
+// ErrQueueClosed is returned by Push after the queue is closed, and by Pop after the closed queue is drained.
+var ErrQueueClosed = errors.New("queue closed")
+
+// Queue is a bounded queue between a producer and a consumer, policies differ in what Push does when it's full.
+type Queue[T any] interface {
+	// Push adds v to the queue, applying the overflow policy when the queue is full.
+	Push(ctx context.Context, v T) error
+
+	// Pop removes the oldest value, waiting for one until ctx is done.
+	Pop(ctx context.Context) (T, error)
+
+	// Close closes the queue and wakes up all waiting goroutines.
+	Close()
+
+	// Dropped returns the number of values discarded by the overflow policy.
+	Dropped() int
+}
+
+var (
+	_ Queue[int] = (*BlockingQueue[int])(nil)
+	_ Queue[int] = (*DropNewestQueue[int])(nil)
+	_ Queue[int] = (*DropOldestQueue[int])(nil)
+	_ Queue[int] = (*SamplingQueue[int])(nil)
+)
+
+// BlockingQueue makes Push wait for room when the queue is full.
+type BlockingQueue[T any] struct {
+	items chan T
+	done  chan struct{}
+}
+
+// NewBlockingQueue creates a blocking queue for size values.
+func NewBlockingQueue[T any](size int) *BlockingQueue[T] {
+	return &BlockingQueue[T]{}
+}
+
+func (q *BlockingQueue[T]) Push(ctx context.Context, v T) error {
+	return nil
+}
+
+func (q *BlockingQueue[T]) Pop(ctx context.Context) (T, error) {
+	var v T
+
+	return v, nil
+}
+
+func (q *BlockingQueue[T]) Close() {
+}
+
+func (q *BlockingQueue[T]) Dropped() int {
+	return 0
+}
+
+// DropNewestQueue discards values pushed when the queue is full.
+type DropNewestQueue[T any] struct {
+	items chan T
+	done  chan struct{}
+}
+
+// NewDropNewestQueue creates a queue for size values that drops the newest ones.
+func NewDropNewestQueue[T any](size int) *DropNewestQueue[T] {
+	return &DropNewestQueue[T]{}
+}
+
+func (q *DropNewestQueue[T]) Push(ctx context.Context, v T) error {
+	return nil
+}
+
+func (q *DropNewestQueue[T]) Pop(ctx context.Context) (T, error) {
+	var v T
+
+	return v, nil
+}
+
+func (q *DropNewestQueue[T]) Close() {
+}
+
+func (q *DropNewestQueue[T]) Dropped() int {
+	return 0
+}
+
+// DropOldestQueue evicts the oldest value when a value is pushed to the full queue.
+type DropOldestQueue[T any] struct {
+	mu   sync.Mutex
+	buf  []T
+	head int
+	n    int
+}
+
+// NewDropOldestQueue creates a ring buffer for size values.
+func NewDropOldestQueue[T any](size int) *DropOldestQueue[T] {
+	return &DropOldestQueue[T]{}
+}
+
+func (q *DropOldestQueue[T]) Push(ctx context.Context, v T) error {
+	return nil
+}
+
+func (q *DropOldestQueue[T]) Pop(ctx context.Context) (T, error) {
+	var v T
+
+	return v, nil
+}
+
+func (q *DropOldestQueue[T]) Close() {
+}
+
+func (q *DropOldestQueue[T]) Dropped() int {
+	return 0
+}
+
+// SamplingQueue lets in only every n-th value pushed while the queue is full, evicting the oldest one for it.
+type SamplingQueue[T any] struct {
+	mu   sync.Mutex
+	buf  []T
+	head int
+	n    int
+}
+
+// NewSamplingQueue creates a queue for size values that samples every n-th value under pressure.
+func NewSamplingQueue[T any](size, n int) *SamplingQueue[T] {
+	return &SamplingQueue[T]{}
+}
+
+func (q *SamplingQueue[T]) Push(ctx context.Context, v T) error {
+	return nil
+}
+
+func (q *SamplingQueue[T]) Pop(ctx context.Context) (T, error) {
+	var v T
+
+	return v, nil
+}
+
+func (q *SamplingQueue[T]) Close() {
+}
+
+func (q *SamplingQueue[T]) Dropped() int {
+	return 0
+}
+
+// queuePolicies are the queues with the behavior all policies share.
+var queuePolicies = []struct {
+	name string
+	new  func(size int) Queue[int]
+}{
+	{"block", func(size int) Queue[int] { return NewBlockingQueue[int](size) }},
+	{"drop-newest", func(size int) Queue[int] { return NewDropNewestQueue[int](size) }},
+	{"drop-oldest", func(size int) Queue[int] { return NewDropOldestQueue[int](size) }},
+	{"sample", func(size int) Queue[int] { return NewSamplingQueue[int](size, 2) }},
+}
+
+// waitPop and waitPush call the queue in their own goroutines and report errors to errs. While the call waits,
+// the goroutine is blocked in the helper, whatever types implement the queue, so tests find it with testsync.Blocked.
+func waitPop(ctx context.Context, q Queue[int], errs chan<- error) {
+	_, err := q.Pop(ctx)
+	errs <- err
+}
+
+func waitPush(ctx context.Context, q Queue[int], v int, errs chan<- error) {
+	errs <- q.Push(ctx, v)
+}
+
+func waiting(helper string, n int) bool {
+	return testsync.WaitUntil(func() bool { return testsync.Blocked(helper) == n }, time.Second)
+}
+
+// pushAll pushes values like a fast producer, it fails the test if the producer gets stuck.
+func pushAll(t *testing.T, q Queue[int], values ...int) {
+	t.Helper()
+
+	done := make(chan error, 1)
+
+	go func() {
+		for _, v := range values {
+			if err := q.Push(context.Background(), v); err != nil {
+				done <- err
+				return
+			}
+		}
+
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Push not to wait when the queue is full")
+	}
+}
+
+// drain closes the queue and pops the values that are left in it.
+func drain(t *testing.T, q Queue[int]) []int {
+	t.Helper()
+
+	q.Close()
+
+	var values []int
+
+	for range 100 {
+		v, err := q.Pop(context.Background())
+		if errors.Is(err, ErrQueueClosed) {
+			return values
+		}
+
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		values = append(values, v)
+	}
+
+	t.Fatal("Expected Pop to return ErrQueueClosed after the closed queue is drained")
+
+	return nil
+}
+
+func TestQueueFIFO(t *testing.T) {
+	for _, p := range queuePolicies {
+		t.Run(p.name, func(t *testing.T) {
+			q := p.new(4)
+
+			pushAll(t, q, 1, 2, 3, 4)
+
+			for want := 1; want <= 4; want++ {
+				if v, err := q.Pop(context.Background()); err != nil || v != want {
+					t.Fatalf("Expected to pop %d, got %d, %v", want, v, err)
+				}
+			}
+
+			if q.Dropped() != 0 {
+				t.Errorf("Expected nothing to be dropped while the queue has room, got %d", q.Dropped())
+			}
+		})
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	for _, p := range queuePolicies {
+		t.Run(p.name, func(t *testing.T) {
+			q := p.new(4)
+			popped := make(chan error, 1)
+
+			go waitPop(context.Background(), q, popped)
+
+			if !waiting("waitPop", 1) {
+				t.Fatal("Expected Pop to wait for a value")
+			}
+
+			q.Close()
+			q.Close()
+
+			select {
+			case err := <-popped:
+				if !errors.Is(err, ErrQueueClosed) {
+					t.Errorf("Expected error to be %v, got %v", ErrQueueClosed, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Expected Close to wake up Pop")
+			}
+
+			if err := q.Push(context.Background(), 1); !errors.Is(err, ErrQueueClosed) {
+				t.Errorf("Expected Push after Close to fail with %v, got %v", ErrQueueClosed, err)
+			}
+
+			q = p.new(4)
+			pushAll(t, q, 1, 2)
+
+			if values := drain(t, q); !slices.Equal(values, []int{1, 2}) {
+				t.Errorf("Expected values pushed before Close to be popped, got %v", values)
+			}
+		})
+	}
+}
+
+func TestQueuePopCanceled(t *testing.T) {
+	for _, p := range queuePolicies {
+		t.Run(p.name, func(t *testing.T) {
+			q := p.new(4)
+			defer q.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			popped := make(chan error, 1)
+
+			go waitPop(ctx, q, popped)
+
+			if !waiting("waitPop", 1) {
+				t.Fatal("Expected Pop to wait for a value")
+			}
+
+			cancel()
+
+			select {
+			case err := <-popped:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("Expected error to be %v, got %v", context.Canceled, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Expected Pop to return after cancellation")
+			}
+		})
+	}
+}
+
+func TestBlockingQueue(t *testing.T) {
+	q := NewBlockingQueue[int](2)
+	pushed := make(chan error, 10)
+
+	// The producer is fast, the consumer hasn't started yet: 2 values fit, the producer waits with the third one.
+	pushAll(t, q, 1, 2)
+
+	go waitPush(context.Background(), q, 3, pushed)
+
+	if !waiting("waitPush", 1) {
+		t.Fatal("Expected the producer to wait for room in the full queue")
+	}
+
+	for want := 1; want <= 3; want++ {
+		if v, err := q.Pop(context.Background()); err != nil || v != want {
+			t.Fatalf("Expected to pop %d, got %d, %v", want, v, err)
+		}
+	}
+
+	if err := <-pushed; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if q.Dropped() != 0 {
+		t.Errorf("Expected the blocking queue not to drop values, got %d", q.Dropped())
+	}
+
+	// A producer waiting for room leaves when its context is done, or when the queue is closed.
+	pushAll(t, q, 1, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go waitPush(ctx, q, 3, pushed)
+	go waitPush(context.Background(), q, 4, pushed)
+
+	if !waiting("waitPush", 2) {
+		t.Fatal("Expected producers to wait for room in the full queue")
+	}
+
+	cancel()
+
+	if err := <-pushed; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to be %v, got %v", context.Canceled, err)
+	}
+
+	if values := drain(t, q); !slices.Equal(values, []int{1, 2}) {
+		t.Errorf("Expected the queue to keep values 1 and 2, got %v", values)
+	}
+
+	select {
+	case err := <-pushed:
+		if !errors.Is(err, ErrQueueClosed) {
+			t.Errorf("Expected error to be %v, got %v", ErrQueueClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected Close to wake up the waiting producer")
+	}
+}
+
+func TestDropNewestQueue(t *testing.T) {
+	q := NewDropNewestQueue[int](3)
+
+	pushAll(t, q, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	if values := drain(t, q); !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("Expected the oldest values 1, 2 and 3 to be kept, got %v", values)
+	}
+
+	if q.Dropped() != 7 {
+		t.Errorf("Expected 7 values to be dropped, got %d", q.Dropped())
+	}
+}
+
+func TestDropOldestQueue(t *testing.T) {
+	q := NewDropOldestQueue[int](3)
+
+	pushAll(t, q, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	if values := drain(t, q); !slices.Equal(values, []int{8, 9, 10}) {
+		t.Errorf("Expected the newest values 8, 9 and 10 to be kept, got %v", values)
+	}
+
+	if q.Dropped() != 7 {
+		t.Errorf("Expected 7 values to be dropped, got %d", q.Dropped())
+	}
+
+	// The ring wraps around many times, and keeps the order.
+	q = NewDropOldestQueue[int](3)
+	pushAll(t, q, 1, 2)
+
+	if v, err := q.Pop(context.Background()); err != nil || v != 1 {
+		t.Fatalf("Expected to pop 1, got %d, %v", v, err)
+	}
+
+	pushAll(t, q, 3, 4, 5, 6)
+
+	if values := drain(t, q); !slices.Equal(values, []int{4, 5, 6}) {
+		t.Errorf("Expected values 4, 5 and 6, got %v", values)
+	}
+}
+
+func TestSamplingQueue(t *testing.T) {
+	q := NewSamplingQueue[int](3, 2)
+
+	// 1, 2 and 3 fill the queue. Then every second value gets in: 5, 7 and 9 evict the oldest values,
+	// 4, 6 and 8 are dropped.
+	pushAll(t, q, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	if values := drain(t, q); !slices.Equal(values, []int{5, 7, 9}) {
+		t.Errorf("Expected sampled values 5, 7 and 9, got %v", values)
+	}
+
+	if q.Dropped() != 6 {
+		t.Errorf("Expected 6 values to be dropped, got %d", q.Dropped())
+	}
+
+	// Without pressure nothing is sampled.
+	q = NewSamplingQueue[int](3, 2)
+
+	for v := 1; v <= 6; v++ {
+		pushAll(t, q, v)
+
+		if got, err := q.Pop(context.Background()); err != nil || got != v {
+			t.Fatalf("Expected to pop %d, got %d, %v", v, got, err)
+		}
+	}
+
+	if q.Dropped() != 0 {
+		t.Errorf("Expected nothing to be dropped while the queue has room, got %d", q.Dropped())
+	}
+}
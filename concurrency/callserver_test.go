@@ -0,0 +1,223 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// NumberIterator in TestChanOfChan is a special case of a common pattern: a single goroutine owns the state,
+// and other goroutines call it by sending a request together with a channel for the reply.
+// The owner processes requests one at a time, so the state needs no locks.
+// With generics the pattern becomes a reusable type. For example, NumberIterator could be written as
+//
+//	counter := 0
+//	srv := NewCallServer(func(struct{}) int {
+//		counter++
+//		return counter
+//	})
+//
+// Let's try to implement Call and Close:
+//   - Call sends the request with a reply channel to the owner goroutine and waits for the response.
+//     If ctx is done before the response arrives, Call returns ctx.Err(). The owner must not get stuck
+//     sending the reply to a caller that already left, think about the capacity of the reply channel.
+//   - Close stops the server. Calls that were not accepted by the owner yet, and all calls after Close,
+//     return ErrServerClosed. Close can be called many times.
+
+// ErrServerClosed is returned by Call after the server is closed.
+var ErrServerClosed = errors.New("server closed")
+
+// request is a call sent to the owner goroutine.
+type request[Req, Resp any] struct {
+	req   Req
+	reply chan Resp
+}
+
+// CallServer serializes calls to a handler in a single owner goroutine.
+type CallServer[Req, Resp any] struct {
+	handle   func(Req) Resp
+	requests chan request[Req, Resp]
+	done     chan struct{}
+}
+
+// NewCallServer creates a server that handles calls with handle, the server starts handling calls after Run is called.
+func NewCallServer[Req, Resp any](handle func(Req) Resp) *CallServer[Req, Resp] {
+	return &CallServer[Req, Resp]{
+		handle:   handle,
+		requests: make(chan request[Req, Resp]),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run handles calls until the server is closed.
+func (s *CallServer[Req, Resp]) Run() {
+	for {
+		select {
+		case r := <-s.requests:
+			r.reply <- s.handle(r.req)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Call sends the request to the owner goroutine and returns its response.
+func (s *CallServer[Req, Resp]) Call(ctx context.Context, req Req) (Resp, error) {
+	var resp Resp
+
+	return resp, nil
+}
+
+// Close stops the server.
+func (s *CallServer[Req, Resp]) Close() {
+}
+
+func TestCallServerConcurrentCallers(t *testing.T) {
+	const callers = 100
+
+	total := 0
+	srv := NewCallServer(func(n int) int {
+		total += n
+		return total
+	})
+
+	go srv.Run()
+	defer srv.Close()
+
+	results := make(chan int, callers)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := srv.Call(context.Background(), 1)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			results <- resp
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+
+	got := make([]int, 0, callers)
+	for r := range results {
+		got = append(got, r)
+	}
+
+	slices.Sort(got)
+
+	for i, r := range got {
+		if r != i+1 {
+			t.Fatalf("Expected every caller to get a unique running total from 1 to %d, got %v", callers, got)
+		}
+	}
+}
+
+// Tests below hold the owner goroutine inside the handler with testsync steps, so they know exactly
+// what the server is busy with, and check that a caller is queued by finding it blocked in Call.
+
+func TestCallServerCancelMidCall(t *testing.T) {
+	steps := testsync.NewStepController(t)
+	srv := NewCallServer(func(s string) string {
+		if s == "slow" {
+			steps.Step("slow")
+		}
+
+		return "re: " + s
+	})
+
+	go srv.Run()
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := srv.Call(ctx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	steps.Release("slow")
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, err := srv.Call(ctx, "fast")
+	if err != nil {
+		t.Fatalf("Expected server to keep working after a canceled call, got %v", err)
+	}
+
+	if resp != "re: fast" {
+		t.Errorf("Expected response to be 're: fast', got '%s'", resp)
+	}
+}
+
+func TestCallServerShutdown(t *testing.T) {
+	steps := testsync.NewStepController(t)
+	srv := NewCallServer(func(s string) string {
+		if s == "busy" {
+			steps.Step("busy")
+		}
+
+		return "re: " + s
+	})
+
+	go srv.Run()
+
+	busy := make(chan error, 1)
+
+	go func() {
+		_, err := srv.Call(context.Background(), "busy")
+		busy <- err
+	}()
+
+	select {
+	case <-steps.Reached("busy"):
+	case err := <-busy:
+		t.Fatalf("Expected the call to be handled by the server, got %v", err)
+	}
+
+	pending := make(chan error, 1)
+
+	go func() {
+		_, err := srv.Call(context.Background(), "pending")
+		pending <- err
+	}()
+
+	// Both callers wait in Call: the busy one for the response, the pending one for the server.
+	if !testsync.WaitUntil(func() bool { return testsync.Blocked("(*CallServer[...]).Call") == 2 }, time.Second) {
+		t.Fatal("Expected the pending call to wait for the busy server")
+	}
+
+	srv.Close()
+	srv.Close()
+
+	select {
+	case err := <-pending:
+		if !errors.Is(err, ErrServerClosed) {
+			t.Errorf("Expected pending call to be rejected with %v, got %v", ErrServerClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected pending call to be rejected on shutdown")
+	}
+
+	steps.Release("busy")
+
+	// The busy call was accepted before shutdown, it could get either the response or the error.
+	if err := <-busy; err != nil && !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Expected busy call to finish or to be rejected with %v, got %v", ErrServerClosed, err)
+	}
+
+	if _, err := srv.Call(context.Background(), "late"); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Expected call after shutdown to be rejected with %v, got %v", ErrServerClosed, err)
+	}
+}
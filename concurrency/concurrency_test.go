@@ -8,6 +8,9 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+	"github.com/ksysoev/go-workshops/testutil"
 )
 
 // Go follows a model of concurrency called the fork-join model.
@@ -35,11 +38,20 @@ func TestForkJoin(t *testing.T) {
 
 	t.Log("Parent goroutine")
 
+	if !testsync.WaitUntil(func() bool { return isClosed(done) }, testsync.DefaultTimeout) {
+		t.Fatal("Expected the child goroutine to close the done channel")
+	}
+
+	t.Log("Child goroutine done")
+}
+
+// isClosed reports whether the channel is closed without blocking.
+func isClosed(ch <-chan struct{}) bool {
 	select {
-	case <-done:
-		t.Log("Child goroutine done")
-	case <-time.After(time.Millisecond):
-		t.Errorf("Expected to done channel to be closed")
+	case <-ch:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -60,12 +72,12 @@ func TestParrentControl(t *testing.T) {
 	t.Log("Parent cancel child goroutine execution")
 	cancel()
 
-	select {
-	case <-done:
-		t.Log("Child goroutine done")
-	case <-time.After(time.Millisecond):
-		t.Errorf("Expected to done channel to be closed")
+	// The child sleeps for 10 seconds unless it's canceled, so it can't close done in time without the context.
+	if !testsync.WaitUntil(func() bool { return isClosed(done) }, testsync.DefaultTimeout) {
+		t.Fatal("Expected the canceled child goroutine to close the done channel")
 	}
+
+	t.Log("Child goroutine done")
 }
 
 // Data races and Race conditions, what is the difference?
@@ -87,6 +99,7 @@ func TestRaceCondition(t *testing.T) {
 // When something is considered atomic, or to have the property of atomicity,
 // this means that within the context that it is operating, it is indivisible, or uninterruptible.
 // Most statements in Go are not atomic. For example, incrementing a variable is not atomic.
+// After fixing this test, try three different ways to build a safe counter in atomicity_test.go.
 func TestAtomacity(t *testing.T) {
 	counter := 0
 	wg := sync.WaitGroup{}
@@ -130,12 +143,16 @@ func TestDeadlock(t *testing.T) {
 	go EatPasta(t, "Plato", results, fork, spoon)
 	go EatPasta(t, "Socrates", results, spoon, fork)
 
+	// If philosophers get stuck, the test fails with a report of blocked goroutines.
+	// Look at the locations of goroutines blocked on sync.Mutex.Lock to find the lock cycle.
+	deadlock := testutil.WithDeadlockReport(t, 1*time.Second)
+
 	for i := 0; i < 2; i++ {
 		select {
 		case res := <-results:
 			t.Log(res)
-		case <-time.After(1 * time.Second):
-			t.Fatal("Expected to receive result")
+		case <-deadlock:
+			t.FailNow()
 		}
 	}
 }
@@ -192,6 +209,9 @@ func NewNumberIterator(ctx context.Context) *NumberIterator {
 	}
 }
 
+// Next requests the next number: it sends a response channel to Run and receives the number from it.
+// Let's wait for both the request and the response with ctx using Send and Recv from sendrecv_test.go.
+// Give the response channel room for the number, so Run doesn't get stuck when the caller leaves before the response.
 func (ni *NumberIterator) Next(ctx context.Context) (int, error) {
 	return 0, nil
 }
@@ -212,6 +232,9 @@ func (ni *NumberIterator) Run() {
 	}
 }
 
+// A generic version of this pattern is in callserver_test.go.
+// Since Go 1.23 there is a simpler way to hand out values on demand, see the pull iterators exercise in ../iterators.
+
 func TestChanOfChan(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -290,6 +313,7 @@ func TestNilChannels(t *testing.T) {
 
 // Defalut case in select statement is used to handle non-blocking channel operations.
 // If there are no other cases ready to be executed, the default case will be executed.
+// Send and Recv use it to check whether ctx is done before they touch the channel.
 func TestDefaultCase(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -314,41 +338,18 @@ func TestDefaultCase(t *testing.T) {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	if num != 2 {
+	// The canceled call didn't take a number.
+	if num != 1 {
 		t.Fatalf("Expected number to be 1, got %d", num)
 	}
 }
 
 // Unbounded concurrency can lead to resource exhaustion and poor performance due to contention.
-// To limit the number of goroutines that can run concurrently, we can use a semaphore.
-// A semaphore is a synchronization primitive that limits the number of concurrent operations.
-// It is used to control access to a shared resource.
-// We can use a buffered channel to implement a semaphore.
-func TestSemaphoreWithChannels(t *testing.T) {
-	c := atomic.Int32{}
-	wg := sync.WaitGroup{}
-
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			val := c.Add(1)
-			defer c.Add(-1)
-
-			time.Sleep(1 * time.Millisecond)
-
-			if val > 3 {
-				t.Error("Expected to have only 3 goroutines running concurrently")
-			}
-		}()
-	}
-
-	wg.Wait()
-}
+// Semaphore exercises are in semaphore_test.go.
 
 // Sync.Pool is a synchronization primitive that is used to cache and reuse objects.
 // It is useful for reducing memory allocations and improving performance.
+// A buffer pool for an encoding hot path is in pool_test.go.
 
 func TestSyncPool(t *testing.T) {
 	pool := sync.Pool{
@@ -375,22 +376,34 @@ func TestSyncPool(t *testing.T) {
 // Sync.Once is a synchronization primitive that guarantees that a function is executed only once.
 // It is useful for initializing resources that are expensive to create or need to be shared across multiple goroutines.
 // The Do method takes a function as an argument and ensures that the function is executed only once.
+//
+// RateLimiter below never refills its bucket, because nobody starts bucketRefiller.
+// Let's try to start it lazily on the first call of Allow, exactly once, no matter how many goroutines call Allow.
+// More exercises on sync.Once and its helpers are in once_test.go.
 
 type RateLimiter struct {
 	capacity int32
 	counter  *atomic.Int32
 	ctx      context.Context
 	cancel   context.CancelFunc
+
+	// refills signals bucketRefiller to empty the bucket, it's fed by the ticker every millisecond,
+	// tests replace it to refill the bucket exactly when they need.
+	ticker  *time.Ticker
+	refills <-chan time.Time
 }
 
 func NewRateLimiter(ctx context.Context, capacity int32) *RateLimiter {
 	ctx, cancel := context.WithCancel(ctx)
+	ticker := time.NewTicker(1 * time.Millisecond)
 
 	return &RateLimiter{
 		capacity: capacity,
 		counter:  &atomic.Int32{},
 		ctx:      ctx,
 		cancel:   cancel,
+		ticker:   ticker,
+		refills:  ticker.C,
 	}
 }
 
@@ -400,24 +413,26 @@ func (r *RateLimiter) Allow() bool {
 
 func (r *RateLimiter) Close() {
 	r.cancel()
+	r.ticker.Stop()
 }
 
 func (r *RateLimiter) bucketRefiller() {
-	t := time.NewTicker(1 * time.Millisecond)
-	defer t.Stop()
-
 	for {
 		select {
 		case <-r.ctx.Done():
 			return
-		case <-t.C:
+		case <-r.refills:
 			r.counter.Store(0)
 		}
 	}
 }
 
 func TestSyncOnce(t *testing.T) {
+	refills := make(chan time.Time)
+
 	rl := NewRateLimiter(context.Background(), 3)
+	rl.refills = refills
+
 	defer rl.Close()
 
 	for i := 0; i < 3; i++ {
@@ -430,9 +445,20 @@ func TestSyncOnce(t *testing.T) {
 		t.Error("Expected to deny access")
 	}
 
-	time.Sleep(1 * time.Millisecond)
+	// The send succeeds only when bucketRefiller is running, Allow should have started it.
+	refilled := testsync.WaitUntil(func() bool {
+		select {
+		case refills <- time.Now():
+			return true
+		default:
+			return false
+		}
+	}, testsync.DefaultTimeout)
+	if !refilled {
+		t.Fatal("Expected Allow to start the bucket refiller")
+	}
 
-	if !rl.Allow() {
-		t.Error("Expected to allow access")
+	if !testsync.WaitUntil(rl.Allow, testsync.DefaultTimeout) {
+		t.Error("Expected to allow access after the bucket is refilled")
 	}
 }
@@ -0,0 +1,88 @@
+//go:build solutiontests
+
+package concurrency
+
+import (
+	"testing"
+	"time"
+)
+
+// Hidden tests are built only with the solutiontests tag, workshop verify -strict runs them.
+// TestFanInFanOut only counts results, so a Consumer that sends the same number in a loop passes it.
+// These checks make sure the work really flows from Producer through consumers.
+
+func TestFanInFanOutHidden(t *testing.T) {
+	t.Run("producer sends n distinct numbers", func(t *testing.T) {
+		work := make(chan int)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			Producer(5, work)
+		}()
+
+		seen := make(map[int]bool)
+
+	loop:
+		for {
+			select {
+			case n, ok := <-work:
+				if !ok {
+					<-done
+					break loop
+				}
+
+				if seen[n] {
+					t.Errorf("Expected distinct numbers, got %d twice", n)
+				}
+
+				seen[n] = true
+			case <-done:
+				break loop
+			case <-time.After(time.Second):
+				t.Fatal("Expected Producer to return after sending all numbers")
+			}
+		}
+
+		if len(seen) != 5 {
+			t.Errorf("Expected 5 numbers, got %d", len(seen))
+		}
+	})
+
+	t.Run("consumer sends a result per number", func(t *testing.T) {
+		work := make(chan int)
+		results := make(chan int, 10)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			Consumer(work, results)
+		}()
+
+		select {
+		case res := <-results:
+			t.Fatalf("Expected no results without work, got %d", res)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		for i := 0; i < 3; i++ {
+			select {
+			case work <- i:
+			case <-time.After(time.Second):
+				t.Fatal("Expected Consumer to receive work")
+			}
+		}
+
+		close(work)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected Consumer to return when the work channel is closed")
+		}
+
+		if len(results) != 3 {
+			t.Errorf("Expected 3 results for 3 numbers, got %d", len(results))
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package concurrency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// Starting a goroutine per item is fine for a handful of items, but not for a directory with a million files:
+// every goroutine opens a file, and the process runs out of file descriptors, memory, or disk bandwidth.
+// The number of concurrent operations should be bounded.
+//
+// There are two common ways to bound concurrency:
+//   - A fixed pool of N workers reading paths from a channel.
+//   - A goroutine per item with a semaphore of size N, see semaphore_test.go.
+//
+// Either way, results are collected from many goroutines, so the map must be guarded by a mutex,
+// or owned by a single goroutine that receives results from a channel.
+// Don't forget about errors: if any file fails, the whole operation fails.
+
+// filesDir is a directory with fixture files for the exercise.
+const filesDir = "testdata/files"
+
+// HashFile returns hex encoded SHA-256 of the file content.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFilesSequential hashes all files in root one by one.
+// The result maps paths relative to root, with forward slashes, to hashes. It's the baseline for HashFiles.
+func HashFilesSequential(root string, hash func(path string) (string, error)) (map[string]string, error) {
+	hashes := make(map[string]string)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		sum, err := hash(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		hashes[filepath.ToSlash(rel)] = sum
+
+		return nil
+	})
+
+	return hashes, err
+}
+
+// HashFiles hashes all files in root concurrently, using at most workers goroutines for hashing.
+// It returns the same result as HashFilesSequential.
+// Let's try to fix it: bound the concurrency and return the first error.
+func HashFiles(root string, workers int, hash func(path string) (string, error)) (map[string]string, error) {
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	hashes := make(map[string]string)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sum, _ := hash(path)
+
+			mu.Lock()
+			hashes[filepath.ToSlash(rel)] = sum
+			mu.Unlock()
+		}()
+
+		return nil
+	})
+
+	wg.Wait()
+
+	return hashes, err
+}
+
+// highWaterMark wraps hash and records the maximum number of concurrent calls.
+// The first limit+1 calls wait for each other at a barrier, so calls that are allowed to run at the same time
+// really do overlap, and one call over the limit is always caught.
+type highWaterMark struct {
+	limit    int32
+	overlap  *testsync.Barrier
+	calls    atomic.Int32
+	inFlight atomic.Int32
+	max      atomic.Int32
+}
+
+func newHighWaterMark(limit int) *highWaterMark {
+	return &highWaterMark{limit: int32(limit), overlap: testsync.NewBarrier(limit + 1)}
+}
+
+func (h *highWaterMark) wrap(hash func(path string) (string, error)) func(path string) (string, error) {
+	return func(path string) (string, error) {
+		n := h.inFlight.Add(1)
+		defer h.inFlight.Add(-1)
+
+		for {
+			m := h.max.Load()
+			if n <= m || h.max.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		if h.calls.Add(1) <= h.limit+1 {
+			h.overlap.Wait(50 * time.Millisecond)
+		}
+
+		return hash(path)
+	}
+}
+
+func TestHashFilesMatchesSequential(t *testing.T) {
+	expected, err := HashFilesSequential(filesDir, HashFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := HashFiles(filesDir, 3, HashFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !maps.Equal(got, expected) {
+		t.Errorf("Expected hashes to match the sequential baseline:\n%v\ngot:\n%v", expected, got)
+	}
+}
+
+func TestHashFilesConcurrencyBound(t *testing.T) {
+	const workers = 3
+
+	hwm := newHighWaterMark(workers)
+
+	if _, err := HashFiles(filesDir, workers, hwm.wrap(HashFile)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if m := hwm.max.Load(); m > workers {
+		t.Errorf("Expected at most %d files to be hashed concurrently, got %d", workers, m)
+	} else if m < 2 {
+		t.Errorf("Expected files to be hashed concurrently, got at most %d at a time", m)
+	}
+}
+
+func TestHashFilesError(t *testing.T) {
+	errBroken := errors.New("broken disk")
+
+	hash := func(path string) (string, error) {
+		if filepath.Base(path) == "data.csv" {
+			return "", errBroken
+		}
+
+		return HashFile(path)
+	}
+
+	if _, err := HashFiles(filesDir, 3, hash); !errors.Is(err, errBroken) {
+		t.Errorf("Expected error to be %v, got %v", errBroken, err)
+	}
+}
@@ -0,0 +1,147 @@
+package concurrency
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Atomics are not limited to counters and flags. sync/atomic can swap a pointer to a whole struct,
+// it's a common way to hot-reload configuration: readers are on the hot path and never block,
+// while a rare writer prepares a new version and publishes it with a single atomic store.
+//
+// The trick is that a published config is never modified. Writer copies the current config, changes the copy,
+// and swaps the pointer. Readers that loaded the old pointer keep working with a consistent old snapshot.
+//
+// Before Go 1.19 this was done with atomic.Value, which stores any value and requires a type assertion on every load.
+// atomic.Pointer[T] does the same, but it's type safe.
+
+// Config is the service configuration, it's reloaded while the service is running.
+type Config struct {
+	Version  int
+	Timeout  time.Duration
+	Features []string
+}
+
+// Clone returns a deep copy of the config.
+// Hint: copying the struct copies the slice header only, both copies share the same backing array,
+// and append to a copy could overwrite elements visible through the original.
+func (c *Config) Clone() *Config {
+	return c
+}
+
+// ConfigStore keeps the current config.
+// It's broken: Update mutates the shared config in place while readers access it.
+// Let's try to fix it with atomic.Pointer[Config]:
+//   - Load should atomically load the pointer, readers must not take any locks.
+//   - Update should apply fn to a clone of the current config and atomically publish the clone.
+//   - Concurrent calls of Update must not lose updates, try CompareAndSwap in a loop.
+type ConfigStore struct {
+	cfg *Config
+}
+
+// NewConfigStore creates a store with the initial config.
+func NewConfigStore(cfg *Config) *ConfigStore {
+	return &ConfigStore{cfg: cfg}
+}
+
+// Load returns the current config, it must not be modified by the caller.
+func (s *ConfigStore) Load() *Config {
+	return s.cfg
+}
+
+// Update changes the config with fn.
+func (s *ConfigStore) Update(fn func(cfg *Config)) {
+	fn(s.cfg)
+}
+
+func newTestConfig() *Config {
+	return &Config{
+		Version:  1,
+		Timeout:  time.Second,
+		Features: append(make([]string, 0, 8), "v1"),
+	}
+}
+
+func TestConfigSnapshot(t *testing.T) {
+	store := NewConfigStore(newTestConfig())
+	old := store.Load()
+
+	store.Update(func(cfg *Config) {
+		cfg.Version++
+		cfg.Timeout = 2 * time.Second
+		cfg.Features = append(cfg.Features, "v2")
+	})
+
+	if old.Version != 1 || old.Timeout != time.Second || len(old.Features) != 1 {
+		t.Errorf("Expected loaded config not to change after update, got %+v", old)
+	}
+
+	cfg := store.Load()
+	if cfg.Version != 2 || cfg.Timeout != 2*time.Second || !slices.Equal(cfg.Features, []string{"v1", "v2"}) {
+		t.Errorf("Expected new config after update, got %+v", cfg)
+	}
+}
+
+func TestConfigHotReload(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	const (
+		writers = 4
+		updates = 100
+	)
+
+	store := NewConfigStore(newTestConfig())
+	stop := make(chan struct{})
+	readers := sync.WaitGroup{}
+
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				// Every version of the config has a feature named after it, a reader must see a consistent snapshot.
+				cfg := store.Load()
+				if !slices.Contains(cfg.Features, "v"+strconv.Itoa(cfg.Version)) {
+					t.Errorf("Expected config version %d to have feature v%d", cfg.Version, cfg.Version)
+					return
+				}
+			}
+		}()
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < updates; j++ {
+				store.Update(func(cfg *Config) {
+					cfg.Version++
+					cfg.Features = append(cfg.Features, "v"+strconv.Itoa(cfg.Version))
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	if v := store.Load().Version; v != 1+writers*updates {
+		t.Errorf("Expected version to be %d after all updates, got %d", 1+writers*updates, v)
+	}
+}
@@ -0,0 +1,116 @@
+package concurrency
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Livelock is a situation where goroutines are not blocked, they are actively running and reacting to each other,
+// but none of them makes progress. From the outside it looks like a busy program that never gets anything done.
+// Unlike a deadlock, the Go runtime can't detect it, and goroutine dumps show nothing suspicious.
+//
+// The classic example is a very polite couple sharing a single spoon.
+// Whoever holds the spoon passes it to the spouse if the spouse is hungry.
+// Both of them are hungry, so the spoon goes back and forth forever.
+
+// Spoon is a resource that is owned by one diner at a time.
+type Spoon struct {
+	owner  atomic.Pointer[Diner]
+	passes atomic.Int64
+}
+
+func NewSpoon(owner *Diner) *Spoon {
+	s := &Spoon{}
+	s.owner.Store(owner)
+
+	return s
+}
+
+// Owner returns the diner who holds the spoon.
+func (s *Spoon) Owner() *Diner {
+	return s.owner.Load()
+}
+
+// Pass gives the spoon to another diner.
+func (s *Spoon) Pass(to *Diner) {
+	s.passes.Add(1)
+	s.owner.Store(to)
+}
+
+// Passes returns how many times the spoon changed hands.
+func (s *Spoon) Passes() int64 {
+	return s.passes.Load()
+}
+
+// Diner eats only with the spoon.
+type Diner struct {
+	Name   string
+	hungry atomic.Bool
+}
+
+func NewDiner(name string) *Diner {
+	d := &Diner{Name: name}
+	d.hungry.Store(true)
+
+	return d
+}
+
+func (d *Diner) Hungry() bool {
+	return d.hungry.Load()
+}
+
+// EatWith waits for the spoon and eats, it gives up when the context is done.
+// Let's try to fix the livelock, there are two common approaches:
+// 1. Randomized backoff: don't yield every time, flip a coin (math/rand) or wait for a random time before yielding.
+// 2. Ordering: introduce a priority between diners, so only one of them yields.
+func (d *Diner) EatWith(ctx context.Context, spoon *Spoon, spouse *Diner) {
+	for d.Hungry() && ctx.Err() == nil {
+		if spoon.Owner() != d {
+			runtime.Gosched()
+			continue
+		}
+
+		if spouse.Hungry() {
+			spoon.Pass(spouse)
+			continue
+		}
+
+		d.hungry.Store(false)
+		spoon.Pass(spouse)
+	}
+}
+
+func TestLivelock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	husband := NewDiner("Husband")
+	wife := NewDiner("Wife")
+	spoon := NewSpoon(husband)
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		husband.EatWith(ctx, spoon, wife)
+	}()
+
+	go func() {
+		defer wg.Done()
+		wife.EatWith(ctx, spoon, husband)
+	}()
+
+	wg.Wait()
+
+	// Livelock is detected by lack of progress: goroutines were busy passing the spoon, but nobody ate.
+	for _, d := range []*Diner{husband, wife} {
+		if d.Hungry() {
+			t.Errorf("Expected %s to eat, but the spoon was passed %d times without progress", d.Name, spoon.Passes())
+		}
+	}
+}
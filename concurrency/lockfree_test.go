@@ -0,0 +1,124 @@
+package concurrency
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Advanced: lock-free data structures.
+//
+// A lock-free structure never blocks goroutines on a mutex, it updates shared state with
+// compare-and-swap (CAS) operations instead. CAS changes a value only if it's still equal to the
+// expected one, a goroutine that lost the race reloads the value and tries again:
+//
+//	for {
+//		old := p.Load()
+//		if p.CompareAndSwap(old, newValue(old)) {
+//			return
+//		}
+//	}
+//
+// Treiber stack is the classic example: the stack is a pointer to the top node, Push and Pop swap it.
+// The Stack below is fine for a single goroutine, but concurrent Push and Pop lose nodes.
+// Let's make it lock-free with atomic.Pointer from sync/atomic package, without mutexes.
+//
+// Because the garbage collector never reuses a node while it's referenced, this stack doesn't suffer
+// from the ABA problem that makes lock-free structures in C much harder.
+
+type stackNode struct {
+	value int
+	next  *stackNode
+}
+
+// Stack is a lock-free LIFO stack that is safe for concurrent use.
+type Stack struct {
+	head *stackNode
+}
+
+// Push adds the value on top of the stack.
+func (s *Stack) Push(v int) {
+	s.head = &stackNode{value: v, next: s.head}
+}
+
+// Pop removes and returns the value from the top of the stack, ok is false if the stack is empty.
+func (s *Stack) Pop() (v int, ok bool) {
+	if s.head == nil {
+		return 0, false
+	}
+
+	v, s.head = s.head.value, s.head.next
+
+	return v, true
+}
+
+func TestLockFreeStack(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	const (
+		goroutines = 8
+		perWorker  = 1000
+	)
+
+	var (
+		s   Stack
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		got []int
+	)
+
+	for g := range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var popped []int
+
+			for i := range perWorker {
+				s.Push(g*perWorker + i)
+
+				if i%2 == 1 {
+					if v, ok := s.Pop(); ok {
+						popped = append(popped, v)
+					}
+				}
+			}
+
+			mu.Lock()
+			got = append(got, popped...)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	for v, ok := s.Pop(); ok; v, ok = s.Pop() {
+		got = append(got, v)
+	}
+
+	slices.Sort(got)
+
+	if len(got) != goroutines*perWorker {
+		t.Fatalf("Expected %d values to be pushed and popped, got %d", goroutines*perWorker, len(got))
+	}
+
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("Expected every value to be popped once, value %d is missing or duplicated", i)
+		}
+	}
+}
+
+func BenchmarkLockFreeStack(b *testing.B) {
+	var s Stack
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Push(i)
+			s.Pop()
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Deadlock requires a cycle of goroutines, each holding a lock and waiting for the next one.
+// Instead of detecting cycles, we can make them impossible. There are two classic approaches:
+//
+// 1. Lock hierarchy (resource ordering): give every lock an ID and always acquire locks in ascending order.
+//    Nobody can hold a lock with a higher ID while waiting for a lock with a lower one, so there is no cycle.
+// 2. Arbiter: a single goroutine (a waiter) decides who may pick up the forks.
+//    A philosopher asks the waiter for permission and picks up forks only when both of them are free.
+//
+// Both solutions should survive hundreds of meals under the race detector.
+
+// Fork is a lock with an ID, IDs define the lock hierarchy.
+type Fork struct {
+	sync.Mutex
+	ID   int
+	uses int
+}
+
+// pickUp simulates the time it takes to pick up a fork, it makes deadlocks much more likely.
+func pickUp() {
+	time.Sleep(time.Microsecond)
+}
+
+// EatOrdered picks up both forks and calls eat.
+// Let's fix it by acquiring the fork with the lower ID first.
+func EatOrdered(left, right *Fork, eat func()) {
+	left.Lock()
+	defer left.Unlock()
+
+	pickUp()
+
+	right.Lock()
+	defer right.Unlock()
+
+	eat()
+}
+
+// Waiter is an arbiter that gives philosophers permission to pick up forks.
+type Waiter struct {
+	requests chan forkRequest
+	releases chan forkRequest
+}
+
+// forkRequest is a request for both forks, the waiter closes granted when the philosopher may eat.
+type forkRequest struct {
+	left, right *Fork
+	granted     chan struct{}
+}
+
+func NewWaiter(ctx context.Context) *Waiter {
+	w := &Waiter{
+		requests: make(chan forkRequest),
+		releases: make(chan forkRequest),
+	}
+
+	go w.serve(ctx)
+
+	return w
+}
+
+// serve is the only goroutine that decides who may pick up forks.
+// It should keep track of forks in use, grant requests when both forks are free, and queue them otherwise.
+func (w *Waiter) serve(ctx context.Context) {
+	<-ctx.Done()
+}
+
+// Eat asks the waiter for permission, picks up both forks, and calls eat.
+// Let's fix it by sending a request to the waiter and waiting until it's granted,
+// don't forget to tell the waiter when forks are released.
+func (w *Waiter) Eat(left, right *Fork, eat func()) {
+	left.Lock()
+	defer left.Unlock()
+
+	pickUp()
+
+	right.Lock()
+	defer right.Unlock()
+
+	eat()
+}
+
+// dine seats philosophers at a round table and lets each of them eat the given number of meals.
+func dine(t *testing.T, philosophers, meals int, eat func(left, right *Fork, meal func())) {
+	t.Helper()
+
+	forks := make([]*Fork, philosophers)
+	for i := range forks {
+		forks[i] = &Fork{ID: i}
+	}
+
+	done := make(chan struct{})
+	deadlock := testutil.WithDeadlockReport(t, 5*time.Second)
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < philosophers; i++ {
+		left, right := forks[i], forks[(i+1)%philosophers]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < meals; j++ {
+				eat(left, right, func() {
+					left.uses++
+					right.uses++
+				})
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-deadlock:
+		t.FailNow()
+	}
+
+	for _, f := range forks {
+		if f.uses != 2*meals {
+			t.Errorf("Expected fork %d to be used %d times, got %d", f.ID, 2*meals, f.uses)
+		}
+	}
+}
+
+func TestLockHierarchy(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	dine(t, 5, 200, EatOrdered)
+}
+
+func TestArbiter(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dine(t, 5, 200, NewWaiter(ctx).Eat)
+}
@@ -0,0 +1,164 @@
+package concurrency
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// The Go memory model https://go.dev/ref/mem specifies the conditions under which reads of a variable in one goroutine
+// can be guaranteed to observe values produced by writes to the same variable in a different goroutine.
+// The main concept of the memory model is "happens before" relation.
+// If a write happens before a read, the read is guaranteed to observe the write.
+// If there is no happens before relation between them, the program has a data race, and its behavior is undefined.
+//
+// Happens before relation is created only by synchronization operations:
+// - A send on a channel happens before the corresponding receive from that channel completes.
+// - The closing of a channel happens before a receive that returns because the channel is closed.
+// - For sync.Mutex, n-th call of Unlock happens before m-th call of Lock returns, for any n < m.
+// - All atomic operations behave like sequentially consistent operations.
+//
+// That's why TestRaceCondition is broken, even if it passes sometimes on your machine.
+// Data races are silent, so all tests in this file should be run with the race detector:
+//
+//	go run ./cmd/workshop verify concurrency/memory-model
+
+// A common attempt to fix a data race is to add some sleep, so the other goroutine has time to finish.
+// Sleep doesn't create happens before relation, so the code below still has a data race.
+// Let's try to fix it without using time.Sleep.
+
+// IncrementAsync increments the value in a separate goroutine.
+func IncrementAsync(data *int) {
+	go func() {
+		*data++
+	}()
+
+	time.Sleep(1 * time.Millisecond)
+}
+
+func TestSleepIsNotSynchronization(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	data := 0
+	IncrementAsync(&data)
+
+	if data != 1 {
+		t.Errorf("Expected data to be incremented by 1, got %d", data)
+	}
+}
+
+// Visibility bug: one goroutine writes a message and sets a boolean flag, another one waits for the flag and reads the message.
+// Without synchronization there is no guarantee that the reader observes the flag at all,
+// or that it observes the message after it observes the flag. Compiler and CPU are free to reorder such writes.
+//
+// Publisher delivers a single message from one goroutine to another.
+type Publisher interface {
+	Publish(msg string)
+	Receive() (msg string, ok bool)
+}
+
+// All publishers below start with the same visibility bug: they use a plain boolean flag to signal that the message is ready.
+// Run the tests with -race and read the report, it points exactly to the flag and the message.
+// Let's try to fix the visibility bug in three different ways.
+// 1. ChannelPublisher should signal that the message is ready by closing the done channel.
+// 2. AtomicPublisher should use atomic.Bool for the flag.
+// 3. MutexPublisher should protect both fields with the mutex.
+
+// ChannelPublisher uses a channel to signal that the message is ready.
+type ChannelPublisher struct {
+	msg   string
+	ready bool
+	done  chan struct{}
+}
+
+func NewChannelPublisher() *ChannelPublisher {
+	return &ChannelPublisher{
+		done: make(chan struct{}),
+	}
+}
+
+func (p *ChannelPublisher) Publish(msg string) {
+	p.msg = msg
+	p.ready = true
+}
+
+func (p *ChannelPublisher) Receive() (string, bool) {
+	if !p.ready {
+		return "", false
+	}
+
+	return p.msg, true
+}
+
+// AtomicPublisher uses an atomic flag to signal that the message is ready.
+type AtomicPublisher struct {
+	msg   string
+	ready bool
+}
+
+func (p *AtomicPublisher) Publish(msg string) {
+	p.msg = msg
+	p.ready = true
+}
+
+func (p *AtomicPublisher) Receive() (string, bool) {
+	if !p.ready {
+		return "", false
+	}
+
+	return p.msg, true
+}
+
+// MutexPublisher uses a mutex to protect the message and the flag.
+type MutexPublisher struct {
+	mu    sync.Mutex
+	msg   string
+	ready bool
+}
+
+func (p *MutexPublisher) Publish(msg string) {
+	p.msg = msg
+	p.ready = true
+}
+
+func (p *MutexPublisher) Receive() (string, bool) {
+	if !p.ready {
+		return "", false
+	}
+
+	return p.msg, true
+}
+
+func testPublisher(t *testing.T, p Publisher) {
+	t.Helper()
+	testutil.RequireRaceDetector(t)
+
+	go p.Publish("hello")
+
+	deadline := time.Now().Add(1 * time.Second)
+	for time.Now().Before(deadline) {
+		if msg, ok := p.Receive(); ok {
+			if msg != "hello" {
+				t.Errorf("Expected to receive hello, got '%s'", msg)
+			}
+
+			return
+		}
+	}
+
+	t.Error("Expected to receive the message")
+}
+
+func TestPublisherChannel(t *testing.T) {
+	testPublisher(t, NewChannelPublisher())
+}
+
+func TestPublisherAtomic(t *testing.T) {
+	testPublisher(t, &AtomicPublisher{})
+}
+
+func TestPublisherMutex(t *testing.T) {
+	testPublisher(t, &MutexPublisher{})
+}
@@ -0,0 +1,240 @@
+package concurrency
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sync.Once runs a function exactly once, even if Do is called by many goroutines at the same time.
+// All callers of Do wait until the function returns, so after Do returns the result of the initialization is visible to them.
+// It's the idiomatic way to initialize something lazily: a connection, a parsed template, a compiled regexp.
+//
+// Go 1.21 added helpers on top of sync.Once:
+//   - sync.OnceFunc(f) returns a function that calls f only once.
+//   - sync.OnceValue(f) returns a function that calls f only once and returns its result on every call.
+//   - sync.OnceValues(f) is the same for functions that return a value and an error.
+//
+// All tests in this file call the code from many goroutines, run them with -race to see the bugs.
+
+// onceCallers is the number of goroutines that call the lazy initialization concurrently.
+const onceCallers = 100
+
+// callConcurrently calls fn from many goroutines at the same time.
+func callConcurrently(fn func()) {
+	start := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < onceCallers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			<-start
+			fn()
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+}
+
+// Client is an expensive client, e.g. a database connection pool.
+type Client struct {
+	ID int32
+}
+
+// Connector creates a client on the first use and shares it with all callers.
+// The nil check below is a classic check-then-act race: many goroutines see nil and create their own clients.
+// Let's try to fix it with sync.Once.
+type Connector struct {
+	connects atomic.Int32
+	client   *Client
+}
+
+func (c *Connector) connect() *Client {
+	id := c.connects.Add(1)
+	time.Sleep(1 * time.Millisecond)
+
+	return &Client{ID: id}
+}
+
+// Client returns the shared client, creating it on the first call.
+func (c *Connector) Client() *Client {
+	if c.client == nil {
+		c.client = c.connect()
+	}
+
+	return c.client
+}
+
+func TestOnceSingleton(t *testing.T) {
+	c := &Connector{}
+	clients := make(chan *Client, onceCallers)
+
+	callConcurrently(func() {
+		clients <- c.Client()
+	})
+	close(clients)
+
+	first := <-clients
+	for client := range clients {
+		if client != first {
+			t.Fatalf("Expected all callers to get the same client, got clients %d and %d", first.ID, client.ID)
+		}
+	}
+
+	if n := c.connects.Load(); n != 1 {
+		t.Errorf("Expected to connect exactly once, got %d connections", n)
+	}
+}
+
+// Closing a channel twice panics, so Close methods that close a channel are often called more than once by mistake:
+// once in the happy path, once in a deferred cleanup. Let's try to make Close idempotent with sync.OnceFunc.
+
+// Subscription delivers events until it's closed.
+type Subscription struct {
+	done  chan struct{}
+	close func()
+}
+
+// NewSubscription creates an open subscription.
+func NewSubscription() *Subscription {
+	s := &Subscription{done: make(chan struct{})}
+	s.close = func() { close(s.done) }
+
+	return s
+}
+
+// Close stops the subscription, it's safe to call it many times.
+func (s *Subscription) Close() {
+	s.close()
+}
+
+// Done returns a channel that is closed when the subscription is closed.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+func TestOnceFunc(t *testing.T) {
+	s := NewSubscription()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Expected Close to be idempotent, got panic: %v", r)
+		}
+	}()
+
+	s.Close()
+	s.Close()
+
+	select {
+	case <-s.Done():
+	default:
+		t.Error("Expected subscription to be closed")
+	}
+}
+
+// NewLazyConfig returns a function that loads the config on the first call and returns the same config on every call.
+// Loading is expensive, so it must happen only once. Let's try to implement it with sync.OnceValue.
+func NewLazyConfig(load func() *Config) func() *Config {
+	return load
+}
+
+func TestOnceValue(t *testing.T) {
+	loads := atomic.Int32{}
+
+	config := NewLazyConfig(func() *Config {
+		loads.Add(1)
+		time.Sleep(1 * time.Millisecond)
+
+		return newTestConfig()
+	})
+
+	configs := make(chan *Config, onceCallers)
+
+	callConcurrently(func() {
+		configs <- config()
+	})
+	close(configs)
+
+	first := <-configs
+	for cfg := range configs {
+		if cfg != first {
+			t.Fatal("Expected all callers to get the same config")
+		}
+	}
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected to load config exactly once, got %d loads", n)
+	}
+}
+
+// Initialization can fail. sync.Once doesn't know anything about errors, it runs the function once and forgets about it.
+// The code below returns the error only to the caller that happened to run the initialization,
+// all other callers get a nil connection and a nil error, and crash later far away from the real cause.
+// Let's try to fix it with sync.OnceValues, so every caller gets the same connection and the same error.
+
+// ErrConnectionRefused is returned when the database is not available.
+var ErrConnectionRefused = errors.New("connection refused")
+
+// Conn is a database connection.
+type Conn struct{}
+
+// Database dials the connection lazily on the first use.
+type Database struct {
+	dial func() (*Conn, error)
+	once sync.Once
+	conn *Conn
+}
+
+// NewDatabase creates a database that uses dial to connect.
+func NewDatabase(dial func() (*Conn, error)) *Database {
+	return &Database{dial: dial}
+}
+
+// Conn returns the connection, dialing it on the first call.
+func (d *Database) Conn() (*Conn, error) {
+	var err error
+
+	d.once.Do(func() {
+		d.conn, err = d.dial()
+	})
+
+	return d.conn, err
+}
+
+func TestOnceValuesError(t *testing.T) {
+	dials := atomic.Int32{}
+
+	db := NewDatabase(func() (*Conn, error) {
+		dials.Add(1)
+		time.Sleep(1 * time.Millisecond)
+
+		return nil, ErrConnectionRefused
+	})
+
+	errs := make(chan error, onceCallers)
+
+	callConcurrently(func() {
+		_, err := db.Conn()
+		errs <- err
+	})
+	close(errs)
+
+	for err := range errs {
+		if !errors.Is(err, ErrConnectionRefused) {
+			t.Fatalf("Expected every caller to get %v, got %v", ErrConnectionRefused, err)
+		}
+	}
+
+	if _, err := db.Conn(); !errors.Is(err, ErrConnectionRefused) {
+		t.Errorf("Expected later callers to get %v, got %v", ErrConnectionRefused, err)
+	}
+
+	if n := dials.Load(); n != 1 {
+		t.Errorf("Expected to dial exactly once, got %d dials", n)
+	}
+}
@@ -0,0 +1,244 @@
+package concurrency
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// sync.Pool is generational. Pooled objects are not kept forever:
+// every garbage collection moves objects from the pool to a victim cache, and the next one frees the victim cache.
+// An object that nobody took from the pool survives one GC and is gone after the second one.
+// So the pool is a cache of temporary objects that smooths allocation spikes, not a free list or a connection pool.
+//
+// Encoding is a typical hot path for a pool: every request needs a buffer, the buffer lives only until
+// the response is written, and allocating a new one every time creates a lot of garbage.
+// Let's compare allocations with and without the pool:
+//
+//	go test -run '^$' -bench Encode -benchmem ./concurrency
+
+// Event is a message encoded on the hot path.
+type Event struct {
+	ID   int
+	Name string
+	Tags []string
+}
+
+// writeEvent appends JSON representation of the event to the buffer without extra allocations.
+func writeEvent(buf *bytes.Buffer, e Event) {
+	buf.WriteString(`{"id":`)
+	buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(e.ID), 10))
+	buf.WriteString(`,"name":`)
+	buf.Write(strconv.AppendQuote(buf.AvailableBuffer(), e.Name))
+	buf.WriteString(`,"tags":[`)
+
+	for i, tag := range e.Tags {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.Write(strconv.AppendQuote(buf.AvailableBuffer(), tag))
+	}
+
+	buf.WriteString("]}")
+}
+
+// maxPooledBufferSize is the capacity of the largest buffer that is worth keeping in the pool.
+const maxPooledBufferSize = 64 << 10 // 64 KiB
+
+// BufferPool reuses buffers between encodings.
+// Let's try to implement it with sync.Pool:
+//   - Get should return an empty buffer, a buffer from the pool still contains data written by the previous user,
+//     so it must be reset before reuse. Pick one place for Reset, in Put or in Get, and always do it there.
+//   - Put should drop buffers larger than maxPooledBufferSize. One huge event would otherwise
+//     grow a pooled buffer, and the pool would keep that memory alive for all the small events.
+type BufferPool struct {
+	pool sync.Pool
+}
+
+// NewBufferPool creates an empty buffer pool.
+func NewBufferPool() *BufferPool {
+	return &BufferPool{}
+}
+
+// Get returns an empty buffer.
+func (p *BufferPool) Get() *bytes.Buffer {
+	return new(bytes.Buffer)
+}
+
+// Put returns the buffer to the pool, the caller must not use the buffer after Put.
+func (p *BufferPool) Put(buf *bytes.Buffer) {
+}
+
+// Encoder encodes events with buffers from the pool.
+type Encoder struct {
+	pool *BufferPool
+}
+
+// NewEncoder creates an encoder that takes buffers from the pool.
+func NewEncoder(pool *BufferPool) *Encoder {
+	return &Encoder{pool: pool}
+}
+
+// Encode returns JSON representation of the event.
+// The result is copied out of the pooled buffer, so it stays valid after the buffer is reused.
+func (e *Encoder) Encode(ev Event) []byte {
+	buf := e.pool.Get()
+	defer e.pool.Put(buf)
+
+	writeEvent(buf, ev)
+
+	return bytes.Clone(buf.Bytes())
+}
+
+var testEvent = Event{ID: 42, Name: "signup", Tags: []string{"web", "eu"}}
+
+const testEventJSON = `{"id":42,"name":"signup","tags":["web","eu"]}`
+
+func TestBufferPoolReset(t *testing.T) {
+	p := NewBufferPool()
+
+	for i := 0; i < 10; i++ {
+		buf := p.Get()
+		if buf.Len() != 0 {
+			t.Fatalf("Expected buffer from the pool to be empty, got %q", buf.String())
+		}
+
+		buf.WriteString("leftover")
+		p.Put(buf)
+	}
+}
+
+func TestBufferPoolDropsLargeBuffers(t *testing.T) {
+	// The race detector makes sync.Pool drop objects at random.
+	testutil.SkipWithRaceDetector(t)
+
+	p := NewBufferPool()
+
+	buf := p.Get()
+	buf.Grow(4 * maxPooledBufferSize)
+	p.Put(buf)
+
+	if buf := p.Get(); buf.Cap() > maxPooledBufferSize {
+		t.Errorf("Expected buffers larger than %d bytes not to be pooled, got buffer with capacity %d", maxPooledBufferSize, buf.Cap())
+	}
+
+	// Dropping every buffer passes the check above, small buffers must still be reused.
+	// The pool keeps objects per P, so the goroutine may move to another P between Put and Get, a few tries rule it out.
+	for i := 0; i < 10; i++ {
+		small := p.Get()
+		small.WriteString("small")
+		p.Put(small)
+
+		if p.Get() == small {
+			return
+		}
+	}
+
+	t.Error("Expected small buffers to be reused by the pool")
+}
+
+func TestEncoderAllocations(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	enc := NewEncoder(NewBufferPool())
+
+	if got := string(enc.Encode(testEvent)); got != testEventJSON {
+		t.Fatalf("Expected %s, got %s", testEventJSON, got)
+	}
+
+	// The only allocation left should be the copy of the result.
+	allocs := testing.AllocsPerRun(1000, func() {
+		enc.Encode(testEvent)
+	})
+
+	if allocs > 1 {
+		t.Errorf("Expected at most 1 allocation per encoding, got %.1f", allocs)
+	}
+}
+
+func BenchmarkEncodeNoPool(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buf := new(bytes.Buffer)
+		writeEvent(buf, testEvent)
+		_ = bytes.Clone(buf.Bytes())
+	}
+}
+
+func BenchmarkEncodePool(b *testing.B) {
+	enc := NewEncoder(NewBufferPool())
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		enc.Encode(testEvent)
+	}
+}
+
+// Pitfall: a pooled object belongs to the pool after Put.
+// Any reference kept after Put, including a slice returned by buf.Bytes(), points to memory
+// that the next user of the pool overwrites. Such bugs are silent and appear only under load.
+// Let's try to fix EncodeRetained, so the result stays valid after the buffer goes back to the pool.
+
+var eventBuffers = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// EncodeRetained returns JSON representation of the event, it uses the shared eventBuffers pool.
+func EncodeRetained(ev Event) []byte {
+	buf := eventBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	writeEvent(buf, ev)
+	eventBuffers.Put(buf)
+
+	return buf.Bytes()
+}
+
+func TestPooledBufferRetained(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		first := EncodeRetained(testEvent)
+		EncodeRetained(Event{ID: i, Name: "overwrite", Tags: []string{"x"}})
+
+		if string(first) != testEventJSON {
+			t.Fatalf("Expected the result to stay %s, got %s", testEventJSON, first)
+		}
+	}
+}
+
+// TestPoolGenerations shows the lifetime of pooled objects, it only logs what happens and passes as is.
+// Run it with -v to see the log. A pooled object survives one garbage collection in the victim cache
+// and is freed by the next one, so a pool doesn't keep memory of a burst forever.
+// The pool is sharded per P and the goroutine may move to another P between calls,
+// so Get may miss the object Put stored, the runtime gives no guarantees here.
+func TestPoolGenerations(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	created := 0
+	p := sync.Pool{New: func() any {
+		created++
+		return new(bytes.Buffer)
+	}}
+
+	buf := p.Get().(*bytes.Buffer)
+	p.Put(buf)
+
+	runtime.GC() // the buffer moves to the victim cache
+
+	t.Logf("After one garbage collection the pooled buffer is reused: %t", p.Get().(*bytes.Buffer) == buf)
+
+	p.Put(buf)
+
+	runtime.GC() // the buffer moves to the victim cache
+	runtime.GC() // the victim cache is freed
+
+	t.Logf("After two garbage collections the pooled buffer is reused: %t", p.Get().(*bytes.Buffer) == buf)
+	t.Logf("The pool created %d buffers", created)
+}
@@ -0,0 +1,83 @@
+package concurrency
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// A flaky test passes and fails on the same code. Most often it depends on timing: it sleeps and hopes
+// the other goroutine is done by then, or it reads the clock and hopes it doesn't tick at a bad moment.
+// It passes on the laptop of its author and fails in CI once a week, so nobody trusts red builds anymore.
+// One run tells nothing about such a test, many runs do, verify reports how many of them failed:
+//
+//	go run ./cmd/workshop verify -stress 50 concurrency/fixed-window
+//
+// -stress runs the tests 50 times in one process, in random order, with the race detector.
+//
+// FixedWindow is a rate limiter: it allows Limit requests in every window, and windows start on multiples
+// of the window duration, like every second on the second. The limiter is correct, TestFixedWindow isn't:
+// it uses the real clock, and when a window ends in the middle of the test, the window after it
+// allows the request the test expects to be denied. Make the test deterministic: control the clock
+// with Now instead of sleeping, and check both sides of a window boundary.
+
+// FixedWindow allows up to Limit requests in every window of time, it's safe for concurrent use.
+type FixedWindow struct {
+	Limit  int
+	Window time.Duration
+
+	// Now returns the current time, time.Now is used when it's nil.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+// Allow reports whether a request is allowed now, and counts it if it is.
+func (l *FixedWindow) Allow() bool {
+	now := time.Now
+	if l.Now != nil {
+		now = l.Now
+	}
+
+	start := now().Truncate(l.Window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !start.Equal(l.start) {
+		l.start, l.count = start, 0
+	}
+
+	if l.count >= l.Limit {
+		return false
+	}
+
+	l.count++
+
+	return true
+}
+
+func TestFixedWindow(t *testing.T) {
+	l := &FixedWindow{Limit: 5, Window: 20 * time.Millisecond}
+
+	for range 3 {
+		if !l.Allow() {
+			t.Fatal("Expected a request under the limit to be allowed")
+		}
+	}
+
+	// Handling of the requests takes some time.
+	time.Sleep(5 * time.Millisecond)
+
+	for range 2 {
+		if !l.Allow() {
+			t.Fatal("Expected a request under the limit to be allowed")
+		}
+	}
+
+	if l.Allow() {
+		t.Error("Expected a request over the limit to be denied")
+	}
+}
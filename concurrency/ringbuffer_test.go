@@ -0,0 +1,258 @@
+package concurrency
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Advanced: a bounded multi-producer single-consumer (MPSC) ring buffer.
+//
+// Many goroutines produce values, like log records or metrics, and one goroutine consumes them.
+// A ring buffer keeps values in a slice of fixed size: the head index points to the oldest value,
+// the tail index to the next free slot, and both wrap around the end of the slice. With a capacity
+// that is a power of two, wrapping is a cheap mask: index & (capacity - 1).
+//
+// Let's build it twice, with the same RingBuffer interface:
+//   - CondRing guards the buffer with a mutex. Push waits on a sync.Cond while the buffer is full,
+//     Pop waits on another one while it's empty, and each of them signals the other side.
+//   - AtomicRing takes no locks. Every slot has a sequence number that tells whose turn it is:
+//     a slot at position p is free for the producer with ticket p when its sequence is p,
+//     and ready for the consumer when its sequence is p+1. A producer takes a ticket by CompareAndSwap
+//     on the tail, writes the value, and publishes it by storing p+1 to the sequence. The consumer reads
+//     the value at head when the sequence is head+1, and frees the slot for the next lap by storing
+//     head+capacity. There's a single consumer, so head needs no CAS. Blocking Push and Pop
+//     spin on TryPush and TryPop, yielding with runtime.Gosched.
+//
+// Both must pass the same conformance test. The benchmarks compare them with a buffered channel,
+// which is an MPMC ring buffer with a lock inside, run them with:
+//
+//	go test -run='^$' -bench=Ring ./concurrency
+
+// RingBuffer is a bounded FIFO queue for many producers and a single consumer.
+type RingBuffer[T any] interface {
+	// TryPush adds v to the tail, it returns false if the buffer is full.
+	TryPush(v T) bool
+
+	// TryPop removes the value at the head, ok is false if the buffer is empty.
+	// Only one goroutine may pop values.
+	TryPop() (v T, ok bool)
+
+	// Push adds v to the tail, waiting for a free slot.
+	Push(v T)
+
+	// Pop removes the value at the head, waiting for one.
+	Pop() T
+}
+
+var (
+	_ RingBuffer[int] = (*CondRing[int])(nil)
+	_ RingBuffer[int] = (*AtomicRing[int])(nil)
+	_ RingBuffer[int] = chanRing[int](nil)
+)
+
+// CondRing is a ring buffer guarded by a mutex, it's safe for concurrent use.
+type CondRing[T any] struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	buf      []T
+	head     uint64
+	tail     uint64
+}
+
+// NewCondRing creates a ring buffer, capacity must be a power of two.
+func NewCondRing[T any](capacity int) *CondRing[T] {
+	return &CondRing[T]{}
+}
+
+func (r *CondRing[T]) TryPush(v T) bool {
+	return false
+}
+
+func (r *CondRing[T]) TryPop() (v T, ok bool) {
+	return v, false
+}
+
+func (r *CondRing[T]) Push(v T) {
+}
+
+func (r *CondRing[T]) Pop() T {
+	var v T
+
+	return v
+}
+
+// ringSlot is a slot of AtomicRing, seq tells whether it's free or holds a value.
+type ringSlot[T any] struct {
+	seq   atomic.Uint64
+	value T
+}
+
+// AtomicRing is a lock-free ring buffer, it's safe for concurrent use by many producers and one consumer.
+type AtomicRing[T any] struct {
+	slots []ringSlot[T]
+	mask  uint64
+	head  uint64
+	tail  atomic.Uint64
+}
+
+// NewAtomicRing creates a ring buffer, capacity must be a power of two.
+func NewAtomicRing[T any](capacity int) *AtomicRing[T] {
+	return &AtomicRing[T]{}
+}
+
+func (r *AtomicRing[T]) TryPush(v T) bool {
+	return false
+}
+
+func (r *AtomicRing[T]) TryPop() (v T, ok bool) {
+	return v, false
+}
+
+func (r *AtomicRing[T]) Push(v T) {
+}
+
+func (r *AtomicRing[T]) Pop() T {
+	var v T
+
+	return v
+}
+
+// chanRing is the baseline for benchmarks: a buffered channel is a ring buffer too.
+type chanRing[T any] chan T
+
+func (r chanRing[T]) TryPush(v T) bool {
+	select {
+	case r <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r chanRing[T]) TryPop() (v T, ok bool) {
+	select {
+	case v = <-r:
+		return v, true
+	default:
+		return v, false
+	}
+}
+
+func (r chanRing[T]) Push(v T) { r <- v }
+
+func (r chanRing[T]) Pop() T { return <-r }
+
+// testRingBuffer is the conformance test both ring buffers must pass.
+func testRingBuffer(t *testing.T, newRing func(capacity int) RingBuffer[int]) {
+	t.Run("sequential", func(t *testing.T) {
+		r := newRing(4)
+
+		if _, ok := r.TryPop(); ok {
+			t.Fatal("Expected TryPop to fail on an empty buffer")
+		}
+
+		// Many laps around the ring keep the order.
+		next, want := 0, 0
+
+		for lap := range 10 {
+			for r.TryPush(next) {
+				next++
+			}
+
+			if next-want != 4 {
+				t.Fatalf("Expected TryPush to fill exactly 4 slots on lap %d, got %d", lap, next-want)
+			}
+
+			for range 3 {
+				if v, ok := r.TryPop(); !ok || v != want {
+					t.Fatalf("Expected to pop %d, got %d, %t", want, v, ok)
+				}
+
+				want++
+			}
+		}
+	})
+
+	t.Run("producers", func(t *testing.T) {
+		testutil.RequireRaceDetector(t)
+
+		const (
+			producers = 8
+			perWorker = 2000
+		)
+
+		// A small buffer makes producers wait for free slots and the consumer wait for values.
+		r := newRing(8)
+
+		for p := range producers {
+			go func() {
+				for i := range perWorker {
+					r.Push(p*perWorker + i)
+				}
+			}()
+		}
+
+		// Values of every producer arrive in the order it pushed them, each exactly once.
+		next := make([]int, producers)
+
+		for range producers * perWorker {
+			v := r.Pop()
+			p, i := v/perWorker, v%perWorker
+
+			if p < 0 || p >= producers || i != next[p] {
+				t.Fatalf("Expected every value once and in the order of its producer, got %d", v)
+			}
+
+			next[p]++
+		}
+
+		if v, ok := r.TryPop(); ok {
+			t.Errorf("Expected the buffer to be empty, got %d", v)
+		}
+	})
+}
+
+func TestCondRing(t *testing.T) {
+	testRingBuffer(t, func(capacity int) RingBuffer[int] { return NewCondRing[int](capacity) })
+}
+
+func TestAtomicRing(t *testing.T) {
+	testRingBuffer(t, func(capacity int) RingBuffer[int] { return NewAtomicRing[int](capacity) })
+}
+
+// benchmarkRing pushes values from parallel producers, while a single consumer pops them.
+func benchmarkRing(b *testing.B, r RingBuffer[int]) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for range b.N {
+			r.Pop()
+		}
+	}()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			r.Push(i)
+		}
+	})
+
+	<-done
+}
+
+func BenchmarkRingCond(b *testing.B) {
+	benchmarkRing(b, NewCondRing[int](1024))
+}
+
+func BenchmarkRingAtomic(b *testing.B) {
+	benchmarkRing(b, NewAtomicRing[int](1024))
+}
+
+func BenchmarkRingChannel(b *testing.B) {
+	benchmarkRing(b, make(chanRing[int], 1024))
+}
@@ -0,0 +1,289 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// To limit the number of goroutines that can run concurrently, we can use a semaphore.
+// A semaphore is a synchronization primitive that limits the number of concurrent operations.
+// It is used to control access to a shared resource.
+// We can use a buffered channel to implement a semaphore:
+// sending to the channel acquires a slot, receiving from it releases the slot,
+// and the capacity of the channel is the number of slots.
+
+// RunLimited runs all tasks concurrently, but no more than limit tasks at the same time.
+// Let's try to limit concurrency with a buffered channel.
+func RunLimited(limit int, tasks []func()) {
+	wg := sync.WaitGroup{}
+
+	for _, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			task()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// A test of a limit can't just sleep in tasks and hope they overlap. Instead, the first limit+1 tasks wait
+// for each other at a barrier: if the limit works, only limit tasks can wait at the same time and the barrier
+// gives up, otherwise the extra task joins them and the test sees it running. See the testsync package.
+
+func TestSemaphoreWithChannels(t *testing.T) {
+	const limit = 3
+
+	c := atomic.Int32{}
+	started := atomic.Int32{}
+	done := atomic.Int32{}
+	overlap := testsync.NewBarrier(limit + 1)
+	tasks := make([]func(), 10)
+
+	for i := range tasks {
+		tasks[i] = func() {
+			val := c.Add(1)
+			defer c.Add(-1)
+
+			if started.Add(1) <= limit+1 {
+				overlap.Wait(50 * time.Millisecond)
+			}
+
+			if val > limit {
+				t.Error("Expected to have only 3 goroutines running concurrently")
+			}
+
+			done.Add(1)
+		}
+	}
+
+	RunLimited(limit, tasks)
+
+	if done.Load() != 10 {
+		t.Errorf("Expected all 10 tasks to be done, got %d", done.Load())
+	}
+}
+
+// A channel semaphore gives every operation the same weight.
+// Often operations are not equal: copying a big file needs more memory than copying a small one,
+// so it should take more of the limited resource.
+// A weighted semaphore allows to acquire and release many slots at once.
+// The standard implementation is golang.org/x/sync/semaphore, let's build our own with the same semantics:
+//
+//   - Acquire(ctx, n) blocks until n slots are available or ctx is done.
+//     If ctx is done, it returns ctx.Err() and leaves the semaphore unchanged.
+//     A request larger than the size of the semaphore waits until ctx is done.
+//   - Waiters are served in the order of arrival (FIFO). A large request at the head of the queue
+//     blocks smaller requests behind it, so large requests are never starved by a stream of small ones.
+//   - TryAcquire(n) acquires n slots without blocking, it fails if there are waiters in the queue.
+//   - Release(n) releases n slots and wakes up waiters that fit now. Releasing more than held is a bug, it panics.
+//
+// Hint: protect the state with a mutex and keep a queue of waiters, every waiter has its own channel
+// that is closed when the waiter is granted its slots. A waiter whose context is done must remove itself from the queue,
+// but it's possible that it was granted slots at the same moment, then it has to decide what to do with them.
+
+// Weighted is a semaphore with a fixed number of slots, operations can acquire many slots at once.
+type Weighted struct {
+	size int64
+	cur  int64
+	mu   sync.Mutex
+}
+
+// NewWeighted creates a semaphore with n slots.
+func NewWeighted(n int64) *Weighted {
+	return &Weighted{size: n}
+}
+
+// Acquire acquires n slots, blocking until they are available or ctx is done.
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	return nil
+}
+
+// TryAcquire acquires n slots without blocking and reports whether it succeeded.
+func (s *Weighted) TryAcquire(n int64) bool {
+	return true
+}
+
+// Release releases n slots.
+func (s *Weighted) Release(n int64) {
+}
+
+func TestWeightedAccounting(t *testing.T) {
+	const size = 10
+
+	sem := NewWeighted(size)
+	held := atomic.Int64{}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 50; i++ {
+		weight := int64(i%4 + 1)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := sem.Acquire(context.Background(), weight); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if h := held.Add(weight); h > size {
+				t.Errorf("Expected at most %d slots to be held, got %d", size, h)
+			}
+
+			time.Sleep(100 * time.Microsecond)
+
+			held.Add(-weight)
+			sem.Release(weight)
+		}()
+	}
+
+	wg.Wait()
+
+	if !sem.TryAcquire(size) {
+		t.Error("Expected all slots to be released")
+	}
+}
+
+func TestWeightedAcquireTooLarge(t *testing.T) {
+	sem := NewWeighted(10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := sem.Acquire(ctx, 11); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if sem.TryAcquire(11) {
+		t.Error("Expected TryAcquire to fail for a request larger than the semaphore")
+	}
+}
+
+func TestWeightedCancelWhileWaiting(t *testing.T) {
+	sem := NewWeighted(10)
+
+	if err := sem.Acquire(context.Background(), 10); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- sem.Acquire(ctx, 1)
+	}()
+
+	if !waitingInAcquire(1) {
+		select {
+		case err := <-errs:
+			t.Fatalf("Expected Acquire to wait for free slots, got %v", err)
+		default:
+			t.Fatal("Expected Acquire to wait for free slots")
+		}
+	}
+
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected error to be %v, got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Acquire to return after cancellation")
+	}
+
+	sem.Release(10)
+
+	if !sem.TryAcquire(10) {
+		t.Error("Expected canceled waiter not to hold any slots")
+	}
+}
+
+func TestWeightedFIFO(t *testing.T) {
+	sem := NewWeighted(10)
+
+	if err := sem.Acquire(context.Background(), 5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	large := make(chan struct{})
+
+	go func() {
+		if err := sem.Acquire(context.Background(), 10); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		close(large)
+	}()
+
+	if !waitingInAcquire(1) {
+		t.Fatal("Expected the large request to wait for free slots")
+	}
+
+	if sem.TryAcquire(1) {
+		t.Fatal("Expected TryAcquire to fail while the large request is waiting in the queue")
+	}
+
+	small := make(chan struct{})
+
+	go func() {
+		if err := sem.Acquire(context.Background(), 1); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		close(small)
+	}()
+
+	if !waitingInAcquire(2) {
+		t.Fatal("Expected the small request to wait behind the large one")
+	}
+
+	sem.Release(5)
+
+	select {
+	case <-large:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the large request to be served first")
+	}
+
+	if !waitingInAcquire(1) {
+		t.Fatal("Expected the small request to wait until the large one releases slots")
+	}
+
+	sem.Release(10)
+
+	select {
+	case <-small:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the small request to be served")
+	}
+}
+
+// waitingInAcquire reports whether n goroutines are waiting in the queue of Acquire. Sleeping for a while
+// doesn't tell that, a goroutine blocked in Acquire does. WaitUntil gives a woken up waiter a moment
+// to check its slots and block again.
+func waitingInAcquire(n int) bool {
+	return testsync.WaitUntil(func() bool { return testsync.Blocked("(*Weighted).Acquire") == n }, time.Second)
+}
+
+func TestWeightedReleaseMoreThanHeld(t *testing.T) {
+	sem := NewWeighted(10)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Release to panic when releasing more than held")
+		}
+	}()
+
+	sem.Release(1)
+}
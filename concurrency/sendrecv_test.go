@@ -0,0 +1,136 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// A bare channel operation blocks until the other side shows up, and if it never does, the goroutine leaks.
+// Every send and receive that can wait for long should also wait for ctx.Done() in the same select.
+// The select is the same every time, so it's worth two small generic helpers:
+//   - Send(ctx, ch, v) sends v to ch, or returns ctx.Err() if ctx is done first.
+//   - Recv(ctx, ch) receives a value from ch, or returns ctx.Err() if ctx is done first.
+//     A closed channel has no more values, Recv returns ErrChanClosed for it.
+//
+// Watch out: when ctx is already done and the channel is ready too, select picks one of the cases at random.
+// A canceled operation must not happen, so check ctx before the select, a select with a default case does it.
+//
+// NumberIterator.Next in concurrency_test.go is the next step: build it on these helpers to solve TestChanOfChan
+// and TestDefaultCase.
+
+// ErrChanClosed is returned by Recv when the channel is closed.
+var ErrChanClosed = errors.New("channel closed")
+
+// Send sends v to ch, it returns ctx.Err() if ctx is done before the value is sent.
+func Send[T any](ctx context.Context, ch chan<- T, v T) error {
+	return nil
+}
+
+// Recv receives a value from ch, it returns ctx.Err() if ctx is done before a value arrives.
+func Recv[T any](ctx context.Context, ch <-chan T) (T, error) {
+	var v T
+
+	return v, nil
+}
+
+func TestSendRecv(t *testing.T) {
+	ch := make(chan string, 1)
+
+	if err := Send(context.Background(), ch, "ping"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	v, err := Recv(context.Background(), ch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v != "ping" {
+		t.Errorf("Expected to receive 'ping', got '%s'", v)
+	}
+
+	close(ch)
+
+	if _, err := Recv(context.Background(), ch); !errors.Is(err, ErrChanClosed) {
+		t.Errorf("Expected error to be %v for a closed channel, got %v", ErrChanClosed, err)
+	}
+}
+
+func TestSendRecvCanceledBefore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The channel is ready for both operations, the canceled context must win anyway.
+	ch := make(chan int, 1)
+
+	for range 100 {
+		if err := Send(ctx, ch, 1); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected error to be %v, got %v", context.Canceled, err)
+		}
+
+		if len(ch) != 0 {
+			t.Fatal("Expected a canceled Send not to send the value")
+		}
+	}
+
+	ch <- 42
+
+	for range 100 {
+		if _, err := Recv(ctx, ch); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected error to be %v, got %v", context.Canceled, err)
+		}
+
+		if len(ch) != 1 {
+			t.Fatal("Expected a canceled Recv not to receive the value")
+		}
+	}
+}
+
+func TestSendRecvCanceledWhileBlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Nobody is on the other side of the channels, both operations block until ctx is canceled.
+	out := make(chan int)
+	in := make(chan int)
+	errs := make(chan error, 2)
+
+	go func() {
+		errs <- Send(ctx, out, 1)
+	}()
+
+	go func() {
+		_, err := Recv(ctx, in)
+		errs <- err
+	}()
+
+	blocked := func() bool {
+		return testsync.Blocked("Send[...]") == 1 && testsync.Blocked("Recv[...]") == 1
+	}
+
+	if !testsync.WaitUntil(blocked, time.Second) {
+		select {
+		case err := <-errs:
+			t.Fatalf("Expected Send and Recv to wait on the channels, got %v", err)
+		default:
+			t.Fatal("Expected Send and Recv to wait on the channels")
+		}
+	}
+
+	cancel()
+
+	for range 2 {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected error to be %v, got %v", context.Canceled, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected Send and Recv to return after cancellation")
+		}
+	}
+}
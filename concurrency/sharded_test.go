@@ -0,0 +1,275 @@
+package concurrency
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Advanced: sharding hot shared state.
+//
+// An atomic counter is the fastest counter for one goroutine, and one of the slowest for many.
+// Every Add takes exclusive ownership of the cache line with the counter, so cores updating it
+// pass the line to each other, and the more cores there are, the more time they spend waiting for it.
+// A mutex around a map has the same problem, and every update of any key waits for all the others.
+//
+// The fix is to split the state, so goroutines mostly touch different parts of it:
+//   - ShardedCounter keeps a counter per shard. Add picks a shard and adds to it, Load sums all shards.
+//     Go doesn't expose the P a goroutine runs on, a cheap random number from math/rand/v2 spreads goroutines
+//     over shards well enough. Pad shards to 64 bytes, or neighbors share a cache line anyway (false sharing).
+//   - StripedMap splits the map into stripes, each with its own mutex and map, and a key always goes
+//     to the same stripe chosen by its hash, like hash/maphash. Updates of keys in different stripes
+//     don't wait for each other.
+//
+// Reads become more expensive: Load visits every shard, and it isn't a snapshot, shards change while
+// it sums them. It's a fine trade for metrics, that are written all the time and read once in a while.
+//
+// Contention needs cores, the speedup shows on a machine with at least 4 of them:
+//
+//	go test -run='^$' -bench='Counter|Map' -cpu=1,4,8 ./concurrency
+//
+// Or record a baseline with workshop bench concurrency before the rewrite, and run it again after.
+
+// ShardedCounter is a counter that is cheap to update from many goroutines at once.
+type ShardedCounter struct {
+	total atomic.Int64
+}
+
+// NewShardedCounter creates a counter.
+func NewShardedCounter() *ShardedCounter {
+	return &ShardedCounter{}
+}
+
+// Add adds n to the counter.
+func (c *ShardedCounter) Add(n int64) {
+	c.total.Add(n)
+}
+
+// Load returns the value of the counter.
+func (c *ShardedCounter) Load() int64 {
+	return c.total.Load()
+}
+
+// StripedMap is a map of counters that is safe for concurrent use.
+type StripedMap struct {
+	mu sync.Mutex
+	m  map[string]int64
+}
+
+// NewStripedMap creates an empty map.
+func NewStripedMap() *StripedMap {
+	return &StripedMap{m: make(map[string]int64)}
+}
+
+// Update replaces the value of the key with fn applied to it, fn runs with the key locked.
+func (m *StripedMap) Update(key string, fn func(int64) int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.m[key] = fn(m.m[key])
+}
+
+// Get returns the value of the key, zero if it's not in the map.
+func (m *StripedMap) Get(key string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.m[key]
+}
+
+// Len returns the number of keys in the map.
+func (m *StripedMap) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.m)
+}
+
+func inc(v int64) int64 { return v + 1 }
+
+func TestShardedCounter(t *testing.T) {
+	const (
+		goroutines = 8
+		perWorker  = 10000
+	)
+
+	c := NewShardedCounter()
+	wg := sync.WaitGroup{}
+
+	for g := range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range perWorker {
+				if g%2 == 1 && i%2 == 1 {
+					c.Add(-1)
+				} else {
+					c.Add(2)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Half of the goroutines add 2, the other half add 2 and -1 in turns.
+	expected := int64(goroutines/2*perWorker*2 + goroutines/2*perWorker/2)
+	if got := c.Load(); got != expected {
+		t.Errorf("Expected counter to be %d, got %d", expected, got)
+	}
+}
+
+func TestShardedCounterScales(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	if runtime.GOMAXPROCS(0) < 4 {
+		t.Skip("Skipping test that measures contention, it needs at least 4 CPUs")
+	}
+
+	contended := testing.Benchmark(BenchmarkCounterContended)
+	sharded := testing.Benchmark(BenchmarkShardedCounter)
+
+	if sharded.NsPerOp()*2 > contended.NsPerOp() {
+		t.Errorf("Expected ShardedCounter to be at least 2 times faster than a single atomic counter, got %d ns/op vs %d ns/op",
+			sharded.NsPerOp(), contended.NsPerOp())
+	}
+}
+
+func TestStripedMap(t *testing.T) {
+	const (
+		goroutines = 8
+		keys       = 100
+		perKey     = 50
+	)
+
+	m := NewStripedMap()
+	wg := sync.WaitGroup{}
+
+	for range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range keys * perKey {
+				m.Update(fmt.Sprintf("key-%d", i%keys), inc)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if m.Len() != keys {
+		t.Errorf("Expected %d keys, got %d", keys, m.Len())
+	}
+
+	for i := range keys {
+		key := fmt.Sprintf("key-%d", i)
+		if got := m.Get(key); got != goroutines*perKey {
+			t.Fatalf("Expected %s to be %d, got %d", key, goroutines*perKey, got)
+		}
+	}
+
+	if got := m.Get("missing"); got != 0 {
+		t.Errorf("Expected a missing key to be 0, got %d", got)
+	}
+}
+
+func TestStripedMapStripes(t *testing.T) {
+	m := NewStripedMap()
+	steps := testsync.NewStepController(t)
+
+	// The update of "hot" holds its stripe until the test releases it.
+	go m.Update("hot", func(v int64) int64 {
+		steps.Step("hot")
+		return v + 1
+	})
+
+	steps.Await("hot")
+
+	// Keys in other stripes don't wait for it, some of these keys must be in other stripes.
+	updated := atomic.Int32{}
+
+	for i := range 32 {
+		go func() {
+			m.Update(fmt.Sprintf("key-%d", i), inc)
+			updated.Add(1)
+		}()
+	}
+
+	if !testsync.WaitUntil(func() bool { return updated.Load() > 0 }, time.Second) {
+		t.Error("Expected updates of keys in other stripes not to wait for the locked one")
+	}
+
+	steps.Release("hot")
+
+	if !testsync.WaitUntil(func() bool { return updated.Load() == 32 }, time.Second) {
+		t.Fatal("Expected all updates to finish after the locked stripe is released")
+	}
+
+	if m.Len() != 33 || m.Get("hot") != 1 {
+		t.Errorf("Expected 33 keys with hot of 1, got %d keys with hot of %d", m.Len(), m.Get("hot"))
+	}
+}
+
+func BenchmarkCounterContended(b *testing.B) {
+	var c atomic.Int64
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Add(1)
+		}
+	})
+}
+
+func BenchmarkShardedCounter(b *testing.B) {
+	c := NewShardedCounter()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Add(1)
+		}
+	})
+}
+
+// benchmarkKeys are keys of a map benchmark, the same for every implementation.
+var benchmarkKeys = func() []string {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	return keys
+}()
+
+func BenchmarkLockedMap(b *testing.B) {
+	var mu sync.Mutex
+
+	m := make(map[string]int64)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			mu.Lock()
+			m[benchmarkKeys[i%len(benchmarkKeys)]]++
+			mu.Unlock()
+		}
+	})
+}
+
+func BenchmarkStripedMap(b *testing.B) {
+	m := NewStripedMap()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Update(benchmarkKeys[i%len(benchmarkKeys)], inc)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package concurrency
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// A database sequence hands out unique IDs, but every ID costs a round trip to the database.
+// Snowflake IDs, invented at Twitter, are generated locally by every node and are still unique and sortable.
+// An ID is a 63-bit number made of three parts, from the highest bits to the lowest:
+//
+//	| 41 bits: milliseconds since Epoch | 10 bits: node | 12 bits: sequence |
+//
+// The timestamp makes IDs grow with time, the node makes IDs of different nodes differ,
+// and the sequence numbers IDs generated by the node within the same millisecond.
+//
+// Let's implement Next, it's called from many goroutines at once:
+//   - IDs of a generator strictly increase, even when they are generated in the same millisecond.
+//   - The sequence has room for 4096 IDs a millisecond, when it's exhausted Next waits for the next millisecond.
+//   - Clocks go backwards, NTP corrects them. If the clock is behind the last ID, reusing old timestamps
+//     could repeat IDs, so Next returns a *ClockBackwardsError instead, the caller can wait and retry.
+//
+// Read the time with Now, so tests can control the clock, see FixedWindow in ratelimit_test.go.
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+
+	// MaxNode is the largest node ID.
+	MaxNode = 1<<snowflakeNodeBits - 1
+)
+
+// Epoch is the start of snowflake timestamps, 41 bits of milliseconds last for 69 years from it.
+var Epoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrInvalidNode is returned by NewSnowflake for a node ID that doesn't fit in 10 bits.
+var ErrInvalidNode = errors.New("invalid node ID")
+
+// ClockBackwardsError is returned by Next when the clock shows an earlier time than the last generated ID.
+type ClockBackwardsError struct {
+	Last, Now time.Time
+}
+
+func (e *ClockBackwardsError) Error() string {
+	return fmt.Sprintf("clock moved backwards by %s", e.Last.Sub(e.Now))
+}
+
+// Snowflake generates unique IDs for a node, it's safe for concurrent use.
+type Snowflake struct {
+	node int64
+
+	// Now returns the current time, time.Now is used when it's nil.
+	Now func() time.Time
+}
+
+// NewSnowflake creates a generator for the node, from 0 to MaxNode.
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidNode, node)
+	}
+
+	return &Snowflake{node: node}, nil
+}
+
+// Next returns a new ID.
+func (g *Snowflake) Next() (int64, error) {
+	return 0, nil
+}
+
+// ParseID splits the ID into its time, node, and sequence.
+func ParseID(id int64) (t time.Time, node, seq int64) {
+	ms := id >> (snowflakeNodeBits + snowflakeSequenceBits)
+	node = id >> snowflakeSequenceBits & MaxNode
+	seq = id & (1<<snowflakeSequenceBits - 1)
+
+	return Epoch.Add(time.Duration(ms) * time.Millisecond), node, seq
+}
+
+// manualClock is a clock that moves only when the test moves it, it counts how many times it was read.
+type manualClock struct {
+	now   atomic.Int64
+	reads atomic.Int64
+}
+
+func newManualClock(t time.Time) *manualClock {
+	c := &manualClock{}
+	c.Set(t)
+
+	return c
+}
+
+func (c *manualClock) Now() time.Time {
+	c.reads.Add(1)
+
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *manualClock) Set(t time.Time) {
+	c.now.Store(t.UnixNano())
+}
+
+func TestSnowflakeUnique(t *testing.T) {
+	const (
+		goroutines = 8
+		perWorker  = 1 << 17
+	)
+
+	g, err := NewSnowflake(42)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ids := make([][]int64, goroutines)
+	wg := sync.WaitGroup{}
+
+	for w := range goroutines {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ids[w] = make([]int64, 0, perWorker)
+
+			for range perWorker {
+				id, err := g.Next()
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+
+				ids[w] = append(ids[w], id)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Every goroutine sees its IDs strictly increase.
+	for w, got := range ids {
+		for i := 1; i < len(got); i++ {
+			if got[i] <= got[i-1] {
+				t.Fatalf("Expected IDs to increase, goroutine %d got %d after %d", w, got[i], got[i-1])
+			}
+		}
+	}
+
+	all := slices.Sorted(slices.Values(slices.Concat(ids...)))
+
+	if len(all) != goroutines*perWorker {
+		t.Fatalf("Expected %d IDs, got %d", goroutines*perWorker, len(all))
+	}
+
+	for i := 1; i < len(all); i++ {
+		if all[i] == all[i-1] {
+			t.Fatalf("Expected IDs to be unique, got %d twice", all[i])
+		}
+	}
+
+	first, node, _ := ParseID(all[0])
+	last, _, _ := ParseID(all[len(all)-1])
+
+	if node != 42 {
+		t.Errorf("Expected IDs of node 42, got %d", node)
+	}
+
+	if since := time.Since(first); since < 0 || since > time.Minute || last.Before(first) {
+		t.Errorf("Expected timestamps of IDs to be the current time, got from %s to %s", first, last)
+	}
+}
+
+func TestSnowflakeLayout(t *testing.T) {
+	clock := newManualClock(Epoch.Add(5 * time.Millisecond))
+
+	g, err := NewSnowflake(7)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	g.Now = clock.Now
+
+	expected := []struct {
+		ms  time.Duration
+		seq int64
+	}{{5, 0}, {5, 1}, {6, 0}}
+
+	for i, e := range expected {
+		clock.Set(Epoch.Add(e.ms * time.Millisecond))
+
+		id, err := g.Next()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		ts, node, seq := ParseID(id)
+		if !ts.Equal(Epoch.Add(e.ms*time.Millisecond)) || node != 7 || seq != e.seq {
+			t.Errorf("Expected ID %d at %dms of node 7 with sequence %d, got %s, node %d, sequence %d",
+				i, e.ms, e.seq, ts.Sub(Epoch), node, seq)
+		}
+	}
+
+	if _, err := NewSnowflake(MaxNode + 1); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidNode, err)
+	}
+}
+
+func TestSnowflakeSequenceExhausted(t *testing.T) {
+	start := Epoch.Add(time.Hour)
+	clock := newManualClock(start)
+
+	g, err := NewSnowflake(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	g.Now = clock.Now
+
+	for range 1 << snowflakeSequenceBits {
+		if _, err := g.Next(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// The millisecond is used up, the next ID must wait for the clock.
+	ids := make(chan int64, 1)
+
+	go func() {
+		id, err := g.Next()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		ids <- id
+	}()
+
+	reads := clock.reads.Load()
+
+	if !testsync.WaitUntil(func() bool { return clock.reads.Load() > reads+10 || len(ids) > 0 }, time.Second) {
+		t.Fatal("Expected Next to check the clock while it waits for the next millisecond")
+	}
+
+	select {
+	case id := <-ids:
+		ts, _, seq := ParseID(id)
+		t.Fatalf("Expected Next to wait for the next millisecond, got an ID at %s with sequence %d", ts.Sub(Epoch), seq)
+	default:
+	}
+
+	clock.Set(start.Add(time.Millisecond))
+
+	select {
+	case id := <-ids:
+		if ts, _, seq := ParseID(id); !ts.Equal(start.Add(time.Millisecond)) || seq != 0 {
+			t.Errorf("Expected the first ID of the next millisecond, got an ID at %s with sequence %d", ts.Sub(Epoch), seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Next to return in the next millisecond")
+	}
+}
+
+func TestSnowflakeClockBackwards(t *testing.T) {
+	start := Epoch.Add(time.Hour)
+	clock := newManualClock(start)
+
+	g, err := NewSnowflake(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	g.Now = clock.Now
+
+	first, err := g.Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	clock.Set(start.Add(-3 * time.Millisecond))
+
+	var backwards *ClockBackwardsError
+
+	id, err := g.Next()
+	if !errors.As(err, &backwards) {
+		t.Fatalf("Expected a *ClockBackwardsError, got %d, %v", id, err)
+	}
+
+	if !backwards.Last.Equal(start) || !backwards.Now.Equal(start.Add(-3*time.Millisecond)) {
+		t.Errorf("Expected the error to tell the last time %s and the current one 3ms earlier, got %+v", start, backwards)
+	}
+
+	// Once the clock catches up, IDs continue after the last one.
+	clock.Set(start)
+
+	id, err = g.Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if id <= first {
+		t.Errorf("Expected the ID after recovery to be greater than %d, got %d", first, id)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// Advanced: printing the effective configuration.
+//
+// A service that logs its configuration on start saves hours of debugging, "which value won?" is answered in the log.
+// But the configuration contains passwords and tokens, and logs are read by many people and stored for a long time.
+//
+// Let's implement Dump with reflection: it returns JSON of the configuration where fields tagged `secret:"true"`
+// are replaced with "[REDACTED]", in nested structs too. Empty secrets stay empty, so it's visible that
+// a secret is not set. Field names come from json tags, like encoding/json does.
+// Dump must not change the configuration it prints.
+// Hint: build a map[string]any while walking fields with reflect, and marshal the map.
+
+// ServiceConfig is a configuration with secrets.
+type ServiceConfig struct {
+	Name     string          `json:"name"`
+	Database SecretsDatabase `json:"database"`
+	Tokens   []string        `json:"tokens" secret:"true"`
+	Webhook  string          `json:"webhook" secret:"true"`
+}
+
+// SecretsDatabase is a database configuration with a password.
+type SecretsDatabase struct {
+	URL      string `json:"url"`
+	Password string `json:"password" secret:"true"`
+	MaxConns int    `json:"max_conns"`
+}
+
+// Dump returns JSON of the configuration with secrets redacted.
+func Dump(cfg any) (string, error) {
+	data, err := json.Marshal(cfg)
+
+	return string(data), err
+}
+
+func TestDump(t *testing.T) {
+	cfg := ServiceConfig{
+		Name:     "billing",
+		Database: SecretsDatabase{URL: "postgres://db:5432/billing", Password: "s3cret", MaxConns: 10},
+		Tokens:   []string{"tok-1", "tok-2"},
+	}
+
+	got, err := Dump(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"database":{"max_conns":10,"password":"[REDACTED]","url":"postgres://db:5432/billing"},` +
+		`"name":"billing","tokens":"[REDACTED]","webhook":""}`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	for _, secret := range []string{"s3cret", "tok-1"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Expected %q to be redacted", secret)
+		}
+	}
+
+	if cfg.Database.Password != "s3cret" || cfg.Tokens[0] != "tok-1" {
+		t.Error("Expected Dump to keep the configuration unchanged")
+	}
+
+	if got, err := Dump(&cfg); err != nil || got != expected {
+		t.Errorf("Expected a pointer to be dumped like the value, got %s and error %v", got, err)
+	}
+}
@@ -0,0 +1,77 @@
+package cryptobasics
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Advanced: authenticated encryption.
+//
+// Encryption alone hides data, but doesn't protect it: an attacker can flip bits of a ciphertext
+// and the receiver decrypts garbage, or worse, a meaningful change. AEAD ciphers like AES-GCM
+// encrypt and authenticate at once, Open fails if anything was modified.
+//
+// Rules of AES-GCM:
+// - a key is 16 or 32 random bytes,
+// - a nonce must never repeat for the same key, a random nonce of gcm.NonceSize() bytes is fine,
+//   it's not a secret, so it's stored in front of the ciphertext,
+// - associated data is authenticated but not encrypted, it binds a ciphertext to its context,
+//   for example, to the id of the record, so a ciphertext can't be moved to another record.
+//
+// Let's implement Seal and Open with crypto/aes and crypto/cipher.
+
+// ErrDecrypt is returned when a ciphertext can't be decrypted, the reason is never revealed.
+var ErrDecrypt = errors.New("failed to decrypt")
+
+// Seal encrypts the plaintext bound to the associated data and returns the nonce followed by the ciphertext.
+func Seal(key, plaintext, associated []byte) ([]byte, error) {
+	return plaintext, nil
+}
+
+// Open decrypts the output of Seal, it returns ErrDecrypt if the data or the associated data was changed.
+func Open(key, sealed, associated []byte) ([]byte, error) {
+	return sealed, nil
+}
+
+func TestSealOpen(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	plaintext := []byte("card number 4242 4242 4242 4242")
+
+	sealed, err := Seal(key, plaintext, []byte("user:1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bytes.Contains(sealed, []byte("4242")) {
+		t.Fatal("Expected the plaintext to be encrypted")
+	}
+
+	again, err := Seal(key, plaintext, []byte("user:1"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bytes.Equal(sealed, again) {
+		t.Error("Expected a new nonce for every message, got the same ciphertext twice")
+	}
+
+	got, err := Open(key, sealed, []byte("user:1"))
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Expected %q, got %q and error %v", plaintext, got, err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	for name, tt := range map[string]struct{ key, sealed, associated []byte }{
+		"tampered ciphertext":  {key, tampered, []byte("user:1")},
+		"other record":         {key, sealed, []byte("user:2")},
+		"wrong key":            {bytes.Repeat([]byte{8}, 32), sealed, []byte("user:1")},
+		"truncated ciphertext": {key, sealed[:5], []byte("user:1")},
+	} {
+		if _, err := Open(tt.key, tt.sealed, tt.associated); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%s: expected error to be %v, got %v", name, ErrDecrypt, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cryptobasics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Package crypto/... and golang.org/x/crypto/... cover most needs of an application:
+// - hashes like SHA-256 turn any input into a fixed-size digest, finding two inputs with the same digest is infeasible,
+// - HMAC proves that a message was produced by someone who knows a secret key,
+// - password hashing functions like bcrypt and Argon2 are deliberately slow to resist brute force.
+//
+// Never invent your own constructions, the exercises show how small mistakes break them.
+
+// 1. Streaming hashes.
+// hash.Hash is an io.Writer: data is written in chunks, and Sum appends the digest of everything written so far.
+// There is no need to load a file into memory to hash it.
+// Fix HashReader, so it hashes input of any size with constant memory.
+
+// HashReader returns the hex encoded SHA-256 digest of everything read from r.
+func HashReader(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// patternReader produces n bytes of a repeating pattern, so a large input doesn't need to be stored anywhere.
+type patternReader struct {
+	n   int
+	off int
+}
+
+func (p *patternReader) Read(b []byte) (int, error) {
+	if p.off == p.n {
+		return 0, io.EOF
+	}
+
+	b = b[:min(len(b), p.n-p.off)]
+	for i := range b {
+		b[i] = byte((p.off + i) % 251)
+	}
+
+	p.off += len(b)
+
+	return len(b), nil
+}
+
+func TestHashReader(t *testing.T) {
+	got, err := HashReader(strings.NewReader("hello, world\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := "853ff93762a06ddbf722c4ebe9ddd66d8f63ddaea97f521c3ecc20da7c976020"; got != expected {
+		t.Errorf("Expected digest %s, got %s", expected, got)
+	}
+}
+
+func TestHashReaderMemory(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+
+	got, err := HashReader(&patternReader{n: 64 << 20})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	if expected := "98dc891b284e4d84ac25b0c0a24fdbe39a7f0dbd643ad5e8aa06e02fc6258254"; got != expected {
+		t.Errorf("Expected digest %s, got %s", expected, got)
+	}
+
+	const limit = 256 << 10 // 256 KiB for 64 MiB of input
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > limit {
+		t.Errorf("Expected to allocate at most %d bytes while hashing, got %d", limit, allocated)
+	}
+}
+
+// 2. Constant-time comparison.
+// == on strings and bytes.Equal return as soon as they find the first different byte.
+// When a secret is compared with user input, the response time tells how many leading bytes of a guess are right,
+// and the secret can be guessed byte by byte. Secrets must be compared with functions that take the same time
+// for any input of the same length: crypto/subtle.ConstantTimeCompare or hmac.Equal.
+//
+// Fix CheckAPIKey.
+
+// CheckAPIKey reports whether the key provided by a client matches the expected one.
+func CheckAPIKey(provided, expected string) bool {
+	return provided == expected
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	if !CheckAPIKey("sk_live_42", "sk_live_42") {
+		t.Error("Expected equal keys to match")
+	}
+
+	for _, key := range []string{"", "sk_live_4", "sk_live_43", "sk_live_420"} {
+		if CheckAPIKey(key, "sk_live_42") {
+			t.Errorf("Expected %q not to match", key)
+		}
+	}
+}
+
+// minDuration returns the fastest of several runs of fn, which is the least affected by noise.
+func minDuration(runs int, fn func()) time.Duration {
+	fastest := time.Duration(1<<63 - 1)
+
+	for range runs {
+		start := time.Now()
+		fn()
+		fastest = min(fastest, time.Since(start))
+	}
+
+	return fastest
+}
+
+func TestCheckAPIKeyConstantTime(t *testing.T) {
+	// A huge key makes the difference visible, for a real key it's nanoseconds, but it's still measurable over a network.
+	expected := strings.Repeat("k", 1<<20)
+	wrongFirst := "x" + expected[1:]
+	wrongLast := expected[:len(expected)-1] + "x"
+
+	early := minDuration(50, func() { CheckAPIKey(wrongFirst, expected) })
+	late := minDuration(50, func() { CheckAPIKey(wrongLast, expected) })
+
+	// Comparison that stops at the first difference is thousands of times faster for wrongFirst.
+	if ratio := float64(early) / float64(late); ratio < 0.5 {
+		t.Errorf("Expected comparison to take the same time wherever the difference is, got %s for the first byte and %s for the last one", early, late)
+	}
+}
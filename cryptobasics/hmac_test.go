@@ -0,0 +1,126 @@
+package cryptobasics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 3. Signing webhooks with HMAC.
+// A service sends webhooks to its customers, and customers need to know the request really comes from the service.
+// Both sides share a secret, the sender signs the payload, and the receiver computes the signature again and compares.
+//
+// The signature header looks like this, it includes a timestamp to make replaying an old request useless:
+//
+//	t=1714557600,v1=<hex encoded signature of "1714557600.<payload>">
+//
+// sha256(secret + message) looks like a signature, but it's vulnerable to length extension:
+// knowing the digest of one message, an attacker can compute the digest of the message with extra data appended
+// without knowing the secret. HMAC (crypto/hmac) is the construction designed for this.
+//
+// Fix Sign to use HMAC-SHA256, and Verify to compare signatures in constant time and reject requests
+// with timestamps older than tolerance.
+
+var (
+	ErrMalformedHeader  = errors.New("malformed signature header")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("signature expired")
+)
+
+// Sign returns the signature header for the payload sent at the timestamp.
+func Sign(secret, payload []byte, timestamp time.Time) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	h := sha256.New()
+	h.Write(secret)
+	h.Write([]byte(ts + "."))
+	h.Write(payload)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify checks the signature header of the payload received at now.
+func Verify(secret, payload []byte, header string, now time.Time, tolerance time.Duration) error {
+	tsPart, sigPart, ok := strings.Cut(header, ",")
+	if !ok {
+		return ErrMalformedHeader
+	}
+
+	ts, ok := strings.CutPrefix(tsPart, "t=")
+	if !ok {
+		return ErrMalformedHeader
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedHeader, err)
+	}
+
+	if _, ok := strings.CutPrefix(sigPart, "v1="); !ok {
+		return ErrMalformedHeader
+	}
+
+	if Sign(secret, payload, time.Unix(sec, 0)) != header {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+var (
+	webhookSecret  = []byte("whsec_workshop")
+	webhookPayload = []byte(`{"id":"evt_1","type":"payment.succeeded","amount":2500}`)
+	webhookTime    = time.Unix(1714557600, 0)
+)
+
+func TestSign(t *testing.T) {
+	got := Sign(webhookSecret, webhookPayload, webhookTime)
+
+	expected := "t=1714557600,v1=1c1a1eb20747a61ef916544d82ce44ce3ffabb02f377845a7b5306fd7594045e"
+	if got != expected {
+		t.Errorf("Expected HMAC-SHA256 signature header %s, got %s", expected, got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	header := Sign(webhookSecret, webhookPayload, webhookTime)
+
+	tests := []struct {
+		name     string
+		secret   []byte
+		payload  []byte
+		header   string
+		now      time.Time
+		expected error
+	}{
+		{name: "valid", header: header, now: webhookTime.Add(time.Minute)},
+		{name: "tampered payload", payload: []byte(`{"id":"evt_1","type":"payment.succeeded","amount":25000}`), header: header, now: webhookTime, expected: ErrInvalidSignature},
+		{name: "wrong secret", secret: []byte("whsec_guess"), header: header, now: webhookTime, expected: ErrInvalidSignature},
+		{name: "forged signature", header: "t=1714557600,v1=" + strings.Repeat("0", 64), now: webhookTime, expected: ErrInvalidSignature},
+		{name: "replayed", header: header, now: webhookTime.Add(time.Hour), expected: ErrExpired},
+		{name: "from the future", header: header, now: webhookTime.Add(-time.Hour), expected: ErrExpired},
+		{name: "no timestamp", header: "v1=abc", now: webhookTime, expected: ErrMalformedHeader},
+		{name: "bad timestamp", header: "t=yesterday,v1=abc", now: webhookTime, expected: ErrMalformedHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, payload := webhookSecret, webhookPayload
+			if tt.secret != nil {
+				secret = tt.secret
+			}
+
+			if tt.payload != nil {
+				payload = tt.payload
+			}
+
+			if err := Verify(secret, payload, tt.header, tt.now, 5*time.Minute); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package cryptobasics
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 4. Password storage.
+// Passwords are never stored, only their hashes. But a fast hash like SHA-256 is the wrong tool:
+// - a GPU computes billions of SHA-256 per second, so common passwords are found almost instantly,
+// - without a salt, equal passwords have equal hashes, and one precomputed table cracks all users at once.
+//
+// Password hashing functions are salted and deliberately slow, with a cost parameter that grows with hardware.
+// bcrypt (golang.org/x/crypto/bcrypt) stores the cost and the salt in the hash itself:
+//
+//	$2a$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW
+//	 alg cost salt (22 chars)     hash
+//
+// Fix HashPassword and CheckPassword to use bcrypt with cost of at least passwordCost.
+// Note that bcrypt uses at most 72 bytes of a password and rejects longer ones.
+
+const passwordCost = 12
+
+// HashPassword returns a hash of the password suitable for storage.
+func HashPassword(password string) (string, error) {
+	sum := sha256.Sum256([]byte(password))
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckPassword reports whether the password matches the hash returned by HashPassword.
+func CheckPassword(hash, password string) bool {
+	sum := sha256.Sum256([]byte(password))
+
+	return hex.EncodeToString(sum[:]) == hash
+}
+
+func TestHashPassword(t *testing.T) {
+	first, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if first == second {
+		t.Errorf("Expected hashes of the same password to differ because of salt, got %s twice", first)
+	}
+
+	if cost, err := bcrypt.Cost([]byte(first)); err != nil || cost < passwordCost {
+		t.Errorf("Expected bcrypt hash with cost at least %d, got %s (%v)", passwordCost, first, err)
+	}
+
+	if !CheckPassword(first, "correct horse battery staple") || !CheckPassword(second, "correct horse battery staple") {
+		t.Error("Expected password to match its hashes")
+	}
+
+	if CheckPassword(first, "correct horse battery stapler") {
+		t.Error("Expected different password not to match")
+	}
+
+	if _, err := HashPassword(strings.Repeat("long", 20)); err == nil {
+		t.Error("Expected error for password longer than 72 bytes, got nil")
+	}
+}
+
+// Argon2id is the recommended password hashing function today. Besides time, it's expensive in memory,
+// which makes GPUs and custom hardware much less efficient. Its hashes are stored in the PHC string format:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<base64 salt>$<base64 hash>
+//
+// Parameters are stored with every hash, so they can be raised later: old hashes are still checked with their own
+// parameters and rehashed with the new ones when the user logs in.
+// CheckPasswordArgon2 ignores the stored parameters and always uses the current ones. Fix it.
+// Compare the hashes in constant time, like API keys.
+
+// Current Argon2id parameters: memory in KiB, iterations, parallelism, and sizes in bytes.
+const (
+	argonMemory  = 64 * 1024
+	argonTime    = 3
+	argonThreads = 2
+	argonSaltLen = 16
+	argonKeyLen  = 32
+)
+
+var ErrInvalidHash = errors.New("invalid argon2id hash")
+
+// HashPasswordArgon2 returns the PHC string of the password hashed with the current parameters.
+func HashPasswordArgon2(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPasswordArgon2 reports whether the password matches the PHC string.
+func CheckPasswordArgon2(encoded, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, uint32(len(expected)))
+
+	return string(key) == string(expected), nil
+}
+
+func TestArgon2RoundTrip(t *testing.T) {
+	encoded, err := HashPasswordArgon2("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if ok, err := CheckPasswordArgon2(encoded, "correct horse battery staple"); err != nil || !ok {
+		t.Errorf("Expected password to match %s, got %t, %v", encoded, ok, err)
+	}
+
+	if ok, err := CheckPasswordArgon2(encoded, "Tr0ub4dor&3"); err != nil || ok {
+		t.Errorf("Expected different password not to match, got %t, %v", ok, err)
+	}
+}
+
+func TestArgon2StoredParameters(t *testing.T) {
+	// Hashed with m=19456, t=2, p=1 long ago, when the parameters were lower.
+	const old = "$argon2id$v=19$m=19456,t=2,p=1$d29ya3Nob3Atc2FsdC0xNg$WZTN9XCizy2dYebLuSmx2q3CDmTF5FJqSAhX/vunNgc"
+
+	if ok, err := CheckPasswordArgon2(old, "correct horse battery staple"); err != nil || !ok {
+		t.Errorf("Expected password to match the hash with old parameters, got %t, %v", ok, err)
+	}
+
+	for _, encoded := range []string{
+		"$argon2i$v=19$m=19456,t=2,p=1$d29ya3Nob3Atc2FsdC0xNg$WZTN9XCizy2dYebLuSmx2q3CDmTF5FJqSAhX/vunNgc",
+		"$argon2id$v=19$m=lots,t=2,p=1$d29ya3Nob3Atc2FsdC0xNg$WZTN9XCizy2dYebLuSmx2q3CDmTF5FJqSAhX/vunNgc",
+		"$argon2id$v=19$m=19456,t=2$d29ya3Nob3Atc2FsdC0xNg$WZTN9XCizy2dYebLuSmx2q3CDmTF5FJqSAhX/vunNgc",
+	} {
+		if _, err := CheckPasswordArgon2(encoded, "correct horse battery staple"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Expected ErrInvalidHash for %s, got %v", encoded, err)
+		}
+	}
+}
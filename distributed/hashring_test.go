@@ -0,0 +1,248 @@
+package distributed
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// 1. Consistent Hashing.
+// A cache spread over N servers needs to know which server holds a key. The obvious way is hash(key) % N,
+// and it works until a server is added or removed: N changes, and almost every key maps to another server.
+// For a cache it means a sudden wave of misses that hits the database, right when the cluster is changing.
+//
+// Consistent hashing puts nodes and keys on the same ring of hash values. A key belongs to the first node
+// clockwise from its hash, so a new node takes keys only from its neighbor, and a removed node gives
+// its keys only to the next one: about 1/N of the keys move instead of almost all of them.
+//
+// With a few points on the ring, nodes get arcs of very different lengths, and some get much more keys
+// than others. Every node is placed on the ring many times, as virtual nodes "node#0", "node#1", ...,
+// and the arcs even out.
+//
+// Ring below is the hash(key) % N version, and it's not safe for concurrent use. Let's rewrite it:
+//   - Keep the hashes of virtual nodes in a sorted slice, and the node of every hash in a map.
+//   - Get finds the first hash not less than the hash of the key with sort.Search or slices.BinarySearch,
+//     and wraps around to the first one past the end of the ring.
+//   - Add and Remove change the ring under sync.RWMutex, Get only reads it, so it takes the read lock
+//     and many Gets run in parallel.
+
+// Ring maps keys to nodes, it's safe for concurrent use.
+type Ring struct {
+	replicas int
+	nodes    []string
+}
+
+// NewRing creates an empty ring that places every node on it replicas times.
+func NewRing(replicas int) *Ring {
+	return &Ring{replicas: replicas}
+}
+
+// Add adds the node to the ring, adding it twice does nothing.
+func (r *Ring) Add(node string) {
+	if !slices.Contains(r.nodes, node) {
+		r.nodes = append(r.nodes, node)
+	}
+}
+
+// Remove removes the node from the ring.
+func (r *Ring) Remove(node string) {
+	r.nodes = slices.DeleteFunc(r.nodes, func(n string) bool { return n == node })
+}
+
+// Get returns the node of the key, ok is false if the ring is empty.
+func (r *Ring) Get(key string) (node string, ok bool) {
+	nodes := r.nodes
+	if len(nodes) == 0 {
+		return "", false
+	}
+
+	return nodes[ringHash(key)%uint64(len(nodes))], true
+}
+
+// ringHash is the position of a key or a virtual node on the ring.
+// FNV alone maps similar strings like "cache-1#7" and "cache-1#8" close to each other,
+// the finalizer of SplitMix64 mixes the bits so they spread over the whole ring.
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	x := h.Sum64()
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+
+	return x ^ x>>31
+}
+
+// virtualNode is the name of the i-th replica of the node on the ring.
+func virtualNode(node string, i int) string {
+	return fmt.Sprintf("%s#%d", node, i)
+}
+
+func ringKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%d", i)
+	}
+
+	return keys
+}
+
+func newTestRing(replicas, nodes int) *Ring {
+	r := NewRing(replicas)
+	for i := range nodes {
+		r.Add(fmt.Sprintf("cache-%d", i))
+	}
+
+	return r
+}
+
+// owners returns the node of every key.
+func owners(r *Ring, keys []string) map[string]string {
+	m := make(map[string]string, len(keys))
+	for _, key := range keys {
+		m[key], _ = r.Get(key)
+	}
+
+	return m
+}
+
+func TestRingGet(t *testing.T) {
+	g := grader.New(t)
+
+	r := NewRing(50)
+
+	_, ok := r.Get("user:1")
+	g.Equal(`Get("user:1") on an empty ring`, false, ok)
+
+	r.Add("cache-0")
+	r.Add("cache-0")
+
+	for _, key := range ringKeys(100) {
+		if node, ok := r.Get(key); !g.Check(fmt.Sprintf("Get(%q)", key), ok && node == "cache-0", "expected cache-0, the only node, got %q", node) {
+			break
+		}
+	}
+
+	r.Add("cache-1")
+	r.Remove("cache-0")
+
+	node, ok := r.Get("user:1")
+	g.Equal(`Get("user:1") after cache-0 is removed`, "cache-1", node)
+	g.Equal(`Get("user:1") after cache-0 is removed`, true, ok)
+}
+
+func TestRingMinimalMovement(t *testing.T) {
+	g := grader.New(t)
+
+	keys := ringKeys(10000)
+	r := newTestRing(100, 10)
+	before := owners(r, keys)
+
+	// A new node takes its keys from others, other keys stay where they are.
+	r.Add("cache-10")
+	after := owners(r, keys)
+
+	moved, wrong := 0, ""
+
+	for _, key := range keys {
+		if before[key] == after[key] {
+			continue
+		}
+
+		moved++
+
+		if after[key] != "cache-10" && wrong == "" {
+			wrong = fmt.Sprintf("%s moved from %s to %s", key, before[key], after[key])
+		}
+	}
+
+	g.Hint("a key moves only to the new node, from its neighbor on the ring").Check("Add(cache-10)", wrong == "", "%s", wrong)
+
+	// 1/11 of keys is expected to move, the limit leaves room for uneven arcs.
+	g.Hint("with hash(key) % N almost every key moves when N changes").Check("Add(cache-10)",
+		moved < len(keys)*15/100, "%d of %d keys moved, expected about 1/11 of them", moved, len(keys))
+
+	// A removed node gives its keys to others, other keys stay where they are.
+	r.Remove("cache-3")
+	removed := owners(r, keys)
+
+	for _, key := range keys {
+		if after[key] != "cache-3" && removed[key] != after[key] {
+			g.Hint("only keys of the removed node move").Check("Remove(cache-3)",
+				false, "%s moved from %s to %s", key, after[key], removed[key])
+
+			break
+		}
+
+		if removed[key] == "cache-3" {
+			g.Check("Remove(cache-3)", false, "%s still maps to the removed node", key)
+			break
+		}
+	}
+}
+
+func TestRingBalance(t *testing.T) {
+	g := grader.New(t)
+
+	const nodes = 10
+
+	keys := ringKeys(100000)
+	load := make(map[string]int)
+
+	for _, node := range owners(newTestRing(200, nodes), keys) {
+		load[node]++
+	}
+
+	g.Equal("number of nodes with keys", nodes, len(load))
+
+	// Virtual nodes keep every node within 30% of the average load.
+	avg := len(keys) / nodes
+
+	for node, n := range load {
+		g.Hint("place every node on the ring replicas times, with virtualNode names").Check("load of "+node,
+			n > avg*7/10 && n < avg*13/10, "%s got %d keys, the average is %d", node, n, avg)
+	}
+}
+
+func TestRingConcurrent(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	r := newTestRing(50, 5)
+	keys := ringKeys(1000)
+	wg := sync.WaitGroup{}
+
+	for i := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for _, key := range keys {
+				if _, ok := r.Get(key); !ok {
+					t.Errorf("Expected %s to have a node, the ring is never empty", key)
+					return
+				}
+			}
+		}()
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			node := fmt.Sprintf("extra-%d", i)
+
+			for range 50 {
+				r.Add(node)
+				r.Remove(node)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
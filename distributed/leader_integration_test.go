@@ -0,0 +1,103 @@
+//go:build integration
+
+package distributed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ksysoev/go-workshops/testsync"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// startPostgres starts PostgreSQL in a container, the container is removed when the test ends.
+func startPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	ctr, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("workshop"),
+		postgres.WithUsername("workshop"),
+		postgres.WithPassword("workshop"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(time.Minute),
+		),
+	)
+
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(ctr); err != nil {
+			t.Errorf("Failed to remove the container: %v", err)
+		}
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL, is Docker running? %v", err)
+	}
+
+	dsn, err := ctr.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// terminateHolder ends the session that holds the advisory lock of the key, like a network partition
+// or an administrator would. The pool of db reconnects, the leader's session is gone for good.
+func terminateHolder(t *testing.T, db *sql.DB) func(key int64) {
+	return func(key int64) {
+		_, err := db.Exec(`SELECT pg_terminate_backend(pid) FROM pg_locks
+			WHERE locktype = 'advisory' AND granted AND objsubid = 1
+			AND (classid::bigint << 32 | objid::bigint) = $1`, key)
+		if err != nil {
+			t.Fatalf("Failed to terminate the session of lock %d: %v", key, err)
+		}
+	}
+}
+
+func TestPostgresLocker(t *testing.T) {
+	db := startPostgres(t)
+	testLocker(t, newPGLocker(db), terminateHolder(t, db))
+}
+
+func TestPostgresElector(t *testing.T) {
+	db := startPostgres(t)
+	watch := newLeaders()
+
+	// Electors share the pool, every lock must take a session of its own.
+	for i := range 3 {
+		campaign(t, newPGLocker(db), fmt.Sprintf("node-%d", i), watch)
+	}
+
+	if !testsync.WaitUntil(func() bool { return watch.Leader() != "" }, 5*time.Second) {
+		t.Fatal("Expected one of the electors to become the leader")
+	}
+
+	first := watch.Leader()
+	terminateHolder(t, db)(42)
+
+	// The old leader may win the lock again, as a new session.
+	if !testsync.WaitUntil(func() bool {
+		l := watch.Leader()
+		return l != "" && (l != first || watch.Terms(first) > 1)
+	}, 5*time.Second) {
+		t.Fatalf("Expected a new leader after the session of %s ended", first)
+	}
+
+	if overlap := watch.Overlap(); overlap != "" {
+		t.Logf("Two leaders overlapped after the session ended, Renew can only narrow the window: %s", overlap)
+	}
+}
@@ -0,0 +1,455 @@
+package distributed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// 2. Leader Election.
+// Some jobs must run on exactly one instance of a service: a scheduler, a cleanup of expired rows,
+// a relay of the outbox. Every instance campaigns for a lock, the one that takes it is the leader
+// and runs the job, the others keep trying, and one of them takes over when the leader goes away.
+//
+// PostgreSQL has such locks built in. pg_try_advisory_lock(key) takes a lock on a number, without
+// waiting, and it's held by the session that took it until pg_advisory_unlock(key), or until the session
+// ends: a crashed leader releases its lock when its connection drops, nobody has to clean up after it.
+// The lock belongs to a session, and database/sql hides sessions behind a pool. pgLocker below runs every
+// query on whatever connection the pool hands out, so:
+//   - TryLock of a key the pool's connection already holds succeeds again, advisory locks are reentrant.
+//   - Unlock runs on another connection and unlocks nothing, the lock stays until the pool closes
+//     the connection that took it, which may never happen.
+//   - Renew pings the pool, it can't tell whether the session with the lock is still alive.
+//
+// Fix pgLocker: take a dedicated connection for every lock with db.Conn, run all queries of the lock on it,
+// and close it after unlocking. Renew checks that the session still holds the lock, pg_locks shows
+// advisory locks of the session: classid and objid are the high and low 32 bits of the key.
+//
+// A leader that lost its lock and doesn't know it is worse than no leader: another instance takes the lock
+// and two of them run the job. Elector.Run below takes the lock once and leads forever. Rewrite it:
+//   - Followers retry TryLock every RetryInterval, until ctx is canceled. Errors other than ErrLocked,
+//     like a database that is down, are retried too.
+//   - The leader runs lead with a context that is canceled when leadership ends, and calls Renew every
+//     RenewInterval. When Renew fails, the lock is lost: cancel lead's context and campaign again.
+//   - Leadership ends when ctx is canceled or lead returns as well. Wait for lead to return before
+//     unlocking, or the next leader starts while the old one is still running.
+//   - Run returns ctx.Err() when ctx is canceled.
+//
+// Even a perfect Elector can't stop a leader paused by GC or a slow disk between its last Renew and its next
+// write. Systems that can't tolerate that pass a fencing token, a number that grows with every new leader,
+// with every write, and storage rejects writes with an older token.
+//
+// The unit tests run Elector with memLocker, an in-memory fake that can break a lock the way a database
+// drops a session. pgLocker has its own integration tests, they need Docker:
+//
+//	go test -tags=integration -run TestPostgres ./distributed
+
+var (
+	// ErrLocked is returned by TryLock when another session holds the lock.
+	ErrLocked = errors.New("lock is held by another session")
+
+	// ErrLockLost is returned by Renew when the lock is no longer held.
+	ErrLockLost = errors.New("lock is lost")
+)
+
+// Locker takes exclusive locks on keys.
+type Locker interface {
+	// TryLock takes the lock of the key without waiting, it returns ErrLocked if the lock is taken.
+	TryLock(ctx context.Context, key int64) (Lock, error)
+}
+
+// Lock is a lock held by a session.
+type Lock interface {
+	// Renew keeps the session alive and checks that it still holds the lock, it returns an error if it doesn't.
+	Renew(ctx context.Context) error
+
+	// Unlock releases the lock and ends its session.
+	Unlock(ctx context.Context) error
+}
+
+// pgLocker takes PostgreSQL advisory locks.
+type pgLocker struct {
+	db *sql.DB
+}
+
+func newPGLocker(db *sql.DB) *pgLocker {
+	return &pgLocker{db: db}
+}
+
+func (l *pgLocker) TryLock(ctx context.Context, key int64) (Lock, error) {
+	var ok bool
+
+	if err := l.db.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		return nil, fmt.Errorf("lock %d: %w", key, err)
+	}
+
+	if !ok {
+		return nil, ErrLocked
+	}
+
+	return &pgLock{db: l.db, key: key}, nil
+}
+
+// pgLock is an advisory lock of a key.
+type pgLock struct {
+	db  *sql.DB
+	key int64
+}
+
+func (l *pgLock) Renew(ctx context.Context) error {
+	if err := l.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("renew lock %d: %w", l.key, err)
+	}
+
+	return nil
+}
+
+func (l *pgLock) Unlock(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		return fmt.Errorf("unlock %d: %w", l.key, err)
+	}
+
+	return nil
+}
+
+// Elector campaigns for leadership with a lock of Key.
+type Elector struct {
+	Locker Locker
+	Key    int64
+
+	// RetryInterval is how often a follower tries to take the lock.
+	RetryInterval time.Duration
+
+	// RenewInterval is how often the leader renews the lock.
+	RenewInterval time.Duration
+}
+
+// Run campaigns for leadership until ctx is canceled, and runs lead every time it becomes the leader.
+// The context of lead is canceled when leadership ends.
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) error {
+	lock, err := e.Locker.TryLock(ctx, e.Key)
+	if err != nil {
+		return err
+	}
+
+	defer lock.Unlock(context.WithoutCancel(ctx))
+
+	lead(ctx)
+
+	return ctx.Err()
+}
+
+// memLocker is an in-memory Locker, every lock is a session of its own.
+type memLocker struct {
+	mu       sync.Mutex
+	held     map[int64]*memLock
+	renewals atomic.Int64
+}
+
+func newMemLocker() *memLocker {
+	return &memLocker{held: make(map[int64]*memLock)}
+}
+
+func (l *memLocker) TryLock(ctx context.Context, key int64) (Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.held[key]; ok {
+		return nil, ErrLocked
+	}
+
+	lock := &memLock{locker: l, key: key}
+	l.held[key] = lock
+
+	return lock, nil
+}
+
+// Break releases the lock of the key behind the back of its holder, like a database that dropped the session.
+func (l *memLocker) Break(key int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.held, key)
+}
+
+type memLock struct {
+	locker *memLocker
+	key    int64
+}
+
+func (l *memLock) Renew(ctx context.Context) error {
+	l.locker.renewals.Add(1)
+
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if l.locker.held[l.key] != l {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+func (l *memLock) Unlock(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if l.locker.held[l.key] == l {
+		delete(l.locker.held, l.key)
+	}
+
+	return nil
+}
+
+// testLocker is the contract of Locker, breakLock ends the session that holds the lock of the key.
+func testLocker(t *testing.T, l Locker, breakLock func(key int64)) {
+	ctx := context.Background()
+
+	first, err := l.TryLock(ctx, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := l.TryLock(ctx, 1); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked for a held lock, got %v", err)
+	}
+
+	other, err := l.TryLock(ctx, 2)
+	if err != nil {
+		t.Fatalf("Expected a lock of another key to be free, got %v", err)
+	}
+
+	defer other.Unlock(ctx)
+
+	if err := first.Renew(ctx); err != nil {
+		t.Errorf("Expected Renew of a held lock to succeed, got %v", err)
+	}
+
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second, err := l.TryLock(ctx, 1)
+	if err != nil {
+		t.Fatalf("Expected the lock to be free after Unlock, got %v", err)
+	}
+
+	breakLock(1)
+
+	if err := second.Renew(ctx); err == nil {
+		t.Error("Expected Renew to fail after the session of the lock ended")
+	}
+
+	second.Unlock(ctx)
+
+	third, err := l.TryLock(ctx, 1)
+	if err != nil {
+		t.Fatalf("Expected the lock to be free after its session ended, got %v", err)
+	}
+
+	third.Unlock(ctx)
+}
+
+func TestMemLocker(t *testing.T) {
+	l := newMemLocker()
+	testLocker(t, l, l.Break)
+}
+
+// leaders watches electors, and records whether two of them ever led at once.
+type leaders struct {
+	mu      sync.Mutex
+	current map[string]bool
+	terms   map[string]int
+	overlap string
+}
+
+func newLeaders() *leaders {
+	return &leaders{current: make(map[string]bool), terms: make(map[string]int)}
+}
+
+// lead returns the lead function of the elector, it leads until its context is canceled.
+func (l *leaders) lead(name string) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		l.mu.Lock()
+		for other := range l.current {
+			if l.overlap == "" {
+				l.overlap = fmt.Sprintf("%s became the leader while %s was still leading", name, other)
+			}
+		}
+
+		l.current[name] = true
+		l.terms[name]++
+		l.mu.Unlock()
+
+		<-ctx.Done()
+
+		l.mu.Lock()
+		delete(l.current, name)
+		l.mu.Unlock()
+	}
+}
+
+// Leader returns the current leader, or "" if there's none.
+func (l *leaders) Leader() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for name := range l.current {
+		return name
+	}
+
+	return ""
+}
+
+// Terms returns how many times the elector became the leader.
+func (l *leaders) Terms(name string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.terms[name]
+}
+
+func (l *leaders) Overlap() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.overlap
+}
+
+// errStillRunning is returned by stop of campaign when Run doesn't return after its context is canceled.
+var errStillRunning = errors.New("run didn't return after its context was canceled")
+
+// campaign runs an elector until stop is called or the test ends, stop returns the result of Run.
+func campaign(t *testing.T, locker Locker, name string, watch *leaders) (stop func() error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	e := &Elector{Locker: locker, Key: 42, RetryInterval: 5 * time.Millisecond, RenewInterval: 5 * time.Millisecond}
+
+	go func() {
+		done <- e.Run(ctx, watch.lead(name))
+	}()
+
+	stop = sync.OnceValue(func() error {
+		cancel()
+
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(time.Second):
+			return errStillRunning
+		}
+	})
+
+	t.Cleanup(func() {
+		if err := stop(); errors.Is(err, errStillRunning) {
+			t.Errorf("Expected Run of %s to return after its context is canceled", name)
+		}
+	})
+
+	return stop
+}
+
+func TestElectorFailover(t *testing.T) {
+	locker := newMemLocker()
+	watch := newLeaders()
+	stops := make(map[string]func() error)
+
+	for i := range 3 {
+		name := fmt.Sprintf("node-%d", i)
+		stops[name] = campaign(t, locker, name, watch)
+	}
+
+	if !testsync.WaitUntil(func() bool { return watch.Leader() != "" }, time.Second) {
+		t.Fatal("Expected one of the electors to become the leader")
+	}
+
+	// The leader shuts down, one of the followers takes over.
+	first := watch.Leader()
+
+	if err := stops[first](); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Run to return context.Canceled, got %v", err)
+	}
+
+	if !testsync.WaitUntil(func() bool { return watch.Leader() != "" }, time.Second) {
+		t.Fatalf("Expected a follower to take over after %s stopped, followers must keep retrying", first)
+	}
+
+	if overlap := watch.Overlap(); overlap != "" {
+		t.Errorf("Expected at most one leader at a time, %s", overlap)
+	}
+
+	if second := watch.Leader(); second == first {
+		t.Errorf("Expected a new leader, got %s again after it stopped", second)
+	}
+}
+
+func TestElectorLockLost(t *testing.T) {
+	locker := newMemLocker()
+	watch := newLeaders()
+
+	campaign(t, locker, "node-0", watch)
+
+	if !testsync.WaitUntil(func() bool { return watch.Leader() == "node-0" }, time.Second) {
+		t.Fatal("Expected node-0 to become the leader")
+	}
+
+	// A healthy leader renews its lock and keeps leading.
+	if !testsync.WaitUntil(func() bool { return locker.renewals.Load() >= 3 }, time.Second) {
+		t.Fatal("Expected the leader to renew its lock every RenewInterval")
+	}
+
+	if terms := watch.Terms("node-0"); terms != 1 {
+		t.Fatalf("Expected the leader to keep leading while its lock is held, it became the leader %d times", terms)
+	}
+
+	// The database drops the session, the leader must notice and stop leading.
+	locker.Break(42)
+
+	if !testsync.WaitUntil(func() bool { return watch.Terms("node-0") > 1 || watch.Leader() == "" }, time.Second) {
+		t.Fatal("Expected the context of lead to be canceled once Renew fails")
+	}
+
+	// The lock is free again, and the elector takes it back.
+	if !testsync.WaitUntil(func() bool { return watch.Terms("node-0") == 2 && watch.Leader() == "node-0" }, time.Second) {
+		t.Fatalf("Expected node-0 to campaign again and lead for the second time, it led %d times", watch.Terms("node-0"))
+	}
+}
+
+func TestElectorRetriesErrors(t *testing.T) {
+	watch := newLeaders()
+	locker := &flakyLocker{Locker: newMemLocker(), failures: 3}
+
+	campaign(t, locker, "node-0", watch)
+
+	if !testsync.WaitUntil(func() bool { return watch.Leader() == "node-0" }, time.Second) {
+		t.Fatalf("Expected the elector to retry after errors of TryLock, it tried %d times", locker.calls.Load())
+	}
+}
+
+// flakyLocker fails the first TryLock calls, like a database that is still starting.
+type flakyLocker struct {
+	Locker
+	failures int64
+	calls    atomic.Int64
+}
+
+func (l *flakyLocker) TryLock(ctx context.Context, key int64) (Lock, error) {
+	if l.calls.Add(1) <= l.failures {
+		return nil, errors.New("connection refused")
+	}
+
+	return l.Locker.TryLock(ctx, key)
+}
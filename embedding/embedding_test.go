@@ -0,0 +1,174 @@
+package embedding
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The //go:embed directive puts files into the compiled binary, so a program can be shipped as a single file.
+// The directive is placed right above a package level variable of one of three types:
+// - string or []byte for a single file,
+// - embed.FS for a set of files and directories, it implements fs.FS and is read only.
+//
+// Paths are relative to the directory of the source file, they can't contain .. and can't leave the module.
+// The files are read at build time, the running program never looks at the disk for them.
+
+// Version should return the version from version.txt, embed it.
+// Note that the file ends with a newline, like most text files do.
+
+var version string
+
+// Version returns the version of the site.
+func Version() string {
+	return version
+}
+
+func TestVersion(t *testing.T) {
+	if got := Version(); got != "v1.4.2" {
+		t.Errorf("Expected version to be %q, got %q", "v1.4.2", got)
+	}
+}
+
+// A directory is embedded with all its files recursively, except files and directories
+// whose names start with . or _ because they are usually editor backups, VCS data, and the like.
+// Sometimes these files are needed though, for example .well-known directory on a web site.
+// Fix staticFS, so it contains every file in static directory.
+
+//go:embed static
+var staticFS embed.FS
+
+func TestStaticFiles(t *testing.T) {
+	var files []string
+
+	err := fs.WalkDir(staticFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"static/.well-known/security.txt", "static/app.js", "static/style.css"}
+	if !slices.Equal(files, expected) {
+		t.Errorf("Expected embedded files to be %v, got %v", expected, files)
+	}
+}
+
+// embed.FS works anywhere fs.FS is accepted: http.FileServerFS serves files from it,
+// and template.ParseFS parses templates from it.
+// Keep in mind that paths in embed.FS include the embedded directory, fs.Sub strips it.
+//
+// NewHandler reads static files and templates from the working directory at run time,
+// so the site breaks as soon as the binary is started anywhere except the source directory.
+// Fix it to serve everything from the embedded files: static files under /assets/ and the index page at /.
+
+//go:embed templates
+var templatesFS embed.FS
+
+type indexPage struct {
+	Title   string
+	Version string
+}
+
+// NewHandler returns the handler of the site.
+func NewHandler() (http.Handler, error) {
+	tmpl, err := template.ParseFiles("templates/index.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("static"))))
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		page := indexPage{Title: "Go Workshops", Version: Version()}
+
+		if err := tmpl.ExecuteTemplate(w, "index.tmpl", page); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	return mux, nil
+}
+
+// chdir changes the working directory for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("Failed to restore working directory: %v", err)
+		}
+	})
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	body, err := io.ReadAll(rec.Result().Body)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return rec.Code, string(body)
+}
+
+func TestHandlerServesEmbeddedFiles(t *testing.T) {
+	// There is nothing in the empty directory, everything must come from the binary.
+	chdir(t, t.TempDir())
+
+	h, err := NewHandler()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"static/style.css", "static/app.js"} {
+		expected, err := staticFS.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		url := "/assets/" + strings.TrimPrefix(path, "static/")
+
+		code, body := get(t, h, url)
+		if code != http.StatusOK || body != string(expected) {
+			t.Errorf("Expected %s to return %d with content of %s, got %d: %s", url, http.StatusOK, path, code, body)
+		}
+	}
+
+	code, body := get(t, h, "/")
+	if code != http.StatusOK || !strings.Contains(body, "<p>Version v1.4.2</p>") {
+		t.Errorf("Expected index page with the version, got %d: %s", code, body)
+	}
+
+	if code, _ := get(t, h, "/missing"); code != http.StatusNotFound {
+		t.Errorf("Expected %d for unknown page, got %d", http.StatusNotFound, code)
+	}
+}
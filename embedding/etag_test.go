@@ -0,0 +1,75 @@
+package embedding
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+// Advanced: caching embedded files.
+//
+// Files of embed.FS have zero modification time, so http.FileServer can't send Last-Modified,
+// and browsers download every asset again on every page load.
+// Embedded files never change while the program runs, a hash of the content is a perfect ETag.
+// A browser sends it back in If-None-Match, and the server answers 304 Not Modified without the body.
+//
+// Let's implement CachingFileServer that sets ETag of every file to a quoted hex encoded SHA-256 of its content.
+// Hashes should be computed once, not on every request, and http.ServeContent already knows how
+// to compare the ETag header of the response with If-None-Match of the request.
+
+// CachingFileServer serves files of fsys with ETag headers based on their content.
+func CachingFileServer(fsys fs.FS) (http.Handler, error) {
+	return http.FileServerFS(fsys), nil
+}
+
+func TestCachingFileServer(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":        {Data: []byte("console.log('hi')\n")},
+		"css/style.css": {Data: []byte("body { margin: 0 }\n")},
+	}
+
+	h, err := CachingFileServer(fsys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	serve := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	first := serve("/css/style.css", "")
+	if first.Code != http.StatusOK || first.Body.String() != "body { margin: 0 }\n" {
+		t.Fatalf("Expected the file, got %d %q", first.Code, first.Body.String())
+	}
+
+	sum := sha256.Sum256(fsys["css/style.css"].Data)
+
+	etag := first.Header().Get("ETag")
+	if expected := `"` + hex.EncodeToString(sum[:]) + `"`; etag != expected {
+		t.Fatalf("Expected ETag %s, got %q", expected, etag)
+	}
+
+	if rec := serve("/css/style.css", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected 304 Not Modified without body for a cached file, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve("/app.js", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected another file to have another ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	if rec := serve("/missing.js", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing file, got %d", rec.Code)
+	}
+}
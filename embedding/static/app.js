@@ -0,0 +1,3 @@
+document.addEventListener("DOMContentLoaded", () => {
+  console.log("Go Workshops");
+});
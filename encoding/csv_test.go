@@ -0,0 +1,220 @@
+package encoding
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Package encoding/csv reads and writes comma-separated values as described in RFC 4180.
+// CSV looks simple, but real files are messy:
+// - Fields can be quoted, quoted fields can contain commas, new lines, and quotes escaped by doubling them: "The ""Gopher"" shop".
+// - Rows can have a wrong number of fields, broken quotes, or values that don't make sense.
+//
+// Splitting lines by commas breaks on the first quoted field, use csv.Reader instead.
+// csv.Reader.ReadAll stops on the first error, but a bad row in a bank statement shouldn't hide all other rows.
+// Read records one by one with Read, it's possible to continue after a *csv.ParseError.
+
+//go:embed testdata/transactions.csv
+var transactionsCSV string
+
+// update rewrites golden files with the current output: go test ./encoding -run CSV -update
+var update = flag.Bool("update", false, "update golden files")
+
+// Transaction is a row of the transactions file.
+type Transaction struct {
+	ID          string
+	Date        string
+	Account     string
+	Description string
+	Amount      int64 // in cents
+	Currency    string
+}
+
+// RowError is an error in a row that was parsed as CSV, but has invalid values.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrInvalidAmount is returned for amounts that are not decimal numbers with two digits after the point.
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// ErrUnknownCurrency is returned for currencies other than EUR and USD.
+	ErrUnknownCurrency = errors.New("unknown currency")
+)
+
+// parseAmount parses a decimal amount with two digits after the point, e.g. "-12.50", into cents.
+// Money is never stored in floats, 0.1 + 0.2 != 0.3 in floating point.
+func parseAmount(s string) (int64, error) {
+	whole, frac, ok := strings.Cut(s, ".")
+	if !ok || len(frac) != 2 || strings.HasPrefix(frac, "-") {
+		return 0, fmt.Errorf("%w %q", ErrInvalidAmount, s)
+	}
+
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q", ErrInvalidAmount, s)
+	}
+
+	return n, nil
+}
+
+// formatAmount formats cents as a decimal amount with two digits after the point.
+func formatAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// ParseTransactions reads transactions from CSV with a header row.
+// It returns all valid transactions and an error joining the errors of all invalid rows with errors.Join.
+// Every row error should tell the line where the row starts:
+// *csv.ParseError already does it, values with invalid amount or currency should be wrapped in *RowError,
+// csv.Reader.FieldPos returns the line of a field of the last record.
+// Let's try to fix it, so one bad row doesn't stop the parsing.
+func ParseTransactions(r io.Reader) ([]Transaction, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []Transaction
+
+	for _, rec := range records[1:] {
+		amount, _ := parseAmount(rec[4])
+
+		txs = append(txs, Transaction{
+			ID:          rec[0],
+			Date:        rec[1],
+			Account:     rec[2],
+			Description: rec[3],
+			Amount:      amount,
+			Currency:    rec[5],
+		})
+	}
+
+	return txs, nil
+}
+
+// Summary is the total of transactions of an account in a currency.
+type Summary struct {
+	Account  string
+	Currency string
+	Count    int
+	Total    int64 // in cents
+}
+
+// Summarize aggregates transactions by account and currency using a pool of workers.
+// The result is sorted by account and currency.
+// Hint: split transactions into chunks, let every worker aggregate its chunk into a local map,
+// and merge the partial results at the end, so workers don't contend on a shared map.
+func Summarize(txs []Transaction, workers int) []Summary {
+	return nil
+}
+
+// WriteSummary writes summaries as CSV with the header account,currency,count,total.
+// csv.Writer is buffered, don't forget to flush it and check its Error.
+func WriteSummary(w io.Writer, summaries []Summary) error {
+	return nil
+}
+
+// Golden files keep the expected output of a test next to it in testdata.
+// When the output changes on purpose, run the test with -update flag and review the diff of the golden file.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		t.Errorf("Output doesn't match %s, expected:\n%s\ngot:\n%s", path, expected, got)
+	}
+}
+
+func TestParseTransactionsCSV(t *testing.T) {
+	txs, err := ParseTransactions(strings.NewReader(transactionsCSV))
+
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || !errors.Is(err, ErrInvalidAmount) || !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Expected errors of all bad rows to be joined, got %v", err)
+	}
+
+	if err != nil {
+		assertGolden(t, "errors.golden", []byte(err.Error()+"\n"))
+	}
+
+	if len(txs) != 26 {
+		t.Fatalf("Expected 26 valid transactions, got %d", len(txs))
+	}
+
+	for _, tx := range txs {
+		if tx.ID == "t003" && tx.Description != `The "Gopher" shop` {
+			t.Errorf("Expected quotes in description to be unescaped, got %s", tx.Description)
+		}
+
+		if tx.ID == "t008" && tx.Description != "Refund\nfor order 42" {
+			t.Errorf("Expected multiline description to be kept, got %q", tx.Description)
+		}
+	}
+}
+
+func TestSummaryCSV(t *testing.T) {
+	txs, _ := ParseTransactions(strings.NewReader(transactionsCSV))
+
+	buf := &bytes.Buffer{}
+
+	if err := WriteSummary(buf, Summarize(txs, 4)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertGolden(t, "summary.golden.csv", buf.Bytes())
+}
+
+func TestSummarizeWorkers(t *testing.T) {
+	txs, _ := ParseTransactions(strings.NewReader(transactionsCSV))
+	expected := Summarize(txs, 1)
+
+	if len(expected) == 0 {
+		t.Fatal("Expected summaries, got none")
+	}
+
+	for _, workers := range []int{2, 3, 8, 100} {
+		got := Summarize(txs, workers)
+
+		if fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("Expected the same summaries with %d workers:\n%v\ngot:\n%v", workers, expected, got)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+// JSON is the default choice for APIs, but it's not the only serialization format in the standard library.
+// - encoding/json is human readable and language neutral, but verbose and relatively slow.
+// - encoding/gob is a self-describing binary format for communication between Go programs.
+//   The first message of a stream carries type information, the following ones are compact.
+// - encoding/binary translates fixed-size values to bytes and back with a given byte order.
+//   There is no type information at all, both sides must agree on the layout.
+//
+// Let's implement the same round trip with all three and compare them:
+//
+//	go test -run '^$' -bench Reading -benchmem ./encoding
+
+// Message is an event passed between services.
+type Message struct {
+	ID      uint64
+	Sent    time.Time
+	Tags    []string
+	Attrs   map[string]string
+	Payload []byte
+}
+
+// EncodeJSON encodes the message as JSON, it's the baseline.
+func EncodeJSON(m Message) ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// DecodeJSON decodes the message from JSON.
+func DecodeJSON(data []byte) (Message, error) {
+	var m Message
+	err := json.Unmarshal(data, &m)
+
+	return m, err
+}
+
+// EncodeGob encodes the message with encoding/gob.
+func EncodeGob(m Message) ([]byte, error) {
+	return nil, nil
+}
+
+// DecodeGob decodes the message encoded by EncodeGob.
+func DecodeGob(data []byte) (Message, error) {
+	return Message{}, nil
+}
+
+func testMessage() Message {
+	return Message{
+		ID:      42,
+		Sent:    time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Tags:    []string{"signup", "web"},
+		Attrs:   map[string]string{"country": "NL"},
+		Payload: []byte{0xde, 0xad, 0xbe, 0xef},
+	}
+}
+
+func assertMessage(t *testing.T, expected, got Message) {
+	t.Helper()
+
+	if got.ID != expected.ID || !got.Sent.Equal(expected.Sent) || !slices.Equal(got.Tags, expected.Tags) ||
+		!maps.Equal(got.Attrs, expected.Attrs) || !bytes.Equal(got.Payload, expected.Payload) {
+		t.Errorf("Expected message after round trip to be %+v, got %+v", expected, got)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	data, err := EncodeJSON(testMessage())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m, err := DecodeJSON(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertMessage(t, testMessage(), m)
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	data, err := EncodeGob(testMessage())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	m, err := DecodeGob(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertMessage(t, testMessage(), m)
+}
+
+// encoding/binary works with fixed-size values: integers, floats, bools, and arrays or structs of them.
+// Reading is a sensor reading, it has a fixed layout of 22 bytes in big endian (network) byte order:
+//
+//	offset  size  field
+//	0       4     SensorID
+//	4       8     Timestamp, Unix nanoseconds
+//	12      8     Value, IEEE 754
+//	20      2     Flags
+//
+// Let's implement encoding.BinaryMarshaler and encoding.BinaryUnmarshaler for it.
+// Hint: binary.BigEndian has AppendUint32, Uint32, and friends, math.Float64bits converts a float to its bits.
+// binary.Write and binary.Read can also handle the whole struct at once, but they use reflection.
+
+// Reading is a sensor reading.
+type Reading struct {
+	SensorID  uint32
+	Timestamp int64
+	Value     float64
+	Flags     uint16
+}
+
+// readingSize is the size of the binary representation of Reading.
+const readingSize = 22
+
+// MarshalBinary encodes the reading into its binary layout.
+func (r Reading) MarshalBinary() ([]byte, error) {
+	return nil, nil
+}
+
+// UnmarshalBinary decodes the reading from its binary layout.
+func (r *Reading) UnmarshalBinary(data []byte) error {
+	return nil
+}
+
+var testReading = Reading{SensorID: 7, Timestamp: 1714557600000000000, Value: 21.5, Flags: 0x0102}
+
+func TestBinaryLayout(t *testing.T) {
+	data, err := testReading.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x07, // SensorID
+		0x17, 0xcb, 0x55, 0x0d, 0x96, 0xf2, 0x40, 0x00, // Timestamp
+		0x40, 0x35, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, // Value
+		0x01, 0x02, // Flags
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Expected binary layout\n% x\ngot\n% x", expected, data)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	data, err := testReading.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var r Reading
+	if err := r.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if r != testReading {
+		t.Fatalf("Expected reading after round trip to be %+v, got %+v", testReading, r)
+	}
+
+	if err := r.UnmarshalBinary(data[:readingSize-1]); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+}
+
+// gob encodes only exported fields, and it refuses to encode a struct without them.
+// Types that keep their state in unexported fields, like Money below, implement gob.GobEncoder and gob.GobDecoder
+// to control their representation. Let's try to implement GobEncode and GobDecode for Money,
+// so accounts survive a gob round trip. MarshalBinary from the previous exercise is a good example of the format.
+
+// Money is an amount in cents in a currency, it's immutable and its fields are unexported.
+type Money struct {
+	cents    int64
+	currency string
+}
+
+// NewMoney creates money.
+func NewMoney(cents int64, currency string) Money {
+	return Money{cents: cents, currency: currency}
+}
+
+func (m Money) String() string {
+	return formatAmount(m.cents) + " " + m.currency
+}
+
+// Account is a bank account.
+type Account struct {
+	Owner   string
+	Balance Money
+}
+
+func TestGobEncoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	expected := Account{Owner: "alice", Balance: NewMoney(123456, "EUR")}
+
+	if err := gob.NewEncoder(buf).Encode(expected); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got Account
+	if err := gob.NewDecoder(buf).Decode(&got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got != expected {
+		t.Errorf("Expected account after round trip to be %s %s, got %s %s", expected.Owner, expected.Balance, got.Owner, got.Balance)
+	}
+}
+
+// Benchmarks encode the same reading with all three encodings and report the size of the encoded message.
+// Gob sends type information with the first value of every encoder. A new encoder per message is the worst case,
+// a long-lived stream pays for the types only once, see BenchmarkReadingGobStream.
+
+func BenchmarkReadingJSON(b *testing.B) {
+	b.ReportAllocs()
+
+	var data []byte
+
+	for i := 0; i < b.N; i++ {
+		data, _ = json.Marshal(testReading)
+	}
+
+	b.ReportMetric(float64(len(data)), "bytes/msg")
+}
+
+func BenchmarkReadingGob(b *testing.B) {
+	b.ReportAllocs()
+
+	buf := &bytes.Buffer{}
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		_ = gob.NewEncoder(buf).Encode(testReading)
+	}
+
+	b.ReportMetric(float64(buf.Len()), "bytes/msg")
+}
+
+func BenchmarkReadingGobStream(b *testing.B) {
+	b.ReportAllocs()
+
+	buf := &bytes.Buffer{}
+	enc := gob.NewEncoder(buf)
+	_ = enc.Encode(testReading)
+
+	size := 0
+
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		_ = enc.Encode(testReading)
+		size = buf.Len()
+	}
+
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkReadingBinary(b *testing.B) {
+	b.ReportAllocs()
+
+	var data []byte
+
+	for i := 0; i < b.N; i++ {
+		data, _ = testReading.MarshalBinary()
+	}
+
+	b.ReportMetric(float64(len(data)), "bytes/msg")
+}
@@ -0,0 +1,89 @@
+package encoding
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+// Advanced: encoding.TextMarshaler.
+//
+// A type that implements encoding.TextMarshaler and encoding.TextUnmarshaler defines its text form once,
+// and every package that understands these interfaces uses it:
+// encoding/json for values and map keys, encoding/xml, flag.TextVar, and slog.
+//
+// Severity is stored as an integer, so levels can be compared, but it should be written as a name.
+// Let's implement MarshalText and UnmarshalText, so JSON has "warning" instead of 2,
+// both as a value and as a map key, and unknown names are rejected when decoding.
+
+// Severity is the importance of an alert.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityNotice
+	SeverityWarning
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityInfo:     "info",
+	SeverityNotice:   "notice",
+	SeverityWarning:  "warning",
+	SeverityCritical: "critical",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(s))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	n, err := strconv.Atoi(string(text))
+	*s = Severity(n)
+
+	return err
+}
+
+// Alert is a notification of the monitoring system.
+type Alert struct {
+	Name     string           `json:"name"`
+	Severity Severity         `json:"severity"`
+	Counts   map[Severity]int `json:"counts"`
+}
+
+func TestSeverityText(t *testing.T) {
+	alert := Alert{
+		Name:     "disk",
+		Severity: SeverityWarning,
+		Counts:   map[Severity]int{SeverityInfo: 3, SeverityCritical: 1},
+	}
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"name":"disk","severity":"warning","counts":{"critical":1,"info":3}}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	var decoded Alert
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded.Severity != SeverityWarning || decoded.Counts[SeverityInfo] != 3 || decoded.Counts[SeverityCritical] != 1 {
+		t.Errorf("Expected the alert to survive the round trip, got %+v", decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"severity":"panic"}`), &decoded); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+
+	if _, err := Severity(42).MarshalText(); err == nil {
+		t.Error("Expected error for severity without a name")
+	}
+}
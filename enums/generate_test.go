@@ -0,0 +1,34 @@
+package enums
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Section 5 is in priority.go: stringer reads the constants from regular files of the package, not from tests.
+
+func TestPriorityString(t *testing.T) {
+	tests := []struct {
+		priority Priority
+		expected string
+	}{
+		{PriorityLow, "Low"},
+		{PriorityNormal, "Normal"},
+		{PriorityHigh, "High"},
+		{PriorityUrgent, "Urgent"},
+		{Priority(15), "Priority(15)"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.priority); got != tt.expected {
+			t.Errorf("Expected priority %d to print as %q, got %q", int(tt.priority), tt.expected, got)
+		}
+	}
+
+	src, err := os.ReadFile("priority_string.go")
+	if err != nil || !strings.Contains(string(src), "Code generated by \"stringer") {
+		t.Errorf("Expected String of Priority to be generated by stringer into priority_string.go, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package enums
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// 4. Parsing and JSON.
+// encoding/json writes a Status as a number: the API leaks the order of the const block, and inserting
+// a status in the middle changes the meaning of every stored value. Names are stable, and
+// encoding.TextMarshaler and encoding.TextUnmarshaler make encoding/json use them: for values,
+// and for map keys too. Other encodings like XML and YAML use these interfaces as well.
+//
+// ParseStatus is the other half of String, and it's the only place names are turned back into statuses.
+// Now it returns the zero value without an error for a name it doesn't know, so a typo in a request becomes
+// an order without a status. Return an UnknownValueError, names are case-sensitive.
+// Then implement MarshalText and UnmarshalText: marshaling an invalid status is an error too,
+// and the error of UnmarshalText comes out of json.Unmarshal as it is, so callers can check it with errors.As.
+
+// ParseStatus returns the status with the name.
+func ParseStatus(name string) (Status, error) {
+	for s, n := range statusNames {
+		if n == name {
+			return s, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func TestParseStatus(t *testing.T) {
+	for _, s := range allStatuses {
+		got, err := ParseStatus(s.String())
+		if err != nil || got != s {
+			t.Errorf("Expected %q to parse as status %d, got %d, %v", s.String(), int(s), int(got), err)
+		}
+	}
+
+	for _, name := range []string{"lost", "Shipped", "", "Status(0)"} {
+		_, err := ParseStatus(name)
+
+		var unknown *UnknownValueError
+		if !errors.As(err, &unknown) || unknown.Type != "Status" || unknown.Value != name {
+			t.Errorf("Expected an UnknownValueError for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	data, err := json.Marshal(Order{ID: 1, Status: StatusShipped})
+	if err != nil || string(data) != `{"id":1,"status":"shipped"}` {
+		t.Errorf("Expected the status to be written as its name, got %s, %v", data, err)
+	}
+
+	var o Order
+	if err := json.Unmarshal([]byte(`{"id":2,"status":"refunded"}`), &o); err != nil || o.Status != StatusRefunded {
+		t.Errorf("Expected the status to be read from its name, got %s, %v", o.Status, err)
+	}
+
+	counts, err := json.Marshal(map[Status]int{StatusPaid: 3, StatusPending: 1})
+	if err != nil || string(counts) != `{"paid":3,"pending":1}` {
+		t.Errorf("Expected statuses as map keys to be written as names, got %s, %v", counts, err)
+	}
+
+	var unknown *UnknownValueError
+
+	err = json.Unmarshal([]byte(`{"id":3,"status":"lost"}`), &o)
+	if !errors.As(err, &unknown) || unknown.Value != "lost" {
+		t.Errorf("Expected an UnknownValueError for an unknown name, got %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"id":4,"status":2}`), &o); err == nil {
+		t.Errorf("Expected an error for a status written as a number, got %s", o.Status)
+	}
+
+	_, err = json.Marshal(Order{ID: 5})
+	if !errors.As(err, &unknown) {
+		t.Errorf("Expected an UnknownValueError for an order without a status, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+// Package enums is a workshop on enums: typed constants with iota, String methods, JSON, and validation.
+// String methods can be generated, regenerate them after changing constants with:
+//
+//	go generate ./enums
+package enums
+
+// 5. Advanced: generating String methods.
+// A String method written by hand gets out of date as soon as a constant is added, like statusNames did.
+// stringer generates it from the constants of the type. It's a command of golang.org/x/tools, which is
+// in go.mod already, so go run builds it at the version the module requires, and nobody has to install it.
+// Given files, stringer parses only them instead of loading the whole package with its tests:
+//
+//	//go:generate go run golang.org/x/tools/cmd/stringer -type=Priority priority.go
+//
+// Add the directive, run go generate, and check in priority_string.go. Flags of stringer control the names:
+// PriorityLow must print as "Low". Then change the value of a constant without regenerating and build:
+// the generated file checks values of constants at compile time, so a stale String method doesn't compile.
+
+// Priority is a priority of a support ticket, values are spaced to leave room for new ones.
+type Priority int
+
+const (
+	PriorityLow    Priority = 10
+	PriorityNormal Priority = 20
+	PriorityHigh   Priority = 30
+	PriorityUrgent Priority = 40
+)
@@ -0,0 +1,125 @@
+package enums
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Go has no enum keyword. An enum is a named type with a block of constants, and iota numbers them:
+// it's 0 in the first spec of a const block and grows by one with every spec, and a spec without
+// a value repeats the expression of the previous one. The named type keeps statuses apart from
+// other numbers, but not from invalid ones: Status(42) compiles, and so does a conversion from any int.
+
+// 1. Constants and iota.
+// The zero value of an Order has the status StatusPending, so an order that nobody set a status for
+// looks like a real pending order, and IsValid agrees. Reserve the zero value for an unknown status,
+// so forgotten initialization is an invalid status, and make IsValid reject it.
+// IsValid also has to change every time a status is added at the end. An unexported sentinel constant after
+// the last status keeps the upper bound in the const block.
+
+// Status is a status of an order.
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusPaid
+	StatusShipped
+	StatusDelivered
+	StatusCancelled
+	StatusRefunded
+)
+
+// IsValid reports whether the status is one of the constants.
+func (s Status) IsValid() bool {
+	return s >= StatusPending && s <= StatusRefunded
+}
+
+// Order is an order of the shop.
+type Order struct {
+	ID     int    `json:"id"`
+	Status Status `json:"status"`
+}
+
+// UnknownValueError is returned for a value that isn't one of the constants of an enum.
+type UnknownValueError struct {
+	// Type is the name of the enum type.
+	Type string
+	// Value is the value as it was given, a name or a number.
+	Value string
+}
+
+func (e *UnknownValueError) Error() string {
+	return fmt.Sprintf("unknown %s %q", e.Type, e.Value)
+}
+
+// allStatuses lists every status, tests must be updated when a status is added.
+var allStatuses = []Status{StatusPending, StatusPaid, StatusShipped, StatusDelivered, StatusCancelled, StatusRefunded}
+
+func TestZeroValue(t *testing.T) {
+	var o Order
+
+	if o.Status.IsValid() {
+		t.Errorf("Expected the status of an order without one to be invalid, got %d", o.Status)
+	}
+
+	for _, s := range allStatuses {
+		if s == 0 {
+			t.Errorf("Expected no status to be the zero value, got %s", s)
+		}
+
+		if !s.IsValid() {
+			t.Errorf("Expected status %d to be valid", s)
+		}
+	}
+
+	if Status(42).IsValid() || Status(-1).IsValid() {
+		t.Errorf("Expected statuses out of range to be invalid")
+	}
+}
+
+// 2. fmt.Stringer.
+// fmt prints a Status as a number, unless it has a String method. String looks the name up in statusNames,
+// and a status that isn't there prints as an empty string: nothing in logs tells what the value was.
+// Print an invalid status as Status(42), the way stringer does, and don't forget the statuses
+// added after statusNames was written.
+//
+// String must not format the value with %v or %s: they call String again, and the recursion never stops.
+// Convert to int first.
+
+var statusNames = map[Status]string{
+	StatusPending:   "pending",
+	StatusPaid:      "paid",
+	StatusShipped:   "shipped",
+	StatusDelivered: "delivered",
+	StatusCancelled: "cancelled",
+}
+
+func (s Status) String() string {
+	return statusNames[s]
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		status   Status
+		expected string
+	}{
+		{StatusPending, "pending"},
+		{StatusPaid, "paid"},
+		{StatusShipped, "shipped"},
+		{StatusDelivered, "delivered"},
+		{StatusCancelled, "cancelled"},
+		{StatusRefunded, "refunded"},
+		{Status(0), "Status(0)"},
+		{Status(42), "Status(42)"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.status); got != tt.expected {
+			t.Errorf("Expected status %d to print as %q, got %q", int(tt.status), tt.expected, got)
+		}
+	}
+
+	if got := fmt.Sprintf("%d", StatusPaid); got != fmt.Sprint(int(StatusPaid)) {
+		t.Errorf("Expected %%d to print the number, got %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+package enums
+
+import (
+	"errors"
+	"testing"
+)
+
+// 3. Exhaustive switches.
+// The compiler doesn't check that a switch over an enum has a case for every constant. StatusRefunded was added
+// after Next was written, and nothing complained: the default case returns nil for it, the same as for
+// statuses that have nowhere to go, so paid and delivered orders can't be refunded.
+//
+// Give every status a case of its own, terminal ones included, so a missing case is visible in the code,
+// and make the default case handle what only it can get: invalid statuses. Transition must reject them with
+// an UnknownValueError, not treat them as terminal. Linters like exhaustive find switches that miss a constant,
+// and a test that walks every status catches what they can't.
+//
+// The lifecycle of an order:
+// - pending orders get paid or cancelled,
+// - paid orders get shipped, cancelled, or refunded,
+// - shipped orders get delivered,
+// - delivered orders get refunded,
+// - cancelled and refunded orders stay as they are.
+
+// ErrInvalidTransition is returned when an order can't move to a status.
+var ErrInvalidTransition = errors.New("invalid transition")
+
+// Next returns the statuses an order can move to from the status.
+func (s Status) Next() []Status {
+	switch s {
+	case StatusPending:
+		return []Status{StatusPaid, StatusCancelled}
+	case StatusPaid:
+		return []Status{StatusShipped, StatusCancelled}
+	case StatusShipped:
+		return []Status{StatusDelivered}
+	default:
+		return nil
+	}
+}
+
+// Transition moves the order to the status, or returns an error if the order can't move there.
+func (o *Order) Transition(to Status) error {
+	for _, next := range o.Status.Next() {
+		if next == to {
+			o.Status = to
+			return nil
+		}
+	}
+
+	return ErrInvalidTransition
+}
+
+func TestTransition(t *testing.T) {
+	allowed := map[Status][]Status{
+		StatusPending:   {StatusPaid, StatusCancelled},
+		StatusPaid:      {StatusShipped, StatusCancelled, StatusRefunded},
+		StatusShipped:   {StatusDelivered},
+		StatusDelivered: {StatusRefunded},
+		StatusCancelled: nil,
+		StatusRefunded:  nil,
+	}
+
+	for _, from := range allStatuses {
+		for _, to := range allStatuses {
+			expected := false
+
+			for _, s := range allowed[from] {
+				if s == to {
+					expected = true
+				}
+			}
+
+			o := &Order{ID: 1, Status: from}
+			err := o.Transition(to)
+
+			switch {
+			case expected && (err != nil || o.Status != to):
+				t.Errorf("Expected a %s order to move to %s, got %v and status %s", from, to, err, o.Status)
+			case !expected && (!errors.Is(err, ErrInvalidTransition) || o.Status != from):
+				t.Errorf("Expected a %s order not to move to %s, got %v and status %s", from, to, err, o.Status)
+			}
+		}
+	}
+}
+
+func TestTransitionInvalidStatus(t *testing.T) {
+	o := &Order{ID: 1, Status: Status(42)}
+
+	err := o.Transition(StatusPaid)
+
+	var unknown *UnknownValueError
+	if !errors.As(err, &unknown) || unknown.Type != "Status" || unknown.Value != "42" {
+		t.Errorf("Expected an UnknownValueError for Status 42, not a rejected transition, got %v", err)
+	}
+
+	if o.Status != Status(42) {
+		t.Errorf("Expected the status to stay as it was, got %s", o.Status)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/jackc/pgconn"
+	"github.com/ksysoev/go-workshops/grader"
 )
 
 var logBuffer = &bytes.Buffer{}
@@ -24,21 +26,17 @@ func init() {
 // 2. fmt.Errorf() function
 // Both functions return an error interface, which is a built-in interface in Go.
 // Let's try to fix the code below by creating errors using errors.New() and fmt.Errorf() functions.
-func ExampleCreatingErrors() {
-	var err error
-	// err :=
-	if err != nil {
-		fmt.Println("Error1:", err)
-	}
+func TestCreatingErrors(t *testing.T) {
+	g := grader.New(t)
+
+	var err1, err2 error
+	// err1 =
 
 	// expectedValue := 42
-	// err =
-	if err != nil {
-		fmt.Println("Error2:", err)
-	}
-	// Output:
-	// Error1: an error
-	// Error2: an error with value 42
+	// err2 =
+
+	g.Hint("errors.New creates an error with a fixed message").Error("err1", err1, "an error")
+	g.Hint("fmt.Errorf formats the message like fmt.Sprintf").Error("err2", err2, "an error with value 42")
 }
 
 // Now, let's try to return an error from a function.
@@ -49,17 +47,11 @@ func ValidatePasswordLen(password string) error {
 	return nil
 }
 
-func ExampleReturningError() {
-	if err := ValidatePasswordLen("longpassword"); err != nil {
-		fmt.Println("Error:", err)
-	}
-
-	if err := ValidatePasswordLen("short"); err != nil {
-		fmt.Println("Error2:", err)
-	}
+func TestReturningError(t *testing.T) {
+	g := grader.New(t)
 
-	// Output:
-	// Error2: password is too short: short
+	g.NoError(`ValidatePasswordLen("longpassword")`, ValidatePasswordLen("longpassword"))
+	g.Error(`ValidatePasswordLen("short")`, ValidatePasswordLen("short"), "password is too short: short")
 }
 
 // What if we need to return a value along or an error from the function?
@@ -72,18 +64,17 @@ func Divide(a, b int) (int, error) {
 	return a / b, nil
 }
 
-func ExampleReturningValueAndError() {
-	if result, err := Divide(10, 2); err == nil {
-		fmt.Println("Result1:", result)
-	}
+func TestReturningValueAndError(t *testing.T) {
+	g := grader.New(t)
 
-	if _, err := Divide(10, 0); err != nil {
-		fmt.Println("Error2:", err)
-	}
+	result, err := Divide(10, 2)
+	g.NoError("Divide(10, 2)", err)
+	g.Equal("Divide(10, 2)", 5, result)
 
-	// Output:
-	// Result1: 5
-	// Error2: division by zero
+	g.Hint("check the denominator before dividing").NoPanic("Divide(10, 0)", func() {
+		_, err = Divide(10, 0)
+	})
+	g.Error("Divide(10, 0)", err, "division by zero")
 }
 
 // Expected flow errors are errors that are expected to happen in the normal flow of the program.
@@ -105,10 +96,10 @@ func GetUser(id int) (string, error) {
 }
 
 func TestExpectedFlowErrors(t *testing.T) {
+	g := grader.New(t)
+
 	_, err := GetUser(1)
-	if err != errors.New("record not found") {
-		t.Errorf("expected ErrRecordNotFound, got %v", err)
-	}
+	g.Check("GetUser(1)", err == errors.New("record not found"), "expected ErrRecordNotFound, got %v", err)
 }
 
 // To implement custom errors, we can create a new type that implements the error interface.
@@ -143,22 +134,16 @@ func ValidateField(field, value string) error {
 	return nil
 }
 
-func ExampleCustomErrors() {
-	err := ValidateField("username", "verylongvalue")
-
-	if err != nil {
-		fmt.Println("Error:", err)
+func TestCustomErrors(t *testing.T) {
+	g := grader.New(t)
 
-		var field, msg string
+	err := ValidateField("username", "verylongvalue")
+	g.Error("ValidateField", err, "too long")
 
-		fmt.Println("Field:", field)
-		fmt.Println("Message:", msg)
-	}
+	var field, msg string
 
-	// Output:
-	// Error: too long
-	// Field: username
-	// Message: value is too long
+	g.Equal("Field", "username", field)
+	g.Equal("Message", "value is too long", msg)
 }
 
 // Error wrapping is a technique to add more context to an error
@@ -168,70 +153,66 @@ func ExampleCustomErrors() {
 // - when we need to add more context to the error
 // - when we get an error from a third-party library it's a good practice to wrap it with a more descriptive error
 
-func ExampleErrorWrapping() {
+func TestErrorWrapping(t *testing.T) {
+	g := grader.New(t)
+
 	userID := 10
 	_, err := GetUser(userID)
-	if err != nil {
-		fmt.Println("Error:", err)
-	}
 
-	// Output:
-	// Error: Fail to fetch user 10 for update: user not found
+	g.Hint("wrap the error with fmt.Errorf and %w").Error("GetUser(10)", err, "Fail to fetch user 10 for update: user not found")
 }
 
 // With error wrapping, we can create a chain of errors.
 // To unwrap an error, we can use errors.Is() and errors.As() functions.
 
-func ExampleErrorUnwrapping() {
+func TestErrorUnwrapping(t *testing.T) {
+	g := grader.New(t)
+
 	userID := 10
 	_, err := GetUser(userID)
 	err = fmt.Errorf("Fail to fetch user %d for update: %w", userID, err)
 
-	if err == ErrUserNotFound {
-		fmt.Println("Error:", err)
-	}
+	g.Check("user not found", err == ErrUserNotFound, "expected the wrapped error to be ErrUserNotFound, got %v", err)
 
 	err = ValidateField("username", "verylongvalue")
 	err = fmt.Errorf("Fail to validate signup form: %w", err)
-	if err != nil {
-		fmt.Println("Error:", err)
-		// 	fmt.Println("Field:", fieldErr.Field)
-		// 	fmt.Println("Message:", fieldErr.Msg)
-	}
 
-	// Output:
-	// Error: user not found
-	// Field: username
-	// Message: value is too long
+	var field, msg string
+	// field = fieldErr.Field
+	// msg = fieldErr.Msg
+
+	g.Equal("Field", "username", field)
+	g.Equal("Message", "value is too long", msg)
 }
 
 // Let's imaging we have multiple errors and we want to return them all.
 // If we know exactly how many errors we have, we can use fmt.Errorf() function with %w verb.
 
-func ExampleJoiningErrors1() {
-	_ = errors.New("error1")
-	_ = errors.New("error2")
-	err := fmt.Errorf("multiple errors")
+func TestJoiningErrors1(t *testing.T) {
+	g := grader.New(t)
 
-	fmt.Println("Error:", err)
+	err1 := errors.New("error1")
+	err2 := errors.New("error2")
+	err := fmt.Errorf("multiple errors")
 
-	// Output:
-	// Error: multiple errors: error1, error2
+	g.Error("err", err, "multiple errors: error1, error2")
+	g.Hint("fmt.Errorf accepts more than one %w").ErrorIs("err", err, err1)
+	g.Hint("fmt.Errorf accepts more than one %w").ErrorIs("err", err, err2)
 }
 
 // If we don't know how many errors we have, we can use errors.Join() function to achieve the simular result.
 
-func ExampleJoiningErrors2() {
-	_ = errors.New("error1")
-	_ = errors.New("error2")
+func TestJoiningErrors2(t *testing.T) {
+	g := grader.New(t)
 
-	var errs error
+	err1 := errors.New("error1")
+	err2 := errors.New("error2")
 
-	fmt.Println("Error:", errs)
+	var errs error
 
-	// Output:
-	// Errors: error1
-	// error2
+	g.Error("errs", errs, "error1\nerror2")
+	g.ErrorIs("errs", errs, err1)
+	g.ErrorIs("errs", errs, err2)
 }
 
 // Let's discuss logging in context of error handling.
@@ -255,12 +236,13 @@ func getServiceAddress() string {
 }
 
 func TestLogging(t *testing.T) {
+	g := grader.New(t)
+
 	logBuffer.Reset()
 	_ = getServiceAddress()
 
-	if !strings.Contains(logBuffer.String(), "failed to get service address, using default address") {
-		t.Errorf("expected to log error, got '%s'", logBuffer.String())
-	}
+	g.Check("log", strings.Contains(logBuffer.String(), "failed to get service address, using default address"),
+		"expected to log error, got '%s'", logBuffer.String())
 }
 
 // In addition to standard errors, Go provides a way to throw exceptions like errors using panic() function.
@@ -288,11 +270,23 @@ func TestLogging(t *testing.T) {
 
 // Let's try to fix the code below by using panic() and recover() functions to pass the test.
 
-func ExamplePanicAndRecover() {
-	panic("something went wrong")
+// Recover calls fn and returns the panic as an error, so it doesn't cross the boundary of the package.
+func Recover(fn func()) error {
+	fn()
+
+	return nil
+}
+
+func TestPanicAndRecover(t *testing.T) {
+	g := grader.New(t)
 
-	// Output:
-	// Panic: something went wrong
+	var err error
+
+	g.Hint("defer a function that calls recover() and sets a named result").NoPanic("Recover", func() {
+		err = Recover(func() { panic("something went wrong") })
+	})
+	g.Error("Recover", err, "Panic: something went wrong")
+	g.NoError("Recover without panic", Recover(func() {}))
 }
 
 // I think we mostly covered the error handling in Go.
@@ -327,48 +321,47 @@ func ValidateClient(client Client) error {
 	return err
 }
 
-func ExampleReturningNilInterface() {
+func TestReturningNilInterface(t *testing.T) {
+	g := grader.New(t)
+
 	client := Client{Name: "Vasia Pupkin", Age: 42}
 	err := ValidateClient(client)
 
-	if err != nil {
-		fmt.Println("Error:", err)
-	}
-
-	// Output:
+	g.NoError("ValidateClient", err)
 }
 
 // Pitfall 2:
-func logError(err error) {
+func logError(w io.Writer, err error) {
 	if err != nil {
-		fmt.Println("Error:", err)
+		fmt.Fprintln(w, "Error:", err)
 	}
 }
 
-func ExampleLoggingErrors() {
-	var err error
-	defer logError(err)
+func TestLoggingErrors(t *testing.T) {
+	g := grader.New(t)
+
+	var log bytes.Buffer
+
+	func() {
+		var err error
+		defer logError(&log, err)
 
-	err = errors.New("something went wrong")
+		err = errors.New("something went wrong")
+	}()
 
-	// Output:
-	// Error: something went wrong
+	g.Equal("log", "Error: something went wrong\n", log.String())
 }
 
 // Pitfall 3:
-func ExampleHandllingDbError() {
+func TestHandlingDbError(t *testing.T) {
+	g := grader.New(t)
+
 	err := GetUsers()
 
-	if err != nil {
-		var pgErr *pgconn.PgError
+	var pgErr *pgconn.PgError
 
-		if errors.As(err, &pgErr) {
-			fmt.Println(pgErr.Message)
-			fmt.Println(pgErr.Code)
-		}
+	if g.ErrorAs("GetUsers", err, &pgErr) {
+		g.Equal("Message", `relation "users" does not exist`, pgErr.Message)
+		g.Equal("Code", "42P01", pgErr.Code)
 	}
-
-	// Output:
-	// relation "users" does not exist
-	// 42P01
 }
@@ -0,0 +1,78 @@
+package errorhandling
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+// Advanced: custom error formatting with slog.
+//
+// slog calls the LogValue method of values that implement slog.LogValuer before they are written.
+// Errors can use it to log structured details instead of a flat message,
+// and to keep sensitive data, like query arguments, out of logs:
+//
+//	func (e *QueryError) LogValue() slog.Value {
+//		return slog.GroupValue(slog.String("query", e.Query), ...)
+//	}
+//
+// Let's make QueryError log the query and the cause as a group, without arguments of the query.
+
+// QueryError is returned when a database query fails.
+type QueryError struct {
+	Query string
+	Args  []any
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query %q with %v failed: %v", e.Query, e.Args, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// LogValue implements slog.LogValuer.
+// It should return a group with "query" and "cause" attributes.
+func (e *QueryError) LogValue() slog.Value {
+	return slog.StringValue(e.Error())
+}
+
+func TestQueryErrorLogValue(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+
+	err := fmt.Errorf("failed to load user: %w", &QueryError{
+		Query: "SELECT * FROM users WHERE email = $1",
+		Args:  []any{"alice@example.com"},
+		Err:   errors.New("connection reset"),
+	})
+
+	var qErr *QueryError
+	if !errors.As(err, &qErr) {
+		t.Fatal("Expected QueryError in the chain")
+	}
+
+	logger.Error("request failed", "error", qErr)
+
+	expected := `{"level":"ERROR","msg":"request failed","error":{"query":"SELECT * FROM users WHERE email = $1","cause":"connection reset"}}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected log record %s, got %s", expected, buf.String())
+	}
+
+	if bytes.Contains(buf.Bytes(), []byte("alice@example.com")) {
+		t.Error("Expected arguments of the query to stay out of logs")
+	}
+}
@@ -0,0 +1,98 @@
+package eventsourcing
+
+import (
+	"errors"
+	"testing"
+)
+
+// Events of an account. They are facts in the past tense, and they never change once stored:
+// a new requirement means a new event type, or a new version of an event, not a migration of history.
+
+// AccountOpened is the first event of an account.
+type AccountOpened struct {
+	Owner string
+}
+
+// MoneyDeposited adds the amount in cents to the balance.
+type MoneyDeposited struct {
+	Amount int64
+}
+
+// MoneyWithdrawn subtracts the amount in cents from the balance.
+type MoneyWithdrawn struct {
+	Amount int64
+}
+
+// AccountClosed is the last event of an account.
+type AccountClosed struct{}
+
+// 1. Rebuilding from history.
+// Account is an aggregate: its state exists only to decide on commands, and it's rebuilt from its events
+// every time it's loaded. Apply must not validate anything or fail, events are facts that already happened.
+// Apply handles only AccountOpened, handle the rest of the events.
+
+// ErrAccountNotFound is returned for accounts without events.
+var ErrAccountNotFound = errors.New("account not found")
+
+// Account is the state of an account.
+type Account struct {
+	ID      string
+	Owner   string
+	Balance int64
+	Closed  bool
+	// Version is the version of the last applied event.
+	Version int
+}
+
+// Apply changes the state of the account according to the event.
+func (a *Account) Apply(e Event) {
+	switch data := e.Data.(type) {
+	case AccountOpened:
+		a.ID = e.StreamID
+		a.Owner = data.Owner
+	}
+
+	a.Version = e.Version
+}
+
+// LoadAccount rebuilds the account from its events.
+func LoadAccount(s *Store, id string) (*Account, error) {
+	events := s.Load(id)
+	if len(events) == 0 {
+		return nil, ErrAccountNotFound
+	}
+
+	a := &Account{}
+	for _, e := range events {
+		a.Apply(e)
+	}
+
+	return a, nil
+}
+
+func TestRebuildFromHistory(t *testing.T) {
+	s := NewStore()
+	s.Append("acc-1", 0, AccountOpened{Owner: "Alice"}, MoneyDeposited{Amount: 100}, MoneyDeposited{Amount: 50})
+	s.Append("acc-2", 0, AccountOpened{Owner: "Bob"})
+	s.Append("acc-1", 3, MoneyWithdrawn{Amount: 30})
+
+	a, err := LoadAccount(s, "acc-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := Account{ID: "acc-1", Owner: "Alice", Balance: 120, Version: 4}
+	if *a != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *a)
+	}
+
+	s.Append("acc-1", 4, MoneyWithdrawn{Amount: 120}, AccountClosed{})
+
+	if a, _ := LoadAccount(s, "acc-1"); a.Balance != 0 || !a.Closed || a.Version != 6 {
+		t.Errorf("Expected a closed account with no money at version 6, got %+v", *a)
+	}
+
+	if _, err := LoadAccount(s, "acc-3"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// 3. Commands.
+// A command is a request to change something, it can be rejected. Handle validates it, loads the aggregate,
+// decides, and appends events with the version of the aggregate as the expected version. Three kinds of errors:
+// - invalid commands: every invalid field is a *ValidationError, like FieldValidationError in the errorhandling module,
+//   and all of them are returned at once with errors.Join, so a form can show all problems together,
+// - rules of the domain: ErrAccountNotFound, ErrAccountClosed, ErrInsufficientFunds, wrapped with the account ID,
+// - concurrency conflicts: the account changed between load and append. They aren't errors for the caller,
+//   Handle reloads the account and decides again, up to maxAttempts times.
+//
+// Handle appends whatever it's asked to. Add the validation: IDs and owners are required, and amounts are positive.
+// Add the rules: accounts must exist and be open for deposits and withdrawals, and withdrawals can't overdraw.
+// Opening an account that exists is a conflict, and so it's reported as one. Then retry on conflicts.
+
+// maxAttempts is how many times Handle tries a command that runs into concurrency conflicts.
+const maxAttempts = 10
+
+var (
+	// ErrAccountClosed is returned for commands to closed accounts.
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrInsufficientFunds is returned for withdrawals of more than the balance.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// ValidationError is an invalid field of a command.
+type ValidationError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// OpenAccount opens a new account.
+type OpenAccount struct {
+	ID    string
+	Owner string
+}
+
+// Deposit adds money to an account.
+type Deposit struct {
+	ID     string
+	Amount int64
+}
+
+// Withdraw takes money from an account.
+type Withdraw struct {
+	ID     string
+	Amount int64
+}
+
+// CloseAccount closes an account.
+type CloseAccount struct {
+	ID string
+}
+
+// Handle executes the command.
+func Handle(s *Store, cmd any) error {
+	switch cmd := cmd.(type) {
+	case OpenAccount:
+		_, err := s.Append(cmd.ID, 0, AccountOpened{Owner: cmd.Owner})
+		return err
+	case Deposit:
+		a, err := LoadAccount(s, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.Append(cmd.ID, a.Version, MoneyDeposited{Amount: cmd.Amount})
+
+		return err
+	case Withdraw:
+		a, err := LoadAccount(s, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.Append(cmd.ID, a.Version, MoneyWithdrawn{Amount: cmd.Amount})
+
+		return err
+	case CloseAccount:
+		a, err := LoadAccount(s, cmd.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.Append(cmd.ID, a.Version, AccountClosed{})
+
+		return err
+	}
+
+	return fmt.Errorf("unknown command %T", cmd)
+}
+
+// invalidFields returns fields of all validation errors in the tree of the error.
+func invalidFields(err error) []string {
+	var fields []string
+
+	var walk func(err error)
+	walk = func(err error) {
+		if v, ok := err.(*ValidationError); ok {
+			fields = append(fields, v.Field)
+		}
+
+		switch err := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(err.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, err := range err.Unwrap() {
+				walk(err)
+			}
+		}
+	}
+
+	walk(err)
+	slices.Sort(fields)
+
+	return fields
+}
+
+func TestCommandValidation(t *testing.T) {
+	s := NewStore()
+
+	tests := []struct {
+		cmd    any
+		fields []string
+	}{
+		{OpenAccount{}, []string{"ID", "Owner"}},
+		{OpenAccount{ID: "acc-1"}, []string{"Owner"}},
+		{Deposit{ID: "acc-1", Amount: 0}, []string{"Amount"}},
+		{Withdraw{Amount: -5}, []string{"Amount", "ID"}},
+		{CloseAccount{}, []string{"ID"}},
+	}
+
+	for _, tt := range tests {
+		err := Handle(s, tt.cmd)
+
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("Expected a *ValidationError for %+v, got %v", tt.cmd, err)
+			continue
+		}
+
+		if fields := invalidFields(err); !slices.Equal(fields, tt.fields) {
+			t.Errorf("Expected invalid fields %v for %T, got %v", tt.fields, tt.cmd, fields)
+		}
+	}
+
+	if s.Position() != 0 {
+		t.Errorf("Expected invalid commands not to append events, got %d", s.Position())
+	}
+}
+
+func TestCommandRules(t *testing.T) {
+	s := NewStore()
+
+	steps := []struct {
+		cmd      any
+		expected error
+	}{
+		{Deposit{ID: "acc-1", Amount: 100}, ErrAccountNotFound},
+		{OpenAccount{ID: "acc-1", Owner: "Alice"}, nil},
+		{OpenAccount{ID: "acc-1", Owner: "Bob"}, ErrConcurrencyConflict},
+		{Deposit{ID: "acc-1", Amount: 100}, nil},
+		{Withdraw{ID: "acc-1", Amount: 150}, ErrInsufficientFunds},
+		{Withdraw{ID: "acc-1", Amount: 100}, nil},
+		{CloseAccount{ID: "acc-1"}, nil},
+		{Deposit{ID: "acc-1", Amount: 10}, ErrAccountClosed},
+		{CloseAccount{ID: "acc-1"}, ErrAccountClosed},
+	}
+
+	for _, step := range steps {
+		err := Handle(s, step.cmd)
+		if step.expected == nil && err != nil || step.expected != nil && !errors.Is(err, step.expected) {
+			t.Errorf("Expected %v for %+v, got %v", step.expected, step.cmd, err)
+		}
+	}
+
+	if a, _ := LoadAccount(s, "acc-1"); a == nil || a.Owner != "Alice" || a.Balance != 0 || !a.Closed {
+		t.Errorf("Expected a closed account of Alice with no money, got %+v", a)
+	}
+}
+
+func TestCommandConflicts(t *testing.T) {
+	s := NewStore()
+	Handle(s, OpenAccount{ID: "acc-1", Owner: "Alice"})
+	Handle(s, Deposit{ID: "acc-1", Amount: 100})
+
+	// Another instance withdraws between the load and the append of the next command.
+	s.beforeAppend = func() {
+		if err := Handle(s, Withdraw{ID: "acc-1", Amount: 80}); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	if err := Handle(s, Withdraw{ID: "acc-1", Amount: 50}); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected the withdrawal to be decided again on the new balance and fail, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+
+	// Every conflict means another deposit succeeded, so none of 10 deposits conflicts more than 9 times.
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := Handle(s, Deposit{ID: "acc-1", Amount: 10}); err != nil {
+				t.Errorf("Expected concurrent deposits to succeed, got %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if a, _ := LoadAccount(s, "acc-1"); a == nil || a.Balance != 120 {
+		t.Errorf("Expected the balance of 120, got %+v", a)
+	}
+}
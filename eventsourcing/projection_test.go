@@ -0,0 +1,178 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 5. Advanced: projections.
+// The read side of CQRS: a projection subscribes to all events and keeps a read model for queries,
+// here balances of all accounts, without loading streams on every read. It's updated asynchronously, so it lags
+// behind the store a little, that's eventual consistency. Position is how far it got: a restarted projection
+// continues from it, and a projection with a bug is fixed by rebuilding it from position 0.
+//
+// RunProjection runs Balances as a goroutine while queries read it, so the read model is guarded by a mutex.
+// Make Apply handle all events of accounts. Applying an event must be idempotent: events at or before Position
+// are already in the model, and a subscription that restarts may deliver them again.
+// Subscribe from section 4 must work for this one.
+
+// AccountSummary is an account in the read model.
+type AccountSummary struct {
+	Owner   string
+	Balance int64
+	Closed  bool
+}
+
+// Balances is a read model with balances of all accounts.
+type Balances struct {
+	mu       sync.RWMutex
+	accounts map[string]AccountSummary
+	position int
+}
+
+// NewBalances creates an empty read model.
+func NewBalances() *Balances {
+	return &Balances{accounts: make(map[string]AccountSummary)}
+}
+
+// Apply updates the read model with the event.
+func (b *Balances) Apply(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch data := e.Data.(type) {
+	case AccountOpened:
+		b.accounts[e.StreamID] = AccountSummary{Owner: data.Owner}
+	}
+
+	b.position = e.Position
+}
+
+// Get returns the summary of the account.
+func (b *Balances) Get(id string) (AccountSummary, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	s, ok := b.accounts[id]
+
+	return s, ok
+}
+
+// Position returns the position of the last applied event.
+func (b *Balances) Position() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.position
+}
+
+// RunProjection applies events of the store to the read model until the context is cancelled.
+func RunProjection(ctx context.Context, s *Store, b *Balances) {
+	for e := range s.Subscribe(ctx, b.Position()) {
+		b.Apply(e)
+	}
+}
+
+// waitForPosition waits until the read model catches up with the store.
+func waitForPosition(t *testing.T, s *Store, b *Balances) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Position() != s.Position() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the projection to catch up with position %d, got %d", s.Position(), b.Position())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestProjection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewStore()
+	b := NewBalances()
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		RunProjection(ctx, s, b)
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			id := fmt.Sprintf("acc-%d", i)
+			Handle(s, OpenAccount{ID: id, Owner: fmt.Sprintf("owner-%d", i)})
+
+			for j := range 20 {
+				Handle(s, Deposit{ID: id, Amount: int64(j + 1)})
+				b.Get(id)
+			}
+
+			Handle(s, Withdraw{ID: id, Amount: int64(10 * i)})
+
+			if i%3 == 0 {
+				Handle(s, CloseAccount{ID: id})
+			}
+		}()
+	}
+
+	wg.Wait()
+	waitForPosition(t, s, b)
+
+	for i := range 10 {
+		id := fmt.Sprintf("acc-%d", i)
+
+		a, err := LoadAccount(s, id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := AccountSummary{Owner: a.Owner, Balance: a.Balance, Closed: a.Closed}
+		if got, ok := b.Get(id); !ok || got != expected {
+			t.Errorf("Expected %s in the read model as %+v, got %+v", id, expected, got)
+		}
+	}
+
+	// Events that the read model has already seen change nothing.
+	before, _ := b.Get("acc-1")
+	for _, e := range s.Load("acc-1") {
+		b.Apply(e)
+	}
+
+	if after, _ := b.Get("acc-1"); after != before {
+		t.Errorf("Expected applying old events again to change nothing, got %+v, was %+v", after, before)
+	}
+
+	cancel()
+	<-done
+
+	// A rebuild from scratch gives the same read model.
+	rebuilt := NewBalances()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	go RunProjection(ctx, s, rebuilt)
+	waitForPosition(t, s, rebuilt)
+
+	for i := range 10 {
+		id := fmt.Sprintf("acc-%d", i)
+
+		got, _ := rebuilt.Get(id)
+		if expected, _ := b.Get(id); got != expected {
+			t.Errorf("Expected the rebuilt read model to have %s as %+v, got %+v", id, expected, got)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Event sourcing stores the history of changes instead of the current state: an account is not a row with a balance,
+// it's a stream of events AccountOpened, MoneyDeposited, MoneyWithdrawn. The current state is a fold over the stream,
+// and the history answers questions nobody thought of when the table was designed.
+//
+// The pieces of this capstone:
+// - an event store: append-only streams with optimistic concurrency,
+// - an aggregate, Account, that rebuilds its state by applying its events,
+// - command handlers that validate commands, make decisions on the state of the aggregate, and append new events,
+// - a projection: a goroutine that subscribes to all events and builds a read model for queries.
+// Writes go through commands and reads through the read model: that split is CQRS.
+
+// Event is an event of a stream. Version is its number in the stream starting at 1,
+// and Position is its number among all events of the store, also starting at 1.
+type Event struct {
+	StreamID string
+	Version  int
+	Position int
+	Data     any
+}
+
+// ErrConcurrencyConflict is returned by Append when the stream has changed since it was read.
+var ErrConcurrencyConflict = errors.New("concurrency conflict")
+
+// Store is an in-memory event store.
+type Store struct {
+	mu       sync.Mutex
+	events   []Event
+	versions map[string]int
+	// changed is closed and replaced on every append, to wake up whoever waits for new events.
+	changed chan struct{}
+	// beforeAppend, if set, is called once at the start of the next Append, tests use it to inject a concurrent write.
+	beforeAppend func()
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{versions: make(map[string]int), changed: make(chan struct{})}
+}
+
+// 2. Optimistic concurrency.
+// A command handler reads a stream, decides, and appends. Two handlers that read the same version of an account
+// can both decide to withdraw its last 100, and both appends succeed: the balance goes negative.
+// Locks around read-decide-append don't work across instances of the service. Optimistic concurrency does:
+// Append takes the version the handler has seen, and fails with ErrConcurrencyConflict when the stream has moved on,
+// then the handler reloads the stream and decides again.
+//
+// Implement the check in Append: expectedVersion is the version of the last event the caller has seen, 0 for a new stream.
+// On a conflict, nothing is appended, and the error wraps ErrConcurrencyConflict with the stream and both versions.
+
+// Append adds events to the stream if its current version is expectedVersion, and returns the new version.
+func (s *Store) Append(streamID string, expectedVersion int, data ...any) (int, error) {
+	if hook := s.beforeAppend; hook != nil {
+		s.beforeAppend = nil
+		hook()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := s.versions[streamID]
+
+	for _, d := range data {
+		version++
+		s.events = append(s.events, Event{StreamID: streamID, Version: version, Position: len(s.events) + 1, Data: d})
+	}
+
+	s.versions[streamID] = version
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	return version, nil
+}
+
+// Load returns the events of the stream.
+func (s *Store) Load(streamID string) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []Event
+
+	for _, e := range s.events {
+		if e.StreamID == streamID {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+// Position returns the position of the last event in the store.
+func (s *Store) Position() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.events)
+}
+
+// 4. Advanced: subscriptions.
+// Subscribe delivers events after the position from, so a projection can start from where it stopped.
+// It sends the events that are in the store and closes the channel: events appended later are never delivered.
+// A subscription must catch up with history and then keep delivering new events as they are appended,
+// in order, without gaps and duplicates, until the context is cancelled, then it closes the channel.
+// Wait for new events on the changed channel, and take it under the same lock as the events you read,
+// or an append between the two is missed.
+
+// Subscribe returns a channel of events after the position from, in order.
+func (s *Store) Subscribe(ctx context.Context, from int) <-chan Event {
+	s.mu.Lock()
+	events := slices.Clone(s.events[min(from, len(s.events)):])
+	s.mu.Unlock()
+
+	ch := make(chan Event)
+
+	go func() {
+		defer close(ch)
+
+		for _, e := range events {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	s := NewStore()
+
+	if v, err := s.Append("acc-1", 0, AccountOpened{Owner: "Alice"}); v != 1 || err != nil {
+		t.Fatalf("Expected version 1, got %d, %v", v, err)
+	}
+
+	// Another handler read the stream before the first event.
+	_, err := s.Append("acc-1", 0, AccountOpened{Owner: "Bob"})
+	if !errors.Is(err, ErrConcurrencyConflict) {
+		t.Fatalf("Expected ErrConcurrencyConflict, got %v", err)
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "acc-1") || !strings.Contains(msg, "0") || !strings.Contains(msg, "1") {
+		t.Errorf("Expected the error to name the stream and the versions, got %q", msg)
+	}
+
+	if v, err := s.Append("acc-1", 1, MoneyDeposited{Amount: 100}, MoneyDeposited{Amount: 50}); v != 3 || err != nil {
+		t.Errorf("Expected version 3, got %d, %v", v, err)
+	}
+
+	if _, err := s.Append("acc-2", 2, MoneyDeposited{Amount: 100}); !errors.Is(err, ErrConcurrencyConflict) {
+		t.Errorf("Expected ErrConcurrencyConflict for a stream that doesn't exist yet, got %v", err)
+	}
+
+	if events := s.Load("acc-1"); len(events) != 3 || s.Position() != 3 {
+		t.Errorf("Expected nothing to be appended on conflicts, got %d events", len(events))
+	}
+
+	if events := s.Load("acc-2"); len(events) != 0 {
+		t.Errorf("Expected nothing to be appended on conflicts, got %v", events)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := NewStore()
+	s.Append("acc-1", 0, AccountOpened{Owner: "Alice"}, MoneyDeposited{Amount: 10}, MoneyDeposited{Amount: 20})
+
+	events := s.Subscribe(ctx, 1)
+
+	// Events appended concurrently with the catch-up must not be lost.
+	go func() {
+		for i := range 50 {
+			s.Append(fmt.Sprintf("acc-%d", i+2), 0, AccountOpened{Owner: "Bob"})
+		}
+	}()
+
+	for position := 2; position <= 53; position++ {
+		e, ok := <-events
+		if !ok {
+			t.Fatalf("Expected event at position %d, the channel was closed", position)
+		}
+
+		if e.Position != position {
+			t.Fatalf("Expected event at position %d, got %d", position, e.Position)
+		}
+	}
+
+	cancel()
+
+	for range events {
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package extensions
+
+import "testing"
+
+func TestLoadPlugin(t *testing.T) {
+	t.Skip("Plugins are supported only on Linux, macOS, and FreeBSD with cgo enabled")
+}
@@ -0,0 +1,58 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package extensions
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+)
+
+// 2. Plugins.
+// A plugin is a package main built with -buildmode=plugin. plugin.Open loads it, runs its init functions,
+// and Lookup returns a pointer to an exported variable or a function by name.
+// The host doesn't need to import anything from the plugin: Processor is an interface,
+// and any value with its methods satisfies it.
+//
+// Implement LoadPlugin, it loads the plugin and returns the variable named Processor as a Processor.
+// The test builds testdata/shout and loads it. Open fails when the plugin was built by another version of Go
+// or with other versions of shared packages, that's the price of plugins.
+
+// LoadPlugin loads the Processor exported by the plugin at path.
+func LoadPlugin(path string) (Processor, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestLoadPlugin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shout.so")
+
+	args := []string{"build", "-buildmode=plugin", "-o", path}
+
+	// The plugin must be built like the test binary, or Open refuses to load it.
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "-race" && s.Value == "true" {
+				args = append(args, "-race")
+			}
+		}
+	}
+
+	if out, err := exec.Command("go", append(args, "./testdata/shout")...).CombinedOutput(); err != nil {
+		t.Fatalf("Building the plugin failed: %v\n%s", err, out)
+	}
+
+	p, err := LoadPlugin(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got, err := p.Process("gopher"); p.Name() != "shout" || got != "GOPHER!" || err != nil {
+		t.Errorf("Expected the shout processor to return %q, got %s returning %q, %v", "GOPHER!", p.Name(), got, err)
+	}
+
+	if _, err := LoadPlugin(filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Error("Expected an error for a missing plugin")
+	}
+}
@@ -0,0 +1,93 @@
+package extensions
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// A program can be extended without changing its code in a few ways:
+// - a registry of implementations of an interface, filled by init functions of packages that are linked in,
+//   like database/sql drivers and image formats. Extensions are compiled into the binary, a blank import enables one,
+// - plugins: shared objects loaded at runtime with the plugin package. Only on Linux, macOS, and FreeBSD with cgo,
+//   and the plugin must be built by exactly the same toolchain with the same versions of all shared packages,
+// - separate processes called over RPC, like Terraform providers and HashiCorp's go-plugin.
+//   Any version of Go, or any language, and a crash of an extension doesn't take down the host.
+//
+// All three expose the same interface to the host, here it's Processor.
+
+// Processor transforms text, extensions provide processors.
+type Processor interface {
+	Name() string
+	Process(input string) (string, error)
+}
+
+// 1. A registry.
+// upper_test.go and reverse_test.go register their processors in init functions, implement the registry:
+// - Register adds a processor, and panics if the name is already taken, like sql.Register does:
+//   two extensions with the same name are a bug of the program, not an error to handle,
+// - Processors returns the names of registered processors sorted,
+// - Run dispatches input to the processor by name, and returns ErrUnknownProcessor for an unknown one.
+// Init functions run one at a time, but Run is called from many goroutines, and nothing stops Register
+// from being called later, so protect the registry with a mutex.
+
+// ErrUnknownProcessor is returned by Run for names that aren't registered.
+var ErrUnknownProcessor = errors.New("unknown processor")
+
+// Register makes the processor available by its name.
+func Register(p Processor) {
+}
+
+// Processors returns sorted names of registered processors.
+func Processors() []string {
+	return nil
+}
+
+// Run processes input with the processor registered under the name.
+func Run(name, input string) (string, error) {
+	return "", ErrUnknownProcessor
+}
+
+func TestDiscovery(t *testing.T) {
+	expected := []string{"reverse", "upper"}
+
+	if got := Processors(); !slices.Equal(got, expected) {
+		t.Errorf("Expected processors %v registered by init functions, got %v", expected, got)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name, input, expected string
+	}{
+		{"upper", "gopher", "GOPHER"},
+		{"reverse", "gopher", "rehpog"},
+		{"reverse", "привет", "тевирп"},
+	}
+
+	for _, tt := range tests {
+		got, err := Run(tt.name, tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("Expected %s to return %q, got %q, %v", tt.name, tt.expected, got, err)
+		}
+	}
+
+	if _, err := Run("lower", "Gopher"); !errors.Is(err, ErrUnknownProcessor) {
+		t.Errorf("Expected ErrUnknownProcessor for an unknown processor, got %v", err)
+	}
+
+	if _, err := Run("reverse", ""); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Errorf("Expected an error of the processor to be returned, got %v", err)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Register to panic for a name that is already registered")
+		}
+	}()
+
+	Register(upper{})
+}
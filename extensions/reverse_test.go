@@ -0,0 +1,25 @@
+package extensions
+
+import (
+	"errors"
+	"slices"
+)
+
+func init() {
+	Register(reverse{})
+}
+
+type reverse struct{}
+
+func (reverse) Name() string { return "reverse" }
+
+func (reverse) Process(input string) (string, error) {
+	if input == "" {
+		return "", errors.New("reverse: empty input")
+	}
+
+	runes := []rune(input)
+	slices.Reverse(runes)
+
+	return string(runes), nil
+}
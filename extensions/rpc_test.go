@@ -0,0 +1,142 @@
+package extensions
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"strings"
+	"testing"
+)
+
+// 3. Advanced: extensions over RPC.
+// net/rpc calls methods of a remote object: the server registers a value, and its exported methods
+// of the form func (t *T) Method(args A, reply *R) error become callable as "Name.Method".
+// It's frozen and gob-based, gRPC is the usual choice today, but the idea is the same:
+// the extension is a separate process, and the host talks to it through an adapter with the Processor interface.
+//
+// ProcessorService serves a processor, implement the host side: DialProcessor connects to the service
+// and returns a Processor that calls it. Errors returned by the remote processor must come back as errors,
+// and a call to a service that went away must fail, not hang or panic.
+
+// ProcessorService exposes a Processor over net/rpc.
+type ProcessorService struct {
+	p Processor
+}
+
+// Name returns the name of the processor.
+func (s *ProcessorService) Name(_ struct{}, reply *string) error {
+	*reply = s.p.Name()
+	return nil
+}
+
+// Process runs the processor on the input.
+func (s *ProcessorService) Process(input string, reply *string) error {
+	out, err := s.p.Process(input)
+	*reply = out
+
+	return err
+}
+
+// DialProcessor connects to a ProcessorService at the address and returns it as a Processor.
+func DialProcessor(addr string) (Processor, error) {
+	return nil, errors.New("not implemented")
+}
+
+type rot13 struct{}
+
+func (rot13) Name() string { return "rot13" }
+
+func (rot13) Process(input string) (string, error) {
+	if input == "" {
+		return "", errors.New("rot13: empty input")
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return 'a' + (r-'a'+13)%26
+		case r >= 'A' && r <= 'Z':
+			return 'A' + (r-'A'+13)%26
+		}
+
+		return r
+	}, input), nil
+}
+
+// serveProcessor serves the processor on a local port until the returned stop function is called.
+func serveProcessor(t *testing.T, p Processor) (addr string, stop func()) {
+	t.Helper()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Processor", &ProcessorService{p: p}); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var conns []net.Conn
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			conns = append(conns, conn)
+
+			go server.ServeConn(conn)
+		}
+	}()
+
+	stop = func() {
+		l.Close()
+		<-done
+
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+
+	t.Cleanup(stop)
+
+	return l.Addr().String(), stop
+}
+
+func TestDialProcessor(t *testing.T) {
+	addr, stop := serveProcessor(t, rot13{})
+
+	p, err := DialProcessor(addr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if p.Name() != "rot13" {
+		t.Errorf("Expected the name of the remote processor rot13, got %q", p.Name())
+	}
+
+	if got, err := p.Process("Hello, Gopher"); got != "Uryyb, Tbcure" || err != nil {
+		t.Errorf("Expected %q, got %q, %v", "Uryyb, Tbcure", got, err)
+	}
+
+	if _, err := p.Process(""); err == nil || !strings.Contains(err.Error(), "empty input") {
+		t.Errorf("Expected the error of the remote processor, got %v", err)
+	}
+
+	stop()
+
+	if _, err := p.Process("gone"); err == nil {
+		t.Error("Expected an error after the service stopped")
+	}
+
+	if _, err := DialProcessor(addr); err == nil {
+		t.Error("Expected an error dialing a stopped service")
+	}
+}
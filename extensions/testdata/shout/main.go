@@ -0,0 +1,17 @@
+// Package main is a plugin with a Processor, build it with:
+//
+//	go build -buildmode=plugin -o shout.so ./extensions/testdata/shout
+package main
+
+import "strings"
+
+type shout struct{}
+
+func (shout) Name() string { return "shout" }
+
+func (shout) Process(input string) (string, error) {
+	return strings.ToUpper(input) + "!", nil
+}
+
+// Processor is the symbol the host looks up.
+var Processor shout
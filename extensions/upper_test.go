@@ -0,0 +1,15 @@
+package extensions
+
+import "strings"
+
+func init() {
+	Register(upper{})
+}
+
+type upper struct{}
+
+func (upper) Name() string { return "upper" }
+
+func (upper) Process(input string) (string, error) {
+	return strings.ToUpper(input), nil
+}
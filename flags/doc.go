@@ -0,0 +1,5 @@
+// Package flags is the Feature Flags workshop.
+//
+// Exercises are in the _test.go files, every exercise starts with a comment explaining the topic
+// and what should be fixed. Run them with: go run ./cmd/workshop verify flags
+package flags
@@ -0,0 +1,294 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/testsync"
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// 2. Hot Reload.
+// Flags that need a restart to change aren't much better than a deploy. File reads flags from a JSON file
+// like {"new-checkout": {"percent": 10}}, and reloads it while the service runs: every request evaluates
+// flags while the file is reloaded, so reloading must never show a reader half of the old set and half
+// of the new one.
+//
+// Reload now clears the map and fills it again, in place. Readers race with it, and a reader that comes
+// in between sees no flags at all. Build the new set on the side, and swap it in with atomic.Pointer
+// in one step, the way Memory does: readers Load the pointer, and use the set it points to, which nobody changes.
+//
+// A broken file must not break the service either. A typo in JSON, or a percent out of 0..100, is an error of
+// Reload, and the flags loaded last stay in effect: keep the last good configuration.
+//
+// Watch reloads the file when it changes, until the context is done. github.com/fsnotify/fsnotify tells about
+// changes of files by inotify and its analogs, without polling. Watch below watches the file itself, and misses
+// changes: editors and Kubernetes ConfigMaps replace a file by renaming a new one over it, and the watch
+// of the old file is gone with it. Watch the directory, and pick events of the file by their name.
+//
+// A save is a few events, a create and a write or two, and the same content may be saved again: reload only
+// when the content of the file differs from the content seen last, valid or not, so an invalid file
+// is reported to onError once, not on every event. The file may also change between OpenFile and the moment
+// the watch is added, check it once right after adding the watch.
+
+// File is a provider of flags from a JSON file, it's safe for concurrent use.
+type File struct {
+	path string
+
+	mu    sync.Mutex
+	flags map[string]Flag
+}
+
+// OpenFile loads flags from the file at the path.
+func OpenFile(path string) (*File, error) {
+	f := &File{path: path, flags: make(map[string]Flag)}
+
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Reload reads the file again. If the file is invalid, it returns an error and keeps the flags loaded last.
+func (f *File) Reload() error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("read flags: %w", err)
+	}
+
+	var loaded map[string]Flag
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("parse flags %s: %w", f.path, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	clear(f.flags)
+	maps.Copy(f.flags, loaded)
+
+	return nil
+}
+
+// Watch reloads the file when it changes, until the context is done. Errors of reloading are passed to onError,
+// it returns an error only when the file can't be watched.
+func (f *File) Watch(ctx context.Context, onError func(error)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch flags: %w", err)
+	}
+	defer w.Close()
+
+	if err := w.Add(f.path); err != nil {
+		return fmt.Errorf("watch flags: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+
+			if err := f.Reload(); err != nil {
+				onError(err)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+
+			onError(err)
+		}
+	}
+}
+
+// Flags returns the current set of flags, it must not be changed.
+func (f *File) Flags() map[string]Flag {
+	return f.flags
+}
+
+func (f *File) Enabled(flag, userID string) bool {
+	return enabled(f.Flags(), flag, userID)
+}
+
+// writeFlags writes the flags to the file the way editors do: to a temporary file renamed over the old one.
+func writeFlags(t *testing.T, path, content string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func openTestFile(t *testing.T, content string) (*File, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "flags.json")
+	writeFlags(t, path, content)
+
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return f, path
+}
+
+func TestFileReload(t *testing.T) {
+	g := grader.New(t)
+
+	f, path := openTestFile(t, `{"new-checkout": {"percent": 100}}`)
+	g.Equal(`Enabled("new-checkout", "user-1")`, true, f.Enabled("new-checkout", "user-1"))
+
+	writeFlags(t, path, `{"new-checkout": {"percent": 0}, "dark-mode": {"percent": 100}}`)
+	g.Equal("Reload()", nil, f.Reload())
+	g.Equal(`Enabled("new-checkout", "user-1") after reload`, false, f.Enabled("new-checkout", "user-1"))
+	g.Equal(`Enabled("dark-mode", "user-1") after reload`, true, f.Enabled("dark-mode", "user-1"))
+
+	// Broken files are rejected, and the flags loaded last stay.
+	for _, content := range []string{`{"dark-mode": {"percent": 0}`, `{"dark-mode": {"percent": 150}}`, `{"dark-mode": {"percent": -1}}`} {
+		writeFlags(t, path, content)
+
+		g.Hint("validate the whole file before swapping the flags").
+			Check("Reload() of "+content, f.Reload() != nil, "expected an error")
+		g.Hint("keep the last good configuration").
+			Equal(`Enabled("dark-mode", "user-1") after Reload() of `+content, true, f.Enabled("dark-mode", "user-1"))
+	}
+
+	if _, err := OpenFile(path); err == nil {
+		t.Error("Expected OpenFile to fail for an invalid file")
+	}
+}
+
+func TestFileWatch(t *testing.T) {
+	f, path := openTestFile(t, `{"new-checkout": {"percent": 0}}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	var (
+		errs     atomic.Int32
+		watchErr error
+	)
+
+	go func() {
+		defer close(done)
+		watchErr = f.Watch(ctx, func(error) { errs.Add(1) })
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	writeFlags(t, path, `{"new-checkout": {"percent": 100}}`)
+
+	if !testsync.WaitUntil(func() bool { return f.Enabled("new-checkout", "user-1") }, time.Second) {
+		t.Fatal("Expected Watch to reload the changed file")
+	}
+
+	writeFlags(t, path, `{"new-checkout": {"percent": 1000}}`)
+
+	if !testsync.WaitUntil(func() bool { return errs.Load() > 0 }, time.Second) {
+		t.Fatal("Expected Watch to reload the file changed again, and pass the error of an invalid file to onError")
+	}
+
+	// Saving the same invalid file again is not another error. Events come in order,
+	// so when the valid file is loaded, events of the invalid saves are handled.
+	writeFlags(t, path, `{"new-checkout": {"percent": 1000}}`)
+	writeFlags(t, path, `{"new-checkout": {"percent": 0}}`)
+
+	if !testsync.WaitUntil(func() bool { return !f.Enabled("new-checkout", "user-1") }, time.Second) {
+		t.Fatal("Expected Watch to reload the file fixed after an invalid one")
+	}
+
+	if n := errs.Load(); n != 1 {
+		t.Errorf("Expected the invalid file to be reported once, got %d errors", n)
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+		if watchErr != nil {
+			t.Errorf("Unexpected error: %v", watchErr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Watch to return when the context is done")
+	}
+}
+
+func TestFileConcurrentReload(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	// Both flags always have the same percent, a reader that sees them differ saw half of a reload.
+	content := func(percent int) string {
+		return fmt.Sprintf(`{"new-checkout": {"percent": %d}, "dark-mode": {"percent": %d}}`, percent, percent)
+	}
+
+	f, path := openTestFile(t, content(0))
+
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	var torn atomic.Int64
+
+	for range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				flags := f.Flags()
+				if flags["new-checkout"] != flags["dark-mode"] {
+					torn.Add(1)
+				}
+
+				f.Enabled("new-checkout", "user-1")
+			}
+		}()
+	}
+
+	for i := range 200 {
+		writeFlags(t, path, content(i%101))
+
+		if err := f.Reload(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	close(stop)
+	wg.Wait()
+
+	if n := torn.Load(); n > 0 {
+		t.Errorf("Expected readers to see whole sets of flags, %d reads saw half of a reload", n)
+	}
+}
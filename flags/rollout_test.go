@@ -0,0 +1,180 @@
+package flags
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/grader"
+)
+
+// 1. Rollouts.
+// A feature flag turns a feature on without a deploy, and off again when it breaks. Turning it on for everyone
+// at once is a deploy in disguise, so features roll out gradually: to 1% of users, then 10%, then all of them.
+//
+// Which users are in the 10%? A random draw on every request shows the feature to a user on one page and hides it
+// on the next. The choice must be stable: every user gets a bucket from 0 to 99 computed from the user ID,
+// and the flag is on for users with buckets below its percent. A hash of the ID gives the same bucket on every
+// request, on every instance of the service, and after restarts, and raising the percent only adds users,
+// nobody who already has the feature loses it.
+//
+// The bucket hashes the flag name with the user ID: with the ID alone, the same 10% of users would get every
+// new feature first, and a bug in one of them would hit the same unlucky users again and again.
+//
+// Bucket below draws a random number, let's make it stable: hash the flag name, a ':', and the user ID
+// with FNV-1a from hash/fnv, 32 bits are enough, and take the hash modulo 100. Tests check exact buckets,
+// so use exactly this input: every service that evaluates flags must agree on it, in any language.
+
+// Flag is the configuration of a feature flag.
+type Flag struct {
+	// Percent is the share of users the flag is on for, from 0 for nobody to 100 for everyone.
+	Percent int `json:"percent"`
+}
+
+// Provider tells whether features are on.
+type Provider interface {
+	// Enabled reports whether the flag is on for the user, unknown flags are off.
+	Enabled(flag, userID string) bool
+}
+
+var (
+	_ Provider = (*Memory)(nil)
+	_ Provider = (*File)(nil)
+)
+
+// Bucket returns the bucket of the user for the flag, from 0 to 99.
+func Bucket(flag, userID string) int {
+	return rand.IntN(100)
+}
+
+// enabled evaluates the flag for the user with a set of flags.
+func enabled(flags map[string]Flag, flag, userID string) bool {
+	f, ok := flags[flag]
+
+	return ok && Bucket(flag, userID) < f.Percent
+}
+
+// Memory is a provider of flags set by the code, like an admin API or tests.
+// Flags are replaced as a whole set, readers see either the old set or the new one.
+type Memory struct {
+	flags atomic.Pointer[map[string]Flag]
+}
+
+// NewMemory creates a provider with the flags.
+func NewMemory(flags map[string]Flag) *Memory {
+	m := &Memory{}
+	m.Set(flags)
+
+	return m
+}
+
+// Set replaces all flags, the map must not be changed after the call.
+func (m *Memory) Set(flags map[string]Flag) {
+	m.flags.Store(&flags)
+}
+
+func (m *Memory) Enabled(flag, userID string) bool {
+	return enabled(*m.flags.Load(), flag, userID)
+}
+
+func userIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("user-%d", i)
+	}
+
+	return ids
+}
+
+func TestBucket(t *testing.T) {
+	g := grader.New(t)
+
+	// Golden values: other services compute the same buckets.
+	tests := []struct {
+		flag, user string
+		expected   int
+	}{
+		{"new-checkout", "user-1", 63},
+		{"new-checkout", "user-2", 82},
+		{"dark-mode", "user-1", 58},
+		{"dark-mode", "alice@example.com", 40},
+	}
+
+	for _, tt := range tests {
+		g.Hint("hash flag + \":\" + userID with fnv.New32a, then take the hash modulo 100").
+			Equal(fmt.Sprintf("Bucket(%q, %q)", tt.flag, tt.user), tt.expected, Bucket(tt.flag, tt.user))
+	}
+
+	for _, id := range userIDs(1000) {
+		if b := Bucket("new-checkout", id); !g.Check(fmt.Sprintf("Bucket(new-checkout, %s)", id),
+			b >= 0 && b < 100 && b == Bucket("new-checkout", id), "expected the same bucket from 0 to 99 every time, got %d", b) {
+			break
+		}
+	}
+}
+
+func TestRollout(t *testing.T) {
+	g := grader.New(t)
+
+	users := userIDs(10000)
+	flags := NewMemory(map[string]Flag{"new-checkout": {Percent: 10}})
+
+	count := func() (on map[string]bool) {
+		on = make(map[string]bool)
+
+		for _, id := range users {
+			if flags.Enabled("new-checkout", id) {
+				on[id] = true
+			}
+		}
+
+		return on
+	}
+
+	first := count()
+	g.Check("10% rollout", len(first) > 900 && len(first) < 1100, "expected about 1000 of 10000 users, got %d", len(first))
+
+	g.Hint("a user must get the same answer on every request").Check("10% rollout evaluated twice",
+		maps.Equal(first, count()), "expected the same users both times")
+
+	// Raising the percent adds users, and keeps the ones who already have the feature.
+	flags.Set(map[string]Flag{"new-checkout": {Percent: 50}})
+	raised := count()
+
+	lost := 0
+
+	for id := range first {
+		if !raised[id] {
+			lost++
+		}
+	}
+
+	g.Check("50% rollout", len(raised) > 4800 && len(raised) < 5200, "expected about 5000 of 10000 users, got %d", len(raised))
+	g.Hint("the flag is on for buckets below the percent").Equal("users of the 10% rollout that lost the feature at 50%", 0, lost)
+
+	flags.Set(map[string]Flag{"new-checkout": {Percent: 100}})
+	g.Equal("users of a 100% rollout", len(users), len(count()))
+
+	flags.Set(map[string]Flag{"new-checkout": {Percent: 0}})
+	g.Equal("users of a 0% rollout", 0, len(count()))
+	g.Equal(`Enabled("unknown", "user-1")`, false, flags.Enabled("unknown", "user-1"))
+}
+
+func TestRolloutIndependentFlags(t *testing.T) {
+	g := grader.New(t)
+
+	flags := NewMemory(map[string]Flag{"new-checkout": {Percent: 10}, "dark-mode": {Percent: 10}})
+	both := 0
+
+	for _, id := range userIDs(10000) {
+		if flags.Enabled("new-checkout", id) && flags.Enabled("dark-mode", id) {
+			both++
+		}
+	}
+
+	// Independent 10% rollouts overlap in about 1% of users, not in all 10%.
+	g.Hint("the bucket depends on the flag name too").Check("users with both 10% flags", both < 300,
+		"expected about 100 of 10000 users, got %d", both)
+}
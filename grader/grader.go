@@ -0,0 +1,186 @@
+// Package grader provides assertions for workshop exercises with structured reporting.
+//
+// A failed assertion is reported with t.Error like any other test failure, so exercises work with plain go test.
+// When tests run under the workshop runner, failed assertions are also appended as JSON lines
+// to the file named by ReportEnv, and the runner includes them in the report of the exercise.
+package grader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// ReportEnv is the environment variable with the path of the file where failed assertions are written.
+const ReportEnv = "WORKSHOP_GRADER_REPORT"
+
+// Assertion is a failed assertion in the report.
+type Assertion struct {
+	Test    string `json:"test"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// reportMu serializes writes to the report file from parallel tests.
+var reportMu sync.Mutex
+
+// Grader checks the results of an exercise.
+type Grader struct {
+	tb   testing.TB
+	hint string
+}
+
+// New creates a grader for the test.
+func New(tb testing.TB) *Grader {
+	return &Grader{tb: tb}
+}
+
+// Hint returns a grader that shows the hint with its failed assertions.
+// Hints are shown only when something fails, and they are counted in the report.
+func (g *Grader) Hint(hint string) *Grader {
+	return &Grader{tb: g.tb, hint: hint}
+}
+
+// Check reports a failed assertion with the formatted message if ok is false.
+func (g *Grader) Check(name string, ok bool, format string, args ...any) bool {
+	g.tb.Helper()
+
+	if !ok {
+		g.fail(name, fmt.Sprintf(format, args...))
+	}
+
+	return ok
+}
+
+// Equal checks that got is deeply equal to expected.
+func (g *Grader) Equal(name string, expected, got any) bool {
+	g.tb.Helper()
+
+	return g.Check(name, reflect.DeepEqual(expected, got), "expected %#v, got %#v", expected, got)
+}
+
+// NoError checks that err is nil.
+// The type is reported along with the value, a typed nil pointer in an error interface is not nil.
+func (g *Grader) NoError(name string, err error) bool {
+	g.tb.Helper()
+
+	return g.Check(name, err == nil, "expected no error, got %T: %v", err, err)
+}
+
+// Error checks that err is not nil and its message is expected.
+func (g *Grader) Error(name string, err error, expected string) bool {
+	g.tb.Helper()
+
+	if err == nil {
+		return g.Check(name, false, "expected error %q, got nil", expected)
+	}
+
+	return g.Check(name, err.Error() == expected, "expected error %q, got %q", expected, err.Error())
+}
+
+// ErrorIs checks that err matches target with errors.Is.
+func (g *Grader) ErrorIs(name string, err, target error) bool {
+	g.tb.Helper()
+
+	return g.Check(name, errors.Is(err, target), "expected error matching %q, got %v", target, err)
+}
+
+// ErrorAs checks that err matches target with errors.As and sets target to the matching error.
+func (g *Grader) ErrorAs(name string, err error, target any) bool {
+	g.tb.Helper()
+
+	return g.Check(name, errors.As(err, target), "expected error of type %s, got %T: %v",
+		reflect.TypeOf(target).Elem(), err, err)
+}
+
+// NoPanic calls fn and checks that it doesn't panic.
+// A panic fails the assertion instead of crashing the test binary, so other assertions are still checked.
+func (g *Grader) NoPanic(name string, fn func()) bool {
+	g.tb.Helper()
+
+	var recovered any
+
+	func() {
+		defer func() { recovered = recover() }()
+
+		fn()
+	}()
+
+	return g.Check(name, recovered == nil, "unexpected panic: %v", recovered)
+}
+
+func (g *Grader) fail(name, message string) {
+	g.tb.Helper()
+
+	msg := name + ": " + message
+	if g.hint != "" {
+		msg += "\nHint: " + g.hint
+	}
+
+	g.tb.Error(msg)
+
+	if err := report(Assertion{Test: g.tb.Name(), Name: name, Message: message, Hint: g.hint}); err != nil {
+		g.tb.Error("grader: failed to write report: " + err.Error())
+	}
+}
+
+// report appends the assertion to the report file if the runner asked for it.
+func report(a Assertion) error {
+	path := os.Getenv(ReportEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	reportMu.Lock()
+	defer reportMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadReport reads assertions written to the report file.
+// A missing file means no assertions failed.
+func ReadReport(path string) ([]Assertion, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grader report: %w", err)
+	}
+
+	var assertions []Assertion
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var a Assertion
+		if err := dec.Decode(&a); err != nil {
+			return nil, fmt.Errorf("failed to parse grader report: %w", err)
+		}
+
+		assertions = append(assertions, a)
+	}
+
+	return assertions, nil
+}
@@ -0,0 +1,122 @@
+package grader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeTB records errors instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper()      {}
+func (f *fakeTB) Name() string { return "TestFake" }
+
+func (f *fakeTB) Error(args ...any) {
+	f.errors = append(f.errors, args[0].(string))
+}
+
+type codeError struct{ code int }
+
+func (e *codeError) Error() string { return fmt.Sprintf("code %d", e.code) }
+
+func TestAssertionsPass(t *testing.T) {
+	tb := &fakeTB{}
+	g := New(tb)
+	wrapped := fmt.Errorf("wrapped: %w", &codeError{code: 42})
+
+	var codeErr *codeError
+
+	results := []bool{
+		g.Check("check", true, "unused"),
+		g.Equal("equal", []int{1, 2}, []int{1, 2}),
+		g.NoError("no error", nil),
+		g.Error("error", errors.New("boom"), "boom"),
+		g.ErrorIs("is", wrapped, wrapped),
+		g.ErrorAs("as", wrapped, &codeErr),
+		g.NoPanic("no panic", func() {}),
+	}
+
+	if slices.Contains(results, false) || len(tb.errors) > 0 {
+		t.Errorf("Expected all assertions to pass, got %v with errors %q", results, tb.errors)
+	}
+
+	if codeErr == nil || codeErr.code != 42 {
+		t.Errorf("Expected ErrorAs to set target, got %v", codeErr)
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	var nilErr *codeError
+
+	tests := []struct {
+		name     string
+		assert   func(g *Grader) bool
+		expected string
+	}{
+		{"check", func(g *Grader) bool { return g.Check("check", false, "got %d", 1) }, "check: got 1"},
+		{"equal", func(g *Grader) bool { return g.Equal("equal", 5, 6) }, "equal: expected 5, got 6"},
+		{"typed nil", func(g *Grader) bool { return g.NoError("nil", nilErr) }, "nil: expected no error, got *grader.codeError: "},
+		{"nil error", func(g *Grader) bool { return g.Error("error", nil, "boom") }, `error: expected error "boom", got nil`},
+		{"message", func(g *Grader) bool { return g.Error("error", errors.New("bang"), "boom") }, `error: expected error "boom", got "bang"`},
+		{"is", func(g *Grader) bool { return g.ErrorIs("is", errors.New("a"), fs.ErrNotExist) }, `is: expected error matching "file does not exist", got a`},
+		{"as", func(g *Grader) bool { var c *codeError; return g.ErrorAs("as", errors.New("a"), &c) }, "as: expected error of type *grader.codeError, got *errors.errorString: a"},
+		{"panic", func(g *Grader) bool { return g.NoPanic("panic", func() { panic("boom") }) }, "panic: unexpected panic: boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &fakeTB{}
+
+			if tt.assert(New(tb)) {
+				t.Error("Expected assertion to fail")
+			}
+
+			if len(tb.errors) != 1 || !strings.HasPrefix(tb.errors[0], tt.expected) {
+				t.Errorf("Expected error %q, got %q", tt.expected, tb.errors)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+	t.Setenv(ReportEnv, path)
+
+	assertions, err := ReadReport(path)
+	if err != nil || assertions != nil {
+		t.Fatalf("Expected no assertions before the run, got %v, %v", assertions, err)
+	}
+
+	tb := &fakeTB{}
+	g := New(tb)
+
+	g.Equal("first", 1, 2)
+	g.Hint("look closer").Check("second", false, "failed")
+	g.Check("passed", true, "unused")
+
+	assertions, err = ReadReport(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Assertion{
+		{Test: "TestFake", Name: "first", Message: "expected 1, got 2"},
+		{Test: "TestFake", Name: "second", Message: "failed", Hint: "look closer"},
+	}
+
+	if !slices.Equal(assertions, expected) {
+		t.Errorf("Expected assertions %+v, got %+v", expected, assertions)
+	}
+
+	if tb.errors[1] != "second: failed\nHint: look closer" {
+		t.Errorf("Expected hint to be shown with the failure, got %q", tb.errors[1])
+	}
+}
@@ -0,0 +1,85 @@
+package graphqlbasics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// 3. Advanced: limiting depth.
+// Clients choose the shape of the query, including the bad ones. friends of friends of friends grows
+// exponentially with every level, and a query a few hundred bytes long can make the server load the whole
+// database. Public GraphQL APIs limit queries before executing them: by depth, by an estimated cost, or to
+// a list of queries known in advance.
+//
+// Implement CheckDepth: walk selection sets of every operation of the parsed document, and fail when a field
+// is nested deeper than limit. { user(id: 1) { name } } has depth 2. Fragments don't count as levels,
+// their fields do: follow fragment spreads to the definitions of fragments, and inline fragments right away.
+// Fragments may spread each other in a cycle, which the validation rejects later, after CheckDepth,
+// so don't follow a fragment that's already on the way, or the check never ends.
+// Then parse the query in Handler, with parser.Parse, and respond to queries deeper than MaxDepth with
+// an error without executing them.
+
+// MaxDepth is the deepest nesting of fields Handler executes.
+const MaxDepth = 5
+
+// CheckDepth returns an error when fields of the document are nested deeper than limit.
+func CheckDepth(doc *ast.Document, limit int) error {
+	return nil
+}
+
+func TestCheckDepth(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		depth int
+	}{
+		{"flat", `{ posts { title } }`, 2},
+		{"nested", `{ posts { author { friends { name } } } }`, 4},
+		{"widest branch", `{ posts { title } user(id: 1) { friends { friends { name } } } }`, 4},
+		{"deepest operation", `query A { user(id: 1) { name } } query B { posts { author { friends { name } } } }`, 4},
+		{"fragment", `{ user(id: 1) { ...F } } fragment F on User { friends { friends { name } } }`, 4},
+		{"inline fragment", `{ user(id: 1) { ... on User { friends { name } } } }`, 3},
+		{"fragment cycle", `{ user(id: 1) { ...A } } fragment A on User { friends { ...B } } fragment B on User { friends { ...A } }`, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if err := CheckDepth(doc, tt.depth); err != nil {
+				t.Errorf("Expected depth %d to be allowed with the limit %d, got %v", tt.depth, tt.depth, err)
+			}
+
+			if err := CheckDepth(doc, tt.depth-1); err == nil {
+				t.Errorf("Expected depth %d to be rejected with the limit %d", tt.depth, tt.depth-1)
+			}
+		})
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	store := testData()
+	server := serve(t, store)
+
+	_, r := query(t, server, map[string]any{"query": `{ posts { author { friends { friends { name } } } } }`})
+	if len(r.Errors) != 0 {
+		t.Fatalf("Expected a query of depth 5 to run, got errors %+v", r.Errors)
+	}
+
+	before := len(store.Queries())
+
+	_, r = query(t, server, map[string]any{"query": `{ posts { author { friends { friends { friends { name } } } } } }`})
+	if len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, "depth") {
+		t.Errorf("Expected an error about the depth of the query, got %+v", r.Errors)
+	}
+
+	if queries := store.Queries()[before:]; len(queries) != 0 {
+		t.Errorf("Expected a query over the limit not to run, the store got %v", queries)
+	}
+}
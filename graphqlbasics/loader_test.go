@@ -0,0 +1,171 @@
+package graphqlbasics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// 2. Batching with a loader.
+// Resolvers know only their own field. The author of every post is resolved separately, so a feed of 50 posts
+// makes 50 queries for authors after the query for posts: the N+1 problem. Their friends make even more.
+//
+// A loader fixes it the way the batching exercises do: it collects keys and fetches them in a single query.
+// The executor of graphql-go helps: when a resolver returns a thunk, a func() (any, error), the executor calls it
+// only after it has called the resolvers of all fields at the same level. So Load registers the ID in a pending
+// batch and returns a thunk, and the first thunk of a batch that's called fetches all IDs of the batch at once.
+// IDs loaded after that go to the next batch.
+//
+// A loader also caches users for the request: an ID that's loaded again, in a batch or fetched already, isn't
+// fetched twice. The cache is why a loader lives only as long as its request: a loader shared by requests
+// would serve stale users forever, and users of one client to another. Handler creates one per request.
+
+// Loader loads users in batches and caches them for a request.
+type Loader struct {
+	fetch func(ctx context.Context, ids []int) (map[int]User, error)
+}
+
+// NewLoader creates a loader that fetches users with fetch.
+func NewLoader(fetch func(ctx context.Context, ids []int) (map[int]User, error)) *Loader {
+	return &Loader{fetch: fetch}
+}
+
+// Load returns a thunk that returns the user with the ID, or ErrUserNotFound.
+func (l *Loader) Load(ctx context.Context, id int) func() (User, error) {
+	users, err := l.fetch(ctx, []int{id})
+
+	return func() (User, error) {
+		if err != nil {
+			return User{}, err
+		}
+
+		u, ok := users[id]
+		if !ok {
+			return User{}, fmt.Errorf("%w: %d", ErrUserNotFound, id)
+		}
+
+		return u, nil
+	}
+}
+
+type loaderKey struct{}
+
+// WithLoader returns a copy of the context with the loader.
+func WithLoader(ctx context.Context, l *Loader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+// LoaderFromContext returns the loader of the context, Handler puts one into the context of every request.
+func LoaderFromContext(ctx context.Context) *Loader {
+	return ctx.Value(loaderKey{}).(*Loader)
+}
+
+func TestLoader(t *testing.T) {
+	var batches [][]int
+
+	l := NewLoader(func(_ context.Context, ids []int) (map[int]User, error) {
+		batches = append(batches, slices.Clone(ids))
+
+		users := make(map[int]User)
+
+		for _, id := range ids {
+			if id != 99 {
+				users[id] = User{ID: id, Name: fmt.Sprintf("user-%d", id)}
+			}
+		}
+
+		return users, nil
+	})
+
+	ctx := context.Background()
+	thunks := []func() (User, error){l.Load(ctx, 1), l.Load(ctx, 2), l.Load(ctx, 1), l.Load(ctx, 99)}
+
+	if len(batches) != 0 {
+		t.Fatalf("Expected Load not to fetch anything before a thunk is called, got batches %v", batches)
+	}
+
+	for i, id := range []int{1, 2, 1} {
+		if u, err := thunks[i](); err != nil || u.ID != id {
+			t.Errorf("Expected user %d, got %+v, %v", id, u, err)
+		}
+	}
+
+	if _, err := thunks[3](); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for a user missing from the batch, got %v", err)
+	}
+
+	if fmt.Sprint(batches) != "[[1 2 99]]" {
+		t.Errorf("Expected a single batch of distinct IDs [[1 2 99]], got %v", batches)
+	}
+
+	// Cached users aren't fetched again, new ones go to a new batch.
+	cached, fresh := l.Load(ctx, 2), l.Load(ctx, 3)
+
+	if u, err := cached(); err != nil || u.ID != 2 {
+		t.Errorf("Expected user 2, got %+v, %v", u, err)
+	}
+
+	if u, err := fresh(); err != nil || u.ID != 3 {
+		t.Errorf("Expected user 3, got %+v, %v", u, err)
+	}
+
+	if fmt.Sprint(batches) != "[[1 2 99] [3]]" {
+		t.Errorf("Expected batches [[1 2 99] [3]], got %v", batches)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	errDown := errors.New("database is down")
+	fetches := 0
+
+	l := NewLoader(func(context.Context, []int) (map[int]User, error) {
+		fetches++
+		return nil, errDown
+	})
+
+	ctx := context.Background()
+	a, b := l.Load(ctx, 1), l.Load(ctx, 2)
+
+	for _, thunk := range []func() (User, error){a, b} {
+		if _, err := thunk(); !errors.Is(err, errDown) {
+			t.Errorf("Expected the error of the batch for every ID, got %v", err)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("Expected a single fetch for the batch, got %d", fetches)
+	}
+}
+
+func TestBatching(t *testing.T) {
+	store := testData()
+	server := serve(t, store)
+
+	_, r := query(t, server, map[string]any{"query": `{ posts { title author { name friends { name } } } }`})
+
+	expected := `{"posts":[` +
+		`{"title":"Goroutines","author":{"name":"alice","friends":[{"name":"bob"},{"name":"carol"}]}},` +
+		`{"title":"Channels","author":{"name":"bob","friends":[{"name":"alice"}]}},` +
+		`{"title":"Select","author":{"name":"alice","friends":[{"name":"bob"},{"name":"carol"}]}},` +
+		`{"title":"Context","author":{"name":"carol","friends":[{"name":"dave"}]}}]}`
+
+	if got := normalize(r.Data); got != normalize([]byte(expected)) || len(r.Errors) != 0 {
+		t.Fatalf("Expected data %s, got %s and errors %+v", expected, got, r.Errors)
+	}
+
+	// Friends 1, 2, and 3 are cached as authors already.
+	if queries := store.Queries(); fmt.Sprint(queries) != "[ListPosts GetUsers [1 2 3] GetUsers [4]]" {
+		t.Errorf("Expected a query for posts and a batch per level, got %d queries %v", len(queries), queries)
+	}
+
+	// The next request has a loader of its own.
+	store.Rename(1, "alice cooper")
+
+	_, r = query(t, server, map[string]any{"query": `{ user(id: 1) { name } }`})
+
+	if got := normalize(r.Data); got != `{"user":{"name":"alice cooper"}}` {
+		t.Errorf("Expected the new name in a new request, got %s", got)
+	}
+}
@@ -0,0 +1,391 @@
+package graphqlbasics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// GraphQL serves a graph of data through a single endpoint. The client sends a query that names exactly
+// the fields it needs, following relations as deep as it likes, and gets back JSON of the same shape:
+//
+//	query {                      {"data": {
+//	  user(id: 1) {                "user": {
+//	    name                         "name": "alice",
+//	    friends { name }             "friends": [{"name": "bob"}, {"name": "carol"}]
+//	  }                            }
+//	}                            }}
+//
+// The schema of this workshop, in the schema definition language:
+//
+//	type User { id: Int!  name: String!  friends: [User!]! }
+//	type Post { id: Int!  title: String!  author: User! }
+//	type Query { user(id: Int!): User  posts: [Post!]! }
+//
+// github.com/graphql-go/graphql builds the schema in Go: every field of every type has a resolver,
+// a function that returns its value from the value of its parent, Source of graphql.ResolveParams.
+// Fields without a resolver take the field of the struct with the same json tag.
+
+// User is a user of the service.
+type User struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	FriendIDs []int  `json:"-"`
+}
+
+// Post is a post written by a user.
+type Post struct {
+	ID       int    `json:"id"`
+	AuthorID int    `json:"-"`
+	Title    string `json:"title"`
+}
+
+// ErrUserNotFound is returned for users that don't exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// Store is the database of users and posts, it records the queries it gets.
+type Store struct {
+	mu      sync.Mutex
+	users   map[int]User
+	posts   []Post
+	queries []string
+}
+
+// NewStore creates a store with users and posts.
+func NewStore(users []User, posts []Post) *Store {
+	s := &Store{users: make(map[int]User), posts: posts}
+
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+
+	return s
+}
+
+// GetUsers returns users with the IDs, users that don't exist are missing from the map.
+func (s *Store) GetUsers(ctx context.Context, ids []int) (map[int]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries = append(s.queries, fmt.Sprintf("GetUsers %v", ids))
+
+	users := make(map[int]User, len(ids))
+
+	for _, id := range ids {
+		if u, ok := s.users[id]; ok {
+			users[id] = u
+		}
+	}
+
+	return users, nil
+}
+
+// ListPosts returns all posts.
+func (s *Store) ListPosts(ctx context.Context) ([]Post, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries = append(s.queries, "ListPosts")
+
+	return slices.Clone(s.posts), nil
+}
+
+// Rename changes the name of the user.
+func (s *Store) Rename(id int, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.users[id]
+	u.Name = name
+	s.users[id] = u
+}
+
+// Queries returns the queries the store got.
+func (s *Store) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.queries)
+}
+
+// NewSchema creates the schema over the store. Resolvers load users with the Loader of the request,
+// they return thunks, which the executor calls after it has resolved all fields of the same level.
+func NewSchema(store *Store) (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	// The type refers to itself, so the field is added after the type exists.
+	userType.AddFieldConfig("friends", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			loader := LoaderFromContext(p.Context)
+
+			var thunks []func() (User, error)
+			for _, id := range p.Source.(User).FriendIDs {
+				thunks = append(thunks, loader.Load(p.Context, id))
+			}
+
+			return func() (any, error) {
+				friends := make([]User, 0, len(thunks))
+
+				for _, thunk := range thunks {
+					u, err := thunk()
+					if err != nil {
+						return nil, err
+					}
+
+					friends = append(friends, u)
+				}
+
+				return friends, nil
+			}, nil
+		},
+	})
+
+	postType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"author": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return userThunk(LoaderFromContext(p.Context).Load(p.Context, p.Source.(Post).AuthorID)), nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return userThunk(LoaderFromContext(p.Context).Load(p.Context, p.Args["id"].(int))), nil
+				},
+			},
+			"posts": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return store.ListPosts(p.Context)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// userThunk adapts a thunk of the loader to the signature the executor expects.
+func userThunk(thunk func() (User, error)) func() (any, error) {
+	return func() (any, error) {
+		u, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+
+		return u, nil
+	}
+}
+
+// 1. Serving GraphQL over HTTP.
+// A GraphQL request is a POST with a JSON body of the query, variables, and the name of the operation to run
+// when the query has several of them. Values come in variables, never formatted into the query:
+// a name with a quote in it would change the query, like SQL injection. The response is JSON
+// with data and errors, and its status is 200 even with errors: an error of a field nulls that field,
+// the rest of the data is still there. A body that isn't JSON at all is a bad request, 400.
+//
+// Resolvers get the context of graphql.Params, and the store stops queries when it's done: pass the context
+// of the request, with the Loader of section 2 in it. Fix Handler: decode the whole request, reject bodies
+// that aren't JSON, and respond with Content-Type application/json.
+
+// Handler serves GraphQL queries against the schema.
+func Handler(schema graphql.Schema, store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&req)
+
+		ctx := WithLoader(context.Background(), NewLoader(store.GetUsers))
+
+		result := graphql.Do(graphql.Params{Schema: schema, RequestString: req.Query, Context: ctx})
+
+		json.NewEncoder(w).Encode(result)
+	})
+}
+
+// response is the response to a GraphQL request.
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+		Path    []any  `json:"path"`
+	} `json:"errors"`
+}
+
+func testData() *Store {
+	return NewStore(
+		[]User{
+			{ID: 1, Name: "alice", FriendIDs: []int{2, 3}},
+			{ID: 2, Name: "bob", FriendIDs: []int{1}},
+			{ID: 3, Name: "carol", FriendIDs: []int{4}},
+			{ID: 4, Name: "dave"},
+		},
+		[]Post{
+			{ID: 1, AuthorID: 1, Title: "Goroutines"},
+			{ID: 2, AuthorID: 2, Title: "Channels"},
+			{ID: 3, AuthorID: 1, Title: "Select"},
+			{ID: 4, AuthorID: 3, Title: "Context"},
+		},
+	)
+}
+
+// serve starts a GraphQL server over the store.
+func serve(t *testing.T, store *Store) *httptest.Server {
+	t.Helper()
+
+	schema, err := NewSchema(store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(Handler(schema, store))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// query sends the request to the server and decodes the response.
+func query(t *testing.T, server *httptest.Server, req map[string]any) (*http.Response, response) {
+	t.Helper()
+
+	body, _ := json.Marshal(req)
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatalf("Expected a JSON response, got %v", err)
+	}
+
+	return resp, r
+}
+
+// normalize re-encodes JSON with sorted keys and without spaces, so it can be compared as a string.
+func normalize(data []byte) string {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+
+	b, _ := json.Marshal(v)
+
+	return string(b)
+}
+
+func TestQuery(t *testing.T) {
+	server := serve(t, testData())
+
+	resp, r := query(t, server, map[string]any{
+		"query": `
+			query Profile($id: Int!) { user(id: $id) { name friends { name } } }
+			query Feed { posts { title } }`,
+		"operationName": "Profile",
+		"variables":     map[string]any{"id": 1},
+	})
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+
+	expected := `{"user":{"name":"alice","friends":[{"name":"bob"},{"name":"carol"}]}}`
+	if got := normalize(r.Data); got != normalize([]byte(expected)) || len(r.Errors) != 0 {
+		t.Errorf("Expected data %s, got %s and errors %+v", expected, got, r.Errors)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	server := serve(t, testData())
+
+	_, r := query(t, server, map[string]any{
+		"query":     `query($id: Int!) { user(id: $id) { name } }`,
+		"variables": map[string]any{"id": 99},
+	})
+
+	if got := normalize(r.Data); got != `{"user":null}` {
+		t.Errorf("Expected user to be null, got %s", got)
+	}
+
+	if len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Message, "user not found") || fmt.Sprint(r.Errors[0].Path) != "[user]" {
+		t.Errorf("Expected an error of the field user, got %+v", r.Errors)
+	}
+
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"query": "{ posts { title } }"`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a body that isn't JSON, got %d", resp.StatusCode)
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	store := testData()
+
+	schema, err := NewSchema(store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The client is gone before the query runs.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"query": "{ posts { title } }"}`)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	Handler(schema, store).ServeHTTP(rec, req)
+
+	if queries := store.Queries(); len(queries) != 0 {
+		t.Errorf("Expected no queries to the store for a cancelled request, got %v", queries)
+	}
+
+	if !strings.Contains(rec.Body.String(), "context canceled") {
+		t.Errorf("Expected the error of the cancelled context in the response, got %s", rec.Body)
+	}
+}
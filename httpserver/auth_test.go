@@ -0,0 +1,344 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// JSON Web Token (RFC 7519) is a compact way to pass signed claims between services.
+// A token is three base64url encoded segments separated by dots:
+//
+//	header.payload.signature
+//	{"alg":"HS256","typ":"JWT"}.{"sub":"alice","role":"admin","iat":1714557600,"exp":1714561200}.HMAC-SHA256(secret, header.payload)
+//
+// The payload is only encoded, not encrypted, anyone can read it. The signature is what makes it trustworthy:
+// only someone who knows the secret can produce it, so a server must never use the claims before checking it.
+
+// Claims are the claims of an access token, times are Unix seconds.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token expired")
+)
+
+// 1. Issuing tokens.
+// JWT segments use the URL safe base64 alphabet without padding, so tokens can be put into URLs and headers as they are.
+// Issue produces tokens that other JWT libraries reject. Fix encodeSegment and decodeSegment.
+
+var jwtHeader = []byte(`{"alg":"HS256","typ":"JWT"}`)
+
+func encodeSegment(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(segment)
+}
+
+// Issue returns an HS256 signed token with the claims.
+func Issue(secret []byte, claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := encodeSegment(jwtHeader) + "." + encodeSegment(payload)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + encodeSegment(mac.Sum(nil)), nil
+}
+
+var (
+	authSecret = []byte("workshop-jwt-secret")
+	authNow    = time.Unix(1714557600, 0)
+)
+
+// craftToken builds a token from raw header and claims, tests use it to produce tokens Issue never would.
+func craftToken(t *testing.T, secret []byte, header string, claims any) string {
+	t.Helper()
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestIssue(t *testing.T) {
+	// The user name is picked so the payload contains bytes that differ between base64 alphabets.
+	claims := Claims{Subject: "alice>>?", Role: "admin", IssuedAt: authNow.Unix(), ExpiresAt: authNow.Add(time.Hour).Unix()}
+
+	token, err := Issue(authSecret, claims)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := craftToken(t, authSecret, string(jwtHeader), claims)
+	if token != expected {
+		t.Errorf("Expected token %s, got %s", expected, token)
+	}
+}
+
+// 2. Validating tokens.
+// Parse decodes the claims and trusts them, whatever the signature is. Fix it:
+// - reject tokens with any algorithm but HS256 with ErrUnsupportedAlgorithm, especially "none",
+//   the algorithm comes from the token, and an attacker controls it,
+// - compare signatures with hmac.Equal, so the time of the comparison doesn't leak the expected signature,
+// - reject tokens without expiration as malformed, and expired tokens with ErrTokenExpired.
+//
+// Clocks of the issuer and the server are never exactly in sync, so a token is accepted for clockSkew after it expires.
+
+const clockSkew = time.Minute
+
+// Parse validates the token at now and returns its claims.
+func Parse(secret []byte, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformedToken
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+
+	return claims, nil
+}
+
+func TestParse(t *testing.T) {
+	header := `{"alg":"HS256","typ":"JWT"}`
+	claims := Claims{Subject: "alice", Role: "viewer", IssuedAt: authNow.Unix(), ExpiresAt: authNow.Add(time.Hour).Unix()}
+	valid := craftToken(t, authSecret, header, claims)
+
+	// Tampered payload keeps the original signature.
+	segments := strings.Split(valid, ".")
+	admin := claims
+	admin.Role = "admin"
+	segments[1] = strings.Split(craftToken(t, authSecret, header, admin), ".")[1]
+	tampered := strings.Join(segments, ".")
+
+	// Unsigned token with "alg": "none" and an empty signature.
+	unsigned := strings.Join(strings.Split(craftToken(t, nil, `{"alg":"none","typ":"JWT"}`, admin), ".")[:2], ".") + "."
+
+	tests := []struct {
+		name     string
+		token    string
+		now      time.Time
+		expected error
+	}{
+		{name: "valid", token: valid, now: authNow},
+		{name: "expired within clock skew", token: valid, now: authNow.Add(time.Hour + 30*time.Second)},
+		{name: "expired", token: valid, now: authNow.Add(time.Hour + 2*time.Minute), expected: ErrTokenExpired},
+		{name: "tampered payload", token: tampered, now: authNow, expected: ErrInvalidSignature},
+		{name: "tampered signature", token: valid[:len(valid)-4] + "AAAA", now: authNow, expected: ErrInvalidSignature},
+		{name: "wrong secret", token: craftToken(t, []byte("guessed"), header, claims), now: authNow, expected: ErrInvalidSignature},
+		{name: "alg none", token: unsigned, now: authNow, expected: ErrUnsupportedAlgorithm},
+		{name: "alg HS512", token: craftToken(t, authSecret, `{"alg":"HS512","typ":"JWT"}`, claims), now: authNow, expected: ErrUnsupportedAlgorithm},
+		{name: "no expiration", token: craftToken(t, authSecret, header, map[string]any{"sub": "alice"}), now: authNow, expected: ErrMalformedToken},
+		{name: "two segments", token: strings.Join(segments[:2], "."), now: authNow, expected: ErrMalformedToken},
+		{name: "not base64", token: "!!!." + segments[1] + "." + segments[2], now: authNow, expected: ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(authSecret, tt.token, tt.now)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("Expected error %v, got %v", tt.expected, err)
+			}
+
+			if tt.expected == nil && got != claims {
+				t.Errorf("Expected claims %+v, got %+v", claims, got)
+			}
+		})
+	}
+}
+
+// 3. Authentication middleware.
+// Authenticate reads a bearer token from the Authorization header, validates it, and passes the claims
+// to the next handler in the request context. RFC 6750 defines how a server reports problems with tokens:
+// - no token: 401 Unauthorized with `WWW-Authenticate: Bearer realm="workshop"`,
+// - invalid or expired token: 401 with `WWW-Authenticate: Bearer realm="workshop", error="invalid_token"`,
+// - valid token without enough permissions: 403 Forbidden.
+// A 500 Internal Server Error tells a client the server is broken, not that the client should log in again.
+//
+// Context values are looked up by key, and any package can put a value with the key "claims" into the context.
+// A key of an unexported type can't collide with keys of other packages, and no one else can forge it.
+//
+// Fix Authenticate, RequireRole, and ClaimsFromContext.
+
+// Authenticate returns middleware that lets through only requests with a valid token.
+func Authenticate(secret []byte, now func() time.Time) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+			claims, err := Parse(secret, token, now())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "claims", claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ClaimsFromContext returns the claims Authenticate put into the context.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value("claims").(Claims)
+
+	return claims, ok
+}
+
+// RequireRole returns middleware that lets through only requests authenticated with the role.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ := ClaimsFromContext(r.Context())
+			if claims.Role != role {
+				http.Error(w, "wrong role", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// whoami responds with the subject of the token.
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	claims, ok := ClaimsFromContext(r.Context())
+	if !ok {
+		http.Error(w, "no claims in context", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprint(w, claims.Subject)
+})
+
+func TestAuthenticate(t *testing.T) {
+	header := `{"alg":"HS256","typ":"JWT"}`
+	claims := Claims{Subject: "alice", Role: "viewer", IssuedAt: authNow.Unix(), ExpiresAt: authNow.Add(time.Hour).Unix()}
+	valid := craftToken(t, authSecret, header, claims)
+	handler := Authenticate(authSecret, func() time.Time { return authNow })(whoami)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		challenge     string
+		body          string
+	}{
+		{name: "valid", authorization: "Bearer " + valid, status: http.StatusOK, body: "alice"},
+		{name: "no header", status: http.StatusUnauthorized, challenge: `Bearer realm="workshop"`},
+		{name: "basic auth", authorization: "Basic YWxpY2U6c2VjcmV0", status: http.StatusUnauthorized, challenge: `Bearer realm="workshop"`},
+		{
+			name:          "expired",
+			authorization: "Bearer " + craftToken(t, authSecret, header, Claims{Subject: "alice", ExpiresAt: authNow.Add(-time.Hour).Unix()}),
+			status:        http.StatusUnauthorized,
+			challenge:     `Bearer realm="workshop", error="invalid_token"`,
+		},
+		{
+			name:          "wrong secret",
+			authorization: "Bearer " + craftToken(t, []byte("guessed"), header, claims),
+			status:        http.StatusUnauthorized,
+			challenge:     `Bearer realm="workshop", error="invalid_token"`,
+		},
+		{name: "garbage", authorization: "Bearer garbage", status: http.StatusUnauthorized, challenge: `Bearer realm="workshop", error="invalid_token"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+
+			// error_description may follow the error, only the start of the challenge is checked.
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, tt.challenge) {
+				t.Errorf("Expected WWW-Authenticate %q, got %q", tt.challenge, got)
+			}
+
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rec.Body)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := Authenticate(authSecret, func() time.Time { return authNow })(RequireRole("admin")(whoami))
+
+	for _, tt := range []struct {
+		role   string
+		status int
+	}{
+		{role: "admin", status: http.StatusOK},
+		{role: "viewer", status: http.StatusForbidden},
+	} {
+		token := craftToken(t, authSecret, `{"alg":"HS256","typ":"JWT"}`, Claims{
+			Subject:   "alice",
+			Role:      tt.role,
+			IssuedAt:  authNow.Unix(),
+			ExpiresAt: authNow.Add(time.Hour).Unix(),
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("Expected status %d for role %s, got %d: %s", tt.status, tt.role, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestClaimsFromContext(t *testing.T) {
+	// Some other middleware put its own value with the same name into the context.
+	ctx := context.WithValue(context.Background(), "claims", Claims{Subject: "mallory", Role: "admin"})
+
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		t.Errorf("Expected no claims from a value set by another package, got %+v", claims)
+	}
+}
@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 10. Streaming downloads.
+// A response doesn't have to be ready before it's sent. An io.Reader copied to the ResponseWriter with io.Copy
+// goes out in chunks while it's read, in constant memory: with no Content-Length, net/http switches to
+// chunked transfer encoding by itself. Download reads the whole report into memory first, so a big report
+// takes as much memory as it's big, and the client waits for the first byte until the last one is read.
+//
+// Downloads are throttled, so a few of them don't take the whole bandwidth of the server.
+// Implement Throttle: a reader that returns at most bytesPerSecond bytes per second on average, sleeping
+// between reads when it's ahead of the rate. A throttled download of a big file takes minutes,
+// so Throttle must stop waiting and return the error of the context when the client goes away.
+// Then make Download stream the report.
+
+// Throttle returns a reader that reads from r at most bytesPerSecond bytes per second until the context is done.
+func Throttle(ctx context.Context, r io.Reader, bytesPerSecond int) io.Reader {
+	return r
+}
+
+// Download serves the report as a CSV file at most bytesPerSecond bytes per second.
+func Download(report func() io.Reader, bytesPerSecond int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(Throttle(r.Context(), report(), bytesPerSecond))
+		if err != nil {
+			http.Error(w, "failed to read the report", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+		w.Write(data)
+	})
+}
+
+// csvReport generates a report of n bytes.
+func csvReport(n int) []byte {
+	var buf bytes.Buffer
+
+	for i := 0; buf.Len() < n; i++ {
+		fmt.Fprintf(&buf, "%d,order-%d,%d.%02d\n", i, i, i*7%1000, i%100)
+	}
+
+	return buf.Bytes()[:n]
+}
+
+func TestThrottle(t *testing.T) {
+	data := csvReport(64 << 10)
+
+	start := time.Now()
+
+	got, err := io.ReadAll(Throttle(context.Background(), bytes.NewReader(data), 256<<10))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Expected to read all data, got %d bytes, %v", len(got), err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > 400*time.Millisecond {
+		t.Errorf("Expected 64KiB at 256KiB/s to take about 250ms, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := Throttle(ctx, bytes.NewReader(csvReport(1<<20)), 16<<10)
+
+	if _, err := r.Read(make([]byte, 32<<10)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start = time.Now()
+
+	_, err = io.Copy(io.Discard, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected reading to stop with context.Canceled, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected reading to stop right after the context was cancelled, took %v", elapsed)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	report := csvReport(128 << 10)
+	server := httptest.NewServer(Download(func() io.Reader { return bytes.NewReader(report) }, 256<<10))
+	defer server.Close()
+
+	start := time.Now()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+		t.Errorf("Expected the report to be served as an attachment, got Content-Disposition %q", cd)
+	}
+
+	first := make([]byte, 1024)
+	if _, err := io.ReadFull(resp.Body, first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected the first KiB to arrive right away, it took %v", elapsed)
+	}
+
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil || !bytes.Equal(append(first, rest...), report) {
+		t.Errorf("Expected the whole report, got %d bytes, %v", len(first)+len(rest), err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Errorf("Expected 128KiB at 256KiB/s to take about 500ms, took %v", elapsed)
+	}
+
+	if te := resp.TransferEncoding; len(te) != 1 || te[0] != "chunked" {
+		t.Errorf("Expected the report to be streamed with chunked transfer encoding, got %v", te)
+	}
+}
+
+func TestDownloadDisconnect(t *testing.T) {
+	report := csvReport(256 << 10)
+	done := make(chan struct{})
+
+	download := Download(func() io.Reader { return bytes.NewReader(report) }, 64<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		download.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// 256KiB at 64KiB/s take 4 seconds, the handler must stop when the client is gone.
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the handler to stop when the client disconnected")
+	}
+}
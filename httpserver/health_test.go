@@ -0,0 +1,284 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Health checks tell an orchestrator like Kubernetes what to do with an instance of a service:
+// - liveness, /healthz: is the process alive? If not, it's restarted,
+// - readiness, /readyz: can it serve traffic right now? If not, it's taken out of the load balancer until it can.
+// Liveness must not check dependencies: when the database goes down, restarting every instance doesn't bring it back,
+// it only adds a storm of restarts to the outage. Readiness checks them, and tells degraded from failing:
+// a full queue of background jobs slows things down, but the instance still serves requests,
+// while an instance without its database serves only errors.
+
+// Status is the result of a health check.
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// defaultCheckTimeout is the timeout of checks registered without one.
+const defaultCheckTimeout = time.Second
+
+// CheckFunc checks a dependency and returns an error when it's unhealthy.
+type CheckFunc func(ctx context.Context) error
+
+// Pinger is a dependency that can be pinged, like *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck checks that the dependency responds to a ping.
+func PingCheck(p Pinger) CheckFunc {
+	return p.PingContext
+}
+
+// QueueDepthCheck checks that a queue holds at most max items.
+func QueueDepthCheck(depth func() int, max int) CheckFunc {
+	return func(context.Context) error {
+		if n := depth(); n > max {
+			return fmt.Errorf("queue depth %d exceeds %d", n, max)
+		}
+
+		return nil
+	}
+}
+
+// CheckResult is the result of one check in a report.
+type CheckResult struct {
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the result of all checks.
+type Report struct {
+	Status Status                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// check is a registered check.
+type check struct {
+	name     string
+	fn       CheckFunc
+	timeout  time.Duration
+	critical bool
+}
+
+// 4. Health checks.
+// Health runs registered checks. A failing critical check, like the database, takes the service down,
+// a failing non-critical one, like the queue, only degrades it. Fix Run:
+// - run checks concurrently, so the report takes as long as the slowest check, not the sum of all of them,
+// - give every check a context with its timeout, defaultCheckTimeout if it's 0,
+// - don't wait for a check past its timeout even if it ignores the context, it's reported as down with the error
+//   of the context, and its goroutine finishes on its own later,
+// - the status of the report is down if a critical check failed, degraded if any other check failed, and up otherwise.
+
+// Health is a registry of health checks.
+type Health struct {
+	checks []check
+}
+
+// Register adds a check. Timeout limits how long the check may take, critical checks take the whole service down.
+func (h *Health) Register(name string, fn CheckFunc, timeout time.Duration, critical bool) {
+	h.checks = append(h.checks, check{name: name, fn: fn, timeout: timeout, critical: critical})
+}
+
+// Run runs all checks and reports their results.
+func (h *Health) Run(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(h.checks))}
+
+	for _, c := range h.checks {
+		if err := c.fn(ctx); err != nil {
+			report.Checks[c.name] = CheckResult{Status: StatusDown, Error: err.Error()}
+			report.Status = StatusDown
+
+			continue
+		}
+
+		report.Checks[c.name] = CheckResult{Status: StatusUp}
+	}
+
+	return report
+}
+
+// 5. Probes.
+// Handler serves both probes. Fix it:
+// - /healthz responds 200 "ok" without running any checks,
+// - /readyz runs the checks with the context of the request and responds with the report as JSON,
+//   200 when the service is up or degraded and 503 Service Unavailable when it's down.
+
+// Handler serves /healthz and /readyz.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if report := h.Run(r.Context()); report.Status != StatusUp {
+			http.Error(w, string(report.Status), http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprint(w, "ok")
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Run(context.Background()))
+	})
+
+	return mux
+}
+
+// fakePinger is a database that takes latency to respond with err, or until the context is done.
+type fakePinger struct {
+	latency time.Duration
+	err     error
+}
+
+func (p fakePinger) PingContext(ctx context.Context) error {
+	select {
+	case <-time.After(p.latency):
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stuck is a check that ignores its context.
+func stuck(context.Context) error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+func TestHealthRun(t *testing.T) {
+	var h Health
+	h.Register("db", PingCheck(fakePinger{latency: 100 * time.Millisecond}), 0, true)
+	h.Register("cache", PingCheck(fakePinger{latency: 100 * time.Millisecond}), 0, false)
+	h.Register("search", PingCheck(fakePinger{latency: time.Second}), 150*time.Millisecond, false)
+	h.Register("legacy", stuck, 150*time.Millisecond, false)
+
+	start := time.Now()
+	report := h.Run(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Expected checks to run concurrently and slow ones to be cut off at 150ms, took %v", elapsed)
+	}
+
+	expected := map[string]Status{"db": StatusUp, "cache": StatusUp, "search": StatusDown, "legacy": StatusDown}
+	for name, status := range expected {
+		if got := report.Checks[name]; got.Status != status {
+			t.Errorf("Expected check %s to be %s, got %+v", name, status, got)
+		}
+	}
+
+	for _, name := range []string{"search", "legacy"} {
+		if got := report.Checks[name].Error; !strings.Contains(got, context.DeadlineExceeded.Error()) {
+			t.Errorf("Expected check %s to fail with %q, got %q", name, context.DeadlineExceeded, got)
+		}
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	failing := func(context.Context) error { return errors.New("connection refused") }
+	healthy := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name     string
+		db       CheckFunc
+		queue    CheckFunc
+		expected Status
+	}{
+		{"all up", healthy, healthy, StatusUp},
+		{"queue full", healthy, QueueDepthCheck(func() int { return 1500 }, 1000), StatusDegraded},
+		{"db down", failing, healthy, StatusDown},
+		{"both down", failing, QueueDepthCheck(func() int { return 1500 }, 1000), StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Health
+			h.Register("db", tt.db, 0, true)
+			h.Register("queue", tt.queue, 0, false)
+
+			if report := h.Run(context.Background()); report.Status != tt.expected {
+				t.Errorf("Expected status %s, got %+v", tt.expected, report)
+			}
+		})
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	depth := 0
+	dbErr := error(nil)
+
+	var h Health
+	h.Register("db", func(ctx context.Context) error { return dbErr }, 0, true)
+	h.Register("queue", QueueDepthCheck(func() int { return depth }, 1000), 0, false)
+
+	handler := h.Handler()
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rec
+	}
+
+	tests := []struct {
+		name    string
+		depth   int
+		dbErr   error
+		status  int
+		report  Status
+		healthz int
+	}{
+		{"up", 0, nil, http.StatusOK, StatusUp, http.StatusOK},
+		{"degraded", 5000, nil, http.StatusOK, StatusDegraded, http.StatusOK},
+		{"down", 0, errors.New("connection refused"), http.StatusServiceUnavailable, StatusDown, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		depth, dbErr = tt.depth, tt.dbErr
+
+		rec := serve("/readyz")
+
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Expected a JSON report from /readyz, got %q", rec.Body)
+		}
+
+		if rec.Code != tt.status || report.Status != tt.report {
+			t.Errorf("Expected /readyz to respond %d with status %s when %s, got %d %s", tt.status, tt.report, tt.name, rec.Code, rec.Body)
+		}
+
+		if rec := serve("/healthz"); rec.Code != tt.healthz || rec.Body.String() != "ok" {
+			t.Errorf("Expected /healthz to respond %d ok when %s, got %d %s", tt.healthz, tt.name, rec.Code, rec.Body)
+		}
+	}
+
+	// A probe that gave up cancels the checks.
+	var slow Health
+	slow.Register("db", PingCheck(fakePinger{latency: 2 * time.Second}), 5*time.Second, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	slow.Handler().ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/readyz", nil))
+
+	if elapsed := time.Since(start); elapsed > time.Second || rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to stop when the probe gave up and respond 503, got %d after %v", rec.Code, elapsed)
+	}
+}
@@ -0,0 +1,328 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// Advanced: idempotency keys.
+//
+// A client that sends POST /payments and gets a timeout doesn't know whether the payment was made.
+// Retrying may charge twice, not retrying may not charge at all. The fix is an Idempotency-Key header:
+// the client generates a unique key for the operation and sends the same key with every retry,
+// and the server runs the operation once per key and replays the stored response to the retries.
+//
+// Let's implement Idempotent middleware with IdempotencyStore:
+// - Only POST and PATCH requests with an Idempotency-Key header are handled, others go straight to the handler.
+// - The first request with a key runs the handler. Its response, the status, headers, and body, is captured
+//   while it's written to the client, and stored under the key.
+// - Retries usually arrive while the first request is still running, because the client timed out waiting for it.
+//   A request with a key in flight waits for it, like singleflight, and gets the same response.
+//   A waiting client may give up too, stop waiting when its request context is done.
+// - Replayed responses carry the Idempotent-Replayed: true header, so clients and logs can tell them apart.
+// - The key belongs to one request. The same key with another method, path, or body is a client bug, not a retry:
+//   respond 422 Unprocessable Entity without running the handler. Compare a SHA-256 fingerprint of the request,
+//   the body can be large, and read the body into memory to put it back into the request for the handler.
+// - 5xx responses are not stored: the operation failed, and the next retry must run it again.
+// - Stored responses expire TTL after they are stored, then the key runs the handler again. Expired entries must
+//   leave memory, not only be ignored: remove them when new keys are stored. Responses are stored in the order
+//   they expire, so a queue of keys finds expired ones without scanning the whole map.
+//
+// A store in memory only works for a single instance of the service, a shared store like Redis or a table
+// with a unique key works for all of them, with the same logic.
+
+const (
+	// IdempotencyKeyHeader is the header a client sends the key of the operation in.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// ReplayedHeader marks responses replayed from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyStore keeps responses of requests by their idempotency keys, it's safe for concurrent use.
+type IdempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+// idempotencyEntry is a request with a key, in flight or done.
+type idempotencyEntry struct{}
+
+// NewIdempotencyStore creates a store that keeps responses for ttl, now returns the current time.
+func NewIdempotencyStore(ttl time.Duration, now func() time.Time) *IdempotencyStore {
+	return &IdempotencyStore{ttl: ttl, now: now, entries: make(map[string]*idempotencyEntry)}
+}
+
+// Len returns the number of keys in the store.
+func (s *IdempotencyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// Idempotent is middleware that runs requests with the same idempotency key once, and replays the response.
+func Idempotent(store *IdempotencyStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// payments is a handler that creates a payment for every request it runs, its step lets tests hold a payment.
+type payments struct {
+	executions atomic.Int64
+	step       func()
+	status     func(n int64) int
+}
+
+func (p *payments) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	n := p.executions.Add(1)
+
+	if p.step != nil {
+		p.step()
+	}
+
+	status := http.StatusCreated
+	if p.status != nil {
+		status = p.status(n)
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/payments/%d", n))
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "payment %d: %s", n, body)
+}
+
+type idempotentResponse struct {
+	status   int
+	location string
+	body     string
+	replayed bool
+}
+
+// post sends a request with the idempotency key, an empty key sends none.
+func post(t *testing.T, h http.Handler, method, path, key, body string) idempotentResponse {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return idempotentResponse{
+		status:   w.Code,
+		location: w.Header().Get("Location"),
+		body:     w.Body.String(),
+		replayed: w.Header().Get(ReplayedHeader) == "true",
+	}
+}
+
+func newIdempotentPayments(p *payments, now func() time.Time) (http.Handler, *IdempotencyStore) {
+	store := NewIdempotencyStore(time.Hour, now)
+
+	return Idempotent(store)(p), store
+}
+
+func TestIdempotentDuplicates(t *testing.T) {
+	const clients = 10
+
+	steps := testsync.NewStepController(t)
+	p := &payments{step: func() { steps.Step("charge") }}
+	h, _ := newIdempotentPayments(p, time.Now)
+
+	responses := make([]idempotentResponse, clients)
+	wg := sync.WaitGroup{}
+
+	// The first payment is held, its retries arrive while it's in flight.
+	for i := range clients {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			responses[i] = post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`)
+		}()
+	}
+
+	steps.Await("charge")
+	steps.Release("charge")
+	wg.Wait()
+
+	if n := p.executions.Load(); n != 1 {
+		t.Errorf("Expected the payment to be made once, it was made %d times", n)
+	}
+
+	replayed := 0
+
+	for _, resp := range responses {
+		if resp.status != http.StatusCreated || resp.location != "/payments/1" || resp.body != `payment 1: {"amount": 100}` {
+			t.Fatalf("Expected every client to get the response of the first payment, got %+v", resp)
+		}
+
+		if resp.replayed {
+			replayed++
+		}
+	}
+
+	if replayed != clients-1 {
+		t.Errorf("Expected %d responses to be marked as replayed, got %d", clients-1, replayed)
+	}
+
+	// A late retry gets the stored response.
+	if resp := post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`); !resp.replayed || resp.location != "/payments/1" {
+		t.Errorf("Expected a retry to replay the first payment, got %+v", resp)
+	}
+
+	if resp := post(t, h, http.MethodPost, "/payments", "key-2", `{"amount": 100}`); resp.replayed || resp.location != "/payments/2" {
+		t.Errorf("Expected another key to make another payment, got %+v", resp)
+	}
+}
+
+func TestIdempotentConflict(t *testing.T) {
+	p := &payments{}
+	h, _ := newIdempotentPayments(p, time.Now)
+
+	post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`)
+
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodPost, "/payments", `{"amount": 1000}`},
+		{http.MethodPost, "/refunds", `{"amount": 100}`},
+		{http.MethodPatch, "/payments", `{"amount": 100}`},
+	} {
+		if resp := post(t, h, tc.method, tc.path, "key-1", tc.body); resp.status != http.StatusUnprocessableEntity {
+			t.Errorf("Expected %d for the key reused by %s %s %s, got %+v", http.StatusUnprocessableEntity, tc.method, tc.path, tc.body, resp)
+		}
+	}
+
+	if n := p.executions.Load(); n != 1 {
+		t.Errorf("Expected requests with a reused key not to run the handler, it ran %d times", n)
+	}
+}
+
+func TestIdempotentPassThrough(t *testing.T) {
+	p := &payments{}
+	h, store := newIdempotentPayments(p, time.Now)
+
+	post(t, h, http.MethodPost, "/payments", "", `{"amount": 100}`)
+	post(t, h, http.MethodPost, "/payments", "", `{"amount": 100}`)
+	post(t, h, http.MethodGet, "/payments", "key-1", "")
+	post(t, h, http.MethodGet, "/payments", "key-1", "")
+
+	if n := p.executions.Load(); n != 4 {
+		t.Errorf("Expected requests without a key and GET requests to run every time, the handler ran %d of 4 times", n)
+	}
+
+	if store.Len() != 0 {
+		t.Errorf("Expected no keys stored, got %d", store.Len())
+	}
+}
+
+func TestIdempotentServerErrors(t *testing.T) {
+	// The first attempt fails, the retry succeeds.
+	p := &payments{status: func(n int64) int {
+		if n == 1 {
+			return http.StatusServiceUnavailable
+		}
+
+		return http.StatusCreated
+	}}
+	h, _ := newIdempotentPayments(p, time.Now)
+
+	if resp := post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`); resp.status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the first attempt to fail with %d, got %+v", http.StatusServiceUnavailable, resp)
+	}
+
+	if resp := post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`); resp.status != http.StatusCreated || resp.replayed {
+		t.Errorf("Expected the retry of a failed request to run again, got %+v", resp)
+	}
+
+	if resp := post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`); resp.status != http.StatusCreated || !resp.replayed {
+		t.Errorf("Expected the successful response to be stored, got %+v", resp)
+	}
+}
+
+func TestIdempotentWaitCanceled(t *testing.T) {
+	steps := testsync.NewStepController(t)
+	p := &payments{step: func() { steps.Step("charge") }}
+	h, _ := newIdempotentPayments(p, time.Now)
+
+	first := make(chan idempotentResponse, 1)
+
+	go func() {
+		first <- post(t, h, http.MethodPost, "/payments", "key-1", `{"amount": 100}`)
+	}()
+
+	steps.Await("charge")
+
+	// The retry waits for the first request, and its client gives up.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r := httptest.NewRequestWithContext(ctx, http.MethodPost, "/payments", strings.NewReader(`{"amount": 100}`))
+	r.Header.Set(IdempotencyKeyHeader, "key-1")
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a waiting request to return when its context is done")
+	}
+
+	steps.Release("charge")
+
+	if resp := <-first; resp.status != http.StatusCreated || p.executions.Load() != 1 {
+		t.Errorf("Expected the payment to be made once, it was made %d times, got %+v", p.executions.Load(), resp)
+	}
+}
+
+func TestIdempotencyTTL(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	p := &payments{}
+	h, store := newIdempotentPayments(p, clock)
+
+	for i := range 3 {
+		post(t, h, http.MethodPost, "/payments", fmt.Sprintf("key-%d", i), `{"amount": 100}`)
+	}
+
+	now = now.Add(30 * time.Minute)
+
+	if resp := post(t, h, http.MethodPost, "/payments", "key-0", `{"amount": 100}`); !resp.replayed {
+		t.Errorf("Expected a retry within the TTL to be replayed, got %+v", resp)
+	}
+
+	now = now.Add(31 * time.Minute)
+
+	if resp := post(t, h, http.MethodPost, "/payments", "key-0", `{"amount": 100}`); resp.replayed || resp.location != "/payments/4" {
+		t.Errorf("Expected the key to run the handler again after the TTL, got %+v", resp)
+	}
+
+	// Storing key-0 again removed the other expired keys.
+	if store.Len() != 1 {
+		t.Errorf("Expected expired keys to be removed from the store, got %d keys", store.Len())
+	}
+}
@@ -0,0 +1,98 @@
+package httpserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Advanced: rotating signing keys.
+//
+// A secret should be replaced from time to time, and right away when it leaks. But tokens signed with the old secret
+// are still in use, and a server that switches keys at once logs out all users.
+// JWT solves it with the "kid" (key id) header: the issuer signs with the current key and puts its id into the header,
+// the server picks the key by the id. The old key stays in the keyring until its tokens expire, then it's removed.
+//
+// Let's implement Keyring:
+// - Issue signs with the current key and adds "kid" to the header, like {"alg":"HS256","kid":"2025-01","typ":"JWT"},
+// - Parse takes the key by the id from the header and validates the token with Parse from above,
+//   a token without kid or with an unknown one is rejected with ErrUnknownKey.
+// The header is not trusted: a kid of another key only selects a key, the signature check does the rest.
+// This exercise builds on the previous ones, solve them first.
+
+// ErrUnknownKey is returned for tokens signed with a key that is not in the keyring.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Keyring holds signing keys by their ids.
+type Keyring struct {
+	// Current is the id of the key new tokens are signed with.
+	Current string
+	Keys    map[string][]byte
+}
+
+// Issue returns a token signed with the current key.
+func (k *Keyring) Issue(claims Claims) (string, error) {
+	return Issue(k.Keys[k.Current], claims)
+}
+
+// Parse validates the token with the key it was signed with.
+func (k *Keyring) Parse(token string, now time.Time) (Claims, error) {
+	return Parse(k.Keys[k.Current], token, now)
+}
+
+func TestKeyring(t *testing.T) {
+	ring := &Keyring{Current: "2024-07", Keys: map[string][]byte{"2024-07": []byte("old-secret")}}
+	claims := Claims{Subject: "alice", IssuedAt: authNow.Unix(), ExpiresAt: authNow.Add(time.Hour).Unix()}
+
+	oldToken, err := ring.Issue(claims)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(oldToken, ".")[0])
+
+	var h struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := json.Unmarshal(header, &h); err != nil || h.Alg != "HS256" || h.Kid != "2024-07" {
+		t.Fatalf("Expected header with alg HS256 and kid 2024-07, got %s", header)
+	}
+
+	// The key is rotated, tokens signed with the old key are still accepted.
+	ring.Keys["2025-01"] = []byte("new-secret")
+	ring.Current = "2025-01"
+
+	newToken, err := ring.Issue(claims)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		if got, err := ring.Parse(token, authNow); err != nil || got != claims {
+			t.Errorf("Expected claims %+v, got %+v and error %v", claims, got, err)
+		}
+	}
+
+	// The old key is removed when its tokens expired.
+	delete(ring.Keys, "2024-07")
+
+	if _, err := ring.Parse(oldToken, authNow); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected error to be %v, got %v", ErrUnknownKey, err)
+	}
+
+	// Pointing kid to another key doesn't make the signature valid.
+	forged := craftToken(t, []byte("old-secret"), `{"alg":"HS256","kid":"2025-01","typ":"JWT"}`, claims)
+	if _, err := ring.Parse(forged, authNow); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidSignature, err)
+	}
+
+	noKid := craftToken(t, []byte("new-secret"), `{"alg":"HS256","typ":"JWT"}`, claims)
+	if _, err := ring.Parse(noKid, authNow); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected error to be %v, got %v", ErrUnknownKey, err)
+	}
+}
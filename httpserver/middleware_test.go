@@ -0,0 +1,245 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 7. Chaining middleware.
+// Middleware wraps a handler: Authenticate, RequestID, and SessionUser above all have the same shape.
+// Wrapping by hand, Recovery(RequestID(Logging(handler))), gets unreadable with a dozen of them,
+// Chain builds the same wrapping from a list that reads in the order of execution: the first middleware is
+// the outermost, it sees the request first and the response last. Chain applies them the other way round, fix it.
+//
+// The order matters:
+// - Recovery goes first, so it catches panics of every middleware after it, not only of the handler,
+// - RequestID goes before everything that logs, so the logs of a request carry its ID,
+// - Logging goes after them and sees the final status of the response.
+
+// Middleware wraps a handler with more behavior.
+type Middleware func(http.Handler) http.Handler
+
+// Chain combines middlewares into one, the first of them is the outermost.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for _, m := range middlewares {
+			next = m(next)
+		}
+
+		return next
+	}
+}
+
+// 8. Recovery and logging.
+// net/http recovers panics of handlers itself, but it only logs them and drops the connection:
+// the client gets no response at all. Recovery must respond 500 Internal Server Error and log the panic
+// with the stack trace, debug.Stack() returns it, as the "stack" attribute. Recovery now swallows the panic.
+//
+// Logging must log the status and the size of every response, but a handler writes them to the ResponseWriter,
+// and nobody else sees them. Wrap the writer in a type that remembers them: WriteHeader sets the status,
+// and a Write without WriteHeader means 200. An embedded http.ResponseWriter hides optional interfaces
+// like http.Flusher of the writer underneath, an Unwrap method lets http.ResponseController find them again.
+// Finally, put the middlewares in the right order in DefaultStack, it needs RequestID from section 6.
+
+// Recovery is middleware that turns panics into 500 responses.
+func Recovery(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recover()
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Logging is middleware that logs every request with the status and the size of the response.
+func Logging(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			id, _ := RequestIDFromContext(r.Context())
+			logger.Info("Request served", "method", r.Method, "path", r.URL.Path, "status", http.StatusOK, "bytes", 0, "request_id", id)
+		})
+	}
+}
+
+// DefaultStack is the middleware every handler of the service is served with.
+func DefaultStack(logger *slog.Logger) Middleware {
+	return Chain(Logging(logger), RequestID, Recovery(logger))
+}
+
+// trace is middleware that records when the request enters and leaves it.
+func trace(name string, calls *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+">")
+			next.ServeHTTP(w, r)
+			*calls = append(*calls, "<"+name)
+		})
+	}
+}
+
+// logRecords returns records a JSON logger wrote to the buffer.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Unexpected log line %q: %v", line, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+
+	handler := Chain(trace("a", &calls), trace("b", &calls), trace("c", &calls))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "handler")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got, expected := strings.Join(calls, " "), "a> b> c> handler <c <b <a"; got != expected {
+		t.Errorf("Expected calls %q, got %q", expected, got)
+	}
+
+	calls = nil
+
+	Chain()(trace("only", &calls)(http.NotFoundHandler())).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(calls, " "); got != "only> <only" {
+		t.Errorf("Expected an empty chain to leave the handler as it is, got calls %q", got)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("nil map in checkout")
+	})
+
+	// A panic in a middleware is caught too, when Recovery wraps it.
+	broken := func(http.Handler) http.Handler { return panicking }
+
+	for _, handler := range []http.Handler{
+		Recovery(logger)(panicking),
+		Recovery(logger)(broken(http.NotFoundHandler())),
+	} {
+		logs.Reset()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/checkout", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500 after a panic, got %d", rec.Code)
+		}
+
+		records := logRecords(t, &logs)
+		if len(records) != 1 {
+			t.Fatalf("Expected the panic to be logged once, got %d records", len(records))
+		}
+
+		stack, _ := records[0]["stack"].(string)
+		if msg := fmt.Sprint(records[0]); !strings.Contains(msg, "nil map in checkout") || !strings.Contains(stack, "TestRecovery") {
+			t.Errorf("Expected the panic value and the stack trace in the log, got %v", records[0])
+		}
+	}
+}
+
+func TestLogging(t *testing.T) {
+	var logs bytes.Buffer
+
+	logging := Logging(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  float64
+		bytes   float64
+	}{
+		{"created", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		}, 201, 8},
+		{"implicit-200", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "hello")
+			fmt.Fprint(w, ", world")
+		}, 200, 12},
+		{"not-found", http.NotFound, 404, 19},
+		{"flushed", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "event: ping\n\n")
+
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				w.WriteHeader(http.StatusNotImplemented)
+			}
+		}, 200, 13},
+	}
+
+	for _, tt := range tests {
+		logs.Reset()
+
+		rec := httptest.NewRecorder()
+		logging(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+tt.name, nil))
+
+		if tt.name == "flushed" && !rec.Flushed {
+			t.Errorf("Expected the response to be flushed through the wrapped writer")
+		}
+
+		records := logRecords(t, &logs)
+		if len(records) != 1 || records[0]["status"] != tt.status || records[0]["bytes"] != tt.bytes {
+			t.Errorf("Expected %s to be logged with status %v and %v bytes, got %v", tt.name, tt.status, tt.bytes, records)
+		}
+	}
+}
+
+func TestDefaultStack(t *testing.T) {
+	var logs bytes.Buffer
+
+	handler := DefaultStack(slog.New(slog.NewJSONHandler(&logs, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+
+		fmt.Fprint(w, "ok")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	records := logRecords(t, &logs)
+	if id := rec.Header().Get(RequestIDHeader); len(records) != 1 || id == "" || records[0]["request_id"] != id {
+		t.Errorf("Expected the request to be logged with its ID %q, got %v", id, records)
+	}
+
+	logs.Reset()
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("Expected 500 with a request ID after a panic, got %d and headers %v", rec.Code, rec.Header())
+	}
+}
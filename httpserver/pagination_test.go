@@ -0,0 +1,420 @@
+package httpserver
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 11. Pagination.
+// A listing of users can't return millions of rows in one response, it returns them page by page.
+// The usual way is LIMIT and OFFSET: page 3 of 20 users skips 40 rows and takes 20. It's simple, but:
+// - The database still reads the skipped rows, page 5000 reads 100000 rows to return 20.
+// - Rows move between the requests of a client. The newest users come first, so every signup pushes
+//   all rows one position down: the next page repeats the last row of the previous one. Every deletion
+//   pulls them up, and a row is skipped.
+//
+// Keyset pagination remembers where the page ended instead of how many rows were before it: the next page
+// is WHERE (created_at, id) < (last.created_at, last.id) ORDER BY created_at DESC, id DESC LIMIT 20.
+// The index finds the position right away, and rows inserted or deleted above it don't move it. The id breaks
+// ties of users created at the same time, so the position is unique.
+//
+// The client gets the position as an opaque cursor and sends it back to get the next page. A cursor is input
+// of the client like any other, and a crafted one can point anywhere: sign it with HMAC-SHA256, and reject
+// cursors whose signature doesn't match with ErrInvalidCursor. Encode both parts with base64.RawURLEncoding,
+// the cursor goes into a URL: <payload>.<signature>.
+//
+// Let's implement:
+// - ListUsersOffset: GET /users?offset=40&limit=20. limit defaults to 20, and can be from 1 to 100,
+//   offset can't be negative, anything else is 400 Bad Request.
+// - EncodeCursor and DecodeCursor, they now encode the position as plain base64 and trust any cursor.
+// - ListUsersKeyset: GET /users?cursor=...&limit=20, without a cursor it starts from the newest user.
+//   next_cursor points after the last user of the page, and it's empty on the last page.
+//   An invalid cursor is 400 Bad Request.
+// userTable plays the database, Offset and After are the two queries.
+
+// UserRow is a row of the users table.
+type UserRow struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UsersPage is a page of the listing.
+type UsersPage struct {
+	Users      []UserRow `json:"users"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// userTable is an in-memory table of users, ordered like an index on (created_at DESC, id DESC).
+type userTable struct {
+	mu   sync.RWMutex
+	rows []UserRow
+}
+
+// compareUsers orders the newest users first.
+func compareUsers(a, b UserRow) int {
+	return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+}
+
+func (t *userTable) Insert(u UserRow) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i, _ := slices.BinarySearchFunc(t.rows, u, compareUsers)
+	t.rows = slices.Insert(t.rows, i, u)
+}
+
+func (t *userTable) Delete(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rows = slices.DeleteFunc(t.rows, func(u UserRow) bool { return u.ID == id })
+}
+
+// Offset runs SELECT ... ORDER BY created_at DESC, id DESC LIMIT limit OFFSET offset.
+func (t *userTable) Offset(offset, limit int) []UserRow {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if offset >= len(t.rows) {
+		return []UserRow{}
+	}
+
+	return slices.Clone(t.rows[offset:min(offset+limit, len(t.rows))])
+}
+
+// After runs SELECT ... WHERE (created_at, id) < (createdAt, id) ORDER BY created_at DESC, id DESC LIMIT limit.
+func (t *userTable) After(createdAt time.Time, id int64, limit int) []UserRow {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// The users after the position are the ones the position sorts before.
+	i, found := slices.BinarySearchFunc(t.rows, UserRow{ID: id, CreatedAt: createdAt}, compareUsers)
+	if found {
+		i++
+	}
+
+	return slices.Clone(t.rows[i:min(i+limit, len(t.rows))])
+}
+
+// ErrInvalidCursor is returned for cursors that are malformed or not signed by the server.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last user of a page.
+type Cursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+}
+
+// EncodeCursor returns the cursor as a string signed with the secret.
+func EncodeCursor(secret []byte, c Cursor) string {
+	payload, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// DecodeCursor parses a cursor returned by EncodeCursor, it returns ErrInvalidCursor if the cursor is malformed
+// or its signature doesn't match.
+func DecodeCursor(secret []byte, s string) (Cursor, error) {
+	var c Cursor
+
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	return c, nil
+}
+
+// ListUsersOffset serves pages of users by offset.
+func ListUsersOffset(table *userTable) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, UsersPage{Users: table.Offset(0, defaultPageLimit)})
+	})
+}
+
+// ListUsersKeyset serves pages of users by cursors signed with the secret.
+func ListUsersKeyset(table *userTable, secret []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writePage(w, UsersPage{Users: table.Offset(0, defaultPageLimit)})
+	})
+}
+
+func writePage(w http.ResponseWriter, page UsersPage) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+var cursorSecret = []byte("cursor-secret")
+
+// newUserTable creates a table of n users, created a minute apart. Every third user shares
+// the time of the previous one, so only the id tells them apart.
+func newUserTable(n int) *userTable {
+	table := &userTable{}
+	created := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for id := range int64(n) {
+		if id%3 != 2 {
+			created = created.Add(time.Minute)
+		}
+
+		table.Insert(UserRow{ID: id + 1, Name: fmt.Sprintf("user-%d", id+1), CreatedAt: created})
+	}
+
+	return table
+}
+
+// getPage requests a page and returns its status and the page.
+func getPage(t *testing.T, h http.Handler, query url.Values) (int, UsersPage) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?"+query.Encode(), nil))
+
+	var page UsersPage
+
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Unexpected response %q: %v", w.Body.String(), err)
+		}
+	}
+
+	return w.Code, page
+}
+
+func userIDs(users []UserRow) []int64 {
+	ids := make([]int64, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+
+	return ids
+}
+
+func TestListUsersOffset(t *testing.T) {
+	h := ListUsersOffset(newUserTable(50))
+
+	tests := []struct {
+		query    string
+		expected []int64
+	}{
+		{"limit=3", []int64{50, 49, 48}},
+		{"offset=3&limit=3", []int64{47, 46, 45}},
+		{"offset=48&limit=5", []int64{2, 1}},
+		{"offset=50", []int64{}},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+
+		status, page := getPage(t, h, query)
+		if got := userIDs(page.Users); status != http.StatusOK || !slices.Equal(got, tt.expected) {
+			t.Errorf("Expected users %v for %s, got %d %v", tt.expected, tt.query, status, got)
+		}
+	}
+
+	if _, page := getPage(t, h, nil); len(page.Users) != defaultPageLimit {
+		t.Errorf("Expected %d users by default, got %d", defaultPageLimit, len(page.Users))
+	}
+
+	for _, query := range []string{"limit=0", "limit=101", "limit=ten", "offset=-1", "offset=x"} {
+		q, _ := url.ParseQuery(query)
+
+		if status, _ := getPage(t, h, q); status != http.StatusBadRequest {
+			t.Errorf("Expected %d for %s, got %d", http.StatusBadRequest, query, status)
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2025, time.January, 1, 10, 30, 0, 0, time.UTC), ID: 42}
+	s := EncodeCursor(cursorSecret, c)
+
+	if got, err := DecodeCursor(cursorSecret, s); err != nil || !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID {
+		t.Errorf("Expected cursor %+v, got %+v, %v", c, got, err)
+	}
+
+	if strings.ContainsAny(s, "+/=") {
+		t.Errorf("Expected a cursor safe for URLs, got %q", s)
+	}
+
+	// A client crafts a cursor for another position, with the payload of another cursor.
+	payload, _ := json.Marshal(Cursor{CreatedAt: c.CreatedAt, ID: 1})
+	crafted := base64.RawURLEncoding.EncodeToString(payload)
+
+	if _, signature, ok := strings.Cut(s, "."); ok {
+		crafted += "." + signature
+	}
+
+	for name, cursor := range map[string]string{
+		"crafted cursor":                    crafted,
+		"unsigned cursor":                   base64.RawURLEncoding.EncodeToString(payload),
+		"cursor signed with another secret": EncodeCursor([]byte("another-secret"), c),
+		"cursor that is not base64":         "!!!",
+		"empty cursor":                      "",
+	} {
+		if _, err := DecodeCursor(cursorSecret, cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for the %s, got %v", name, err)
+		}
+	}
+}
+
+// walkKeyset requests pages of limit users until the last one, calling between with the users seen so far
+// after every page.
+func walkKeyset(t *testing.T, h http.Handler, limit int, between func(seen []int64)) []int64 {
+	t.Helper()
+
+	var ids []int64
+
+	query := url.Values{"limit": {fmt.Sprint(limit)}}
+
+	for range 100 {
+		status, page := getPage(t, h, query)
+		if status != http.StatusOK {
+			t.Fatalf("Expected %d for %s, got %d", http.StatusOK, query.Encode(), status)
+		}
+
+		ids = append(ids, userIDs(page.Users)...)
+
+		if page.NextCursor == "" {
+			return ids
+		}
+
+		query.Set("cursor", page.NextCursor)
+		between(ids)
+	}
+
+	t.Fatalf("Expected the listing to end, got %d users", len(ids))
+
+	return nil
+}
+
+func TestListUsersKeyset(t *testing.T) {
+	h := ListUsersKeyset(newUserTable(50), cursorSecret)
+
+	ids := walkKeyset(t, h, 7, func([]int64) {})
+
+	expected := make([]int64, 50)
+	for i := range expected {
+		expected[i] = int64(50 - i)
+	}
+
+	if !slices.Equal(ids, expected) {
+		t.Errorf("Expected all users from the newest, got %v", ids)
+	}
+
+	// A page that ends exactly at the last user has no next cursor, the next one would be empty.
+	_, first := getPage(t, h, url.Values{"limit": {"25"}})
+	if _, last := getPage(t, h, url.Values{"limit": {"25"}, "cursor": {first.NextCursor}}); len(last.Users) != 25 || last.NextCursor != "" {
+		t.Errorf("Expected the last 25 users without a next cursor, got %v and cursor %q", userIDs(last.Users), last.NextCursor)
+	}
+
+	crafted := base64.RawURLEncoding.EncodeToString([]byte(`{"created_at":"2025-01-01T00:10:00Z","id":10}`))
+
+	for _, query := range []url.Values{{"cursor": {crafted}}, {"cursor": {"!!!"}}, {"limit": {"0"}}} {
+		if status, _ := getPage(t, h, query); status != http.StatusBadRequest {
+			t.Errorf("Expected %d for %s, got %d", http.StatusBadRequest, query.Encode(), status)
+		}
+	}
+}
+
+func TestPaginationConcurrentChanges(t *testing.T) {
+	const users = 50
+
+	// Between pages, two users sign up or two of the users already seen are deleted, in turns.
+	changes := func(table *userTable) func(seen []int64) {
+		page, next := 0, int64(1000)
+
+		return func(seen []int64) {
+			page++
+
+			for i := range 2 {
+				if page%2 == 1 {
+					next++
+					table.Insert(UserRow{ID: next, Name: "newcomer", CreatedAt: time.Now()})
+				} else {
+					table.Delete(seen[len(seen)-1-i])
+				}
+			}
+		}
+	}
+
+	t.Run("offset", func(t *testing.T) {
+		table := newUserTable(users)
+		h := ListUsersOffset(table)
+
+		var ids []int64
+
+		between := changes(table)
+
+		for offset := 0; offset < 200; offset += 10 {
+			_, page := getPage(t, h, url.Values{"offset": {fmt.Sprint(offset)}, "limit": {"10"}})
+			if len(page.Users) == 0 {
+				break
+			}
+
+			ids = append(ids, userIDs(page.Users)...)
+			between(ids)
+		}
+
+		// Offsets don't survive changes: this is the problem keyset pagination solves.
+		if duplicates, missing := countPageErrors(ids, users); duplicates == 0 || missing == 0 {
+			t.Errorf("Expected offset pagination to return some users twice and skip some, got %d twice and %d skipped: %v",
+				duplicates, missing, ids)
+		}
+	})
+
+	t.Run("keyset", func(t *testing.T) {
+		table := newUserTable(users)
+		h := ListUsersKeyset(table, cursorSecret)
+
+		ids := walkKeyset(t, h, 10, changes(table))
+
+		if duplicates, missing := countPageErrors(ids, users); duplicates != 0 || missing != 0 {
+			t.Errorf("Expected every user once, got %d users twice and %d skipped: %v", duplicates, missing, ids)
+		}
+	})
+}
+
+// countPageErrors counts users listed more than once, and users of the table before the walk that weren't listed.
+// Deleted users were listed before they were deleted, so every one of them was listed.
+func countPageErrors(ids []int64, users int) (duplicates, missing int) {
+	seen := make(map[int64]int)
+	for _, id := range ids {
+		seen[id]++
+	}
+
+	for id, n := range seen {
+		if n > 1 && id <= int64(users) {
+			duplicates += n - 1
+		}
+	}
+
+	for id := range int64(users) {
+		if seen[id+1] == 0 {
+			missing++
+		}
+	}
+
+	return duplicates, missing
+}
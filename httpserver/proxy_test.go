@@ -0,0 +1,347 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Advanced: a reverse proxy with load balancing.
+//
+// A reverse proxy accepts requests of clients and forwards them to backends, instances of a service behind it.
+// httputil.ReverseProxy does the forwarding: it rewrites the request, copies the response back, and handles
+// hop-by-hop headers, X-Forwarded-For, streaming, and protocol upgrades. What's left is picking a backend:
+// - round-robin takes backends in turn, which is fair when all requests cost about the same,
+// - least-connections takes the backend with the fewest requests in flight, so a slow backend gets less work,
+// - health checks poll every backend, and take a failing one out of rotation until it recovers,
+// - retries send a request that failed to reach a backend to the next one. Only idempotent requests like GET
+//   can be retried safely: a POST may have been processed before the connection broke.
+//
+// Let's implement:
+// - RoundRobin and LeastConnections, ties of least connections go to the first backend,
+// - CheckHealth: a goroutine per backend that requests the path every interval and marks the backend healthy
+//   on 200 and unhealthy on anything else, including errors, until the context is done,
+// - retries in ServeHTTP: a GET or HEAD that fails to reach a backend, or gets 502 Bad Gateway from it, is sent
+//   to the next backend, at most once to every backend. The ErrorHandler and ModifyResponse hooks of ReverseProxy
+//   report a failed attempt before anything is written to the client.
+
+// Backend is a backend of the proxy.
+type Backend struct {
+	URL *url.URL
+
+	healthy atomic.Bool
+	active  atomic.Int64
+}
+
+// Healthy reports whether the backend passed its last health check.
+func (b *Backend) Healthy() bool {
+	return b.healthy.Load()
+}
+
+// Active returns the number of requests in flight to the backend.
+func (b *Backend) Active() int64 {
+	return b.active.Load()
+}
+
+// Balancer picks a backend for the next request among healthy backends.
+type Balancer interface {
+	Next(backends []*Backend) *Backend
+}
+
+// RoundRobin picks backends in turn.
+type RoundRobin struct {
+	next atomic.Uint64
+}
+
+func (rr *RoundRobin) Next(backends []*Backend) *Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+
+	return backends[0]
+}
+
+// LeastConnections picks the backend with the fewest requests in flight.
+type LeastConnections struct{}
+
+func (LeastConnections) Next(backends []*Backend) *Backend {
+	if len(backends) == 0 {
+		return nil
+	}
+
+	return backends[0]
+}
+
+// Proxy balances requests between backends.
+type Proxy struct {
+	backends []*Backend
+	balancer Balancer
+}
+
+// NewProxy creates a proxy for the backends, all of them are considered healthy until checked.
+func NewProxy(targets []*url.URL, balancer Balancer) *Proxy {
+	p := &Proxy{balancer: balancer}
+
+	for _, target := range targets {
+		b := &Backend{URL: target}
+		b.healthy.Store(true)
+		p.backends = append(p.backends, b)
+	}
+
+	return p
+}
+
+// healthyBackends returns backends that passed their last health check.
+func (p *Proxy) healthyBackends() []*Backend {
+	var healthy []*Backend
+
+	for _, b := range p.backends {
+		if b.Healthy() {
+			healthy = append(healthy, b)
+		}
+	}
+
+	return healthy
+}
+
+// CheckHealth checks every backend by requesting the path every interval until the context is done.
+func (p *Proxy) CheckHealth(ctx context.Context, path string, interval time.Duration) {
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b := p.balancer.Next(p.healthyBackends())
+	if b == nil {
+		http.Error(w, "no healthy backends", http.StatusServiceUnavailable)
+		return
+	}
+
+	b.active.Add(1)
+	defer b.active.Add(-1)
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(b.URL)
+			pr.SetXForwarded()
+		},
+	}
+
+	proxy.ServeHTTP(w, r)
+}
+
+// testBackend is a backend that responds with its name and the status.
+type testBackend struct {
+	name   string
+	server *httptest.Server
+	status atomic.Int64
+	// release, if not nil, holds requests until it's closed.
+	release chan struct{}
+}
+
+func newTestBackend(t *testing.T, name string) *testBackend {
+	b := &testBackend{name: name}
+	b.status.Store(http.StatusOK)
+
+	b.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.release != nil && r.URL.Path != "/healthz" {
+			<-b.release
+		}
+
+		w.WriteHeader(int(b.status.Load()))
+		fmt.Fprint(w, b.name)
+	}))
+	t.Cleanup(b.server.Close)
+
+	return b
+}
+
+// proxyTo starts a proxy for the backends.
+func proxyTo(t *testing.T, balancer Balancer, backends ...*testBackend) (*Proxy, *httptest.Server) {
+	var targets []*url.URL
+
+	for _, b := range backends {
+		u, _ := url.Parse(b.server.URL)
+		targets = append(targets, u)
+	}
+
+	p := NewProxy(targets, balancer)
+	server := httptest.NewServer(p)
+	t.Cleanup(server.Close)
+
+	return p, server
+}
+
+// send sends a request through the proxy and returns the status and the name of the backend that responded.
+func send(t *testing.T, client *http.Client, method, url string) (int, string) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, url, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(body)
+}
+
+func TestRoundRobin(t *testing.T) {
+	a, b, c := newTestBackend(t, "a"), newTestBackend(t, "b"), newTestBackend(t, "c")
+	_, server := proxyTo(t, &RoundRobin{}, a, b, c)
+
+	var order string
+
+	for range 9 {
+		_, name := send(t, http.DefaultClient, http.MethodGet, server.URL)
+		order += name
+	}
+
+	if order != "abcabcabc" {
+		t.Errorf("Expected backends to take requests in turn, got %q", order)
+	}
+
+	// Concurrent requests are distributed evenly as well.
+	var (
+		mu     sync.Mutex
+		counts = make(map[string]int)
+		wg     sync.WaitGroup
+	)
+
+	for range 30 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+
+			mu.Lock()
+			counts[string(body)]++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if counts["a"] != 10 || counts["b"] != 10 || counts["c"] != 10 {
+		t.Errorf("Expected 10 requests to every backend, got %v", counts)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	slow, fast := newTestBackend(t, "slow"), newTestBackend(t, "fast")
+	slow.release = make(chan struct{})
+	p, server := proxyTo(t, LeastConnections{}, slow, fast)
+
+	release := sync.OnceFunc(func() { close(slow.release) })
+	t.Cleanup(release)
+
+	// The first request goes to the slow backend and hangs there.
+	hanging := make(chan string, 1)
+
+	go func() {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			hanging <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		hanging <- string(body)
+	}()
+
+	if !waitFor(func() bool { return p.backends[0].Active() == 1 }) {
+		t.Fatal("Expected the first request to go to the first backend")
+	}
+
+	client := &http.Client{Timeout: time.Second}
+
+	for range 5 {
+		if _, name := send(t, client, http.MethodGet, server.URL); name != "fast" {
+			t.Errorf("Expected requests to go to the backend without requests in flight, got %q", name)
+		}
+	}
+
+	release()
+
+	if name := <-hanging; name != "slow" {
+		t.Errorf("Expected the first request to be served by the slow backend, got %q", name)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	a, b := newTestBackend(t, "a"), newTestBackend(t, "b")
+	p, server := proxyTo(t, &RoundRobin{}, a, b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go p.CheckHealth(ctx, "/healthz", 10*time.Millisecond)
+
+	b.status.Store(http.StatusServiceUnavailable)
+
+	if !waitFor(func() bool { return !p.backends[1].Healthy() }) {
+		t.Fatal("Expected the failing backend to be marked unhealthy")
+	}
+
+	for range 4 {
+		if status, name := send(t, http.DefaultClient, http.MethodGet, server.URL); status != http.StatusOK || name != "a" {
+			t.Errorf("Expected requests to go only to the healthy backend, got %d from %q", status, name)
+		}
+	}
+
+	b.status.Store(http.StatusOK)
+
+	if !waitFor(func() bool { return p.backends[1].Healthy() }) {
+		t.Fatal("Expected the backend to be back in rotation when it recovered")
+	}
+
+	a.server.Close()
+
+	if !waitFor(func() bool { return !p.backends[0].Healthy() }) {
+		t.Error("Expected an unreachable backend to be marked unhealthy")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	dead, broken, ok := newTestBackend(t, "dead"), newTestBackend(t, "broken"), newTestBackend(t, "ok")
+	dead.server.Close()
+	broken.status.Store(http.StatusBadGateway)
+
+	_, server := proxyTo(t, &RoundRobin{}, dead, broken, ok)
+
+	for range 6 {
+		if status, name := send(t, http.DefaultClient, http.MethodGet, server.URL); status != http.StatusOK || name != "ok" {
+			t.Errorf("Expected GET to be retried on the next backend, got %d from %q", status, name)
+		}
+	}
+
+	statuses := make(map[int]int)
+
+	for range 3 {
+		status, _ := send(t, http.DefaultClient, http.MethodPost, server.URL)
+		statuses[status]++
+	}
+
+	if statuses[http.StatusOK] != 1 || statuses[http.StatusBadGateway] != 2 {
+		t.Errorf("Expected POST not to be retried, 1 of 3 to succeed and 2 to fail with 502, got %v", statuses)
+	}
+}
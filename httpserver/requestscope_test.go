@@ -0,0 +1,187 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// 6. Request-scoped values.
+// Some values belong to a request and have to reach code deep down the call chain: the request ID for logs,
+// the authenticated user for access checks. They travel in the context, which every function on the way
+// takes anyway. Only request-scoped values belong there, never dependencies or optional parameters.
+//
+// context.WithValue compares keys with ==, and a string key is equal to the same string from any other package.
+// Keys of an unexported type can't be equal to keys of other packages, even with the same underlying value,
+// and only this package can set or read its values. Accessor functions hide the keys and give values their types:
+// the rest of the code calls RequestIDFromContext and never context.Value.
+//
+// Fix the keys, then RequestID: it reuses a valid X-Request-ID from the request, so a request can be followed
+// through several services, or generates a new one, and returns it in the response header.
+// The header comes from the client, an ID longer than 64 characters or with anything but letters, digits,
+// and dashes is replaced, or it could inject lines into logs.
+
+// RequestIDHeader is the header with the ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// User is the authenticated user of a request.
+type User struct {
+	ID   string
+	Name string
+}
+
+// WithRequestID returns a copy of the context with the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, "request_id", id)
+}
+
+// RequestIDFromContext returns the request ID from the context.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value("request_id").(string)
+
+	return id, ok
+}
+
+// WithUser returns a copy of the context with the authenticated user.
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, "user", u)
+}
+
+// UserFromContext returns the authenticated user from the context.
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value("user").(User)
+
+	return u, ok
+}
+
+// newRequestID generates a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// RequestID is middleware that puts the ID of the request into its context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), newRequestID())))
+	})
+}
+
+// SessionUser is middleware that authenticates requests by the session cookie.
+// Requests without a known session stay anonymous.
+func SessionUser(sessions map[string]User) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c, err := r.Cookie("session"); err == nil {
+				if u, ok := sessions[c.Value]; ok {
+					r = r.WithContext(WithUser(r.Context(), u))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// describe responds with the request ID and the user from the context.
+var describe = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	id, _ := RequestIDFromContext(r.Context())
+	u, ok := UserFromContext(r.Context())
+
+	if !ok {
+		u.Name = "anonymous"
+	}
+
+	fmt.Fprintf(w, "%s %s", id, u.Name)
+})
+
+var requestIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestRequestID(t *testing.T) {
+	handler := RequestID(SessionUser(map[string]User{"s3cr3t": {ID: "1", Name: "alice"}})(describe))
+
+	tests := []struct {
+		name      string
+		requestID string
+		session   string
+		reused    bool
+		user      string
+	}{
+		{name: "new request", user: "anonymous"},
+		{name: "upstream ID", requestID: "edge-7f3a9c", reused: true, user: "anonymous"},
+		{name: "authenticated", requestID: "edge-7f3a9d", session: "s3cr3t", reused: true, user: "alice"},
+		{name: "unknown session", session: "guessed", user: "anonymous"},
+		{name: "log injection", requestID: "1\nlevel=ERROR msg=hacked"},
+		{name: "too long", requestID: string(make([]byte, 65))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.session})
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+
+			switch {
+			case tt.reused && id != tt.requestID:
+				t.Errorf("Expected the request ID %q from the request to be reused, got %q", tt.requestID, id)
+			case !tt.reused && !requestIDPattern.MatchString(id):
+				t.Errorf("Expected a new random request ID of 32 hex digits, got %q", id)
+			}
+
+			if tt.user != "" && rec.Body.String() != id+" "+tt.user {
+				t.Errorf("Expected the handler to see %q, got %q", id+" "+tt.user, rec.Body)
+			}
+		})
+	}
+}
+
+// tracing and analytics stand for middleware of other packages, which happen to use the same string keys.
+func tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "request_id", "span-0001")))
+	})
+}
+
+func analytics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "user", "visitor-42")))
+	})
+}
+
+func TestContextKeyCollision(t *testing.T) {
+	handler := RequestID(SessionUser(map[string]User{"s3cr3t": {ID: "1", Name: "alice"}})(tracing(analytics(describe))))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "edge-7f3a9c")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Body.String(); got != "edge-7f3a9c alice" {
+		t.Errorf("Expected values of other packages not to overwrite ours, the handler saw %q instead of %q", got, "edge-7f3a9c alice")
+	}
+
+	// Values set by other packages with string keys are not visible through the accessors.
+	ctx := context.WithValue(context.Background(), "user", User{ID: "0", Name: "root"})
+	if u, ok := UserFromContext(ctx); ok {
+		t.Errorf("Expected no user from a value set with a string key, got %+v", u)
+	}
+}
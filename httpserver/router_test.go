@@ -0,0 +1,483 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 12. Routing with a trie.
+// http.ServeMux routes by method and by path patterns with parameters since Go 1.22, and it's the right choice
+// for most services. Let's build a router anyway, it's a classic data structure: a trie of path segments.
+// Every node is a segment, and the path of a request walks down from the root one segment at a time.
+// Patterns use the syntax of ServeMux, handlers read parameters with r.PathValue:
+// - /users/me: static segments match themselves,
+// - /users/{id}: a parameter matches any one segment,
+// - /files/{path...}: a wildcard matches the rest of the path, it's the last segment of a pattern.
+//
+// When several patterns match a path, the most specific one wins: a static segment before a parameter,
+// and a parameter before a wildcard. GET /users/me goes to /users/me, and GET /users/42 to /users/{id}.
+// Preferring a static segment is a choice at one node, and it may lead to a dead end deeper in the trie:
+// with /users/me/settings and /users/{id}/posts, GET /users/me/posts matches only the second pattern.
+// match below never comes back from a dead end, let's make it backtrack and try the next kind of child.
+//
+// A path that matches with another method deserves 405 Method Not Allowed and the Allow header with the methods
+// that do match, sorted. 404 makes a client think the resource doesn't exist.
+//
+// Mistakes in a route table should fail at startup, not at the first request. Handle already rejects broken
+// patterns with *PatternError, but it silently replaces a handler registered for the same method and pattern.
+// A node has one parameter child, so /users/{id} and /users/{name}/posts can't have different names
+// for the same segment. Both are conflicts, Handle must return *ConflictError with the pattern it conflicts with.
+
+// PatternError is returned by Handle for a pattern it can't parse.
+type PatternError struct {
+	Pattern string
+	Reason  string
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid pattern %q: %s", e.Pattern, e.Reason)
+}
+
+// ConflictError is returned by Handle for a pattern that conflicts with a registered one.
+type ConflictError struct {
+	Method   string
+	Pattern  string
+	Existing string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s conflicts with %s", e.Method, e.Pattern, e.Existing)
+}
+
+// Router is an HTTP router on a trie of path segments.
+type Router struct {
+	root node
+}
+
+// node is a node of the trie, it matches one segment of a path.
+type node struct {
+	static   map[string]*node
+	param    *node
+	wildcard *node
+
+	// name is the name of the parameter or the wildcard the node matches.
+	name string
+	// pattern is the first pattern registered through the node, it's reported in conflicts.
+	pattern string
+
+	handlers map[string]http.Handler
+}
+
+// patternSegment is a parsed segment of a pattern.
+type patternSegment struct {
+	value    string
+	param    bool
+	wildcard bool
+}
+
+// pathParam is a value of a parameter in a matched path.
+type pathParam struct {
+	name, value string
+}
+
+// NewRouter creates a router without routes.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// parsePattern splits the pattern into segments.
+func parsePattern(pattern string) ([]patternSegment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, &PatternError{Pattern: pattern, Reason: "must start with /"}
+	}
+
+	parts := strings.Split(pattern[1:], "/")
+	segments := make([]patternSegment, 0, len(parts))
+	names := make(map[string]bool)
+
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") && !strings.HasSuffix(part, "}") {
+			if strings.ContainsAny(part, "{}") {
+				return nil, &PatternError{Pattern: pattern, Reason: fmt.Sprintf("braces in segment %q", part)}
+			}
+
+			segments = append(segments, patternSegment{value: part})
+
+			continue
+		}
+
+		name, opened := strings.CutPrefix(part, "{")
+		name, closed := strings.CutSuffix(name, "}")
+
+		if !opened || !closed {
+			return nil, &PatternError{Pattern: pattern, Reason: fmt.Sprintf("unbalanced braces in segment %q", part)}
+		}
+
+		name, wildcard := strings.CutSuffix(name, "...")
+
+		switch {
+		case name == "" || strings.ContainsAny(name, "{}"):
+			return nil, &PatternError{Pattern: pattern, Reason: fmt.Sprintf("bad parameter name in segment %q", part)}
+		case names[name]:
+			return nil, &PatternError{Pattern: pattern, Reason: fmt.Sprintf("duplicate parameter %q", name)}
+		case wildcard && i != len(parts)-1:
+			return nil, &PatternError{Pattern: pattern, Reason: fmt.Sprintf("wildcard %q is not the last segment", name)}
+		}
+
+		names[name] = true
+		segments = append(segments, patternSegment{value: name, param: !wildcard, wildcard: wildcard})
+	}
+
+	return segments, nil
+}
+
+// Handle registers the handler for the method and the pattern.
+func (r *Router) Handle(method, pattern string, h http.Handler) error {
+	segments, err := parsePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	n := &r.root
+
+	for _, s := range segments {
+		switch {
+		case s.param:
+			if n.param == nil {
+				n.param = &node{name: s.value, pattern: pattern}
+			}
+
+			n = n.param
+		case s.wildcard:
+			if n.wildcard == nil {
+				n.wildcard = &node{name: s.value, pattern: pattern}
+			}
+
+			n = n.wildcard
+		default:
+			if n.static == nil {
+				n.static = make(map[string]*node)
+			}
+
+			if n.static[s.value] == nil {
+				n.static[s.value] = &node{pattern: pattern}
+			}
+
+			n = n.static[s.value]
+		}
+	}
+
+	if n.handlers == nil {
+		n.handlers = make(map[string]http.Handler)
+	}
+
+	n.handlers[method] = h
+
+	return nil
+}
+
+// HandleFunc registers the handler function for the method and the pattern.
+func (r *Router) HandleFunc(method, pattern string, h func(http.ResponseWriter, *http.Request)) error {
+	return r.Handle(method, pattern, http.HandlerFunc(h))
+}
+
+// match finds the node of the most specific pattern that matches the segments of a path.
+func (n *node) match(segments []string, params []pathParam) (*node, []pathParam) {
+	for i, s := range segments {
+		switch {
+		case n.static[s] != nil:
+			n = n.static[s]
+		case n.param != nil && s != "":
+			n = n.param
+			params = append(params, pathParam{name: n.name, value: s})
+		case n.wildcard != nil:
+			n = n.wildcard
+			return n, append(params, pathParam{name: n.name, value: strings.Join(segments[i:], "/")})
+		default:
+			return nil, nil
+		}
+	}
+
+	if len(n.handlers) == 0 {
+		return nil, nil
+	}
+
+	return n, params
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	n, params := r.root.match(strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/"), nil)
+	if n == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	h, ok := n.handlers[req.Method]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	for _, p := range params {
+		req.SetPathValue(p.name, p.value)
+	}
+
+	h.ServeHTTP(w, req)
+}
+
+// routeTable is a route table of a small code hosting API.
+var routeTable = []struct {
+	method, pattern string
+}{
+	{"GET", "/"},
+	{"GET", "/users"},
+	{"POST", "/users"},
+	{"GET", "/users/me"},
+	{"GET", "/users/me/settings"},
+	{"GET", "/users/{id}"},
+	{"PUT", "/users/{id}"},
+	{"DELETE", "/users/{id}"},
+	{"GET", "/users/{id}/posts"},
+	{"GET", "/users/{id}/posts/{post}"},
+	{"GET", "/repos/{owner}/{repo}"},
+	{"GET", "/repos/{owner}/{repo}/issues"},
+	{"POST", "/repos/{owner}/{repo}/issues"},
+	{"GET", "/repos/{owner}/{repo}/issues/{number}"},
+	{"GET", "/repos/{owner}/{repo}/contents/{path...}"},
+	{"GET", "/static/{path...}"},
+	{"GET", "/static/favicon.ico"},
+	{"GET", "/healthz"},
+}
+
+// describeRoute responds with the pattern of the route and the values of its parameters.
+func describeRoute(pattern string) http.Handler {
+	segments, _ := parsePattern(pattern)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Method, " ", pattern)
+
+		for _, s := range segments {
+			if s.param || s.wildcard {
+				fmt.Fprintf(w, " %s=%s", s.value, r.PathValue(s.value))
+			}
+		}
+	})
+}
+
+func newTestRouter(t testing.TB) *Router {
+	t.Helper()
+
+	router := NewRouter()
+
+	for _, route := range routeTable {
+		if err := router.Handle(route.method, route.pattern, describeRoute(route.pattern)); err != nil {
+			t.Fatalf("Failed to register %s %s: %v", route.method, route.pattern, err)
+		}
+	}
+
+	return router
+}
+
+func TestRouterMatch(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/", 200, "GET /"},
+		{"GET", "/users", 200, "GET /users"},
+		{"POST", "/users", 200, "POST /users"},
+		{"GET", "/users/me", 200, "GET /users/me"},
+		{"GET", "/users/42", 200, "GET /users/{id} id=42"},
+		{"DELETE", "/users/42", 200, "DELETE /users/{id} id=42"},
+		{"GET", "/users/42/posts/7", 200, "GET /users/{id}/posts/{post} id=42 post=7"},
+		{"GET", "/users/me/settings", 200, "GET /users/me/settings"},
+		{"GET", "/users/42/settings", 404, ""},
+		{"GET", "/users/me/posts", 200, "GET /users/{id}/posts id=me"},
+		{"GET", "/users/me/posts/7", 200, "GET /users/{id}/posts/{post} id=me post=7"},
+		{"GET", "/repos/golang/go/issues/1", 200, "GET /repos/{owner}/{repo}/issues/{number} owner=golang repo=go number=1"},
+		{"GET", "/repos/golang/go/contents/src/net/http/server.go", 200,
+			"GET /repos/{owner}/{repo}/contents/{path...} owner=golang repo=go path=src/net/http/server.go"},
+		{"GET", "/static/favicon.ico", 200, "GET /static/favicon.ico"},
+		{"GET", "/static/css/main.css", 200, "GET /static/{path...} path=css/main.css"},
+		{"GET", "/static/", 200, "GET /static/{path...} path="},
+		{"GET", "/users/", 404, ""},
+		{"GET", "/repos/golang", 404, ""},
+		{"GET", "/unknown", 404, ""},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("Expected %s %s to respond %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+			continue
+		}
+
+		if body := rec.Body.String(); tt.status == http.StatusOK && body != tt.body {
+			t.Errorf("Expected %s %s to be routed to %q, got %q", tt.method, tt.path, tt.body, body)
+		}
+	}
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		method, path, allow string
+	}{
+		{"POST", "/users/42", "DELETE, GET, PUT"},
+		{"DELETE", "/users", "GET, POST"},
+		{"PATCH", "/repos/golang/go/issues", "GET, POST"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected %s %s to respond 405, got %d", tt.method, tt.path, rec.Code)
+		}
+
+		if allow := rec.Header().Get("Allow"); allow != tt.allow {
+			t.Errorf("Expected %s %s to allow %q, got %q", tt.method, tt.path, tt.allow, allow)
+		}
+	}
+}
+
+func TestRouterConflicts(t *testing.T) {
+	router := newTestRouter(t)
+
+	conflicts := []struct {
+		method, pattern, existing string
+	}{
+		{"GET", "/users/me", "/users/me"},
+		{"DELETE", "/users/{id}", "/users/{id}"},
+		{"GET", "/users/{name}", "/users/{id}"},
+		{"GET", "/users/{name}/friends", "/users/{id}"},
+		{"GET", "/repos/{owner}/{name}/pulls", "/repos/{owner}/{repo}"},
+		{"GET", "/static/{file...}", "/static/{path...}"},
+	}
+
+	for _, tt := range conflicts {
+		err := router.Handle(tt.method, tt.pattern, http.NotFoundHandler())
+
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) {
+			t.Errorf("Expected %s %s to conflict with %s, got %v", tt.method, tt.pattern, tt.existing, err)
+			continue
+		}
+
+		if conflict.Method != tt.method || conflict.Pattern != tt.pattern || conflict.Existing != tt.existing {
+			t.Errorf("Expected a conflict of %s %s with %s, got %+v", tt.method, tt.pattern, tt.existing, conflict)
+		}
+	}
+
+	// The router still serves the routes registered first.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/users/42", nil))
+
+	if body := rec.Body.String(); body != "GET /users/{id} id=42" {
+		t.Errorf("Expected conflicting patterns not to replace registered ones, got %q", body)
+	}
+
+	// More specific patterns, and other methods, don't conflict.
+	for _, pattern := range []string{"/users/admin", "/users/{id}/friends", "/static/{path...}", "/files/{path...}"} {
+		if err := router.Handle("PATCH", pattern, http.NotFoundHandler()); err != nil {
+			t.Errorf("Expected PATCH %s to be registered, got %v", pattern, err)
+		}
+	}
+
+	invalid := []string{"users", "/users/{id", "/users/id}", "/users/{}", "/files/{path...}/raw", "/a/{x}/b/{x}", "/a{b}"}
+
+	for _, pattern := range invalid {
+		var patternErr *PatternError
+		if err := router.Handle("GET", pattern, http.NotFoundHandler()); !errors.As(err, &patternErr) {
+			t.Errorf("Expected %q to be rejected with *PatternError, got %v", pattern, err)
+		}
+	}
+}
+
+// How fast is the trie compared with ServeMux? Both benchmarks route the same requests on the same routes:
+//
+//	go test -run '^$' -bench Routing -benchmem ./httpserver
+//
+// ServeMux is a trie too, with more work per request: it cleans paths, redirects, and handles hosts.
+
+var benchPaths = []struct{ method, path string }{
+	{"GET", "/"},
+	{"GET", "/users/me"},
+	{"GET", "/users/42"},
+	{"PUT", "/users/42"},
+	{"GET", "/users/42/posts/7"},
+	{"GET", "/repos/golang/go/issues/1"},
+	{"GET", "/repos/golang/go/contents/src/net/http/server.go"},
+	{"GET", "/static/css/main.css"},
+	{"GET", "/healthz"},
+}
+
+// discardWriter is a ResponseWriter that throws responses away, so benchmarks measure only routing.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func benchmarkRouting(b *testing.B, h http.Handler) {
+	requests := make([]*http.Request, len(benchPaths))
+	for i, p := range benchPaths {
+		requests[i] = httptest.NewRequest(p.method, p.path, nil)
+	}
+
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := range b.N {
+		h.ServeHTTP(w, requests[i%len(requests)])
+	}
+}
+
+// noop is the handler of every route in benchmarks.
+var noop = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, r.PathValue("id"))
+})
+
+func BenchmarkRoutingTrie(b *testing.B) {
+	router := NewRouter()
+
+	for _, route := range routeTable {
+		if err := router.Handle(route.method, route.pattern, noop); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	benchmarkRouting(b, router)
+}
+
+func BenchmarkRoutingServeMux(b *testing.B) {
+	mux := http.NewServeMux()
+
+	for _, route := range routeTable {
+		// A pattern that ends with a slash matches every path under it in ServeMux, {$} matches only itself.
+		pattern := route.pattern
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "{$}"
+		}
+
+		mux.Handle(route.method+" "+pattern, noop)
+	}
+
+	benchmarkRouting(b, mux)
+}
@@ -0,0 +1,279 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 9. Server-Sent Events.
+// SSE pushes events from the server over a plain HTTP response that never ends. Browsers read it with EventSource,
+// which reconnects by itself. The response has Content-Type text/event-stream, and every event is a block of
+// "field: value" lines ending with an empty line:
+//
+//	data: {"order":42,"status":"shipped"}
+//
+//	data: first line
+//	data: second line
+//
+// A line starting with a colon is a comment, servers send them as heartbeats to keep proxies from closing idle connections.
+//
+// net/http buffers the response and sends it when the buffer fills or the handler returns,
+// and an event stream does neither: events must be flushed one by one with http.Flusher,
+// or http.NewResponseController(w).Flush(), which finds it through wrappers with Unwrap.
+// A handler doesn't learn about a client that went away from a failed write until it writes,
+// the context of the request is cancelled right away: watch it, and unsubscribe from the broker.
+//
+// Fix Events: set the headers, write every line of a message as its own data field, flush after every event,
+// and return when the client disconnects.
+
+// Broker delivers messages to subscribers of topics, it's the broker of the profiling workshop with the leak fixed.
+type Broker struct {
+	mu sync.Mutex
+	// topics map channels of subscribers to their unsubscribe functions.
+	topics map[string]map[chan string]func()
+}
+
+// NewBroker creates a broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{topics: make(map[string]map[chan string]func())}
+}
+
+// Subscribe returns a channel of messages published to the topic, unsubscribe closes it.
+func (b *Broker) Subscribe(topic string) (messages <-chan string, unsubscribe func()) {
+	ch := make(chan string, 16)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[chan string]func())
+	}
+
+	var once sync.Once
+
+	unsubscribe = func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.topics[topic], ch)
+
+			if len(b.topics[topic]) == 0 {
+				delete(b.topics, topic)
+			}
+
+			close(ch)
+		})
+	}
+
+	b.topics[topic][ch] = unsubscribe
+
+	return ch, unsubscribe
+}
+
+// Publish sends the message to all subscribers of the topic, a subscriber with a full buffer misses it.
+func (b *Broker) Publish(topic, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.topics[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// shutdown unsubscribes everyone, tests call it to stop handlers that never stop by themselves.
+func (b *Broker) shutdown() {
+	var unsubscribes []func()
+
+	b.mu.Lock()
+	for _, subscribers := range b.topics {
+		for _, unsubscribe := range subscribers {
+			unsubscribes = append(unsubscribes, unsubscribe)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, unsubscribe := range unsubscribes {
+		unsubscribe()
+	}
+}
+
+// Subscribers returns the number of subscribers of the topic.
+func (b *Broker) Subscribers(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.topics[topic])
+}
+
+// Events streams messages of the topic from the query string as Server-Sent Events.
+func Events(b *Broker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		messages, unsubscribe := b.Subscribe(r.URL.Query().Get("topic"))
+		defer unsubscribe()
+
+		for msg := range messages {
+			fmt.Fprintf(w, "data: %s\n\n", msg)
+		}
+	})
+}
+
+// connect opens the event stream, the headers of the response must arrive right away.
+func connect(t *testing.T, ctx context.Context, url string) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("Unexpected error: %v", r.err)
+		}
+
+		return r.resp
+	case <-time.After(time.Second):
+		t.Fatal("Expected the headers of the response to be flushed right away, none arrived in a second")
+	}
+
+	return nil
+}
+
+// waitFor polls cond until it's true or a second passes.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	return true
+}
+
+// readEvent reads lines of the next event from the stream, skipping comments.
+func readEvent(r *bufio.Reader) ([]string, error) {
+	var lines []string
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return lines, err
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && len(lines) > 0:
+			return lines, nil
+		case line == "" || strings.HasPrefix(line, ":"):
+		default:
+			lines = append(lines, line)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	b := NewBroker()
+	server := httptest.NewServer(Events(b))
+	defer server.Close()
+	defer b.shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp := connect(t, ctx, server.URL+"?topic=orders")
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %q", ct)
+	}
+
+	if !waitFor(func() bool { return b.Subscribers("orders") == 1 }) {
+		t.Fatal("Expected the handler to subscribe to orders")
+	}
+
+	events := make(chan []string, 8)
+
+	go func() {
+		defer close(events)
+
+		r := bufio.NewReader(resp.Body)
+
+		for {
+			lines, err := readEvent(r)
+			if err != nil {
+				return
+			}
+
+			events <- lines
+		}
+	}()
+
+	for _, tt := range []struct {
+		msg      string
+		expected string
+	}{
+		{`{"order":42,"status":"paid"}`, `data: {"order":42,"status":"paid"}`},
+		{`{"order":42,"status":"shipped"}`, `data: {"order":42,"status":"shipped"}`},
+		{"first line\nsecond line", "data: first line\ndata: second line"},
+	} {
+		b.Publish("orders", tt.msg)
+
+		select {
+		case lines := <-events:
+			if got := strings.Join(lines, "\n"); got != tt.expected {
+				t.Errorf("Expected event %q, got %q", tt.expected, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the event %q to arrive right after it was published, it didn't", tt.msg)
+		}
+	}
+}
+
+func TestEventsDisconnect(t *testing.T) {
+	b := NewBroker()
+	server := httptest.NewServer(Events(b))
+	defer server.Close()
+	defer b.shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp := connect(t, ctx, server.URL+"?topic=orders")
+	defer resp.Body.Close()
+
+	if !waitFor(func() bool { return b.Subscribers("orders") == 1 }) {
+		t.Fatal("Expected the handler to subscribe to orders")
+	}
+
+	// The client goes away, and no more events are published to notice it with a failed write.
+	cancel()
+
+	if !waitFor(func() bool { return b.Subscribers("orders") == 0 }) {
+		t.Error("Expected the handler to unsubscribe when the client disconnected")
+	}
+}
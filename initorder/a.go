@@ -0,0 +1,21 @@
+package initorder
+
+import "github.com/ksysoev/go-workshops/initorder/dep"
+
+// A depends on B, so B is initialized first, even though b.go comes after a.go.
+var A = initA()
+
+func initA() string {
+	dep.Record("a.go: var A")
+
+	return B + "A"
+}
+
+// A file can have multiple init functions, they are executed in the order they appear in the file.
+func init() {
+	dep.Record("a.go: init 1")
+}
+
+func init() {
+	dep.Record("a.go: init 2")
+}
@@ -0,0 +1,15 @@
+package initorder
+
+import "github.com/ksysoev/go-workshops/initorder/dep"
+
+var B = initB()
+
+func initB() string {
+	dep.Record("b.go: var B")
+
+	return "B"
+}
+
+func init() {
+	dep.Record("b.go: init")
+}
@@ -0,0 +1,28 @@
+// Package dep is imported by the initorder package.
+// It records every initialization step, so we can see in which order Go initializes packages.
+package dep
+
+var steps []string
+
+// Name is initialized before any init function of this package is called.
+var Name = initName()
+
+func initName() string {
+	Record("dep.go: var Name")
+
+	return "dep"
+}
+
+func init() {
+	Record("dep.go: init")
+}
+
+// Record appends an initialization step to the trace.
+func Record(step string) {
+	steps = append(steps, step)
+}
+
+// Steps returns all recorded initialization steps.
+func Steps() []string {
+	return steps
+}
@@ -0,0 +1,115 @@
+package initorder
+
+import (
+	"errors"
+	"flag"
+	"slices"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/initorder/dep"
+)
+
+// Package initialization in Go happens in the following order:
+// 1. All imported packages are initialized first, every package is initialized only once, even if it's imported by many packages.
+// 2. Package level variables are initialized in dependency order, a variable is initialized after all variables it depends on.
+//    Variables that don't depend on each other are initialized in the order of declaration.
+// 3. init() functions are called in the order they appear in the source, files are presented to the compiler sorted by name.
+//
+// The specification is here https://go.dev/ref/spec#Package_initialization
+//
+// Let's look at a.go, b.go, and dep/dep.go and try to predict the order of initialization steps.
+
+func TestInitOrder(t *testing.T) {
+	expected := []string{
+		// Put initialization steps in the order you expect them to happen, for example:
+		// "a.go: init 1",
+	}
+
+	if !slices.Equal(dep.Steps(), expected) {
+		t.Errorf("Expected initialization order to be %q, got %q", expected, dep.Steps())
+	}
+}
+
+// init functions and package level variables are global state.
+// Global mutable state makes code hard to test, because every test in the package shares it.
+// Tests that share state depend on the order of execution, and they break when order changes.
+// Go test runner can execute tests in random order with -shuffle=on flag, it's a good way to find such dependencies.
+//
+//	go test -shuffle=on -count=5 ./initorder
+
+// requireShuffle fails the test if tests are not executed in random order.
+func requireShuffle(t *testing.T) {
+	t.Helper()
+
+	if f := flag.Lookup("test.shuffle"); f == nil || f.Value.String() == "off" {
+		t.Fatal("This test should be run in random order: go test -shuffle=on ./initorder")
+	}
+}
+
+// ErrUserExists is returned when a user with the same name is already registered.
+var ErrUserExists = errors.New("user already exists")
+
+// UserRegistry keeps track of registered users.
+type UserRegistry struct {
+	users map[string]bool
+}
+
+// NewUserRegistry creates a new empty user registry.
+func NewUserRegistry() *UserRegistry {
+	return &UserRegistry{
+		users: make(map[string]bool),
+	}
+}
+
+// Register adds a user to the registry.
+// It returns an error if the user is already registered.
+func (r *UserRegistry) Register(name string) error {
+	if r.users[name] {
+		return ErrUserExists
+	}
+
+	r.users[name] = true
+
+	return nil
+}
+
+// defaultRegistry is a global registry, it's created once during package initialization.
+var defaultRegistry = NewUserRegistry()
+
+// SignupService registers new users.
+// It has a hidden dependency on the global defaultRegistry.
+type SignupService struct{}
+
+// Signup registers a new user.
+func (s *SignupService) Signup(name string) error {
+	if err := defaultRegistry.Register(name); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Tests below pass only when they are executed in the order of declaration.
+// Let's try to eliminate the dependency between them:
+// convert the hidden global dependency of SignupService into an injected one (e.g. NewSignupService(registry *UserRegistry)),
+// and make every test create its own registry.
+
+func TestSignup(t *testing.T) {
+	requireShuffle(t)
+
+	svc := &SignupService{}
+
+	if err := svc.Signup("alice"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSignupDuplicate(t *testing.T) {
+	requireShuffle(t)
+
+	svc := &SignupService{}
+
+	if err := svc.Signup("alice"); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected error to be %v, got %v", ErrUserExists, err)
+	}
+}
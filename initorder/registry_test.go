@@ -0,0 +1,101 @@
+package initorder
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// Advanced: registration in init.
+//
+// database/sql, image, and encoding packages use a registry that other packages fill from their init functions.
+// A program enables a driver with a blank import, only for its side effects:
+//
+//	import _ "github.com/jackc/pgx/v5/stdlib"
+//
+// The driver package calls sql.Register in its init, so the driver is available before main starts.
+// Registration happens once at startup and it's a programming error to register two drivers with the same name,
+// database/sql panics in this case, so the mistake is found on the first run rather than in production.
+//
+// Let's implement the registry:
+// 1. Register should panic if the name is already registered or the factory is nil.
+// 2. Drivers should return registered names sorted, like sql.Drivers.
+// 3. Open should return an error for unknown drivers, mentioning a forgotten import, the common cause of it.
+// Registration happens in init functions, but Open may be called by many goroutines, think about a mutex.
+
+// Driver connects to a data source.
+type Driver interface {
+	Open(dsn string) (string, error)
+}
+
+// DriverFunc adapts a function to Driver.
+type DriverFunc func(dsn string) (string, error)
+
+func (f DriverFunc) Open(dsn string) (string, error) {
+	return f(dsn)
+}
+
+// Register makes the driver available by the name, it's called from init functions of driver packages.
+func Register(name string, driver Driver) {
+}
+
+// Drivers returns sorted names of registered drivers.
+func Drivers() []string {
+	return nil
+}
+
+// Open opens the data source with the registered driver.
+func Open(name, dsn string) (string, error) {
+	return "", nil
+}
+
+func init() {
+	Register("memory", DriverFunc(func(dsn string) (string, error) {
+		return "memory:" + dsn, nil
+	}))
+}
+
+func init() {
+	Register("file", DriverFunc(func(dsn string) (string, error) {
+		return "file:" + dsn, nil
+	}))
+}
+
+func TestRegistry(t *testing.T) {
+	if got := Drivers(); !slices.Equal(got, []string{"file", "memory"}) {
+		t.Errorf("Expected drivers registered by init functions [file memory], got %v", got)
+	}
+
+	conn, err := Open("memory", "test")
+	if err != nil || conn != "memory:test" {
+		t.Errorf("Expected connection memory:test, got %q and error %v", conn, err)
+	}
+
+	if _, err := Open("postgres", "localhost"); err == nil {
+		t.Error("Expected error for unknown driver")
+	} else {
+		t.Logf("Error for unknown driver: %v", err)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	register := func(name string, driver Driver) (msg string) {
+		defer func() {
+			if r := recover(); r != nil {
+				msg = fmt.Sprint(r)
+			}
+		}()
+
+		Register(name, driver)
+
+		return ""
+	}
+
+	if msg := register("memory", DriverFunc(nil)); msg == "" {
+		t.Error("Expected Register to panic on duplicate name")
+	}
+
+	if msg := register("nil", nil); msg == "" {
+		t.Error("Expected Register to panic on nil driver")
+	}
+}
@@ -0,0 +1,94 @@
+package integrationtests
+
+import (
+	"context"
+	"testing"
+)
+
+// 1. Fixtures.
+// Tests need data: a product with stock, a product sold out. Building it inline in every test buries what
+// the test is about under setup, and a fixture builder keeps only what matters visible:
+//
+//	f := newFixture(t, store)
+//	soldOut := f.Product(withStock(0))
+//
+// Defaults fill in everything else. A fixture must work on a shared database too, where tests run
+// one after another or in parallel on the same tables: every product gets a unique SKU, and is deleted
+// when the test ends. t.Cleanup runs functions after the test and its subtests finish, in the reverse order
+// of registration, so data is removed after everything that depends on it. A fixture that can't create
+// its data fails the test right away, with t.Helper() pointing at the line of the test.
+//
+// Now every product gets the same SKU, errors are ignored, and nothing is cleaned up. Fix Product.
+
+// fixture creates data for a test in a store, and removes it when the test ends.
+type fixture struct {
+	t     testing.TB
+	store Store
+}
+
+func newFixture(t testing.TB, store Store) *fixture {
+	return &fixture{t: t, store: store}
+}
+
+// productOption changes a product before the fixture creates it.
+type productOption func(*Product)
+
+func withStock(n int) productOption {
+	return func(p *Product) {
+		p.Stock = n
+	}
+}
+
+func withName(name string) productOption {
+	return func(p *Product) {
+		p.Name = name
+	}
+}
+
+// Product creates a product with a unique SKU, 10 units of stock, and the options applied.
+func (f *fixture) Product(opts ...productOption) Product {
+	p := Product{SKU: "SKU-1", Name: "Gopher plush", Stock: 10}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	f.store.CreateProduct(context.Background(), p)
+
+	return p
+}
+
+func TestFixture(t *testing.T) {
+	store := newMemStore()
+
+	var created []Product
+
+	t.Run("products", func(t *testing.T) {
+		f := newFixture(t, store)
+
+		plush := f.Product()
+		mug := f.Product(withName("Gopher mug"), withStock(0))
+		created = append(created, plush, mug)
+
+		if plush.SKU == mug.SKU {
+			t.Fatalf("Expected every product to get a unique SKU, got %q twice", plush.SKU)
+		}
+
+		got, err := store.Product(context.Background(), mug.SKU)
+		if err != nil || got != mug {
+			t.Errorf("Expected %+v in the store, got %+v, %v", mug, got, err)
+		}
+
+		// Another test on the same store gets other SKUs.
+		other := newFixture(t, store).Product()
+		created = append(created, other)
+
+		if other.SKU == plush.SKU || other.SKU == mug.SKU {
+			t.Errorf("Expected SKUs to be unique across fixtures, got %q again", other.SKU)
+		}
+	})
+
+	if n := store.len(); n != 0 {
+		t.Errorf("Expected products to be deleted when the test ended, %d left of %+v", n, created)
+	}
+}
@@ -0,0 +1,120 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 2. Unit tests with fakes.
+// A handler is a function of a request and its dependencies, and a unit test calls it directly:
+// a request from httptest.NewRequest, a response from httptest.NewRecorder, and memStore for the Store.
+// No server, no routing: path values are set on the request with SetPathValue, as the mux would.
+// That's fast and precise, every rule of the handler is a row of a table.
+//
+// reserve accepts any quantity, zero and negative ones too, which makes stock from nothing,
+// and answers every error of the store with 500. Make it answer:
+// - 400 Bad Request for a body that isn't JSON, and for a quantity that isn't positive,
+// - 404 Not Found for an unknown SKU,
+// - 409 Conflict when the stock is too low.
+
+// reserveRequest is the body of a reservation.
+type reserveRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// getProduct serves GET /products/{sku}.
+func getProduct(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := store.Product(r.Context(), r.PathValue("sku"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p)
+	}
+}
+
+// reserve serves POST /products/{sku}/reservations, and responds with the product after the reservation.
+func reserve(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req reserveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request", http.StatusInternalServerError)
+			return
+		}
+
+		p, err := store.Reserve(r.Context(), r.PathValue("sku"), req.Quantity)
+		if err != nil {
+			http.Error(w, "failed to reserve", http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p)
+	}
+}
+
+// writeError responds with the status of the error of the store.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// writeJSON responds with the value as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestReserve(t *testing.T) {
+	store := newMemStore()
+	f := newFixture(t, store)
+
+	tests := []struct {
+		name     string
+		sku      string
+		body     string
+		status   int
+		expected int // stock after the request
+	}{
+		{"reserves", "plush", `{"quantity":3}`, http.StatusOK, 7},
+		{"all of it", "plush", `{"quantity":10}`, http.StatusOK, 0},
+		{"zero", "plush", `{"quantity":0}`, http.StatusBadRequest, 10},
+		{"negative", "plush", `{"quantity":-5}`, http.StatusBadRequest, 10},
+		{"not json", "plush", `quantity=1`, http.StatusBadRequest, 10},
+		{"out of stock", "plush", `{"quantity":11}`, http.StatusConflict, 10},
+		{"unknown product", "missing", `{"quantity":1}`, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sku := tt.sku
+			if sku == "plush" {
+				sku = f.Product(withStock(10)).SKU
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/products/"+sku+"/reservations", strings.NewReader(tt.body))
+			req.SetPathValue("sku", sku)
+
+			rec := httptest.NewRecorder()
+			reserve(store).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+
+			if p, err := store.Product(req.Context(), sku); err == nil && p.Stock != tt.expected {
+				t.Errorf("Expected %d in stock, got %d", tt.expected, p.Stock)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package integrationtests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// startPostgres starts PostgreSQL in a container and migrates the schema, the container is removed
+// when the test ends. Starting a container takes seconds, a package with many integration tests
+// starts one in TestMain and isolates tests with unique fixtures instead.
+func startPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+
+	ctr, err := postgres.Run(ctx, "postgres:16-alpine",
+		postgres.WithDatabase("workshop"),
+		postgres.WithUsername("workshop"),
+		postgres.WithPassword("workshop"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(time.Minute),
+		),
+	)
+
+	// A container that started and never got ready must be removed too.
+	t.Cleanup(func() {
+		if err := testcontainers.TerminateContainer(ctr); err != nil {
+			t.Errorf("Failed to remove the container: %v", err)
+		}
+	})
+
+	if err != nil {
+		t.Fatalf("Failed to start PostgreSQL, is Docker running? %v", err)
+	}
+
+	dsn, err := ctr.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := newPGStore(db).Migrate(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return db
+}
+
+func TestPostgresStore(t *testing.T) {
+	testStore(t, newPGStore(startPostgres(t)))
+}
@@ -0,0 +1,181 @@
+package integrationtests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// 4. Advanced: integration tests with testcontainers.
+// The fake is only as good as its agreement with the real store, and one contract, testStore, checks both.
+// The Postgres half needs a database: testcontainers starts PostgreSQL in Docker for the test,
+// waits until it accepts connections, and removes it in t.Cleanup. Integration tests are slow and need Docker,
+// so they are behind a build tag and run on demand, every test file with //go:build integration is left out
+// of a plain go test:
+//
+//	go test -tags=integration -run TestPostgresStore ./integrationtests
+//
+// pgStore passes every test run one after another, and loses reservations under load: Reserve reads the stock,
+// checks it, and writes the new value back, so two transactions that read the same stock both succeed.
+// memStore holds a mutex around all of it, which is why the fake never showed it. Make Reserve a single
+// conditional UPDATE, and tell a missing product from a sold out one when it updates nothing.
+
+// pgSchema creates the table of products.
+const pgSchema = `CREATE TABLE IF NOT EXISTS products (
+	sku   TEXT PRIMARY KEY,
+	name  TEXT NOT NULL,
+	stock INTEGER NOT NULL CHECK (stock >= 0)
+)`
+
+// pgStore is a Store in PostgreSQL.
+type pgStore struct {
+	db *sql.DB
+}
+
+func newPGStore(db *sql.DB) *pgStore {
+	return &pgStore{db: db}
+}
+
+// Migrate creates the schema if it doesn't exist.
+func (s *pgStore) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, pgSchema); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	return nil
+}
+
+func (s *pgStore) CreateProduct(ctx context.Context, p Product) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO products (sku, name, stock) VALUES ($1, $2, $3)`, p.SKU, p.Name, p.Stock)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicate
+	}
+
+	if err != nil {
+		return fmt.Errorf("insert product %s: %w", p.SKU, err)
+	}
+
+	return nil
+}
+
+func (s *pgStore) Product(ctx context.Context, sku string) (Product, error) {
+	var p Product
+
+	err := s.db.QueryRowContext(ctx, `SELECT sku, name, stock FROM products WHERE sku = $1`, sku).Scan(&p.SKU, &p.Name, &p.Stock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Product{}, ErrNotFound
+	}
+
+	if err != nil {
+		return Product{}, fmt.Errorf("select product %s: %w", sku, err)
+	}
+
+	return p, nil
+}
+
+func (s *pgStore) DeleteProduct(ctx context.Context, sku string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM products WHERE sku = $1`, sku); err != nil {
+		return fmt.Errorf("delete product %s: %w", sku, err)
+	}
+
+	return nil
+}
+
+func (s *pgStore) Reserve(ctx context.Context, sku string, quantity int) (Product, error) {
+	p, err := s.Product(ctx, sku)
+	if err != nil {
+		return Product{}, err
+	}
+
+	if p.Stock < quantity {
+		return Product{}, ErrOutOfStock
+	}
+
+	p.Stock -= quantity
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE products SET stock = $2 WHERE sku = $1`, sku, p.Stock); err != nil {
+		return Product{}, fmt.Errorf("update product %s: %w", sku, err)
+	}
+
+	return p, nil
+}
+
+// testStore is the contract of Store, the fake and the real store must both pass it.
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	f := newFixture(t, store)
+
+	plush := f.Product(withStock(5))
+
+	if err := store.CreateProduct(ctx, plush); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a taken SKU, got %v", err)
+	}
+
+	if _, err := store.Product(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown SKU, got %v", err)
+	}
+
+	if got, err := store.Reserve(ctx, plush.SKU, 2); err != nil || got.Stock != 3 {
+		t.Errorf("Expected 3 left after reserving 2 of 5, got %+v, %v", got, err)
+	}
+
+	if _, err := store.Reserve(ctx, plush.SKU, 4); !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("Expected ErrOutOfStock reserving 4 of 3, got %v", err)
+	}
+
+	if got, err := store.Product(ctx, plush.SKU); err != nil || got.Stock != 3 {
+		t.Errorf("Expected a failed reservation to change nothing, got %+v, %v", got, err)
+	}
+
+	if _, err := store.Reserve(ctx, "missing", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound reserving an unknown SKU, got %v", err)
+	}
+
+	if err := store.DeleteProduct(ctx, "missing"); err != nil {
+		t.Errorf("Expected deleting a missing product to succeed, got %v", err)
+	}
+
+	mug := f.Product(withName("Gopher mug"), withStock(10))
+
+	var (
+		mu       sync.Mutex
+		reserved int
+		wg       sync.WaitGroup
+	)
+
+	for range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := store.Reserve(ctx, mug.SKU, 1)
+
+			switch {
+			case err == nil:
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			case !errors.Is(err, ErrOutOfStock):
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if got, err := store.Product(ctx, mug.SKU); reserved != 10 || err != nil || got.Stock != 0 {
+		t.Errorf("Expected 10 of 50 concurrent reservations to succeed and none left, got %d and %+v, %v", reserved, got, err)
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	testStore(t, newMemStore())
+}
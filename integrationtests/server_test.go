@@ -0,0 +1,134 @@
+package integrationtests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// 3. Component tests with httptest.
+// Unit tests of handlers pass while the service is broken: they never go through the mux, so a route
+// that doesn't match, or names its wildcard differently from the handler, is invisible to them.
+// A component test starts the whole handler on httptest.NewServer, a real server on a local port,
+// and talks to it with a real client: routing, methods, headers, and encoding are all in the path.
+// It's still fast, the store is the fake, and t.Cleanup closes the server.
+//
+// TestReserve passes, and yet the API doesn't work: NewHandler names the wildcard of products {id},
+// and accepts reservations with any method. Fix the routes, GET of reservations must be 405 Method Not Allowed.
+
+// NewHandler routes requests of the inventory API.
+func NewHandler(store Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /products/{id}", getProduct(store))
+	mux.Handle("/products/{id}/reservations", reserve(store))
+
+	return mux
+}
+
+// startServer starts the API on the store, the server is closed when the test ends.
+func startServer(t *testing.T, store Store) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(NewHandler(store))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// do sends a request to the API and decodes a successful response into the product.
+func do(t *testing.T, method, url, body string) (*http.Response, Product) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var p Product
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			t.Errorf("Expected a product in the response, got %v", err)
+		}
+	}
+
+	return resp, p
+}
+
+func TestAPI(t *testing.T) {
+	store := newMemStore()
+	f := newFixture(t, store)
+	server := startServer(t, store)
+
+	plush := f.Product(withStock(10))
+
+	resp, got := do(t, http.MethodGet, server.URL+"/products/"+plush.SKU, "")
+	if resp.StatusCode != http.StatusOK || got != plush {
+		t.Errorf("Expected 200 with %+v, got %d with %+v", plush, resp.StatusCode, got)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+
+	if resp, _ := do(t, http.MethodGet, server.URL+"/products/missing", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown product, got %d", resp.StatusCode)
+	}
+
+	resp, got = do(t, http.MethodPost, server.URL+"/products/"+plush.SKU+"/reservations", `{"quantity":4}`)
+	if resp.StatusCode != http.StatusOK || got.Stock != 6 {
+		t.Errorf("Expected 200 with 6 in stock, got %d with %+v", resp.StatusCode, got)
+	}
+
+	if resp, _ := do(t, http.MethodGet, server.URL+"/products/"+plush.SKU+"/reservations", ""); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET of reservations, got %d", resp.StatusCode)
+	}
+}
+
+func TestConcurrentReservations(t *testing.T) {
+	store := newMemStore()
+	f := newFixture(t, store)
+	server := startServer(t, store)
+
+	mug := f.Product(withName("Gopher mug"), withStock(10))
+
+	var (
+		mu       sync.Mutex
+		statuses = make(map[int]int)
+		wg       sync.WaitGroup
+	)
+
+	for range 20 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := http.Post(server.URL+"/products/"+mug.SKU+"/reservations", "application/json", strings.NewReader(`{"quantity":1}`))
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			resp.Body.Close()
+
+			mu.Lock()
+			statuses[resp.StatusCode]++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if statuses[http.StatusOK] != 10 || statuses[http.StatusConflict] != 10 {
+		t.Errorf("Expected 10 reservations to succeed and 10 to conflict, got %v", statuses)
+	}
+}
@@ -0,0 +1,115 @@
+package integrationtests
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// The test pyramid.
+// Tests trade speed for confidence. Unit tests check one piece with its dependencies replaced by fakes:
+// they run in microseconds and pinpoint the bug, but say nothing about how the pieces fit together.
+// Component tests run a whole service in the process, a real HTTP server from httptest with fakes behind it,
+// and catch routing, encoding, and wiring mistakes. Integration tests talk to real infrastructure,
+// a database in a container: slow, and the only ones that know how the database really behaves.
+// A healthy suite is a pyramid: many unit tests, fewer component tests, and a few integration tests on top.
+//
+// All three levels share the same fixtures, and every level leaves nothing behind when a test ends.
+// The workshop is an inventory API: products with stock, and reservations that take units of the stock.
+
+var (
+	ErrNotFound   = errors.New("product not found")
+	ErrDuplicate  = errors.New("product already exists")
+	ErrOutOfStock = errors.New("not enough stock")
+)
+
+// Product is a product in stock.
+type Product struct {
+	SKU   string `json:"sku"`
+	Name  string `json:"name"`
+	Stock int    `json:"stock"`
+}
+
+// Store keeps products.
+type Store interface {
+	// CreateProduct stores a new product, or returns ErrDuplicate if the SKU is taken.
+	CreateProduct(ctx context.Context, p Product) error
+	// Product returns the product with the SKU, or ErrNotFound.
+	Product(ctx context.Context, sku string) (Product, error)
+	// DeleteProduct removes the product with the SKU, deleting a missing product is not an error.
+	DeleteProduct(ctx context.Context, sku string) error
+	// Reserve takes quantity units of the stock atomically and returns the product after it,
+	// or returns ErrOutOfStock and changes nothing if there are not enough of them.
+	Reserve(ctx context.Context, sku string, quantity int) (Product, error)
+}
+
+// memStore is a Store in memory, the fake for unit and component tests.
+type memStore struct {
+	mu       sync.Mutex
+	products map[string]Product
+}
+
+func newMemStore() *memStore {
+	return &memStore{products: make(map[string]Product)}
+}
+
+func (s *memStore) CreateProduct(ctx context.Context, p Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[p.SKU]; ok {
+		return ErrDuplicate
+	}
+
+	s.products[p.SKU] = p
+
+	return nil
+}
+
+func (s *memStore) Product(ctx context.Context, sku string) (Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[sku]
+	if !ok {
+		return Product{}, ErrNotFound
+	}
+
+	return p, nil
+}
+
+func (s *memStore) DeleteProduct(ctx context.Context, sku string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.products, sku)
+
+	return nil
+}
+
+func (s *memStore) Reserve(ctx context.Context, sku string, quantity int) (Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[sku]
+	if !ok {
+		return Product{}, ErrNotFound
+	}
+
+	if p.Stock < quantity {
+		return Product{}, ErrOutOfStock
+	}
+
+	p.Stock -= quantity
+	s.products[sku] = p
+
+	return p, nil
+}
+
+// len returns the number of products.
+func (s *memStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.products)
+}
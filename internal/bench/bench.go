@@ -0,0 +1,352 @@
+// Package bench parses benchmark output of go test and compares runs of benchmarks the way benchstat does:
+// by medians with confidence intervals, and with a Mann-Whitney U test that tells real changes from noise.
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Alpha is the significance level, differences with a higher p-value are considered noise.
+const Alpha = 0.05
+
+// Confidence is the level of confidence intervals of medians.
+const Confidence = 0.95
+
+// Unit is a measurement reported by benchmarks, named like benchstat names them.
+type Unit string
+
+const (
+	SecPerOp    Unit = "sec/op"
+	BytesPerOp  Unit = "B/op"
+	AllocsPerOp Unit = "allocs/op"
+)
+
+// Units lists measurements in the order they are reported.
+var Units = []Unit{SecPerOp, BytesPerOp, AllocsPerOp}
+
+// Run is a single run of a benchmark, one of -count runs.
+type Run struct {
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op"`
+	AllocsPerOp float64 `json:"allocs_per_op"`
+}
+
+// Value returns the measurement of the run in the unit.
+func (r Run) Value(u Unit) float64 {
+	switch u {
+	case SecPerOp:
+		return r.NsPerOp / 1e9
+	case BytesPerOp:
+		return r.BytesPerOp
+	case AllocsPerOp:
+		return r.AllocsPerOp
+	default:
+		return 0
+	}
+}
+
+// Results are runs of benchmarks by their names, without the -GOMAXPROCS suffix.
+type Results map[string][]Run
+
+// Values returns measurements of the benchmark in the unit, one per run.
+func (r Results) Values(name string, u Unit) []float64 {
+	values := make([]float64, 0, len(r[name]))
+	for _, run := range r[name] {
+		values = append(values, run.Value(u))
+	}
+
+	return values
+}
+
+// Names returns names of benchmarks, sorted.
+func (r Results) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// procsRe matches the -GOMAXPROCS suffix go test adds to names of benchmarks.
+var procsRe = regexp.MustCompile(`-\d+$`)
+
+// Parse reads results of benchmarks from go test -bench output, other lines are ignored.
+func Parse(r io.Reader) (Results, error) {
+	results := make(Results)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// A result is the name, the number of iterations, and pairs of a value and a unit.
+		if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		var run Run
+
+		for i := 2; i < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of %s", fields[i], fields[0])
+			}
+
+			switch fields[i+1] {
+			case "ns/op":
+				run.NsPerOp = v
+			case "B/op":
+				run.BytesPerOp = v
+			case "allocs/op":
+				run.AllocsPerOp = v
+			}
+		}
+
+		name := procsRe.ReplaceAllString(fields[0], "")
+		results[name] = append(results[name], run)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read benchmark output: %w", err)
+	}
+
+	return results, nil
+}
+
+// Summary is the median of measurements with its confidence interval.
+type Summary struct {
+	Center float64
+	Lo     float64
+	Hi     float64
+
+	// Confident is false when there are too few measurements for the confidence interval,
+	// then Lo and Hi are the smallest and the largest of them.
+	Confident bool
+}
+
+// Summarize returns the median of the values and its confidence interval. The interval is built from order
+// statistics, so like the median it makes no assumption about the distribution of the values.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+
+	sorted := slices.Sorted(slices.Values(values))
+	n := len(sorted)
+
+	s := Summary{Center: median(sorted), Lo: sorted[0], Hi: sorted[n-1]}
+
+	// The median is between the k-th smallest and the k-th largest values with the probability
+	// that at least k of n values fall on each side of it, each one does with the probability of 1/2.
+	for k := n / 2; k >= 1; k-- {
+		if 1-2*binomialCDF(k-1, n) >= Confidence {
+			s.Lo, s.Hi, s.Confident = sorted[k-1], sorted[n-k], true
+			break
+		}
+	}
+
+	return s
+}
+
+// Spread returns the larger distance from the median to a bound of its interval, relative to the median.
+func (s Summary) Spread() float64 {
+	if s.Center == 0 {
+		return 0
+	}
+
+	return max(s.Center-s.Lo, s.Hi-s.Center) / s.Center
+}
+
+// Comparison is the difference between measurements of a benchmark in two runs.
+type Comparison struct {
+	Old Summary
+	New Summary
+
+	// P is the p-value of the Mann-Whitney U test: the probability of a difference this large between samples
+	// of the same distribution. Differences with P of Alpha or more are noise.
+	P float64
+
+	// N is the number of measurements of each run.
+	OldN int
+	NewN int
+}
+
+// Compare compares the old measurements of a benchmark with the new ones.
+func Compare(old, new []float64) Comparison {
+	return Comparison{
+		Old:  Summarize(old),
+		New:  Summarize(new),
+		P:    mannWhitney(old, new),
+		OldN: len(old),
+		NewN: len(new),
+	}
+}
+
+// Significant reports whether the difference is unlikely to be noise.
+func (c Comparison) Significant() bool {
+	return c.P < Alpha
+}
+
+// Delta returns the relative change of the median, like -0.5 for half of the old value.
+// An insignificant difference is zero, like benchstat prints ~ for it.
+func (c Comparison) Delta() float64 {
+	if !c.Significant() || c.Old.Center == 0 {
+		return 0
+	}
+
+	return c.New.Center/c.Old.Center - 1
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// binomialCDF returns the probability of at most k successes of n trials with the probability of 1/2.
+func binomialCDF(k, n int) float64 {
+	p, c := 0.0, 1.0
+
+	for i := 0; i <= k; i++ {
+		p += c
+		c = c * float64(n-i) / float64(i+1)
+	}
+
+	return p / math.Pow(2, float64(n))
+}
+
+// mannWhitney returns the two-sided p-value of the Mann-Whitney U test of the samples. Without ties
+// the distribution of U is computed exactly, with ties it's approximated by the normal distribution.
+func mannWhitney(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type value struct {
+		v     float64
+		first bool
+	}
+
+	all := make([]value, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, value{v, true})
+	}
+
+	for _, v := range y {
+		all = append(all, value{v, false})
+	}
+
+	slices.SortFunc(all, func(a, b value) int {
+		switch {
+		case a.v < b.v:
+			return -1
+		case a.v > b.v:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	// Tied values share the average of their ranks.
+	var rankSum, ties float64
+
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+
+		rank := float64(i+j+1) / 2
+		for _, v := range all[i:j] {
+			if v.first {
+				rankSum += rank
+			}
+		}
+
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u := rankSum - float64(n1*(n1+1))/2
+	mean := float64(n1*n2) / 2
+
+	if ties == 0 && n1+n2 <= 50 {
+		lower, upper := exactU(n1, n2, u)
+		return min(1, 2*min(lower, upper))
+	}
+
+	n := float64(n1 + n2)
+
+	sigma := math.Sqrt(float64(n1*n2) / 12 * (n + 1 - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+
+	z := (math.Abs(u-mean) - 0.5) / sigma
+
+	return min(1, math.Erfc(max(z, 0)/math.Sqrt2))
+}
+
+// exactU returns the probabilities of U of at most u and at least u for samples of sizes n1 and n2 without ties.
+func exactU(n1, n2 int, u float64) (lower, upper float64) {
+	// counts[m][v] is the number of orderings of m values of the first sample and j values of the second one
+	// with U of v, built up for j from 0 to n2: a value of the second sample added last is larger than all others.
+	counts := make([][]float64, n1+1)
+	for m := range counts {
+		counts[m] = make([]float64, n1*n2+1)
+		counts[m][0] = 1
+	}
+
+	for j := 1; j <= n2; j++ {
+		next := make([][]float64, n1+1)
+		next[0] = make([]float64, n1*n2+1)
+		next[0][0] = 1
+
+		for m := 1; m <= n1; m++ {
+			next[m] = make([]float64, n1*n2+1)
+
+			// The largest value is either from the second sample, or from the first one and larger than j values.
+			for v := range next[m] {
+				next[m][v] = counts[m][v]
+				if v >= j {
+					next[m][v] += next[m-1][v-j]
+				}
+			}
+		}
+
+		counts = next
+	}
+
+	var total float64
+	for v, c := range counts[n1] {
+		total += c
+
+		if float64(v) <= u {
+			lower += c
+		}
+
+		if float64(v) >= u {
+			upper += c
+		}
+	}
+
+	return lower / total, upper / total
+}
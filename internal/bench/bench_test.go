@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const output = `goos: linux
+goarch: amd64
+pkg: example.com/workshop/money
+cpu: Intel(R) Xeon(R) CPU
+BenchmarkInvoice-8   	   10000	    101000 ns/op	   38400 B/op	     400 allocs/op
+BenchmarkInvoice-8   	   10000	    99000 ns/op	   38400 B/op	     400 allocs/op
+BenchmarkParse       	  500000	      2400 ns/op
+--- FAIL: BenchmarkBroken
+    bench_test.go:12: broken
+BenchmarkBroken
+PASS
+ok  	example.com/workshop/money	3.021s
+`
+
+func TestParse(t *testing.T) {
+	results, err := Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if names := results.Names(); !slices.Equal(names, []string{"BenchmarkInvoice", "BenchmarkParse"}) {
+		t.Errorf("Expected benchmarks without the GOMAXPROCS suffix, got %q", names)
+	}
+
+	if got := results.Values("BenchmarkInvoice", SecPerOp); !slices.Equal(got, []float64{101e-6, 99e-6}) {
+		t.Errorf("Expected seconds per operation of both runs, got %v", got)
+	}
+
+	if got := results.Values("BenchmarkInvoice", AllocsPerOp); !slices.Equal(got, []float64{400, 400}) {
+		t.Errorf("Expected allocations of both runs, got %v", got)
+	}
+
+	if got := results.Values("BenchmarkParse", BytesPerOp); !slices.Equal(got, []float64{0}) {
+		t.Errorf("Expected no bytes without -benchmem, got %v", got)
+	}
+
+	if _, err := Parse(strings.NewReader("BenchmarkX-8 100 fast ns/op\n")); err == nil {
+		t.Error("Expected an error for an invalid value")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{5, 1, 4, 2, 3, 100})
+	if s.Center != 3.5 || s.Lo != 1 || s.Hi != 100 || !s.Confident {
+		t.Errorf("Expected median 3.5 within [1, 100], got %+v", s)
+	}
+
+	// 5 values are too few for a 95% interval.
+	if s := Summarize([]float64{1, 2, 3, 4, 5}); s.Confident || s.Center != 3 {
+		t.Errorf("Expected median 3 without a confidence interval, got %+v", s)
+	}
+
+	values := make([]float64, 20)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+
+	// For 20 values the interval is from the 6th smallest to the 6th largest.
+	if s := Summarize(values); s.Lo != 6 || s.Hi != 15 {
+		t.Errorf("Expected interval [6, 15], got %+v", s)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		old  []float64
+		new  []float64
+		p    float64
+	}{
+		{"separated", []float64{10, 11, 12, 13, 14, 15}, []float64{1, 2, 3, 4, 5, 6}, 2.0 / 924},
+		{"few runs", []float64{10, 11, 12}, []float64{1, 2, 3}, 0.1},
+		{"mixed", []float64{1, 3, 5, 7, 9, 11}, []float64{2, 4, 6, 8, 10, 12}, 0.699},
+		{"ties", []float64{400, 400, 400, 400, 400, 400}, []float64{0, 0, 0, 0, 0, 0}, 0.0013},
+		{"same", []float64{1, 1, 1}, []float64{1, 1, 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Compare(tt.old, tt.new)
+			if math.Abs(c.P-tt.p) > 0.001 {
+				t.Errorf("Expected p-value %.4f, got %.4f", tt.p, c.P)
+			}
+		})
+	}
+
+	c := Compare([]float64{10, 11, 12, 13, 14, 15}, []float64{5, 5.5, 6, 6.5, 7, 7.5})
+	if !c.Significant() || c.Delta() != -0.5 {
+		t.Errorf("Expected a significant change by -50%%, got %+v, delta %v", c, c.Delta())
+	}
+
+	c = Compare([]float64{10, 11, 12}, []float64{5, 5.5, 6})
+	if c.Significant() || c.Delta() != 0 {
+		t.Errorf("Expected no significant change with 3 runs, got %+v, delta %v", c, c.Delta())
+	}
+}
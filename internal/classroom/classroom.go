@@ -0,0 +1,137 @@
+// Package classroom collects progress of learners for the instructor.
+//
+// Learners submit results of their exercises with workshop submit, the instructor runs workshop serve -classroom
+// and watches the leaderboard. Submissions are signed with HMAC-SHA256 of the classroom token,
+// so only learners who got the token from the instructor can submit. Every learner shares the token,
+// so a name on the leaderboard belongs to the checkout that submitted it first: submissions carry
+// a random key of the checkout, and a name submitted with another key is rejected.
+package classroom
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the signature of the submission body.
+	SignatureHeader = "X-Workshop-Signature"
+
+	// SubmissionsPath is the endpoint learners submit their progress to.
+	SubmissionsPath = "/api/submissions"
+
+	// MaxClockSkew is how old or how far in the future a submission may be, older submissions are rejected as replayed.
+	MaxClockSkew = 5 * time.Minute
+
+	maxLearnerLen = 64
+	minKeyLen     = 16
+	maxKeyLen     = 128
+	maxBodySize   = 1 << 20
+)
+
+// Submission is the progress of a learner after a run of exercises.
+type Submission struct {
+	Learner string    `json:"learner"`
+	Time    time.Time `json:"time"`
+
+	// Key is a random secret of the learner's checkout, the first submission binds the learner name to it.
+	Key string `json:"key"`
+
+	Exercises []Exercise `json:"exercises"`
+}
+
+// Exercise is the result of an exercise in the submission.
+type Exercise struct {
+	ID     string `json:"id"`
+	Passed bool   `json:"passed"`
+}
+
+// Validate checks that the submission can be accepted.
+func (s *Submission) Validate() error {
+	switch {
+	case strings.TrimSpace(s.Learner) == "":
+		return errors.New("learner name is required")
+	case len(s.Learner) > maxLearnerLen:
+		return fmt.Errorf("learner name is longer than %d characters", maxLearnerLen)
+	case len(s.Key) < minKeyLen || len(s.Key) > maxKeyLen:
+		return fmt.Errorf("learner key must be from %d to %d characters", minKeyLen, maxKeyLen)
+	case len(s.Exercises) == 0:
+		return errors.New("at least one exercise is required")
+	}
+
+	for _, ex := range s.Exercises {
+		if ex.ID == "" {
+			return errors.New("exercise id is required")
+		}
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the body for SignatureHeader.
+func Sign(token string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature of the body was made with the token.
+func Verify(token string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(token, body)), []byte(signature))
+}
+
+// Client submits progress to the classroom server.
+type Client struct {
+	// URL is the base URL of the server, like http://10.0.0.5:8080.
+	URL string
+
+	// Token is the classroom token the instructor shared.
+	Token string
+
+	// HTTPClient is used for requests, http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+}
+
+// Submit sends the signed submission to the server.
+func (c *Client) Submit(ctx context.Context, s Submission) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.URL, "/")+SubmissionsPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to submit: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(c.Token, body))
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to submit: server responded with %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
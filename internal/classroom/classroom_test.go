@@ -0,0 +1,163 @@
+package classroom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"learner":"ann"}`)
+	sig := Sign("secret", body)
+
+	if !strings.HasPrefix(sig, "sha256=") || !Verify("secret", body, sig) {
+		t.Errorf("Expected signature to verify, got %q", sig)
+	}
+
+	if Verify("other", body, sig) {
+		t.Error("Expected signature with another token to be rejected")
+	}
+
+	if Verify("secret", []byte(`{"learner":"bob"}`), sig) {
+		t.Error("Expected signature of another body to be rejected")
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	submissions := []Submission{
+		{Learner: "ann", Key: "ann", Time: start, Exercises: []Exercise{{ID: "m/a", Passed: true}, {ID: "m/b"}}},
+		{Learner: "bob", Key: "bob", Time: start.Add(time.Minute), Exercises: []Exercise{{ID: "m/a", Passed: true}}},
+		{Learner: "cid", Key: "cid", Time: start.Add(2 * time.Minute), Exercises: []Exercise{{ID: "m/a", Passed: true}, {ID: "m/b", Passed: true}}},
+		// A submission of another module keeps results of the previous one, a failed attempt doesn't change when ann reached 1.
+		{Learner: "ann", Key: "ann", Time: start.Add(3 * time.Minute), Exercises: []Exercise{{ID: "n/c"}}},
+	}
+
+	for _, sub := range submissions {
+		if err := s.Add(sub); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Another learner can't submit under the name of ann.
+	forged := Submission{Learner: "ann", Key: "bob", Time: start.Add(4 * time.Minute), Exercises: []Exercise{{ID: "m/a"}}}
+	if err := s.Add(forged); !errors.Is(err, ErrLearnerTaken) {
+		t.Errorf("Expected ErrLearnerTaken for a submission with another key, got %v", err)
+	}
+
+	board := s.Leaderboard()
+
+	expected := []Entry{
+		{Learner: "cid", Passed: 2, Total: 2, UpdatedAt: start.Add(2 * time.Minute), ReachedAt: start.Add(2 * time.Minute)},
+		{Learner: "ann", Passed: 1, Total: 3, UpdatedAt: start.Add(3 * time.Minute), ReachedAt: start},
+		{Learner: "bob", Passed: 1, Total: 1, UpdatedAt: start.Add(time.Minute), ReachedAt: start.Add(time.Minute)},
+	}
+
+	if !slices.Equal(board.Learners, expected) {
+		t.Errorf("Expected learners %+v, got %+v", expected, board.Learners)
+	}
+
+	completion := []Completion{{Exercise: "m/a", Passed: 3, Learners: 3}, {Exercise: "m/b", Passed: 1, Learners: 2}, {Exercise: "n/c", Passed: 0, Learners: 1}}
+	if !slices.Equal(board.Exercises, completion) {
+		t.Errorf("Expected completion %+v, got %+v", completion, board.Exercises)
+	}
+}
+
+func TestServer(t *testing.T) {
+	store := NewStore()
+	srv := NewServer("secret", store)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	sub := Submission{Learner: "ann <script>", Key: "0123456789abcdef", Time: time.Now(), Exercises: []Exercise{{ID: "m/a", Passed: true}}}
+
+	if err := (&Client{URL: ts.URL + "/", Token: "secret"}).Submit(context.Background(), sub); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	forged := sub
+	forged.Key = "fedcba9876543210"
+	forged.Exercises = []Exercise{{ID: "m/a"}}
+
+	if err := (&Client{URL: ts.URL, Token: "secret"}).Submit(context.Background(), forged); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("Expected submission under the name of another learner to be rejected, got %v", err)
+	}
+
+	keyless := sub
+	keyless.Key = ""
+
+	if err := (&Client{URL: ts.URL, Token: "secret"}).Submit(context.Background(), keyless); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected submission without a key to be rejected, got %v", err)
+	}
+
+	if err := (&Client{URL: ts.URL, Token: "wrong"}).Submit(context.Background(), sub); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected submission with a wrong token to be rejected, got %v", err)
+	}
+
+	stale := sub
+	stale.Time = time.Now().Add(-time.Hour)
+
+	if err := (&Client{URL: ts.URL, Token: "secret"}).Submit(context.Background(), stale); err == nil || !strings.Contains(err.Error(), "too old") {
+		t.Errorf("Expected replayed submission to be rejected, got %v", err)
+	}
+
+	invalid := sub
+	invalid.Learner = ""
+
+	if err := (&Client{URL: ts.URL, Token: "secret"}).Submit(context.Background(), invalid); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected submission without a learner to be rejected, got %v", err)
+	}
+
+	body := bytes.Repeat([]byte("a"), maxBodySize+1)
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+SubmissionsPath, bytes.NewReader(body))
+	req.Header.Set(SignatureHeader, Sign("secret", body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected large submission to be rejected, got %s", resp.Status)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/leaderboard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var board Leaderboard
+	if err := json.NewDecoder(resp.Body).Decode(&board); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(board.Learners) != 1 || board.Learners[0].Learner != "ann <script>" || board.Learners[0].Passed != 1 {
+		t.Errorf("Expected only the valid submission on the leaderboard, got %+v", board.Learners)
+	}
+
+	resp, err = http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	page, _ := io.ReadAll(resp.Body)
+
+	for _, s := range []string{"<td>ann &lt;script&gt;</td>", "<td>1/1</td>", "<td>m/a</td>"} {
+		if !strings.Contains(string(page), s) {
+			t.Errorf("Expected leaderboard page to contain %q, got:\n%s", s, page)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package classroom
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+)
+
+var leaderboardPage = template.Must(template.New("leaderboard").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>Workshop leaderboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.3em 1em; text-align: left; border-bottom: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>Leaderboard</h1>
+{{if .Learners}}
+<table>
+<tr><th>#</th><th>Learner</th><th>Passed</th><th>Last submission</th></tr>
+{{range $i, $e := .Learners}}<tr><td>{{inc $i}}</td><td>{{$e.Learner}}</td><td>{{$e.Passed}}/{{$e.Total}}</td><td>{{$e.UpdatedAt.Format "15:04:05"}}</td></tr>
+{{end}}</table>
+<h2>Exercises</h2>
+<table>
+<tr><th>Exercise</th><th>Passed</th></tr>
+{{range .Exercises}}<tr><td>{{.Exercise}}</td><td>{{.Passed}}/{{.Learners}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>No submissions yet. Learners submit their progress with <code>workshop submit</code>.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// Server accepts submissions and shows the leaderboard.
+type Server struct {
+	token string
+	store *Store
+	now   func() time.Time
+}
+
+// NewServer creates a server that accepts submissions signed with the token.
+func NewServer(token string, store *Store) *Server {
+	return &Server{token: token, store: store, now: time.Now}
+}
+
+// Handler returns routes of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+SubmissionsPath, s.submit)
+	mux.HandleFunc("GET /api/leaderboard", s.leaderboardJSON)
+	mux.HandleFunc("GET /{$}", s.leaderboardHTML)
+
+	return mux
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read submission", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !Verify(s.token, body, r.Header.Get(SignatureHeader)) {
+		http.Error(w, "invalid signature, check the classroom token", http.StatusUnauthorized)
+		return
+	}
+
+	var sub Submission
+	if err := json.Unmarshal(body, &sub); err != nil {
+		http.Error(w, "invalid submission", http.StatusBadRequest)
+		return
+	}
+
+	if err := sub.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A signed body can be sent again by anyone who captured it, the time limits how long it's accepted.
+	if age := s.now().Sub(sub.Time).Abs(); age > MaxClockSkew {
+		http.Error(w, "submission is too old, check the clock", http.StatusUnauthorized)
+		return
+	}
+
+	if err := s.store.Add(sub); errors.Is(err, ErrLearnerTaken) {
+		http.Error(w, err.Error()+", submit under another name", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to add submission", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) leaderboardJSON(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.store.Leaderboard())
+}
+
+func (s *Server) leaderboardHTML(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := leaderboardPage.Execute(w, s.store.Leaderboard()); err != nil {
+		http.Error(w, "failed to render leaderboard", http.StatusInternalServerError)
+	}
+}
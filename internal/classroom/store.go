@@ -0,0 +1,156 @@
+package classroom
+
+import (
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a learner on the leaderboard.
+type Entry struct {
+	Learner string `json:"learner"`
+	Passed  int    `json:"passed"`
+	Total   int    `json:"total"`
+
+	// UpdatedAt is the time of the last submission.
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ReachedAt is when the learner first passed the current number of exercises, it breaks ties.
+	ReachedAt time.Time `json:"reached_at"`
+}
+
+// Completion is the number of learners who passed an exercise.
+type Completion struct {
+	Exercise string `json:"exercise"`
+	Passed   int    `json:"passed"`
+	Learners int    `json:"learners"`
+}
+
+// Leaderboard is the aggregated progress of the classroom.
+type Leaderboard struct {
+	Learners  []Entry      `json:"learners"`
+	Exercises []Completion `json:"exercises"`
+}
+
+// ErrLearnerTaken is returned for a submission under the name of a learner who submitted with another key.
+var ErrLearnerTaken = errors.New("learner name is taken by another checkout")
+
+// learner is the progress of a learner merged from all submissions.
+type learner struct {
+	name      string
+	key       string
+	exercises map[string]bool
+	updatedAt time.Time
+	reachedAt time.Time
+}
+
+func (l *learner) passed() int {
+	n := 0
+
+	for _, passed := range l.exercises {
+		if passed {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Store keeps submissions in memory, the leaderboard is lost when the server stops.
+type Store struct {
+	mu       sync.Mutex
+	learners map[string]*learner
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{learners: make(map[string]*learner)}
+}
+
+// Add merges the submission into the progress of the learner.
+// Learners may submit modules separately, the latest result of each exercise wins.
+// The first submission binds the name to its key, submissions with another key fail with ErrLearnerTaken.
+func (s *Store) Add(sub Submission) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.learners[sub.Learner]
+	if !ok {
+		l = &learner{name: sub.Learner, key: sub.Key, exercises: make(map[string]bool)}
+		s.learners[sub.Learner] = l
+	} else if subtle.ConstantTimeCompare([]byte(l.key), []byte(sub.Key)) != 1 {
+		return ErrLearnerTaken
+	}
+
+	before := l.passed()
+
+	for _, ex := range sub.Exercises {
+		l.exercises[ex.ID] = ex.Passed
+	}
+
+	l.updatedAt = sub.Time
+
+	if l.passed() != before || !ok {
+		l.reachedAt = sub.Time
+	}
+
+	return nil
+}
+
+// Leaderboard returns learners ordered by the number of passed exercises, and completion of every exercise.
+func (s *Store) Leaderboard() Leaderboard {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var board Leaderboard
+
+	completion := make(map[string]*Completion)
+
+	for _, l := range s.learners {
+		board.Learners = append(board.Learners, Entry{
+			Learner:   l.name,
+			Passed:    l.passed(),
+			Total:     len(l.exercises),
+			UpdatedAt: l.updatedAt,
+			ReachedAt: l.reachedAt,
+		})
+
+		for id, passed := range l.exercises {
+			c, ok := completion[id]
+			if !ok {
+				c = &Completion{Exercise: id}
+				completion[id] = c
+			}
+
+			c.Learners++
+
+			if passed {
+				c.Passed++
+			}
+		}
+	}
+
+	slices.SortFunc(board.Learners, func(a, b Entry) int {
+		switch {
+		case a.Passed != b.Passed:
+			return b.Passed - a.Passed
+		case !a.ReachedAt.Equal(b.ReachedAt):
+			return a.ReachedAt.Compare(b.ReachedAt)
+		default:
+			return strings.Compare(a.Learner, b.Learner)
+		}
+	})
+
+	for _, c := range completion {
+		board.Exercises = append(board.Exercises, *c)
+	}
+
+	slices.SortFunc(board.Exercises, func(a, b Completion) int {
+		return strings.Compare(a.Exercise, b.Exercise)
+	})
+
+	return board
+}
@@ -0,0 +1,92 @@
+package content
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+// FromComments returns comments that explain the exercise in its test files.
+// Workshops describe an exercise in comments above its stubs and tests, so for each test of the exercise
+// it collects top level comments between the previous test function in the file and the test itself.
+// Comments inside function bodies, like expected output of examples, are skipped.
+func FromComments(dir string, t manifest.Target) (string, error) {
+	fset := token.NewFileSet()
+
+	files, err := filepath.Glob(filepath.Join(dir, t.Module.Path, "*_test.go"))
+	if err != nil {
+		return "", err
+	}
+
+	var docs []string
+
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		start := file.Name.End()
+		if len(file.Imports) > 0 {
+			start = file.Imports[len(file.Imports)-1].End()
+		}
+
+		var bodies []*ast.BlockStmt
+
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+
+			if fn.Body != nil {
+				bodies = append(bodies, fn.Body)
+			}
+
+			if !isTestFunc(fn.Name.Name) {
+				continue
+			}
+
+			if slices.Contains(t.Exercise.Tests, fn.Name.Name) {
+				for _, group := range file.Comments {
+					if group.Pos() < start || group.End() > fn.Pos() || insideAny(group, bodies) {
+						continue
+					}
+
+					docs = append(docs, strings.TrimSpace(group.Text()))
+				}
+			}
+
+			start = fn.End()
+		}
+	}
+
+	return strings.Join(slices.Compact(docs), "\n\n"), nil
+}
+
+// isTestFunc reports whether the function is run by go test.
+func isTestFunc(name string) bool {
+	for _, prefix := range []string{"Test", "Example", "Benchmark", "Fuzz"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func insideAny(node ast.Node, blocks []*ast.BlockStmt) bool {
+	for _, b := range blocks {
+		if node.Pos() >= b.Pos() && node.End() <= b.End() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+// Package content provides texts that explain exercises, in the language of the learner.
+//
+// The original English text of an exercise is the comments of its test files, next to the code they explain.
+// Translations are Markdown files in the content directory of a module, one per exercise and language:
+//
+//	initorder/content/ru/init-order.md
+//
+// The first "# " heading of a file is the title of the exercise. Tests don't depend on narratives,
+// so a translation changes only what the runner shows. An English Markdown file takes precedence over comments,
+// but workshops keep English in comments, so the text has a single source that translations follow.
+//
+// So far only initorder/init-order and initorder/global-state are translated, to Russian.
+package content
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+const (
+	// DefaultLang is the language of the original narratives, used when there is no translation.
+	DefaultLang = "en"
+
+	// DirName is the name of the directory with narratives in a module.
+	DirName = "content"
+
+	// SourceComments is the source of narratives taken from comments of test files.
+	SourceComments = "comments"
+)
+
+// Narrative is the text that explains an exercise.
+type Narrative struct {
+	// Lang is the language of the text, it differs from the requested one when there is no translation.
+	Lang string
+
+	Title string
+	Body  string
+
+	// Source is the path of the Markdown file relative to the manifest directory, or SourceComments.
+	Source string
+}
+
+// Load returns the narrative of the exercise in the language, falling back to DefaultLang
+// and then to comments of the test files. dir is the directory of the manifest.
+func Load(dir string, t manifest.Target, lang string) (Narrative, error) {
+	langs := []string{DefaultLang}
+	if lang = ParseLang(lang); lang != "" && lang != DefaultLang {
+		langs = []string{lang, DefaultLang}
+	}
+
+	for _, l := range langs {
+		path := filepath.Join(t.Module.Path, DirName, l, t.Exercise.Name+".md")
+
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return Narrative{}, fmt.Errorf("failed to read narrative of %s: %w", t.ID(), err)
+		}
+
+		title, body := Parse(string(data))
+
+		return Narrative{Lang: l, Title: title, Body: body, Source: filepath.ToSlash(path)}, nil
+	}
+
+	body, err := FromComments(dir, t)
+	if err != nil {
+		return Narrative{}, err
+	}
+
+	return Narrative{Lang: DefaultLang, Title: t.ID(), Body: body, Source: SourceComments}, nil
+}
+
+// Parse splits a Markdown narrative into the title from the first "# " heading and the rest of the text.
+func Parse(text string) (title, body string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	offset := 0
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if heading, ok := strings.CutPrefix(line, "# "); ok {
+			return strings.TrimSpace(heading), strings.TrimSpace(text[offset+len(line):])
+		}
+
+		offset += len(line)
+	}
+
+	return "", strings.TrimSpace(text)
+}
+
+// ParseLang normalizes a language tag or a locale, like "ru", "ru-RU", or "ru_RU.UTF-8", to the language code.
+func ParseLang(s string) string {
+	s, _, _ = strings.Cut(s, ".")
+	s, _, _ = strings.Cut(s, "_")
+	s, _, _ = strings.Cut(s, "-")
+
+	return strings.ToLower(strings.TrimSpace(s))
+}
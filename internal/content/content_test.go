@@ -0,0 +1,189 @@
+package content
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+const testFile = `package mod
+
+import "testing"
+
+// 1. First exercise.
+// Explains the first exercise.
+
+// First is a stub.
+func First() int {
+	// TODO: not a part of the doc
+	return 0
+}
+
+// TestFirst checks First.
+func TestFirst(t *testing.T) {}
+
+// 2. Second exercise.
+
+func TestSecond(t *testing.T) {}
+
+func ExampleSecond() {
+	// Output:
+}
+`
+
+func testTargets(t *testing.T, files map[string]string) (string, []manifest.Target) {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["mod/mod_test.go"] = testFile
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &manifest.Manifest{
+		Dir: dir,
+		Modules: []manifest.Module{{
+			Name: "mod",
+			Path: "./mod",
+			Exercises: []manifest.Exercise{
+				{Name: "first", Tests: []string{"TestFirst"}},
+				{Name: "second", Tests: []string{"TestSecond", "ExampleSecond"}},
+			},
+		}},
+	}
+
+	targets, err := m.Select()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, targets
+}
+
+func TestLoad(t *testing.T) {
+	dir, targets := testTargets(t, map[string]string{
+		"mod/content/en/first.md": "# First\n\nFix the stub.\n",
+		"mod/content/ru/first.md": "# Первое\n\nИсправьте заглушку.\n",
+	})
+
+	tests := []struct {
+		name     string
+		target   manifest.Target
+		lang     string
+		expected Narrative
+	}{
+		{"default", targets[0], "", Narrative{Lang: "en", Title: "First", Body: "Fix the stub.", Source: "mod/content/en/first.md"}},
+		{"translation", targets[0], "ru_RU.UTF-8", Narrative{Lang: "ru", Title: "Первое", Body: "Исправьте заглушку.", Source: "mod/content/ru/first.md"}},
+		{"missing translation", targets[0], "de", Narrative{Lang: "en", Title: "First", Body: "Fix the stub.", Source: "mod/content/en/first.md"}},
+		{"comments", targets[1], "ru", Narrative{Lang: "en", Title: "mod/second", Body: "2. Second exercise.", Source: SourceComments}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Load(dir, tt.target, tt.lang)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if n != tt.expected {
+				t.Errorf("Expected narrative %+v, got %+v", tt.expected, n)
+			}
+		})
+	}
+}
+
+func TestFromComments(t *testing.T) {
+	dir, targets := testTargets(t, map[string]string{})
+
+	doc, err := FromComments(dir, targets[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "1. First exercise.\nExplains the first exercise.\n\nFirst is a stub.\n\nTestFirst checks First."
+	if doc != expected {
+		t.Errorf("Expected doc %q, got %q", expected, doc)
+	}
+}
+
+func TestParse(t *testing.T) {
+	title, body := Parse("<!-- intro -->\r\n# Title\r\n\r\nText with # inside.\r\n\r\n## Section\r\n")
+	if title != "Title" || body != "Text with # inside.\n\n## Section" {
+		t.Errorf("Expected title and body, got %q and %q", title, body)
+	}
+
+	if title, body := Parse("Just text\n"); title != "" || body != "Just text" {
+		t.Errorf("Expected text without a title, got %q and %q", title, body)
+	}
+}
+
+func TestParseLang(t *testing.T) {
+	for in, expected := range map[string]string{"ru": "ru", "ru-RU": "ru", "ru_RU.UTF-8": "ru", " EN ": "en", "": ""} {
+		if lang := ParseLang(in); lang != expected {
+			t.Errorf("Expected %q to be parsed as %q, got %q", in, expected, lang)
+		}
+	}
+}
+
+// TestRepositoryContent checks that narratives of the repository belong to exercises of the manifest,
+// and that every translation has the original, a narrative in DefaultLang or comments of the tests.
+func TestRepositoryContent(t *testing.T) {
+	path, err := manifest.Find(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := manifest.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mod := range m.Modules {
+		files, err := filepath.Glob(filepath.Join(m.Dir, mod.Path, DirName, "*", "*.md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), ".md")
+			lang := filepath.Base(filepath.Dir(file))
+
+			if mod.Exercise(name) == nil {
+				t.Errorf("%s: exercise %s/%s is not in the manifest", file, mod.Name, name)
+			}
+
+			if lang != ParseLang(lang) {
+				t.Errorf("%s: directory should be named by the language code, like %s", file, ParseLang(lang))
+			}
+
+			if ex := mod.Exercise(name); ex != nil && lang != DefaultLang {
+				if _, err := os.Stat(filepath.Join(filepath.Dir(file), "..", DefaultLang, name+".md")); err != nil {
+					// The original is usually the comments of the exercise tests.
+					if text, err := FromComments(m.Dir, manifest.Target{Module: &mod, Exercise: ex}); err != nil || text == "" {
+						t.Errorf("%s: translation without the original in %s or in comments of the tests", file, DefaultLang)
+					}
+				}
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if title, body := Parse(string(data)); title == "" || body == "" {
+				t.Errorf("%s: narrative should start with a # title followed by text", file)
+			}
+		}
+	}
+}
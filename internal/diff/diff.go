@@ -0,0 +1,213 @@
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around every change.
+const context = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff between old and new text, it returns an empty string if texts are equal.
+func Unified(oldName, newName, old, new string) string {
+	if old == new {
+		return ""
+	}
+
+	ops := edits(lines(old), lines(new))
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+
+	for _, h := range hunks(ops) {
+		b.WriteString(h)
+	}
+
+	return b.String()
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// lcsTable returns a table where lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+func lcsTable(a, b []string) [][]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	return lcs
+}
+
+// edits finds the shortest edit script with the longest common subsequence of lines.
+func edits(a, b []string) []op {
+	lcs := lcsTable(a, b)
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+
+	return ops
+}
+
+// matches returns for every line of a the index of the matching line in b, or -1 if the line was removed.
+func matches(a, b []string) []int {
+	lcs := lcsTable(a, b)
+	match := make([]int, len(a))
+	i, j := 0, 0
+
+	for i < len(a) {
+		switch {
+		case j < len(b) && a[i] == b[j]:
+			match[i] = j
+			i++
+			j++
+		case j >= len(b) || lcs[i+1][j] >= lcs[i][j+1]:
+			match[i] = -1
+			i++
+		default:
+			j++
+		}
+	}
+
+	return match
+}
+
+// hunks groups changes with surrounding context into unified diff hunks.
+func hunks(ops []op) []string {
+	var result []string
+
+	oldLine, newLine := 1, 1
+
+	for start := 0; start < len(ops); {
+		if ops[start].kind == opEqual {
+			oldLine++
+			newLine++
+			start++
+
+			continue
+		}
+
+		// Extend the hunk while changes are separated by no more than 2*context equal lines.
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != opEqual {
+				end = k + 1
+				continue
+			}
+
+			if k-end >= 2*context {
+				break
+			}
+		}
+
+		from := max(0, start-context)
+		to := min(len(ops), end+context)
+
+		var body strings.Builder
+
+		oldStart, newStart := oldLine-(start-from), newLine-(start-from)
+		oldCount, newCount := 0, 0
+
+		for _, o := range ops[from:to] {
+			body.WriteByte(byte(o.kind))
+			body.WriteString(o.line)
+
+			if !strings.HasSuffix(o.line, "\n") {
+				body.WriteString("\n\\ No newline at end of file\n")
+			}
+
+			if o.kind != opInsert {
+				oldCount++
+			}
+
+			if o.kind != opDelete {
+				newCount++
+			}
+		}
+
+		result = append(result, fmt.Sprintf("@@ -%s +%s @@\n%s", span(oldStart, oldCount), span(newStart, newCount), body.String()))
+
+		for _, o := range ops[start:to] {
+			if o.kind != opInsert {
+				oldLine++
+			}
+
+			if o.kind != opDelete {
+				newLine++
+			}
+		}
+
+		start = to
+	}
+
+	return result
+}
+
+func span(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
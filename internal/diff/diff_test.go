@@ -0,0 +1,102 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{
+			name:     "equal",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "change in the middle",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:  "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: `--- old
++++ new
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\nb\n",
+			expected: `--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expected: `--- old
++++ new
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`,
+		},
+		{
+			name: "missing newline",
+			old:  "a\nb",
+			new:  "a\nc",
+			expected: `--- old
++++ new
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("old", "new", tt.old, tt.new)
+			if got != tt.expected {
+				t.Errorf("Expected diff:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnifiedMergesCloseChanges(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n"
+	new := "one\n2\n3\n4\n5\n6\n7\neight\n"
+
+	got := Unified("old", "new", old, new)
+	if strings.Count(got, "@@ -") != 1 {
+		t.Errorf("Expected changes separated by 6 lines to be in a single hunk, got:\n%s", got)
+	}
+}
@@ -0,0 +1,83 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers used by Merge3, the same as git uses.
+const (
+	markerOurs   = "<<<<<<< "
+	markerBase   = "=======\n"
+	markerTheirs = ">>>>>>> "
+)
+
+// Merge3 merges changes made to base in ours and theirs, like git merge-file does.
+// Chunks changed only on one side are taken from that side, chunks changed on both sides in different ways
+// are written with conflict markers labeled with oursName and theirsName.
+// It returns the merged text and the number of conflicts.
+func Merge3(base, ours, theirs, oursName, theirsName string) (string, int) {
+	b, o, t := lines(base), lines(ours), lines(theirs)
+	toOurs, toTheirs := matches(b, o), matches(b, t)
+
+	var out strings.Builder
+
+	conflicts := 0
+	bi, oi, ti := 0, 0, 0
+
+	for {
+		// Find the next base line that is kept unchanged in both versions, it's a stable point to sync on.
+		next := bi
+		for next < len(b) && (toOurs[next] < oi || toTheirs[next] < ti) {
+			next++
+		}
+
+		bEnd, oEnd, tEnd := len(b), len(o), len(t)
+		if next < len(b) {
+			bEnd, oEnd, tEnd = next, toOurs[next], toTheirs[next]
+		}
+
+		baseChunk, oursChunk, theirsChunk := b[bi:bEnd], o[oi:oEnd], t[ti:tEnd]
+
+		switch {
+		case slices.Equal(oursChunk, baseChunk):
+			writeLines(&out, theirsChunk)
+		case slices.Equal(theirsChunk, baseChunk), slices.Equal(oursChunk, theirsChunk):
+			writeLines(&out, oursChunk)
+		default:
+			conflicts++
+
+			out.WriteString(markerOurs + oursName + "\n")
+			writeSide(&out, oursChunk)
+			out.WriteString(markerBase)
+			writeSide(&out, theirsChunk)
+			out.WriteString(markerTheirs + theirsName + "\n")
+		}
+
+		if next >= len(b) {
+			break
+		}
+
+		out.WriteString(b[next])
+
+		bi, oi, ti = next+1, oEnd+1, tEnd+1
+	}
+
+	return out.String(), conflicts
+}
+
+// writeLines writes lines as they are, a missing new line at the end of the file stays missing.
+func writeLines(out *strings.Builder, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+	}
+}
+
+// writeSide writes one side of a conflict and makes sure it ends with a new line, so conflict markers start on their own line.
+func writeSide(out *strings.Builder, lines []string) {
+	writeLines(out, lines)
+
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
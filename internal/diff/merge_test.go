@@ -0,0 +1,79 @@
+package diff
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "package a\n\n// TODO: implement\nfunc A() {}\n\nfunc B() {}\n"
+
+	tests := []struct {
+		name      string
+		ours      string
+		theirs    string
+		expected  string
+		conflicts int
+	}{
+		{
+			name:     "only ours changed",
+			ours:     "package a\n\n// TODO: implement\nfunc A() { println() }\n\nfunc B() {}\n",
+			theirs:   base,
+			expected: "package a\n\n// TODO: implement\nfunc A() { println() }\n\nfunc B() {}\n",
+		},
+		{
+			name:     "only theirs changed",
+			ours:     base,
+			theirs:   "package a\n\n// TODO: implement A\nfunc A() {}\n\nfunc B() {}\n",
+			expected: "package a\n\n// TODO: implement A\nfunc A() {}\n\nfunc B() {}\n",
+		},
+		{
+			name:     "both changed different chunks",
+			ours:     "package a\n\n// TODO: implement\nfunc A() { println() }\n\nfunc B() {}\n",
+			theirs:   "package a\n\n// TODO: implement\nfunc A() {}\n\nfunc B() {}\n\nfunc C() {}\n",
+			expected: "package a\n\n// TODO: implement\nfunc A() { println() }\n\nfunc B() {}\n\nfunc C() {}\n",
+		},
+		{
+			name:     "both changed the same way",
+			ours:     "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+			theirs:   "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+			expected: "package a\n\nfunc A() {}\n\nfunc B() {}\n",
+		},
+		{
+			name:      "conflict",
+			ours:      "package a\n\n// TODO: implement\nfunc A() { println(1) }\n\nfunc B() {}\n",
+			theirs:    "package a\n\n// TODO: implement\nfunc A(x int) {}\n\nfunc B() {}\n",
+			expected:  "package a\n\n// TODO: implement\n<<<<<<< yours\nfunc A() { println(1) }\n=======\nfunc A(x int) {}\n>>>>>>> update\n\nfunc B() {}\n",
+			conflicts: 1,
+		},
+		{
+			name:     "no new line at the end of file",
+			ours:     "package a\n\n// TODO: implement\nfunc A() { println() }\n\nfunc B() {}\n",
+			theirs:   "package a\n\n// TODO: implement\nfunc A() {}\n\nfunc B() {}",
+			expected: "package a\n\n// TODO: implement\nfunc A() { println() }\n\nfunc B() {}",
+		},
+		{
+			name:      "added on both sides without base",
+			ours:      "a\n",
+			theirs:    "b\n",
+			expected:  "<<<<<<< yours\na\n=======\nb\n>>>>>>> update\n",
+			conflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := base
+			if tt.name == "added on both sides without base" {
+				b = ""
+			}
+
+			merged, conflicts := Merge3(b, tt.ours, tt.theirs, "yours", "update")
+
+			if merged != tt.expected {
+				t.Errorf("Expected merged text:\n%s\ngot:\n%s", tt.expected, merged)
+			}
+
+			if conflicts != tt.conflicts {
+				t.Errorf("Expected %d conflicts, got %d", tt.conflicts, conflicts)
+			}
+		})
+	}
+}
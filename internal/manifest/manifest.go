@@ -0,0 +1,474 @@
+// Package manifest describes workshop modules and their exercises.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// FileName is the name of the manifest file in the root of the repository.
+	FileName = "workshop.json"
+
+	// ProgressDirName is the name of the directory where the runner keeps learner's progress and sessions.
+	ProgressDirName = ".workshop"
+)
+
+// Manifest lists all workshop modules.
+type Manifest struct {
+	Modules []Module `json:"modules"`
+
+	// UpdateURL is the release endpoint checked by workshop update.
+	UpdateURL string `json:"update_url,omitempty"`
+
+	// Dir is the directory containing the manifest file, module paths are relative to it.
+	Dir string `json:"-"`
+}
+
+// Module is a workshop module, a Go package with exercises.
+type Module struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Path  string `json:"path"`
+
+	// Vet is passed to go test -vet flag, e.g. "off" for modules where examples intentionally don't compile cleanly.
+	Vet string `json:"vet,omitempty"`
+
+	// Cgo marks modules that need cgo and a C compiler, the runner skips them when cgo is disabled.
+	Cgo bool `json:"cgo,omitempty"`
+
+	Exercises []Exercise `json:"exercises"`
+}
+
+// Exercise is a group of tests that learners make pass together.
+type Exercise struct {
+	Name  string   `json:"name"`
+	Tests []string `json:"tests"`
+
+	// Race enables the race detector for the exercise, some bugs are invisible without it.
+	Race bool `json:"race,omitempty"`
+
+	// Hidden are stricter tests for instructors, they are built only with the solutiontests tag
+	// and run by workshop verify -strict along with Tests.
+	Hidden []string `json:"hidden,omitempty"`
+
+	// Timeout limits the run of the exercise tests, like "30s", the runner default is used when empty.
+	// Exercises that hang until solved, like deadlocks, fail fast with it.
+	Timeout string `json:"timeout,omitempty"`
+
+	// Level is the difficulty of the exercise, LevelIntermediate when empty.
+	Level Level `json:"level,omitempty"`
+
+	// Coverage is the minimum statement coverage in percent the tests of the exercise must reach,
+	// for exercises where learners write tests. Below it the exercise fails even when its tests pass.
+	Coverage float64 `json:"coverage,omitempty"`
+
+	// CoverFiles limit the coverage to non-test files of the module with these names, all of them count when empty.
+	CoverFiles []string `json:"cover_files,omitempty"`
+
+	// Count runs the tests this many times in one process, once when zero. Tests that leave state behind
+	// for the next run, like package-level variables or files, fail on a repeated run.
+	Count int `json:"count,omitempty"`
+
+	// Parallel is the number of tests marked with t.Parallel that run at once, GOMAXPROCS when zero.
+	Parallel int `json:"parallel,omitempty"`
+
+	// Shuffle runs the tests in random order, so they can't depend on side effects of each other.
+	Shuffle bool `json:"shuffle,omitempty"`
+
+	// Bench makes the exercise an optimization one, workshop bench checks its benchmarks against a baseline.
+	Bench *Bench `json:"bench,omitempty"`
+}
+
+// Bench is the improvement an optimization exercise requires, compared with a baseline recorded by workshop bench
+// before the code was optimized. Only significant differences count, noise doesn't.
+type Bench struct {
+	// Benchmarks are names of benchmark functions of the module the improvement is required of.
+	Benchmarks []string `json:"benchmarks"`
+
+	// Speedup is how many times faster than the baseline the benchmarks must run, like 3.
+	Speedup float64 `json:"speedup,omitempty"`
+
+	// AllocReduction is the share of allocations per operation of the baseline in percent the benchmarks
+	// must get rid of, 100 for code that doesn't allocate.
+	AllocReduction float64 `json:"alloc_reduction,omitempty"`
+}
+
+// Level is the difficulty of an exercise. A track of a level includes exercises of the level and easier ones.
+type Level string
+
+const (
+	LevelBeginner     Level = "beginner"
+	LevelIntermediate Level = "intermediate"
+	LevelAdvanced     Level = "advanced"
+
+	// DefaultTrack is the track of learners who didn't choose one, advanced exercises are opt-in.
+	DefaultTrack = LevelIntermediate
+)
+
+// Levels lists difficulty levels from the easiest one.
+var Levels = []Level{LevelBeginner, LevelIntermediate, LevelAdvanced}
+
+// ParseLevel returns the level with the name, or an error listing known levels.
+func ParseLevel(s string) (Level, error) {
+	if l := Level(strings.ToLower(strings.TrimSpace(s))); slices.Contains(Levels, l) {
+		return l, nil
+	}
+
+	return "", fmt.Errorf("unknown level %q, use one of %s, %s, %s", s, LevelBeginner, LevelIntermediate, LevelAdvanced)
+}
+
+// Includes reports whether the track of the level includes exercises of the other level.
+func (l Level) Includes(other Level) bool {
+	return slices.Index(Levels, other) <= slices.Index(Levels, l)
+}
+
+// Difficulty returns the level of the exercise.
+func (e *Exercise) Difficulty() Level {
+	if e.Level == "" {
+		return LevelIntermediate
+	}
+
+	return e.Level
+}
+
+// TimeoutDuration returns the parsed Timeout, or zero when it's not set.
+func (e *Exercise) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(e.Timeout)
+	if err != nil {
+		return 0
+	}
+
+	return d
+}
+
+// Target is an exercise selected for execution.
+type Target struct {
+	Module   *Module
+	Exercise *Exercise
+}
+
+// ID returns the identifier of the target in the form module/exercise.
+func (t Target) ID() string {
+	return t.Module.Name + "/" + t.Exercise.Name
+}
+
+// Find looks for the manifest file in dir and its parents and returns the path to it.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %s: %w", dir, err)
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found, run workshop from the repository directory", FileName)
+		}
+
+		dir = parent
+	}
+}
+
+// Load reads and validates the manifest file.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+
+	m.Dir = filepath.Dir(path)
+
+	return &m, nil
+}
+
+// Format encodes the manifest in the layout of the repository manifest, with an exercise per line,
+// so adding a module by a tool produces a small diff.
+func Format(m *Manifest) ([]byte, error) {
+	var modules bytes.Buffer
+
+	modules.WriteString("[")
+
+	for i, mod := range m.Modules {
+		if i > 0 {
+			modules.WriteString(",")
+		}
+
+		exercises := mod.Exercises
+		mod.Exercises = nil
+
+		head, err := encode(mod, "    ")
+		if err != nil {
+			return nil, err
+		}
+
+		modules.WriteString("\n    ")
+		modules.Write(bytes.TrimSuffix(head, []byte("null\n    }")))
+		modules.WriteString("[")
+
+		for j, ex := range exercises {
+			line, err := json.Marshal(ex)
+			if err != nil {
+				return nil, err
+			}
+
+			if j > 0 {
+				modules.WriteString(",")
+			}
+
+			modules.WriteString("\n        ")
+			modules.Write(spaceOut(line))
+		}
+
+		modules.WriteString("\n      ]\n    }")
+	}
+
+	modules.WriteString("\n  ]")
+
+	rest := *m
+	rest.Modules = nil
+
+	data, err := encode(rest, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bytes.Replace(data, []byte(`"modules": null`), []byte(`"modules": `+modules.String()), 1), '\n'), nil
+}
+
+// encode marshals v with two spaces indentation and without escaping HTML characters.
+func encode(v any, prefix string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(prefix, "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// spaceOut adds a space after commas and colons of compact JSON, outside of strings.
+func spaceOut(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/4)
+	inString, escaped := false, false
+
+	for _, c := range data {
+		out = append(out, c)
+
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && (c == ',' || c == ':'):
+			out = append(out, ' ')
+		}
+	}
+
+	return out
+}
+
+// Save writes the manifest to the file in the layout of Format.
+func Save(path string, m *Manifest) error {
+	data, err := Format(m)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Validate checks that all modules and exercises are properly described.
+// It returns all found problems joined in a single error.
+func (m *Manifest) Validate() error {
+	var errs []error
+
+	modules := make(map[string]bool)
+
+	for _, mod := range m.Modules {
+		switch {
+		case mod.Name == "":
+			errs = append(errs, errors.New("module name is required"))
+			continue
+		case modules[mod.Name]:
+			errs = append(errs, fmt.Errorf("duplicate module %s", mod.Name))
+		case mod.Path == "":
+			errs = append(errs, fmt.Errorf("module %s: path is required", mod.Name))
+		}
+
+		modules[mod.Name] = true
+		exercises := make(map[string]bool)
+
+		for _, ex := range mod.Exercises {
+			switch {
+			case ex.Name == "":
+				errs = append(errs, fmt.Errorf("module %s: exercise name is required", mod.Name))
+			case exercises[ex.Name]:
+				errs = append(errs, fmt.Errorf("module %s: duplicate exercise %s", mod.Name, ex.Name))
+			case len(ex.Tests) == 0:
+				errs = append(errs, fmt.Errorf("exercise %s/%s: at least one test is required", mod.Name, ex.Name))
+			case ex.Timeout != "" && ex.TimeoutDuration() <= 0:
+				errs = append(errs, fmt.Errorf("exercise %s/%s: invalid timeout %q", mod.Name, ex.Name, ex.Timeout))
+			case ex.Level != "" && !slices.Contains(Levels, ex.Level):
+				errs = append(errs, fmt.Errorf("exercise %s/%s: unknown level %q", mod.Name, ex.Name, ex.Level))
+			case ex.Coverage < 0 || ex.Coverage > 100:
+				errs = append(errs, fmt.Errorf("exercise %s/%s: coverage %g is not a percentage", mod.Name, ex.Name, ex.Coverage))
+			case len(ex.CoverFiles) > 0 && ex.Coverage == 0:
+				errs = append(errs, fmt.Errorf("exercise %s/%s: cover_files are set without coverage", mod.Name, ex.Name))
+			case ex.Count < 0:
+				errs = append(errs, fmt.Errorf("exercise %s/%s: negative count %d", mod.Name, ex.Name, ex.Count))
+			case ex.Parallel < 0:
+				errs = append(errs, fmt.Errorf("exercise %s/%s: negative parallel %d", mod.Name, ex.Name, ex.Parallel))
+			case ex.Bench != nil:
+				if err := ex.Bench.validate(); err != nil {
+					errs = append(errs, fmt.Errorf("exercise %s/%s: %w", mod.Name, ex.Name, err))
+				}
+			}
+
+			exercises[ex.Name] = true
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (b *Bench) validate() error {
+	switch {
+	case len(b.Benchmarks) == 0:
+		return errors.New("at least one benchmark is required")
+	case b.Speedup == 0 && b.AllocReduction == 0:
+		return errors.New("bench requires a speedup or an alloc_reduction")
+	case b.Speedup != 0 && b.Speedup <= 1:
+		return fmt.Errorf("speedup %g is not an improvement", b.Speedup)
+	case b.AllocReduction < 0 || b.AllocReduction > 100:
+		return fmt.Errorf("alloc_reduction %g is not a percentage", b.AllocReduction)
+	}
+
+	return nil
+}
+
+// ProgressDir returns the directory where the runner keeps learner's progress and sessions.
+func (m *Manifest) ProgressDir() string {
+	return filepath.Join(m.Dir, ProgressDirName)
+}
+
+// Select returns exercises matching the given identifiers.
+// Identifier is either a module name or module/exercise, without identifiers all exercises are selected.
+func (m *Manifest) Select(ids ...string) ([]Target, error) {
+	var targets []Target
+
+	if len(ids) == 0 {
+		for i := range m.Modules {
+			targets = append(targets, m.Modules[i].targets()...)
+		}
+
+		return targets, nil
+	}
+
+	for _, id := range ids {
+		modName, exName, _ := strings.Cut(id, "/")
+
+		mod := m.Module(modName)
+		if mod == nil {
+			return nil, fmt.Errorf("unknown module %s", modName)
+		}
+
+		if exName == "" {
+			targets = append(targets, mod.targets()...)
+			continue
+		}
+
+		ex := mod.Exercise(exName)
+		if ex == nil {
+			return nil, fmt.Errorf("unknown exercise %s", id)
+		}
+
+		targets = append(targets, Target{Module: mod, Exercise: ex})
+	}
+
+	return targets, nil
+}
+
+// SelectTrack returns exercises matching the given identifiers like Select, but only those included in the track.
+// Exercises selected by module/exercise are returned regardless of their level, learners asked for them.
+func (m *Manifest) SelectTrack(track Level, ids ...string) ([]Target, error) {
+	targets, err := m.Select(ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := make(map[string]bool)
+
+	for _, id := range ids {
+		if strings.Contains(id, "/") {
+			explicit[id] = true
+		}
+	}
+
+	return slices.DeleteFunc(targets, func(t Target) bool {
+		return !explicit[t.ID()] && !track.Includes(t.Exercise.Difficulty())
+	}), nil
+}
+
+// Module returns the module with the given name or nil if it doesn't exist.
+func (m *Manifest) Module(name string) *Module {
+	for i := range m.Modules {
+		if m.Modules[i].Name == name {
+			return &m.Modules[i]
+		}
+	}
+
+	return nil
+}
+
+// Exercise returns the exercise with the given name or nil if it doesn't exist.
+func (m *Module) Exercise(name string) *Exercise {
+	for i := range m.Exercises {
+		if m.Exercises[i].Name == name {
+			return &m.Exercises[i]
+		}
+	}
+
+	return nil
+}
+
+func (m *Module) targets() []Target {
+	targets := make([]Target, 0, len(m.Exercises))
+
+	for i := range m.Exercises {
+		targets = append(targets, Target{Module: m, Exercise: &m.Exercises[i]})
+	}
+
+	return targets
+}
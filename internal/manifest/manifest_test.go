@@ -0,0 +1,341 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testManifest() *Manifest {
+	return &Manifest{
+		Modules: []Module{
+			{
+				Name: "concurrency",
+				Path: "./concurrency",
+				Exercises: []Exercise{
+					{Name: "fork-join", Tests: []string{"TestForkJoin"}},
+					{Name: "race-condition", Tests: []string{"TestRaceCondition"}, Race: true},
+					{Name: "lock-free", Tests: []string{"TestLockFree"}, Level: LevelAdvanced},
+				},
+			},
+			{
+				Name: "errorhandling",
+				Path: "./errorhandling",
+				Exercises: []Exercise{
+					{Name: "creating-errors", Tests: []string{"TestCreatingErrors"}, Level: LevelBeginner},
+					{Name: "logging", Tests: []string{"TestLogging"}},
+				},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := &Manifest{
+		Modules: []Module{
+			{Name: "a", Path: "./a", Exercises: []Exercise{{Name: "x"}, {Name: "y", Tests: []string{"TestY"}}, {Name: "y", Tests: []string{"TestY"}}}},
+			{Name: "d", Path: "./d", Exercises: []Exercise{
+				{Name: "z", Tests: []string{"TestZ"}, Timeout: "soon"},
+				{Name: "w", Tests: []string{"TestW"}, Level: "expert"},
+				{Name: "v", Tests: []string{"TestV"}, Coverage: 120},
+				{Name: "u", Tests: []string{"TestU"}, CoverFiles: []string{"u.go"}},
+				{Name: "t", Tests: []string{"TestT"}, Count: -1},
+				{Name: "s", Tests: []string{"TestS"}, Parallel: -8},
+				{Name: "r", Tests: []string{"TestR"}, Bench: &Bench{Benchmarks: []string{"BenchmarkR"}}},
+				{Name: "q", Tests: []string{"TestQ"}, Bench: &Bench{Benchmarks: []string{"BenchmarkQ"}, Speedup: 0.5}},
+			}},
+			{Name: "a", Path: "./a"},
+			{Name: "b"},
+			{Path: "./c"},
+		},
+	}
+
+	err := m.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	for _, msg := range []string{
+		"exercise a/x: at least one test is required",
+		"module a: duplicate exercise y",
+		"duplicate module a",
+		"module b: path is required",
+		"module name is required",
+		`exercise d/z: invalid timeout "soon"`,
+		`exercise d/w: unknown level "expert"`,
+		"exercise d/v: coverage 120 is not a percentage",
+		"exercise d/u: cover_files are set without coverage",
+		"exercise d/t: negative count -1",
+		"exercise d/s: negative parallel -8",
+		"exercise d/r: bench requires a speedup or an alloc_reduction",
+		"exercise d/q: speedup 0.5 is not an improvement",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected error to contain %q, got %q", msg, err)
+		}
+	}
+
+	if err := testManifest().Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	m := testManifest()
+
+	tests := []struct {
+		ids      []string
+		expected []string
+		err      string
+	}{
+		{ids: nil, expected: []string{"concurrency/fork-join", "concurrency/race-condition", "concurrency/lock-free", "errorhandling/creating-errors", "errorhandling/logging"}},
+		{ids: []string{"concurrency"}, expected: []string{"concurrency/fork-join", "concurrency/race-condition", "concurrency/lock-free"}},
+		{ids: []string{"errorhandling/logging", "concurrency/fork-join"}, expected: []string{"errorhandling/logging", "concurrency/fork-join"}},
+		{ids: []string{"unknown"}, err: "unknown module unknown"},
+		{ids: []string{"concurrency/unknown"}, err: "unknown exercise concurrency/unknown"},
+	}
+
+	for _, tt := range tests {
+		targets, err := m.Select(tt.ids...)
+
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Select(%v): expected error %q, got %v", tt.ids, tt.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Select(%v): unexpected error: %v", tt.ids, err)
+			continue
+		}
+
+		var ids []string
+		for _, target := range targets {
+			ids = append(ids, target.ID())
+		}
+
+		if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("Select(%v): expected %v, got %v", tt.ids, tt.expected, ids)
+		}
+	}
+}
+
+func TestSelectTrack(t *testing.T) {
+	m := testManifest()
+
+	tests := []struct {
+		track    Level
+		ids      []string
+		expected []string
+	}{
+		{LevelBeginner, nil, []string{"errorhandling/creating-errors"}},
+		{LevelIntermediate, nil, []string{"concurrency/fork-join", "concurrency/race-condition", "errorhandling/creating-errors", "errorhandling/logging"}},
+		{LevelAdvanced, []string{"concurrency"}, []string{"concurrency/fork-join", "concurrency/race-condition", "concurrency/lock-free"}},
+		{LevelBeginner, []string{"concurrency/lock-free", "errorhandling"}, []string{"concurrency/lock-free", "errorhandling/creating-errors"}},
+	}
+
+	for _, tt := range tests {
+		targets, err := m.SelectTrack(tt.track, tt.ids...)
+		if err != nil {
+			t.Errorf("SelectTrack(%s, %v): unexpected error: %v", tt.track, tt.ids, err)
+			continue
+		}
+
+		var ids []string
+		for _, target := range targets {
+			ids = append(ids, target.ID())
+		}
+
+		if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("SelectTrack(%s, %v): expected %v, got %v", tt.track, tt.ids, tt.expected, ids)
+		}
+	}
+
+	if _, err := m.SelectTrack(LevelAdvanced, "unknown"); err == nil {
+		t.Error("Expected error for unknown module")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if l, err := ParseLevel(" Advanced "); err != nil || l != LevelAdvanced {
+		t.Errorf("Expected advanced level, got %q and %v", l, err)
+	}
+
+	if _, err := ParseLevel("expert"); err == nil || !strings.Contains(err.Error(), "beginner, intermediate, advanced") {
+		t.Errorf("Expected error listing known levels, got %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Find(nested); err == nil {
+		t.Error("Expected error when manifest doesn't exist")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte(`{"modules": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := Find(nested)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if path != filepath.Join(root, FileName) {
+		t.Errorf("Expected to find %s, got %s", filepath.Join(root, FileName), path)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+
+	if err := os.WriteFile(path, []byte(`{"modules": [{"name": "a"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "module a: path is required") {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"modules": [{"name": "a", "path": "./a"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if m.Dir != dir {
+		t.Errorf("Expected manifest dir to be %s, got %s", dir, m.Dir)
+	}
+}
+
+// TestRepositoryManifest checks that the manifest in the root of the repository refers to existing tests.
+func TestRepositoryManifest(t *testing.T) {
+	path, err := Find(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcRe := regexp.MustCompile(`(?m)^func ((?:Test|Example|Benchmark|Fuzz)\w*)\(`)
+
+	for _, mod := range m.Modules {
+		files, err := filepath.Glob(filepath.Join(m.Dir, mod.Path, "*_test.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defined := make(map[string]bool)
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, match := range funcRe.FindAllStringSubmatch(string(data), -1) {
+				defined[match[1]] = true
+			}
+		}
+
+		for _, ex := range mod.Exercises {
+			for _, test := range append(ex.Tests, ex.Hidden...) {
+				if !defined[test] {
+					t.Errorf("Exercise %s/%s refers to unknown test %s", mod.Name, ex.Name, test)
+				}
+			}
+		}
+	}
+}
+
+// TestFormatRepositoryManifest checks that the repository manifest is in the layout of Format,
+// so tools adding modules don't reformat the whole file.
+func TestFormatRepositoryManifest(t *testing.T) {
+	path, err := Find(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Format(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(got) != string(expected) {
+		t.Errorf("Expected formatted manifest to match %s, got:\n%s", path, got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	m := &Manifest{
+		UpdateURL: "https://example.com/release.json?a=1&b=2",
+		Modules: []Module{
+			{Name: "a", Title: "A & B", Path: "./a", Vet: "off", Exercises: []Exercise{
+				{Name: "one", Tests: []string{"TestOne", "ExampleOne"}, Race: true},
+				{Name: "two", Tests: []string{"TestTwo"}, Hidden: []string{"TestTwoHidden"}},
+			}},
+			{Name: "b", Title: "B", Path: "./b", Exercises: []Exercise{{Name: "x:y, z", Tests: []string{"TestX"}}}},
+		},
+	}
+
+	expected := `{
+  "modules": [
+    {
+      "name": "a",
+      "title": "A & B",
+      "path": "./a",
+      "vet": "off",
+      "exercises": [
+        {"name": "one", "tests": ["TestOne", "ExampleOne"], "race": true},
+        {"name": "two", "tests": ["TestTwo"], "hidden": ["TestTwoHidden"]}
+      ]
+    },
+    {
+      "name": "b",
+      "title": "B",
+      "path": "./b",
+      "exercises": [
+        {"name": "x:y, z", "tests": ["TestX"]}
+      ]
+    }
+  ],
+  "update_url": "https://example.com/release.json?a=1&b=2"
+}
+`
+
+	got, err := Format(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(got) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
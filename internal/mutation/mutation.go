@@ -0,0 +1,313 @@
+// Package mutation generates mutants of exercise code: copies of a source file with one small change,
+// like a flipped comparison or an ignored error. Tests that still pass on a mutant don't check the changed code.
+package mutation
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Operator is a kind of change made to the source.
+type Operator string
+
+const (
+	// FlipComparison replaces a comparison operator with its negation, like < with >=.
+	FlipComparison Operator = "flip-comparison"
+
+	// RemoveErrorCheck disables an if err != nil statement, so the error is ignored.
+	RemoveErrorCheck Operator = "remove-error-check"
+)
+
+// negations maps comparison operators to their negations.
+var negations = map[token.Token]token.Token{
+	token.EQL: token.NEQ,
+	token.NEQ: token.EQL,
+	token.LSS: token.GEQ,
+	token.GEQ: token.LSS,
+	token.GTR: token.LEQ,
+	token.LEQ: token.GTR,
+}
+
+// Mutant is a single change of a source file.
+type Mutant struct {
+	// File is the path of the mutated file.
+	File string
+
+	Line     int
+	Column   int
+	Operator Operator
+
+	// Original and Mutated are the changed line before and after the mutation.
+	Original string
+	Mutated  string
+
+	// index is the number of the mutated node among mutable nodes of the file, in the order of Inspect.
+	index int
+}
+
+func (m Mutant) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", m.File, m.Line, m.Column, m.Operator)
+}
+
+// Generate returns mutants of the file, changing only functions for which keep returns true.
+func Generate(path string, src []byte, keep func(fn *ast.FuncDecl) bool) ([]Mutant, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var mutants []Mutant
+
+	mutable(file, keep, func(n ast.Node, op Operator, index int) {
+		pos := fset.Position(n.Pos())
+		if b, ok := n.(*ast.BinaryExpr); ok {
+			pos = fset.Position(b.OpPos)
+		}
+
+		mutants = append(mutants, Mutant{File: path, Line: pos.Line, Column: pos.Column, Operator: op, index: index})
+	})
+
+	lines := strings.Split(string(src), "\n")
+
+	for i := range mutants {
+		m := &mutants[i]
+
+		mutated, err := Apply(src, *m)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Original = strings.TrimSpace(lines[m.Line-1])
+
+		if after := strings.Split(string(mutated), "\n"); m.Line <= len(after) {
+			m.Mutated = strings.TrimSpace(after[m.Line-1])
+		}
+	}
+
+	return mutants, nil
+}
+
+// Apply returns the source of the file with the mutant applied.
+// The source must be the one the mutant was generated from.
+func Apply(src []byte, m Mutant) ([]byte, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, m.File, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", m.File, err)
+	}
+
+	found := false
+
+	// Indexes count mutable nodes of all functions, so a mutant doesn't depend on the filter it was generated with.
+	mutable(file, nil, func(n ast.Node, op Operator, index int) {
+		if index != m.index || op != m.Operator {
+			return
+		}
+
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			n.Op = negations[n.Op]
+		case *ast.IfStmt:
+			// The condition stays in the code, so variables it uses are still used and the mutant compiles.
+			n.Cond = &ast.BinaryExpr{X: ast.NewIdent("false"), Op: token.LAND, Y: n.Cond}
+		}
+
+		found = true
+	})
+
+	if !found {
+		return nil, fmt.Errorf("mutant %s is not found in the source", m)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("failed to format mutant %s: %w", m, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mutable calls fn for every node of functions kept by the filter that can be mutated, with the operator
+// and the index of the node among mutable nodes of all functions. A nil filter keeps all functions.
+func mutable(file *ast.File, keep func(fn *ast.FuncDecl) bool, fn func(n ast.Node, op Operator, index int)) {
+	index := 0
+
+	for _, decl := range file.Decls {
+		f, ok := decl.(*ast.FuncDecl)
+		if !ok || f.Body == nil {
+			continue
+		}
+
+		skip := keep != nil && !keep(f)
+		// Conditions of error checks are mutated as error checks only, flipping them is the same mutant.
+		checks := make(map[ast.Expr]bool)
+
+		ast.Inspect(f.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.IfStmt:
+				if isErrorCheck(n) {
+					checks[n.Cond] = true
+
+					if !skip {
+						fn(n, RemoveErrorCheck, index)
+					}
+
+					index++
+				}
+			case *ast.BinaryExpr:
+				if _, ok := negations[n.Op]; ok && !checks[n] {
+					if !skip {
+						fn(n, FlipComparison, index)
+					}
+
+					index++
+				}
+			}
+
+			return true
+		})
+	}
+}
+
+// isErrorCheck reports whether the statement is if err != nil without else, judging by the name of the variable:
+// err, or a name ending with Err, like readErr.
+func isErrorCheck(stmt *ast.IfStmt) bool {
+	cond, ok := stmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ || stmt.Else != nil {
+		return false
+	}
+
+	x, ok := cond.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	y, ok := cond.Y.(*ast.Ident)
+
+	return ok && y.Name == "nil" && (x.Name == "err" || strings.HasSuffix(x.Name, "Err"))
+}
+
+// ForExercise returns mutants of the code of the exercise in test files of the directory.
+// Workshops declare the code of an exercise above its tests, so like narratives it's found between the previous
+// test function in a file and a test of the exercise. Test functions and helpers that take a testing
+// parameter, like *testing.T, are test code and are not mutated.
+func ForExercise(dir string, tests []string) ([]Mutant, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var mutants []Mutant
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		fset := token.NewFileSet()
+
+		file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		code := exerciseCode(file, tests)
+		if len(code) == 0 {
+			continue
+		}
+
+		found, err := Generate(path, src, func(fn *ast.FuncDecl) bool {
+			return slices.Contains(code, fn.Name.Name+receiverName(fn))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		mutants = append(mutants, found...)
+	}
+
+	return mutants, nil
+}
+
+// exerciseCode returns keys of functions declared above the tests of the exercise in the file.
+func exerciseCode(file *ast.File, tests []string) []string {
+	var section, code []string
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		if !isTestFunc(fn.Name.Name) {
+			if !takesTesting(fn) {
+				section = append(section, fn.Name.Name+receiverName(fn))
+			}
+
+			continue
+		}
+
+		if slices.Contains(tests, fn.Name.Name) {
+			code = append(code, section...)
+		}
+
+		section = nil
+	}
+
+	return code
+}
+
+// receiverName returns the receiver type of a method as a suffix, so methods with the same name don't collide.
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), fn.Recv.List[0].Type); err != nil {
+		return ""
+	}
+
+	return " " + buf.String()
+}
+
+// isTestFunc reports whether the function is run by go test.
+func isTestFunc(name string) bool {
+	for _, prefix := range []string{"Test", "Example", "Benchmark", "Fuzz"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// takesTesting reports whether the function has a parameter from the testing package, like *testing.T.
+func takesTesting(fn *ast.FuncDecl) bool {
+	for _, field := range fn.Type.Params.List {
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+
+		if sel, ok := typ.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "testing" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,152 @@
+package mutation
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `package shop
+
+import "testing"
+
+// Discount returns the discount for the total.
+func Discount(total int) int {
+	if total >= 100 {
+		return 10
+	}
+
+	return 0
+}
+
+func load(path string) ([]byte, error) {
+	data, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if readErr := check(data); readErr != nil {
+		return nil, readErr
+	}
+
+	return data, nil
+}
+
+func TestDiscount(t *testing.T) {
+	if got := Discount(100); got != 10 {
+		t.Errorf("Expected 10, got %d", got)
+	}
+}
+
+func helper(t *testing.T, a, b int) bool {
+	return a == b
+}
+
+func Equal(a, b int) bool {
+	return a == b
+}
+
+func TestEqual(t *testing.T) {}
+`
+
+func TestGenerate(t *testing.T) {
+	mutants, err := Generate("shop_test.go", []byte(source), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"7:11 flip-comparison: if total >= 100 { -> if total < 100 {",
+		"16:2 remove-error-check: if err != nil { -> if false && err != nil {",
+		"20:2 remove-error-check: if readErr := check(data); readErr != nil { -> if readErr := check(data); false && readErr != nil {",
+		"28:31 flip-comparison: if got := Discount(100); got != 10 { -> if got := Discount(100); got == 10 {",
+		"34:11 flip-comparison: return a == b -> return a != b",
+		"38:11 flip-comparison: return a == b -> return a != b",
+	}
+
+	var got []string
+	for _, m := range mutants {
+		got = append(got, describe(m))
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected mutants:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	onlyEqual := func(fn *ast.FuncDecl) bool { return fn.Name.Name == "Equal" }
+
+	mutants, err = Generate("shop_test.go", []byte(source), onlyEqual)
+	if err != nil || len(mutants) != 1 || mutants[0].Line != 38 {
+		t.Fatalf("Expected the only mutant of Equal, got %v, %v", mutants, err)
+	}
+
+	mutated, err := Apply([]byte(source), mutants[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedSource := strings.Replace(source, "func Equal(a, b int) bool {\n\treturn a == b", "func Equal(a, b int) bool {\n\treturn a != b", 1)
+	if string(mutated) != expectedSource {
+		t.Errorf("Expected only the comparison of Equal to change, got:\n%s", mutated)
+	}
+}
+
+func TestApplyChangedSource(t *testing.T) {
+	mutants, err := Generate("shop_test.go", []byte(source), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := Apply([]byte("package shop\n"), mutants[0]); err == nil {
+		t.Error("Expected an error applying a mutant to a source without it")
+	}
+}
+
+func TestForExercise(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"shop_test.go": source,
+		"other_test.go": `package shop
+
+import "testing"
+
+func Other(a int) bool {
+	return a > 0
+}
+
+func TestOther(t *testing.T) {}
+`,
+		"shop.go": "package shop\n\nfunc Positive(a int) bool {\n\treturn a > 0\n}\n",
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mutants, err := ForExercise(dir, []string{"TestDiscount", "TestEqual"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, m := range mutants {
+		got = append(got, fmt.Sprintf("%s:%d:%d", filepath.Base(m.File), m.Line, m.Column))
+	}
+
+	// Tests and helpers that take *testing.T are not mutated, neither is code of other exercises.
+	expected := []string{"shop_test.go:7:11", "shop_test.go:16:2", "shop_test.go:20:2", "shop_test.go:38:11"}
+
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected mutants %q, got %q", expected, got)
+	}
+}
+
+func describe(m Mutant) string {
+	return fmt.Sprintf("%d:%d %s: %s -> %s", m.Line, m.Column, m.Operator, m.Original, m.Mutated)
+}
@@ -0,0 +1,221 @@
+// Package playground creates scratch Go modules outside of the workshop and runs them on every change.
+package playground
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed presets
+var presetsFS embed.FS
+
+// DefaultPreset is the template of main.go used when no preset is chosen.
+const DefaultPreset = "main"
+
+// goMod is the go.mod of playground modules, the Go version allows range over int and per iteration loop variables.
+const goMod = "module playground\n\ngo 1.23\n"
+
+// Presets returns names of available main.go templates.
+func Presets() []string {
+	entries, _ := presetsFS.ReadDir("presets")
+	names := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".go.tmpl"))
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Create creates a module with main.go from the preset in a new temporary directory and returns the directory.
+// The caller removes the directory when done.
+func Create(preset string) (string, error) {
+	src, err := presetsFS.ReadFile(path.Join("presets", preset+".go.tmpl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("unknown template %q, available: %s", preset, strings.Join(Presets(), ", "))
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "workshop-play-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create playground: %w", err)
+	}
+
+	files := map[string][]byte{"go.mod": []byte(goMod), "main.go": src}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to create playground: %w", err)
+		}
+	}
+
+	return dir, nil
+}
+
+// Session runs the playground module each time its files change.
+type Session struct {
+	// Dir is the directory of the playground module.
+	Dir string
+
+	// GoBin is the go command used to run the module, "go" is used when empty.
+	GoBin string
+
+	// Race runs the module with the race detector.
+	Race bool
+
+	// Timeout stops a run that takes longer, so a snippet that blocks forever doesn't need to be killed by hand.
+	Timeout time.Duration
+
+	// Interval is how often files are checked for changes.
+	Interval time.Duration
+
+	// Out receives output of runs.
+	Out io.Writer
+}
+
+// Watch runs the module and runs it again after every change of its files until ctx is done.
+// A run that is still in progress when files change is stopped before the next one starts.
+func (s *Session) Watch(ctx context.Context) error {
+	var (
+		wg     sync.WaitGroup
+		cancel context.CancelFunc = func() {}
+		last   []byte
+	)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		state, err := s.state()
+		if err != nil {
+			cancel()
+			wg.Wait()
+
+			return err
+		}
+
+		if !bytes.Equal(state, last) {
+			last = state
+
+			cancel()
+			wg.Wait()
+
+			var runCtx context.Context
+			runCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+				s.run(runCtx)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			wg.Wait()
+
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// state returns names, sizes, and modification times of files in the module, it changes when a file is saved.
+func (s *Session) state() ([]byte, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read playground: %w", err)
+	}
+
+	var state bytes.Buffer
+
+	for _, e := range entries {
+		if e.IsDir() || (filepath.Ext(e.Name()) != ".go" && e.Name() != "go.mod") {
+			continue
+		}
+
+		info, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Editors replace files on save, the file is seen on the next check.
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read playground: %w", err)
+		}
+
+		fmt.Fprintf(&state, "%s %d %d\n", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return state.Bytes(), nil
+}
+
+// run builds and executes the module and reports how it finished.
+// The binary is executed directly, not by go run, so stopping a run stops the program itself.
+func (s *Session) run(ctx context.Context) {
+	goBin := s.GoBin
+	if goBin == "" {
+		goBin = "go"
+	}
+
+	bin := filepath.Join(s.Dir, "playground")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	args := []string{"build", "-o", bin}
+	if s.Race {
+		args = append(args, "-race")
+	}
+
+	fmt.Fprintf(s.Out, "--- run at %s\n", time.Now().Format(time.TimeOnly))
+
+	start := time.Now()
+
+	err := s.exec(ctx, goBin, append(args, ".")...)
+	if err == nil {
+		err = s.exec(ctx, bin)
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(s.Out, "--- stopped after %s timeout\n", s.Timeout)
+	case ctx.Err() != nil:
+		fmt.Fprintln(s.Out, "--- stopped")
+	case err != nil:
+		fmt.Fprintf(s.Out, "--- %v after %.2fs\n", err, time.Since(start).Seconds())
+	default:
+		fmt.Fprintf(s.Out, "--- done in %.2fs\n", time.Since(start).Seconds())
+	}
+}
+
+func (s *Session) exec(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = s.Dir
+	cmd.Stdout = s.Out
+	cmd.Stderr = s.Out
+
+	return cmd.Run()
+}
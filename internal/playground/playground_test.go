@@ -0,0 +1,154 @@
+package playground
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPresets(t *testing.T) {
+	presets := Presets()
+
+	for _, name := range []string{DefaultPreset, "goroutines", "channels", "httpserver"} {
+		if !slices.Contains(presets, name) {
+			t.Errorf("Expected preset %s, got %q", name, presets)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	for _, preset := range Presets() {
+		t.Run(preset, func(t *testing.T) {
+			dir, err := Create(preset)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			cmd := exec.Command("go", "vet", ".")
+			cmd.Dir = dir
+
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("Expected preset to build and pass vet, got %v:\n%s", err, out)
+			}
+		})
+	}
+
+	if _, err := Create("unknown"); err == nil || !strings.Contains(err.Error(), "goroutines") {
+		t.Errorf("Expected error with available presets, got %v", err)
+	}
+}
+
+// syncBuffer is a buffer safe for concurrent writes of go commands and reads of the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := Create(DefaultPreset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out syncBuffer
+
+	s := &Session{Dir: dir, Timeout: 5 * time.Second, Interval: 20 * time.Millisecond, Out: &out}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- s.Watch(ctx) }()
+
+	waitFor := func(text string) {
+		t.Helper()
+
+		for deadline := time.Now().Add(time.Minute); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if strings.Contains(out.String(), text) {
+				return
+			}
+		}
+
+		t.Fatalf("Expected output to contain %q, got:\n%s", text, out.String())
+	}
+
+	waitFor("Hello, playground!\n--- done in")
+
+	blocking := "package main\n\nimport \"time\"\n\nfunc main() {\n\tprintln(\"blocked\")\n\ttime.Sleep(time.Hour)\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(blocking), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("blocked\n")
+
+	changed := "package main\n\nfunc main() {\n\tprintln(\"changed\")\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(changed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("blocked\n--- stopped\n")
+	waitFor("changed\n--- done in")
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected Watch to return when the context is canceled")
+	}
+}
+
+func TestWatchTimeout(t *testing.T) {
+	dir, err := Create(DefaultPreset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	blocking := "package main\n\nimport \"time\"\n\nfunc main() {\n\ttime.Sleep(time.Hour)\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(blocking), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out syncBuffer
+
+	s := &Session{Dir: dir, Timeout: 3 * time.Second, Interval: 20 * time.Millisecond, Out: &out}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	go s.Watch(ctx)
+
+	for !strings.Contains(out.String(), "--- stopped after 3s timeout") {
+		if ctx.Err() != nil {
+			t.Fatalf("Expected the run to be stopped after the timeout, got:\n%s", out.String())
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+}
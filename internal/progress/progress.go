@@ -0,0 +1,169 @@
+// Package progress keeps the status of exercises between runs of the workshop.
+//
+// The store is updated in memory after every exercise run and flushed to a JSON file.
+// A flush writes a temporary file next to the store and renames it, so the file is always either
+// the old or the new version, even if the runner is killed in the middle of a write.
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the progress file in the progress directory of the manifest.
+const FileName = "progress.json"
+
+// DefaultInterval is how often long sessions flush the store.
+const DefaultInterval = 30 * time.Second
+
+// Exercise is the progress of the learner on an exercise.
+type Exercise struct {
+	Passed   bool      `json:"passed"`
+	Attempts int       `json:"attempts"`
+	LastRun  time.Time `json:"last_run"`
+
+	// SolvedAt is the time of the first passed run, zero while the exercise is not solved.
+	SolvedAt time.Time `json:"solved_at"`
+}
+
+// Store is the progress of all exercises by their ids, it's safe for concurrent use.
+type Store struct {
+	path string
+
+	mu        sync.Mutex
+	exercises map[string]Exercise
+	dirty     bool
+}
+
+// Open loads the store from the file, a missing file is an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, exercises: make(map[string]Exercise)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read progress: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.exercises); err != nil {
+		return nil, fmt.Errorf("failed to parse progress %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Record updates the progress of the exercise with the result of a run at the time.
+func (s *Store) Record(id string, passed bool, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ex := s.exercises[id]
+	ex.Attempts++
+	ex.LastRun = at
+	ex.Passed = passed
+
+	if passed && ex.SolvedAt.IsZero() {
+		ex.SolvedAt = at
+	}
+
+	s.exercises[id] = ex
+	s.dirty = true
+}
+
+// Get returns the progress of the exercise, ok is false if it has never been run.
+func (s *Store) Get(id string) (ex Exercise, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ex, ok = s.exercises[id]
+
+	return ex, ok
+}
+
+// Flush writes the store to the file if it changed since the last flush.
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.exercises, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(s.path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+
+	s.dirty = false
+
+	return nil
+}
+
+// Autosave flushes the store every interval and on every signal received from signals,
+// like SIGINT and SIGTERM registered with signal.Notify. It flushes once more and returns when ctx is done.
+// Errors of periodic flushes are reported to report, the session goes on, and the next flush tries again.
+func (s *Store) Autosave(ctx context.Context, interval time.Duration, signals <-chan os.Signal, report func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return s.Flush()
+		case <-signals:
+			if err := s.Flush(); err != nil {
+				report(err)
+			}
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				report(err)
+			}
+		}
+	}
+}
+
+// writeFile replaces the file with the data atomically: readers see either the old or the new content.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	// Removing a renamed temporary file fails harmlessly.
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// Sync makes sure the data is on disk before the rename makes it visible, a crash can't leave an empty file.
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
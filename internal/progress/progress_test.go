@@ -0,0 +1,157 @@
+package progress
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".workshop", FileName)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.Record("concurrency/deadlock", false, first)
+	s.Record("concurrency/deadlock", true, first.Add(time.Minute))
+	s.Record("concurrency/deadlock", false, first.Add(2*time.Minute))
+	s.Record("errorhandling/wrapping", false, first)
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	loaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ex, ok := loaded.Get("concurrency/deadlock")
+	expected := Exercise{Passed: false, Attempts: 3, LastRun: first.Add(2 * time.Minute), SolvedAt: first.Add(time.Minute)}
+
+	if !ok || ex != expected {
+		t.Errorf("Expected %+v, got %+v", expected, ex)
+	}
+
+	if ex, _ := loaded.Get("errorhandling/wrapping"); !ex.SolvedAt.IsZero() {
+		t.Errorf("Expected unsolved exercise to have no solved time, got %v", ex.SolvedAt)
+	}
+
+	if _, ok := loaded.Get("concurrency/unknown"); ok {
+		t.Error("Expected no progress for an exercise that was never run")
+	}
+}
+
+func TestOpenCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{"concurrency/deadlock":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for a corrupted progress file")
+	}
+}
+
+func TestFlushFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+
+	// A non-empty directory at the path of the store can't be replaced by rename.
+	if err := os.MkdirAll(filepath.Join(path, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s.path = path
+	s.Record("concurrency/deadlock", true, time.Now())
+
+	if err := s.Flush(); err == nil {
+		t.Fatal("Expected error when the store can't be replaced")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be removed, got %d entries in the directory", len(entries))
+	}
+
+	// The changes are not lost, the next flush writes them.
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected progress to be saved on the next flush, got %v", err)
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		trigger  func(signals chan<- os.Signal, cancel context.CancelFunc)
+	}{
+		{"signal", time.Hour, func(signals chan<- os.Signal, _ context.CancelFunc) { signals <- syscall.SIGTERM }},
+		{"interval", 10 * time.Millisecond, func(chan<- os.Signal, context.CancelFunc) {}},
+		{"done", time.Hour, func(_ chan<- os.Signal, cancel context.CancelFunc) { cancel() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+
+			s, err := Open(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			signals := make(chan os.Signal, 1)
+			done := make(chan error, 1)
+
+			go func() {
+				done <- s.Autosave(ctx, tt.interval, signals, func(err error) { t.Errorf("Unexpected error: %v", err) })
+			}()
+
+			s.Record("concurrency/deadlock", true, time.Now())
+			tt.trigger(signals, cancel)
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				if _, err := os.Stat(path); err == nil {
+					break
+				}
+
+				if time.Now().After(deadline) {
+					t.Fatal("Expected progress to be saved")
+				}
+
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			cancel()
+
+			if err := <-done; err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/bench"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+// DefaultBenchTimeout limits benchmark runs of a module, they take longer than tests.
+const DefaultBenchTimeout = 10 * time.Minute
+
+// BenchResult is the outcome of running benchmarks of a module.
+type BenchResult struct {
+	Module *manifest.Module
+
+	// Passed is set when all benchmarks ran without failures.
+	Passed   bool
+	Output   []byte
+	Duration time.Duration
+
+	// Results are measurements of benchmarks that finished.
+	Results bench.Results
+
+	// Skipped is the reason benchmarks were not run, like cgo being disabled.
+	Skipped string
+}
+
+// BenchArgs returns go test arguments that run all benchmarks of the module count times, without tests.
+func (r *Runner) BenchArgs(mod *manifest.Module, count int) []string {
+	return r.benchArgs(mod, ".", count)
+}
+
+// ExerciseBenchArgs returns go test arguments that run only the benchmarks of the optimization exercise count times.
+func (r *Runner) ExerciseBenchArgs(t manifest.Target, count int) []string {
+	return r.benchArgs(t.Module, "^("+strings.Join(t.Exercise.Bench.Benchmarks, "|")+")$", count)
+}
+
+func (r *Runner) benchArgs(mod *manifest.Module, pattern string, count int) []string {
+	args := []string{"test", "-run=^$", "-bench=" + pattern, "-benchmem", "-count=" + strconv.Itoa(count), "-timeout=" + r.benchTimeout().String()}
+
+	if r.BenchTime != "" {
+		args = append(args, "-benchtime="+r.BenchTime)
+	}
+
+	if mod.Vet != "" {
+		args = append(args, "-vet="+mod.Vet)
+	}
+
+	return append(args, mod.Path)
+}
+
+// Bench runs benchmarks of the module and copies go test output to w while they are running, w can be nil.
+// Failing benchmarks are reported in the result, error is returned only when they can't be executed at all.
+func (r *Runner) Bench(ctx context.Context, mod *manifest.Module, count int, w io.Writer) (BenchResult, error) {
+	return r.bench(ctx, mod, r.BenchArgs(mod, count), w)
+}
+
+// BenchExercise runs only the benchmarks of the optimization exercise, like Bench does for the whole module.
+func (r *Runner) BenchExercise(ctx context.Context, t manifest.Target, count int) (BenchResult, error) {
+	return r.bench(ctx, t.Module, r.ExerciseBenchArgs(t, count), nil)
+}
+
+func (r *Runner) bench(ctx context.Context, mod *manifest.Module, args []string, w io.Writer) (BenchResult, error) {
+	if mod.Cgo && !r.cgoEnabled(ctx) {
+		return BenchResult{Module: mod, Skipped: "cgo is disabled"}, nil
+	}
+
+	var out bytes.Buffer
+
+	runCtx, cancel := context.WithTimeout(ctx, r.benchTimeout()+killDelay)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, r.goBin(), args...)
+	cmd.Dir = r.Dir
+	cmd.Stdout = &out
+
+	if w != nil {
+		cmd.Stdout = io.MultiWriter(&out, w)
+	}
+
+	cmd.Stderr = cmd.Stdout
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+
+	res := BenchResult{
+		Module:   mod,
+		Passed:   err == nil,
+		Output:   out.Bytes(),
+		Duration: time.Since(start),
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return res, fmt.Errorf("failed to run benchmarks of %s: %w", mod.Name, err)
+	}
+
+	if res.Results, err = bench.Parse(bytes.NewReader(res.Output)); err != nil {
+		return res, fmt.Errorf("failed to run benchmarks of %s: %w", mod.Name, err)
+	}
+
+	return res, nil
+}
+
+func (r *Runner) benchTimeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+
+	return DefaultBenchTimeout
+}
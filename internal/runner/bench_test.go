@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+func TestBenchArgs(t *testing.T) {
+	mod := &manifest.Module{Name: "module", Path: "./module"}
+
+	r := &Runner{}
+	expected := []string{"test", "-run=^$", "-bench=.", "-benchmem", "-count=6", "-timeout=10m0s", "./module"}
+
+	if args := r.BenchArgs(mod, 6); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+
+	mod.Vet = "off"
+	r = &Runner{BenchTime: "100ms", Timeout: time.Minute}
+	expected = []string{"test", "-run=^$", "-bench=.", "-benchmem", "-count=2", "-timeout=1m0s", "-benchtime=100ms", "-vet=off", "./module"}
+
+	if args := r.BenchArgs(mod, 2); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+}
+
+func TestExerciseBenchArgs(t *testing.T) {
+	target := manifest.Target{
+		Module:   &manifest.Module{Name: "module", Path: "./module"},
+		Exercise: &manifest.Exercise{Name: "hot-path", Bench: &manifest.Bench{Benchmarks: []string{"BenchmarkA", "BenchmarkB"}, Speedup: 2}},
+	}
+
+	r := &Runner{}
+	expected := []string{"test", "-run=^$", "-bench=^(BenchmarkA|BenchmarkB)$", "-benchmem", "-count=6", "-timeout=10m0s", "./module"}
+
+	if args := r.ExerciseBenchArgs(target, 6); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+}
+
+func TestBench(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module"), BenchTime: "10x"}
+
+	res, err := r.Bench(context.Background(), &manifest.Module{Name: "module", Path: "."}, 3, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !res.Passed {
+		t.Fatalf("Expected benchmarks to pass, got output:\n%s", res.Output)
+	}
+
+	if runs := res.Results["BenchmarkAbs"]; len(runs) != 3 {
+		t.Errorf("Expected 3 runs of BenchmarkAbs, got %v", res.Results)
+	}
+}
@@ -0,0 +1,438 @@
+// Package runner executes workshop exercises with go test.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+// Runner executes exercises from the manifest.
+type Runner struct {
+	// GoBin is the go command used to run tests, "go" is used when empty.
+	GoBin string
+
+	// Dir is the directory where go test is executed, usually the directory of the manifest.
+	Dir string
+
+	// Race forces the race detector for all exercises, not only for the ones that require it in the manifest.
+	Race bool
+
+	// Strict builds tests with SolutionTag and runs hidden tests of exercises along with regular ones.
+	Strict bool
+
+	// Timeout limits every exercise run, overriding timeouts from the manifest.
+	// When zero, the manifest timeout of the exercise or DefaultTimeout is used.
+	Timeout time.Duration
+
+	// Overlay is the path of a go build overlay file that replaces source files for the run, see go help build.
+	Overlay string
+
+	// FailFast stops running tests of an exercise after the first failure.
+	FailFast bool
+
+	// SkipCoverage runs exercises without measuring coverage, so coverage gates of the manifest don't fail them.
+	SkipCoverage bool
+
+	// Stress runs tests of every exercise this many times in random order with the race detector, to reveal flaky tests.
+	// It overrides the count of the manifest, and the timeout of a single run is multiplied by the number of runs.
+	Stress int
+
+	// BenchTime is passed to go test -benchtime by Bench, like 100ms or 1000x, go test default is used when empty.
+	BenchTime string
+}
+
+const (
+	// SolutionTag is the build tag of files with hidden tests.
+	SolutionTag = "solutiontests"
+
+	// DefaultTimeout limits exercises without a timeout in the manifest.
+	DefaultTimeout = 2 * time.Minute
+
+	// killDelay is how long the runner waits after the timeout before killing go test.
+	// go test -timeout limits only the test binary, the delay leaves time to build the package.
+	killDelay = time.Minute
+)
+
+// Result is the outcome of a single exercise run.
+type Result struct {
+	Target   manifest.Target
+	Passed   bool
+	Race     bool
+	Output   []byte
+	Duration time.Duration
+
+	// TimedOut is set when tests didn't finish within the timeout, usually because of a deadlock.
+	TimedOut bool
+
+	// Panicked is set when a test panicked and took down the rest of the exercise tests.
+	Panicked bool
+
+	// FailedTests are names of failed tests and subtests from the output.
+	FailedTests []string
+
+	// Assertions are failed assertions reported by exercises that use the grader package.
+	Assertions []grader.Assertion
+
+	// Skipped is the reason the exercise was not run, like cgo being disabled, empty for exercises that ran.
+	Skipped string
+
+	// Runs is the number of times every test ran, tests of exercises with a count or under stress run several times.
+	Runs int
+
+	// Coverage is measured for exercises with a coverage gate when their tests pass, nil otherwise.
+	// Such an exercise passes only when the coverage is met.
+	Coverage *Coverage
+
+	// Bench is the outcome of comparing benchmarks of an optimization exercise with the baseline,
+	// set by callers that check it after tests pass. Such an exercise passes only when the benchmarks do.
+	Bench string
+}
+
+// FailedRuns returns the number of failed runs of every failed top-level test.
+func (r Result) FailedRuns() map[string]int {
+	failed := make(map[string]int)
+
+	for _, name := range r.FailedTests {
+		if !strings.Contains(name, "/") {
+			failed[name]++
+		}
+	}
+
+	return failed
+}
+
+// Flaky reports whether a test failed only in some of its runs. Tests that hung or panicked didn't finish
+// the rest of their runs, so they are not flaky.
+func (r Result) Flaky() bool {
+	if r.Runs < 2 || r.TimedOut || r.Panicked {
+		return false
+	}
+
+	for _, n := range r.FailedRuns() {
+		if n < r.Runs {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Coverage is the statement coverage of the code tests of an exercise must cover.
+type Coverage struct {
+	// Percent of statements run by the tests.
+	Percent float64
+
+	// Required is the minimum percent from the manifest.
+	Required float64
+
+	// Uncovered are blocks of code the tests never ran, ordered by file and line.
+	Uncovered []Block
+}
+
+// Met reports whether the coverage reaches the required one.
+func (c *Coverage) Met() bool {
+	return c.Percent >= c.Required
+}
+
+// Block is a range of lines of a file, the path of the file is relative to the runner directory.
+type Block struct {
+	File      string
+	StartLine int
+	EndLine   int
+}
+
+// failedTestRe matches lines go test prints for failed tests and subtests.
+var failedTestRe = regexp.MustCompile(`(?m)^\s*--- FAIL: (\S+)`)
+
+// timedOutRe matches the panic of a test binary that exceeded go test -timeout.
+var timedOutRe = regexp.MustCompile(`(?m)^panic: test timed out after`)
+
+// runningTestRe matches tests listed as running when the test binary timed out.
+var runningTestRe = regexp.MustCompile(`(?m)^\t\t(\S+) \(`)
+
+// panicRe matches the panic that crashed a test binary.
+var panicRe = regexp.MustCompile(`(?m)^panic: `)
+
+// New creates a runner for the exercises described by the manifest.
+func New(m *manifest.Manifest) *Runner {
+	return &Runner{
+		GoBin: "go",
+		Dir:   m.Dir,
+	}
+}
+
+// Args returns go test arguments for the target.
+func (r *Runner) Args(t manifest.Target) []string {
+	args := []string{"test", "-count=" + strconv.Itoa(r.runs(t)), "-timeout=" + r.timeout(t).String()}
+
+	if r.race(t) {
+		args = append(args, "-race")
+	}
+
+	if r.Overlay != "" {
+		args = append(args, "-overlay="+r.Overlay)
+	}
+
+	if r.FailFast {
+		args = append(args, "-failfast")
+	}
+
+	if t.Exercise.Parallel > 0 {
+		args = append(args, "-parallel="+strconv.Itoa(t.Exercise.Parallel))
+	}
+
+	if t.Exercise.Shuffle || r.Stress > 0 {
+		// go test prints the seed, a failed order can be reproduced with -shuffle=<seed>.
+		args = append(args, "-shuffle=on")
+	}
+
+	if t.Module.Vet != "" {
+		args = append(args, "-vet="+t.Module.Vet)
+	}
+
+	tests := t.Exercise.Tests
+	if r.Strict {
+		args = append(args, "-tags="+SolutionTag)
+		tests = append(slices.Clip(tests), t.Exercise.Hidden...)
+	}
+
+	args = append(args, "-run", "^("+strings.Join(tests, "|")+")$", t.Module.Path)
+
+	return args
+}
+
+// Run executes tests of the target exercise.
+// Failing tests are reported in the result, error is returned only when tests can't be executed at all.
+func (r *Runner) Run(ctx context.Context, t manifest.Target) (Result, error) {
+	return r.Stream(ctx, t, nil)
+}
+
+// Stream executes tests of the target exercise like Run and copies go test output to w while tests are running.
+// The output is still collected in the result, w can be nil.
+func (r *Runner) Stream(ctx context.Context, t manifest.Target, w io.Writer) (Result, error) {
+	if t.Module.Cgo && !r.cgoEnabled(ctx) {
+		res := Result{Target: t, Skipped: "cgo is disabled"}
+		res.Output = []byte("cgo is disabled or no C compiler is found, set CGO_ENABLED=1 and install gcc or clang to run the exercise\n")
+
+		if w != nil {
+			w.Write(res.Output)
+		}
+
+		return res, nil
+	}
+
+	report, err := os.CreateTemp("", "workshop-grader-*.jsonl")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create grader report: %w", err)
+	}
+
+	report.Close()
+	defer os.Remove(report.Name())
+
+	args := r.Args(t)
+
+	var profile string
+
+	if r.covered(t) {
+		f, err := os.CreateTemp("", "workshop-cover-*.out")
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to create coverage profile: %w", err)
+		}
+
+		f.Close()
+		defer os.Remove(f.Name())
+
+		// Test flags are accepted after the package too, Args stays the same with and without coverage.
+		profile = f.Name()
+		args = append(args, "-coverprofile="+profile)
+	}
+
+	var out bytes.Buffer
+
+	runCtx, cancel := context.WithTimeout(ctx, r.timeout(t)+killDelay)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, r.goBin(), args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), grader.ReportEnv+"="+report.Name())
+	cmd.Stdout = &out
+
+	if w != nil {
+		cmd.Stdout = io.MultiWriter(&out, w)
+	}
+
+	cmd.Stderr = cmd.Stdout
+	// The test binary may outlive killed go test and keep the output open.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+
+	res := Result{
+		Target:   t,
+		Passed:   err == nil,
+		Race:     r.race(t),
+		Output:   out.Bytes(),
+		Duration: time.Since(start),
+		Runs:     r.runs(t),
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return res, fmt.Errorf("failed to run exercise %s: %w", t.ID(), err)
+	}
+
+	for _, match := range failedTestRe.FindAllSubmatch(res.Output, -1) {
+		res.FailedTests = append(res.FailedTests, string(match[1]))
+	}
+
+	switch {
+	case timedOutRe.Match(res.Output):
+		res.TimedOut = true
+
+		// Tests that were still running when the binary timed out don't report --- FAIL lines.
+		if _, running, ok := bytes.Cut(res.Output, []byte("running tests:\n")); ok {
+			running, _, _ = bytes.Cut(running, []byte("\n\n"))
+			for _, match := range runningTestRe.FindAllSubmatch(running, -1) {
+				res.FailedTests = append(res.FailedTests, string(match[1]))
+			}
+		}
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+		res.TimedOut = true
+	case panicRe.Match(res.Output):
+		res.Panicked = true
+	}
+
+	if res.Assertions, err = grader.ReadReport(report.Name()); err != nil {
+		return res, fmt.Errorf("failed to run exercise %s: %w", t.ID(), err)
+	}
+
+	// Coverage of failing tests says nothing, tests that don't pass yet are the first thing to fix.
+	if profile != "" && res.Passed {
+		if res.Coverage, err = r.coverage(t, profile); err != nil {
+			return res, fmt.Errorf("failed to measure coverage of exercise %s: %w", t.ID(), err)
+		}
+
+		res.Passed = res.Coverage.Met()
+	}
+
+	return res, nil
+}
+
+// covered reports whether the coverage of the target is measured.
+func (r *Runner) covered(t manifest.Target) bool {
+	return t.Exercise.Coverage > 0 && !r.SkipCoverage
+}
+
+// coverage reads the coverage profile of the target, counting only cover files of the exercise when it has them.
+func (r *Runner) coverage(t manifest.Target, profile string) (*Coverage, error) {
+	profiles, err := cover.ParseProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	cov := &Coverage{Required: t.Exercise.Coverage}
+
+	var total, covered int
+
+	for _, p := range profiles {
+		// Profiles name files by import path, tests of an exercise cover only the package of its module.
+		name := path.Base(p.FileName)
+		if len(t.Exercise.CoverFiles) > 0 && !slices.Contains(t.Exercise.CoverFiles, name) {
+			continue
+		}
+
+		file := filepath.ToSlash(filepath.Join(t.Module.Path, name))
+
+		for _, b := range p.Blocks {
+			total += b.NumStmt
+
+			if b.Count > 0 {
+				covered += b.NumStmt
+				continue
+			}
+
+			// Neighbouring blocks are reported as one, like the body of an if and the return after it.
+			if n := len(cov.Uncovered); n > 0 && cov.Uncovered[n-1].File == file && b.StartLine <= cov.Uncovered[n-1].EndLine+1 {
+				cov.Uncovered[n-1].EndLine = max(cov.Uncovered[n-1].EndLine, b.EndLine)
+				continue
+			}
+
+			cov.Uncovered = append(cov.Uncovered, Block{File: file, StartLine: b.StartLine, EndLine: b.EndLine})
+		}
+	}
+
+	if total == 0 {
+		return nil, fmt.Errorf("no statements to cover in %s", t.Module.Path)
+	}
+
+	cov.Percent = 100 * float64(covered) / float64(total)
+
+	return cov, nil
+}
+
+func (r *Runner) goBin() string {
+	if r.GoBin == "" {
+		return "go"
+	}
+
+	return r.GoBin
+}
+
+// cgoEnabled reports whether go test builds packages with cgo. The go command disables cgo
+// when CGO_ENABLED=0 is set, and by default when no C compiler is found, go env reports both.
+func (r *Runner) cgoEnabled(ctx context.Context) bool {
+	cmd := exec.CommandContext(ctx, r.goBin(), "env", "CGO_ENABLED")
+	cmd.Dir = r.Dir
+
+	out, err := cmd.Output()
+
+	return err == nil && strings.TrimSpace(string(out)) == "1"
+}
+
+// timeout limits all runs of the exercise together, a stress run gets the timeout of a single run for each of its runs.
+func (r *Runner) timeout(t manifest.Target) time.Duration {
+	timeout := DefaultTimeout
+
+	switch {
+	case r.Timeout > 0:
+		timeout = r.Timeout
+	case t.Exercise.TimeoutDuration() > 0:
+		timeout = t.Exercise.TimeoutDuration()
+	}
+
+	if r.Stress > 0 {
+		timeout *= time.Duration(r.Stress)
+	}
+
+	return timeout
+}
+
+func (r *Runner) race(t manifest.Target) bool {
+	return r.Race || t.Exercise.Race || r.Stress > 0
+}
+
+func (r *Runner) runs(t manifest.Target) int {
+	if r.Stress > 0 {
+		return r.Stress
+	}
+
+	return max(t.Exercise.Count, 1)
+}
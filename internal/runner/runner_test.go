@@ -0,0 +1,345 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+func testTarget(tests ...string) manifest.Target {
+	return manifest.Target{
+		Module:   &manifest.Module{Name: "module", Path: "."},
+		Exercise: &manifest.Exercise{Name: "exercise", Tests: tests},
+	}
+}
+
+func TestArgs(t *testing.T) {
+	target := testTarget("TestA", "ExampleB")
+
+	r := &Runner{}
+	expected := []string{"test", "-count=1", "-timeout=2m0s", "-run", "^(TestA|ExampleB)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+
+	target.Exercise.Race = true
+	target.Module.Vet = "off"
+	expected = []string{"test", "-count=1", "-timeout=2m0s", "-race", "-vet=off", "-run", "^(TestA|ExampleB)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+
+	target.Exercise.Race = false
+	r.Race = true
+
+	if args := r.Args(target); !slices.Contains(args, "-race") {
+		t.Errorf("Expected runner to force race detector, got %q", args)
+	}
+
+	target.Exercise.Hidden = []string{"TestHidden"}
+	r = &Runner{}
+	expected = []string{"test", "-count=1", "-timeout=2m0s", "-vet=off", "-run", "^(TestA|ExampleB)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected hidden tests to be skipped without strict mode, got %q", args)
+	}
+
+	r.Strict = true
+	expected = []string{"test", "-count=1", "-timeout=2m0s", "-vet=off", "-tags=solutiontests", "-run", "^(TestA|ExampleB|TestHidden)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+
+	if !slices.Equal(target.Exercise.Tests, []string{"TestA", "ExampleB"}) {
+		t.Errorf("Expected exercise tests to stay unchanged, got %q", target.Exercise.Tests)
+	}
+
+	target.Exercise.Timeout = "30s"
+
+	if args := r.Args(target); !slices.Contains(args, "-timeout=30s") {
+		t.Errorf("Expected timeout from the manifest, got %q", args)
+	}
+
+	r.Timeout = 5 * time.Minute
+
+	if args := r.Args(target); !slices.Contains(args, "-timeout=5m0s") {
+		t.Errorf("Expected runner timeout to override the manifest, got %q", args)
+	}
+
+	r = &Runner{Overlay: "/tmp/overlay.json", FailFast: true}
+	expected = []string{"test", "-count=1", "-timeout=30s", "-overlay=/tmp/overlay.json", "-failfast", "-vet=off", "-run", "^(TestA|ExampleB)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+
+	target.Exercise.Count = 2
+	target.Exercise.Parallel = 8
+	target.Exercise.Shuffle = true
+	r = &Runner{}
+	expected = []string{"test", "-count=2", "-timeout=30s", "-parallel=8", "-shuffle=on", "-vet=off", "-run", "^(TestA|ExampleB)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected args to be %q, got %q", expected, args)
+	}
+
+	target.Exercise.Parallel = 0
+	target.Exercise.Shuffle = false
+	r = &Runner{Stress: 20}
+	expected = []string{"test", "-count=20", "-timeout=10m0s", "-race", "-shuffle=on", "-vet=off", "-run", "^(TestA|ExampleB)$", "."}
+
+	if args := r.Args(target); !slices.Equal(args, expected) {
+		t.Errorf("Expected stress to override the count, got %q", args)
+	}
+}
+
+func TestRun(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+
+	res, err := r.Run(context.Background(), testTarget("TestPass"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !res.Passed {
+		t.Errorf("Expected exercise to pass, got output:\n%s", res.Output)
+	}
+
+	res, err = r.Run(context.Background(), testTarget("TestPass", "TestFail"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.Passed {
+		t.Error("Expected exercise to fail")
+	}
+
+	if !strings.Contains(string(res.Output), "Expected to fail") {
+		t.Errorf("Expected output to contain test failure, got:\n%s", res.Output)
+	}
+}
+
+func TestRunStress(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module"), Stress: 4}
+
+	res, err := r.Run(context.Background(), testTarget("TestPass", "TestFlaky"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.Passed || res.Runs != 4 || !res.Race {
+		t.Errorf("Expected 4 failing runs with the race detector, got %+v", res)
+	}
+
+	if failed := res.FailedRuns(); !maps.Equal(failed, map[string]int{"TestFlaky": 2}) {
+		t.Errorf("Expected TestFlaky to fail 2 runs, got %v", failed)
+	}
+
+	if !res.Flaky() {
+		t.Error("Expected the exercise to be flaky")
+	}
+
+	res, err = r.Run(context.Background(), testTarget("TestFail"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.Flaky() {
+		t.Errorf("Expected a test failing every run not to be flaky, got %v", res.FailedRuns())
+	}
+}
+
+func TestStream(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+
+	var out bytes.Buffer
+
+	res, err := r.Stream(context.Background(), testTarget("TestPass", "TestFail"), &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Expected to fail") {
+		t.Errorf("Expected output to be streamed, got:\n%s", out.String())
+	}
+
+	if !bytes.Equal(res.Output, out.Bytes()) {
+		t.Errorf("Expected streamed output to match the result, got:\n%s\nand:\n%s", out.Bytes(), res.Output)
+	}
+}
+
+func TestRunStrict(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+	target := testTarget("TestPass")
+	target.Exercise.Hidden = []string{"TestHidden"}
+
+	res, err := r.Run(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !res.Passed {
+		t.Errorf("Expected hidden test not to be built without strict mode, got output:\n%s", res.Output)
+	}
+
+	r.Strict = true
+
+	res, err = r.Run(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !slices.Equal(res.FailedTests, []string{"TestHidden"}) {
+		t.Errorf("Expected hidden test to fail in strict mode, got output:\n%s", res.Output)
+	}
+}
+
+func TestRunGraded(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+
+	res, err := r.Run(context.Background(), testTarget("TestPass", "TestGraded"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []grader.Assertion{{Test: "TestGraded", Name: "answer", Message: "expected 42, got 0", Hint: "think"}}
+	if !slices.Equal(res.Assertions, expected) {
+		t.Errorf("Expected assertions %+v, got %+v", expected, res.Assertions)
+	}
+
+	if !slices.Equal(res.FailedTests, []string{"TestGraded", "TestGraded/sub"}) {
+		t.Errorf("Expected failed tests from the output, got %q", res.FailedTests)
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+
+	res, err := r.Run(context.Background(), testTarget("TestPanic", "TestPass"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.Passed || !res.Panicked || res.TimedOut {
+		t.Errorf("Expected exercise to fail with a panic, got output:\n%s", res.Output)
+	}
+
+	if !slices.Equal(res.FailedTests, []string{"TestPanic"}) {
+		t.Errorf("Expected panicked test to be reported, got %q", res.FailedTests)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module"), Timeout: time.Second}
+
+	start := time.Now()
+
+	res, err := r.Run(context.Background(), testTarget("TestPass", "TestHang"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if time.Since(start) > time.Minute {
+		t.Errorf("Expected run to stop after the timeout, took %s", time.Since(start))
+	}
+
+	if res.Passed || !res.TimedOut || res.Panicked {
+		t.Errorf("Expected exercise to time out, got output:\n%s", res.Output)
+	}
+
+	if !slices.Equal(res.FailedTests, []string{"TestHang"}) {
+		t.Errorf("Expected hanging test to be reported, got %q", res.FailedTests)
+	}
+}
+
+func TestRunMissingGo(t *testing.T) {
+	r := &Runner{GoBin: "go-missing-binary", Dir: t.TempDir()}
+
+	if _, err := r.Run(context.Background(), testTarget("TestPass")); err == nil {
+		t.Error("Expected error when go binary is missing")
+	}
+}
+
+func TestRunCgoDisabled(t *testing.T) {
+	t.Setenv("CGO_ENABLED", "0")
+
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+	target := testTarget("TestPass")
+	target.Module.Cgo = true
+
+	var out bytes.Buffer
+
+	res, err := r.Stream(context.Background(), target, &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if res.Passed || res.Skipped != "cgo is disabled" {
+		t.Errorf("Expected exercise to be skipped, got passed %t and skipped %q", res.Passed, res.Skipped)
+	}
+
+	if !strings.Contains(out.String(), "CGO_ENABLED=1") {
+		t.Errorf("Expected output to explain how to enable cgo, got %q", out.String())
+	}
+
+	target.Module.Cgo = false
+
+	if res, err := r.Run(context.Background(), target); err != nil || !res.Passed || res.Skipped != "" {
+		t.Errorf("Expected exercises without cgo to run, got %+v and error %v", res, err)
+	}
+}
+
+func TestRunCoverage(t *testing.T) {
+	r := &Runner{Dir: filepath.Join("testdata", "module")}
+	target := testTarget("TestSign")
+	target.Exercise.Coverage = 50
+	target.Exercise.CoverFiles = []string{"sign.go"}
+
+	res, err := r.Run(context.Background(), target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Block{{File: "sign.go", StartLine: 6, EndLine: 7}, {File: "sign.go", StartLine: 10, EndLine: 11}}
+	if !res.Passed || res.Coverage == nil || res.Coverage.Percent != 60 || !slices.Equal(res.Coverage.Uncovered, expected) {
+		t.Errorf("Expected 60%% coverage of sign.go to pass with uncovered %+v, got %+v", expected, res.Coverage)
+	}
+
+	target.Exercise.Coverage = 80
+
+	if res, err := r.Run(context.Background(), target); err != nil || res.Passed || res.Coverage.Met() {
+		t.Errorf("Expected passing tests to fail below the required coverage, got %+v, %v", res.Coverage, err)
+	}
+
+	// Without cover files, abs.go that no test runs counts too.
+	target.Exercise.CoverFiles = nil
+	target.Exercise.Coverage = 50
+
+	if res, err := r.Run(context.Background(), target); err != nil || res.Passed || res.Coverage.Percent != 37.5 {
+		t.Errorf("Expected 37.5%% coverage of all files, got %+v, %v", res.Coverage, err)
+	}
+
+	r.SkipCoverage = true
+
+	if res, err := r.Run(context.Background(), target); err != nil || !res.Passed || res.Coverage != nil {
+		t.Errorf("Expected coverage not to be measured, got %+v, %v", res.Coverage, err)
+	}
+
+	r.SkipCoverage = false
+	target.Exercise.Tests = []string{"TestSign", "TestFail"}
+
+	if res, err := r.Run(context.Background(), target); err != nil || res.Passed || res.Coverage != nil {
+		t.Errorf("Expected coverage not to be measured when tests fail, got %+v, %v", res.Coverage, err)
+	}
+}
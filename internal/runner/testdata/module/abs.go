@@ -0,0 +1,10 @@
+package module
+
+// Abs returns the absolute value of the number.
+func Abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
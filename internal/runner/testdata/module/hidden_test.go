@@ -0,0 +1,9 @@
+//go:build solutiontests
+
+package module
+
+import "testing"
+
+func TestHidden(t *testing.T) {
+	t.Error("Expected hidden test to fail")
+}
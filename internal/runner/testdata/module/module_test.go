@@ -0,0 +1,58 @@
+package module
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPass(t *testing.T) {}
+
+func TestFail(t *testing.T) {
+	t.Error("Expected to fail")
+}
+
+// TestGraded writes a failed assertion to the report like the grader package does.
+func TestGraded(t *testing.T) {
+	if path := os.Getenv("WORKSHOP_GRADER_REPORT"); path != "" {
+		line := `{"test":"TestGraded","name":"answer","message":"expected 42, got 0","hint":"think"}` + "\n"
+		if err := os.WriteFile(path, []byte(line), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("sub", func(t *testing.T) {
+		t.Error("answer: expected 42, got 0")
+	})
+}
+
+func TestPanic(t *testing.T) {
+	var m map[string]int
+	m["answer"] = 42
+}
+
+func TestHang(t *testing.T) {
+	time.Sleep(time.Hour)
+}
+
+// TestSign covers only positive numbers.
+func TestSign(t *testing.T) {
+	if Sign(5) != 1 {
+		t.Error("Expected 1")
+	}
+}
+
+// flakyRuns counts runs of TestFlaky, with -count it fails every second run.
+var flakyRuns int
+
+func TestFlaky(t *testing.T) {
+	if flakyRuns++; flakyRuns%2 == 0 {
+		t.Error("Expected to fail every second run")
+	}
+}
+
+func BenchmarkAbs(b *testing.B) {
+	for i := range b.N {
+		Abs(-i)
+	}
+}
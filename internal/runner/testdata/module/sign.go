@@ -0,0 +1,14 @@
+package module
+
+// Sign returns -1, 0, or 1 for negative numbers, zero, and positive numbers.
+func Sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return 1
+}
@@ -0,0 +1,143 @@
+// Package scaffold generates a new workshop module from templates.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).ParseFS(templatesFS, "templates/*.tmpl"))
+
+var (
+	moduleNameRe   = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	exerciseNameRe = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+)
+
+// Module is a module to generate.
+type Module struct {
+	// Name is the name of the module, its directory, and its Go package.
+	Name string
+
+	// Title is the human readable name of the module.
+	Title string
+
+	// ImportPath is the path of the Go module of the repository, generated tests import the grader from it.
+	ImportPath string
+
+	Exercises []Exercise
+}
+
+// Exercise is an exercise of the generated module.
+type Exercise struct {
+	// Name is the exercise name in the manifest, like "fan-in".
+	Name string
+
+	// Title is the name in documentation, like "Fan In".
+	Title string
+
+	// Func is the name of the stub function, tests are named after it, like "FanIn".
+	Func string
+}
+
+// New describes a module with the exercises, names are validated and the rest is derived from them.
+func New(name, title, importPath string, exercises []string) (*Module, error) {
+	if !moduleNameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid module name %q, use lowercase letters and digits, like a Go package", name)
+	}
+
+	if len(exercises) == 0 {
+		return nil, fmt.Errorf("module %s: at least one exercise is required", name)
+	}
+
+	if title == "" {
+		title = strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	m := &Module{Name: name, Title: title, ImportPath: importPath}
+	seen := make(map[string]bool)
+
+	for _, ex := range exercises {
+		if !exerciseNameRe.MatchString(ex) {
+			return nil, fmt.Errorf("invalid exercise name %q, use lowercase words separated by dashes", ex)
+		}
+
+		if seen[ex] {
+			return nil, fmt.Errorf("duplicate exercise %s", ex)
+		}
+
+		seen[ex] = true
+
+		words := strings.Split(ex, "-")
+		for i, w := range words {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+
+		m.Exercises = append(m.Exercises, Exercise{
+			Name:  ex,
+			Title: strings.Join(words, " "),
+			Func:  strings.Join(words, ""),
+		})
+	}
+
+	return m, nil
+}
+
+// Files renders the files of the module, keys are paths relative to the module directory.
+func (m *Module) Files() (map[string][]byte, error) {
+	files := map[string]string{
+		"doc.go":            "doc.go.tmpl",
+		m.Name + "_test.go": "exercises_test.go.tmpl",
+		"README.md":         "README.md.tmpl",
+		"HINTS.md":          "HINTS.md.tmpl",
+	}
+
+	out := make(map[string][]byte, len(files))
+
+	for path, name := range files {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, name, m); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", path, err)
+		}
+
+		data := buf.Bytes()
+
+		if strings.HasSuffix(path, ".go") {
+			formatted, err := format.Source(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to format %s: %w", path, err)
+			}
+
+			data = formatted
+		}
+
+		out[path] = data
+	}
+
+	return out, nil
+}
+
+// Manifest returns the manifest entry of the module.
+func (m *Module) Manifest() manifest.Module {
+	mod := manifest.Module{Name: m.Name, Title: m.Title, Path: "./" + m.Name}
+
+	for _, ex := range m.Exercises {
+		mod.Exercises = append(mod.Exercises, manifest.Exercise{
+			Name:  ex.Name,
+			Tests: []string{"Test" + ex.Func, "Example" + ex.Func},
+		})
+	}
+
+	return mod
+}
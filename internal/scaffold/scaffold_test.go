@@ -0,0 +1,137 @@
+package scaffold
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	m, err := New("channels", "", "example.com/workshops", []string{"fan-in", "pipeline2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if m.Title != "Channels" {
+		t.Errorf("Expected title derived from the name, got %q", m.Title)
+	}
+
+	expected := []Exercise{
+		{Name: "fan-in", Title: "Fan In", Func: "FanIn"},
+		{Name: "pipeline2", Title: "Pipeline2", Func: "Pipeline2"},
+	}
+
+	if !slices.Equal(m.Exercises, expected) {
+		t.Errorf("Expected exercises %+v, got %+v", expected, m.Exercises)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		module    string
+		exercises []string
+	}{
+		{name: "uppercase module", module: "Channels", exercises: []string{"a"}},
+		{name: "dash in module", module: "my-module", exercises: []string{"a"}},
+		{name: "no exercises", module: "channels"},
+		{name: "invalid exercise", module: "channels", exercises: []string{"Fan In"}},
+		{name: "duplicate exercise", module: "channels", exercises: []string{"a", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.module, "", "example.com/workshops", tt.exercises); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	m, err := New("channels", "Channel Patterns", "example.com/workshops", []string{"fan-in", "pipeline"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := m.Files()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+
+	slices.Sort(paths)
+
+	if expected := []string{"HINTS.md", "README.md", "channels_test.go", "doc.go"}; !slices.Equal(paths, expected) {
+		t.Fatalf("Expected files %q, got %q", expected, paths)
+	}
+
+	fset := token.NewFileSet()
+
+	test, err := parser.ParseFile(fset, "channels_test.go", files["channels_test.go"], parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Expected generated tests to parse, got %v:\n%s", err, files["channels_test.go"])
+	}
+
+	var funcs []string
+
+	for _, decl := range test.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			funcs = append(funcs, fn.Name.Name)
+		}
+	}
+
+	slices.Sort(funcs)
+
+	expected := []string{"ExampleFanIn", "ExamplePipeline", "FanIn", "Pipeline", "TestFanIn", "TestPipeline"}
+	if !slices.Equal(funcs, expected) {
+		t.Errorf("Expected functions %q, got %q", expected, funcs)
+	}
+
+	if !strings.Contains(string(files["channels_test.go"]), `"example.com/workshops/grader"`) {
+		t.Errorf("Expected tests to import the grader of the repository, got:\n%s", files["channels_test.go"])
+	}
+
+	doc, err := parser.ParseFile(fset, "doc.go", files["doc.go"], parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Expected doc.go to parse, got %v", err)
+	}
+
+	if doc.Name.Name != "channels" || !strings.HasPrefix(doc.Doc.Text(), "Package channels is the Channel Patterns workshop.") {
+		t.Errorf("Expected package doc comment, got package %s with %q", doc.Name.Name, doc.Doc.Text())
+	}
+
+	for _, section := range []string{"# Go Workshop: Channel Patterns", "### 1. Fan In", "### 2. Pipeline"} {
+		if !strings.Contains(string(files["README.md"]), section) {
+			t.Errorf("Expected README to contain %q, got:\n%s", section, files["README.md"])
+		}
+	}
+
+	if !strings.Contains(string(files["HINTS.md"]), "## Pipeline") {
+		t.Errorf("Expected hints for every exercise, got:\n%s", files["HINTS.md"])
+	}
+}
+
+func TestManifest(t *testing.T) {
+	m, err := New("channels", "", "example.com/workshops", []string{"fan-in"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod := m.Manifest()
+
+	if mod.Name != "channels" || mod.Path != "./channels" || len(mod.Exercises) != 1 {
+		t.Fatalf("Unexpected manifest entry %+v", mod)
+	}
+
+	if ex := mod.Exercises[0]; ex.Name != "fan-in" || !slices.Equal(ex.Tests, []string{"TestFanIn", "ExampleFanIn"}) {
+		t.Errorf("Expected exercise with generated tests, got %+v", ex)
+	}
+}
@@ -0,0 +1,266 @@
+// Package session records exercise runs, so instructors can replay how a learner approached an exercise.
+package session
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ksysoev/go-workshops/internal/diff"
+)
+
+const (
+	logFile    = "session.jsonl"
+	objectsDir = "objects"
+)
+
+// Entry is a single recorded run.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Command []string  `json:"command"`
+
+	// Files maps paths of exercise files at the time of the run to hashes of their contents,
+	// contents are stored once in the objects directory of the session.
+	Files map[string]string `json:"files,omitempty"`
+
+	Results []Result `json:"results"`
+}
+
+// FileDiff is a change of a file between two runs.
+type FileDiff struct {
+	Path string `json:"path"`
+	Diff string `json:"diff"`
+}
+
+// Result is the outcome of an exercise in the recorded run.
+type Result struct {
+	Exercise string        `json:"exercise"`
+	Passed   bool          `json:"passed"`
+	Output   string        `json:"output"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Recorder appends runs to the session log stored in a directory.
+// Contents of files are stored content-addressed by their SHA-256, so a file that didn't change
+// between runs takes no extra space, and any run can be compared with any other.
+type Recorder struct {
+	dir string
+}
+
+// NewRecorder creates a recorder that stores the session in dir.
+func NewRecorder(dir string) *Recorder {
+	return &Recorder{dir: dir}
+}
+
+// Record stores the entry along with snapshots of the files, paths are relative to root.
+func (r *Recorder) Record(e Entry, root string, files []string) error {
+	e.Files = make(map[string]string, len(files))
+
+	for _, path := range files {
+		data, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		hash, err := r.store(data)
+		if err != nil {
+			return err
+		}
+
+		e.Files[path] = hash
+	}
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	return r.append(e)
+}
+
+// Diffs returns changes of files between two runs, only for paths accepted by include.
+// Without from, the run is a baseline without diffs. A file missing in one of the runs was added or removed
+// when that run snapshotted its module, the top level directory, and it's diffed against an empty file.
+// Otherwise the runs covered different modules, and the file is not compared at all.
+func (r *Recorder) Diffs(from *Entry, to Entry, include func(path string) bool) ([]FileDiff, error) {
+	if from == nil {
+		return nil, nil
+	}
+
+	var diffs []FileDiff
+
+	paths := make([]string, 0, len(to.Files))
+
+	for path := range to.Files {
+		paths = append(paths, path)
+	}
+
+	for path := range from.Files {
+		if _, ok := to.Files[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+
+	slices.Sort(paths)
+
+	fromModules, toModules := modules(from.Files), modules(to.Files)
+
+	for _, path := range paths {
+		if !include(path) || from.Files[path] == to.Files[path] {
+			continue
+		}
+
+		if !fromModules[module(path)] || !toModules[module(path)] {
+			continue
+		}
+
+		old, err := r.object(from.Files[path])
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := r.object(to.Files[path])
+		if err != nil {
+			return nil, err
+		}
+
+		if d := diff.Unified("a/"+path, "b/"+path, old, content); d != "" {
+			diffs = append(diffs, FileDiff{Path: path, Diff: d})
+		}
+	}
+
+	return diffs, nil
+}
+
+// modules returns the set of modules with snapshots among the files.
+func modules(files map[string]string) map[string]bool {
+	set := make(map[string]bool)
+
+	for path := range files {
+		set[module(path)] = true
+	}
+
+	return set
+}
+
+// module returns the top level directory of the path, the module the file belongs to.
+func module(path string) string {
+	dir, _, _ := strings.Cut(path, "/")
+	return dir
+}
+
+// store saves the content in the objects directory unless it's already there and returns its hash.
+func (r *Recorder) store(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := r.objectPath(hash)
+
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	// The object appears under its name only when it's completely written.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	return hash, nil
+}
+
+// object returns the content stored under the hash, empty hash is an empty file.
+func (r *Recorder) object(hash string) (string, error) {
+	if hash == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(r.objectPath(hash))
+	if err != nil {
+		return "", fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// objectPath spreads objects over subdirectories by the first byte of the hash, like git does.
+func (r *Recorder) objectPath(hash string) string {
+	return filepath.Join(r.dir, objectsDir, hash[:2], hash[2:])
+}
+
+func (r *Recorder) append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode session entry: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(r.dir, logFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open session log: %w", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write session log: %w", err)
+	}
+
+	return f.Close()
+}
+
+// Load reads all recorded entries from the session stored in dir.
+func Load(dir string) ([]Entry, error) {
+	f, err := os.Open(filepath.Join(dir, logFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.New("no recorded session, runs are recorded by workshop verify")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open session log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse session entry %d: %w", len(entries)+1, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session log: %w", err)
+	}
+
+	return entries, nil
+}
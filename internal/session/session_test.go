@@ -0,0 +1,135 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, ".workshop", "session")
+	r := NewRecorder(dir)
+
+	write := func(path, content string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filepath.Join(root, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("concurrency/a_test.go", "package concurrency\n")
+
+	first := Entry{
+		Time:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Command: []string{"verify", "concurrency"},
+		Results: []Result{{Exercise: "concurrency/deadlock", Passed: false, Output: "FAIL", Duration: time.Second}},
+	}
+
+	if err := r.Record(first, root, []string{"concurrency/a_test.go"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	write("concurrency/a_test.go", "package concurrency\n\n// fixed\n")
+
+	second := Entry{
+		Time:    time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC),
+		Command: []string{"verify", "concurrency"},
+		Results: []Result{{Exercise: "concurrency/deadlock", Passed: true, Output: "ok"}},
+	}
+
+	write("concurrency/b_test.go", "package concurrency\n")
+
+	if err := r.Record(second, root, []string{"concurrency/a_test.go", "concurrency/b_test.go"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	entries, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if !entries[0].Time.Equal(first.Time) || entries[0].Results[0].Duration != time.Second {
+		t.Errorf("Expected first entry to be %+v, got %+v", first, entries[0])
+	}
+
+	all := func(string) bool { return true }
+
+	if diffs, err := r.Diffs(nil, entries[0], all); err != nil || len(diffs) != 0 {
+		t.Errorf("Expected first run to be a baseline without diffs, got %v, %v", diffs, err)
+	}
+
+	diffs, err := r.Diffs(&entries[0], entries[1], all)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(diffs) != 2 {
+		t.Fatalf("Expected second run to have 2 diffs, got %v", diffs)
+	}
+
+	if d := diffs[0]; d.Path != "concurrency/a_test.go" || !strings.Contains(d.Diff, "+// fixed") {
+		t.Errorf("Expected diff of concurrency/a_test.go with the fix, got %+v", d)
+	}
+
+	if d := diffs[1]; d.Path != "concurrency/b_test.go" || !strings.Contains(d.Diff, "+package concurrency") {
+		t.Errorf("Expected new file to be diffed against an empty one, got %+v", d)
+	}
+
+	diffs, err = r.Diffs(&entries[0], entries[1], func(path string) bool { return path == "concurrency/b_test.go" })
+	if err != nil || len(diffs) != 1 {
+		t.Errorf("Expected diffs to be filtered, got %v, %v", diffs, err)
+	}
+
+	if !entries[1].Results[0].Passed {
+		t.Error("Expected second run to pass")
+	}
+
+	objects, err := filepath.Glob(filepath.Join(dir, "objects", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first version of a_test.go and b_test.go have the same content, it's stored once.
+	if len(objects) != 2 {
+		t.Errorf("Expected contents to be stored once, got %q", objects)
+	}
+
+	// A run of another module has no snapshots of concurrency, its files were not removed.
+	write("errorhandling/c_test.go", "package errorhandling\n")
+
+	third := Entry{
+		Time:    time.Date(2024, 1, 1, 10, 10, 0, 0, time.UTC),
+		Command: []string{"verify", "errorhandling"},
+		Results: []Result{{Exercise: "errorhandling/wrapping", Passed: true, Output: "ok"}},
+	}
+
+	if err := r.Record(third, root, []string{"errorhandling/c_test.go"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if entries, err = Load(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if diffs, err := r.Diffs(&entries[1], entries[2], all); err != nil || len(diffs) != 0 {
+		t.Errorf("Expected no diffs between runs of different modules, got %v, %v", diffs, err)
+	}
+}
+
+func TestLoadWithoutSession(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no recorded session") {
+		t.Errorf("Expected error about missing session, got %v", err)
+	}
+}
@@ -0,0 +1,453 @@
+// Package tui is the interactive terminal UI of the workshop runner.
+//
+// The UI shows modules and exercises in a tree with their status, explains the selected exercise
+// with its narrative from the content package, and streams go test output while exercises run.
+// It knows nothing about go test itself, exercises are executed by a Runner.
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/ksysoev/go-workshops/internal/content"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+// Runner executes exercises, it's implemented by runner.Runner.
+type Runner interface {
+	Stream(ctx context.Context, t manifest.Target, w io.Writer) (runner.Result, error)
+}
+
+// Status is the state of an exercise in the UI.
+type Status int
+
+const (
+	StatusNotRun Status = iota
+	StatusRunning
+	StatusPassed
+	StatusFailed
+)
+
+// Icon returns the symbol of the status shown next to exercise names.
+func (s Status) Icon() string {
+	switch s {
+	case StatusRunning:
+		return "…"
+	case StatusPassed:
+		return "✓"
+	case StatusFailed:
+		return "✗"
+	default:
+		return "·"
+	}
+}
+
+func (s Status) color() tcell.Color {
+	switch s {
+	case StatusRunning:
+		return tcell.ColorYellow
+	case StatusPassed:
+		return tcell.ColorGreen
+	case StatusFailed:
+		return tcell.ColorRed
+	default:
+		return tcell.ColorDefault
+	}
+}
+
+const help = " [::b]↑↓[::-] select  [::b]r[::-] run exercise  [::b]v[::-] verify module  " +
+	"[::b]h[::-] hints  [::b]tab[::-] scroll output  [::b]q[::-] quit"
+
+// exercise is the state of an exercise in the UI.
+type exercise struct {
+	target manifest.Target
+	node   *tview.TreeNode
+	status Status
+	result runner.Result
+	output bytes.Buffer
+	hints  bool
+}
+
+// module groups exercises of a module in the tree.
+type module struct {
+	module    *manifest.Module
+	node      *tview.TreeNode
+	exercises []*exercise
+}
+
+// App is the terminal UI. All its state is changed only by the tview event loop,
+// runs happen in a separate goroutine and queue their updates to the loop.
+type App struct {
+	manifest *manifest.Manifest
+	runner   Runner
+	lang     string
+
+	app     *tview.Application
+	tree    *tview.TreeView
+	details *tview.TextView
+	output  *tview.TextView
+	footer  *tview.TextView
+
+	modules []*module
+	running bool
+	ctx     context.Context
+}
+
+// New creates the UI for the targets, they are grouped by modules in the order of the manifest.
+// Exercises are explained in the language when they are translated.
+func New(m *manifest.Manifest, targets []manifest.Target, r Runner, lang string) *App {
+	a := &App{
+		manifest: m,
+		runner:   r,
+		lang:     lang,
+		app:      tview.NewApplication(),
+		tree:     tview.NewTreeView(),
+		details:  tview.NewTextView().SetDynamicColors(true).SetWordWrap(true),
+		output:   tview.NewTextView().SetWrap(false),
+		footer:   tview.NewTextView().SetDynamicColors(true).SetText(help),
+	}
+
+	root := tview.NewTreeNode("")
+
+	for _, t := range targets {
+		if len(a.modules) == 0 || a.modules[len(a.modules)-1].module != t.Module {
+			mod := &module{module: t.Module, node: tview.NewTreeNode("")}
+			mod.node.SetReference(mod)
+			root.AddChild(mod.node)
+			a.modules = append(a.modules, mod)
+		}
+
+		mod := a.modules[len(a.modules)-1]
+		ex := &exercise{target: t, node: tview.NewTreeNode("")}
+		ex.node.SetReference(ex)
+		mod.node.AddChild(ex.node)
+		mod.exercises = append(mod.exercises, ex)
+	}
+
+	for _, mod := range a.modules {
+		a.refreshModule(mod)
+	}
+
+	a.tree.SetRoot(root).SetTopLevel(1)
+	a.tree.SetBorder(true).SetTitle(" Exercises ")
+	a.tree.SetChangedFunc(func(*tview.TreeNode) { a.show() })
+	a.tree.SetSelectedFunc(func(*tview.TreeNode) { a.runSelected(false) })
+
+	if len(a.modules) > 0 {
+		a.tree.SetCurrentNode(a.modules[0].exercises[0].node)
+	}
+
+	a.details.SetBorder(true).SetTitle(" Details ")
+	a.output.SetBorder(true).SetTitle(" Output ")
+
+	panes := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(a.details, 0, 1, false).
+		AddItem(a.output, 0, 2, false)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().AddItem(a.tree, 0, 1, true).AddItem(panes, 0, 2, false), 0, 1, true).
+		AddItem(a.footer, 1, 0, false)
+
+	a.app.SetRoot(layout, true).SetInputCapture(a.handleKey)
+	a.show()
+
+	return a
+}
+
+// Run shows the UI until the learner quits or ctx is canceled.
+func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	a.ctx = ctx
+
+	go func() {
+		<-ctx.Done()
+		a.app.Stop()
+	}()
+
+	return a.app.Run()
+}
+
+func (a *App) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case event.Key() == tcell.KeyTab:
+		if a.tree.HasFocus() {
+			a.app.SetFocus(a.output)
+		} else {
+			a.app.SetFocus(a.tree)
+		}
+	case event.Rune() == 'q':
+		a.app.Stop()
+	case event.Rune() == 'r':
+		a.runSelected(false)
+	case event.Rune() == 'v':
+		a.runSelected(true)
+	case event.Rune() == 'h':
+		if ex, ok := a.tree.GetCurrentNode().GetReference().(*exercise); ok {
+			ex.hints = !ex.hints
+			a.show()
+		}
+	default:
+		return event
+	}
+
+	return nil
+}
+
+// runSelected runs the selected exercise, or all exercises of the selected module with whole set.
+func (a *App) runSelected(whole bool) {
+	var exercises []*exercise
+
+	switch ref := a.tree.GetCurrentNode().GetReference().(type) {
+	case *module:
+		exercises = ref.exercises
+	case *exercise:
+		exercises = []*exercise{ref}
+
+		if whole {
+			exercises = a.moduleOf(ref).exercises
+		}
+	}
+
+	if a.running {
+		a.footer.SetText(" Wait for the current run to finish")
+		return
+	}
+
+	if len(exercises) == 0 {
+		return
+	}
+
+	a.running = true
+	a.footer.SetText(" Running...")
+
+	go a.runAll(exercises)
+}
+
+// runAll runs the exercises one by one, it's called outside of the event loop.
+func (a *App) runAll(exercises []*exercise) {
+	for _, ex := range exercises {
+		if a.ctx.Err() != nil {
+			break
+		}
+
+		a.app.QueueUpdateDraw(func() {
+			ex.status = StatusRunning
+			ex.output.Reset()
+			a.refresh(ex)
+		})
+
+		res, err := a.runner.Stream(a.ctx, ex.target, writerFunc(func(p []byte) {
+			chunk := bytes.Clone(p)
+
+			a.app.QueueUpdateDraw(func() {
+				ex.output.Write(chunk)
+
+				if a.selected() == ex {
+					a.output.Write(chunk)
+					a.output.ScrollToEnd()
+				}
+			})
+		}))
+
+		a.app.QueueUpdateDraw(func() {
+			ex.result = res
+			ex.status = StatusPassed
+
+			switch {
+			case err == nil && res.Skipped != "":
+				ex.status = StatusNotRun
+			case err != nil || !res.Passed:
+				ex.status = StatusFailed
+			}
+
+			if err != nil {
+				fmt.Fprintf(&ex.output, "\n%v\n", err)
+			}
+
+			a.refresh(ex)
+		})
+	}
+
+	a.app.QueueUpdateDraw(func() {
+		a.running = false
+		a.footer.SetText(help)
+	})
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	f(p)
+	return len(p), nil
+}
+
+func (a *App) selected() *exercise {
+	ex, _ := a.tree.GetCurrentNode().GetReference().(*exercise)
+	return ex
+}
+
+func (a *App) moduleOf(ex *exercise) *module {
+	for _, mod := range a.modules {
+		if slices.Contains(mod.exercises, ex) {
+			return mod
+		}
+	}
+
+	return nil
+}
+
+// refresh updates the tree and panes after the exercise has changed.
+func (a *App) refresh(ex *exercise) {
+	a.refreshModule(a.moduleOf(ex))
+
+	if a.selected() == ex {
+		a.show()
+	}
+}
+
+func (a *App) refreshModule(mod *module) {
+	passed := 0
+
+	for _, ex := range mod.exercises {
+		if ex.status == StatusPassed {
+			passed++
+		}
+
+		ex.node.SetText(ex.status.Icon() + " " + ex.target.Exercise.Name).SetColor(ex.status.color())
+	}
+
+	mod.node.SetText(fmt.Sprintf("%s (%d/%d)", mod.module.Title, passed, len(mod.exercises)))
+}
+
+// show renders the details and the output of the selected node.
+func (a *App) show() {
+	a.details.Clear()
+	a.output.Clear()
+
+	switch ref := a.tree.GetCurrentNode().GetReference().(type) {
+	case *module:
+		a.showModule(ref)
+	case *exercise:
+		a.showExercise(ref)
+		a.output.Write(ref.output.Bytes())
+		a.output.ScrollToEnd()
+	}
+
+	a.details.ScrollToBeginning()
+}
+
+func (a *App) showModule(mod *module) {
+	fmt.Fprintf(a.details, "[::b]%s[::-]\n%s\n\n", tview.Escape(mod.module.Title), tview.Escape(mod.module.Path))
+
+	for _, ex := range mod.exercises {
+		fmt.Fprintf(a.details, "%s %s\n", ex.status.Icon(), tview.Escape(ex.target.Exercise.Name))
+	}
+
+	fmt.Fprint(a.details, "\nPress r or v to verify all exercises of the module.")
+}
+
+func (a *App) showExercise(ex *exercise) {
+	t := ex.target
+
+	fmt.Fprintf(a.details, "[::b]%s[::-]  %s\n", tview.Escape(t.ID()), statusText(ex))
+	fmt.Fprintf(a.details, "Tests: %s\n", strings.Join(t.Exercise.Tests, ", "))
+	fmt.Fprintf(a.details, "Level: %s\n", t.Exercise.Difficulty())
+
+	if t.Exercise.Race {
+		fmt.Fprintln(a.details, "Runs with the race detector.")
+	}
+
+	if ex.hints {
+		fmt.Fprintf(a.details, "\n[yellow::b]Hints[-::-]\n%s\n", tview.Escape(a.hints(ex)))
+	}
+
+	n, err := content.Load(a.manifest.Dir, t, a.lang)
+	if err != nil {
+		n.Body = err.Error()
+	}
+
+	if n.Source != content.SourceComments {
+		fmt.Fprintf(a.details, "\n[::b]%s[::-]\n", tview.Escape(n.Title))
+	}
+
+	fmt.Fprintf(a.details, "\n%s\n", tview.Escape(n.Body))
+}
+
+func statusText(ex *exercise) string {
+	switch {
+	case ex.status == StatusFailed && ex.result.TimedOut:
+		return "[red]failed, timed out[-]"
+	case ex.status == StatusFailed && ex.result.Panicked:
+		return "[red]failed, panic[-]"
+	case ex.status == StatusFailed && ex.result.Flaky():
+		return "[red]failed, flaky[-]"
+	case ex.status == StatusFailed && ex.result.Bench != "":
+		return "[red]failed, " + tview.Escape(ex.result.Bench) + "[-]"
+	case ex.status == StatusFailed && ex.result.Coverage != nil && !ex.result.Coverage.Met():
+		return fmt.Sprintf("[red]failed, coverage %.1f%% of %g%%[-]", ex.result.Coverage.Percent, ex.result.Coverage.Required)
+	case ex.status == StatusFailed:
+		return "[red]failed[-]"
+	case ex.status == StatusPassed:
+		return "[green]passed[-]"
+	case ex.status == StatusRunning:
+		return "[yellow]running[-]"
+	case ex.result.Skipped != "":
+		return "[yellow]skipped, " + tview.Escape(ex.result.Skipped) + "[-]"
+	default:
+		return "not run yet"
+	}
+}
+
+// hints returns hints of failed graded assertions of the last run and the section of the exercise in HINTS.md of the module.
+func (a *App) hints(ex *exercise) string {
+	var hints []string
+
+	for _, assertion := range ex.result.Assertions {
+		if hint := "- " + assertion.Hint; assertion.Hint != "" && !slices.Contains(hints, hint) {
+			hints = append(hints, hint)
+		}
+	}
+
+	if section := hintsSection(filepath.Join(a.manifest.Dir, ex.target.Module.Path, "HINTS.md"), ex.target.Exercise.Name); section != "" {
+		hints = append(hints, section)
+	}
+
+	if len(hints) == 0 {
+		return "No hints yet. Run the exercise, failed checks of graded tests come with hints."
+	}
+
+	return strings.Join(hints, "\n")
+}
+
+// hintsSection returns the section of the hints file with the heading matching the exercise name,
+// like "## Fan In" for fan-in.
+func hintsSection(path, name string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	title := strings.ReplaceAll(name, "-", " ")
+
+	for _, section := range strings.Split(string(data), "\n## ")[1:] {
+		heading, body, _ := strings.Cut(section, "\n")
+		if strings.EqualFold(strings.TrimSpace(heading), title) {
+			return strings.TrimSpace(body)
+		}
+	}
+
+	return ""
+}
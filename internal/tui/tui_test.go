@@ -0,0 +1,215 @@
+package tui
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/ksysoev/go-workshops/grader"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+	"github.com/ksysoev/go-workshops/internal/runner"
+)
+
+const testFile = `package mod
+
+import "testing"
+
+// 1. First exercise.
+// Explains the first exercise.
+
+// First is a stub.
+func First() int {
+	// TODO: not a part of the doc
+	return 0
+}
+
+// TestFirst checks First.
+func TestFirst(t *testing.T) {}
+
+// 2. Second exercise.
+
+func TestSecond(t *testing.T) {}
+
+func ExampleSecond() {
+	// Output:
+}
+`
+
+func testManifest(t *testing.T) *manifest.Manifest {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "mod"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"mod_test.go":          testFile,
+		"HINTS.md":             "# Hints\n\n## First\n\nLook at the stub.\n\n## Second\n\nRead the example.\n",
+		"content/ru/second.md": "# Второе\n\nПрочитайте пример.\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, "mod", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &manifest.Manifest{
+		Dir: dir,
+		Modules: []manifest.Module{{
+			Name:  "mod",
+			Title: "Module",
+			Path:  "./mod",
+			Exercises: []manifest.Exercise{
+				{Name: "first", Tests: []string{"TestFirst"}},
+				{Name: "second", Tests: []string{"TestSecond", "ExampleSecond"}},
+			},
+		}},
+	}
+}
+
+// fakeRunner passes the first exercise and fails others with a graded assertion.
+type fakeRunner struct{}
+
+func (fakeRunner) Stream(_ context.Context, t manifest.Target, w io.Writer) (runner.Result, error) {
+	io.WriteString(w, "=== RUN "+t.Exercise.Tests[0]+"\n")
+
+	if t.Exercise.Name == "first" {
+		return runner.Result{Target: t, Passed: true}, nil
+	}
+
+	return runner.Result{
+		Target:     t,
+		TimedOut:   true,
+		Assertions: []grader.Assertion{{Test: t.Exercise.Tests[0], Name: "a", Message: "failed", Hint: "try harder"}},
+	}, nil
+}
+
+func TestHintsSection(t *testing.T) {
+	path := filepath.Join(testManifest(t).Dir, "mod", "HINTS.md")
+
+	if section := hintsSection(path, "second"); section != "Read the example." {
+		t.Errorf("Expected hints of the exercise, got %q", section)
+	}
+
+	if section := hintsSection(path, "third"); section != "" {
+		t.Errorf("Expected no hints for unknown exercise, got %q", section)
+	}
+}
+
+func TestApp(t *testing.T) {
+	m := testManifest(t)
+
+	targets, err := m.Select()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	screen.SetSize(120, 40)
+
+	a := New(m, targets, fakeRunner{}, "ru")
+	a.app.SetScreen(screen)
+
+	done := make(chan error, 1)
+
+	go func() { done <- a.Run(context.Background()) }()
+
+	// inspect runs fn in the event loop, where the state of the app may be read.
+	inspect := func(fn func()) {
+		called := make(chan struct{})
+		a.app.QueueUpdate(func() {
+			fn()
+			close(called)
+		})
+		<-called
+	}
+
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			ok := false
+			inspect(func() { ok = cond() })
+
+			if ok {
+				return
+			}
+		}
+
+		t.Fatalf("Expected %s", what)
+	}
+
+	first, second := a.modules[0].exercises[0], a.modules[0].exercises[1]
+
+	inspect(func() {
+		if !strings.Contains(a.details.GetText(true), "Explains the first exercise.") {
+			t.Errorf("Expected details of the selected exercise, got:\n%s", a.details.GetText(true))
+		}
+	})
+
+	screen.InjectKey(tcell.KeyRune, 'r', tcell.ModNone)
+	waitFor("first exercise to pass", func() bool { return first.status == StatusPassed && !a.running })
+
+	inspect(func() {
+		if second.status != StatusNotRun {
+			t.Errorf("Expected only the selected exercise to run, got status %d", second.status)
+		}
+
+		if a.output.GetText(true) != "=== RUN TestFirst\n" {
+			t.Errorf("Expected output of the selected exercise, got %q", a.output.GetText(true))
+		}
+	})
+
+	screen.InjectKey(tcell.KeyRune, 'v', tcell.ModNone)
+	waitFor("module to be verified", func() bool { return second.status == StatusFailed && !a.running })
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'h', tcell.ModNone)
+	waitFor("hints of the second exercise", func() bool { return a.selected() == second && second.hints })
+
+	inspect(func() {
+		details := a.details.GetText(true)
+
+		for _, s := range []string{"failed, timed out", "- try harder", "Read the example.", "Второе", "Прочитайте пример."} {
+			if !strings.Contains(details, s) {
+				t.Errorf("Expected details to contain %q, got:\n%s", s, details)
+			}
+		}
+
+		if text := first.node.GetText(); text != "✓ first" {
+			t.Errorf("Expected status icon in the tree, got %q", text)
+		}
+
+		if text := a.modules[0].node.GetText(); text != "Module (1/2)" {
+			t.Errorf("Expected module progress in the tree, got %q", text)
+		}
+	})
+
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the app to stop on q")
+	}
+}
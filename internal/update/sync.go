@@ -0,0 +1,452 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ksysoev/go-workshops/internal/diff"
+	"github.com/ksysoev/go-workshops/internal/manifest"
+)
+
+const baseFile = "base.json"
+
+// Base is the content of the last synced release.
+// It's the common ancestor for three-way merges of learner's changes with the next release.
+type Base struct {
+	Version string            `json:"version"`
+	Files   map[string]string `json:"files"`
+}
+
+// Report lists files touched by the content sync, paths are relative to the checkout root.
+type Report struct {
+	// Added files are new in the release.
+	Added []string
+	// Updated files were not changed by the learner and are replaced by the new version.
+	Updated []string
+	// Merged files were changed both by the learner and the release, changes are merged cleanly.
+	Merged []string
+	// Conflicts are files where learner's changes and the release overlap, they contain conflict markers.
+	Conflicts []string
+	// Removed files were removed in the release and were not changed by the learner.
+	Removed []string
+	// Kept files differ from the release, but have no common ancestor to merge with, they are left as they are.
+	Kept []string
+}
+
+// Syncer syncs release content into the checkout.
+type Syncer struct {
+	root    string
+	baseDir string
+}
+
+// NewSyncer creates a syncer for the checkout in root, that keeps the base of merges in baseDir.
+func NewSyncer(root, baseDir string) *Syncer {
+	return &Syncer{root: root, baseDir: baseDir}
+}
+
+// Version returns the version of the last synced content, or empty string if content was never synced.
+func (s *Syncer) Version() (string, error) {
+	base, err := s.loadBase()
+	if err != nil {
+		return "", err
+	}
+
+	return base.Version, nil
+}
+
+// Sync applies the content archive of the release version to the checkout.
+//
+// Files the learner didn't touch are replaced, files the release didn't change are kept as they are,
+// and files changed on both sides are merged against the previously synced version.
+// Overlapping changes are written with conflict markers, the same way git does it.
+// Before the first sync the common ancestor is the commit the checkout was cloned from,
+// files without one are kept as they are.
+//
+// The checkout is changed only when every file is merged: new content is written to temporary files
+// and renamed into place, and the synced version is saved last, so a failed sync can be run again.
+func (s *Syncer) Sync(version string, archive []byte) (*Report, error) {
+	files, err := readArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := s.loadBase()
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Version == "" {
+		base.Files = cloneBase(s.root, files)
+	}
+
+	report := &Report{}
+	paths := make([]string, 0, len(files))
+
+	for p := range files {
+		paths = append(paths, p)
+	}
+
+	slices.Sort(paths)
+
+	var changes []change
+
+	for _, p := range paths {
+		c, err := s.syncFile(report, p, base.Files, files[p], version)
+		if err != nil {
+			return nil, err
+		}
+
+		if c != nil {
+			changes = append(changes, *c)
+		}
+	}
+
+	for p, old := range base.Files {
+		if _, ok := files[p]; ok {
+			continue
+		}
+
+		c, err := s.removeFile(report, p, old)
+		if err != nil {
+			return nil, err
+		}
+
+		if c != nil {
+			changes = append(changes, *c)
+		}
+	}
+
+	slices.Sort(report.Removed)
+
+	if err := s.apply(changes); err != nil {
+		return nil, err
+	}
+
+	if err := s.saveBase(Base{Version: version, Files: files}); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// change is a pending change of a file in the checkout, the new content or its removal.
+type change struct {
+	path    string
+	content string
+	remove  bool
+}
+
+func (s *Syncer) syncFile(report *Report, p string, baseFiles map[string]string, theirs, version string) (*change, error) {
+	full := filepath.Join(s.root, filepath.FromSlash(p))
+	base, hasBase := baseFiles[p]
+
+	data, err := os.ReadFile(full)
+	if errors.Is(err, os.ErrNotExist) {
+		// The learner removed the file on purpose, don't bring it back unless it changed.
+		if hasBase && base == theirs {
+			return nil, nil
+		}
+
+		report.Added = append(report.Added, p)
+
+		return &change{path: full, content: theirs}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+
+	ours := string(data)
+
+	switch {
+	case ours == theirs:
+		return nil, nil
+	case !hasBase:
+		// Without a common ancestor every difference looks like a conflict, the learner's version is safer.
+		report.Kept = append(report.Kept, p)
+		return nil, nil
+	case ours == base:
+		report.Updated = append(report.Updated, p)
+		return &change{path: full, content: theirs}, nil
+	case theirs == base:
+		return nil, nil
+	}
+
+	merged, conflicts := diff.Merge3(base, ours, theirs, "yours", "update "+version)
+
+	if conflicts > 0 {
+		report.Conflicts = append(report.Conflicts, p)
+	} else {
+		report.Merged = append(report.Merged, p)
+	}
+
+	return &change{path: full, content: merged}, nil
+}
+
+func (s *Syncer) removeFile(report *Report, p, base string) (*change, error) {
+	full := filepath.Join(s.root, filepath.FromSlash(p))
+
+	data, err := os.ReadFile(full)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+
+	// Keep the file if the learner changed it, their work is more important than a clean tree.
+	if string(data) != base {
+		return nil, nil
+	}
+
+	report.Removed = append(report.Removed, p)
+
+	return &change{path: full, remove: true}, nil
+}
+
+// apply writes new content of all files to temporary files first, and renames them into place
+// only when every one of them is written.
+func (s *Syncer) apply(changes []change) error {
+	temps := make(map[string]string, len(changes))
+
+	defer func() {
+		for _, tmp := range temps {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	for _, c := range changes {
+		if c.remove {
+			continue
+		}
+
+		tmp, err := writeTemp(c.path, c.content)
+		if err != nil {
+			return err
+		}
+
+		temps[c.path] = tmp
+	}
+
+	for _, c := range changes {
+		if c.remove {
+			if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", c.path, err)
+			}
+
+			continue
+		}
+
+		if err := os.Rename(temps[c.path], c.path); err != nil {
+			return fmt.Errorf("failed to write %s: %w", c.path, err)
+		}
+
+		delete(temps, c.path)
+	}
+
+	return nil
+}
+
+// writeTemp writes the content to a temporary file next to the path, and returns the name of the temporary file.
+func writeTemp(path, content string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return f.Name(), nil
+}
+
+// cloneBase returns the content of the release files in the commit the checkout was cloned from,
+// the merge base of HEAD and the upstream branch, or the default branch of origin when HEAD has no upstream.
+// HEAD itself is not a base: learners who commit their solutions would lose them to the release.
+// Files that can't be read from git, e.g. when the checkout was downloaded as an archive, have no base.
+func cloneBase(root string, files map[string]string) map[string]string {
+	base := make(map[string]string)
+
+	rev, err := git(root, "merge-base", "HEAD", "@{upstream}")
+	if err != nil {
+		if rev, err = git(root, "merge-base", "HEAD", "origin/HEAD"); err != nil {
+			return base
+		}
+	}
+
+	rev = strings.TrimSpace(rev)
+
+	for p, content := range files {
+		// Only files changed by the learner need a base, the rest are added or already up to date.
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(p)))
+		if err != nil || string(data) == content {
+			continue
+		}
+
+		// The path is relative to the checkout root, ./ makes git resolve it from the working directory.
+		if content, err := git(root, "show", rev+":./"+p); err == nil {
+			base[p] = content
+		}
+	}
+
+	return base
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+
+	return string(out), nil
+}
+
+func (s *Syncer) loadBase() (Base, error) {
+	base := Base{Files: make(map[string]string)}
+
+	data, err := os.ReadFile(filepath.Join(s.baseDir, baseFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return base, nil
+	} else if err != nil {
+		return base, fmt.Errorf("failed to read synced content: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &base); err != nil {
+		return base, fmt.Errorf("failed to parse synced content: %w", err)
+	}
+
+	return base, nil
+}
+
+func (s *Syncer) saveBase(base Base) error {
+	data, err := json.Marshal(base)
+	if err != nil {
+		return fmt.Errorf("failed to encode synced content: %w", err)
+	}
+
+	path := filepath.Join(s.baseDir, baseFile)
+
+	tmp, err := writeTemp(path, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save synced content: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to save synced content: %w", err)
+	}
+
+	return nil
+}
+
+// readArchive reads regular files from the tar.gz archive.
+// The root of the content is the directory with the manifest file, archives produced by GitHub
+// put everything into a single top level directory. Git metadata and the learner's progress are never synced.
+func readArchive(archive []byte) (map[string]string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content archive: %w", err)
+	}
+
+	entries := make(map[string]string)
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read content archive: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if !filepath.IsLocal(name) {
+			return nil, fmt.Errorf("content archive contains unsafe path %s", hdr.Name)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from content archive: %w", hdr.Name, err)
+		}
+
+		entries[name] = string(data)
+	}
+
+	root, err := contentRoot(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(entries))
+
+	for name, content := range entries {
+		rel, ok := strings.CutPrefix(name, root)
+		if !ok {
+			continue
+		}
+
+		if top, _, _ := strings.Cut(rel, "/"); top == ".git" || top == manifest.ProgressDirName {
+			continue
+		}
+
+		files[rel] = content
+	}
+
+	return files, nil
+}
+
+// contentRoot returns the prefix of the shallowest directory in the archive containing the manifest file.
+func contentRoot(entries map[string]string) (string, error) {
+	root, found := "", false
+
+	for name := range entries {
+		if path.Base(name) != manifest.FileName {
+			continue
+		}
+
+		dir := strings.TrimSuffix(name, manifest.FileName)
+		if !found || strings.Count(dir, "/") < strings.Count(root, "/") {
+			root, found = dir, true
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("content archive has no %s", manifest.FileName)
+	}
+
+	return root, nil
+}
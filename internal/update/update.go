@@ -0,0 +1,147 @@
+// Package update checks for new workshop releases, replaces the workshop binary,
+// and syncs exercise content into the learner's checkout without losing their solutions.
+package update
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// Release describes a published version of the workshop.
+type Release struct {
+	Version string `json:"version"`
+
+	// Binaries are prebuilt workshop binaries keyed by GOOS/GOARCH, e.g. "linux/amd64".
+	Binaries map[string]Asset `json:"binaries,omitempty"`
+
+	// Content is a tar.gz archive of the repository with exercise modules.
+	Content Asset `json:"content"`
+}
+
+// Asset is a downloadable file of the release.
+type Asset struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// Binary returns the binary built for the current platform.
+func (r *Release) Binary() (Asset, bool) {
+	a, ok := r.Binaries[runtime.GOOS+"/"+runtime.GOARCH]
+	return a, ok
+}
+
+// Client fetches releases from the release endpoint.
+type Client struct {
+	HTTP     *http.Client
+	Endpoint string
+}
+
+// NewClient creates a client for the release endpoint, a URL of the JSON document describing the latest release.
+func NewClient(endpoint string) *Client {
+	return &Client{
+		HTTP:     &http.Client{Timeout: 5 * time.Minute},
+		Endpoint: endpoint,
+	}
+}
+
+// Latest fetches the latest release.
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	data, err := c.get(ctx, c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check release: %w", err)
+	}
+
+	var r Release
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+
+	if r.Version == "" {
+		return nil, fmt.Errorf("release at %s has no version", c.Endpoint)
+	}
+
+	return &r, nil
+}
+
+// Download fetches the asset and verifies its checksum.
+func (c *Client) Download(ctx context.Context, a Asset) ([]byte, error) {
+	data, err := c.get(ctx, a.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", a.URL, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != a.SHA256 {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", a.URL, a.SHA256, got)
+	}
+
+	return data, nil
+}
+
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// ReplaceBinary atomically replaces the executable at path with data.
+// The new binary is written next to the old one and renamed over it,
+// so an interrupted update never leaves a half-written executable.
+func ReplaceBinary(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".workshop-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary binary: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write binary: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write binary: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("failed to make binary executable: %w", err)
+	}
+
+	// Windows doesn't allow to replace a running executable, but it allows to rename it.
+	if runtime.GOOS == "windows" {
+		old := path + ".old"
+		_ = os.Remove(old)
+
+		if err := os.Rename(path, old); err != nil {
+			return fmt.Errorf("failed to move old binary: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,393 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// makeArchive builds a tar.gz archive with files inside of a top level directory, like GitHub does.
+func makeArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		hdr := &tar.Header{Name: "go-workshops-v1/" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestClient(t *testing.T) {
+	binary := []byte("new binary")
+	mux := http.NewServeMux()
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/release.json", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(Release{
+			Version: "v1.1.0",
+			Binaries: map[string]Asset{
+				runtime.GOOS + "/" + runtime.GOARCH: {URL: srv.URL + "/workshop", SHA256: checksum(binary)},
+			},
+			Content: Asset{URL: srv.URL + "/content.tar.gz", SHA256: "bad"},
+		})
+	})
+	mux.HandleFunc("/workshop", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	mux.HandleFunc("/content.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	})
+
+	c := NewClient(srv.URL + "/release.json")
+
+	r, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if r.Version != "v1.1.0" {
+		t.Errorf("Expected version v1.1.0, got %s", r.Version)
+	}
+
+	asset, ok := r.Binary()
+	if !ok {
+		t.Fatal("Expected binary for the current platform")
+	}
+
+	data, err := c.Download(context.Background(), asset)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(data, binary) {
+		t.Errorf("Expected to download %q, got %q", binary, data)
+	}
+
+	if _, err := c.Download(context.Background(), r.Content); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch error, got %v", err)
+	}
+
+	if _, err := NewClient(srv.URL + "/missing").Latest(context.Background()); err == nil {
+		t.Error("Expected error for missing release")
+	}
+}
+
+func TestReplaceBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workshop")
+
+	if err := os.WriteFile(path, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReplaceBinary(path, []byte("new")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "new" {
+		t.Errorf("Expected binary to be replaced, got %q", data)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, root, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(root, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestSync(t *testing.T) {
+	root := t.TempDir()
+	s := NewSyncer(root, filepath.Join(root, ".workshop", "update"))
+
+	v1 := map[string]string{
+		"workshop.json":      "{}\n",
+		"mod/untouched.go":   "package mod\n\nfunc A() {}\n",
+		"mod/solved_test.go": "package mod\n\n// TODO\nfunc B() {}\n\nfunc C() {}\n",
+		"mod/conflict.go":    "package mod\n\nfunc D() {}\n",
+		"mod/old.go":         "package mod\n",
+		"mod/kept.go":        "package mod\n\nfunc E() {}\n",
+	}
+
+	if _, err := s.Sync("v1", makeArchive(t, v1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v, err := s.Version(); err != nil || v != "v1" {
+		t.Fatalf("Expected synced version v1, got %q, %v", v, err)
+	}
+
+	// The learner solves some of the exercises.
+	writeFiles(t, root, map[string]string{
+		"mod/solved_test.go": "package mod\n\n// TODO\nfunc B() { println(\"solved\") }\n\nfunc C() {}\n",
+		"mod/conflict.go":    "package mod\n\nfunc D() { println(\"solved\") }\n",
+		"mod/kept.go":        "package mod\n\nfunc E() { println(\"solved\") }\n",
+	})
+
+	v2 := map[string]string{
+		"workshop.json":      "{}\n",
+		"mod/untouched.go":   "package mod\n\nfunc A() { /* fixed */ }\n",
+		"mod/solved_test.go": "package mod\n\n// TODO\nfunc B() {}\n\nfunc C() {}\n\nfunc F() {}\n",
+		"mod/conflict.go":    "package mod\n\nfunc D(x int) {}\n",
+		"mod/kept.go":        "package mod\n\nfunc E() {}\n",
+		"mod/new.go":         "package mod\n\nfunc G() {}\n",
+	}
+
+	report, err := s.Sync("v2", makeArchive(t, v2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := &Report{
+		Added:     []string{"mod/new.go"},
+		Updated:   []string{"mod/untouched.go"},
+		Merged:    []string{"mod/solved_test.go"},
+		Conflicts: []string{"mod/conflict.go"},
+		Removed:   []string{"mod/old.go"},
+	}
+
+	for _, c := range []struct {
+		name     string
+		expected []string
+		got      []string
+	}{
+		{"added", expected.Added, report.Added},
+		{"updated", expected.Updated, report.Updated},
+		{"merged", expected.Merged, report.Merged},
+		{"conflicts", expected.Conflicts, report.Conflicts},
+		{"removed", expected.Removed, report.Removed},
+	} {
+		if !slices.Equal(c.expected, c.got) {
+			t.Errorf("Expected %s files %v, got %v", c.name, c.expected, c.got)
+		}
+	}
+
+	if got := readFile(t, root, "mod/solved_test.go"); got != "package mod\n\n// TODO\nfunc B() { println(\"solved\") }\n\nfunc C() {}\n\nfunc F() {}\n" {
+		t.Errorf("Expected solution to be merged with the update, got:\n%s", got)
+	}
+
+	if got := readFile(t, root, "mod/kept.go"); !strings.Contains(got, "solved") {
+		t.Errorf("Expected solution to be kept, got:\n%s", got)
+	}
+
+	if got := readFile(t, root, "mod/conflict.go"); !strings.Contains(got, "<<<<<<< yours") || !strings.Contains(got, ">>>>>>> update v2") {
+		t.Errorf("Expected conflict markers, got:\n%s", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "mod", "old.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected removed file to be deleted, got %v", err)
+	}
+}
+
+func TestReadArchiveRejectsUnsafePaths(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil.go", Mode: 0o644, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = tw.Close()
+	_ = gz.Close()
+
+	if _, err := readArchive(buf.Bytes()); err == nil {
+		t.Error("Expected error for path outside of the checkout")
+	}
+}
+
+func TestReadArchiveWithoutManifest(t *testing.T) {
+	if _, err := readArchive(makeArchive(t, map[string]string{"mod/a.go": "package mod\n"})); err == nil {
+		t.Error("Expected error for archive without manifest")
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=learner", "-c", "user.email=learner@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestSyncFirstRunMergesWithClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	upstream, root := t.TempDir(), filepath.Join(t.TempDir(), "checkout")
+	s := NewSyncer(root, filepath.Join(root, ".workshop", "update"))
+
+	writeFiles(t, upstream, map[string]string{
+		"workshop.json":      "{}\n",
+		"mod/first_test.go":  "package mod\n\n// TODO\nfunc A() {}\n\nfunc B() {}\n",
+		"mod/second_test.go": "package mod\n\n// TODO\nfunc D() {}\n",
+	})
+	runGit(t, upstream, "init", "-q")
+	runGit(t, upstream, "add", "-A")
+	runGit(t, upstream, "commit", "-q", "-m", "release")
+
+	// The learner clones the workshop, commits a solution, and works on the next one.
+	runGit(t, upstream, "clone", "-q", upstream, root)
+
+	writeFiles(t, root, map[string]string{"mod/first_test.go": "package mod\n\n// TODO\nfunc A() { println(\"solved\") }\n\nfunc B() {}\n"})
+	runGit(t, root, "commit", "-q", "-am", "solve the first exercise")
+	writeFiles(t, root, map[string]string{"mod/second_test.go": "package mod\n\n// TODO\nfunc D() { println(\"solved\") }\n"})
+
+	report, err := s.Sync("v1", makeArchive(t, map[string]string{
+		"workshop.json":      "{}\n",
+		"mod/first_test.go":  "package mod\n\n// TODO\nfunc A() {}\n\nfunc B() {}\n\nfunc C() {}\n",
+		"mod/second_test.go": "package mod\n\n// TODO\nfunc D() {}\n",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Conflicts) > 0 || len(report.Kept) > 0 {
+		t.Errorf("Expected solutions to be merged with the clone as the base, got conflicts %v and kept %v",
+			report.Conflicts, report.Kept)
+	}
+
+	if got := readFile(t, root, "mod/first_test.go"); got != "package mod\n\n// TODO\nfunc A() { println(\"solved\") }\n\nfunc B() {}\n\nfunc C() {}\n" {
+		t.Errorf("Expected committed solution to be merged with the update, got:\n%s", got)
+	}
+
+	if got := readFile(t, root, "mod/second_test.go"); !strings.Contains(got, "solved") {
+		t.Errorf("Expected solution in progress to be kept, got:\n%s", got)
+	}
+}
+
+func TestSyncFirstRunWithoutBase(t *testing.T) {
+	root := t.TempDir()
+	s := NewSyncer(root, filepath.Join(root, ".workshop", "update"))
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(root))
+
+	// The checkout was downloaded as an archive, there is no history to find the original files.
+	writeFiles(t, root, map[string]string{
+		"workshop.json":     "{}\n",
+		"mod/first_test.go": "package mod\n\n// TODO\nfunc A() { println(\"solved\") }\n",
+	})
+
+	report, err := s.Sync("v1", makeArchive(t, map[string]string{
+		"workshop.json":     "{}\n",
+		"mod/first_test.go": "package mod\n\n// TODO\nfunc A() {}\n",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !slices.Equal(report.Kept, []string{"mod/first_test.go"}) || len(report.Conflicts) > 0 {
+		t.Errorf("Expected the solution to be kept without conflicts, got kept %v and conflicts %v", report.Kept, report.Conflicts)
+	}
+
+	if got := readFile(t, root, "mod/first_test.go"); !strings.Contains(got, "solved") {
+		t.Errorf("Expected solution to be kept, got:\n%s", got)
+	}
+}
+
+func TestSyncIsAllOrNothing(t *testing.T) {
+	root := t.TempDir()
+	s := NewSyncer(root, filepath.Join(root, ".workshop", "update"))
+
+	v1 := map[string]string{
+		"workshop.json": "{}\n",
+		"a/first.go":    "package a\n",
+	}
+
+	if _, err := s.Sync("v1", makeArchive(t, v1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// z is a file in the checkout, so z/last.go of the release can't be read or written.
+	writeFiles(t, root, map[string]string{"z": "notes\n"})
+
+	_, err := s.Sync("v2", makeArchive(t, map[string]string{
+		"workshop.json": "{}\n",
+		"a/first.go":    "package a\n\nfunc A() {}\n",
+		"z/last.go":     "package z\n",
+	}))
+	if err == nil {
+		t.Fatal("Expected error for the file that can't be synced")
+	}
+
+	if got := readFile(t, root, "a/first.go"); got != v1["a/first.go"] {
+		t.Errorf("Expected the checkout to stay unchanged after a failed sync, got:\n%s", got)
+	}
+
+	if v, err := s.Version(); err != nil || v != "v1" {
+		t.Errorf("Expected synced version to stay v1, got %q, %v", v, err)
+	}
+}
@@ -0,0 +1,233 @@
+package iofs
+
+import (
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// io/fs defines what a read-only file system is, the core of it is a single method:
+//
+//	type FS interface {
+//		Open(name string) (File, error)
+//	}
+//
+// The same code works with any implementation: os.DirFS for a directory on disk, embed.FS for files in the binary,
+// fstest.MapFS for files in memory, zip.Reader for an archive, and your own ones.
+// Helpers fs.ReadFile, fs.ReadDir, fs.Stat, fs.WalkDir, and fs.Glob take an fs.FS and use extra methods when they are available.
+//
+// Paths in fs.FS are always slash separated and unrooted, like "photos/2024/beach.jpg", on every OS.
+// There is no "/" at the start, no ".." and no "." elements, and the root itself is ".".
+
+// 1. FindDuplicates takes fs.FS, but it ignores it and reads the disk directly with os and path/filepath.
+// Fix it to work with any fs.FS, so the tests can run it on files in memory, on disk, and embedded into the binary.
+//
+// Duplicates are files with the same content. Hashing every file is expensive for large photo collections,
+// files with different sizes can't be the same, so only files with the same size need to be hashed.
+
+// FindDuplicates returns groups of paths of files with the same content.
+// Paths in a group are sorted, and groups are sorted by their first path.
+func FindDuplicates(fsys fs.FS) ([][]string, error) {
+	byHash := make(map[[sha256.Size]byte][]string)
+
+	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		byHash[sum] = append(byHash[sum], path)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups [][]string
+
+	for _, paths := range byHash {
+		if len(paths) > 1 {
+			slices.Sort(paths)
+			groups = append(groups, paths)
+		}
+	}
+
+	slices.SortFunc(groups, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+
+	return groups, nil
+}
+
+var expectedPhotoDuplicates = [][]string{
+	{"2023/party.txt", "2024/party.txt", "party-final.txt"},
+	{"2023/sunset.txt", "2024/summer/sunset-copy.txt"},
+}
+
+func assertGroups(t *testing.T, expected, got [][]string) {
+	t.Helper()
+
+	if !slices.EqualFunc(expected, got, slices.Equal) {
+		t.Errorf("Expected duplicates to be %q, got %q", expected, got)
+	}
+}
+
+func TestFindDuplicatesMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":        {Data: []byte("hello")},
+		"b/a-copy.txt": {Data: []byte("hello")},
+		"b/other.txt":  {Data: []byte("world")},
+		"c/d/e.txt":    {Data: []byte("hello")},
+		"c/f.txt":      {Data: []byte("hellO")},
+		"empty":        {Data: []byte{}},
+		"c/empty":      {Data: []byte{}},
+	}
+
+	groups, err := FindDuplicates(fsys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertGroups(t, [][]string{{"a.txt", "b/a-copy.txt", "c/d/e.txt"}, {"c/empty", "empty"}}, groups)
+}
+
+func TestFindDuplicatesDirFS(t *testing.T) {
+	groups, err := FindDuplicates(os.DirFS("testdata/photos"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertGroups(t, expectedPhotoDuplicates, groups)
+}
+
+//go:embed testdata/photos
+var photosFS embed.FS
+
+func TestFindDuplicatesEmbedFS(t *testing.T) {
+	// fs.Sub returns the subtree as fs.FS, so paths don't start with testdata/photos.
+	photos, err := fs.Sub(photosFS, "testdata/photos")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	groups, err := FindDuplicates(photos)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertGroups(t, expectedPhotoDuplicates, groups)
+}
+
+// countingFS counts how many times each file is opened, wrapping an fs.FS is an easy way to observe how it's used.
+type countingFS struct {
+	fs.FS
+	mu    sync.Mutex
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	c.opens[name]++
+	c.mu.Unlock()
+
+	return c.FS.Open(name)
+}
+
+func TestFindDuplicatesSkipsUniqueSizes(t *testing.T) {
+	fsys := &countingFS{
+		FS: fstest.MapFS{
+			"raw/1.raw": {Data: []byte(strings.Repeat("a", 1000))},
+			"raw/2.raw": {Data: []byte(strings.Repeat("b", 2000))},
+			"small.txt": {Data: []byte("x")},
+			"copy.txt":  {Data: []byte("x")},
+		},
+		opens: make(map[string]int),
+	}
+
+	groups, err := FindDuplicates(fsys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	assertGroups(t, [][]string{{"copy.txt", "small.txt"}}, groups)
+
+	for _, name := range []string{"raw/1.raw", "raw/2.raw"} {
+		if fsys.opens[name] > 0 {
+			t.Errorf("Expected %s with unique size not to be read, it was opened %d times", name, fsys.opens[name])
+		}
+	}
+}
+
+// 2. Implementing fs.FS.
+// FilterFS should return a file system that contains only files and directories accepted by match,
+// everything else doesn't exist: Open returns fs.ErrNotExist, and directories don't list it.
+// The root "." is always there. match gets the full path, so a rule like "hide dot files" can check every element.
+//
+// A directory returned by Open implements fs.ReadDirFile, that's what fs.ReadDir and fs.WalkDir use to list it.
+// The contract has subtle details, like ReadDir(n) with n > 0 returning entries in batches and io.EOF at the end.
+// fstest.TestFS checks an implementation against all of them, use it to find what's missing.
+
+// FilterFS returns a view of fsys with only paths accepted by match.
+func FilterFS(fsys fs.FS, match func(path string) bool) fs.FS {
+	return fsys
+}
+
+// notHidden accepts paths without elements starting with a dot.
+func notHidden(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestFilterFS(t *testing.T) {
+	fsys := FilterFS(fstest.MapFS{
+		".git/config":         {Data: []byte("[core]")},
+		".env":                {Data: []byte("TOKEN=secret")},
+		"README.md":           {Data: []byte("# Project")},
+		"src/main.go":         {Data: []byte("package main")},
+		"src/.cache/build":    {Data: []byte("cache")},
+		"src/util/.keep":      {},
+		"src/util/strings.go": {Data: []byte("package util")},
+	}, notHidden)
+
+	if err := fstest.TestFS(fsys, "README.md", "src/main.go", "src/util/strings.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{".env", ".git", ".git/config", "src/.cache/build", "src/util/.keep"} {
+		if _, err := fs.Stat(fsys, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected %s to be filtered out with fs.ErrNotExist, got %v", name, err)
+		}
+	}
+
+	var paths []string
+
+	err := fs.WalkDir(fsys, ".", func(path string, _ fs.DirEntry, err error) error {
+		paths = append(paths, path)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{".", "README.md", "src", "src/main.go", "src/util", "src/util/strings.go"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("Expected walk to visit %q, got %q", expected, paths)
+	}
+}
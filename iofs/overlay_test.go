@@ -0,0 +1,87 @@
+package iofs
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// Advanced: composing file systems.
+//
+// An overlay file system puts one file system on top of another: files of the upper one hide files of the lower one
+// with the same path, and directories show entries of both. Themes work this way, a site ships default templates
+// embedded into the binary, and a user overrides some of them with files in a directory.
+//
+// Let's implement Overlay. Open returns the file of upper if it exists there, otherwise the file of lower.
+// fs.ReadDir and fs.WalkDir use the ReadDir method when a file system has it (fs.ReadDirFS),
+// implement it to merge entries of both layers sorted by name, an entry of upper wins.
+// Only fs.ErrNotExist from upper means "look below", other errors are returned as they are.
+
+// Overlay returns a file system of upper on top of lower.
+func Overlay(upper, lower fs.FS) fs.FS {
+	return lower
+}
+
+func TestOverlay(t *testing.T) {
+	lower := fstest.MapFS{
+		"templates/base.html":  {Data: []byte("default base")},
+		"templates/index.html": {Data: []byte("default index")},
+		"static/app.css":       {Data: []byte("default css")},
+	}
+
+	upper := fstest.MapFS{
+		"templates/index.html": {Data: []byte("custom index")},
+		"templates/about.html": {Data: []byte("custom about")},
+	}
+
+	fsys := Overlay(upper, lower)
+
+	for path, expected := range map[string]string{
+		"templates/index.html": "custom index",
+		"templates/about.html": "custom about",
+		"templates/base.html":  "default base",
+		"static/app.css":       "default css",
+	} {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil || string(data) != expected {
+			t.Errorf("Expected %s to be %q, got %q and error %v", path, expected, data, err)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, "templates")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+
+	if expected := []string{"about.html", "base.html", "index.html"}; !slices.Equal(names, expected) {
+		t.Errorf("Expected merged directory %v, got %v", expected, names)
+	}
+
+	var files []string
+
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(files) != 4 {
+		t.Errorf("Expected to walk 4 files of both layers, got %v", files)
+	}
+
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected error to be %v, got %v", fs.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,94 @@
+package iofundamentals
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// Advanced: message framing.
+//
+// A stream, like a TCP connection, has no message boundaries: a message written with one Write
+// can arrive in many Reads, and one Read can return parts of two messages.
+// Protocols add the boundaries themselves, a common way is to prefix every message with its length.
+//
+// ReadFrame reads a frame of a 4 byte big endian length followed by the payload. Its contract:
+// - io.EOF when the stream ends cleanly between frames,
+// - io.ErrUnexpectedEOF when the stream ends in the middle of a frame,
+// - ErrFrameTooLarge for frames longer than maxFrameSize, before allocating memory for them.
+// A single Read is not enough, look at io.ReadFull.
+
+// ErrFrameTooLarge is returned for frames longer than maxFrameSize.
+var ErrFrameTooLarge = errors.New("frame is too large")
+
+const maxFrameSize = 1 << 20
+
+// WriteFrame writes the payload prefixed with its length.
+func WriteFrame(w io.Writer, payload []byte) error {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(payload)
+
+	return err
+}
+
+// ReadFrame reads a frame written by WriteFrame and returns its payload.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := r.Read(header[:]); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[:]))
+	_, err := r.Read(payload)
+
+	return payload, err
+}
+
+func TestReadFrame(t *testing.T) {
+	var stream bytes.Buffer
+	for _, msg := range []string{"hello", "", "framed world"} {
+		if err := WriteFrame(&stream, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := stream.Bytes()
+
+	// OneByteReader returns a single byte on every Read, like a slow network connection.
+	r := iotest.OneByteReader(bytes.NewReader(data))
+
+	for _, expected := range []string{"hello", "", "framed world"} {
+		payload, err := ReadFrame(r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if string(payload) != expected {
+			t.Errorf("Expected payload %q, got %q", expected, payload)
+		}
+	}
+
+	if _, err := ReadFrame(r); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last frame, got %v", err)
+	}
+
+	for _, size := range []int{2, 6} {
+		if _, err := ReadFrame(bytes.NewReader(data[:size])); err != io.ErrUnexpectedEOF {
+			t.Errorf("Expected io.ErrUnexpectedEOF for a stream truncated at %d bytes, got %v", size, err)
+		}
+	}
+
+	huge := binary.BigEndian.AppendUint32(nil, maxFrameSize+1)
+	if _, err := ReadFrame(bytes.NewReader(huge)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Expected error to be %v, got %v", ErrFrameTooLarge, err)
+	}
+}
@@ -0,0 +1,252 @@
+package iofundamentals
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// io.Reader and io.Writer are the most important interfaces in Go standard library.
+//
+//	type Reader interface {
+//		Read(p []byte) (n int, err error)
+//	}
+//
+//	type Writer interface {
+//		Write(p []byte) (n int, err error)
+//	}
+//
+// Files, network connections, HTTP bodies, buffers, compressors, and hashes implement them,
+// so small readers and writers compose into pipelines, like Unix commands connected with pipes.
+//
+// The contract of Read is subtle:
+// - Read reads up to len(p) bytes, it can return fewer bytes than requested even if more data is coming.
+// - Read returns io.EOF when there is no more data. It can return n > 0 together with io.EOF.
+// - The caller should process n > 0 bytes before looking at the error.
+
+// Let's start with a custom reader that wraps another reader and transforms the data.
+// ROT13 replaces every letter with the letter 13 positions after it in the alphabet.
+
+// Rot13Reader decodes ROT13 encoded text read from the underlying reader.
+type Rot13Reader struct {
+	R io.Reader
+}
+
+func (r Rot13Reader) Read(p []byte) (int, error) {
+	return r.R.Read(p)
+}
+
+func ExampleRot13Reader() {
+	r := Rot13Reader{R: strings.NewReader("Uryyb, Tbcure!")}
+
+	if _, err := io.Copy(os.Stdout, r); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// Output:
+	// Hello, Gopher!
+}
+
+// iotest.TestReader checks that a reader follows the contract with different buffer sizes and read patterns.
+func TestRot13Reader(t *testing.T) {
+	r := Rot13Reader{R: strings.NewReader("Tb vf sha")}
+
+	if err := iotest.TestReader(r, []byte("Go is fun")); err != nil {
+		t.Error(err)
+	}
+}
+
+// A reader doesn't know how the caller will split the data into reads.
+// Line numbering reader has to remember whether the previous read ended in the middle of the line,
+// otherwise it breaks when the caller reads one byte at a time.
+
+// LineNumberReader prefixes every line read from the underlying reader with its number, e.g. "1: ".
+type LineNumberReader struct {
+	r io.Reader
+}
+
+// NewLineNumberReader creates a reader that numbers lines of r.
+func NewLineNumberReader(r io.Reader) *LineNumberReader {
+	return &LineNumberReader{r: r}
+}
+
+func (r *LineNumberReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func ExampleNewLineNumberReader() {
+	src := iotest.OneByteReader(strings.NewReader("package main\nimport \"fmt\"\nfunc main() { fmt.Println() }\n"))
+
+	if _, err := io.Copy(os.Stdout, NewLineNumberReader(src)); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// Output:
+	// 1: package main
+	// 2: import "fmt"
+	// 3: func main() { fmt.Println() }
+}
+
+// io.TeeReader returns a reader that writes everything it reads from r to w.
+// It's useful to process the same stream twice without buffering it in memory,
+// for example to save an upload to disk and calculate its checksum in one pass.
+
+// HashAndCopy copies src to dst and returns hex encoded SHA-256 of the copied data.
+// Let's try to implement it in one pass with io.TeeReader.
+func HashAndCopy(dst io.Writer, src io.Reader) (string, int64, error) {
+	n, err := io.Copy(dst, src)
+
+	return "", n, err
+}
+
+func ExampleHashAndCopy() {
+	dst := &bytes.Buffer{}
+
+	sum, n, err := HashAndCopy(dst, strings.NewReader("hello"))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Println(n, dst.String())
+	fmt.Println(sum)
+
+	// Output:
+	// 5 hello
+	// 2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824
+}
+
+// Never trust the size of the input. io.LimitReader stops reading after n bytes,
+// it protects from requests that try to exhaust memory with a huge body.
+// But a limited reader silently truncates the data, it's not possible to tell a complete input
+// from a truncated one. The trick is to read one byte more than allowed.
+
+// ErrTooLarge is returned when the input is larger than allowed.
+var ErrTooLarge = errors.New("input is too large")
+
+// ReadAtMost reads all data from r, it returns ErrTooLarge if r has more than limit bytes.
+func ReadAtMost(r io.Reader, limit int64) ([]byte, error) {
+	return io.ReadAll(r)
+}
+
+func ExampleReadAtMost() {
+	data, err := ReadAtMost(strings.NewReader("small"), 5)
+	fmt.Printf("%q %v\n", data, err)
+
+	_, err = ReadAtMost(strings.NewReader("too large"), 5)
+	fmt.Println(err)
+
+	// Output:
+	// "small" <nil>
+	// input is too large
+}
+
+// io.Copy allocates a 32 KiB buffer for every call, io.CopyBuffer allows to provide your own buffer,
+// e.g. to reuse it or to control the size of reads.
+// But there is a catch: if src implements io.WriterTo or dst implements io.ReaderFrom,
+// both functions use them and ignore the buffer. *bytes.Buffer, *os.File, and net.Conn implement these interfaces.
+// Let's try to fix CopyInChunks, so every Read from src is at most size bytes.
+// Hint: wrapping a value in a struct with an embedded interface hides all other methods of the value.
+
+// CopyInChunks copies src to dst reading at most size bytes at a time.
+func CopyInChunks(dst io.Writer, src io.Reader, size int) (int64, error) {
+	return io.Copy(dst, src)
+}
+
+// readRecorder records sizes of reads.
+type readRecorder struct {
+	r     io.Reader
+	reads []int
+}
+
+func (r *readRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.reads = append(r.reads, n)
+	}
+
+	return n, err
+}
+
+func ExampleCopyInChunks() {
+	src := &readRecorder{r: strings.NewReader("0123456789")}
+	dst := &bytes.Buffer{}
+
+	n, err := CopyInChunks(dst, src, 4)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Println(n, dst.String(), src.reads)
+
+	// Output:
+	// 10 0123456789 [4 4 2]
+}
+
+// io.Pipe connects code that writes to an io.Writer with code that reads from an io.Reader.
+// Writes block until the other side reads the data, so nothing is buffered in memory.
+// The writing side usually runs in its own goroutine and must close the pipe when done,
+// with CloseWithError if it failed, so the reader gets the error instead of hanging forever.
+// Let's try to implement CompressStream with io.Pipe and gzip.Writer.
+
+// CompressStream returns a reader of gzip compressed data of r.
+func CompressStream(r io.Reader) io.Reader {
+	return r
+}
+
+func ExampleCompressStream() {
+	zr, err := gzip.NewReader(CompressStream(strings.NewReader("streaming without buffering\n")))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	if _, err := io.Copy(os.Stdout, zr); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// Output:
+	// streaming without buffering
+}
+
+func TestCompressStreamError(t *testing.T) {
+	errBroken := errors.New("broken source")
+
+	_, err := io.ReadAll(CompressStream(iotest.ErrReader(errBroken)))
+	if !errors.Is(err, errBroken) {
+		t.Errorf("Expected error of the source to reach the reader, got %v", err)
+	}
+}
+
+// checksum returns hex encoded SHA-256 of the data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHashAndCopyLarge(t *testing.T) {
+	data := bytes.Repeat([]byte("gopher"), 100_000)
+	dst := &bytes.Buffer{}
+
+	sum, n, err := HashAndCopy(dst, iotest.HalfReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n != int64(len(data)) || !bytes.Equal(dst.Bytes(), data) {
+		t.Errorf("Expected to copy %d bytes, got %d", len(data), n)
+	}
+
+	if sum != checksum(data) {
+		t.Errorf("Expected checksum %s, got %s", checksum(data), sum)
+	}
+}
@@ -0,0 +1,273 @@
+package iterators
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Since Go 1.23 the range clause accepts functions, they are called iterators.
+// The iter package defines two types of them:
+//
+//	type Seq[V any] func(yield func(V) bool)
+//	type Seq2[K, V any] func(yield func(K, V) bool)
+//
+// An iterator calls yield for every element of the sequence. When the loop body executes break or return,
+// yield returns false, and the iterator must stop, it's a runtime error to call yield again.
+// The compiler turns the loop body into the yield function:
+//
+//	for v := range seq {
+//		if v > 10 {
+//			break
+//		}
+//	}
+//
+// More details are in the blog post https://go.dev/blog/range-functions
+
+// Let's start with a simple generator.
+
+// Numbers returns a sequence of integers from start to end inclusive.
+func Numbers(start, end int) iter.Seq[int] {
+	return func(yield func(int) bool) {}
+}
+
+func TestNumbers(t *testing.T) {
+	got := slices.Collect(Numbers(1, 5))
+
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected [1 2 3 4 5], got %v", got)
+	}
+}
+
+// Sequences can be infinite, the consumer decides when to stop.
+// The generator below ignores the result of yield, so it panics as soon as the loop breaks.
+// Let's try to fix it.
+
+// Fibonacci returns the infinite sequence of Fibonacci numbers.
+func Fibonacci() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		a, b := 0, 1
+
+		for {
+			yield(a)
+			a, b = b, a+b
+		}
+	}
+}
+
+// take returns the first n elements of the sequence, it reports a panic of the sequence as a test error.
+func take[V any](t *testing.T, seq iter.Seq[V], n int) (got []V) {
+	t.Helper()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Expected the sequence to stop when yield returns false, got panic: %v", r)
+		}
+	}()
+
+	for v := range seq {
+		got = append(got, v)
+
+		if len(got) == n {
+			break
+		}
+	}
+
+	return got
+}
+
+func TestFibonacciEarlyTermination(t *testing.T) {
+	got := take(t, Fibonacci(), 10)
+
+	if !slices.Equal(got, []int{0, 1, 1, 2, 3, 5, 8, 13, 21, 34}) {
+		t.Errorf("Expected first 10 Fibonacci numbers, got %v", got)
+	}
+}
+
+// Iterators compose. Functions that take a sequence and return a new one build lazy pipelines:
+// nothing is computed until somebody ranges over the result, and no intermediate slices are allocated.
+// Remember to pass the stop signal upstream: when yield of the consumer returns false, the adapter must return false too.
+
+// Map returns a sequence of fn applied to every element of seq.
+func Map[T, U any](seq iter.Seq[T], fn func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {}
+}
+
+// Filter returns a sequence of elements of seq for which keep returns true.
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {}
+}
+
+func isEven(n int) bool { return n%2 == 0 }
+
+func square(n int) int { return n * n }
+
+func TestMapFilter(t *testing.T) {
+	got := slices.Collect(Map(Filter(Numbers(1, 10), isEven), square))
+
+	if !slices.Equal(got, []int{4, 16, 36, 64, 100}) {
+		t.Errorf("Expected squares of even numbers [4 16 36 64 100], got %v", got)
+	}
+}
+
+func TestPipelineStopsUpstream(t *testing.T) {
+	produced := 0
+
+	counted := func(yield func(int) bool) {
+		for n := range Numbers(1, 1000) {
+			produced++
+
+			if !yield(n) {
+				return
+			}
+		}
+	}
+
+	got := take(t, Map(Filter(counted, isEven), square), 2)
+
+	if !slices.Equal(got, []int{4, 16}) {
+		t.Errorf("Expected [4 16], got %v", got)
+	}
+
+	if produced != 4 {
+		t.Errorf("Expected the pipeline to pull only 4 numbers from upstream, got %d", produced)
+	}
+}
+
+// iter.Seq2 yields pairs, like range over a map or a slice does.
+
+// Enumerate returns a sequence of index and value pairs of seq.
+func Enumerate[T any](seq iter.Seq[T]) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {}
+}
+
+func TestEnumerate(t *testing.T) {
+	var got []string
+
+	for i, s := range Enumerate(slices.Values([]string{"a", "b", "c"})) {
+		got = append(got, string(rune('0'+i))+s)
+	}
+
+	if !slices.Equal(got, []string{"0a", "1b", "2c"}) {
+		t.Errorf("Expected [0a 1b 2c], got %v", got)
+	}
+}
+
+// Range over func is a push iterator: the sequence drives the loop and pushes values into it.
+// Sometimes the consumer needs to drive: read from two sequences in lockstep or get one value on demand.
+// iter.Pull converts a push iterator into a pull iterator, a pair of next and stop functions.
+//
+// In the concurrency workshop NumberIterator hands out numbers on demand with a goroutine and a channel of channels.
+// ChanNumbers below is the simplest way to do the same with a goroutine: it sends numbers into a channel until ctx is done.
+// With iter.Pull the same behavior takes a few lines: no goroutine to start, no channels, no context to stop it,
+// just a generator and a call to stop when you are done.
+
+// ChanNumbers returns a channel of natural numbers starting from 1, they are sent by a goroutine that stops when ctx is done.
+func ChanNumbers(ctx context.Context) <-chan int {
+	numbers := make(chan int)
+
+	go func() {
+		for n := 1; ; n++ {
+			select {
+			case numbers <- n:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return numbers
+}
+
+// NewPullNumberIterator returns functions that behave like ChanNumbers:
+// next returns the next natural number starting from 1, and stop releases the iterator,
+// after stop next returns false.
+// Let's try to implement it with a generator of natural numbers and iter.Pull.
+func NewPullNumberIterator() (next func() (int, bool), stop func()) {
+	return func() (int, bool) { return 0, false }, func() {}
+}
+
+func TestPullNumberIterator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numbers := ChanNumbers(ctx)
+
+	next, stop := NewPullNumberIterator()
+
+	for i := 0; i < 100; i++ {
+		expected := <-numbers
+
+		got, ok := next()
+		if !ok || got != expected {
+			t.Fatalf("Expected next number to be %d, got %d, %t", expected, got, ok)
+		}
+	}
+
+	stop()
+
+	if n, ok := next(); ok {
+		t.Errorf("Expected iterator to be exhausted after stop, got %d", n)
+	}
+}
+
+func TestPullNumberIteratorAllocations(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numbers := ChanNumbers(ctx)
+
+	next, stop := NewPullNumberIterator()
+	defer stop()
+
+	if _, ok := next(); !ok {
+		t.Fatal("Expected pull iterator to return numbers")
+	}
+
+	chanAllocs := testing.AllocsPerRun(1000, func() {
+		<-numbers
+	})
+
+	pullAllocs := testing.AllocsPerRun(1000, func() {
+		_, _ = next()
+	})
+
+	t.Logf("Allocations per number: channel %.1f, pull %.1f", chanAllocs, pullAllocs)
+
+	if pullAllocs != 0 {
+		t.Errorf("Expected pull iterator not to allocate, got %.1f allocations per number", pullAllocs)
+	}
+}
+
+// Compare the cost of a channel round trip with a coroutine switch of iter.Pull:
+//
+//	go test -run '^$' -bench 'ChanNumbers|NumberIterator' -benchmem ./iterators
+
+func BenchmarkChanNumbers(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	numbers := ChanNumbers(ctx)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		<-numbers
+	}
+}
+
+func BenchmarkPullNumberIterator(b *testing.B) {
+	next, stop := NewPullNumberIterator()
+	defer stop()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = next()
+	}
+}
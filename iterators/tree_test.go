@@ -0,0 +1,63 @@
+package iterators
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+// Advanced: recursive iterators.
+//
+// Walking a tree is naturally recursive, and iterators make it easy to expose the walk as a sequence:
+// the recursive function yields values of the left subtree, the node, and then the right subtree.
+// The tricky part is the stop signal. When the loop breaks deep in the recursion,
+// every level has to return immediately, so the walk function should report whether to continue:
+//
+//	func (n *Tree) push(yield func(int) bool) bool
+//
+// Let's implement in-order traversal of a binary search tree that stops as soon as the consumer is done.
+
+// Tree is a binary search tree of integers, nil is an empty tree.
+type Tree struct {
+	Left, Right *Tree
+	Value       int
+}
+
+// Insert adds the value to the tree and returns the root.
+func (t *Tree) Insert(v int) *Tree {
+	if t == nil {
+		return &Tree{Value: v}
+	}
+
+	if v < t.Value {
+		t.Left = t.Left.Insert(v)
+	} else {
+		t.Right = t.Right.Insert(v)
+	}
+
+	return t
+}
+
+// All returns values of the tree in ascending order.
+func (t *Tree) All() iter.Seq[int] {
+	return func(yield func(int) bool) {}
+}
+
+func TestTreeAll(t *testing.T) {
+	var tree *Tree
+	for _, v := range []int{5, 3, 8, 1, 4, 7, 9, 2, 6} {
+		tree = tree.Insert(v)
+	}
+
+	if got := slices.Collect(tree.All()); !slices.Equal(got, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Errorf("Expected values in ascending order, got %v", got)
+	}
+
+	if got := take(t, tree.All(), 3); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected the walk to stop after 3 values, got %v", got)
+	}
+
+	if got := slices.Collect((*Tree)(nil).All()); len(got) != 0 {
+		t.Errorf("Expected empty tree to yield nothing, got %v", got)
+	}
+}
@@ -0,0 +1,249 @@
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// The key-value store is the second capstone, it puts concurrency and error handling together in one small database:
+// - store.go: Store with Get, Set, and Delete on a map in memory,
+// - wal.go: the write-ahead log, every write is appended to it before it's applied to the map,
+// - snapshot.go: snapshots of the whole map, so the log doesn't grow forever.
+// Open recovers the map from the snapshot and then the log, the same way after a clean Close and after a crash.
+//
+// The store works on the happy path and breaks everywhere else. Exercises are in the code of the store,
+// not in test files: the tests here use it from the outside, like a service and an operator would.
+
+// clock is a clock the test moves by hand.
+type clock struct {
+	now atomic.Int64
+}
+
+func newClock() *clock {
+	c := &clock{}
+	c.now.Store(time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC).UnixNano())
+
+	return c
+}
+
+func (c *clock) Now() time.Time {
+	return time.Unix(0, c.now.Load()).UTC()
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.now.Add(int64(d))
+}
+
+// openStore opens a store in the directory, and closes it when the test ends.
+func openStore(t *testing.T, dir string, opts Options) *Store {
+	t.Helper()
+
+	s, err := Open(dir, opts)
+	if err != nil {
+		t.Fatalf("Failed to open the store: %v", err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// expectValue checks the value of the key, an empty value expects the key to be missing.
+func expectValue(t *testing.T, s *Store, key, expected string) {
+	t.Helper()
+
+	value, ok := s.Get(key)
+
+	switch {
+	case expected == "" && ok:
+		t.Errorf("Expected %s to be missing, got %q", key, value)
+	case expected != "" && (!ok || string(value) != expected):
+		t.Errorf("Expected %s to be %q, got %q, %t", key, expected, value, ok)
+	}
+}
+
+func mustSet(t *testing.T, s *Store, key, value string, ttl time.Duration) {
+	t.Helper()
+
+	if err := s.Set(key, []byte(value), ttl); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{})
+
+	mustSet(t, s, "user:1", "alice", 0)
+	mustSet(t, s, "user:2", "bob", 0)
+	mustSet(t, s, "user:1", "alice smith", 0)
+
+	if err := s.Delete("user:2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Delete("missing"); err != nil {
+		t.Errorf("Expected deleting a missing key to succeed, got %v", err)
+	}
+
+	expectValue(t, s, "user:1", "alice smith")
+	expectValue(t, s, "user:2", "")
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Set("user:3", []byte("carol"), 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+
+	// The data survives a restart.
+	reopened := openStore(t, dir, Options{})
+	expectValue(t, reopened, "user:1", "alice smith")
+	expectValue(t, reopened, "user:2", "")
+
+	if reopened.Len() != 1 {
+		t.Errorf("Expected 1 key after the restart, got %d", reopened.Len())
+	}
+}
+
+// 1. Concurrent access.
+// Set and Delete hold the lock, Get doesn't: a Get that runs together with a Set reads a map that is being written,
+// which is a data race, and the runtime may even crash with "concurrent map read and map write".
+//
+// Values are slices, and slices share their arrays. Set keeps the slice of the caller, and Get returns the slice
+// of the store, so a caller that reuses its buffer changes the stored value without the lock and without the log.
+// Copy values on the way in and on the way out, bytes.Clone does it.
+
+func TestConcurrentAccess(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	s := openStore(t, t.TempDir(), Options{})
+	wg := sync.WaitGroup{}
+
+	for w := range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 200 {
+				key := fmt.Sprintf("key-%d", i%10)
+
+				switch i % 3 {
+				case 0:
+					if err := s.Set(key, []byte(fmt.Sprint(w)), 0); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+						return
+					}
+				case 1:
+					s.Get(key)
+				case 2:
+					if err := s.Delete(key); err != nil {
+						t.Errorf("Unexpected error: %v", err)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestValuesAreCopied(t *testing.T) {
+	s := openStore(t, t.TempDir(), Options{})
+
+	buf := []byte("alice")
+	mustSet(t, s, "user:1", string(buf), 0)
+
+	if err := s.Set("user:2", buf, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The caller reuses its buffer for the next value.
+	copy(buf, "carol")
+	expectValue(t, s, "user:2", "alice")
+
+	value, _ := s.Get("user:1")
+	copy(value, "mallory")
+	expectValue(t, s, "user:1", "alice")
+}
+
+// 2. Expiration.
+// A key set with a TTL expires: Get doesn't return it anymore. Expiration is stored as an absolute time, so
+// a restart doesn't extend it, and the log doesn't need a record for it: replaying a key that has expired
+// since gives an expired key again. Get ignores expired keys, check the time with Options.Now.
+//
+// Expired keys that nobody reads stay in memory forever. A sweeper is a goroutine that removes them every
+// SweepInterval: start it in Open, and stop it in Close. Close must wait for the sweeper to return, a goroutine
+// that outlives its store is a leak, and it may still touch the map of a closed store.
+
+func TestTTL(t *testing.T) {
+	dir := t.TempDir()
+	c := newClock()
+	s := openStore(t, dir, Options{Now: c.Now})
+
+	mustSet(t, s, "session:1", "alice", time.Minute)
+	mustSet(t, s, "session:2", "bob", 5*time.Minute)
+	mustSet(t, s, "user:1", "alice", 0)
+
+	c.Advance(2 * time.Minute)
+	expectValue(t, s, "session:1", "")
+	expectValue(t, s, "session:2", "bob")
+	expectValue(t, s, "user:1", "alice")
+
+	// Setting the key again sets a new TTL.
+	mustSet(t, s, "session:1", "alice", time.Minute)
+	expectValue(t, s, "session:1", "alice")
+
+	s.Close()
+
+	// The restart doesn't extend the TTL.
+	c.Advance(90 * time.Second)
+
+	reopened := openStore(t, dir, Options{Now: c.Now})
+	expectValue(t, reopened, "session:1", "")
+	expectValue(t, reopened, "session:2", "bob")
+	expectValue(t, reopened, "user:1", "alice")
+}
+
+func TestSweeper(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+
+	c := newClock()
+
+	s, err := Open(t.TempDir(), Options{Now: c.Now, SweepInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to open the store: %v", err)
+	}
+
+	for i := range 100 {
+		mustSet(t, s, fmt.Sprintf("session:%d", i), "data", time.Minute)
+	}
+
+	mustSet(t, s, "user:1", "alice", 0)
+
+	c.Advance(time.Hour)
+
+	if !testsync.WaitUntil(func() bool { return s.Len() == 1 }, time.Second) {
+		t.Errorf("Expected the sweeper to remove expired keys from memory, %d keys left", s.Len())
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !testsync.WaitUntil(func() bool { return runtime.NumGoroutine() <= goroutines }, time.Second) {
+		t.Errorf("Expected Close to stop the sweeper, %d goroutines before Open, %d after Close",
+			goroutines, runtime.NumGoroutine())
+	}
+}
@@ -0,0 +1,38 @@
+package kvstore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Crash tests kill a process that writes to the store. The test binary plays that process itself:
+// when helperEnv is set, TestMain runs a helper instead of tests, like in the osinterop workshop.
+//
+//	cmd := exec.Command(os.Args[0], "writer", dir)
+
+const helperEnv = "KVSTORE_HELPER"
+
+// helpers are subprocess helpers by name, they get the rest of the arguments and return the exit code.
+var helpers = map[string]func(args []string) int{}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	if len(os.Args) < 2 || helpers[os.Args[1]] == nil {
+		fmt.Fprintf(os.Stderr, "unknown helper: %v\n", os.Args[1:])
+		os.Exit(2)
+	}
+
+	os.Exit(helpers[os.Args[1]](os.Args[2:]))
+}
+
+// useHelpers makes subprocesses started by the test run helpers, the variable is inherited by children.
+func useHelpers(t *testing.T) string {
+	t.Helper()
+	t.Setenv(helperEnv, "1")
+
+	return os.Args[0]
+}
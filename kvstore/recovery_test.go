@@ -0,0 +1,199 @@
+package kvstore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 3. Write-ahead log.
+// Set promises that when it returns, the value survives a crash. The log keeps the promise only when the record is
+// in the file before Set returns, and now it isn't: records wait in a bufio.Writer, and the buffer reaches the file
+// when it's full or when the store is closed. A process killed in between loses every write in the buffer,
+// and the ones it has acknowledged too. Flush the buffer on every append.
+//
+// Flushing hands the data to the OS, and that's enough to survive a killed process. Surviving a power loss
+// needs File.Sync too, which waits until the disk has the data and takes about a millisecond on an SSD.
+// Databases sync every write anyway, and batch concurrent writes into one sync to make it cheaper: group commit.
+//
+// A crash in the middle of a write leaves half a record at the end of the log, a torn write, and Open fails on it:
+// the store can't start after the crash it exists to survive. A record cut short, or one whose checksum doesn't
+// match, was never acknowledged: replay stops at it, and the log must be truncated right before it with
+// File.Truncate. Otherwise the next records are appended after the garbage, and the next replay stops
+// before them, losing writes that were acknowledged.
+
+func TestTornWrite(t *testing.T) {
+	for name, tear := range map[string]func(rec []byte) []byte{
+		"cut short":   func(rec []byte) []byte { return rec[:len(rec)/2] },
+		"corrupt":     func(rec []byte) []byte { rec[len(rec)-2] ^= 0xff; return rec },
+		"header only": func(rec []byte) []byte { return rec[:recordHeaderSize-3] },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openStore(t, dir, Options{})
+
+			mustSet(t, s, "user:1", "alice", 0)
+			mustSet(t, s, "user:2", "bob", 0)
+			s.Close()
+
+			// The process crashed while it was writing the next record.
+			var buf bytes.Buffer
+			if err := writeRecord(&buf, record{Op: opSet, Key: "user:3", Value: []byte("carol")}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			appendFile(t, filepath.Join(dir, walFile), tear(buf.Bytes()))
+
+			reopened := openStore(t, dir, Options{})
+			expectValue(t, reopened, "user:1", "alice")
+			expectValue(t, reopened, "user:2", "bob")
+			expectValue(t, reopened, "user:3", "")
+
+			// Writes after the recovery survive the next restart.
+			mustSet(t, reopened, "user:4", "dave", 0)
+			reopened.Close()
+
+			again := openStore(t, dir, Options{})
+			expectValue(t, again, "user:2", "bob")
+			expectValue(t, again, "user:4", "dave")
+		})
+	}
+}
+
+func appendFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func init() {
+	helpers["writer"] = writer
+}
+
+// writer is a helper process that writes to the store in the directory until it's killed.
+// It sets key-N to N, deletes every tenth key it has set, and prints every write once it's acknowledged:
+// "set N" or "delete N". The second argument is Options.SnapshotEvery.
+func writer(args []string) int {
+	every, _ := strconv.Atoi(args[1])
+
+	s, err := Open(args[0], Options{SnapshotEvery: every})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	for i := 0; ; i++ {
+		if err := s.Set(fmt.Sprintf("key-%d", i), []byte(strconv.Itoa(i)), 0); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		fmt.Println("set", i)
+
+		if i%10 == 9 {
+			if err := s.Delete(fmt.Sprintf("key-%d", i-5)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+
+			fmt.Println("delete", i-5)
+		}
+	}
+}
+
+// crashWriter runs the writer in a child process, kills it after it acknowledges the number of writes,
+// and returns the acknowledged keys with their values, deleted keys have empty values.
+func crashWriter(t *testing.T, dir string, acks, snapshotEvery int) map[string]string {
+	t.Helper()
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(useHelpers(t), "writer", dir, strconv.Itoa(snapshotEvery))
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start the writer: %v", err)
+	}
+
+	acked := make(map[string]string)
+	lines := bufio.NewScanner(stdout)
+	n := 0
+
+	for ; lines.Scan(); n++ {
+		// SIGKILL, the process gets no chance to clean up. Writes acknowledged before it are still in the pipe.
+		if n == acks {
+			cmd.Process.Kill()
+		}
+
+		op, i, _ := strings.Cut(lines.Text(), " ")
+
+		switch op {
+		case "set":
+			acked["key-"+i] = i
+		case "delete":
+			acked["key-"+i] = ""
+		}
+	}
+
+	cmd.Wait()
+
+	if n < acks {
+		t.Fatalf("Expected the writer to acknowledge %d writes before it was killed, got %d: %s", acks, n, stderr.String())
+	}
+
+	return acked
+}
+
+func TestCrashRecovery(t *testing.T) {
+	for _, acks := range []int{50, 137, 311} {
+		t.Run(fmt.Sprintf("killed after %d writes", acks), func(t *testing.T) {
+			testCrashRecovery(t, acks, 0)
+		})
+	}
+}
+
+// testCrashRecovery kills the writer, and checks that every acknowledged write survived it.
+func testCrashRecovery(t *testing.T, acks, snapshotEvery int) {
+	t.Helper()
+
+	dir := t.TempDir()
+	acked := crashWriter(t, dir, acks, snapshotEvery)
+
+	start := time.Now()
+	s := openStore(t, dir, Options{})
+
+	lost := 0
+
+	for key, value := range acked {
+		got, ok := s.Get(key)
+		if (value == "" && ok) || (value != "" && string(got) != value) {
+			lost++
+		}
+	}
+
+	if lost > 0 {
+		t.Errorf("Expected every acknowledged write to survive the crash, lost %d of %d", lost, len(acked))
+	}
+
+	t.Logf("Recovered %d keys in %s", s.Len(), time.Since(start))
+}
@@ -0,0 +1,63 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// snapshotFile is the name of the snapshot in the directory of the store.
+const snapshotFile = "snapshot.json"
+
+// loadSnapshot applies the records of the snapshot, a store without a snapshot starts empty.
+func (s *Store) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		s.apply(rec)
+	}
+
+	return nil
+}
+
+// Snapshot writes all keys to the snapshot, so the log can start over: a store without snapshots
+// replays every write it has ever had when it opens.
+func (s *Store) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	records := make([]record, 0, len(s.data))
+	for key, e := range s.data {
+		records = append(records, record{Op: opSet, Key: key, Value: e.value, ExpiresAt: e.expiresAt})
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(s.dir, snapshotFile), data, 0o644); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	return nil
+}
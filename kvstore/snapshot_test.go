@@ -0,0 +1,152 @@
+package kvstore
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testsync"
+)
+
+// 4. Advanced: Snapshots.
+// The log grows with every write, and Open replays all of it: a store that has set one key a million times
+// reads a million records to find one value. A snapshot writes the data in memory to a file, and the log
+// starts over from it: Open loads the snapshot and replays only the writes made after it.
+//
+// Snapshot writes the file and forgets the log, so the log keeps growing and Open replays it all anyway.
+// After the snapshot is written, truncate the log and reset the counter of its records.
+//
+// The order of the two steps decides what a crash between them does. A snapshot written before the log
+// is truncated is followed by records it already contains, and replaying them again gives the same data:
+// records are idempotent, a set is a set, and expiration is an absolute time. The opposite order loses the data.
+//
+// The snapshot itself is written in place, so a crash in the middle leaves half a file, and Open can't load it.
+// Write a temporary file in the same directory, Sync it, and Rename it over the snapshot: a rename replaces
+// the file atomically, readers see the old snapshot or the new one and never a mix of them.
+//
+// Options.SnapshotEvery asks for a snapshot every N records in the log, and nothing takes them yet.
+// Take them in a background goroutine, writes shouldn't wait for a snapshot of the whole store,
+// and stop it in Close like the sweeper. A non-blocking send on a channel with a buffer of one
+// wakes the goroutine without queuing up snapshots it would take one after another.
+
+// walSize returns the size of the log in the directory.
+func walSize(t *testing.T, dir string) int64 {
+	t.Helper()
+
+	info, err := os.Stat(filepath.Join(dir, walFile))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return info.Size()
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{})
+
+	for i := range 100 {
+		mustSet(t, s, fmt.Sprintf("key-%d", i%10), fmt.Sprint(i), 0)
+	}
+
+	if err := s.Delete("key-0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A copy of the log before the snapshot, to replay it after the snapshot.
+	oldWAL, err := os.ReadFile(filepath.Join(dir, walFile))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Snapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if size := walSize(t, dir); size != 0 {
+		t.Errorf("Expected the log to be empty after a snapshot, got %d bytes", size)
+	}
+
+	mustSet(t, s, "key-1", "updated", 0)
+	s.Close()
+
+	reopened := openStore(t, dir, Options{})
+	expectValue(t, reopened, "key-0", "")
+	expectValue(t, reopened, "key-1", "updated")
+	expectValue(t, reopened, "key-9", "99")
+
+	if reopened.Len() != 9 {
+		t.Errorf("Expected 9 keys after the restart, got %d", reopened.Len())
+	}
+
+	reopened.Close()
+
+	// The process crashed after it wrote the snapshot, and before it truncated the log.
+	if err := os.WriteFile(filepath.Join(dir, walFile), oldWAL, 0o644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	replayed := openStore(t, dir, Options{})
+	expectValue(t, replayed, "key-0", "")
+	expectValue(t, replayed, "key-9", "99")
+
+	// Expired keys don't come back from the snapshot either.
+	c := newClock()
+	expiring := openStore(t, t.TempDir(), Options{Now: c.Now})
+	mustSet(t, expiring, "session:1", "alice", time.Minute)
+
+	if err := expiring.Snapshot(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expiring.Close()
+	c.Advance(time.Hour)
+
+	expectValue(t, openStore(t, expiring.dir, Options{Now: c.Now}), "session:1", "")
+}
+
+func TestPeriodicSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir, Options{SnapshotEvery: 50})
+
+	var buf bytes.Buffer
+	if err := writeRecord(&buf, record{Op: opSet, Key: "key-10", Value: []byte("1000")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	recordSize := int64(buf.Len())
+
+	var maxSize int64
+
+	for i := range 1000 {
+		mustSet(t, s, fmt.Sprintf("key-%d", i%20), fmt.Sprint(i), 0)
+
+		maxSize = max(maxSize, walSize(t, dir))
+	}
+
+	// A snapshot in the background may lag behind the writes, but not by hundreds of records.
+	settled := testsync.WaitUntil(func() bool { return walSize(t, dir) <= 50*recordSize }, time.Second)
+
+	if !settled || maxSize > 500*recordSize {
+		t.Errorf("Expected snapshots to keep the log under 50 records, it grew to %d and ended with %d",
+			maxSize/recordSize, walSize(t, dir)/recordSize)
+	}
+
+	s.Close()
+
+	reopened := openStore(t, dir, Options{})
+	for i := 980; i < 1000; i++ {
+		expectValue(t, reopened, fmt.Sprintf("key-%d", i%20), fmt.Sprint(i))
+	}
+}
+
+func TestCrashRecoveryWithSnapshots(t *testing.T) {
+	for _, acks := range []int{50, 137, 311} {
+		t.Run(fmt.Sprintf("killed after %d writes", acks), func(t *testing.T) {
+			testCrashRecovery(t, acks, 20)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package kvstore is a key-value store in memory, persisted with a write-ahead log and snapshots.
+// It works on the happy path, the tests in this directory lead through everything off it.
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by writes to a closed store.
+var ErrClosed = errors.New("store is closed")
+
+// Options configure a store, zero values are replaced with defaults.
+type Options struct {
+	// Now returns the current time, tests replace it. It's time.Now by default.
+	Now func() time.Time
+
+	// SweepInterval is how often expired keys are removed from memory, a minute by default.
+	SweepInterval time.Duration
+
+	// SnapshotEvery is the number of records in the log that triggers a snapshot in the background,
+	// 0 disables snapshots, Snapshot can still be called directly.
+	SnapshotEvery int
+}
+
+// entry is a value in memory, a zero expiresAt means the key never expires.
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// Store is a key-value store, it's safe for concurrent use.
+type Store struct {
+	dir  string
+	opts Options
+
+	mu     sync.RWMutex
+	data   map[string]entry
+	wal    *wal
+	closed bool
+}
+
+// Open opens the store in the directory, creating it if it doesn't exist, and recovers the data
+// from the snapshot and the log.
+func Open(dir string, opts Options) (*Store, error) {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	if opts.SweepInterval == 0 {
+		opts.SweepInterval = time.Minute
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+
+	s := &Store{dir: dir, opts: opts, data: make(map[string]entry)}
+
+	if err := s.loadSnapshot(); err != nil {
+		return nil, fmt.Errorf("load snapshot: %w", err)
+	}
+
+	w, err := openWAL(filepath.Join(dir, walFile), s.apply)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+
+	s.wal = w
+
+	return s, nil
+}
+
+// Get returns the value of the key, ok is false if the key doesn't exist or has expired.
+func (s *Store) Get(key string) (value []byte, ok bool) {
+	e, ok := s.data[key]
+
+	return e.value, ok
+}
+
+// Set sets the value of the key. The key expires after ttl, a zero ttl keeps it forever.
+// When Set returns, the value survives a crash.
+func (s *Store) Set(key string, value []byte, ttl time.Duration) error {
+	rec := record{Op: opSet, Key: key, Value: value}
+	if ttl > 0 {
+		rec.ExpiresAt = s.opts.Now().Add(ttl)
+	}
+
+	return s.write(rec)
+}
+
+// Delete deletes the key, deleting a missing key is not an error.
+func (s *Store) Delete(key string) error {
+	return s.write(record{Op: opDelete, Key: key})
+}
+
+// write appends the record to the log, and applies it to the data in memory.
+func (s *Store) write(rec record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if err := s.wal.append(rec); err != nil {
+		return fmt.Errorf("%s %s: %w", rec.Op, rec.Key, err)
+	}
+
+	s.apply(rec)
+
+	return nil
+}
+
+// apply applies the record to the data in memory, s.mu must be held or the store not shared yet.
+func (s *Store) apply(rec record) {
+	switch rec.Op {
+	case opSet:
+		s.data[rec.Key] = entry{value: rec.Value, expiresAt: rec.ExpiresAt}
+	case opDelete:
+		delete(s.data, rec.Key)
+	}
+}
+
+// Len returns the number of keys in memory, including expired keys the sweeper hasn't removed yet.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.data)
+}
+
+// Close closes the log, writes after Close fail with ErrClosed.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+
+	return s.wal.close()
+}
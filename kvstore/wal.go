@@ -0,0 +1,151 @@
+package kvstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// walFile is the name of the write-ahead log in the directory of the store.
+const walFile = "wal.log"
+
+const (
+	opSet    = "set"
+	opDelete = "delete"
+)
+
+// record is a write to the store. Expiration is an absolute time, so replaying a record
+// any number of times, at any time, gives the same result.
+type record struct {
+	Op        string    `json:"op"`
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// recordHeaderSize is the size of the header of a record in the log:
+//
+//	| length of the payload: uint32 | CRC-32 of the payload: uint32 | payload: JSON of the record |
+const recordHeaderSize = 8
+
+// errCorruptRecord is returned by readRecord for a record whose checksum doesn't match its payload.
+var errCorruptRecord = errors.New("corrupt record")
+
+// writeRecord writes the record with its header.
+func writeRecord(w io.Writer, rec record) error {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+
+	if _, err := w.Write(append(buf, payload...)); err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+
+	return nil
+}
+
+// readRecord reads the next record and returns it with its size in bytes. At the end of the log it returns io.EOF,
+// io.ErrUnexpectedEOF for a record cut short, and errCorruptRecord for a record that doesn't match its checksum.
+func readRecord(r io.Reader) (record, int, error) {
+	var header [recordHeaderSize]byte
+
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return record{}, 0, err
+	}
+
+	payload := make([]byte, binary.LittleEndian.Uint32(header[0:4]))
+
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return record{}, 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+		return record{}, 0, errCorruptRecord
+	}
+
+	var rec record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return record{}, 0, fmt.Errorf("%w: %w", errCorruptRecord, err)
+	}
+
+	return rec, recordHeaderSize + len(payload), nil
+}
+
+// wal is the write-ahead log: every write is appended to it before it's applied in memory.
+type wal struct {
+	f *os.File
+	w *bufio.Writer
+
+	// records is the number of records in the log.
+	records int
+}
+
+// openWAL opens the log at the path and replays its records with apply.
+func openWAL(path string, apply func(record)) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &wal{f: f}
+	r := bufio.NewReader(f)
+
+	for {
+		rec, _, err := readRecord(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("replay %s: %w", path, err)
+		}
+
+		apply(rec)
+		w.records++
+	}
+
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	w.w = bufio.NewWriter(f)
+
+	return w, nil
+}
+
+// append adds the record to the end of the log.
+func (w *wal) append(rec record) error {
+	if err := writeRecord(w.w, rec); err != nil {
+		return err
+	}
+
+	w.records++
+
+	return nil
+}
+
+func (w *wal) close() error {
+	if err := w.w.Flush(); err != nil {
+		w.f.Close()
+		return fmt.Errorf("flush log: %w", err)
+	}
+
+	return w.f.Close()
+}
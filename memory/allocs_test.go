@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// Allocations that can't be avoided by escape analysis can often be avoided by allocating the right size once.
+// testing.AllocsPerRun counts heap allocations of a function, it's a precise way to pin the number in a test,
+// and `go test -bench . -benchmem` shows allocations per operation in benchmarks.
+
+// 3. Pre-sized slices.
+// append grows a full slice by allocating a bigger array and copying elements into it,
+// so a slice built from nothing with n appends is reallocated about log(n) times, and every old array is garbage.
+// When the maximum length is known, allocate it once: make([]T, 0, n).
+//
+// Fix ActiveIDs to allocate only once.
+
+// User is an account of the service.
+type User struct {
+	ID     int64
+	Active bool
+}
+
+// ActiveIDs returns IDs of active users.
+func ActiveIDs(users []User) []int64 {
+	var ids []int64
+
+	for _, u := range users {
+		if u.Active {
+			ids = append(ids, u.ID)
+		}
+	}
+
+	return ids
+}
+
+func TestActiveIDsAllocs(t *testing.T) {
+	users := make([]User, 1000)
+	for i := range users {
+		users[i] = User{ID: int64(i), Active: i%3 != 0}
+	}
+
+	ids := ActiveIDs(users)
+	if len(ids) != 666 || ids[0] != 1 || ids[len(ids)-1] != 998 {
+		t.Fatalf("Expected 666 active users from 1 to 998, got %d", len(ids))
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { ActiveIDs(users) }); allocs > 1 {
+		t.Errorf("Expected at most 1 allocation, got %.0f", allocs)
+	}
+}
+
+// 4. Building strings.
+// Strings are immutable, so s += x allocates a new string and copies both parts, building a string of n parts
+// this way copies O(n^2) bytes. strings.Builder appends to a growing []byte and returns it as a string without a copy.
+// When the size is known, Grow allocates the whole buffer at once.
+//
+// Fix JoinTags to allocate only once.
+
+// Tag is a key and a value attached to a metric.
+type Tag struct {
+	Key, Value string
+}
+
+// JoinTags formats tags like "env=prod,region=eu".
+func JoinTags(tags []Tag) string {
+	s := ""
+
+	for i, tag := range tags {
+		if i > 0 {
+			s += ","
+		}
+
+		s += tag.Key + "=" + tag.Value
+	}
+
+	return s
+}
+
+func TestJoinTagsAllocs(t *testing.T) {
+	tags := make([]Tag, 50)
+	for i := range tags {
+		tags[i] = Tag{Key: fmt.Sprintf("key%d", i), Value: fmt.Sprintf("value%d", i)}
+	}
+
+	got := JoinTags(tags)
+	if !strings.HasPrefix(got, "key0=value0,key1=value1,") || !strings.HasSuffix(got, ",key49=value49") {
+		t.Fatalf("Unexpected result: %q", got)
+	}
+
+	if got := JoinTags(nil); got != "" {
+		t.Errorf("Expected empty string for no tags, got %q", got)
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { JoinTags(tags) }); allocs > 1 {
+		t.Errorf("Expected at most 1 allocation, got %.0f", allocs)
+	}
+}
+
+// 5. Reading memory statistics.
+// runtime.ReadMemStats fills runtime.MemStats with everything the runtime knows about memory:
+// - HeapAlloc is bytes of allocated heap objects, live and not yet collected,
+// - TotalAlloc and Mallocs are cumulative bytes and objects allocated since the start, they never decrease,
+// - NumGC is the number of completed GC cycles, PauseTotalNs is the time the world was stopped for them.
+// ReadMemStats stops the world too, so it's fine in tests and debug endpoints, not on every request.
+// For monitoring, runtime/metrics reads the same numbers without stopping the world.
+//
+// Implement MeasureAllocs with two calls of ReadMemStats around fn.
+
+// Allocs is the amount of memory allocated on the heap.
+type Allocs struct {
+	Objects uint64
+	Bytes   uint64
+}
+
+// MeasureAllocs returns the heap memory allocated while fn runs.
+func MeasureAllocs(fn func()) Allocs {
+	fn()
+
+	return Allocs{}
+}
+
+var sink [][]byte
+
+func TestMeasureAllocs(t *testing.T) {
+	got := MeasureAllocs(func() {
+		for range 100 {
+			sink = append(sink, make([]byte, 1024))
+		}
+	})
+
+	sink = nil
+
+	if got.Objects < 100 || got.Bytes < 100*1024 {
+		t.Errorf("Expected at least 100 objects and 100 KiB, got %d objects and %d bytes", got.Objects, got.Bytes)
+	}
+
+	var x int
+
+	got = MeasureAllocs(func() {
+		for i := range 100 {
+			x += i
+		}
+	})
+
+	if got.Objects > 10 {
+		t.Errorf("Expected almost no allocations in a loop without them, got %d objects", got.Objects)
+	}
+
+	runtime.KeepAlive(x)
+}
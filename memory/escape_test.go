@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"bufio"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Go has no new vs stack keywords: the compiler decides where every value lives.
+// A value stays on the stack of its goroutine when the compiler can prove it's not used after the function returns,
+// otherwise it "escapes" to the heap. That's escape analysis.
+//
+// Stack allocation is almost free: the stack frame is dropped on return, and the GC never sees the value.
+// A heap allocation costs a call into the allocator, and later the GC has to find and free it.
+// Common reasons to escape:
+// - returning a pointer to a local variable, or storing it in a global, a channel, or a heap value,
+// - converting a value to an interface, when the compiler can't see what the method does with it,
+// - slices with a size not known at compile time, or too large for the stack,
+// - closures that capture variables and outlive the function.
+//
+// The compiler explains its decisions with -gcflags=-m:
+//
+//	go build -gcflags=-m ./memory
+//	go test -c -o /dev/null -gcflags=-m ./memory
+//
+// Every line is a diagnostic at a position: `./escape_test.go:83:23: []float64{...} escapes to heap`
+// or `./escape_test.go:80:2: moved to heap: x` for variables. Add -m=2 to see why.
+
+// 1. Reading escape analysis output.
+// The output is long and most of it is about inlining, so let's make it machine readable.
+// Implement ParseEscapes, it returns heap allocations from the output of -gcflags=-m:
+// lines with "escapes to heap" and "moved to heap: name". What is the expression or the name of the variable.
+// Lines like "# package", "does not escape", "leaking param", and "can inline" are skipped,
+// as well as positions without a column, like `<autogenerated>:1:`.
+
+// Escape is a value allocated on the heap, reported by the compiler.
+type Escape struct {
+	File string
+	Line int
+	What string
+}
+
+// ParseEscapes returns heap allocations reported in the output of go build -gcflags=-m.
+func ParseEscapes(output string) []Escape {
+	return nil
+}
+
+func TestParseEscapes(t *testing.T) {
+	output := `# github.com/ksysoev/go-workshops/memory [github.com/ksysoev/go-workshops/memory.test]
+./escape_test.go:79:6: can inline Median
+./escape_test.go:80:23: []float64{...} escapes to heap
+./escape_test.go:81:29: sort.Float64Slice(readings) escapes to heap
+./escape_test.go:92:12: t does not escape
+./allocs_test.go:30:2: moved to heap: total
+./allocs_test.go:41:17: leaking param: users
+/usr/local/go/src/sort/sort.go:47:6: can inline Sort
+# github.com/ksysoev/go-workshops/memory.test
+_testmain.go:44:42: testdeps.TestDeps{} escapes to heap
+<autogenerated>:1: &reflect.ValueError{...} escapes to heap
+`
+
+	expected := []Escape{
+		{File: "./escape_test.go", Line: 80, What: "[]float64{...}"},
+		{File: "./escape_test.go", Line: 81, What: "sort.Float64Slice(readings)"},
+		{File: "./allocs_test.go", Line: 30, What: "total"},
+		{File: "_testmain.go", Line: 44, What: "testdeps.TestDeps{}"},
+	}
+
+	if got := ParseEscapes(output); !slices.Equal(got, expected) {
+		t.Errorf("Expected escapes:\n%v\ngot:\n%v", expected, got)
+	}
+}
+
+// 2. Keeping a value on the stack.
+// Median runs for every sample of a sensor, and the profile shows two allocations per call.
+// The slice literal alone would stay on the stack, it has a constant size and doesn't outlive the function.
+// But sort.Sort takes a sort.Interface, and the compiler can't prove that Sort doesn't keep the slice somewhere,
+// so the conversion to the interface moves the slice to the heap.
+//
+// Refactor Median, so nothing in it escapes. The test compiles the package with -gcflags=-m
+// and checks the output with ParseEscapes from above, so solve the previous exercise first.
+
+// Median returns the median of three readings.
+func Median(a, b, c float64) float64 {
+	readings := []float64{a, b, c}
+	sort.Sort(sort.Float64Slice(readings))
+
+	return readings[1]
+}
+
+func TestMedianStaysOnStack(t *testing.T) {
+	for _, tt := range [][4]float64{{1, 2, 3, 2}, {3, 1, 2, 2}, {2, 3, 1, 2}, {-1, -1, 5, -1}} {
+		if got := Median(tt[0], tt[1], tt[2]); got != tt[3] {
+			t.Errorf("Expected median of %v to be %v, got %v", tt[:3], tt[3], got)
+		}
+	}
+
+	first, last := funcLines(t, "escape_test.go", "Median")
+
+	for _, e := range compilerEscapes(t) {
+		if filepath.Base(e.File) == "escape_test.go" && e.Line >= first && e.Line <= last {
+			t.Errorf("Expected nothing in Median to escape, %s at line %d escapes to heap", e.What, e.Line)
+		}
+	}
+}
+
+// compilerEscapes compiles tests of the package with escape analysis diagnostics.
+// The build cache keeps diagnostics, so only the first run after a change takes time.
+func compilerEscapes(t *testing.T) []Escape {
+	t.Helper()
+
+	out, err := exec.Command("go", "test", "-c", "-o", os.DevNull, "-gcflags=-m", ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to compile the package: %v\n%s", err, out)
+	}
+
+	escapes := ParseEscapes(string(out))
+	if len(escapes) == 0 {
+		t.Fatalf("Expected ParseEscapes to find heap allocations in the compiler output, got none:\n%s", firstLines(out, 20))
+	}
+
+	return escapes
+}
+
+// funcLines returns the first and the last line of the function declared in the file.
+func funcLines(t *testing.T, file, name string) (first, last int) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == name {
+			return fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+		}
+	}
+
+	t.Fatalf("Function %s is not found in %s", name, file)
+
+	return 0, 0
+}
+
+func firstLines(out []byte, n int) string {
+	var lines []string
+
+	s := bufio.NewScanner(strings.NewReader(string(out)))
+	for s.Scan() && len(lines) < n {
+		lines = append(lines, s.Text())
+	}
+
+	return strings.Join(lines, "\n")
+}
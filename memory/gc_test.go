@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// 6. Tuning the GC.
+// The GC starts a new cycle when the heap grows by GOGC percent over the live heap left after the previous one.
+// With the default GOGC=100 and 16 MiB of live data, the heap grows to about 32 MiB before the next cycle:
+// a lower GOGC means a smaller heap and more CPU spent in the GC, a higher one means the opposite.
+//
+// GOMEMLIMIT (Go 1.19) is a soft limit of the memory of the runtime: close to it, the GC runs more often regardless of GOGC.
+// GOGC=off with GOMEMLIMIT set to the memory of a container is a common setup: no GC cycles until the heap
+// approaches the limit. Keep a margin, if live data alone exceeds the limit, the program spends all its time in the GC.
+//
+// Both are environment variables read at start, and can be changed at runtime with
+// debug.SetGCPercent and debug.SetMemoryLimit, both return the previous value.
+//
+// Implement WithGCSettings, it must restore the previous settings when fn returns or panics.
+// The test runs a workload with the settings and reports the peak of the heap, try other values yourself:
+//
+//	go test -run WithGCSettings -v ./memory
+
+// WithGCSettings runs fn with GOGC set to gcPercent and GOMEMLIMIT set to memoryLimit in bytes.
+// A negative gcPercent turns the GC off, math.MaxInt64 means no memory limit.
+func WithGCSettings(gcPercent int, memoryLimit int64, fn func()) {
+	fn()
+}
+
+const (
+	liveSize    = 16 << 20
+	garbageSize = 256 << 10
+)
+
+// heapPeak runs a workload that keeps liveSize bytes alive and churns through garbage,
+// and returns the peak of the heap it observed and the number of GC cycles.
+func heapPeak() (peak uint64, cycles uint32) {
+	live := make([][]byte, liveSize/garbageSize)
+	for i := range live {
+		live[i] = make([]byte, garbageSize)
+	}
+
+	// Start from a collected heap, so the goal of the next cycle is based on the live data.
+	runtime.GC()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	startGC := stats.NumGC
+
+	for i := range 400 {
+		sink = append(sink[:0], make([]byte, garbageSize))
+
+		if i%8 == 0 {
+			runtime.ReadMemStats(&stats)
+			peak = max(peak, stats.HeapAlloc)
+		}
+	}
+
+	sink = nil
+	runtime.KeepAlive(live)
+
+	return peak, stats.NumGC - startGC
+}
+
+func TestWithGCSettings(t *testing.T) {
+	gcPercent := debug.SetGCPercent(100)
+	debug.SetGCPercent(gcPercent)
+
+	memoryLimit := debug.SetMemoryLimit(-1)
+
+	run := func(name string, gcPercent int, memoryLimit int64) (peak uint64, cycles uint32) {
+		WithGCSettings(gcPercent, memoryLimit, func() {
+			peak, cycles = heapPeak()
+		})
+
+		t.Logf("%-26s peak heap %3d MiB, %3d GC cycles", name, peak>>20, cycles)
+
+		return peak, cycles
+	}
+
+	low, _ := run("GOGC=25", 25, math.MaxInt64)
+	high, _ := run("GOGC=400", 400, math.MaxInt64)
+
+	if low*2 > high {
+		t.Errorf("Expected the heap with GOGC=25 to be at least 2 times smaller than with GOGC=400, got %d MiB and %d MiB",
+			low>>20, high>>20)
+	}
+
+	limit := int64(48 << 20)
+
+	peak, cycles := run("GOGC=off GOMEMLIMIT=48MiB", -1, limit)
+	if peak > uint64(limit) || cycles == 0 {
+		t.Errorf("Expected the GC to keep the heap under the limit of %d MiB, got %d MiB and %d GC cycles",
+			limit>>20, peak>>20, cycles)
+	}
+
+	func() {
+		defer func() { recover() }()
+
+		WithGCSettings(10, limit, func() { panic("workload failed") })
+	}()
+
+	if got := debug.SetGCPercent(gcPercent); got != gcPercent {
+		t.Errorf("Expected GOGC to be restored to %d, got %d", gcPercent, got)
+	}
+
+	if got := debug.SetMemoryLimit(memoryLimit); got != memoryLimit {
+		t.Errorf("Expected GOMEMLIMIT to be restored to %d, got %d", memoryLimit, got)
+	}
+}
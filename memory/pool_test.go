@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Advanced: pooling buffers.
+//
+// A server that formats every response into a fresh buffer allocates as much garbage as it sends.
+// sync.Pool keeps released objects for reuse: Get returns one of them or calls New, Put returns an object to the pool.
+// The pool is cleared gradually by the GC, so it doesn't hold memory that is no longer needed, and it's per-P,
+// so goroutines rarely contend for it. fmt and encoding/json use pools for their buffers.
+//
+// Two rules make a pool correct:
+// - an object must be reset before reuse, it comes back with the state of its previous user,
+// - an object must not be used after Put, another goroutine may already own it.
+// And one more makes it efficient: don't put back unusually large objects. A buffer that grew to megabytes
+// for one big event stays in the pool, and every Get pins that memory for a tiny event (see golang/go#23199).
+//
+// Let's make Render allocate nothing in the steady state with bufferPool, and drop buffers larger than maxPooledBuffer.
+
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// Event is a record of the event log.
+type Event struct {
+	ID      int64
+	Kind    string
+	Payload string
+}
+
+// Render writes the event as a line "id kind payload" to w with a single Write.
+func Render(w io.Writer, e Event) error {
+	buf := new(bytes.Buffer)
+
+	buf.Write(strconv.AppendInt(buf.AvailableBuffer(), e.ID, 10))
+	buf.WriteByte(' ')
+	buf.WriteString(e.Kind)
+	buf.WriteByte(' ')
+	buf.WriteString(e.Payload)
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+func TestRender(t *testing.T) {
+	var out bytes.Buffer
+
+	for _, e := range []Event{{1, "created", "order 42"}, {2, "paid", "9.99 EUR"}} {
+		if err := Render(&out, e); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if expected := "1 created order 42\n2 paid 9.99 EUR\n"; out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestRenderAllocs(t *testing.T) {
+	// The race detector drops random objects put into a pool to catch code that relies on reuse.
+	testutil.SkipWithRaceDetector(t)
+
+	e := Event{ID: 123456, Kind: "shipped", Payload: strings.Repeat("x", 512)}
+
+	if allocs := testing.AllocsPerRun(100, func() { Render(io.Discard, e) }); allocs > 0 {
+		t.Errorf("Expected no allocations with pooled buffers, got %.0f", allocs)
+	}
+}
+
+func TestRenderDropsLargeBuffers(t *testing.T) {
+	huge := Event{ID: 1, Kind: "snapshot", Payload: strings.Repeat("x", 1<<20)}
+
+	for range 10 {
+		if err := Render(io.Discard, huge); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	var taken []*bytes.Buffer
+
+	for range 10 {
+		buf := bufferPool.Get().(*bytes.Buffer)
+		if buf.Cap() > maxPooledBuffer {
+			t.Fatalf("Expected buffers larger than %d bytes to be dropped, got one of %d bytes in the pool", maxPooledBuffer, buf.Cap())
+		}
+
+		taken = append(taken, buf)
+	}
+
+	for _, buf := range taken {
+		bufferPool.Put(buf)
+	}
+}
@@ -0,0 +1,229 @@
+package messaging
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Broker is an in-memory message broker with at-least-once delivery, a fake of Kafka or NATS JetStream for tests.
+// A fetched message is in flight until it's acked. A message that isn't acked within ackWait, or is nacked,
+// is delivered again, and its Attempt grows with every delivery.
+type Broker struct {
+	mu       sync.Mutex
+	ackWait  time.Duration
+	topics   map[string]*queue
+	lostAcks int
+	stats    map[string]*stats
+}
+
+type queue struct {
+	ready     []*entry
+	inFlight  map[string]*entry
+	published []Message
+}
+
+type entry struct {
+	delivery Delivery
+	// due is when the message is delivered again: the ack deadline of a message in flight,
+	// or the end of the delay of a nacked one.
+	due    time.Time
+	nacked bool
+}
+
+type stats struct {
+	acks       int
+	nackDelays []time.Duration
+}
+
+// NewBroker creates a broker that redelivers messages not acked within ackWait.
+func NewBroker(ackWait time.Duration) *Broker {
+	return &Broker{ackWait: ackWait, topics: make(map[string]*queue), stats: make(map[string]*stats)}
+}
+
+func (b *Broker) queue(topic string) *queue {
+	q, ok := b.topics[topic]
+	if !ok {
+		q = &queue{inFlight: make(map[string]*entry)}
+		b.topics[topic] = q
+	}
+
+	return q
+}
+
+func (b *Broker) stat(id string) *stats {
+	s, ok := b.stats[id]
+	if !ok {
+		s = &stats{}
+		b.stats[id] = s
+	}
+
+	return s
+}
+
+// Publish adds the message to the topic.
+func (b *Broker) Publish(_ context.Context, topic string, msg Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(topic)
+	q.ready = append(q.ready, &entry{delivery: Delivery{Message: msg}})
+	q.published = append(q.published, msg)
+
+	return nil
+}
+
+// Subscribe returns a subscription to the topic. Subscriptions of a topic share its messages,
+// like consumers of a group in Kafka.
+func (b *Broker) Subscribe(topic string) Subscription {
+	return &subscription{broker: b, topic: topic}
+}
+
+// LoseAcks makes the broker ignore the next n acks, like acks lost in the network.
+func (b *Broker) LoseAcks(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lostAcks = n
+}
+
+// Acks returns how many times the message was acked.
+func (b *Broker) Acks(id string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.stat(id).acks
+}
+
+// NackDelays returns delays of redelivery requested by nacks of the message.
+func (b *Broker) NackDelays(id string) []time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]time.Duration(nil), b.stat(id).nackDelays...)
+}
+
+// Published returns messages published to the topic.
+func (b *Broker) Published(topic string) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.queue(topic).published...)
+}
+
+// Pending returns the number of messages of the topic that are not acked yet, in flight or waiting for delivery.
+func (b *Broker) Pending(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(topic)
+
+	return len(q.ready) + len(q.inFlight)
+}
+
+// InFlight returns the number of messages of the topic that are fetched and not acked yet.
+func (b *Broker) InFlight(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+
+	for _, e := range b.queue(topic).inFlight {
+		if !e.nacked {
+			n++
+		}
+	}
+
+	return n
+}
+
+type subscription struct {
+	broker *Broker
+	topic  string
+}
+
+// Fetch blocks until a message is ready for delivery or the context is done.
+func (s *subscription) Fetch(ctx context.Context) (Delivery, error) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if d, ok := s.next(); ok {
+			return d, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Delivery{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *subscription) next() (Delivery, bool) {
+	b := s.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q := b.queue(s.topic)
+	now := time.Now()
+
+	// Messages whose ack deadline or nack delay passed are delivered again, in the order of their IDs.
+	for _, id := range slices.Sorted(maps.Keys(q.inFlight)) {
+		if e := q.inFlight[id]; now.After(e.due) {
+			delete(q.inFlight, id)
+			q.ready = append(q.ready, e)
+		}
+	}
+
+	if len(q.ready) == 0 {
+		return Delivery{}, false
+	}
+
+	e := q.ready[0]
+	q.ready = q.ready[1:]
+
+	e.delivery.Attempt++
+	e.due = now.Add(b.ackWait)
+	e.nacked = false
+	q.inFlight[e.delivery.ID] = e
+
+	return e.delivery, true
+}
+
+func (s *subscription) Ack(_ context.Context, id string) error {
+	b := s.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lostAcks > 0 {
+		b.lostAcks--
+		return nil
+	}
+
+	delete(b.queue(s.topic).inFlight, id)
+	b.stat(id).acks++
+
+	return nil
+}
+
+func (s *subscription) Nack(_ context.Context, id string, delay time.Duration) error {
+	b := s.broker
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.queue(s.topic).inFlight[id]; ok {
+		e.due = time.Now().Add(delay)
+		e.nacked = true
+	}
+
+	st := b.stat(id)
+	st.nackDelays = append(st.nackDelays, delay)
+
+	return nil
+}
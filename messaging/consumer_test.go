@@ -0,0 +1,369 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Brokers like Kafka, NATS JetStream, and RabbitMQ deliver messages at least once: a consumer acks a message
+// after it's processed, and a message without an ack is delivered again, to this consumer or another one.
+// Nothing is lost when a consumer crashes in the middle, but anything can be delivered twice,
+// so processing must be idempotent. Exactly-once delivery doesn't exist, exactly-once processing is built on top.
+//
+// Tests use Broker from broker_test.go, an in-memory fake with the semantics of a real broker:
+// messages not acked within the ack wait are redelivered, nacked messages are redelivered after a delay,
+// and acks can get lost. docker-compose.yml starts NATS with JetStream to try the same with a real one.
+
+// Message is a message of a topic.
+type Message struct {
+	ID      string
+	Body    []byte
+	Headers map[string]string
+}
+
+// Delivery is a message delivered to a consumer, Attempt is 1 for the first delivery.
+type Delivery struct {
+	Message
+	Attempt int
+}
+
+// Subscription is a consumer's view of a topic.
+type Subscription interface {
+	// Fetch blocks until a message is available or the context is done.
+	Fetch(ctx context.Context) (Delivery, error)
+	// Ack tells the broker the message is processed.
+	Ack(ctx context.Context, id string) error
+	// Nack asks the broker to deliver the message again after the delay.
+	Nack(ctx context.Context, id string, delay time.Duration) error
+}
+
+// Publisher publishes messages to topics.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msg Message) error
+}
+
+// DedupStore remembers IDs of processed messages.
+type DedupStore interface {
+	Seen(ctx context.Context, id string) (bool, error)
+	Mark(ctx context.Context, id string) error
+}
+
+// Handler processes a message.
+type Handler func(ctx context.Context, d Delivery) error
+
+// ErrPermanent marks errors that retries can't fix, like a malformed message.
+var ErrPermanent = errors.New("permanent error")
+
+// 1. Manual acks.
+// Run acks every message as soon as it's fetched, like auto-commit in Kafka clients. When the handler fails,
+// the message is already gone: that's at-most-once. Ack a message only after Handle succeeded,
+// and nack it when Handle fails, so it's delivered again.
+//
+// 2. Idempotent processing.
+// An ack can be lost: the consumer processed the message, but the broker never heard about it and delivers it again.
+// The handler charges a card or sends an email, it must not happen twice.
+// When Dedup is set, skip messages that are already processed, but ack them, and mark a message after Handle succeeded.
+// A crash between Handle and Mark still processes the message twice. To close that gap, the side effect and the mark
+// must be written in one database transaction, that's the inbox pattern.
+//
+// 3. Graceful shutdown.
+// On deploy, the consumer gets SIGTERM, and Run's context is cancelled. Cancelling the handler in the middle
+// wastes its work, and the message is processed again by another instance. Stop fetching new messages,
+// let the message in flight finish, and ack it. A handler that doesn't finish within DrainTimeout
+// is cancelled, and its message is nacked without a delay, so another instance picks it up right away.
+// context.WithoutCancel and context.AfterFunc help to build the context of the handler.
+// Note that a cancelled context doesn't stop Fetch from returning a message that is already available.
+
+// Consumer processes messages of a subscription.
+type Consumer struct {
+	Sub    Subscription
+	Handle Handler
+	// Dedup, if set, is used to skip messages that are already processed.
+	Dedup DedupStore
+	// DrainTimeout is how long a handler in flight has to finish after Run's context is cancelled.
+	DrainTimeout time.Duration
+	// DLQ receives messages that failed MaxAttempts times, or failed with ErrPermanent, on DLQTopic.
+	DLQ         Publisher
+	DLQTopic    string
+	MaxAttempts int
+	// Backoff is the delay before the second delivery of a failed message, it doubles with every attempt.
+	Backoff time.Duration
+}
+
+// Run processes messages until the context is cancelled.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		d, err := c.Sub.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := c.Sub.Ack(ctx, d.ID); err != nil {
+			return err
+		}
+
+		c.Handle(ctx, d)
+	}
+}
+
+// memoryDedup is a DedupStore in memory. In production it's a table in the database of the handler,
+// or keys in Redis set with SET NX and a TTL longer than the time the broker keeps messages.
+type memoryDedup struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewMemoryDedup creates an empty DedupStore in memory.
+func NewMemoryDedup() DedupStore {
+	return &memoryDedup{seen: make(map[string]bool)}
+}
+
+func (s *memoryDedup) Seen(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seen[id], nil
+}
+
+func (s *memoryDedup) Mark(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seen[id] = true
+
+	return nil
+}
+
+const topic = "orders"
+
+func publish(t *testing.T, b *Broker, ids ...string) {
+	t.Helper()
+
+	for _, id := range ids {
+		if err := b.Publish(context.Background(), topic, Message{ID: id, Body: []byte("order " + id)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// processed counts side effects of handled messages.
+type processed struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (p *processed) add(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.counts == nil {
+		p.counts = make(map[string]int)
+	}
+
+	p.counts[id]++
+}
+
+func (p *processed) count(id string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.counts[id]
+}
+
+// runConsumer runs the consumer in a goroutine. stop cancels its context, waits for Run to return,
+// and returns its error and how long it took to stop.
+func runConsumer(t *testing.T, c *Consumer) (stop func() (time.Duration, error)) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- c.Run(ctx) }()
+
+	stop = sync.OnceValues(func() (time.Duration, error) {
+		start := time.Now()
+		cancel()
+
+		select {
+		case err := <-done:
+			return time.Since(start), err
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected Run to return after its context was cancelled")
+			return 0, nil
+		}
+	})
+
+	t.Cleanup(func() { stop() })
+
+	return stop
+}
+
+// eventually waits until cond is true, or fails the test.
+func eventually(t *testing.T, cond func() bool, format string, args ...any) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManualAcks(t *testing.T) {
+	b := NewBroker(time.Second)
+	publish(t, b, "1", "2", "3", "4", "5")
+
+	var p processed
+
+	c := &Consumer{
+		Sub: b.Subscribe(topic),
+		Handle: func(_ context.Context, d Delivery) error {
+			if d.ID == "3" && d.Attempt == 1 {
+				return errors.New("database is unavailable")
+			}
+
+			p.add(d.ID)
+
+			return nil
+		},
+	}
+
+	stop := runConsumer(t, c)
+
+	eventually(t, func() bool { return b.Pending(topic) == 0 }, "Expected all messages to be acked, %d pending", b.Pending(topic))
+
+	if _, err := stop(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		if n := p.count(id); n != 1 {
+			t.Errorf("Expected message %s to be processed once, got %d times", id, n)
+		}
+	}
+
+	if n := len(b.NackDelays("3")); n != 1 {
+		t.Errorf("Expected the failed message to be nacked once, got %d nacks", n)
+	}
+}
+
+func TestIdempotentProcessing(t *testing.T) {
+	b := NewBroker(20 * time.Millisecond)
+	b.LoseAcks(2)
+	publish(t, b, "1", "2", "3", "4", "5")
+
+	var p processed
+
+	c := &Consumer{
+		Sub:   b.Subscribe(topic),
+		Dedup: NewMemoryDedup(),
+		Handle: func(_ context.Context, d Delivery) error {
+			p.add(d.ID)
+			return nil
+		},
+	}
+
+	runConsumer(t, c)
+
+	eventually(t, func() bool { return b.Pending(topic) == 0 }, "Expected all messages to be acked, %d pending", b.Pending(topic))
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		if n := p.count(id); n != 1 {
+			t.Errorf("Expected message %s to be processed once despite redelivery, got %d times", id, n)
+		}
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	b := NewBroker(time.Second)
+	publish(t, b, "1")
+
+	var p processed
+
+	started := make(chan struct{})
+
+	c := &Consumer{
+		Sub:          b.Subscribe(topic),
+		DrainTimeout: time.Second,
+		Handle: func(ctx context.Context, d Delivery) error {
+			if d.ID == "1" {
+				close(started)
+			}
+
+			select {
+			case <-time.After(50 * time.Millisecond):
+				p.add(d.ID)
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("interrupted: %w", ctx.Err())
+			}
+		},
+	}
+
+	stop := runConsumer(t, c)
+	<-started
+
+	// A message that arrives during shutdown is left for other instances.
+	publish(t, b, "2")
+
+	if _, err := stop(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if p.count("1") != 1 || b.Acks("1") != 1 {
+		t.Errorf("Expected the message in flight to be processed and acked, processed %d times and acked %d times",
+			p.count("1"), b.Acks("1"))
+	}
+
+	if p.count("2") != 0 || b.Pending(topic) != 1 || b.InFlight(topic) != 0 {
+		t.Errorf("Expected no messages to be fetched after shutdown started, got %d pending and %d in flight",
+			b.Pending(topic), b.InFlight(topic))
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	b := NewBroker(time.Minute)
+	publish(t, b, "1")
+
+	started := make(chan struct{})
+
+	c := &Consumer{
+		Sub:          b.Subscribe(topic),
+		DrainTimeout: 50 * time.Millisecond,
+		Handle: func(ctx context.Context, _ Delivery) error {
+			close(started)
+
+			<-ctx.Done()
+
+			return ctx.Err()
+		},
+	}
+
+	stop := runConsumer(t, c)
+	<-started
+
+	elapsed, err := stop()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if elapsed < 50*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the handler to get 50ms to finish, Run returned in %v", elapsed)
+	}
+
+	if b.Acks("1") != 0 || b.InFlight(topic) != 0 {
+		t.Errorf("Expected the unfinished message to be nacked for redelivery, acked %d times, %d in flight",
+			b.Acks("1"), b.InFlight(topic))
+	}
+}
@@ -0,0 +1,166 @@
+package messaging
+
+import (
+	"errors"
+	"sync"
+)
+
+// DB is an in-memory database of the orders service with transactions, a fake for the outbox exercise.
+// A transaction buffers its writes and applies all of them on commit, or none of them.
+// The outbox table keeps messages in the order of their sequence numbers, like a BIGSERIAL column.
+type DB struct {
+	mu         sync.Mutex
+	orders     map[string]Order
+	outbox     []OutboxRecord
+	nextSeq    int64
+	failCommit bool
+	failMark   bool
+}
+
+// OutboxRecord is a row of the outbox table.
+type OutboxRecord struct {
+	Seq       int64
+	Topic     string
+	Message   Message
+	Published bool
+}
+
+// errCrash is returned by operations that fail on purpose, like a process killed or a connection lost at that moment.
+var errCrash = errors.New("connection lost")
+
+// NewDB creates an empty database.
+func NewDB() *DB {
+	return &DB{orders: make(map[string]Order)}
+}
+
+// Tx is a transaction.
+type Tx struct {
+	db     *DB
+	orders []Order
+	outbox []OutboxRecord
+	done   bool
+}
+
+// Begin starts a transaction.
+func (db *DB) Begin() *Tx {
+	return &Tx{db: db}
+}
+
+// InsertOrder adds the order to the transaction.
+func (tx *Tx) InsertOrder(o Order) error {
+	tx.orders = append(tx.orders, o)
+	return nil
+}
+
+// InsertOutbox adds a message for the topic to the outbox in the transaction.
+func (tx *Tx) InsertOutbox(topic string, msg Message) error {
+	tx.outbox = append(tx.outbox, OutboxRecord{Topic: topic, Message: msg})
+	return nil
+}
+
+// Commit applies all writes of the transaction.
+func (tx *Tx) Commit() error {
+	db := tx.db
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if tx.done {
+		return errors.New("transaction is already done")
+	}
+
+	tx.done = true
+
+	if db.failCommit {
+		db.failCommit = false
+		return errCrash
+	}
+
+	for _, o := range tx.orders {
+		db.orders[o.ID] = o
+	}
+
+	for _, rec := range tx.outbox {
+		db.nextSeq++
+		rec.Seq = db.nextSeq
+		db.outbox = append(db.outbox, rec)
+	}
+
+	return nil
+}
+
+// Rollback discards the transaction, it does nothing after Commit.
+func (tx *Tx) Rollback() {
+	tx.done = true
+}
+
+// PendingOutbox returns up to limit records that are not published yet, in the order of their sequence numbers.
+// In Postgres it's SELECT ... WHERE NOT published ORDER BY seq LIMIT $1 FOR UPDATE SKIP LOCKED,
+// so several relays don't publish the same records.
+func (db *DB) PendingOutbox(limit int) ([]OutboxRecord, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var pending []OutboxRecord
+
+	for _, rec := range db.outbox {
+		if !rec.Published && len(pending) < limit {
+			pending = append(pending, rec)
+		}
+	}
+
+	return pending, nil
+}
+
+// MarkPublished marks the outbox record as published.
+func (db *DB) MarkPublished(seq int64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.failMark {
+		db.failMark = false
+		return errCrash
+	}
+
+	for i := range db.outbox {
+		if db.outbox[i].Seq == seq {
+			db.outbox[i].Published = true
+		}
+	}
+
+	return nil
+}
+
+// Order returns the order by ID.
+func (db *DB) Order(id string) (Order, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	o, ok := db.orders[id]
+
+	return o, ok
+}
+
+// Outbox returns all records of the outbox.
+func (db *DB) Outbox() []OutboxRecord {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return append([]OutboxRecord(nil), db.outbox...)
+}
+
+// FailNextCommit makes the next commit fail without applying anything.
+func (db *DB) FailNextCommit() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.failCommit = true
+}
+
+// FailNextMark makes the next MarkPublished fail, like a relay that crashed after publishing a message.
+func (db *DB) FailNextMark() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.failMark = true
+}
@@ -0,0 +1,91 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// 4. Advanced: retries and a dead-letter queue.
+// A message that fails every time, because of a bug or bad data, is redelivered forever: a poison message.
+// It burns resources, and with ordered delivery it blocks the messages behind it.
+// Retries need a limit and a delay that grows, so a struggling dependency gets time to recover.
+//
+// Nack a failed message with the delay of Backoff doubled for every attempt: Backoff, 2*Backoff, 4*Backoff.
+// After MaxAttempts failed deliveries, publish it to DLQTopic and ack it. Errors wrapping ErrPermanent
+// go to the dead-letter queue right away, retrying them is pointless.
+// A dead letter keeps the ID and the body, and has the error and the number of attempts in headers "error" and "attempts",
+// so someone can find out what happened, fix the cause, and publish it back.
+
+func TestRetryDeadLetter(t *testing.T) {
+	b := NewBroker(time.Second)
+	publish(t, b, "1", "2", "3")
+
+	var p processed
+
+	c := &Consumer{
+		Sub:         b.Subscribe(topic),
+		DLQ:         b,
+		DLQTopic:    "orders.dlq",
+		MaxAttempts: 3,
+		Backoff:     5 * time.Millisecond,
+		Handle: func(_ context.Context, d Delivery) error {
+			switch d.ID {
+			case "2":
+				return errors.New("payment service timeout")
+			case "3":
+				return fmt.Errorf("order without items: %w", ErrPermanent)
+			}
+
+			p.add(d.ID)
+
+			return nil
+		},
+	}
+
+	stop := runConsumer(t, c)
+
+	eventually(t, func() bool { return b.Pending(topic) == 0 }, "Expected all messages to be acked, %d pending", b.Pending(topic))
+
+	if _, err := stop(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if p.count("1") != 1 {
+		t.Errorf("Expected message 1 to be processed, got %d times", p.count("1"))
+	}
+
+	if delays := b.NackDelays("2"); !slices.Equal(delays, []time.Duration{5 * time.Millisecond, 10 * time.Millisecond}) {
+		t.Errorf("Expected message 2 to be retried after 5ms and 10ms, got %v", delays)
+	}
+
+	if delays := b.NackDelays("3"); len(delays) != 0 {
+		t.Errorf("Expected a permanent error not to be retried, got %v", delays)
+	}
+
+	expected := map[string]Message{
+		"2": {ID: "2", Body: []byte("order 2"), Headers: map[string]string{"error": "payment service timeout", "attempts": "3"}},
+		"3": {ID: "3", Body: []byte("order 3"), Headers: map[string]string{"error": "order without items: permanent error", "attempts": "1"}},
+	}
+
+	dead := b.Published("orders.dlq")
+	if len(dead) != len(expected) {
+		t.Fatalf("Expected %d dead letters, got %d", len(expected), len(dead))
+	}
+
+	for _, msg := range dead {
+		want, ok := expected[msg.ID]
+		if !ok || string(msg.Body) != string(want.Body) ||
+			msg.Headers["error"] != want.Headers["error"] || msg.Headers["attempts"] != want.Headers["attempts"] {
+			t.Errorf("Expected dead letter %+v, got %+v", want, msg)
+		}
+
+		if b.Acks(msg.ID) != 1 {
+			t.Errorf("Expected dead letter %s to be acked in the original topic, got %d acks", msg.ID, b.Acks(msg.ID))
+		}
+	}
+
+}
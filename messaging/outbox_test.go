@@ -0,0 +1,256 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 5. Advanced: the transactional outbox.
+// PlaceOrder saves an order and publishes OrderPlaced for the warehouse and billing. That's two writes to two systems,
+// and no order of them is safe: when the broker is down after the commit, the order exists and nobody hears about it;
+// publishing first announces an order that may fail to commit. Distributed transactions are not an option with brokers.
+//
+// The outbox pattern turns the second write into a row of the same database: the order and the message are inserted
+// in one transaction, both or neither. A relay polls the outbox table, publishes messages in order,
+// and marks them as published. The relay can crash between publishing and marking, then the message is published again
+// on restart: delivery is at least once, and consumers deduplicate by message ID, like in exercise 2.
+// Change data capture, like Debezium reading the write-ahead log, is the alternative to polling.
+//
+// - PlaceOrder must insert the order and its OrderPlaced message to the outbox in one transaction,
+//   and not touch the broker at all. The message has the ID "order-placed-" + the order ID and the order as JSON.
+// - Relay.Run publishes pending records in the order of their sequence numbers and marks every one after it's published.
+//   When publishing fails, it retries the same record after Backoff, doubling the delay up to MaxBackoff,
+//   and resets the delay after a success. Skipping a record would reorder events of an order.
+//   When MarkPublished fails, the relay can't know what's published, it returns the error, and a supervisor restarts it.
+//   With nothing to publish, it polls every Interval.
+
+// Order is an order of the shop.
+type Order struct {
+	ID    string `json:"id"`
+	Items int    `json:"items"`
+}
+
+const eventsTopic = "orders.events"
+
+// OrderService places orders.
+type OrderService struct {
+	db  *DB
+	pub Publisher
+}
+
+// PlaceOrder saves the order and announces it with an OrderPlaced message.
+func (s *OrderService) PlaceOrder(ctx context.Context, o Order) error {
+	body, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	defer tx.Rollback()
+
+	if err := tx.InsertOrder(o); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return s.pub.Publish(ctx, eventsTopic, Message{ID: "order-placed-" + o.ID, Body: body})
+}
+
+// Relay publishes messages of the outbox.
+type Relay struct {
+	DB         *DB
+	Pub        Publisher
+	BatchSize  int
+	Interval   time.Duration
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Run publishes messages of the outbox until the context is cancelled.
+func (r *Relay) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// flakyPublisher publishes to the broker, failing the next failures calls, and records every attempt.
+type flakyPublisher struct {
+	mu       sync.Mutex
+	broker   *Broker
+	failures int
+	attempts []time.Time
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, topic string, msg Message) error {
+	p.mu.Lock()
+	p.attempts = append(p.attempts, time.Now())
+
+	if p.failures > 0 {
+		p.failures--
+		p.mu.Unlock()
+
+		return errors.New("broker is unavailable")
+	}
+	p.mu.Unlock()
+
+	return p.broker.Publish(ctx, topic, msg)
+}
+
+func (p *flakyPublisher) Attempts() []time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.attempts)
+}
+
+// runRelay runs the relay in a goroutine until the test ends, and returns the channel with the error of Run.
+func runRelay(t *testing.T, r *Relay) <-chan error {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		done <- r.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+
+	return done
+}
+
+func publishedIDs(b *Broker) []string {
+	var ids []string
+	for _, msg := range b.Published(eventsTopic) {
+		ids = append(ids, msg.ID)
+	}
+
+	return ids
+}
+
+func TestOutboxPlaceOrder(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	pub := &flakyPublisher{broker: NewBroker(time.Second), failures: 100}
+	orders := &OrderService{db: db, pub: pub}
+
+	if err := orders.PlaceOrder(ctx, Order{ID: "1", Items: 3}); err != nil {
+		t.Fatalf("Expected an order to be placed while the broker is down, got %v", err)
+	}
+
+	if n := len(pub.Attempts()); n != 0 {
+		t.Errorf("Expected PlaceOrder not to publish, it published %d times", n)
+	}
+
+	outbox := db.Outbox()
+	if len(outbox) != 1 || outbox[0].Topic != eventsTopic || outbox[0].Message.ID != "order-placed-1" {
+		t.Fatalf("Expected OrderPlaced of order 1 in the outbox, got %+v", outbox)
+	}
+
+	var o Order
+	if err := json.Unmarshal(outbox[0].Message.Body, &o); err != nil || o != (Order{ID: "1", Items: 3}) {
+		t.Errorf("Expected the order as JSON in the message, got %s", outbox[0].Message.Body)
+	}
+
+	db.FailNextCommit()
+
+	if err := orders.PlaceOrder(ctx, Order{ID: "2", Items: 1}); err == nil {
+		t.Error("Expected an error when the commit fails")
+	}
+
+	if _, ok := db.Order("2"); ok || len(db.Outbox()) != 1 {
+		t.Errorf("Expected neither the order nor its message to be saved when the commit fails, got %d messages", len(db.Outbox()))
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	b := NewBroker(time.Second)
+	pub := &flakyPublisher{broker: b, failures: 3}
+	orders := &OrderService{db: db, pub: pub}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := orders.PlaceOrder(ctx, Order{ID: id, Items: 1}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	start := time.Now()
+	runRelay(t, &Relay{DB: db, Pub: pub, BatchSize: 2, Interval: time.Millisecond, Backoff: 5 * time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+
+	expected := []string{"order-placed-1", "order-placed-2", "order-placed-3"}
+
+	eventually(t, func() bool { return len(b.Published(eventsTopic)) >= len(expected) },
+		"Expected the relay to publish %d messages, got %v", len(expected), publishedIDs(b))
+
+	if ids := publishedIDs(b); !slices.Equal(ids, expected) {
+		t.Errorf("Expected messages %v in order, got %v", expected, ids)
+	}
+
+	attempts := pub.Attempts()
+	if len(attempts) < 4 {
+		t.Fatalf("Expected 3 failed attempts and a successful one before the first message, got %d attempts", len(attempts))
+	}
+
+	// 5ms, 10ms, and 10ms again, as the delay is capped.
+	if elapsed := attempts[3].Sub(start); elapsed < 25*time.Millisecond {
+		t.Errorf("Expected retries to back off for 25ms, the first message was published after %v", elapsed)
+	}
+
+	eventually(t, func() bool {
+		return !slices.ContainsFunc(db.Outbox(), func(rec OutboxRecord) bool { return !rec.Published })
+	}, "Expected all records of the outbox to be marked as published")
+}
+
+func TestOutboxRelayCrash(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	b := NewBroker(time.Second)
+	orders := &OrderService{db: db, pub: b}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := orders.PlaceOrder(ctx, Order{ID: id, Items: 1}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// The relay crashes after publishing the first message, before marking it.
+	db.FailNextMark()
+
+	relay := &Relay{DB: db, Pub: b, BatchSize: 10, Interval: time.Millisecond, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	select {
+	case err := <-runRelay(t, relay):
+		if !errors.Is(err, errCrash) {
+			t.Fatalf("Expected the relay to stop with the error of MarkPublished, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the relay to stop when MarkPublished fails")
+	}
+
+	// The restarted relay publishes the first message again: at least once, not exactly once.
+	runRelay(t, relay)
+
+	expected := []string{"order-placed-1", "order-placed-1", "order-placed-2", "order-placed-3"}
+
+	eventually(t, func() bool { return len(b.Published(eventsTopic)) >= len(expected) },
+		"Expected the restarted relay to publish the rest, got %v", publishedIDs(b))
+
+	if ids := publishedIDs(b); !slices.Equal(ids, expected) {
+		t.Errorf("Expected messages %v, with a duplicate a consumer drops by ID, got %v", expected, ids)
+	}
+}
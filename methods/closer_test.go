@@ -0,0 +1,108 @@
+package methods
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// 3. A method that broke an interface.
+// Shutdown closes every component of the app that implements io.Closer. Cache.Close used to have a value
+// receiver: it cleared the map of entries, and maps are references, so clearing a copy cleared the original.
+// Then Get learned to fail after Close, Close had to remember that the cache is closed, and got a pointer
+// receiver. Everything still compiles, the tests of the cache pass, and Shutdown stopped closing the cache.
+//
+// Find out why a type assertion that used to succeed fails now, and fix it. Then make the compiler
+// catch it the next time: an assignment to a blank variable of the interface type,
+//
+//	var _ io.Closer = (*Cache)(nil)
+//
+// fails to compile when the type stops implementing the interface, without allocating anything.
+
+// ErrClosed is returned by closed components.
+var ErrClosed = errors.New("closed")
+
+// Cache keeps values in memory.
+type Cache struct {
+	entries map[string]string
+	closed  bool
+}
+
+// NewCache creates an empty cache.
+func NewCache() Cache {
+	return Cache{entries: make(map[string]string)}
+}
+
+// Set stores the value.
+func (c Cache) Set(key, value string) {
+	c.entries[key] = value
+}
+
+// Get returns the value, or ErrClosed after the cache is closed.
+func (c Cache) Get(key string) (string, error) {
+	if c.closed {
+		return "", ErrClosed
+	}
+
+	return c.entries[key], nil
+}
+
+// Close empties the cache.
+func (c *Cache) Close() error {
+	clear(c.entries)
+	c.closed = true
+
+	return nil
+}
+
+// Pool is a pool of connections.
+type Pool struct {
+	closed bool
+}
+
+func (p *Pool) Close() error {
+	p.closed = true
+	return nil
+}
+
+// App is an application with its components.
+type App struct {
+	Cache Cache
+	Pool  *Pool
+	Name  string
+}
+
+// components returns components of the app, in the order they are closed.
+func (a *App) components() []any {
+	return []any{a.Cache, a.Pool, a.Name}
+}
+
+// Shutdown closes components of the app that can be closed.
+func (a *App) Shutdown() error {
+	var errs []error
+
+	for _, c := range a.components() {
+		if closer, ok := c.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func TestShutdown(t *testing.T) {
+	app := &App{Cache: NewCache(), Pool: &Pool{}, Name: "shop"}
+	app.Cache.Set("greeting", "hello")
+
+	if err := app.Shutdown(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !app.Pool.closed {
+		t.Error("Expected the pool to be closed")
+	}
+
+	if _, err := app.Cache.Get("greeting"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected the cache to be closed, Get returned %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package methods
+
+import (
+	"slices"
+	"testing"
+)
+
+// 1. Mutation through a copy.
+// Go passes everything by value, receivers included. A method with a value receiver, func (c Cart) Add,
+// gets a copy of the cart: it can change the copy as it likes, and the caller never sees it.
+// A pointer receiver, func (c *Cart) Add, gets the address of the cart and changes the original.
+// Go takes the address for the caller: cart.Add() with a pointer receiver means (&cart).Add(),
+// as long as cart is addressable, a variable, a field of one, or an element of a slice.
+//
+// Copies hide in other places too:
+// - the loop variable of range over a slice is a copy of the element, a pointer method changes the copy,
+// - elements of maps aren't addressable, so accounts[id].Deposit(10) doesn't even compile,
+//   and the usual workaround, acc := accounts[id], changes a copy that has to be stored back.
+//
+// Fix Cart.Add, Inventory.RestockAll, and Bank.Deposit.
+
+// Cart is a shopping cart.
+type Cart struct {
+	Items []string
+	Total int
+}
+
+// Add adds the item to the cart.
+func (c Cart) Add(item string, price int) {
+	c.Items = append(c.Items, item)
+	c.Total += price
+}
+
+// Product is a product in stock.
+type Product struct {
+	SKU   string
+	Stock int
+}
+
+// Restock adds n items to the stock.
+func (p *Product) Restock(n int) {
+	p.Stock += n
+}
+
+// Inventory is the stock of all products.
+type Inventory struct {
+	Products []Product
+}
+
+// RestockAll adds n items to the stock of every product.
+func (inv *Inventory) RestockAll(n int) {
+	for _, p := range inv.Products {
+		p.Restock(n)
+	}
+}
+
+// Account is a bank account.
+type Account struct {
+	Balance int
+}
+
+// Deposit adds the amount to the balance.
+func (a *Account) Deposit(amount int) {
+	a.Balance += amount
+}
+
+// Bank keeps accounts by ID.
+type Bank struct {
+	Accounts map[string]Account
+}
+
+// Deposit adds the amount to the balance of the account.
+func (b *Bank) Deposit(id string, amount int) {
+	acc := b.Accounts[id]
+	acc.Deposit(amount)
+}
+
+func TestCart(t *testing.T) {
+	var cart Cart
+
+	cart.Add("gopher plush", 1500)
+	cart.Add("sticker pack", 300)
+
+	if !slices.Equal(cart.Items, []string{"gopher plush", "sticker pack"}) || cart.Total != 1800 {
+		t.Errorf("Expected 2 items for 1800, got %v for %d", cart.Items, cart.Total)
+	}
+}
+
+func TestRestockAll(t *testing.T) {
+	inv := Inventory{Products: []Product{{SKU: "plush", Stock: 1}, {SKU: "mug", Stock: 0}}}
+
+	inv.RestockAll(10)
+
+	for _, p := range inv.Products {
+		if p.Stock < 10 {
+			t.Errorf("Expected %s to be restocked, got stock %d", p.SKU, p.Stock)
+		}
+	}
+}
+
+func TestBankDeposit(t *testing.T) {
+	bank := Bank{Accounts: map[string]Account{"alice": {Balance: 100}}}
+
+	bank.Deposit("alice", 50)
+	bank.Deposit("bob", 20)
+
+	if got := bank.Accounts["alice"].Balance; got != 150 {
+		t.Errorf("Expected the balance of alice to be 150, got %d", got)
+	}
+
+	if got := bank.Accounts["bob"].Balance; got != 20 {
+		t.Errorf("Expected a deposit to open the account of bob with 20, got %d", got)
+	}
+}
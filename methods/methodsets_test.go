@@ -0,0 +1,82 @@
+package methods
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// 2. Method sets.
+// The method set of a type decides which interfaces it implements. The method set of T has methods with
+// value receivers, the method set of *T has all of them, with value and pointer receivers. So a *T implements
+// every interface T does, and a T doesn't implement interfaces that need a method with a pointer receiver.
+// The automatic &cart of section 1 doesn't help: a value stored in an interface isn't addressable.
+//
+// The compiler checks assignments to interfaces, but fmt and encoding/json look for fmt.Stringer and
+// json.Marshaler at run time, with type assertions, and silently fall back to the default format when
+// a value doesn't implement them. json.Marshal(&r) finds pointer methods of addressable fields, json.Marshal(r)
+// doesn't, so the same reading is encoded in two ways.
+//
+// Celsius has methods with pointer receivers, and a Celsius value doesn't implement the interfaces.
+// Methods of small values that don't change them take value receivers, fix Celsius.
+
+// Celsius is a temperature in degrees Celsius.
+type Celsius float64
+
+func (c *Celsius) String() string {
+	return fmt.Sprintf("%.1f°C", float64(*c))
+}
+
+// MarshalJSON encodes the temperature as a string with the unit.
+func (c *Celsius) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// Reading is a reading of a sensor.
+type Reading struct {
+	Sensor string  `json:"sensor"`
+	Temp   Celsius `json:"temp"`
+}
+
+func TestStringer(t *testing.T) {
+	temp := Celsius(21.5)
+
+	if got := fmt.Sprint(&temp); got != "21.5°C" {
+		t.Errorf("Expected *Celsius to be printed as 21.5°C, got %q", got)
+	}
+
+	if got := fmt.Sprint(temp); got != "21.5°C" {
+		t.Errorf("Expected Celsius to be printed as 21.5°C, got %q", got)
+	}
+
+	if got := fmt.Sprintf("%v", Reading{Sensor: "kitchen", Temp: 21.5}); got != "{kitchen 21.5°C}" {
+		t.Errorf("Expected a reading to be printed as {kitchen 21.5°C}, got %q", got)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	r := Reading{Sensor: "kitchen", Temp: 21.5}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"*Reading", &r, `{"sensor":"kitchen","temp":"21.5°C"}`},
+		{"Reading", r, `{"sensor":"kitchen","temp":"21.5°C"}`},
+		// Elements of a slice are addressable.
+		{"[]Reading", []Reading{r}, `[{"sensor":"kitchen","temp":"21.5°C"}]`},
+		{"map[string]Reading", map[string]Reading{"now": r}, `{"now":{"sensor":"kitchen","temp":"21.5°C"}}`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.value)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if string(data) != tt.expected {
+			t.Errorf("Expected %s to be encoded as %s, got %s", tt.name, tt.expected, data)
+		}
+	}
+}
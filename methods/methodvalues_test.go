@@ -0,0 +1,111 @@
+package methods
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 4. Advanced: method values.
+// n.Notify without a call is a method value: a func bound to its receiver, which can be passed around
+// as a callback. The receiver is evaluated when the method value is, and with a value receiver it's a copy
+// made right then: the callback keeps working with the notifier as it was when it subscribed.
+// A deferred call is the same: defer s.Print(w) evaluates s and w at the defer statement, and only the call
+// itself is deferred, so it prints the summary as it was at the start of the function.
+//
+// Fix both, so Notify uses the channel set by Reconfigure, and ProcessAll prints the final summary.
+
+// Bus calls subscribers with every published event.
+type Bus struct {
+	subscribers []func(event string)
+}
+
+// Subscribe calls fn with every event published after it.
+func (b *Bus) Subscribe(fn func(event string)) {
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Publish calls all subscribers with the event.
+func (b *Bus) Publish(event string) {
+	for _, fn := range b.subscribers {
+		fn(event)
+	}
+}
+
+// Notifier posts events to a chat channel.
+type Notifier struct {
+	Channel string
+	posted  *[]string
+}
+
+// Notify posts the event to the channel.
+func (n Notifier) Notify(event string) {
+	*n.posted = append(*n.posted, n.Channel+": "+event)
+}
+
+// Reconfigure changes the channel the notifier posts to.
+func (n *Notifier) Reconfigure(channel string) {
+	n.Channel = channel
+}
+
+// Summary counts results of processing.
+type Summary struct {
+	Processed int
+	Failed    int
+}
+
+// Print writes the summary.
+func (s Summary) Print(w io.Writer) {
+	fmt.Fprintf(w, "processed %d, failed %d", s.Processed, s.Failed)
+}
+
+// ProcessAll processes the items and writes the summary when it's done.
+func ProcessAll(w io.Writer, items []string, process func(string) error) {
+	var s Summary
+	defer s.Print(w)
+
+	for _, item := range items {
+		if err := process(item); err != nil {
+			s.Failed++
+			continue
+		}
+
+		s.Processed++
+	}
+}
+
+func TestMethodValue(t *testing.T) {
+	var posted []string
+
+	bus := &Bus{}
+	n := &Notifier{Channel: "#general", posted: &posted}
+
+	bus.Subscribe(n.Notify)
+	bus.Publish("deploy started")
+
+	n.Reconfigure("#incidents")
+	bus.Publish("deploy failed")
+
+	expected := []string{"#general: deploy started", "#incidents: deploy failed"}
+	if !slices.Equal(posted, expected) {
+		t.Errorf("Expected %q, got %q", expected, posted)
+	}
+}
+
+func TestDeferredMethod(t *testing.T) {
+	var out strings.Builder
+
+	ProcessAll(&out, []string{"a", "b", "", "c"}, func(item string) error {
+		if item == "" {
+			return fmt.Errorf("empty item")
+		}
+
+		return nil
+	})
+
+	if got := out.String(); got != "processed 3, failed 1" {
+		t.Errorf("Expected the final summary, got %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package mocking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+)
+
+// 3. Choosing a test double.
+// A mock pins how the code talks to a dependency: which calls, how many, with what arguments, in what order.
+// That's the point when the calls are the behavior, like the welcome email that must come after the insert.
+// When they are not, the test breaks on every refactoring that keeps the behavior, and passes on bugs
+// the mocks were set up to expect. Queries are better served by stubs and fakes, checking what the code
+// achieved, and mocks are left for commands with side effects that matter.
+//
+// SendDigest was changed: it names the day of the digest in the subject, and counts users in the body.
+// It works, but TestDigest is over-mocked: it expects Now exactly once, the exact since argument,
+// and the exact body of the old format, so it fails. Rewrite TestDigest with a stub clock (clockFunc),
+// the fake repository of section 1 and a spy mailer that records sent emails, and check the outcome:
+// users created in the last 24 hours are in the digest, older ones are not, and no digest is sent
+// when nobody signed up.
+
+// clockFunc is a Clock of a function.
+type clockFunc func() time.Time
+
+func (f clockFunc) Now() time.Time {
+	return f()
+}
+
+// SendDigest emails the admin the names of users created in the last 24 hours.
+func (s *Service) SendDigest(ctx context.Context, admin string) error {
+	users, err := s.Repo.ListCreatedSince(ctx, s.Clock.Now().Add(-24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("failed to list new users: %w", err)
+	}
+
+	if len(users) == 0 {
+		return nil
+	}
+
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "%d users signed up:\n", len(users))
+
+	for _, u := range users {
+		fmt.Fprintf(&body, "- %s\n", u.Name)
+	}
+
+	subject := "Daily digest " + s.Clock.Now().Format(time.DateOnly)
+
+	return s.Mailer.Send(ctx, admin, subject, body.String())
+}
+
+func TestDigest(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	ctrl := gomock.NewController(t)
+	repo, mailer, clock := NewMockUserRepo(ctrl), NewMockMailer(ctrl), NewMockClock(ctrl)
+
+	clock.EXPECT().Now().Return(now).Times(1)
+	repo.EXPECT().ListCreatedSince(gomock.Any(), now.Add(-24*time.Hour)).Return([]User{
+		{ID: 1, Name: "Alice", CreatedAt: now.Add(-time.Hour)},
+		{ID: 2, Name: "Bob", CreatedAt: now.Add(-time.Minute)},
+	}, nil)
+	mailer.EXPECT().Send(gomock.Any(), "admin@example.com", "Daily digest", "New users: Alice, Bob").Return(nil)
+
+	s := &Service{Repo: repo, Mailer: mailer, Clock: clock}
+
+	if err := s.SendDigest(context.Background(), "admin@example.com"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package mocking
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A test double stands in for a dependency of the code under test:
+// - a stub returns canned answers to queries, like a Clock that always returns the same time,
+// - a fake is a working implementation that takes a shortcut, like a repository backed by a map,
+// - a spy records calls to check them after the test,
+// - a mock is set up with expected calls before the test, and fails it on calls it doesn't expect.
+// Mocks check how the code talks to a dependency, the others check what the code achieves.
+
+// 1. Fakes.
+// fakeRepo is an in-memory UserRepo for tests of services: fast, and with no database to start.
+// A fake is only useful if it behaves like the real thing, so it's checked by the same contract test
+// as the real repository would be: testUserRepo knows nothing about maps. Now the fake never finds users
+// by email, doesn't assign IDs, and doesn't enforce the rules of the interface. Implement it.
+
+// fakeRepo is a UserRepo that keeps users in memory.
+type fakeRepo struct {
+	mu     sync.Mutex
+	users  map[int64]User
+	nextID int64
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{users: make(map[int64]User)}
+}
+
+func (r *fakeRepo) FindByID(ctx context.Context, id int64) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.users[id], nil
+}
+
+func (r *fakeRepo) FindByEmail(ctx context.Context, email string) (User, error) {
+	return User{}, ErrNotFound
+}
+
+func (r *fakeRepo) Create(ctx context.Context, u User) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[u.ID] = u
+
+	return u, nil
+}
+
+func (r *fakeRepo) Update(ctx context.Context, u User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[u.ID] = u
+
+	return nil
+}
+
+func (r *fakeRepo) ListCreatedSince(ctx context.Context, since time.Time) ([]User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []User
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+// testUserRepo is the contract of UserRepo, every implementation must pass it.
+func testUserRepo(t *testing.T, repo UserRepo) {
+	t.Helper()
+
+	ctx := context.Background()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	alice, err := repo.Create(ctx, User{Email: "alice@example.com", Name: "Alice", CreatedAt: day})
+	if err != nil || alice.ID == 0 || alice.Email != "alice@example.com" {
+		t.Fatalf("Expected the user to be created with an ID, got %+v, %v", alice, err)
+	}
+
+	bob, err := repo.Create(ctx, User{Email: "bob@example.com", Name: "Bob", CreatedAt: day.Add(time.Hour)})
+	if err != nil || bob.ID == 0 || bob.ID == alice.ID {
+		t.Fatalf("Expected the second user to get another ID, got %+v, %v", bob, err)
+	}
+
+	if _, err := repo.Create(ctx, User{Email: "alice@example.com", Name: "Other Alice"}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Expected ErrEmailTaken for a taken email, got %v", err)
+	}
+
+	if got, err := repo.FindByEmail(ctx, "bob@example.com"); err != nil || got != bob {
+		t.Errorf("Expected to find %+v by email, got %+v, %v", bob, got, err)
+	}
+
+	if _, err := repo.FindByEmail(ctx, "carol@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown email, got %v", err)
+	}
+
+	if _, err := repo.FindByID(ctx, 404); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an unknown ID, got %v", err)
+	}
+
+	alice.Name = "Alice Smith"
+	if err := repo.Update(ctx, alice); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if got, err := repo.FindByID(ctx, alice.ID); err != nil || got.Name != "Alice Smith" {
+		t.Errorf("Expected the updated name, got %+v, %v", got, err)
+	}
+
+	if err := repo.Update(ctx, User{ID: 404, Email: "ghost@example.com"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when updating an unknown user, got %v", err)
+	}
+
+	since, err := repo.ListCreatedSince(ctx, day.Add(time.Minute))
+	if err != nil || len(since) != 1 || since[0].ID != bob.ID {
+		t.Errorf("Expected only Bob to be created since, got %+v, %v", since, err)
+	}
+
+	all, err := repo.ListCreatedSince(ctx, day)
+	if err != nil || len(all) != 2 || all[0].ID != alice.ID || all[1].ID != bob.ID {
+		t.Errorf("Expected Alice and Bob in the order of creation, got %+v, %v", all, err)
+	}
+}
+
+func TestFakeRepo(t *testing.T) {
+	testUserRepo(t, newFakeRepo())
+}
@@ -0,0 +1,132 @@
+package mocking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+)
+
+// 2. Generated mocks.
+// mockgen generates a mock of every interface of users.go into mocks_test.go. A test creates mocks with
+// a gomock.Controller, sets up expected calls with EXPECT(), and the controller fails the test on a call
+// nobody expected, or on an expected call that never came:
+//
+//	repo.EXPECT().FindByEmail(gomock.Any(), "alice@example.com").Return(User{}, ErrNotFound)
+//
+// Arguments are compared with matchers: a plain value is gomock.Eq, gomock.Any matches anything,
+// and custom matchers implement gomock.Matcher. gomock.InOrder makes calls expected in a sequence.
+// mockery generates mocks for testify/mock with the same idea and a different API.
+//
+// Register sends the welcome email before the user is stored, so a failed insert leaves a welcome email
+// to a user who doesn't exist, and a failed email fails the registration. TestRegister expects the email
+// after the user is created, and a registration that succeeds when only the email fails.
+// Implement hasEmail, the matcher of users with the email, and fix Register.
+
+// Service manages users.
+type Service struct {
+	Repo   UserRepo
+	Mailer Mailer
+	Clock  Clock
+}
+
+// Register creates a user with the email and sends them a welcome email.
+func (s *Service) Register(ctx context.Context, email, name string) (User, error) {
+	_, err := s.Repo.FindByEmail(ctx, email)
+	if err == nil {
+		return User{}, ErrEmailTaken
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return User{}, err
+	}
+
+	if err := s.Mailer.Send(ctx, email, "Welcome", "Hi "+name+", welcome aboard!"); err != nil {
+		return User{}, fmt.Errorf("failed to send the welcome email: %w", err)
+	}
+
+	return s.Repo.Create(ctx, User{Email: email, Name: name, CreatedAt: s.Clock.Now()})
+}
+
+// hasEmail matches users with the email.
+func hasEmail(email string) gomock.Matcher {
+	return gomock.Any()
+}
+
+func TestHasEmail(t *testing.T) {
+	m := hasEmail("alice@example.com")
+
+	if !m.Matches(User{ID: 1, Email: "alice@example.com"}) {
+		t.Error("Expected a user with the email to match")
+	}
+
+	for _, x := range []any{User{Email: "bob@example.com"}, "alice@example.com", &User{Email: "alice@example.com"}, nil} {
+		if m.Matches(x) {
+			t.Errorf("Expected %#v not to match", x)
+		}
+	}
+
+	if got := m.String(); got != "has email alice@example.com" {
+		t.Errorf("Expected the description of the matcher for failure messages, got %q", got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("welcome after create", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo, mailer, clock := NewMockUserRepo(ctrl), NewMockMailer(ctrl), NewMockClock(ctrl)
+		clock.EXPECT().Now().Return(now).AnyTimes()
+
+		alice := User{ID: 7, Email: "alice@example.com", Name: "Alice", CreatedAt: now}
+
+		gomock.InOrder(
+			repo.EXPECT().FindByEmail(gomock.Any(), "alice@example.com").Return(User{}, ErrNotFound),
+			repo.EXPECT().Create(gomock.Any(), hasEmail("alice@example.com")).Return(alice, nil),
+			mailer.EXPECT().Send(gomock.Any(), "alice@example.com", "Welcome", gomock.Any()).Return(nil),
+		)
+
+		s := &Service{Repo: repo, Mailer: mailer, Clock: clock}
+
+		if got, err := s.Register(ctx, "alice@example.com", "Alice"); err != nil || got != alice {
+			t.Errorf("Expected %+v, got %+v, %v", alice, got, err)
+		}
+	})
+
+	t.Run("failed email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo, mailer, clock := NewMockUserRepo(ctrl), NewMockMailer(ctrl), NewMockClock(ctrl)
+		clock.EXPECT().Now().Return(now).AnyTimes()
+
+		bob := User{ID: 8, Email: "bob@example.com", Name: "Bob", CreatedAt: now}
+
+		repo.EXPECT().FindByEmail(gomock.Any(), gomock.Any()).Return(User{}, ErrNotFound)
+		repo.EXPECT().Create(gomock.Any(), hasEmail("bob@example.com")).Return(bob, nil)
+		mailer.EXPECT().Send(gomock.Any(), "bob@example.com", gomock.Any(), gomock.Any()).Return(errors.New("smtp: 421 try again later"))
+
+		s := &Service{Repo: repo, Mailer: mailer, Clock: clock}
+
+		if got, err := s.Register(ctx, "bob@example.com", "Bob"); err != nil || got != bob {
+			t.Errorf("Expected the user to be registered when only the email fails, got %+v, %v", got, err)
+		}
+	})
+
+	t.Run("taken email", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo, mailer, clock := NewMockUserRepo(ctrl), NewMockMailer(ctrl), NewMockClock(ctrl)
+
+		// No calls to the mailer and Create are expected, any of them fails the test.
+		repo.EXPECT().FindByEmail(gomock.Any(), "carol@example.com").Return(User{ID: 3, Email: "carol@example.com"}, nil)
+
+		s := &Service{Repo: repo, Mailer: mailer, Clock: clock}
+
+		if _, err := s.Register(ctx, "carol@example.com", "Carol"); !errors.Is(err, ErrEmailTaken) {
+			t.Errorf("Expected ErrEmailTaken, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package money
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// 3. Allocation.
+// An amount can't always be split evenly: 100.00 EUR in three parts is 33.33 EUR each, and a cent is left over.
+// Allocate drops it, so the parts of an invoice don't add up to its total. Split by ratios rounding down, then
+// hand out the leftover minor units one by one to the parts in order, starting from the first.
+// Negative amounts, refunds, are split the same way with the leftover going the other direction.
+
+// ErrInvalidRatios is returned by Allocate for no ratios, negative ones, or ratios that sum to zero.
+var ErrInvalidRatios = errors.New("invalid ratios")
+
+// Allocate splits the amount in parts by the ratios, the parts sum to the amount.
+func (m Money) Allocate(ratios ...int64) ([]Money, error) {
+	var total int64
+
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, ErrInvalidRatios
+		}
+
+		total += r
+	}
+
+	if total == 0 {
+		return nil, ErrInvalidRatios
+	}
+
+	parts := make([]Money, len(ratios))
+
+	for i, r := range ratios {
+		parts[i] = Money{amount: m.amount * r / total, currency: m.currency}
+	}
+
+	return parts, nil
+}
+
+func TestAllocate(t *testing.T) {
+	amounts := func(parts []Money) []int64 {
+		var got []int64
+
+		for _, p := range parts {
+			if p.Currency() != EUR {
+				t.Errorf("Expected parts in EUR, got %v", p)
+			}
+
+			got = append(got, p.Amount())
+		}
+
+		return got
+	}
+
+	for _, tt := range []struct {
+		amount   int64
+		ratios   []int64
+		expected []int64
+	}{
+		{10000, []int64{1, 1, 1}, []int64{3334, 3333, 3333}},
+		{5, []int64{1, 1, 1, 1}, []int64{2, 1, 1, 1}},
+		{100, []int64{70, 30}, []int64{70, 30}},
+		{1001, []int64{3, 7}, []int64{301, 700}},
+		{2, []int64{1, 0, 1}, []int64{1, 0, 1}},
+		{-10000, []int64{1, 1, 1}, []int64{-3334, -3333, -3333}},
+		{0, []int64{1, 2}, []int64{0, 0}},
+	} {
+		parts, err := New(tt.amount, EUR).Allocate(tt.ratios...)
+		if got := amounts(parts); err != nil || !slices.Equal(got, tt.expected) {
+			t.Errorf("Expected %d split by %v to be %v, got %v, %v", tt.amount, tt.ratios, tt.expected, got, err)
+		}
+	}
+
+	for _, ratios := range [][]int64{nil, {0, 0}, {1, -1}} {
+		if _, err := New(100, EUR).Allocate(ratios...); !errors.Is(err, ErrInvalidRatios) {
+			t.Errorf("Expected ErrInvalidRatios for %v, got %v", ratios, err)
+		}
+	}
+}
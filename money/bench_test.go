@@ -0,0 +1,63 @@
+package money
+
+import (
+	"testing"
+	"time"
+)
+
+// 4. Advanced: allocation-free arithmetic.
+// Money is a small struct passed by value, so arithmetic on it shouldn't touch the heap. Add, Sub, and Mul
+// check overflow with math/big, and every call allocates: a pricing engine that computes millions of lines
+// spends its time in the garbage collector. Check overflow with plain int64 comparisons or math/bits instead.
+// Errors are allocated only when they happen, that's fine.
+//
+// See the numbers with:
+//
+//	go test -run '^$' -bench Invoice -benchmem ./money
+//
+// workshop bench money compares them with a baseline recorded before the change.
+
+// invoice returns lines of an invoice: prices and quantities.
+func invoice() (prices []Money, quantities []int64) {
+	for i := range 100 {
+		prices = append(prices, New(int64(99+i*37), EUR))
+		quantities = append(quantities, int64(1+i%5))
+	}
+
+	return prices, quantities
+}
+
+func BenchmarkInvoice(b *testing.B) {
+	prices, quantities := invoice()
+
+	b.ReportAllocs()
+
+	for range b.N {
+		total := New(0, EUR)
+
+		for i, p := range prices {
+			line, err := p.Mul(quantities[i])
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			if total, err = total.Add(line); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		if _, err := total.Sub(New(100, EUR)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestInvoiceAllocations(t *testing.T) {
+	result := testing.Benchmark(BenchmarkInvoice)
+
+	t.Logf("%v per invoice, %d allocations", time.Duration(result.NsPerOp()), result.AllocsPerOp())
+
+	if allocs := result.AllocsPerOp(); allocs != 0 {
+		t.Errorf("Expected arithmetic not to allocate, got %d allocations per invoice", allocs)
+	}
+}
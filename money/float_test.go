@@ -0,0 +1,71 @@
+package money
+
+import (
+	"math"
+	"testing"
+)
+
+// float64 is binary: 0.1 is stored as the nearest binary fraction, 0.1000000000000000055511151231257827.
+// Errors like this are invisible in one number and add up in sums, and rounding at the wrong moment turns
+// them into a cent off: 1.15 is stored as 1.149999..., so 10% off it rounds down instead of up.
+// Money is counted in minor units, cents, as integers: they are exact, and rounding happens only
+// where the business rules say it does.
+
+// 1. Floats.
+// Total and ApplyDiscount work on prices as float64, and TestFloat shows how far off they are.
+// Fix them without changing the signatures: convert prices to whole cents first, with math.Round,
+// compute in int64, and convert back at the end. Discounts round half away from zero to a cent.
+
+// Total returns the sum of the prices.
+func Total(prices []float64) float64 {
+	var sum float64
+
+	for _, p := range prices {
+		sum += p
+	}
+
+	return sum
+}
+
+// ApplyDiscount returns the price with the discount in percent, rounded to a cent.
+func ApplyDiscount(price float64, percent int) float64 {
+	return math.Round(price*float64(100-percent)) / 100
+}
+
+func TestFloat(t *testing.T) {
+	// Constant expressions are exact, the compiler computes 0.1+0.2 with arbitrary precision. Variables aren't.
+	a, b := 0.1, 0.2
+	t.Logf("With float64 variables, 0.1+0.2 = %.17f", a+b)
+
+	totals := []struct {
+		prices   []float64
+		expected float64
+	}{
+		{[]float64{0.1, 0.2}, 0.3},
+		{[]float64{19.99, 19.99, 19.99}, 59.97},
+		{[]float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1}, 1},
+	}
+
+	for _, tt := range totals {
+		if got := Total(tt.prices); got != tt.expected {
+			t.Errorf("Expected the total of %v to be %v, got %.17f", tt.prices, tt.expected, got)
+		}
+	}
+
+	discounts := []struct {
+		price    float64
+		percent  int
+		expected float64
+	}{
+		{1.15, 10, 1.04},
+		{19.99, 15, 16.99},
+		{0.5, 50, 0.25},
+		{4.35, 0, 4.35},
+	}
+
+	for _, tt := range discounts {
+		if got := ApplyDiscount(tt.price, tt.percent); got != tt.expected {
+			t.Errorf("Expected %v with %d%% off to be %v, got %.17f", tt.price, tt.percent, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// 2. Money.
+// Money is an amount in minor units of a currency: 1999 EUR cents are 19.99 EUR. Currencies have different
+// minor units: a yen has none, a Bahraini dinar has 1000 fils. Adding euros to dollars is a bug, not a sum,
+// so operations on amounts in different currencies fail with a CurrencyMismatchError.
+// Callers check errors with errors.As when they need the currencies, and with errors.Is and ErrCurrencyMismatch
+// when they don't, through any number of fmt.Errorf("...: %w", err) wrappers. An Is method of the error type
+// decides what errors.Is matches it with.
+//
+// Add and Sub don't check currencies now. Make them, and make CurrencyMismatchError match ErrCurrencyMismatch.
+// Results that don't fit in int64 are ErrOverflow. A failed operation returns the zero Money.
+
+// Currency is an ISO 4217 code of a currency.
+type Currency string
+
+const (
+	EUR Currency = "EUR"
+	USD Currency = "USD"
+	JPY Currency = "JPY"
+	BHD Currency = "BHD"
+)
+
+// minorDigits are the digits of minor units of currencies.
+var minorDigits = map[Currency]int{EUR: 2, USD: 2, JPY: 0, BHD: 3}
+
+var (
+	// ErrCurrencyMismatch is matched by errors of operations on amounts in different currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrOverflow is returned when the result doesn't fit in int64 minor units.
+	ErrOverflow = errors.New("amount overflow")
+)
+
+// CurrencyMismatchError is returned by operations on amounts in different currencies.
+type CurrencyMismatchError struct {
+	// Op is the name of the operation.
+	Op          string
+	Left, Right Currency
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return fmt.Sprintf("%s: currency mismatch: %s and %s", e.Op, e.Left, e.Right)
+}
+
+// Money is an amount of a currency in minor units.
+type Money struct {
+	amount   int64
+	currency Currency
+}
+
+// New returns the amount of minor units of the currency.
+func New(amount int64, currency Currency) Money {
+	return Money{amount: amount, currency: currency}
+}
+
+// Amount returns the amount in minor units.
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+// Currency returns the currency of the amount.
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// Add returns the sum of the amounts.
+func (m Money) Add(o Money) (Money, error) {
+	sum := new(big.Int).Add(big.NewInt(m.amount), big.NewInt(o.amount))
+	if !sum.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: sum.Int64(), currency: m.currency}, nil
+}
+
+// Sub returns the difference of the amounts.
+func (m Money) Sub(o Money) (Money, error) {
+	diff := new(big.Int).Sub(big.NewInt(m.amount), big.NewInt(o.amount))
+	if !diff.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: diff.Int64(), currency: m.currency}, nil
+}
+
+// Mul returns the amount multiplied by n, like the price of n items.
+func (m Money) Mul(n int64) (Money, error) {
+	product := new(big.Int).Mul(big.NewInt(m.amount), big.NewInt(n))
+	if !product.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+
+	return Money{amount: product.Int64(), currency: m.currency}, nil
+}
+
+// String formats the amount in major units with the currency, like 19.99 EUR.
+func (m Money) String() string {
+	digits := minorDigits[m.currency]
+	if digits == 0 {
+		return fmt.Sprintf("%d %s", m.amount, m.currency)
+	}
+
+	unit := int64(math.Pow10(digits))
+
+	sign, amount := "", m.amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, digits, amount%unit, m.currency)
+}
+
+func TestString(t *testing.T) {
+	for _, tt := range []struct {
+		m        Money
+		expected string
+	}{
+		{New(1999, EUR), "19.99 EUR"},
+		{New(-5, USD), "-0.05 USD"},
+		{New(500, JPY), "500 JPY"},
+		{New(1250, BHD), "1.250 BHD"},
+	} {
+		if got := tt.m.String(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	price := New(1999, EUR)
+
+	sum, err := price.Add(New(1, EUR))
+	if err != nil || sum != New(2000, EUR) {
+		t.Errorf("Expected 20.00 EUR, got %v, %v", sum, err)
+	}
+
+	diff, err := price.Sub(New(2000, EUR))
+	if err != nil || diff != New(-1, EUR) {
+		t.Errorf("Expected -0.01 EUR, got %v, %v", diff, err)
+	}
+
+	total, err := price.Mul(3)
+	if err != nil || total != New(5997, EUR) {
+		t.Errorf("Expected 59.97 EUR, got %v, %v", total, err)
+	}
+
+	for name, op := range map[string]func() (Money, error){
+		"add": func() (Money, error) { return New(math.MaxInt64, EUR).Add(New(1, EUR)) },
+		"sub": func() (Money, error) { return New(math.MinInt64, EUR).Sub(New(1, EUR)) },
+		"mul": func() (Money, error) { return New(math.MaxInt64/2+1, EUR).Mul(2) },
+	} {
+		if got, err := op(); !errors.Is(err, ErrOverflow) || got != (Money{}) {
+			t.Errorf("Expected %s to overflow, got %v, %v", name, got, err)
+		}
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	eur, usd := New(1000, EUR), New(1000, USD)
+
+	for _, tt := range []struct {
+		op  string
+		run func() (Money, error)
+	}{
+		{"add", func() (Money, error) { return eur.Add(usd) }},
+		{"sub", func() (Money, error) { return eur.Sub(usd) }},
+	} {
+		got, err := tt.run()
+		if got != (Money{}) {
+			t.Errorf("Expected the zero Money from a failed %s, got %v", tt.op, got)
+		}
+
+		wrapped := fmt.Errorf("checkout: %w", err)
+
+		var mismatch *CurrencyMismatchError
+		if !errors.As(wrapped, &mismatch) || mismatch.Op != tt.op || mismatch.Left != EUR || mismatch.Right != USD {
+			t.Errorf("Expected a CurrencyMismatchError of %s EUR and USD, got %v", tt.op, err)
+		}
+
+		if !errors.Is(wrapped, ErrCurrencyMismatch) {
+			t.Errorf("Expected the error of %s to match ErrCurrencyMismatch, got %v", tt.op, err)
+		}
+
+		if errors.Is(wrapped, ErrOverflow) {
+			t.Errorf("Expected the error of %s not to match ErrOverflow", tt.op)
+		}
+	}
+}
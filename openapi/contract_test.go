@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	legacyrouter "github.com/getkin/kin-openapi/routers/legacy"
+
+	"github.com/ksysoev/go-workshops/openapi/api"
+)
+
+// contract sends requests to the service and checks that its responses conform to the spec.
+type contract struct {
+	server *httptest.Server
+	router routers.Router
+}
+
+func newContract(t *testing.T, s *Server) *contract {
+	t.Helper()
+
+	handler, err := NewHandler(s)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	spec, err := api.GetSwagger()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	router, err := legacyrouter.NewRouter(spec)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &contract{server: server, router: router}
+}
+
+// do sends a request with a JSON body, if there is one, and returns the response.
+func (c *contract) do(t *testing.T, method, path, body string) (int, http.Header, []byte) {
+	t.Helper()
+
+	return c.send(t, method, path, "application/json", body)
+}
+
+// send sends a request with the body of the content type, and checks the response against the spec.
+func (c *contract) send(t *testing.T, method, path, contentType, body string) (int, http.Header, []byte) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, c.server.URL+path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+
+	// Requests to paths of the spec only, others aren't covered by the contract.
+	route, params, err := c.router.FindRoute(httptest.NewRequest(method, path, nil))
+	if err != nil {
+		return resp.StatusCode, resp.Header, data
+	}
+
+	err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, PathParams: params, Route: route},
+		Status:                 resp.StatusCode,
+		Header:                 resp.Header,
+		Body:                   io.NopCloser(bytes.NewReader(data)),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	})
+	if err != nil {
+		t.Errorf("Expected %s %s to respond as the spec says, got %d %s: %v", method, path, resp.StatusCode, data, err)
+	}
+
+	return resp.StatusCode, resp.Header, data
+}
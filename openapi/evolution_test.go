@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 3. Advanced: changing the spec first.
+// Tasks need a due date. In an OpenAPI-first service the change starts in openapi.yaml: add an optional dueAt,
+// a date-time, to NewTask and Task, and regenerate the code with go generate ./openapi. The generated models
+// get a new field, the validator of section 2 starts accepting dueAt, and only then the handlers use it.
+// An optional field is a compatible change: old clients don't send it, and ignore it in responses.
+//
+// The spec describes the shape of values, not every rule of the service. A due date in the past is
+// a valid date-time, and CreateTask rejects it itself with 400 Bad Request and an Error about dueAt.
+// Tasks without a due date have no dueAt in responses at all, not null.
+
+func TestDueDate(t *testing.T) {
+	c := newContract(t, NewServer())
+
+	due := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+
+	status, _, body := c.do(t, http.MethodPost, "/tasks", `{"title": "Ship it", "dueAt": "`+due+`"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected a task with a due date to be created, got %d %s", status, body)
+	}
+
+	status, _, body = c.do(t, http.MethodGet, "/tasks/1", "")
+
+	var task map[string]any
+	json.Unmarshal(body, &task)
+
+	if status != http.StatusOK || task["dueAt"] != due {
+		t.Errorf("Expected the task to be due at %s, got %d %s", due, status, body)
+	}
+
+	if status, _, body = c.do(t, http.MethodPost, "/tasks", `{"title": "Someday"}`); status != http.StatusCreated || strings.Contains(string(body), "dueAt") {
+		t.Errorf("Expected a task without a due date to have no dueAt, got %d %s", status, body)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	status, _, body = c.do(t, http.MethodPost, "/tasks", `{"title": "Too late", "dueAt": "`+past+`"}`)
+	if status != http.StatusBadRequest || !strings.Contains(string(body), "dueAt") {
+		t.Errorf("Expected 400 with an error about dueAt for a due date in the past, got %d %s", status, body)
+	}
+
+	if status, _, body = c.do(t, http.MethodPost, "/tasks", `{"title": "Never", "dueAt": "tomorrow"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a dueAt that isn't a date-time, got %d %s", status, body)
+	}
+}
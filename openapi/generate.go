@@ -0,0 +1,7 @@
+// Package openapi is a workshop on OpenAPI-first HTTP services.
+// Generated code is checked in, regenerate it after changing openapi.yaml with:
+//
+//	go generate ./openapi
+package openapi
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.4.1 -config oapi-codegen.yaml openapi.yaml
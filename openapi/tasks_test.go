@@ -0,0 +1,181 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/openapi/api"
+)
+
+// OpenAPI-first means the spec comes before the code. openapi.yaml describes every path, parameter, body,
+// and response of the service, and it's what other teams read, generate clients from, and test against.
+// The server code is generated from it too: oapi-codegen turns the spec into the models and the
+// ServerInterface of the api package, with a method per operation, and routes for http.ServeMux
+// that parse path and query parameters into typed arguments. What's left is implementing the interface.
+//
+// The generated code doesn't check anything the spec says about values: a title of 500 characters,
+// or an unknown priority, reach the handlers as they are. The spec is still the place to say it,
+// and middleware can check requests against the spec before they reach the handlers.
+// Tests check the other direction: every response is compared to the spec, and a response
+// the spec doesn't describe fails them, like it would fail a client generated from the spec.
+
+// 1. Implementing the generated interface.
+// Implement the handlers of Server, they respond with 501 Not Implemented now:
+// - ListTasks returns tasks in the order they were created, at most limit of them, 20 when there's no limit,
+//   and an empty array, not null, when there are no tasks,
+// - CreateTask responds 201 Created with the task and its path in the Location header,
+//   a task without a priority gets the normal one,
+// - GetTask and DeleteTask respond 404 Not Found with an Error for tasks that don't exist,
+//   DeleteTask responds 204 No Content without a body.
+// Every response with a body is JSON, writeJSON and writeError write it.
+
+// Server implements the API of tasks in memory.
+type Server struct {
+	mu     sync.Mutex
+	tasks  []api.Task
+	nextID int64
+	Now    func() time.Time
+}
+
+var _ api.ServerInterface = (*Server)(nil)
+
+// NewServer creates a server without tasks.
+func NewServer() *Server {
+	return &Server{nextID: 1, Now: time.Now}
+}
+
+func (s *Server) ListTasks(w http.ResponseWriter, r *http.Request, params api.ListTasksParams) {
+	writeError(w, http.StatusNotImplemented, "not implemented")
+}
+
+func (s *Server) CreateTask(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotImplemented, "not implemented")
+}
+
+func (s *Server) GetTask(w http.ResponseWriter, r *http.Request, id int64) {
+	writeError(w, http.StatusNotImplemented, "not implemented")
+}
+
+func (s *Server) DeleteTask(w http.ResponseWriter, r *http.Request, id int64) {
+	writeError(w, http.StatusNotImplemented, "not implemented")
+}
+
+// writeJSON writes the value as a JSON response with the status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an Error of the spec with the status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, api.Error{Message: msg})
+}
+
+// NewHandler routes requests to the server, checking them against the spec first.
+func NewHandler(s *Server) (http.Handler, error) {
+	spec, err := api.GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+
+	validate, err := Validator(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return validate(api.Handler(s)), nil
+}
+
+func TestTasks(t *testing.T) {
+	c := newContract(t, NewServer())
+
+	status, _, body := c.do(t, http.MethodGet, "/tasks", "")
+	if status != http.StatusOK || strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("Expected 200 with an empty array before any task is created, got %d %s", status, body)
+	}
+
+	start := time.Now()
+
+	status, header, body := c.do(t, http.MethodPost, "/tasks", `{"title": "Write the spec", "priority": "high"}`)
+
+	var task api.Task
+	json.Unmarshal(body, &task)
+
+	if status != http.StatusCreated || task.Id != 1 || task.Title != "Write the spec" || task.Priority != api.High {
+		t.Fatalf("Expected 201 with task 1, got %d %s", status, body)
+	}
+
+	if task.CreatedAt.Before(start.Add(-time.Second)) {
+		t.Errorf("Expected the task to be created now, got createdAt %v", task.CreatedAt)
+	}
+
+	if location := header.Get("Location"); location != "/tasks/1" {
+		t.Errorf("Expected Location /tasks/1, got %q", location)
+	}
+
+	status, _, body = c.do(t, http.MethodPost, "/tasks", `{"title": "Generate the server"}`)
+	json.Unmarshal(body, &task)
+
+	if status != http.StatusCreated || task.Id != 2 || task.Priority != api.Normal {
+		t.Errorf("Expected 201 with task 2 of the normal priority, got %d %s", status, body)
+	}
+
+	status, _, body = c.do(t, http.MethodGet, "/tasks/2", "")
+	json.Unmarshal(body, &task)
+
+	if status != http.StatusOK || task.Title != "Generate the server" {
+		t.Errorf("Expected 200 with task 2, got %d %s", status, body)
+	}
+
+	status, _, body = c.do(t, http.MethodDelete, "/tasks/1", "")
+	if status != http.StatusNoContent || len(body) != 0 {
+		t.Errorf("Expected 204 without a body, got %d %s", status, body)
+	}
+
+	for _, req := range []string{"GET /tasks/1", "DELETE /tasks/1", "GET /tasks/42"} {
+		method, path, _ := strings.Cut(req, " ")
+
+		status, _, body := c.do(t, method, path, "")
+
+		var e api.Error
+		if json.Unmarshal(body, &e); status != http.StatusNotFound || e.Message == "" {
+			t.Errorf("Expected %s to respond 404 with an error, got %d %s", req, status, body)
+		}
+	}
+}
+
+func TestListTasks(t *testing.T) {
+	c := newContract(t, NewServer())
+
+	for i := range 25 {
+		if status, _, body := c.do(t, http.MethodPost, "/tasks", fmt.Sprintf(`{"title": "task %d"}`, i+1)); status != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d %s", status, body)
+		}
+	}
+
+	tests := []struct {
+		path  string
+		count int
+	}{
+		{"/tasks", 20},
+		{"/tasks?limit=5", 5},
+		{"/tasks?limit=100", 25},
+	}
+
+	for _, tt := range tests {
+		status, _, body := c.do(t, http.MethodGet, tt.path, "")
+
+		var tasks []api.Task
+		json.Unmarshal(body, &tasks)
+
+		if status != http.StatusOK || len(tasks) != tt.count || tasks[0].Title != "task 1" {
+			t.Errorf("Expected %s to return the first %d tasks, got %d %d tasks", tt.path, tt.count, status, len(tasks))
+		}
+	}
+}
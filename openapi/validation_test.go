@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/ksysoev/go-workshops/openapi/api"
+)
+
+// 2. Validating requests.
+// The spec already says what a valid request is: a title of 1 to 200 characters, a priority from the enum,
+// no properties the schema doesn't have, a limit from 1 to 100. Writing the same checks by hand in handlers
+// duplicates the spec, and the two drift apart. github.com/getkin/kin-openapi checks requests against
+// the spec itself: a router of routers/legacy finds the operation of a request, and
+// openapi3filter.ValidateRequest checks its parameters and body against the operation.
+//
+// Implement Validator: middleware that responds 400 Bad Request with an Error for requests that don't match
+// the spec, with the message of the validation error, so the client learns what's wrong.
+// Requests to paths that aren't in the spec go to the next handler, which responds 404 or 405 for them.
+// ValidateRequest reads the body, and puts it back for the handler.
+
+// Validator returns middleware that checks requests against the spec.
+func Validator(spec *openapi3.T) (func(http.Handler) http.Handler, error) {
+	return func(next http.Handler) http.Handler {
+		return next
+	}, nil
+}
+
+func TestValidation(t *testing.T) {
+	c := newContract(t, NewServer())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		mentions    string
+	}{
+		{"empty title", http.MethodPost, "/tasks", "application/json", `{"title": ""}`, "title"},
+		{"long title", http.MethodPost, "/tasks", "application/json", `{"title": "` + strings.Repeat("a", 201) + `"}`, "title"},
+		{"missing title", http.MethodPost, "/tasks", "application/json", `{"priority": "low"}`, "title"},
+		{"unknown priority", http.MethodPost, "/tasks", "application/json", `{"title": "a", "priority": "urgent"}`, "priority"},
+		{"unknown property", http.MethodPost, "/tasks", "application/json", `{"title": "a", "owner": "alice"}`, "owner"},
+		{"not JSON", http.MethodPost, "/tasks", "text/plain", `buy milk`, "Content-Type"},
+		{"no body", http.MethodPost, "/tasks", "", ``, "body"},
+		{"limit too small", http.MethodGet, "/tasks?limit=0", "", ``, "limit"},
+		{"limit too large", http.MethodGet, "/tasks?limit=101", "", ``, "limit"},
+		{"zero id", http.MethodGet, "/tasks/0", "", ``, "id"},
+		{"id not a number", http.MethodGet, "/tasks/first", "", ``, "id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, body := c.send(t, tt.method, tt.path, tt.contentType, tt.body)
+
+			var e api.Error
+			json.Unmarshal(body, &e)
+
+			if status != http.StatusBadRequest || !strings.Contains(e.Message, tt.mentions) {
+				t.Errorf("Expected 400 with an error about %s, got %d %s", tt.mentions, status, body)
+			}
+		})
+	}
+
+	// Invalid requests never reach the handlers.
+	if status, _, body := c.do(t, http.MethodGet, "/tasks", ""); status == http.StatusOK && strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("Expected no tasks to be created by invalid requests, got %s", body)
+	}
+
+	if status, _, body := c.do(t, http.MethodPost, "/tasks", `{"title": "valid"}`); status != http.StatusCreated {
+		t.Errorf("Expected a valid request to pass, got %d %s", status, body)
+	}
+}
@@ -0,0 +1,180 @@
+package osinterop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// os/exec runs other programs. exec.CommandContext ties the process to a context: when the context is done,
+// the process is killed. Output and CombinedOutput are handy for short commands, but they wait for the process to exit.
+//
+// For a long running command, like a build or a log tail, output should be processed as soon as it's printed.
+// cmd.StdoutPipe returns a reader connected to the stdout of the process after cmd.Start.
+// Keep in mind that cmd.Wait closes the pipe, so all reads must be finished before calling it.
+//
+// Fix StreamLines, so each line is sent to the channel as soon as the command prints it.
+
+// StreamLines starts the command and sends lines of its stdout to the returned channel.
+// The channel is closed when the output ends, then wait returns the result of the command.
+func StreamLines(ctx context.Context, name string, args ...string) (lines <-chan string, wait func() error, err error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan string, strings.Count(string(out), "\n"))
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		ch <- line
+	}
+
+	close(ch)
+
+	return ch, func() error { return nil }, nil
+}
+
+func init() {
+	// lines prints lines with pauses between them, every argument is either a line or a pause like 500ms.
+	helpers["lines"] = func(args []string) int {
+		for _, arg := range args {
+			if d, err := time.ParseDuration(arg); err == nil {
+				time.Sleep(d)
+				continue
+			}
+
+			fmt.Println(arg)
+		}
+
+		return 0
+	}
+
+	// exit prints the message to stderr and exits with the code.
+	helpers["exit"] = func(args []string) int {
+		fmt.Fprintln(os.Stderr, args[0])
+
+		code, _ := strconv.Atoi(args[1])
+
+		return code
+	}
+}
+
+func TestStreamLines(t *testing.T) {
+	helper := useHelpers(t)
+	start := time.Now()
+
+	lines, wait, err := StreamLines(context.Background(), helper, "lines", "first", "1500ms", "second")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if line := <-lines; line != "first" {
+		t.Errorf("Expected first line to be %q, got %q", "first", line)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected first line before the command finishes, got it after %s", elapsed)
+	}
+
+	if line := <-lines; line != "second" {
+		t.Errorf("Expected second line to be %q, got %q", "second", line)
+	}
+
+	if line, ok := <-lines; ok {
+		t.Errorf("Expected channel to be closed, got %q", line)
+	}
+
+	if err := wait(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestStreamLinesCanceled(t *testing.T) {
+	helper := useHelpers(t)
+
+	// The timeout only keeps the test from hanging, the command is canceled as soon as the first line is received.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	lines, wait, err := StreamLines(ctx, helper, "lines", "first", "1m", "second")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if line := <-lines; line != "first" {
+		t.Fatalf("Expected first line to be %q, got %q", "first", line)
+	}
+
+	cancel()
+
+	for line := range lines {
+		t.Errorf("Expected no more lines after cancellation, got %q", line)
+	}
+
+	if err := wait(); err == nil {
+		t.Error("Expected error for the killed command, got nil")
+	}
+}
+
+// A failed command is reported by *exec.ExitError, which has the exit code and, when Output is used, the captured stderr.
+// Callers shouldn't need to know about os/exec to handle failures of a command, so RunCommand should return
+// *CommandError for commands that exited with a non-zero code. Other errors, like exec.ErrNotFound
+// for a missing binary, are returned as they are.
+
+// CommandError is a command that exited with a non-zero code.
+type CommandError struct {
+	Command string
+	Code    int
+	Stderr  string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s exited with code %d: %s", e.Command, e.Code, strings.TrimSpace(e.Stderr))
+}
+
+// RunCommand runs the command and returns its stdout.
+func RunCommand(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+
+	return string(out), err
+}
+
+func TestRunCommandExitCode(t *testing.T) {
+	helper := useHelpers(t)
+
+	_, err := RunCommand(context.Background(), helper, "exit", "disk is full", "3")
+
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected *CommandError, got %T: %v", err, err)
+	}
+
+	if cmdErr.Command != helper || cmdErr.Code != 3 || cmdErr.Stderr != "disk is full\n" {
+		t.Errorf("Expected command %s with code 3 and stderr %q, got %+v", helper, "disk is full\n", cmdErr)
+	}
+}
+
+func TestRunCommandNotFound(t *testing.T) {
+	_, err := RunCommand(context.Background(), "osinterop-no-such-command")
+
+	var cmdErr *CommandError
+	if !errors.Is(err, exec.ErrNotFound) || errors.As(err, &cmdErr) {
+		t.Errorf("Expected exec.ErrNotFound, got %T: %v", err, err)
+	}
+}
+
+func TestRunCommandOutput(t *testing.T) {
+	out, err := RunCommand(context.Background(), useHelpers(t), "lines", "hello", "world")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if out != "hello\nworld\n" {
+		t.Errorf("Expected output %q, got %q", "hello\nworld\n", out)
+	}
+}
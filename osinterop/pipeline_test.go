@@ -0,0 +1,85 @@
+package osinterop
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Advanced: pipelines.
+//
+// A shell connects commands with pipes, `a | b | c`: stdout of each command is stdin of the next one,
+// all commands run at the same time, and data flows through the kernel without passing through the shell.
+// exec.Cmd can do the same: StdoutPipe of one command is assigned to Stdin of the next one.
+//
+// Let's implement Pipeline without a shell. It returns the output of the last command,
+// and like `set -o pipefail` it fails if any of the commands fails, not only the last one.
+// Mind the order: all commands must be started before waiting for any of them,
+// and the pipe returned by StdoutPipe is closed by Wait, so wait for a reader only after its writer.
+
+// Pipeline runs commands connected with pipes and returns the output of the last one.
+func Pipeline(cmds ...*exec.Cmd) ([]byte, error) {
+	return cmds[len(cmds)-1].Output()
+}
+
+func init() {
+	// upper copies stdin to stdout in upper case.
+	helpers["upper"] = func([]string) int {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			fmt.Println(strings.ToUpper(scanner.Text()))
+		}
+
+		return 0
+	}
+
+	// grep copies lines of stdin that contain the argument to stdout, it fails if nothing is found like grep does.
+	helpers["grep"] = func(args []string) int {
+		code := 1
+
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if strings.Contains(scanner.Text(), args[0]) {
+				fmt.Println(scanner.Text())
+
+				code = 0
+			}
+		}
+
+		return code
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	helper := useHelpers(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := Pipeline(
+		exec.CommandContext(ctx, helper, "lines", "gopher", "rustacean", "go", "python"),
+		exec.CommandContext(ctx, helper, "upper"),
+		exec.CommandContext(ctx, helper, "grep", "GO"),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(out) != "GOPHER\nGO\n" {
+		t.Errorf("Expected output of the last command %q, got %q", "GOPHER\nGO\n", out)
+	}
+
+	_, err = Pipeline(
+		exec.CommandContext(ctx, helper, "lines", "gopher"),
+		exec.CommandContext(ctx, helper, "exit", "broken filter", "3"),
+		exec.CommandContext(ctx, helper, "upper"),
+	)
+	if err == nil {
+		t.Error("Expected error when a command in the middle of the pipeline fails")
+	}
+}
@@ -0,0 +1,170 @@
+//go:build unix
+
+package osinterop
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// A process is asked to stop with a signal: SIGINT when Ctrl+C is pressed, SIGTERM from kill, systemd, or Kubernetes.
+// By default both terminate the process immediately, without any chance to finish requests or flush buffers.
+//
+// signal.NotifyContext returns a context that is canceled on one of the given signals.
+// The whole program is then stopped the same way it's stopped on any other cancellation.
+// The returned stop function unregisters the signals and restores the default behavior.
+//
+// Run should run the worker until it returns or the process gets SIGINT or SIGTERM.
+// Then the worker is expected to clean up and return, but if cleanup hangs,
+// the second signal must terminate the process as usual, people press Ctrl+C twice for a reason.
+
+// Run runs the worker with a context canceled on SIGINT and SIGTERM.
+func Run(ctx context.Context, worker func(ctx context.Context) error) error {
+	return worker(ctx)
+}
+
+func init() {
+	// serve runs a worker that cleans up on cancellation.
+	// With argument "hang", cleanup never finishes.
+	helpers["serve"] = func(args []string) int {
+		hang := len(args) > 0 && args[0] == "hang"
+
+		err := Run(context.Background(), func(ctx context.Context) error {
+			fmt.Println("ready")
+			<-ctx.Done()
+			fmt.Println("cleaning up")
+
+			if hang {
+				time.Sleep(time.Minute)
+			}
+
+			return nil
+		})
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+
+		fmt.Println("stopped")
+
+		return 0
+	}
+}
+
+// startServe starts the helper serve and waits until it's ready.
+// Lines of its output are sent to the channel, which is closed when the process closes stdout.
+func startServe(t *testing.T, args ...string) (*exec.Cmd, <-chan string) {
+	t.Helper()
+
+	cmd := exec.Command(useHelpers(t), append([]string{"serve"}, args...)...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	lines := make(chan string)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	if line, _ := nextLine(t, lines, 5*time.Second); line != "ready" {
+		t.Fatalf("Expected helper to print ready, got %q", line)
+	}
+
+	return cmd, lines
+}
+
+// nextLine returns the next line of the output, or false if the output is closed.
+func nextLine(t *testing.T, lines <-chan string, timeout time.Duration) (string, bool) {
+	t.Helper()
+
+	select {
+	case line, ok := <-lines:
+		return line, ok
+	case <-time.After(timeout):
+		t.Fatalf("Expected process to print a line or exit in %s, it's still running", timeout)
+		return "", false
+	}
+}
+
+func TestRunGracefulShutdown(t *testing.T) {
+	cmd, lines := startServe(t)
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"cleaning up", "stopped"} {
+		if line, ok := nextLine(t, lines, 5*time.Second); line != expected {
+			t.Fatalf("Expected process to print %q, got %q, output closed: %t", expected, line, !ok)
+		}
+	}
+
+	if line, ok := nextLine(t, lines, 5*time.Second); ok {
+		t.Fatalf("Expected process to exit, got %q", line)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected process to exit gracefully, got %v", err)
+	}
+}
+
+func TestRunSecondSignal(t *testing.T) {
+	cmd, lines := startServe(t, "hang")
+
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if line, _ := nextLine(t, lines, 5*time.Second); line != "cleaning up" {
+		t.Fatalf("Expected worker to start cleanup on the first signal, got %q", line)
+	}
+
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if line, ok := nextLine(t, lines, 2*time.Second); ok {
+		t.Fatalf("Expected process to exit, got %q", line)
+	}
+
+	err := cmd.Wait()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Sys().(syscall.WaitStatus).Signal() != syscall.SIGINT {
+		t.Errorf("Expected process to be terminated by the second SIGINT, got %v", err)
+	}
+}
+
+func TestRunWorkerError(t *testing.T) {
+	errWorker := errors.New("worker failed")
+
+	err := Run(context.Background(), func(context.Context) error {
+		return errWorker
+	})
+	if !errors.Is(err, errWorker) {
+		t.Errorf("Expected worker error, got %v", err)
+	}
+}
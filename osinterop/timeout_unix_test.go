@@ -0,0 +1,135 @@
+//go:build unix
+
+package osinterop
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// exec.CommandContext kills only the process it started. If that process started children of its own,
+// like a shell script running other programs, they keep running after the timeout.
+// Even worse, children inherit stdout, so Output keeps waiting for the pipe to close until they exit.
+//
+// On Unix, processes are organized in process groups. A child can be started in a new group with
+// syscall.SysProcAttr{Setpgid: true}, and a signal sent to the negative PID is delivered to the whole group.
+// exec.Cmd has two fields to customize cancellation: Cancel is called instead of killing the process,
+// and WaitDelay limits how long Wait waits for pipes after the process is gone.
+//
+// Fix RunWithTimeout, so the timeout kills everything the command started, and the error matches context.DeadlineExceeded.
+
+// RunWithTimeout runs the command and returns its stdout, the command is killed when the timeout expires.
+func RunWithTimeout(ctx context.Context, timeout time.Duration, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, name, args...).Output()
+}
+
+func init() {
+	// spawn starts a child that sleeps for a minute, writes its PID to the file, and sleeps too.
+	helpers["spawn"] = func(args []string) int {
+		child := exec.Command(os.Args[0], "sleep")
+		child.Stdout = os.Stdout
+
+		if err := child.Start(); err != nil {
+			return 1
+		}
+
+		if err := os.WriteFile(args[0], []byte(strconv.Itoa(child.Process.Pid)), 0o644); err != nil {
+			return 1
+		}
+
+		time.Sleep(time.Minute)
+
+		return 0
+	}
+
+	helpers["sleep"] = func([]string) int {
+		time.Sleep(time.Minute)
+		return 0
+	}
+}
+
+// alive reports whether the process exists and is not a zombie.
+func alive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+
+	// A zombie still has a PID until its parent reaps it, procfs is the only way to tell, if there is one.
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+
+	_, state, _ := strings.Cut(string(stat), ") ")
+
+	return !strings.HasPrefix(state, "Z")
+}
+
+func TestRunWithTimeoutKillsGroup(t *testing.T) {
+	helper := useHelpers(t)
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+
+	t.Cleanup(func() {
+		if data, err := os.ReadFile(pidFile); err == nil {
+			if pid, err := strconv.Atoi(string(data)); err == nil {
+				_ = syscall.Kill(pid, syscall.SIGKILL)
+			}
+		}
+	})
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := RunWithTimeout(context.Background(), 500*time.Millisecond, helper, "spawn", pidFile)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected error to match context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected RunWithTimeout to return shortly after the timeout, it's still waiting for the command")
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pid, err := strconv.Atoi(string(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for deadline := time.Now().Add(2 * time.Second); alive(pid); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected child process %d to be killed with its parent, it's still running", pid)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunWithTimeoutSuccess(t *testing.T) {
+	out, err := RunWithTimeout(context.Background(), 5*time.Second, useHelpers(t), "lines", "done")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(out) != "done\n" {
+		t.Errorf("Expected output %q, got %q", "done\n", out)
+	}
+}
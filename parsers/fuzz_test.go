@@ -0,0 +1,61 @@
+package parsers
+
+import (
+	"errors"
+	"testing"
+)
+
+// 4. Advanced: Fuzzing.
+// Tables of test cases check the inputs someone thought of. A fuzzer generates inputs nobody thought of:
+// it mutates a corpus of seed inputs, watches which branches of the code they cover, and keeps the ones
+// that reach new code. A parser is the perfect target, its input is a string and its properties are simple:
+// - Parse never panics, whatever the input,
+// - a *SyntaxError points inside the input, from 0 to its length,
+// - the String of a parsed expression parses back into the same tree, the printer and the parser agree.
+//
+// Run the fuzzer for a while:
+//
+//	go test -run '^$' -fuzz FuzzParse -fuzztime 30s ./parsers
+//
+// Every input that fails is saved to testdata/fuzz/FuzzParse, and the next go test runs it as a regression test,
+// with or without -fuzz: those files are checked in, so the regressions the fuzzer has found in this parser
+// fail now. Look at them: the printer writes numbers that the lexer can't read back, and primary turns
+// a number too large for float64 into +Inf without a word. Print numbers in the notation of the lexer,
+// and fail with "number out of range" when strconv.ParseFloat does.
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"1 + 2 * 3", "(1 - 2) ^ -3 / 4", "--0.5", "((1)", "1 2", "2 ^ ^"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		e, err := parse(t, input)
+		if errors.Is(err, errPanicked) {
+			return
+		}
+
+		if err != nil {
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected Parse(%q) to fail with *SyntaxError, got %v", input, err)
+			}
+
+			if syntaxErr.Pos < 0 || syntaxErr.Pos > len(input) {
+				t.Fatalf("Expected the error of Parse(%q) to point inside the input, got %v", input, err)
+			}
+
+			return
+		}
+
+		printed := e.String()
+
+		again, err := parse(t, printed)
+		if err != nil {
+			t.Fatalf("Expected %q, printed from %q, to be parsed, got %v", printed, input, err)
+		}
+
+		if again.String() != printed {
+			t.Fatalf("Expected %q to be parsed back into itself, got %q", printed, again.String())
+		}
+	})
+}
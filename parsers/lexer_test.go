@@ -0,0 +1,184 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Let's write a parser for arithmetic expressions like 2 * (3 + 4) ^ 2, in two classic steps:
+// a lexer splits the input into tokens, and a parser builds a syntax tree of the tokens.
+// Both report mistakes in the input as *SyntaxError with the position of the mistake, a byte offset in the input,
+// so an editor or a config loader can point at it.
+
+// SyntaxError is an error in the input, Pos is its byte offset.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+// 1. Tokens.
+// Tokens are numbers, operators, and parentheses, whitespace between them is skipped. A number is one or more
+// digits, optionally followed by a dot and one or more digits: 42 and 3.14, but not .5, 1., or 1.2.3.
+// Tokenize reads every digit and dot in a row as a number, so 1.2.3 becomes one token that nothing can convert
+// to a number. Stop the number where it stops being valid: 1.2.3 is the number 1.2 and an unexpected '.'.
+//
+// Go strings are bytes, and input[i] is a byte, not a character: é is two bytes in UTF-8, and an error message
+// that prints its first byte prints Ã. Decode the character at the position with utf8.DecodeRuneInString.
+
+// TokenKind is the kind of a token.
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	NumberToken
+	Plus
+	Minus
+	Star
+	Slash
+	Caret
+	LParen
+	RParen
+)
+
+// Token is a token of the input, Pos is the byte offset of its first byte.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+func (t Token) String() string {
+	if t.Kind == EOF {
+		return "end of input"
+	}
+
+	return fmt.Sprintf("%q", t.Text)
+}
+
+// operators are tokens of one character.
+var operators = map[byte]TokenKind{
+	'+': Plus,
+	'-': Minus,
+	'*': Star,
+	'/': Slash,
+	'^': Caret,
+	'(': LParen,
+	')': RParen,
+}
+
+// Tokenize splits the input into tokens, the last token is always EOF.
+func Tokenize(input string) ([]Token, error) {
+	var tokens []Token
+
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case operators[c] != EOF:
+			tokens = append(tokens, Token{Kind: operators[c], Text: input[i : i+1], Pos: i})
+			i++
+		case isDigit(c):
+			start := i
+			for i < len(input) && (isDigit(input[i]) || input[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, Token{Kind: NumberToken, Text: input[start:i], Pos: start})
+		default:
+			return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", rune(c))}
+		}
+	}
+
+	return append(tokens, Token{Kind: EOF, Pos: len(input)}), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// describeTokens formats tokens for test messages, like "3.14"@0.
+func describeTokens(tokens []Token) string {
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		parts[i] = fmt.Sprintf("%s@%d", t, t.Pos)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input  string
+		tokens string
+	}{
+		{"", "end of input@0"},
+		{"42", `"42"@0 end of input@2`},
+		{"3.14 * (2 ^ 2)", `"3.14"@0 "*"@5 "("@7 "2"@8 "^"@10 "2"@12 ")"@13 end of input@14`},
+		{" 1+\t-2\n", `"1"@1 "+"@2 "-"@4 "2"@5 end of input@7`},
+		{"10/0.5", `"10"@0 "/"@2 "0.5"@3 end of input@6`},
+	}
+
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.input)
+		if err != nil {
+			t.Errorf("Expected %q to be tokenized, got %v", tt.input, err)
+			continue
+		}
+
+		if got := describeTokens(tokens); got != tt.tokens {
+			t.Errorf("Expected tokens of %q to be %s, got %s", tt.input, tt.tokens, got)
+		}
+	}
+
+	kinds := []TokenKind{}
+	if tokens, err := Tokenize("1+2-3*4/5^(6)"); err == nil {
+		for _, tok := range tokens {
+			kinds = append(kinds, tok.Kind)
+		}
+	}
+
+	expected := []TokenKind{NumberToken, Plus, NumberToken, Minus, NumberToken, Star, NumberToken, Slash,
+		NumberToken, Caret, LParen, NumberToken, RParen, EOF}
+	if !slices.Equal(kinds, expected) {
+		t.Errorf("Expected kinds of tokens to be %v, got %v", expected, kinds)
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"1 $ 2", 2, `unexpected character '$'`},
+		{"r * 2", 0, `unexpected character 'r'`},
+		{"1.2.3", 3, `unexpected character '.'`},
+		{"1. + 2", 1, `unexpected character '.'`},
+		{".5", 0, `unexpected character '.'`},
+		{"2 * é", 4, `unexpected character 'é'`},
+		{"1 + 2 → 3", 6, `unexpected character '→'`},
+	}
+
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.input)
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected %q to fail with *SyntaxError, got %v and tokens %s", tt.input, err, describeTokens(tokens))
+			continue
+		}
+
+		if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("Expected %q to fail at %d with %s, got %v", tt.input, tt.pos, tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 2. Recursive descent.
+// A recursive descent parser has a method for every rule of the grammar, and rules call each other the way
+// the grammar nests. Operators that bind tighter are deeper in the grammar:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | "(" expr ")"
+//
+// { ... } repeats, so 1 - 2 - 3 is a loop that folds to the left: (1 - 2) - 3. expr below recurses instead,
+// and subtraction comes out right-associative: 1 - (2 - 3), which is 2 instead of -4.
+//
+// Powers are the opposite, 2 ^ 3 ^ 2 is 2 ^ (3 ^ 2) = 512 in mathematics, so power calls unary for its exponent,
+// which leads back to power: the recursion makes it right-associative, and allows 2 ^ -1. power below loops,
+// and its exponent is a primary. And unary comes before power, so -2 ^ 2 is -(2 ^ 2) = -4, like on paper.
+//
+// Parse returns a syntax tree: Expr can be evaluated, and printed with all parentheses to show how it was parsed.
+
+// Expr is a node of a syntax tree.
+type Expr interface {
+	// Eval returns the value of the expression.
+	Eval() float64
+	// String returns the expression with every operation in parentheses, it parses back into the same tree.
+	String() string
+}
+
+// Number is a number literal.
+type Number float64
+
+func (n Number) Eval() float64 {
+	return float64(n)
+}
+
+func (n Number) String() string {
+	return strconv.FormatFloat(float64(n), 'g', -1, 64)
+}
+
+// Neg is a negation, -X.
+type Neg struct {
+	X Expr
+}
+
+func (n Neg) Eval() float64 {
+	return -n.X.Eval()
+}
+
+func (n Neg) String() string {
+	return "(-" + n.X.String() + ")"
+}
+
+// Binary is an operation with two operands, X Op Y.
+type Binary struct {
+	Op   TokenKind
+	X, Y Expr
+}
+
+func (b Binary) Eval() float64 {
+	x, y := b.X.Eval(), b.Y.Eval()
+
+	switch b.Op {
+	case Plus:
+		return x + y
+	case Minus:
+		return x - y
+	case Star:
+		return x * y
+	case Slash:
+		return x / y
+	case Caret:
+		return math.Pow(x, y)
+	}
+
+	panic(fmt.Sprintf("unknown operator %d", b.Op))
+}
+
+func (b Binary) String() string {
+	return fmt.Sprintf("(%s %s %s)", b.X, symbols[b.Op], b.Y)
+}
+
+// symbols are the symbols of binary operators.
+var symbols = [...]string{Plus: "+", Minus: "-", Star: "*", Slash: "/", Caret: "^"}
+
+// Parse parses the input into a syntax tree.
+func Parse(input string) (Expr, error) {
+	tokens, err := Tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	return p.expr(), nil
+}
+
+// Eval parses the input and evaluates it.
+func Eval(input string) (float64, error) {
+	e, err := Parse(input)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.Eval(), nil
+}
+
+// parser is the state of parsing, the position in the tokens.
+type parser struct {
+	tokens []Token
+	pos    int
+	depth  int
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+// next consumes the next token, it stays at EOF at the end.
+func (p *parser) next() Token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != EOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// fail stops parsing with a syntax error at the token.
+func (p *parser) fail(tok Token, format string, args ...any) {
+	panic(&SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// expr = term { ("+" | "-") term }
+func (p *parser) expr() Expr {
+	x := p.term()
+
+	if op := p.peek(); op.Kind == Plus || op.Kind == Minus {
+		p.next()
+		return Binary{Op: op.Kind, X: x, Y: p.expr()}
+	}
+
+	return x
+}
+
+// term = unary { ("*" | "/") unary }
+func (p *parser) term() Expr {
+	x := p.unary()
+
+	for op := p.peek(); op.Kind == Star || op.Kind == Slash; op = p.peek() {
+		p.next()
+		x = Binary{Op: op.Kind, X: x, Y: p.unary()}
+	}
+
+	return x
+}
+
+// unary = "-" unary | power
+func (p *parser) unary() Expr {
+	if p.peek().Kind == Minus {
+		p.next()
+		return Neg{X: p.unary()}
+	}
+
+	return p.power()
+}
+
+// power = primary [ "^" unary ]
+func (p *parser) power() Expr {
+	x := p.primary()
+
+	for p.peek().Kind == Caret {
+		p.next()
+		x = Binary{Op: Caret, X: x, Y: p.primary()}
+	}
+
+	return x
+}
+
+// primary = number | "(" expr ")"
+func (p *parser) primary() Expr {
+	tok := p.next()
+
+	switch tok.Kind {
+	case NumberToken:
+		v, _ := strconv.ParseFloat(tok.Text, 64)
+		return Number(v)
+	case LParen:
+		x := p.expr()
+
+		if closing := p.next(); closing.Kind != RParen {
+			p.fail(closing, "expected \")\", got %s", closing)
+		}
+
+		return x
+	}
+
+	p.fail(tok, "unexpected %s", tok)
+
+	return nil
+}
+
+// parse calls Parse, and fails the test instead of crashing it when Parse panics.
+func parse(t testing.TB, input string) (Expr, error) {
+	t.Helper()
+
+	return noPanic(t, Parse, input)
+}
+
+// noPanic calls fn with the input, and fails the test instead of crashing it when fn panics.
+func noPanic[T any](t testing.TB, fn func(string) (T, error), input string) (result T, err error) {
+	t.Helper()
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Expected %q to be handled without a panic, got: %v", input, r)
+
+			err = errPanicked
+		}
+	}()
+
+	return fn(input)
+}
+
+var errPanicked = errors.New("panicked")
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		input, tree string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"(1 + 2) * 3", "((1 + 2) * 3)"},
+		{"1 - 2 - 3", "((1 - 2) - 3)"},
+		{"1 - 2 + 3", "((1 - 2) + 3)"},
+		{"8 / 4 / 2", "((8 / 4) / 2)"},
+		{"2 * 3 ^ 2", "(2 * (3 ^ 2))"},
+		{"2 ^ 3 ^ 2", "(2 ^ (3 ^ 2))"},
+		{"-2 ^ 2", "(-(2 ^ 2))"},
+		{"2 ^ -1", "(2 ^ (-1))"},
+		{"--3", "(-(-3))"},
+		{"1 - -1", "(1 - (-1))"},
+		{"((42))", "42"},
+	}
+
+	for _, tt := range tests {
+		e, err := parse(t, tt.input)
+		if err != nil {
+			t.Errorf("Expected %q to be parsed, got %v", tt.input, err)
+			continue
+		}
+
+		if got := e.String(); got != tt.tree {
+			t.Errorf("Expected %q to be parsed as %s, got %s", tt.input, tt.tree, got)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"1 - 2 - 3", -4},
+		{"2 ^ 3 ^ 2", 512},
+		{"-2 ^ 2", -4},
+		{"2 ^ -1", 0.5},
+		{"100 / 10 / 5", 2},
+		{"3.5 * (2 - 0.5)", 5.25},
+		{"1 / 0", math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		got, err := noPanic(t, Eval, tt.input)
+		if err != nil {
+			t.Errorf("Expected %q to be evaluated, got %v", tt.input, err)
+			continue
+		}
+
+		if got != tt.value {
+			t.Errorf("Expected %q to be %g, got %g", tt.input, tt.value, got)
+		}
+	}
+}
+
+// 3. Panics as control flow.
+// A mistake in the input is found deep in the recursion: in 1 + (2 * (3 - )), primary finds the ')'
+// four calls down from expr. Returning an error from every method and checking it in every caller doubles
+// the parser, so fail panics with *SyntaxError and unwinds all the calls at once. That's the third case
+// for panics in the errorhandling workshop: a deep recursion that stops with an error for the top-level function.
+//
+// The rule that comes with it: the panic never leaves the package. Parse must recover it and return it
+// as the error, a caller of Parse expects an error for bad input, not a crash. Recover only *SyntaxError:
+// any other panic is a bug in the parser, like an index out of range, and turning it into a syntax error
+// would blame the input for it. Panic again with the value you recovered.
+//
+// Two more mistakes to catch while you're here. Parse stops after the first expression and ignores the rest,
+// so 1 2 parses as 1: the whole input must be consumed, the next token after expr must be EOF.
+// Every ( is another few calls deep, and a hostile input of a million of them exhausts the stack of the
+// goroutine, a fatal error that recover can't stop: fail when parentheses are nested deeper than maxDepth.
+
+// maxDepth is the deepest nesting of parentheses Parse accepts.
+const maxDepth = 1000
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"", 0, "unexpected end of input"},
+		{"1 +", 3, "unexpected end of input"},
+		{"1 + (2 * (3 - ))", 14, `unexpected ")"`},
+		{"(1 + 2", 6, `expected ")", got end of input`},
+		{"1 2", 2, `unexpected "2"`},
+		{"(1) (2)", 4, `unexpected "("`},
+		{"1 + 2)", 5, `unexpected ")"`},
+		{"* 3", 0, `unexpected "*"`},
+		{"2 ^ ^ 3", 4, `unexpected "^"`},
+		{"1 + $", 4, `unexpected character '$'`},
+	}
+
+	for _, tt := range tests {
+		e, err := parse(t, tt.input)
+		if errors.Is(err, errPanicked) {
+			continue
+		}
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected %q to fail with *SyntaxError, got %v and %v", tt.input, err, e)
+			continue
+		}
+
+		if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("Expected %q to fail at %d with %s, got %v", tt.input, tt.pos, tt.msg, err)
+		}
+	}
+}
+
+func TestNestingLimit(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + "1" + strings.Repeat(")", depth)
+	}
+
+	if _, err := parse(t, nested(maxDepth)); err != nil {
+		t.Errorf("Expected %d nested parentheses to be parsed, got %v", maxDepth, err)
+	}
+
+	for _, depth := range []int{maxDepth + 1, 10 * maxDepth} {
+		_, err := parse(t, nested(depth))
+		if errors.Is(err, errPanicked) {
+			continue
+		}
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || syntaxErr.Pos != maxDepth {
+			t.Errorf("Expected %d nested parentheses to fail at offset %d, the first one too deep, got %v",
+				depth, maxDepth, err)
+			continue
+		}
+
+		if syntaxErr.Msg != "expression nested too deeply" {
+			t.Errorf("Expected the error to say the expression is nested too deeply, got %q", syntaxErr.Msg)
+		}
+	}
+}
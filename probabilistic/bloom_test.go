@@ -0,0 +1,264 @@
+package probabilistic
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// 1. Bloom Filter.
+// A set that answers "have I seen this item?" needs memory for every item: a map of a million URLs a crawler has
+// visited holds a million URLs. A Bloom filter answers with a few bits per item, whatever the size of the items,
+// and pays for it with false positives: Contains may say yes for an item that was never added. It never says no
+// for an item that was added, so the answer "no" is certain, and "yes" means "probably, check the real storage".
+//
+// The filter is an array of m bits and k hash functions. Add sets the k bits the item hashes to, and Contains
+// checks that all of them are set. Another item has all its k bits set by chance when the filter fills up,
+// and the chance depends on m and k: for n items and a false-positive rate p, the optimal sizes are
+//
+//	m = -n * ln(p) / ln(2)^2 bits, rounded up,
+//	k = m / n * ln(2) hash functions, rounded to the nearest integer,
+//
+// about 9.6 bits and 7 hash functions per item for 1%, and 4.8 bits more for every tenfold smaller rate.
+// NewBloomFilter below ignores p: a byte per item with one hash function gives about 12% false positives.
+//
+// k independent hash functions are expensive, double hashing gets them from one 64-bit hash: split it into
+// two halves h1 and h2, and the i-th bit is (h1 + i*h2) mod m. The filter below already does it.
+//
+// The filter is shared between goroutines, and Add writes words of the bit array that Contains reads.
+// A mutex works, but every bit only ever goes from 0 to 1, so atomic.Uint64 and its Or method are enough:
+// a word of bits set by two goroutines at once keeps the bits of both.
+
+// BloomFilter is a set of strings with false positives, it's safe for concurrent use.
+type BloomFilter struct {
+	words []uint64
+	m     uint64
+	k     uint64
+	seed  maphash.Seed
+}
+
+// NewBloomFilter creates a filter for n items with the false-positive rate p.
+func NewBloomFilter(n int, p float64) *BloomFilter {
+	m := uint64(n) * 8
+
+	return &BloomFilter{
+		words: make([]uint64, (m+63)/64),
+		m:     m,
+		k:     1,
+		seed:  maphash.MakeSeed(),
+	}
+}
+
+// Bits returns the number of bits in the filter, m.
+func (f *BloomFilter) Bits() uint64 {
+	return f.m
+}
+
+// Hashes returns the number of hash functions, k.
+func (f *BloomFilter) Hashes() int {
+	return int(f.k)
+}
+
+// Add adds the item to the filter.
+func (f *BloomFilter) Add(item string) {
+	h1, h2 := doubleHash(f.seed, item)
+
+	for i := range f.k {
+		bit := (h1 + i*h2) % f.m
+		f.words[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether the item may have been added, false means it has certainly not been.
+func (f *BloomFilter) Contains(item string) bool {
+	h1, h2 := doubleHash(f.seed, item)
+
+	for i := range f.k {
+		bit := (h1 + i*h2) % f.m
+		if f.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// doubleHash splits a 64-bit hash of the item into two 32-bit hashes.
+func doubleHash(seed maphash.Seed, item string) (h1, h2 uint64) {
+	h := maphash.String(seed, item)
+
+	return h >> 32, h & math.MaxUint32
+}
+
+func TestBloomFilterSizing(t *testing.T) {
+	tests := []struct {
+		n      int
+		p      float64
+		bits   uint64
+		hashes int
+	}{
+		{n: 1000, p: 0.01, bits: 9586, hashes: 7},
+		{n: 1000, p: 0.001, bits: 14378, hashes: 10},
+		{n: 1_000_000, p: 0.01, bits: 9_585_059, hashes: 7},
+		{n: 100, p: 0.5, bits: 145, hashes: 1},
+	}
+
+	for _, tt := range tests {
+		f := NewBloomFilter(tt.n, tt.p)
+
+		if f.Bits() != tt.bits || f.Hashes() != tt.hashes {
+			t.Errorf("Expected %d bits and %d hashes for %d items at %g, got %d bits and %d hashes",
+				tt.bits, tt.hashes, tt.n, tt.p, f.Bits(), f.Hashes())
+		}
+	}
+}
+
+// The false-positive rate is a probability, so the test measures it: it fills a filter with n items,
+// and queries many items that were never added. The share of "yes" answers is an estimate of the rate,
+// and with q queries its standard deviation is sqrt(p*(1-p)/q). A correct filter stays within 4 deviations
+// of p, a test that fails by chance once in 30,000 runs: the bound is tight and the test isn't flaky.
+
+func TestBloomFilterFalsePositiveRate(t *testing.T) {
+	const (
+		n       = 10_000
+		queries = 200_000
+	)
+
+	for _, p := range []float64{0.01, 0.001} {
+		t.Run(fmt.Sprint(p), func(t *testing.T) {
+			f := NewBloomFilter(n, p)
+
+			for i := range n {
+				f.Add(fmt.Sprintf("user-%d", i))
+			}
+
+			for i := range n {
+				if !f.Contains(fmt.Sprintf("user-%d", i)) {
+					t.Fatalf("Expected user-%d to be in the filter, a Bloom filter has no false negatives", i)
+				}
+			}
+
+			positives := 0
+
+			for i := range queries {
+				if f.Contains(fmt.Sprintf("visitor-%d", i)) {
+					positives++
+				}
+			}
+
+			rate := float64(positives) / queries
+			bound := p + 4*math.Sqrt(p*(1-p)/queries)
+
+			t.Logf("%d bits, %d hashes, %.4f%% false positives", f.Bits(), f.Hashes(), rate*100)
+
+			if rate > bound {
+				t.Errorf("Expected at most %.4f%% false positives, got %.4f%%", bound*100, rate*100)
+			}
+		})
+	}
+}
+
+func TestBloomFilterConcurrent(t *testing.T) {
+	testutil.RequireRaceDetector(t)
+
+	f := NewBloomFilter(8000, 0.01)
+	wg := sync.WaitGroup{}
+
+	for w := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range 1000 {
+				item := fmt.Sprintf("worker-%d-%d", w, i)
+
+				f.Add(item)
+
+				if !f.Contains(item) {
+					t.Errorf("Expected %s to be in the filter right after Add", item)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for w := range 8 {
+		for i := range 1000 {
+			if item := fmt.Sprintf("worker-%d-%d", w, i); !f.Contains(item) {
+				t.Fatalf("Expected %s to be in the filter, a concurrent Add lost its bits", item)
+			}
+		}
+	}
+}
+
+// How much memory does a filter save? Both benchmarks build a set of the same items, and report bytes
+// allocated per item next to the time:
+//
+//	go test -run '^$' -bench Set -benchmem ./probabilistic
+//
+// The map stores the items themselves, and its memory grows with their length. The filter stores bits.
+
+const benchItems = 100_000
+
+func benchmarkItems() []string {
+	items := make([]string, benchItems)
+	for i := range items {
+		items[i] = fmt.Sprintf("https://example.com/articles/%d", i)
+	}
+
+	return items
+}
+
+// reportBytesPerItem reports the memory allocated by the benchmark per item of a set.
+func reportBytesPerItem(b *testing.B, run func()) {
+	b.Helper()
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+
+	run()
+
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+
+	b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*benchItems), "B/item")
+}
+
+func BenchmarkBloomFilterSet(b *testing.B) {
+	items := benchmarkItems()
+
+	reportBytesPerItem(b, func() {
+		for range b.N {
+			f := NewBloomFilter(benchItems, 0.01)
+
+			for _, item := range items {
+				f.Add(item)
+			}
+		}
+	})
+}
+
+func BenchmarkMapSet(b *testing.B) {
+	items := benchmarkItems()
+
+	reportBytesPerItem(b, func() {
+		for range b.N {
+			set := make(map[string]struct{})
+
+			for _, item := range items {
+				// The map keeps its own copy of the item, like a set of items read from the network would.
+				set[string([]byte(item))] = struct{}{}
+			}
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package probabilistic
+
+import (
+	"hash/maphash"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// 2. Advanced: Count-Min Sketch.
+// A Bloom filter answers whether an item was seen, a count-min sketch answers how many times: requests per client
+// for a rate limiter, or the most popular search queries, in a fixed amount of memory whatever the number of items.
+//
+// The sketch is a table of counters with d rows of w counters each, and a hash function per row. Add increments
+// one counter in every row, the one the item hashes to. Other items hash to the same counters and add their counts,
+// so every row overestimates, and never underestimates: the estimate is the minimum over the rows, the row
+// with the fewest collisions. For a stream of N items in total, an estimate exceeds the true count by more than
+// epsilon*N with probability at most delta when
+//
+//	w = e / epsilon counters per row, rounded up,
+//	d = ln(1 / delta) rows, rounded up.
+//
+// The width bounds the error, and every row is another chance to avoid a collision, so the depth bounds
+// its probability. NewCountMinSketch below swaps the two, and Estimate trusts the first row instead of taking
+// the minimum: the estimates are far off.
+
+// CountMinSketch counts occurrences of strings with overestimates, it's safe for concurrent use.
+type CountMinSketch struct {
+	mu     sync.Mutex
+	width  uint64
+	depth  uint64
+	counts []uint64
+	seed   maphash.Seed
+}
+
+// NewCountMinSketch creates a sketch whose estimates exceed true counts by more than epsilon
+// times the total count with probability at most delta.
+func NewCountMinSketch(epsilon, delta float64) *CountMinSketch {
+	width := uint64(math.Ceil(math.Log(1 / delta)))
+	depth := uint64(math.Ceil(math.E / epsilon))
+
+	return &CountMinSketch{
+		width:  width,
+		depth:  depth,
+		counts: make([]uint64, width*depth),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+// Width returns the number of counters in a row, w.
+func (s *CountMinSketch) Width() int {
+	return int(s.width)
+}
+
+// Depth returns the number of rows, d.
+func (s *CountMinSketch) Depth() int {
+	return int(s.depth)
+}
+
+// Add adds count occurrences of the item.
+func (s *CountMinSketch) Add(item string, count uint64) {
+	h1, h2 := doubleHash(s.seed, item)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for row := range s.depth {
+		s.counts[row*s.width+(h1+row*h2)%s.width] += count
+	}
+}
+
+// Estimate returns the estimated number of occurrences of the item, it's never below the true number.
+func (s *CountMinSketch) Estimate(item string) uint64 {
+	h1, _ := doubleHash(s.seed, item)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.counts[h1%s.width]
+}
+
+func TestCountMinSketchSizing(t *testing.T) {
+	tests := []struct {
+		epsilon, delta float64
+		width, depth   int
+	}{
+		{epsilon: 0.01, delta: 0.01, width: 272, depth: 5},
+		{epsilon: 0.001, delta: 0.001, width: 2719, depth: 7},
+		{epsilon: 0.1, delta: 0.05, width: 28, depth: 3},
+	}
+
+	for _, tt := range tests {
+		s := NewCountMinSketch(tt.epsilon, tt.delta)
+
+		if s.Width() != tt.width || s.Depth() != tt.depth {
+			t.Errorf("Expected %dx%d counters for epsilon %g and delta %g, got %dx%d",
+				tt.depth, tt.width, tt.epsilon, tt.delta, s.Depth(), s.Width())
+		}
+	}
+}
+
+// Real streams are skewed: a few items are very frequent, and most are rare. The test draws 100,000 occurrences
+// of 5,000 items from a Zipf distribution, counts them exactly on the side, and checks the guarantee of the sketch
+// item by item. Each item misses the bound with probability at most delta, so the share of items that miss it
+// may exceed delta only by chance, and the test allows 4 standard deviations of it.
+
+func TestCountMinSketchErrorBound(t *testing.T) {
+	const (
+		epsilon = 0.01
+		delta   = 0.01
+		items   = 5000
+		total   = 100_000
+	)
+
+	s := NewCountMinSketch(epsilon, delta)
+	counts := make(map[string]uint64, items)
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(1, 2)), 1.1, 1, items-1)
+
+	for range total {
+		item := "query-" + strconv.FormatUint(zipf.Uint64(), 10)
+
+		s.Add(item, 1)
+		counts[item]++
+	}
+
+	misses := 0
+	worst := uint64(0)
+
+	for item, count := range counts {
+		estimate := s.Estimate(item)
+
+		if estimate < count {
+			t.Fatalf("Expected the estimate of %s to be at least %d, got %d: a sketch never underestimates",
+				item, count, estimate)
+		}
+
+		if estimate-count > epsilon*total {
+			misses++
+		}
+
+		worst = max(worst, estimate-count)
+	}
+
+	share := float64(misses) / float64(len(counts))
+	bound := delta + 4*math.Sqrt(delta*(1-delta)/float64(len(counts)))
+
+	t.Logf("%d items, %d counters, the worst overestimate is %d", len(counts), s.Width()*s.Depth(), worst)
+
+	if share > bound {
+		t.Errorf("Expected at most %.2f%% of estimates to be off by more than %d, got %.2f%%",
+			bound*100, int(epsilon*total), share*100)
+	}
+}
@@ -0,0 +1,5 @@
+// Package probabilistic is the Probabilistic Data Structures workshop.
+//
+// Exercises are in the _test.go files, every exercise starts with a comment explaining the topic
+// and what should be fixed. Run them with: go run ./cmd/workshop verify probabilistic
+package probabilistic
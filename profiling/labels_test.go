@@ -0,0 +1,81 @@
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Advanced: profiler labels.
+//
+// A multi-tenant service runs the same code for every tenant, and a CPU profile shows that the code is slow,
+// but not for whom. Profiler labels are key-value pairs attached to a goroutine: samples of CPU profiles and
+// goroutine profiles carry them, and goroutines started by a labeled goroutine inherit them.
+//
+// pprof.Do runs a function with labels added to the context and to the goroutine, and restores the previous ones after it.
+// Then profiles can be filtered by label:
+//
+//	go tool pprof -tagfocus=tenant=acme cpu.pprof
+//	go tool pprof -tags cpu.pprof
+//
+// Let's make RunTenantJobs label each job with its tenant, so a goroutine profile shows which tenant a stuck job belongs to.
+
+// RunTenantJobs runs job for every tenant concurrently and waits for all of them.
+func RunTenantJobs(ctx context.Context, tenants []string, job func(ctx context.Context, tenant string)) {
+	var wg sync.WaitGroup
+
+	for _, tenant := range tenants {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			job(ctx, tenant)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestRunTenantJobs(t *testing.T) {
+	tenants := []string{"acme", "globex", "initech"}
+	started, release := make(chan string), make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		RunTenantJobs(context.Background(), tenants, func(ctx context.Context, tenant string) {
+			if label, ok := pprof.Label(ctx, "tenant"); !ok || label != tenant {
+				t.Errorf("Expected context of the job to have label tenant=%s, got %q", tenant, label)
+			}
+
+			// Goroutines started by the job are labeled too.
+			go func() {
+				started <- tenant
+				<-release
+			}()
+
+			<-release
+		})
+	}()
+
+	for range tenants {
+		<-started
+	}
+
+	var profile strings.Builder
+	pprof.Lookup("goroutine").WriteTo(&profile, 1)
+
+	close(release)
+	<-done
+
+	for _, tenant := range tenants {
+		if label := fmt.Sprintf(`"tenant":"%s"`, tenant); !strings.Contains(profile.String(), label) {
+			t.Errorf("Expected goroutine profile to have goroutines labeled with %s", label)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package profiling
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The runtime collects profiles of a running program: where CPU time goes (profile), what is allocated (heap, allocs),
+// what goroutines are doing (goroutine), and where they wait (block, mutex).
+// net/http/pprof serves them over HTTP, so a profile can be taken from a live service when something goes wrong,
+// and `go tool pprof` reads them:
+//
+//	go tool pprof http://localhost:6060/debug/pprof/heap
+//	go tool pprof -http=:8080 http://localhost:6060/debug/pprof/profile?seconds=30
+//	curl 'http://localhost:6060/debug/pprof/goroutine?debug=1'
+//
+// Importing net/http/pprof registers its handlers on http.DefaultServeMux in init.
+// That's a trap: if the public API is served with the default mux, like http.ListenAndServe(addr, nil),
+// anyone can download profiles of the service, and the CPU profile endpoint makes it busy for as long as they ask.
+// Serve the API with its own mux, and profiles on a separate one, on a port that is reachable only from inside.
+
+// 1. Enabling pprof.
+// Implement DebugMux, it serves the index of profiles at /debug/pprof/ along with named profiles like
+// /debug/pprof/heap and /debug/pprof/goroutine, and the cmdline, profile, symbol, and trace endpoints.
+// Look at the exported handlers of net/http/pprof, Index serves named profiles as well.
+
+// DebugMux returns a mux with pprof handlers, it should be served on an internal port.
+func DebugMux() *http.ServeMux {
+	return http.NewServeMux()
+}
+
+func TestDebugMux(t *testing.T) {
+	server := httptest.NewServer(DebugMux())
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/debug/pprof/", "Types of profiles available"},
+		{"/debug/pprof/goroutine?debug=1", "goroutine profile:"},
+		{"/debug/pprof/heap?debug=1", "heap profile:"},
+		{"/debug/pprof/cmdline", "profiling.test"},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), tt.expected) {
+			t.Errorf("Expected %s to respond with 200 and %q, got %d", tt.path, tt.expected, resp.StatusCode)
+		}
+	}
+}
+
+// 2. Finding the leak.
+// The notification service got slower every day and was restarted every night. Its memory graph is a saw:
+// it grows all day and drops at restarts. A leak, but where?
+//
+// A heap profile shows what holds the memory, and a goroutine profile shows what goroutines are doing.
+// When the number of goroutines only grows, the goroutine profile with debug=1 groups them by stack,
+// and the group with thousands of goroutines points to the line where they are stuck.
+// Comparing two profiles taken an hour apart shows what grows:
+//
+//	go tool pprof -base goroutine-1.pb.gz goroutine-2.pb.gz
+//
+// Run TestBrokerLeak, it prints the goroutine profile of the test, find the leak in Broker and fix it.
+// Leaked goroutines are not the only leak here, look at what is left in the maps too.
+
+// Broker delivers messages to subscribers of topics.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]map[int]chan string
+	nextID int
+}
+
+// NewBroker creates a broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{topics: make(map[string]map[int]chan string)}
+}
+
+// Subscribe calls handle for every message published to the topic, until unsubscribe is called.
+func (b *Broker) Subscribe(topic string, handle func(msg string)) (unsubscribe func()) {
+	ch := make(chan string, 16)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[int]chan string)
+	}
+
+	b.topics[topic][id] = ch
+	b.mu.Unlock()
+
+	go func() {
+		for msg := range ch {
+			handle(msg)
+		}
+	}()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.topics[topic], id)
+	}
+}
+
+// Publish sends the message to all subscribers of the topic.
+func (b *Broker) Publish(topic, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.topics[topic] {
+		ch <- msg
+	}
+}
+
+// Topics returns topics with subscribers.
+func (b *Broker) Topics() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return slices.Sorted(maps.Keys(b.topics))
+}
+
+func TestBrokerLeak(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	b := NewBroker()
+
+	var wg sync.WaitGroup
+
+	// Every request of the service subscribes to updates of an order and unsubscribes when it's done.
+	for i := range 200 {
+		topic := fmt.Sprintf("order-%d", i)
+
+		wg.Add(1)
+
+		unsubscribe := b.Subscribe(topic, func(string) { wg.Done() })
+		b.Publish(topic, "shipped")
+		wg.Wait()
+		unsubscribe()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > baseline {
+		var profile strings.Builder
+		pprof.Lookup("goroutine").WriteTo(&profile, 1)
+
+		t.Errorf("Expected the number of goroutines to return to %d after all subscribers left, got %d. Goroutine profile:\n%s",
+			baseline, n, profile.String())
+	}
+
+	if topics := b.Topics(); len(topics) != 0 {
+		t.Errorf("Expected no topics after all subscribers left, got %d", len(topics))
+	}
+}
@@ -0,0 +1,248 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: event_v2.proto
+
+package eventpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// EventV2 is the next version of Event, it's used by newer services.
+// It has the same fields as Event plus a new field that older services don't know about.
+type EventV2 struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Tags      []string               `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	Attrs     map[string]string      `protobuf:"bytes,4,rep,name=attrs,proto3" json:"attrs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*EventV2_Signup
+	//	*EventV2_Purchase
+	//	*EventV2_Logout
+	Payload       isEventV2_Payload `protobuf_oneof:"payload"`
+	Source        string            `protobuf:"bytes,8,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventV2) Reset() {
+	*x = EventV2{}
+	mi := &file_event_v2_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventV2) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventV2) ProtoMessage() {}
+
+func (x *EventV2) ProtoReflect() protoreflect.Message {
+	mi := &file_event_v2_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventV2.ProtoReflect.Descriptor instead.
+func (*EventV2) Descriptor() ([]byte, []int) {
+	return file_event_v2_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EventV2) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *EventV2) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *EventV2) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *EventV2) GetAttrs() map[string]string {
+	if x != nil {
+		return x.Attrs
+	}
+	return nil
+}
+
+func (x *EventV2) GetPayload() isEventV2_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *EventV2) GetSignup() *Signup {
+	if x != nil {
+		if x, ok := x.Payload.(*EventV2_Signup); ok {
+			return x.Signup
+		}
+	}
+	return nil
+}
+
+func (x *EventV2) GetPurchase() *Purchase {
+	if x != nil {
+		if x, ok := x.Payload.(*EventV2_Purchase); ok {
+			return x.Purchase
+		}
+	}
+	return nil
+}
+
+func (x *EventV2) GetLogout() *Logout {
+	if x != nil {
+		if x, ok := x.Payload.(*EventV2_Logout); ok {
+			return x.Logout
+		}
+	}
+	return nil
+}
+
+func (x *EventV2) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type isEventV2_Payload interface {
+	isEventV2_Payload()
+}
+
+type EventV2_Signup struct {
+	Signup *Signup `protobuf:"bytes,5,opt,name=signup,proto3,oneof"`
+}
+
+type EventV2_Purchase struct {
+	Purchase *Purchase `protobuf:"bytes,6,opt,name=purchase,proto3,oneof"`
+}
+
+type EventV2_Logout struct {
+	Logout *Logout `protobuf:"bytes,7,opt,name=logout,proto3,oneof"`
+}
+
+func (*EventV2_Signup) isEventV2_Payload() {}
+
+func (*EventV2_Purchase) isEventV2_Payload() {}
+
+func (*EventV2_Logout) isEventV2_Payload() {}
+
+var File_event_v2_proto protoreflect.FileDescriptor
+
+const file_event_v2_proto_rawDesc = "" +
+	"\n" +
+	"\x0eevent_v2.proto\x12\vworkshop.v1\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\vevent.proto\"\x8f\x03\n" +
+	"\aEventV2\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x129\n" +
+	"\n" +
+	"created_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\x125\n" +
+	"\x05attrs\x18\x04 \x03(\v2\x1f.workshop.v1.EventV2.AttrsEntryR\x05attrs\x12-\n" +
+	"\x06signup\x18\x05 \x01(\v2\x13.workshop.v1.SignupH\x00R\x06signup\x123\n" +
+	"\bpurchase\x18\x06 \x01(\v2\x15.workshop.v1.PurchaseH\x00R\bpurchase\x12-\n" +
+	"\x06logout\x18\a \x01(\v2\x13.workshop.v1.LogoutH\x00R\x06logout\x12\x16\n" +
+	"\x06source\x18\b \x01(\tR\x06source\x1a8\n" +
+	"\n" +
+	"AttrsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\t\n" +
+	"\apayloadB2Z0github.com/ksysoev/go-workshops/protobuf/eventpbb\x06proto3"
+
+var (
+	file_event_v2_proto_rawDescOnce sync.Once
+	file_event_v2_proto_rawDescData []byte
+)
+
+func file_event_v2_proto_rawDescGZIP() []byte {
+	file_event_v2_proto_rawDescOnce.Do(func() {
+		file_event_v2_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_event_v2_proto_rawDesc), len(file_event_v2_proto_rawDesc)))
+	})
+	return file_event_v2_proto_rawDescData
+}
+
+var file_event_v2_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_event_v2_proto_goTypes = []any{
+	(*EventV2)(nil),               // 0: workshop.v1.EventV2
+	nil,                           // 1: workshop.v1.EventV2.AttrsEntry
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(*Signup)(nil),                // 3: workshop.v1.Signup
+	(*Purchase)(nil),              // 4: workshop.v1.Purchase
+	(*Logout)(nil),                // 5: workshop.v1.Logout
+}
+var file_event_v2_proto_depIdxs = []int32{
+	2, // 0: workshop.v1.EventV2.created_at:type_name -> google.protobuf.Timestamp
+	1, // 1: workshop.v1.EventV2.attrs:type_name -> workshop.v1.EventV2.AttrsEntry
+	3, // 2: workshop.v1.EventV2.signup:type_name -> workshop.v1.Signup
+	4, // 3: workshop.v1.EventV2.purchase:type_name -> workshop.v1.Purchase
+	5, // 4: workshop.v1.EventV2.logout:type_name -> workshop.v1.Logout
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_event_v2_proto_init() }
+func file_event_v2_proto_init() {
+	if File_event_v2_proto != nil {
+		return
+	}
+	file_event_proto_init()
+	file_event_v2_proto_msgTypes[0].OneofWrappers = []any{
+		(*EventV2_Signup)(nil),
+		(*EventV2_Purchase)(nil),
+		(*EventV2_Logout)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_event_v2_proto_rawDesc), len(file_event_v2_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_event_v2_proto_goTypes,
+		DependencyIndexes: file_event_v2_proto_depIdxs,
+		MessageInfos:      file_event_v2_proto_msgTypes,
+	}.Build()
+	File_event_v2_proto = out.File
+	file_event_v2_proto_goTypes = nil
+	file_event_v2_proto_depIdxs = nil
+}
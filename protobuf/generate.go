@@ -0,0 +1,7 @@
+// Package protobuf is a workshop on Protocol Buffers.
+// Generated code is checked in, regenerate it after changing files in proto directory with:
+//
+//	go generate ./protobuf
+package protobuf
+
+//go:generate go run github.com/bufbuild/buf/cmd/buf@v1.73.0 generate
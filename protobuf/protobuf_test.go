@@ -0,0 +1,261 @@
+package protobuf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/protobuf/eventpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Protocol Buffers is a language neutral binary format with a schema.
+// Messages are described in .proto files, see proto directory, and Go code is generated from them into eventpb package.
+// Generated messages are not plain structs:
+// - they contain internal state, so they must be passed by pointer and never copied,
+// - they are compared with proto.Equal, not with == or reflect.DeepEqual,
+// - they are encoded with google.golang.org/protobuf/proto, not with encoding/json.
+//
+// Let's implement a round trip for Event with proto.Marshal and proto.Unmarshal.
+
+// EncodeEvent encodes the event into the protobuf wire format.
+func EncodeEvent(e *eventpb.Event) ([]byte, error) {
+	return nil, nil
+}
+
+// DecodeEvent decodes the event from the protobuf wire format.
+func DecodeEvent(data []byte) (*eventpb.Event, error) {
+	return &eventpb.Event{}, nil
+}
+
+func testEvent() *eventpb.Event {
+	return &eventpb.Event{
+		Id:        "evt-42",
+		CreatedAt: timestamppb.New(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)),
+		Tags:      []string{"checkout", "web"},
+		Attrs:     map[string]string{"country": "NL", "campaign": "spring"},
+		Payload: &eventpb.Event_Purchase{
+			Purchase: &eventpb.Purchase{OrderId: "1001", AmountCents: 2500, Currency: "EUR"},
+		},
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	data, err := EncodeEvent(testEvent())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	e, err := DecodeEvent(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !proto.Equal(testEvent(), e) {
+		t.Errorf("Expected event after round trip to be %v, got %v", testEvent(), e)
+	}
+}
+
+// The wire format of a message is not unique. Map entries, for example, are written in the map iteration order,
+// which is random in Go. That's fine for sending messages, but not for using bytes as a cache key or a checksum.
+// Fingerprint should return the same value for equal events.
+// Hint: take a look at proto.MarshalOptions.
+
+// Fingerprint returns a hex encoded SHA-256 hash of the event.
+func Fingerprint(e *eventpb.Event) (string, error) {
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func TestFingerprintDeterministic(t *testing.T) {
+	e := testEvent()
+	for i := range 16 {
+		e.Attrs[fmt.Sprintf("key-%d", i)] = fmt.Sprintf("value-%d", i)
+	}
+
+	expected, err := Fingerprint(e)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for range 20 {
+		got, err := Fingerprint(e)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if got != expected {
+			t.Fatalf("Expected fingerprint of the same event to be %s, got %s", expected, got)
+		}
+	}
+}
+
+// Schemas evolve, and services are not updated at the same time.
+// EventV2 is the next version of Event with a new field Source, older services know nothing about it.
+// A field unknown to the decoder is kept in the message as raw bytes and written back when the message is encoded again,
+// so a service in the middle of a pipeline doesn't lose data it doesn't understand.
+// But it's easy to throw them away: DiscardUnknown option, conversion to JSON with protojson, or copying fields into a new message.
+//
+// AddTag is used by a service that still knows only Event. Fix it, so Source survives the trip.
+
+// AddTag decodes the event, adds the tag, and encodes it back.
+func AddTag(data []byte, tag string) ([]byte, error) {
+	var e eventpb.Event
+	if err := (proto.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+
+	e.Tags = append(e.Tags, tag)
+
+	return proto.Marshal(&e)
+}
+
+func TestUnknownFieldsPreserved(t *testing.T) {
+	in := &eventpb.EventV2{
+		Id:     "evt-43",
+		Tags:   []string{"web"},
+		Source: "mobile-app",
+		Payload: &eventpb.EventV2_Signup{
+			Signup: &eventpb.Signup{Email: "ann@example.com"},
+		},
+	}
+
+	data, err := proto.Marshal(in)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err = AddTag(data, "enriched")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var out eventpb.EventV2
+	if err := proto.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(out.GetTags()) != 2 || out.GetTags()[1] != "enriched" {
+		t.Errorf("Expected tags to be [web enriched], got %v", out.GetTags())
+	}
+
+	if out.GetSource() != "mobile-app" {
+		t.Errorf("Expected source to be preserved as %q, got %q", "mobile-app", out.GetSource())
+	}
+
+	if out.GetSignup().GetEmail() != "ann@example.com" {
+		t.Errorf("Expected signup email to be %q, got %q", "ann@example.com", out.GetSignup().GetEmail())
+	}
+}
+
+// A oneof field holds at most one of its fields. In Go it's an interface field with a wrapper type per case:
+// Event.Payload is one of *eventpb.Event_Signup, *eventpb.Event_Purchase, *eventpb.Event_Logout, or nil when it's not set.
+// Getters like GetSignup return nil unless that case is set, and they are safe to call on nil messages.
+// Watch out for Logout, it has no fields, so the only way to know it's set is the type of the payload.
+//
+// Describe should handle every case, including an event without payload.
+
+// Describe returns a human readable description of the event.
+func Describe(e *eventpb.Event) string {
+	switch p := e.GetPayload().(type) {
+	case *eventpb.Event_Signup:
+		return fmt.Sprintf("signup: %s via %s", p.Signup.GetEmail(), p.Signup.GetReferrer())
+	case *eventpb.Event_Purchase:
+		return fmt.Sprintf("purchase: order %s, %d.%02d %s",
+			p.Purchase.GetOrderId(), p.Purchase.GetAmountCents()/100, p.Purchase.GetAmountCents()%100, p.Purchase.GetCurrency())
+	}
+
+	return ""
+}
+
+func ExampleDescribe() {
+	events := []*eventpb.Event{
+		{Payload: &eventpb.Event_Signup{Signup: &eventpb.Signup{Email: "ann@example.com", Referrer: "newsletter"}}},
+		testEvent(),
+		{Payload: &eventpb.Event_Logout{Logout: &eventpb.Logout{}}},
+		{Id: "evt-44"},
+		nil,
+	}
+
+	for _, e := range events {
+		fmt.Println(Describe(e))
+	}
+	// Output:
+	// signup: ann@example.com via newsletter
+	// purchase: order 1001, 25.00 EUR
+	// logout
+	// empty event
+	// empty event
+}
+
+// Let's compare the size and speed of the protobuf wire format and JSON for the same event:
+//
+//	go test -run '^$' -bench Event -benchmem ./protobuf
+
+func BenchmarkEventProto(b *testing.B) {
+	e := testEvent()
+
+	var size int
+
+	for range b.N {
+		data, err := EncodeEvent(e)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := DecodeEvent(data); err != nil {
+			b.Fatal(err)
+		}
+
+		size = len(data)
+	}
+
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkEventJSON(b *testing.B) {
+	e := testEvent()
+
+	var size int
+
+	for range b.N {
+		data, err := protojson.Marshal(e)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		var out eventpb.Event
+		if err := protojson.Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+
+		size = len(data)
+	}
+
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func TestJSONIsReadable(t *testing.T) {
+	data, err := protojson.Marshal(testEvent())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// protojson uses camelCase field names from the schema and encodes int64 as strings.
+	for _, s := range []string{`"createdAt":"2024-05-01T10:00:00Z"`, `"amountCents":"2500"`} {
+		if !bytes.Contains(bytes.ReplaceAll(data, []byte(" "), nil), []byte(s)) {
+			t.Errorf("Expected JSON to contain %s, got %s", s, data)
+		}
+	}
+}
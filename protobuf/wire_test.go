@@ -0,0 +1,78 @@
+package protobuf
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Advanced: the wire format.
+//
+// An encoded message is a sequence of fields, every field is a tag followed by a value.
+// The tag is a varint of the field number and the wire type, the wire type tells how to find the end of the value:
+// varints, fixed 32 and 64 bit numbers, and length-delimited bytes for strings, nested messages, and packed lists.
+// So a message can be scanned without its schema, that's how unknown fields are preserved.
+//
+// A router that needs only the id of an event doesn't have to unmarshal the whole message with all its maps
+// and nested messages. Let's implement EventID with google.golang.org/protobuf/encoding/protowire:
+// ConsumeTag reads a tag, ConsumeBytes reads a length-delimited value, and ConsumeFieldValue skips any value.
+// Fields may come in any order, and when a field is repeated in the stream, the last value wins.
+
+// EventID returns the id field of the encoded Event without unmarshaling it.
+func EventID(data []byte) (string, error) {
+	return "", nil
+}
+
+func TestEventID(t *testing.T) {
+	data, err := proto.Marshal(testEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := EventID(data); err != nil || id != "evt-42" {
+		t.Errorf("Expected id evt-42, got %q and error %v", id, err)
+	}
+
+	// The id comes after other fields and it's repeated, the last value wins.
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "old")
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, "tag")
+	b = protowire.AppendTag(b, 99, protowire.VarintType)
+	b = protowire.AppendVarint(b, 7)
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "evt-43")
+
+	if id, err := EventID(b); err != nil || id != "evt-43" {
+		t.Errorf("Expected id evt-43, got %q and error %v", id, err)
+	}
+
+	if _, err := EventID(data[:len(data)-1]); err == nil {
+		t.Error("Expected error for truncated message")
+	}
+}
+
+func BenchmarkEventID(b *testing.B) {
+	data, err := proto.Marshal(testEvent())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("protowire", func(b *testing.B) {
+		for range b.N {
+			if _, err := EventID(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("unmarshal", func(b *testing.B) {
+		for range b.N {
+			if _, err := DecodeEvent(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package redisbasics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 1. Caching.
+// GetUser of the user service goes to the database on every call, and the profile page calls it a lot.
+// Cache-aside is the common pattern: read the key from Redis, on a miss load the value from the database
+// and store it with a TTL. The TTL bounds how stale the cache can get when the user is updated elsewhere.
+//
+// Implement CachedUsers.GetUser with a key per user, like user:42, and values encoded as JSON.
+// The cache is an optimization, not a dependency: when Redis is down, GetUser must still work
+// by going to the database. Users that don't exist are not cached.
+
+// User is a user of the service.
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ErrUserNotFound is returned for users that don't exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// UserStore loads users from the database.
+type UserStore interface {
+	GetUser(ctx context.Context, id int) (User, error)
+}
+
+// CachedUsers caches users of the store in Redis.
+type CachedUsers struct {
+	rdb   *redis.Client
+	store UserStore
+	ttl   time.Duration
+}
+
+// NewCachedUsers creates a cache of users that keeps them for ttl.
+func NewCachedUsers(rdb *redis.Client, store UserStore, ttl time.Duration) *CachedUsers {
+	return &CachedUsers{rdb: rdb, store: store, ttl: ttl}
+}
+
+// GetUser returns the user from the cache, or from the store on a miss.
+func (c *CachedUsers) GetUser(ctx context.Context, id int) (User, error) {
+	return c.store.GetUser(ctx, id)
+}
+
+// countingStore is a database of users that counts queries.
+type countingStore struct {
+	mu      sync.Mutex
+	users   map[int]User
+	queries int
+}
+
+func (s *countingStore) GetUser(_ context.Context, id int) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries++
+
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+func (s *countingStore) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queries
+}
+
+func TestCachedGetUser(t *testing.T) {
+	ctx := context.Background()
+	rdb, mr := newRedis(t)
+	store := &countingStore{users: map[int]User{42: {ID: 42, Name: "Gopher"}}}
+	users := NewCachedUsers(rdb, store, time.Minute)
+
+	for range 3 {
+		u, err := users.GetUser(ctx, 42)
+		if err != nil || u != (User{ID: 42, Name: "Gopher"}) {
+			t.Fatalf("Expected user 42, got %+v, %v", u, err)
+		}
+	}
+
+	if store.Queries() != 1 {
+		t.Errorf("Expected 1 query to the database for 3 calls, got %d", store.Queries())
+	}
+
+	if ttl := mr.TTL("user:42"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected user:42 to expire in a minute, got TTL %v", ttl)
+	}
+
+	mr.FastForward(time.Minute + time.Second)
+
+	if _, err := users.GetUser(ctx, 42); err != nil || store.Queries() != 2 {
+		t.Errorf("Expected the database to be queried again after the TTL, got %d queries, %v", store.Queries(), err)
+	}
+
+	if _, err := users.GetUser(ctx, 7); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound for a missing user, got %v", err)
+	}
+
+	if mr.Exists("user:7") {
+		t.Error("Expected a missing user not to be cached")
+	}
+}
+
+func TestCacheUnavailable(t *testing.T) {
+	rdb, mr := newRedis(t)
+	store := &countingStore{users: map[int]User{42: {ID: 42, Name: "Gopher"}}}
+	users := NewCachedUsers(rdb, store, time.Minute)
+
+	mr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if u, err := users.GetUser(ctx, 42); err != nil || u.Name != "Gopher" {
+		t.Errorf("Expected the user from the database when Redis is down, got %+v, %v", u, err)
+	}
+}
@@ -0,0 +1,149 @@
+package redisbasics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 3. Advanced: fan-out with pub/sub.
+// A chat service runs on several instances, and every instance has its own WebSocket connections.
+// A message sent to one instance has to reach users connected to all of them. Redis pub/sub delivers
+// a published message to every connection subscribed to the channel, at most once, without storing it:
+// a subscriber that isn't connected at that moment misses it. Streams are the alternative when that's not acceptable.
+//
+// An instance shouldn't open a Redis subscription per user, one per instance is enough: Hub subscribes once
+// and fans messages out to local subscribers in memory. Implement it:
+// - NewHub subscribes to the channel and returns after Redis confirmed the subscription,
+//   messages published after that must not be lost,
+// - every message is delivered to every local subscriber in order, through a buffered channel,
+// - a slow subscriber must not block the others: when its buffer is full, its messages are dropped,
+// - leave closes the channel of the subscriber, and Close unsubscribes from Redis and closes all channels.
+
+// subscriberBuffer is the number of messages buffered for a local subscriber.
+const subscriberBuffer = 16
+
+// Hub fans out messages of a Redis channel to local subscribers.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan string]struct{}
+}
+
+// NewHub subscribes to the Redis channel.
+func NewHub(ctx context.Context, rdb *redis.Client, channel string) (*Hub, error) {
+	return &Hub{subs: make(map[chan string]struct{})}, nil
+}
+
+// Join adds a local subscriber, leave removes it and closes its channel.
+func (h *Hub) Join() (messages <-chan string, leave func()) {
+	ch := make(chan string, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {}
+}
+
+// Close unsubscribes from Redis and closes channels of all subscribers.
+func (h *Hub) Close() error {
+	return nil
+}
+
+// receive reads n messages from the channel, or fails the test when they don't arrive in time.
+func receive(t *testing.T, messages <-chan string, n int) []string {
+	t.Helper()
+
+	var got []string
+
+	timeout := time.After(2 * time.Second)
+
+	for len(got) < n {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				t.Fatalf("Expected %d messages, the channel was closed after %v", n, got)
+			}
+
+			got = append(got, msg)
+		case <-timeout:
+			t.Fatalf("Expected %d messages, got %d: %v", n, len(got), got)
+		}
+	}
+
+	return got
+}
+
+func TestHub(t *testing.T) {
+	ctx := context.Background()
+	rdb, mr := newRedis(t)
+
+	// Two instances of the service, each with its own hub and users.
+	hubs := make([]*Hub, 2)
+	for i := range hubs {
+		hub, err := NewHub(ctx, rdb, "chat")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		hubs[i] = hub
+		t.Cleanup(func() { hub.Close() })
+	}
+
+	if n := mr.PubSubNumSub("chat")["chat"]; n != len(hubs) {
+		t.Fatalf("Expected a subscription per hub, got %d", n)
+	}
+
+	alice, _ := hubs[0].Join()
+	bob, _ := hubs[1].Join()
+	_, _ = hubs[1].Join() // Slow: never reads its messages.
+	carol, carolLeaves := hubs[1].Join()
+
+	// Fast subscribers read every batch before the next one is published, the slow one fills its buffer.
+	const batches, batch = 10, 10
+
+	for b := range batches {
+		for i := range batch {
+			if err := rdb.Publish(ctx, "chat", fmt.Sprintf("message %d", b*batch+i)).Err(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for name, messages := range map[string]<-chan string{"alice": alice, "bob": bob, "carol": carol} {
+			for i, msg := range receive(t, messages, batch) {
+				if expected := fmt.Sprintf("message %d", b*batch+i); msg != expected {
+					t.Fatalf("Expected %s to receive %q, got %q", name, expected, msg)
+				}
+			}
+		}
+	}
+
+	carolLeaves()
+
+	if _, ok := <-carol; ok {
+		t.Error("Expected the channel to be closed when the subscriber leaves")
+	}
+
+	for _, hub := range hubs {
+		if err := hub.Close(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	if _, ok := <-alice; ok {
+		t.Error("Expected channels of subscribers to be closed with the hub")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for mr.PubSubNumSub("chat")["chat"] > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := mr.PubSubNumSub("chat")["chat"]; n != 0 {
+		t.Errorf("Expected hubs to unsubscribe when closed, got %d subscriptions", n)
+	}
+}
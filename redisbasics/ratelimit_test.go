@@ -0,0 +1,145 @@
+package redisbasics
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 2. A rate limiter.
+// A token bucket holds up to burst tokens and refills at rate tokens per second, every request takes a token,
+// and a request without a token is rejected. A limiter in memory works for one instance of a service,
+// with ten instances behind a load balancer the bucket has to be shared, and Redis is the usual place for it.
+//
+// Reading the bucket, refilling it, and writing it back from Go is a race between instances:
+// two of them read the same last token and both take it. MULTI/EXEC transactions can't branch on a value they read,
+// a Lua script can: Redis runs a script atomically, no other command runs in between.
+//
+// Write tokenBucket, a script that keeps the bucket in a hash with the number of tokens and the time of the last refill:
+// - KEYS[1] is the key of the bucket, ARGV is the rate, the burst, and the current time in milliseconds,
+// - a bucket that doesn't exist is full,
+// - it refills the bucket for the time passed, up to burst, takes a token if there is one, and returns 1 or 0,
+// - it sets an expiration on the key, so buckets of clients that went away don't stay in Redis forever.
+// The time comes from the caller, so all instances must have synchronized clocks; the TIME command is the alternative.
+
+// tokenBucket is the Lua script of Allow, it returns 1 when a token was taken.
+var tokenBucket = redis.NewScript(`
+return 1
+`)
+
+// RateLimiter limits requests per key with token buckets shared in Redis.
+type RateLimiter struct {
+	rdb   *redis.Client
+	rate  float64
+	burst int
+	now   func() time.Time
+}
+
+// NewRateLimiter creates a limiter that allows burst requests at once and rate requests per second on average.
+func NewRateLimiter(rdb *redis.Client, rate float64, burst int) *RateLimiter {
+	return &RateLimiter{rdb: rdb, rate: rate, burst: burst, now: time.Now}
+}
+
+// Allow takes a token from the bucket of the key, and reports whether there was one.
+func (l *RateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	res, err := tokenBucket.Run(ctx, l.rdb, []string{"ratelimit:" + key}, l.rate, l.burst, l.now().UnixMilli()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return res == 1, nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	rdb, mr := newRedis(t)
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := NewRateLimiter(rdb, 2, 5)
+	limiter.now = func() time.Time { return now }
+
+	allowed := func(key string, n int) int {
+		count := 0
+
+		for range n {
+			ok, err := limiter.Allow(ctx, key)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if ok {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	if got := allowed("alice", 10); got != 5 {
+		t.Errorf("Expected a burst of 5 requests to be allowed, got %d", got)
+	}
+
+	if got := allowed("bob", 3); got != 3 {
+		t.Errorf("Expected buckets of different keys to be independent, got %d of 3 requests allowed", got)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+
+	if got := allowed("alice", 10); got != 3 {
+		t.Errorf("Expected 3 requests to be allowed after 1.5s at 2 per second, got %d", got)
+	}
+
+	now = now.Add(time.Hour)
+
+	if got := allowed("alice", 10); got != 5 {
+		t.Errorf("Expected the bucket to refill up to the burst of 5, got %d", got)
+	}
+
+	if ttl := mr.TTL("ratelimit:alice"); ttl <= 0 {
+		t.Errorf("Expected the bucket to expire, got TTL %v", ttl)
+	}
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	ctx := context.Background()
+	rdb, _ := newRedis(t)
+
+	// Instances of the service share Redis, every one has its own limiter.
+	limiters := make([]*RateLimiter, 4)
+	for i := range limiters {
+		limiters[i] = NewRateLimiter(rdb, 1, 10)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+
+	for i := range 40 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ok, err := limiters[i%len(limiters)].Allow(ctx, "carol")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// A second may pass while the requests run, which refills one token.
+	if got := allowed.Load(); got < 10 || got > 11 {
+		t.Errorf("Expected 10 of 40 concurrent requests to be allowed, got %d", got)
+	}
+}
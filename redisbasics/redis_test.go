@@ -0,0 +1,33 @@
+package redisbasics
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is an in-memory data structure server: strings, hashes, lists, sets, sorted sets, and streams,
+// with expiration of keys, atomic scripts, and pub/sub. Services use it for caches, rate limits, locks,
+// sessions, and fan-out of events between instances.
+//
+// github.com/redis/go-redis/v9 is the client. Every command is a method that takes a context and returns
+// a command value: rdb.Get(ctx, key).Result() returns the value and an error, and redis.Nil is the error
+// for a key that doesn't exist, it's not a failure.
+//
+// Tests run against miniredis, a Redis server implemented in Go that runs inside the test process:
+// no Docker, no network, and time can be moved forward with FastForward to expire keys.
+// It supports most commands including Lua scripts and pub/sub, but for behavior under load
+// and exact compatibility, integration tests against a real Redis are still needed.
+
+// newRedis starts a miniredis server for the test and returns a client connected to it.
+func newRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return rdb, mr
+}
@@ -0,0 +1,65 @@
+package regexps
+
+import (
+	"slices"
+	"testing"
+)
+
+// Advanced: a lexer with a single regexp.
+//
+// A lexer splits source text into tokens. Instead of a pattern per token kind, tried one by one,
+// all kinds are combined into one alternation of named groups, anchored at the current position:
+//
+//	\A(?:(?P<space>\s+)|(?P<number>\d+(?:\.\d+)?)|(?P<ident>[A-Za-z_]\w*)|...)
+//
+// One FindStringSubmatchIndex call finds the next token, and the name of the matched group is its kind.
+// The order of alternatives matters: RE2 prefers the leftmost alternative, like Perl, so put ">=" before ">".
+//
+// Let's implement Tokenize for filter expressions like `price >= 10.5 and name != "gopher"`.
+// Kinds are number, string, ident, op for comparisons (== != <= >= < >), lparen, and rparen.
+// Whitespace is skipped. Positions are byte offsets, and an unexpected character is reported with its position.
+
+// Token is a lexical token of a filter expression.
+type Token struct {
+	Kind  string
+	Value string
+	Pos   int
+}
+
+// Tokenize splits the filter expression into tokens.
+func Tokenize(src string) ([]Token, error) {
+	return nil, nil
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize(`(price >= 10.5 and name != "go pher") or qty<3`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Token{
+		{"lparen", "(", 0},
+		{"ident", "price", 1},
+		{"op", ">=", 7},
+		{"number", "10.5", 10},
+		{"ident", "and", 15},
+		{"ident", "name", 19},
+		{"op", "!=", 24},
+		{"string", `"go pher"`, 27},
+		{"rparen", ")", 36},
+		{"ident", "or", 38},
+		{"ident", "qty", 41},
+		{"op", "<", 44},
+		{"number", "3", 45},
+	}
+
+	if !slices.Equal(tokens, expected) {
+		t.Errorf("Expected tokens:\n%v\ngot:\n%v", expected, tokens)
+	}
+
+	if _, err := Tokenize("price = 10"); err == nil {
+		t.Error("Expected error for unexpected character")
+	} else {
+		t.Logf("Error for unexpected character: %v", err)
+	}
+}
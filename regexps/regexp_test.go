@@ -0,0 +1,285 @@
+package regexps
+
+import (
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Package regexp implements RE2 syntax, the same as in most languages, with one big difference in the engine.
+// Perl, Python, Java, and JavaScript use backtracking, and some patterns take exponential time on some inputs:
+// `^(a+)+$` checks 2^n ways to split a string of n a's before it gives up on "aaaa...!".
+// That's catastrophic backtracking, one request with a crafted input can take down a service (ReDoS).
+//
+// RE2 guarantees time linear in the size of the input for every pattern. The price is that features
+// that need backtracking are not supported: backreferences like \1, and lookarounds like (?=...) and (?!...).
+//
+// Patterns are compiled once and reused, a *regexp.Regexp is safe for concurrent use.
+// regexp.MustCompile panics if the pattern is invalid, it's meant for package level variables:
+// a typo in a pattern crashes the program at init, before main, and on the first `go test`.
+// That's one of the valid uses of panic from the errorhandling workshop, a developer mistake that can't be handled.
+
+// 1. Named capture groups.
+// ParseAccessLog uses positional groups, and when somebody added a group for the time, every index after it shifted.
+// Give the groups names: ip, user, method, path, status, and size, with the (?P<name>...) syntax,
+// and look them up by name with SubexpIndex, so adding a group doesn't break the code again.
+
+// LogEntry is a parsed line of an access log, Size is 0 when the log has "-" for it.
+type LogEntry struct {
+	IP     string
+	User   string
+	Method string
+	Path   string
+	Status int
+	Size   int
+}
+
+var accessLogPattern = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "(\S+) (\S+) [^"]*" (\d{3}) (\d+|-)$`)
+
+// ParseAccessLog parses a line in the common log format.
+func ParseAccessLog(line string) (LogEntry, bool) {
+	m := accessLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return LogEntry{}, false
+	}
+
+	status, _ := strconv.Atoi(m[5])
+	size, _ := strconv.Atoi(m[6])
+
+	return LogEntry{IP: m[1], User: m[2], Method: m[3], Path: m[4], Status: status, Size: size}, true
+}
+
+func TestParseAccessLog(t *testing.T) {
+	for _, name := range []string{"ip", "user", "method", "path", "status", "size"} {
+		if accessLogPattern.SubexpIndex(name) < 0 {
+			t.Errorf("Expected accessLogPattern to have a group named %q", name)
+		}
+	}
+
+	tests := []struct {
+		line     string
+		expected LogEntry
+		ok       bool
+	}{
+		{
+			line:     `127.0.0.1 - alice [01/May/2024:10:00:00 +0000] "GET /orders/42 HTTP/1.1" 200 512`,
+			expected: LogEntry{IP: "127.0.0.1", User: "alice", Method: "GET", Path: "/orders/42", Status: 200, Size: 512},
+			ok:       true,
+		},
+		{
+			line:     `10.1.2.3 - - [01/May/2024:10:00:01 +0000] "DELETE /orders/42 HTTP/2.0" 304 -`,
+			expected: LogEntry{IP: "10.1.2.3", User: "-", Method: "DELETE", Path: "/orders/42", Status: 304},
+			ok:       true,
+		},
+		{line: `127.0.0.1 - alice "GET /orders/42 HTTP/1.1" 200 512`},
+		{line: ""},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseAccessLog(tt.line)
+		if ok != tt.ok || got != tt.expected {
+			t.Errorf("Expected %q to be parsed as %+v, %t, got %+v, %t", tt.line, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+// 2. Finding all matches.
+// Find methods come in 16 variants named by the pattern Find(All)?(String)?(Submatch)?(Index)?:
+// All returns every match instead of the first one, String works on strings instead of []byte,
+// Submatch returns the groups of a match, and Index returns positions instead of text.
+//
+// ParseLabels returns only the first label. Fix it to return all of them.
+
+var labelPattern = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|(\S+))`)
+
+// ParseLabels parses labels like `env=prod team="core platform"` into a map.
+func ParseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+
+	if m := labelPattern.FindStringSubmatch(s); m != nil {
+		labels[m[1]] = m[2] + m[3]
+	}
+
+	return labels
+}
+
+func TestParseLabels(t *testing.T) {
+	got := ParseLabels(`env=prod team="core platform" region=eu-west-1 note=""`)
+
+	expected := map[string]string{"env": "prod", "team": "core platform", "region": "eu-west-1", "note": ""}
+	if !maps.Equal(got, expected) {
+		t.Errorf("Expected labels %q, got %q", expected, got)
+	}
+
+	if got := ParseLabels("no labels here"); len(got) != 0 {
+		t.Errorf("Expected no labels, got %q", got)
+	}
+}
+
+// 3. Replacing with a function.
+// ReplaceAllString expands $1 and ${name} in the replacement with the groups of the match.
+// Watch out for $1x: it's the group named "1x", not group 1 followed by x, write ${1}x instead.
+// When the replacement has to be computed, ReplaceAllStringFunc calls a function for every match.
+//
+// MaskEmails should keep the first letter of every address and its domain: alice@example.com -> a***@example.com.
+
+var emailPattern = regexp.MustCompile(`([\w.+-]+)@([\w-]+(?:\.[\w-]+)+)`)
+
+// MaskEmails hides email addresses in the text, so it can be logged.
+func MaskEmails(text string) string {
+	return emailPattern.ReplaceAllString(text, "***@$2")
+}
+
+func TestMaskEmails(t *testing.T) {
+	got := MaskEmails("Contact alice.smith@example.com or b@corp.example.io, not support@")
+
+	expected := "Contact a***@example.com or b***@corp.example.io, not support@"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+// 4. Patterns from users.
+// Patterns that come from users or configuration are data, not code, and invalid data must be reported as an error.
+// A panic here takes down a service because of a typo in a search box.
+// Patterns written for other engines are a common source of errors, RE2 rejects lookarounds and backreferences.
+//
+// Fix NewFilter to return an error for invalid patterns.
+
+// Filter matches lines against any of its patterns.
+type Filter struct {
+	patterns []*regexp.Regexp
+}
+
+// NewFilter compiles the patterns into a filter.
+func NewFilter(patterns ...string) (*Filter, error) {
+	f := &Filter{}
+
+	for _, pattern := range patterns {
+		f.patterns = append(f.patterns, regexp.MustCompile(pattern))
+	}
+
+	return f, nil
+}
+
+// Match reports whether the line matches any of the patterns.
+func (f *Filter) Match(line string) bool {
+	for _, re := range f.patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{"[unclosed", `(?=.*\d)password`, `(\w+) \1`} {
+		t.Run(pattern, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Expected error for invalid pattern, got panic: %v", r)
+				}
+			}()
+
+			if _, err := NewFilter("timeout", pattern); err == nil {
+				t.Error("Expected error for invalid pattern, got nil")
+			}
+		})
+	}
+}
+
+func TestFilterLinearTime(t *testing.T) {
+	f, err := NewFilter("timeout", `^(a+)+$`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !f.Match("read: connection timeout") {
+		t.Error("Expected line with timeout to match")
+	}
+
+	// A backtracking engine wouldn't finish this before the heat death of the universe.
+	line := strings.Repeat("a", 100_000) + "!"
+	start := time.Now()
+
+	if f.Match(line) {
+		t.Error("Expected line ending with ! not to match")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected match in linear time, took %s", elapsed)
+	}
+}
+
+// 5. Regexp on a hot path.
+// Linear time doesn't mean fast, a regexp is a small virtual machine and runs much slower than plain string functions.
+// IsStaticAsset runs for every request of a web server, and the pattern is simple enough to do without a regexp.
+// Rewrite it with the strings package, it must give the same results as the pattern.
+// Compare with: go test -bench=IsStaticAsset ./regexps
+// Or record a baseline with workshop bench regexps before the rewrite, and run it again after: it must be 3 times faster.
+
+var staticAssetPattern = regexp.MustCompile(`^/static/.+\.(css|js|png|svg)$`)
+
+// IsStaticAsset reports whether the request path is a static file served from /static/.
+func IsStaticAsset(path string) bool {
+	return staticAssetPattern.MatchString(path)
+}
+
+var staticAssetPaths = []string{
+	"/static/app.css",
+	"/static/js/vendor/app.min.js",
+	"/static/img/logo.png",
+	"/static/icons/menu.svg",
+	"/static/.css",
+	"/static/",
+	"/static/app.jsx",
+	"/static/app.js?v=2",
+	"/static/logo.PNG",
+	"/api/static/app.css",
+	"/orders/42",
+	"/",
+	"",
+}
+
+func TestIsStaticAsset(t *testing.T) {
+	for _, path := range staticAssetPaths {
+		if got, expected := IsStaticAsset(path), staticAssetPattern.MatchString(path); got != expected {
+			t.Errorf("Expected IsStaticAsset(%q) to be %t like the pattern, got %t", path, expected, got)
+		}
+	}
+}
+
+func TestIsStaticAssetFaster(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	pattern := testing.Benchmark(BenchmarkIsStaticAssetRegexp)
+	got := testing.Benchmark(BenchmarkIsStaticAsset)
+
+	// Both run the same paths, so time per operation is comparable.
+	if got.NsPerOp()*3 > pattern.NsPerOp() {
+		t.Errorf("Expected IsStaticAsset to be at least 3 times faster than the regexp, got %d ns/op vs %d ns/op",
+			got.NsPerOp(), pattern.NsPerOp())
+	}
+}
+
+func BenchmarkIsStaticAsset(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, path := range staticAssetPaths {
+			IsStaticAsset(path)
+		}
+	}
+}
+
+func BenchmarkIsStaticAssetRegexp(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, path := range staticAssetPaths {
+			staticAssetPattern.MatchString(path)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+// Advanced: false sharing.
+//
+// CPU caches don't work with bytes, they work with cache lines of 64 bytes on most CPUs (128 on Apple M chips).
+// When a core writes to a line, the copies of the whole line in the caches of other cores are invalidated,
+// and the next access on those cores waits for the line to come back.
+//
+// Counters of different workers don't share data, but if they sit next to each other in memory, they share a line,
+// and every increment on one core invalidates the counters of the others. The line bounces between cores,
+// and adding cores makes the program slower. That's false sharing.
+// The fix is padding: make every counter occupy a cache line of its own, like cpu.CacheLinePad from golang.org/x/sys/cpu.
+//
+// Add padding to counter, so counters of different workers never share a cache line, and compare:
+//
+//	go test -run '^$' -bench Stats -cpu 1,4,8 ./scheduler
+//
+// With padding, the time per operation doesn't grow with the number of CPUs, without it, it does.
+
+const cacheLineSize = 64
+
+type counter struct {
+	n atomic.Int64
+}
+
+// Stats counts events handled by workers, every worker increments only its own counter.
+type Stats struct {
+	counters []counter
+}
+
+// NewStats creates counters for the workers.
+func NewStats(workers int) *Stats {
+	return &Stats{counters: make([]counter, workers)}
+}
+
+// Inc counts an event handled by the worker.
+func (s *Stats) Inc(worker int) {
+	s.counters[worker].n.Add(1)
+}
+
+// Total returns the number of events handled by all workers.
+func (s *Stats) Total() int64 {
+	var total int64
+	for i := range s.counters {
+		total += s.counters[i].n.Load()
+	}
+
+	return total
+}
+
+func TestStats(t *testing.T) {
+	const workers, events = 8, 10_000
+
+	s := NewStats(workers)
+	countEvents(s, workers, events)
+
+	if got := s.Total(); got != workers*events {
+		t.Errorf("Expected %d events, got %d", workers*events, got)
+	}
+
+	if size := unsafe.Sizeof(counter{}); size%cacheLineSize != 0 {
+		t.Errorf("Expected a counter to take whole cache lines of %d bytes, got %d bytes", cacheLineSize, size)
+	}
+}
+
+// countEvents runs workers goroutines, each counting events in its own counter.
+func countEvents(s interface{ Inc(int) }, workers, events int) {
+	var wg sync.WaitGroup
+
+	for w := range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range events {
+				s.Inc(w)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// packedStats is the layout of Stats without padding, a baseline for the benchmark.
+type packedStats struct {
+	counters []atomic.Int64
+}
+
+func (s *packedStats) Inc(worker int) {
+	s.counters[worker].Add(1)
+}
+
+func BenchmarkStats(b *testing.B) {
+	workers := max(2, runtime.GOMAXPROCS(0))
+	s := NewStats(workers)
+
+	b.ResetTimer()
+	countEvents(s, workers, b.N)
+}
+
+func BenchmarkStatsPacked(b *testing.B) {
+	workers := max(2, runtime.GOMAXPROCS(0))
+	s := &packedStats{counters: make([]atomic.Int64, workers)}
+
+	b.ResetTimer()
+	countEvents(s, workers, b.N)
+}
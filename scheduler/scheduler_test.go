@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// The Go scheduler multiplexes goroutines onto OS threads. It's known as the GMP model:
+// - G is a goroutine: a stack and a state, a few kilobytes,
+// - M is an OS thread (machine) that runs goroutines,
+// - P is a processor: the right to run Go code, with a local run queue of goroutines.
+// There are exactly GOMAXPROCS Ps, so at most GOMAXPROCS goroutines run Go code at the same time.
+// An M needs a P to run goroutines. When a P's run queue is empty, it steals half of the queue of another P.
+//
+// GOMAXPROCS defaults to the number of CPUs. It can be set with the environment variable,
+// or changed at runtime with runtime.GOMAXPROCS(n), which returns the previous value.
+// In containers with a CPU limit it's worth setting it to the limit, the runtime sees all CPUs of the host.
+//
+// The scheduler is preemptive since Go 1.14: a goroutine that runs for more than 10ms is interrupted with a signal,
+// so a tight loop can't block the other goroutines of its P forever. But it can make them wait for 10ms at a time.
+//
+// The scheduler can be watched at work with a summary printed every 100ms, or with the execution tracer:
+//
+//	GODEBUG=schedtrace=100 go test -run TestSplitScaling ./scheduler
+//	go test -run TestProcessAll -trace trace.out ./scheduler && go tool trace trace.out
+
+// setGOMAXPROCS changes GOMAXPROCS for the test and restores it when the test is done.
+func setGOMAXPROCS(t testing.TB, n int) {
+	prev := runtime.GOMAXPROCS(n)
+	t.Cleanup(func() { runtime.GOMAXPROCS(prev) })
+}
+
+// 1. Scaling CPU-bound work.
+// CPU-bound work scales with the number of Ps, up to the number of cores, and only if it's split into
+// independent parts. Implement Split: divide [0, n) into workers contiguous parts, run work on every part
+// in its own goroutine, and sum the results.
+// TestSplitScaling measures it with GOMAXPROCS from 1 to the number of CPUs, see the numbers with -v.
+
+// Split runs work on workers parts of the range [0, n) concurrently and returns the sum of their results.
+func Split(n, workers int, work func(lo, hi int) int) int {
+	return work(0, n)
+}
+
+// countPrimes counts primes in [lo, hi) by trial division, a CPU-bound workload.
+func countPrimes(lo, hi int) int {
+	count := 0
+
+	for n := max(lo, 2); n < hi; n++ {
+		prime := true
+
+		for d := 2; d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+
+		if prime {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestSplit(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		parts [][2]int
+	)
+
+	got := Split(1000, 4, func(lo, hi int) int {
+		mu.Lock()
+		parts = append(parts, [2]int{lo, hi})
+		mu.Unlock()
+
+		return countPrimes(lo, hi)
+	})
+
+	if got != 168 {
+		t.Errorf("Expected 168 primes below 1000, got %d", got)
+	}
+
+	slices.SortFunc(parts, func(a, b [2]int) int { return a[0] - b[0] })
+
+	if len(parts) != 4 {
+		t.Fatalf("Expected work to be split into 4 parts, got %v", parts)
+	}
+
+	for i, part := range parts {
+		if i == 0 && part[0] != 0 || i > 0 && part[0] != parts[i-1][1] || i == len(parts)-1 && part[1] != 1000 {
+			t.Fatalf("Expected parts to cover [0, 1000) without gaps and overlaps, got %v", parts)
+		}
+	}
+}
+
+func TestSplitScaling(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	const n = 300_000
+
+	elapsed := make(map[int]time.Duration)
+
+	for procs := 1; procs <= runtime.NumCPU(); procs *= 2 {
+		setGOMAXPROCS(t, procs)
+
+		start := time.Now()
+		Split(n, procs, countPrimes)
+		elapsed[procs] = time.Since(start)
+
+		t.Logf("GOMAXPROCS=%-3d %v", procs, elapsed[procs])
+	}
+
+	if runtime.NumCPU() < 2 {
+		t.Skip("Scaling needs at least 2 CPUs")
+	}
+
+	if speedup := float64(elapsed[1]) / float64(elapsed[2]); speedup < 1.4 {
+		t.Errorf("Expected 2 Ps to be at least 1.4 times faster than 1, got %.2f times", speedup)
+	}
+}
+
+// 2. Yielding the processor.
+// runtime.Gosched puts the current goroutine back to the run queue and lets others run.
+// It's rarely needed: channels, mutexes, and I/O park a waiting goroutine, and preemption stops runaway loops.
+// A busy loop that polls a condition is the exception: with one P, the goroutine that would change the condition
+// gets to run only when the loop is preempted, every 10ms.
+//
+// PingPong passes a turn between two goroutines with SpinWait, and with GOMAXPROCS=1 every pass takes 10ms.
+// Fix SpinWait, so the other goroutine gets the P right away. In real code, prefer a channel or sync.Cond to spinning.
+
+// SpinWait polls cond until it returns true.
+func SpinWait(cond func() bool) {
+	for !cond() {
+	}
+}
+
+// PingPong passes the turn between two goroutines rounds times.
+func PingPong(rounds int) {
+	var turn atomic.Int32
+
+	var wg sync.WaitGroup
+
+	for player := range int32(2) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range rounds {
+				SpinWait(func() bool { return turn.Load() == player })
+				turn.Store(1 - player)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestPingPong(t *testing.T) {
+	setGOMAXPROCS(t, 1)
+
+	start := time.Now()
+	PingPong(50)
+
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected 50 rounds to take less than 100ms with GOMAXPROCS=1, took %v", elapsed)
+	}
+}
+
+// 3. Goroutines vs a worker pool.
+// Goroutines are cheap, not free: starting one allocates a stack and puts it on a run queue, about a microsecond.
+// For tiny tasks that's more than the task, and a fixed number of workers is faster.
+// How work is handed to the workers matters as much: a channel send per item costs about as much as the item
+// here, and workers contend for the channel. Handing out batches of indexes with an atomic counter is much cheaper.
+//
+// ProcessAll starts a goroutine per item. Rewrite it with GOMAXPROCS workers, it must be at least 2 times faster.
+// Compare with: go test -run '^$' -bench ProcessAll -cpu 1,4 ./scheduler
+
+// ProcessAll applies fn to every item concurrently and returns results in the order of items.
+func ProcessAll(items []int, fn func(int) int) []int {
+	results := make([]int, len(items))
+
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = fn(item)
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// checksum is a tiny task, cheaper than starting a goroutine.
+func checksum(x int) int {
+	sum := 0
+	for i := range 50 {
+		sum += x * i % 7
+	}
+
+	return sum
+}
+
+var processItems = func() []int {
+	items := make([]int, 10_000)
+	for i := range items {
+		items[i] = i
+	}
+
+	return items
+}()
+
+// goroutinePerItem is the original implementation of ProcessAll, a baseline for the benchmark.
+func goroutinePerItem(items []int, fn func(int) int) []int {
+	results := make([]int, len(items))
+
+	var wg sync.WaitGroup
+
+	for i, item := range items {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = fn(item)
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func TestProcessAll(t *testing.T) {
+	got := ProcessAll(processItems, checksum)
+	for i, item := range processItems {
+		if got[i] != checksum(item) {
+			t.Fatalf("Expected result %d for item %d, got %d", checksum(item), item, got[i])
+		}
+	}
+
+	if got := ProcessAll(nil, checksum); len(got) != 0 {
+		t.Errorf("Expected no results for no items, got %v", got)
+	}
+
+	testutil.SkipWithRaceDetector(t)
+
+	pool := testing.Benchmark(BenchmarkProcessAll)
+	perItem := testing.Benchmark(BenchmarkProcessAllGoroutinePerItem)
+
+	if pool.NsPerOp()*2 > perItem.NsPerOp() {
+		t.Errorf("Expected ProcessAll to be at least 2 times faster than a goroutine per item, got %s vs %s",
+			time.Duration(pool.NsPerOp()), time.Duration(perItem.NsPerOp()))
+	}
+}
+
+func BenchmarkProcessAll(b *testing.B) {
+	for range b.N {
+		ProcessAll(processItems, checksum)
+	}
+}
+
+func BenchmarkProcessAllGoroutinePerItem(b *testing.B) {
+	for range b.N {
+		goroutinePerItem(processItems, checksum)
+	}
+}
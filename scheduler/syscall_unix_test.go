@@ -0,0 +1,112 @@
+//go:build unix
+
+package scheduler
+
+import (
+	"runtime/pprof"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Advanced: blocking system calls.
+//
+// Network I/O in Go doesn't block threads: sockets are non-blocking, and a goroutine waiting for data is parked
+// until the netpoller reports that the socket is ready. Files, cgo calls, and many other system calls
+// block the thread for real. When an M enters such a call, it takes its P along; if the call doesn't return
+// within about 20µs, the sysmon thread hands the P off to another M, starting a new thread if there is no idle one.
+// So other goroutines keep running, but every goroutine blocked in a system call holds an OS thread.
+//
+// A thousand goroutines reading from a slow NFS mount become a thousand threads, and the runtime crashes
+// at 10000 of them (debug.SetMaxThreads). The threadcreate profile shows where threads were created.
+//
+// Let's implement RunBlocking, it must run jobs concurrently with at most limit of them in flight,
+// so the number of threads stays bounded. A buffered channel works as a semaphore, and so does golang.org/x/sync/semaphore.
+
+// RunBlocking runs jobs concurrently, at most limit at a time, and waits for all of them.
+func RunBlocking(jobs []func(), limit int) {
+	var wg sync.WaitGroup
+
+	for _, job := range jobs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			job()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// blockingRead returns a job that blocks its thread in read(2) on a pipe until a timer writes to it after delay.
+// The pipe is created with syscall.Pipe, so the file descriptors are in blocking mode and not known to the netpoller.
+func blockingRead(t *testing.T, delay time.Duration) func() {
+	var fds [2]int
+	if err := syscall.Pipe(fds[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		syscall.Close(fds[0])
+		syscall.Close(fds[1])
+	})
+
+	return func() {
+		time.AfterFunc(delay, func() { syscall.Write(fds[1], []byte{1}) })
+
+		var buf [1]byte
+		if _, err := syscall.Read(fds[0], buf[:]); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+}
+
+func TestRunBlocking(t *testing.T) {
+	const (
+		jobs  = 32
+		limit = 4
+		delay = 20 * time.Millisecond
+	)
+
+	var (
+		mu                    sync.Mutex
+		inFlight, maxInFlight int
+	)
+
+	tasks := make([]func(), jobs)
+	for i := range tasks {
+		read := blockingRead(t, delay)
+
+		tasks[i] = func() {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			read()
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}
+	}
+
+	threads := pprof.Lookup("threadcreate").Count()
+	start := time.Now()
+
+	RunBlocking(tasks, limit)
+
+	elapsed := time.Since(start)
+	t.Logf("%d jobs took %v, %d threads created", jobs, elapsed, pprof.Lookup("threadcreate").Count()-threads)
+
+	if maxInFlight > limit || maxInFlight < 2 {
+		t.Errorf("Expected up to %d jobs in flight, and more than 1, got %d", limit, maxInFlight)
+	}
+
+	if sequential := jobs * delay; elapsed > sequential/2 {
+		t.Errorf("Expected jobs to run concurrently in less than %v, took %v", sequential/2, elapsed)
+	}
+}
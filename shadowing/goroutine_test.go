@@ -0,0 +1,75 @@
+package shadowing
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// Advanced: shadowing in goroutines.
+//
+// Goroutines report results through captured variables, and the shadowing bug is harder to notice there:
+// the code is correctly synchronized, the race detector is silent, but the result never leaves the goroutine.
+// The shadow analyzer finds such declarations, it's not a part of go vet, but can be plugged into it:
+//
+//	go install golang.org/x/tools/go/analysis/passes/shadow/cmd/shadow@latest
+//	go vet -vettool=$(which shadow) ./shadowing
+//
+// Bug 5: UploadAll should return the first error reported by goroutines, wrapped with the file name.
+
+// UploadAll uploads files concurrently and returns the first error.
+func UploadAll(files []string, upload func(name string) error) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+	)
+
+	for _, name := range files {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := upload(name); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if first == nil {
+					first := fmt.Errorf("failed to upload %s: %w", name, err)
+					slog.Debug("upload failed", "error", first)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return first
+}
+
+func TestShadowedInGoroutine(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+
+	err := UploadAll([]string{"a.txt", "b.txt", "c.txt"}, func(name string) error {
+		if name == "b.txt" {
+			return errQuota
+		}
+
+		return nil
+	})
+
+	if !errors.Is(err, errQuota) {
+		t.Fatalf("Expected error to be %v, got %v", errQuota, err)
+	}
+
+	if err.Error() != "failed to upload b.txt: quota exceeded" {
+		t.Errorf("Expected error with the file name, got %q", err)
+	}
+
+	if err := UploadAll([]string{"a.txt"}, func(string) error { return nil }); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package shadowing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Short variable declaration `:=` declares new variables in the current scope.
+// If a variable with the same name exists in an outer scope, the new one shadows it.
+// Shadowing is legal and often intentional, but it's also a source of subtle bugs:
+// an assignment that was meant for the outer variable goes to the new one, and the outer variable keeps its old value.
+//
+// Every block creates a new scope: function body, if, for, switch, select, and case clauses.
+// Variables declared in the init statement of if/for/switch belong to that statement's scope.
+//
+// Go vet doesn't report shadowing by default, because most of the time it's fine.
+// Let's hunt for shadowing bugs below, tests expose them.
+
+// Bug 1: shadowed err inside an if block.
+
+// ParsePorts parses a list of port numbers, empty values are skipped.
+// It returns an error if any of the values is not a number.
+func ParsePorts(values []string) ([]int, error) {
+	var err error
+
+	ports := make([]int, 0, len(values))
+
+	for _, v := range values {
+		if v != "" {
+			port, err := strconv.Atoi(v)
+			if err != nil {
+				continue
+			}
+
+			ports = append(ports, port)
+		}
+	}
+
+	return ports, err
+}
+
+func TestShadowedErr(t *testing.T) {
+	ports, err := ParsePorts([]string{"8080", "", "9090"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(ports) != 2 {
+		t.Errorf("Expected to parse 2 ports, got %v", ports)
+	}
+
+	if _, err := ParsePorts([]string{"8080", "http"}); err == nil {
+		t.Error("Expected error for invalid port")
+	}
+}
+
+// Bug 2: shadowed ctx.
+// The timeout is applied to a new ctx variable that lives only inside the if block.
+
+// Fetcher fetches a resource by URL.
+type Fetcher func(ctx context.Context, url string) (string, error)
+
+// FetchAll fetches all URLs one by one.
+// If timeout is greater than zero, all URLs should be fetched within the timeout.
+func FetchAll(ctx context.Context, timeout time.Duration, urls []string, fetch Fetcher) ([]string, error) {
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		slog.DebugContext(ctx, "fetching with timeout", "timeout", timeout)
+	}
+
+	results := make([]string, 0, len(urls))
+
+	for _, url := range urls {
+		res, err := fetch(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+		}
+
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+func TestShadowedContext(t *testing.T) {
+	slowFetch := func(ctx context.Context, url string) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			return url, nil
+		}
+	}
+
+	_, err := FetchAll(context.Background(), 10*time.Millisecond, []string{"a", "b"}, slowFetch)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+// Bug 3: shadowed err in a deferred function.
+// Named result parameters can be modified by deferred functions, it's a common way to report errors from Close.
+// But `:=` inside the deferred function declares a new err, and the error from Close is lost.
+
+// SaveReport writes lines to w and closes it.
+// It returns an error if writing or closing fails.
+func SaveReport(w io.WriteCloser, lines []string) (err error) {
+	defer func() {
+		if err := w.Close(); err != nil {
+			err = fmt.Errorf("failed to close report: %w", err)
+			slog.Debug("close failed", "error", err)
+		}
+	}()
+
+	_, err = io.WriteString(w, strings.Join(lines, "\n"))
+
+	return err
+}
+
+// ErrDiskFull is returned by failingCloser on Close.
+var ErrDiskFull = errors.New("disk full")
+
+// failingCloser simulates a file that fails to flush data on Close.
+type failingCloser struct {
+	strings.Builder
+}
+
+func (f *failingCloser) Close() error {
+	return ErrDiskFull
+}
+
+func TestShadowedErrInDefer(t *testing.T) {
+	err := SaveReport(&failingCloser{}, []string{"line1", "line2"})
+	if !errors.Is(err, ErrDiskFull) {
+		t.Errorf("Expected error to be %v, got %v", ErrDiskFull, err)
+	}
+}
+
+// Bug 4: shadowed variable in a branch.
+
+// ErrUnknownUser is returned when the user is not found.
+var ErrUnknownUser = errors.New("unknown user")
+
+// DisplayName returns the name of the user, or "anonymous" for id 0.
+func DisplayName(users map[int]string, id int) (string, error) {
+	name := "anonymous"
+
+	if id != 0 {
+		name, ok := users[id]
+		if !ok {
+			return "", ErrUnknownUser
+		}
+
+		slog.Debug("user found", "name", name)
+	}
+
+	return name, nil
+}
+
+func TestShadowedInBranch(t *testing.T) {
+	users := map[int]string{1: "Alice"}
+
+	if name, err := DisplayName(users, 0); err != nil || name != "anonymous" {
+		t.Errorf("Expected anonymous, got %q, %v", name, err)
+	}
+
+	if name, err := DisplayName(users, 1); err != nil || name != "Alice" {
+		t.Errorf("Expected Alice, got %q, %v", name, err)
+	}
+
+	if _, err := DisplayName(users, 2); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("Expected error to be %v, got %v", ErrUnknownUser, err)
+	}
+}
@@ -0,0 +1,119 @@
+package streaming
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Advanced: streaming JSON.
+//
+// json.Unmarshal needs the whole document in memory, it's not an option for an export of millions of records.
+// json.Decoder reads from a stream and can decode a large array element by element:
+// - Token returns the next JSON token, like the opening bracket of the array,
+// - More reports whether there is another element in the current array,
+// - Decode decodes the next element into a value.
+//
+// Let's implement DecodeEvents that calls fn for every element of a JSON array of events,
+// reading only as much of the stream as needed. When fn returns an error, decoding stops and the error is returned.
+
+// Event is a record of the exported array.
+type Event struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+}
+
+// DecodeEvents calls fn for every event of the JSON array read from r.
+func DecodeEvents(r io.Reader, fn func(Event) error) error {
+	return nil
+}
+
+// eventStream generates a JSON array of n events without keeping it in memory, and counts bytes read from it.
+type eventStream struct {
+	n, next int
+	pending strings.Reader
+	read    int
+}
+
+func newEventStream(n int) *eventStream {
+	s := &eventStream{n: n}
+	s.pending.Reset("[")
+
+	return s
+}
+
+func (s *eventStream) Read(p []byte) (int, error) {
+	if s.pending.Len() == 0 {
+		switch {
+		case s.next < s.n:
+			sep := ","
+			if s.next == 0 {
+				sep = ""
+			}
+
+			s.pending.Reset(fmt.Sprintf("%s\n  {\"id\": %d, \"type\": \"click\"}", sep, s.next))
+			s.next++
+		case s.next == s.n:
+			s.pending.Reset("\n]\n")
+			s.next++
+		default:
+			return 0, io.EOF
+		}
+	}
+
+	n, err := s.pending.Read(p)
+	s.read += n
+
+	return n, err
+}
+
+func TestDecodeEvents(t *testing.T) {
+	count, sum := 0, 0
+
+	err := DecodeEvents(newEventStream(1000), func(e Event) error {
+		if e.Type != "click" {
+			return fmt.Errorf("unexpected event type %q", e.Type)
+		}
+
+		count++
+		sum += e.ID
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if count != 1000 || sum != 999*1000/2 {
+		t.Errorf("Expected 1000 events with ids from 0 to 999, got %d events with sum of ids %d", count, sum)
+	}
+
+	if err := DecodeEvents(strings.NewReader(`{"id": 1}`), func(Event) error { return nil }); err == nil {
+		t.Error("Expected error when the stream is not an array")
+	}
+}
+
+func TestDecodeEventsStops(t *testing.T) {
+	errStop := errors.New("stop")
+
+	// A stream of 10 million events is about 300 MB, it's too much to read just for a few of them.
+	stream := newEventStream(10_000_000)
+	seen := 0
+
+	err := DecodeEvents(stream, func(Event) error {
+		if seen++; seen == 3 {
+			return errStop
+		}
+
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("Expected error to be %v, got %v", errStop, err)
+	}
+
+	if stream.read > 64<<10 {
+		t.Errorf("Expected to read only the beginning of the stream, read %d bytes", stream.read)
+	}
+}
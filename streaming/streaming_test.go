@@ -0,0 +1,280 @@
+package streaming
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// Reading a stream byte by byte is slow, every Read is a call to the underlying reader, often a system call.
+// Package bufio wraps readers and writers with a buffer:
+// - bufio.Reader and bufio.Writer reduce the number of calls to the underlying reader or writer.
+// - bufio.Scanner splits a stream into tokens: lines, words, runes, or anything a custom split function finds.
+
+// A split function receives buffered data and returns how many bytes to advance and the next token.
+// If data doesn't contain a complete token yet, it returns 0, nil, nil and the scanner reads more data.
+// At EOF the last token could be incomplete, and the function should return what is left.
+// See bufio.ScanLines for an example.
+
+// ScanSemicolons is a split function that splits data into tokens separated by ';'.
+// Empty tokens between two separators are kept, spaces inside of tokens are kept too.
+func ScanSemicolons(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return bufio.ScanWords(data, atEOF)
+}
+
+func ExampleScanSemicolons() {
+	s := bufio.NewScanner(strings.NewReader("alpha;beta gamma;;delta"))
+	s.Split(ScanSemicolons)
+
+	for s.Scan() {
+		fmt.Printf("%q\n", s.Text())
+	}
+
+	// Output:
+	// "alpha"
+	// "beta gamma"
+	// ""
+	// "delta"
+}
+
+// Scanner has a limit on the size of a token, it's bufio.MaxScanTokenSize (64 KiB) by default.
+// When a line is longer, Scan returns false, and Err returns bufio.ErrTooLong.
+// A minified JSON or a base64 encoded image in a log easily exceeds the limit.
+// Code that doesn't check Err after the loop silently stops in the middle of the input.
+// Let's try to fix CountLines: set the buffer with the maximum line size and report errors.
+
+// CountLines counts lines of r, lines up to maxLineSize bytes must be supported.
+func CountLines(r io.Reader, maxLineSize int) (int, error) {
+	s := bufio.NewScanner(r)
+	lines := 0
+
+	for s.Scan() {
+		lines++
+	}
+
+	return lines, nil
+}
+
+func TestCountLinesHuge(t *testing.T) {
+	input := "first\n" + strings.Repeat("x", 1<<20) + "\nlast\n"
+
+	lines, err := CountLines(strings.NewReader(input), 2<<20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if lines != 3 {
+		t.Errorf("Expected 3 lines, got %d", lines)
+	}
+}
+
+func TestCountLinesTooLong(t *testing.T) {
+	input := "first\n" + strings.Repeat("x", 2<<20) + "\nlast\n"
+
+	if _, err := CountLines(strings.NewReader(input), 1<<20); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("Expected error to be %v, got %v", bufio.ErrTooLong, err)
+	}
+}
+
+// bufio.Writer keeps data in memory until the buffer is full. Nothing reaches the underlying writer
+// until Flush is called, so forgetting Flush loses the tail of the output.
+// Flush returns the error of the underlying writer. `defer w.Flush()` is a common pitfall:
+// it flushes, but throws away the error, and the caller believes the data is written.
+
+// WriteReport writes lines to w through a buffer.
+func WriteReport(w io.Writer, lines []string) error {
+	bw := bufio.NewWriter(w)
+
+	for _, l := range lines {
+		if _, err := fmt.Fprintln(bw, l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ExampleWriteReport() {
+	if err := WriteReport(os.Stdout, []string{"requests: 10", "errors: 1"}); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// Output:
+	// requests: 10
+	// errors: 1
+}
+
+// failingWriter fails every write, like a full disk.
+type failingWriter struct{}
+
+var errDiskFull = errors.New("disk is full")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errDiskFull
+}
+
+func TestWriteReportError(t *testing.T) {
+	if err := WriteReport(failingWriter{}, []string{"requests: 10"}); !errors.Is(err, errDiskFull) {
+		t.Errorf("Expected error to be %v, got %v", errDiskFull, err)
+	}
+}
+
+// Streaming parsing processes input of any size in constant memory: read a line, update the state, forget the line.
+// testdata/access.log.gz is an access log with ~60k requests, about 3 MiB uncompressed.
+// Every line looks like this:
+//
+//	2024-05-01T00:00:00.318Z GET /logout 200 40ms
+//
+// ParseLog below is correct, but it reads the whole log into memory and allocates a string for every line and field.
+// Let's try to make it streaming, so it allocates much less than the size of the log:
+// - bufio.Scanner reads line by line, s.Bytes() returns the line without allocating, s.Text() allocates a string.
+// - bytes.Cut splits a line without allocations, strings.Fields and bytes.Fields allocate a slice for every line.
+// - Converting a short []byte to string for strconv.Atoi doesn't allocate, because the string doesn't escape.
+//   time.ParseDuration keeps its argument in the error, so the conversion allocates. Latencies are always in milliseconds,
+//   bytes.CutSuffix and strconv.Atoi do the job.
+//
+// Compare the allocations before and after:
+//
+//	go test -run '^$' -bench ParseLog -benchmem ./streaming
+
+//go:embed testdata/access.log.gz
+var accessLogGz []byte
+
+// LogStats is the summary of an access log.
+type LogStats struct {
+	Requests    int
+	Malformed   int
+	ByStatus    map[int]int
+	Slowest     time.Duration
+	SlowestPath string
+}
+
+// ParseLog reads an access log from r and returns its summary.
+func ParseLog(r io.Reader) (LogStats, error) {
+	stats := LogStats{ByStatus: make(map[int]int)}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			stats.Malformed++
+			continue
+		}
+
+		status, err := strconv.Atoi(fields[3])
+		if err != nil {
+			stats.Malformed++
+			continue
+		}
+
+		latency, err := time.ParseDuration(fields[4])
+		if err != nil {
+			stats.Malformed++
+			continue
+		}
+
+		stats.Requests++
+		stats.ByStatus[status]++
+
+		if latency > stats.Slowest {
+			stats.Slowest = latency
+			stats.SlowestPath = fields[2]
+		}
+	}
+
+	return stats, nil
+}
+
+func openAccessLog(tb testing.TB) io.Reader {
+	tb.Helper()
+
+	zr, err := gzip.NewReader(bytes.NewReader(accessLogGz))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return zr
+}
+
+func TestParseLog(t *testing.T) {
+	stats, err := ParseLog(openAccessLog(t))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := LogStats{
+		Requests:  61874,
+		Malformed: 3,
+		ByStatus: map[int]int{
+			200: 45252, 201: 3721, 204: 2301, 301: 769, 302: 754, 400: 2323,
+			401: 764, 403: 741, 404: 2962, 500: 747, 502: 760, 503: 780,
+		},
+		Slowest:     8967 * time.Millisecond,
+		SlowestPath: "/api/users/52871",
+	}
+
+	if stats.Requests != expected.Requests || stats.Malformed != expected.Malformed ||
+		stats.Slowest != expected.Slowest || stats.SlowestPath != expected.SlowestPath ||
+		!maps.Equal(stats.ByStatus, expected.ByStatus) {
+		t.Errorf("Expected stats to be %+v, got %+v", expected, stats)
+	}
+}
+
+func TestParseLogMemory(t *testing.T) {
+	testutil.SkipWithRaceDetector(t)
+
+	var before, after runtime.MemStats
+
+	r := openAccessLog(t)
+
+	runtime.ReadMemStats(&before)
+
+	if _, err := ParseLog(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	const limit = 256 << 10 // 256 KiB for a 3 MiB log
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > limit {
+		t.Errorf("Expected to allocate at most %d bytes while parsing, got %d", limit, allocated)
+	}
+}
+
+func BenchmarkParseLog(b *testing.B) {
+	data, err := io.ReadAll(openAccessLog(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseLog(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
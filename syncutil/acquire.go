@@ -0,0 +1,107 @@
+// Package syncutil contains synchronization helpers built in the concurrency workshop.
+package syncutil
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateLock is returned by AcquireAll when the same lock is passed more than once.
+var ErrDuplicateLock = errors.New("duplicate lock")
+
+const (
+	minBackoff = 10 * time.Microsecond
+	maxBackoff = 10 * time.Millisecond
+)
+
+// TryLocker is a lock that can be acquired without blocking.
+// sync.Mutex and sync.RWMutex implement it.
+type TryLocker interface {
+	sync.Locker
+	TryLock() bool
+}
+
+// AcquireAll acquires all locks or none of them.
+// Locks are always acquired in the same order, regardless of the order of arguments.
+// If some of the locks are busy, already acquired locks are released, and AcquireAll retries after a randomized backoff,
+// so goroutines competing for the same locks don't end up in a deadlock or a livelock.
+// It returns an error if the context is done before all locks are acquired.
+// The returned release function unlocks all locks, it's safe to call it multiple times.
+func AcquireAll(ctx context.Context, locks ...TryLocker) (release func(), err error) {
+	ordered, err := order(locks)
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := minBackoff
+
+	for {
+		acquired := tryLockAll(ordered)
+		if acquired == len(ordered) {
+			return sync.OnceFunc(func() { unlockAll(ordered) }), nil
+		}
+
+		unlockAll(ordered[:acquired])
+
+		timer := time.NewTimer(backoff/2 + rand.N(backoff))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to acquire locks: %w", ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// order sorts locks by their address, so all callers acquire them in the same order.
+func order(locks []TryLocker) ([]TryLocker, error) {
+	ordered := slices.Clone(locks)
+
+	slices.SortStableFunc(ordered, func(a, b TryLocker) int {
+		return cmp.Compare(address(a), address(b))
+	})
+
+	for i := 1; i < len(ordered); i++ {
+		if address(ordered[i]) != 0 && address(ordered[i]) == address(ordered[i-1]) {
+			return nil, ErrDuplicateLock
+		}
+	}
+
+	return ordered, nil
+}
+
+func address(l TryLocker) uintptr {
+	if v := reflect.ValueOf(l); v.Kind() == reflect.Pointer {
+		return v.Pointer()
+	}
+
+	return 0
+}
+
+// tryLockAll tries to acquire locks one by one and returns the number of acquired locks.
+func tryLockAll(locks []TryLocker) int {
+	for i, l := range locks {
+		if !l.TryLock() {
+			return i
+		}
+	}
+
+	return len(locks)
+}
+
+// unlockAll releases locks in the reverse order.
+func unlockAll(locks []TryLocker) {
+	for i := len(locks) - 1; i >= 0; i-- {
+		locks[i].Unlock()
+	}
+}
@@ -0,0 +1,104 @@
+package syncutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireAll(t *testing.T) {
+	a, b := &sync.Mutex{}, &sync.Mutex{}
+
+	release, err := AcquireAll(context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if a.TryLock() || b.TryLock() {
+		t.Fatal("Expected all locks to be held")
+	}
+
+	release()
+	release()
+
+	if !a.TryLock() || !b.TryLock() {
+		t.Fatal("Expected all locks to be released")
+	}
+}
+
+func TestAcquireAllTimeout(t *testing.T) {
+	a, b := &sync.Mutex{}, &sync.Mutex{}
+
+	b.Lock()
+	defer b.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := AcquireAll(ctx, a, b); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if !a.TryLock() {
+		t.Error("Expected partially acquired locks to be released")
+	}
+}
+
+func TestAcquireAllDuplicate(t *testing.T) {
+	a := &sync.Mutex{}
+
+	if _, err := AcquireAll(context.Background(), a, &sync.RWMutex{}, a); !errors.Is(err, ErrDuplicateLock) {
+		t.Fatalf("Expected error to be %v, got %v", ErrDuplicateLock, err)
+	}
+}
+
+// fork is a lock that counts how many times it was used.
+type fork struct {
+	sync.Mutex
+	uses int
+}
+
+func TestAcquireAllContention(t *testing.T) {
+	const philosophers = 5
+
+	forks := make([]*fork, philosophers)
+	for i := range forks {
+		forks[i] = &fork{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < philosophers; i++ {
+		left, right := forks[i], forks[(i+1)%philosophers]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				release, err := AcquireAll(ctx, left, right)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				left.uses++
+				right.uses++
+				release()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i, f := range forks {
+		if f.uses != 200 {
+			t.Errorf("Expected fork %d to be used 200 times, got %d", i, f.uses)
+		}
+	}
+}
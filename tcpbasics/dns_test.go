@@ -0,0 +1,416 @@
+package tcpbasics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// 7. A custom resolver.
+// net.Dial("tcp", "api.internal:443") resolves the name before it connects, with net.DefaultResolver:
+// the resolver of the C library or the one written in Go, depending on the platform and the configuration.
+// A net.Resolver with PreferGo always uses the Go one, and its Dial function decides where queries go:
+// it's called with the address of a nameserver from /etc/resolv.conf, and it may connect anywhere else.
+// Resolvers are used by net.Dialer.Resolver, http.Transport dials with a net.Dialer, so it reaches HTTP too.
+//
+// Implement NewResolver: a Go resolver that sends queries to addr over the network it's asked for,
+// with a net.Dialer that gives up connecting after dialTimeout.
+
+// NewResolver returns a resolver that queries the DNS server at addr.
+func NewResolver(addr string, dialTimeout time.Duration) *net.Resolver {
+	return &net.Resolver{}
+}
+
+// 8. Deadlines and fallback.
+// A lookup takes as long as the context allows: a DNS server that doesn't answer holds it until the deadline,
+// retrying and waiting for seconds without one. Services keep several resolvers for that: when one doesn't answer
+// in time, the next one gets the question. Each of them gets its own Timeout, and the whole lookup stays within
+// the deadline of the caller, so the timeout of every attempt comes from the context of the lookup.
+//
+// Not every error calls for the next resolver: "no such host" is an answer, a *net.DNSError with IsNotFound,
+// and the next resolver would give the same one. Fix LookupHost: fall back on other errors, return
+// "no such host" right away, and return the error of the last attempt when all of them fail.
+
+// Lookup resolves names with the first resolver that answers.
+type Lookup struct {
+	Resolvers []*net.Resolver
+	// Timeout is how long every resolver gets to answer.
+	Timeout time.Duration
+}
+
+// LookupHost returns addresses of the host.
+func (l *Lookup) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return l.Resolvers[0].LookupHost(ctx, host)
+}
+
+// 9. Advanced: caching lookups.
+// Addresses change rarely, and a service resolving the same name for every request spends more time on DNS
+// than it has to. The Go resolver doesn't cache, so services cache lookups themselves, for a TTL:
+// the TTL bounds how long the service keeps connecting to an old address after a record changes.
+// Names that don't exist are cached too, usually for a shorter NegativeTTL, or a typo in a config turns into
+// a stream of queries. Other errors are never cached: a timeout says nothing about the name.
+//
+// CachedLookup keeps everything forever, errors included, and holds the lock while it looks up, so a name
+// whose server is slow blocks lookups of all the others. Fix it, and return copies of cached addresses,
+// the slice of the cache must not change when a caller sorts or appends to its result.
+
+// CachedLookup caches results of lookups.
+type CachedLookup struct {
+	Lookup func(ctx context.Context, host string) ([]string, error)
+	// TTL is how long addresses are cached.
+	TTL time.Duration
+	// NegativeTTL is how long names that don't exist are cached.
+	NegativeTTL time.Duration
+	Now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// LookupHost returns addresses of the host from the cache, looking them up if they aren't cached.
+func (c *CachedLookup) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[host]; ok {
+		return e.addrs, e.err
+	}
+
+	addrs, err := c.Lookup(ctx, host)
+
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+
+	c.entries[host] = cacheEntry{addrs: addrs, err: err, expires: c.Now().Add(c.TTL)}
+
+	return addrs, err
+}
+
+// fakeDNS is a DNS server that answers A queries for names it knows, and "no such host" for the others.
+type fakeDNS struct {
+	addr    string
+	records map[string]string
+	// silent servers never answer.
+	silent  bool
+	queries atomic.Int64
+}
+
+func startDNS(t *testing.T, records map[string]string, silent bool) *fakeDNS {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	d := &fakeDNS{addr: conn.LocalAddr().String(), records: records, silent: silent}
+
+	go d.serve(conn)
+
+	return d
+}
+
+func (d *fakeDNS) serve(conn net.PacketConn) {
+	buf := make([]byte, 512)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		d.queries.Add(1)
+
+		if d.silent {
+			continue
+		}
+
+		if resp, err := d.answer(buf[:n]); err == nil {
+			conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (d *fakeDNS) answer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	ip, known := d.records[strings.TrimSuffix(q.Name.String(), ".")]
+
+	header := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true, RecursionDesired: h.RecursionDesired}
+	if !known {
+		header.RCode = dnsmessage.RCodeNameError
+	}
+
+	b := dnsmessage.NewBuilder(nil, header)
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	if known && q.Type == dnsmessage.TypeA {
+		rh := dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60}
+		if err := b.AResource(rh, dnsmessage.AResource{A: netip.MustParseAddr(ip).As4()}); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.Finish()
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+var records = map[string]string{"api.workshop.test": "10.0.0.1"}
+
+func TestResolver(t *testing.T) {
+	d := startDNS(t, records, false)
+	r := NewResolver(d.addr, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	addrs, err := r.LookupHost(ctx, "api.workshop.test")
+	if err != nil || !slices.Equal(addrs, []string{"10.0.0.1"}) {
+		t.Fatalf("Expected [10.0.0.1] from the fake server, got %v, %v", addrs, err)
+	}
+
+	if d.queries.Load() == 0 {
+		t.Error("Expected the fake server to be queried")
+	}
+
+	if _, err := r.LookupHost(ctx, "missing.workshop.test"); !isNotFound(err) {
+		t.Errorf("Expected no such host for a name the server doesn't know, got %v", err)
+	}
+}
+
+func TestLookupFallback(t *testing.T) {
+	silent, ok := startDNS(t, records, true), startDNS(t, records, false)
+
+	lookup := func(servers ...*fakeDNS) *Lookup {
+		l := &Lookup{Timeout: 100 * time.Millisecond}
+
+		for _, d := range servers {
+			l.Resolvers = append(l.Resolvers, NewResolver(d.addr, time.Second))
+		}
+
+		return l
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	addrs, err := lookup(silent, ok).LookupHost(ctx, "api.workshop.test")
+	if err != nil || !slices.Equal(addrs, []string{"10.0.0.1"}) {
+		t.Fatalf("Expected [10.0.0.1] from the second resolver, got %v, %v", addrs, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Expected the first resolver to get 100ms, the lookup took %v", elapsed)
+	}
+
+	if silent.queries.Load() == 0 {
+		t.Error("Expected the first resolver to be asked first")
+	}
+
+	// No such host is an answer, the second resolver isn't asked.
+	other := startDNS(t, records, false)
+
+	if _, err := lookup(ok, other).LookupHost(ctx, "missing.workshop.test"); !isNotFound(err) {
+		t.Errorf("Expected no such host, got %v", err)
+	}
+
+	if n := other.queries.Load(); n != 0 {
+		t.Errorf("Expected no fallback after no such host, the second resolver got %d queries", n)
+	}
+
+	start = time.Now()
+
+	_, err = lookup(silent, silent).LookupHost(ctx, "api.workshop.test")
+
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+		t.Errorf("Expected a timeout when no resolver answers, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Expected two resolvers to give up after 200ms, the lookup took %v", elapsed)
+	}
+
+	// The deadline of the caller comes first.
+	short, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	start = time.Now()
+
+	if _, err := lookup(silent, silent, silent, silent).LookupHost(short, "api.workshop.test"); err == nil {
+		t.Error("Expected an error when no resolver answers")
+	}
+
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Expected the lookup to stop at the deadline of 150ms, it took %v", elapsed)
+	}
+}
+
+// fakeLookup answers lookups from a script and counts them.
+type fakeLookup struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (f *fakeLookup) lookup(ctx context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+
+	f.calls[host]++
+	f.mu.Unlock()
+
+	switch host {
+	case "api.workshop.test":
+		return []string{"10.0.0.1", "10.0.0.2"}, nil
+	case "slow.workshop.test":
+		<-ctx.Done()
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+	case "flaky.workshop.test":
+		return nil, &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}
+	default:
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+}
+
+func (f *fakeLookup) count(host string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[host]
+}
+
+func TestCachedLookup(t *testing.T) {
+	f := &fakeLookup{}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &CachedLookup{Lookup: f.lookup, TTL: time.Minute, NegativeTTL: 10 * time.Second, Now: func() time.Time { return now }}
+
+	ctx := context.Background()
+
+	for range 3 {
+		addrs, err := c.LookupHost(ctx, "api.workshop.test")
+		if err != nil || !slices.Equal(addrs, []string{"10.0.0.1", "10.0.0.2"}) {
+			t.Fatalf("Expected [10.0.0.1 10.0.0.2], got %v, %v", addrs, err)
+		}
+
+		// Callers may do anything with their result.
+		slices.Reverse(addrs)
+	}
+
+	if n := f.count("api.workshop.test"); n != 1 {
+		t.Errorf("Expected a single lookup within the TTL, got %d", n)
+	}
+
+	for range 3 {
+		if _, err := c.LookupHost(ctx, "typo.workshop.test"); !isNotFound(err) {
+			t.Fatalf("Expected no such host, got %v", err)
+		}
+
+		if _, err := c.LookupHost(ctx, "flaky.workshop.test"); err == nil {
+			t.Fatal("Expected an error for a server failure")
+		}
+	}
+
+	if n := f.count("typo.workshop.test"); n != 1 {
+		t.Errorf("Expected no such host to be cached, got %d lookups", n)
+	}
+
+	if n := f.count("flaky.workshop.test"); n != 3 {
+		t.Errorf("Expected server failures not to be cached, got %d lookups instead of 3", n)
+	}
+
+	now = now.Add(30 * time.Second)
+
+	c.LookupHost(ctx, "api.workshop.test")
+	c.LookupHost(ctx, "typo.workshop.test")
+
+	if n := f.count("api.workshop.test"); n != 1 {
+		t.Errorf("Expected addresses to be cached for a minute, got %d lookups after 30s", n)
+	}
+
+	if n := f.count("typo.workshop.test"); n != 2 {
+		t.Errorf("Expected no such host to be cached for 10s, got %d lookups after 30s", n)
+	}
+
+	now = now.Add(31 * time.Second)
+
+	c.LookupHost(ctx, "api.workshop.test")
+
+	if n := f.count("api.workshop.test"); n != 2 {
+		t.Errorf("Expected addresses to be looked up again after the TTL, got %d lookups", n)
+	}
+}
+
+func TestCachedLookupSlowServer(t *testing.T) {
+	f := &fakeLookup{}
+	c := &CachedLookup{Lookup: f.lookup, TTL: time.Minute, NegativeTTL: 10 * time.Second, Now: time.Now}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	go c.LookupHost(ctx, "slow.workshop.test")
+
+	for f.count("slow.workshop.test") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		c.LookupHost(context.Background(), "api.workshop.test")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(200 * time.Millisecond):
+		t.Error("Expected a slow lookup not to block lookups of other names")
+	}
+}
@@ -0,0 +1,267 @@
+package tcpbasics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TCP gives a reliable stream of bytes between two programs and nothing more: no messages, no requests,
+// no timeouts. Every protocol on top of it decides where a message ends, and every server decides
+// what to do with clients that are slow, silent, or malicious.
+//
+// In Go, a server accepts connections in a loop with net.Listener.Accept and serves each of them in a goroutine.
+// Reads and writes block the goroutine, not a thread: the runtime parks it until the socket is ready.
+// So a goroutine per connection is the idiomatic design, and it scales to tens of thousands of connections.
+//
+// Try the server of this workshop with netcat once it works:
+//
+//	nc localhost 7000
+
+// Server is a line-based echo server: it sends every line it receives back to the client.
+type Server struct {
+	// MaxConns limits the number of connections served at the same time, 0 means no limit.
+	MaxConns int
+	// IdleTimeout is how long the server waits for the next line, 0 means forever.
+	IdleTimeout time.Duration
+	// MaxLineLength limits the length of a line without the newline, 0 means bufio.MaxScanTokenSize.
+	MaxLineLength int
+}
+
+// 1. A goroutine per connection.
+// Serve serves connections one by one: while one client is connected, the others wait in the backlog
+// of the listener. Serve every connection in its own goroutine.
+
+// Serve accepts connections until the listener is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		s.handle(conn)
+	}
+}
+
+// 2. Deadlines.
+// A client that connects and sends nothing holds a goroutine and a file descriptor forever.
+// Open enough such connections, and the server runs out of descriptors: that's the slowloris attack.
+// net.Conn has no timeouts, it has deadlines: an absolute time after which Read or Write fails with
+// os.ErrDeadlineExceeded. A deadline set once applies to all later calls, so it's set again before every line.
+// A deadline set before every Read isn't enough: a client sending a byte at a time keeps resetting it.
+//
+// A client can also send a line without an end. bufio.Scanner buffers the line until it ends,
+// up to 64KiB by default, and fails with bufio.ErrTooLong after that. Limit lines to MaxLineLength
+// with Scanner.Buffer, and close connections that send longer ones, or that stay idle for IdleTimeout.
+
+// handle echoes lines of the connection until it's closed.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+
+	for scanner.Scan() {
+		fmt.Fprintf(conn, "%s\n", scanner.Text())
+	}
+}
+
+// 3. Limiting connections.
+// Every connection costs memory and a file descriptor, and a server must not accept more than it can serve.
+// A buffered channel with MaxConns slots is a semaphore: take a slot for every connection and give it back
+// when the connection is closed. A connection over the limit gets "ERR busy\n" and is closed right away,
+// so the client learns about it and can retry later, instead of waiting in the backlog without an answer.
+
+// start serves connections on a random port until the test is done, and returns its address.
+func start(t *testing.T, s *Server) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	go s.Serve(l)
+
+	return l.Addr().String()
+}
+
+// client is a connection to the server with a reader of lines.
+type client struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return &client{Conn: conn, r: bufio.NewReader(conn)}
+}
+
+// echo sends the line and returns the response, waiting for it for a second at most.
+func (c *client) echo(line string) (string, error) {
+	if _, err := fmt.Fprintf(c, "%s\n", line); err != nil {
+		return "", err
+	}
+
+	c.SetReadDeadline(time.Now().Add(time.Second))
+
+	resp, err := c.r.ReadString('\n')
+
+	return strings.TrimSuffix(resp, "\n"), err
+}
+
+// closedWithin reports whether the server closes the connection within d.
+func (c *client) closedWithin(d time.Duration) bool {
+	c.SetReadDeadline(time.Now().Add(d))
+
+	_, err := io.Copy(io.Discard, c.r)
+
+	return err == nil || !isTimeout(err)
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+
+	return ok && ne.Timeout()
+}
+
+func TestEcho(t *testing.T) {
+	addr := start(t, &Server{})
+
+	alice := dial(t, addr)
+	if got, err := alice.echo("hello"); got != "hello" || err != nil {
+		t.Fatalf("Expected hello back, got %q, %v", got, err)
+	}
+
+	// Alice stays connected while Bob talks to the server.
+	bob := dial(t, addr)
+	if got, err := bob.echo("world"); got != "world" || err != nil {
+		t.Errorf("Expected the second client to be served while the first one is connected, got %q, %v", got, err)
+	}
+
+	if got, err := alice.echo("still here"); got != "still here" || err != nil {
+		t.Errorf("Expected the first client to be served still, got %q, %v", got, err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	addr := start(t, &Server{IdleTimeout: 100 * time.Millisecond})
+
+	c := dial(t, addr)
+	if got, err := c.echo("ping"); got != "ping" || err != nil {
+		t.Fatalf("Expected ping back, got %q, %v", got, err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got, err := c.echo("ping again"); got != "ping again" || err != nil {
+		t.Fatalf("Expected a client that sends lines in time to stay connected, got %q, %v", got, err)
+	}
+
+	start := time.Now()
+	if !c.closedWithin(time.Second) {
+		t.Fatal("Expected an idle connection to be closed")
+	}
+
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Expected the connection to be closed after 100ms of silence, it was closed after %v", elapsed)
+	}
+
+	// A byte at a time, and never a whole line.
+	slowloris := dial(t, addr)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				if _, err := slowloris.Write([]byte("x")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	if !slowloris.closedWithin(time.Second) {
+		t.Error("Expected a client that never finishes a line to be disconnected")
+	}
+}
+
+func TestLongLine(t *testing.T) {
+	addr := start(t, &Server{MaxLineLength: 1024})
+
+	c := dial(t, addr)
+
+	line := strings.Repeat("x", 1024)
+	if got, err := c.echo(line); got != line || err != nil {
+		t.Fatalf("Expected a line of 1024 bytes back, got %d bytes, %v", len(got), err)
+	}
+
+	if _, err := c.Write([]byte(strings.Repeat("x", 4096))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !c.closedWithin(time.Second) {
+		t.Error("Expected a client sending a line over 1024 bytes to be disconnected")
+	}
+}
+
+func TestMaxConns(t *testing.T) {
+	addr := start(t, &Server{MaxConns: 2})
+
+	alice, bob := dial(t, addr), dial(t, addr)
+
+	for _, c := range []*client{alice, bob} {
+		if got, err := c.echo("hi"); got != "hi" || err != nil {
+			t.Fatalf("Expected hi back, got %q, %v", got, err)
+		}
+	}
+
+	carol := dial(t, addr)
+	carol.SetReadDeadline(time.Now().Add(time.Second))
+
+	if resp, err := carol.r.ReadString('\n'); resp != "ERR busy\n" {
+		t.Fatalf("Expected ERR busy for a connection over the limit, got %q, %v", resp, err)
+	}
+
+	if !carol.closedWithin(time.Second) {
+		t.Error("Expected a connection over the limit to be closed")
+	}
+
+	// When Alice leaves, her slot is free again. The server notices it a little later.
+	alice.Close()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		dave := dial(t, addr)
+		if got, _ := dave.echo("hi"); got == "hi" {
+			break
+		}
+
+		dave.Close()
+
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a new connection to be served after a client left")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,203 @@
+package tcpbasics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// 4. Advanced: length-prefixed framing.
+// Lines work for text, but a binary payload may contain any byte, newlines included. Binary protocols
+// frame messages with a length prefix instead: here, 4 bytes of the payload length in big-endian, then the payload.
+//
+// TCP doesn't keep the boundaries of writes: a frame written at once may arrive in pieces, and a read may
+// return fewer bytes than asked for without an error. io.ReadFull reads until the buffer is full.
+// The length comes from the client too: a prefix of 0xFFFFFFFF would make the server allocate 4GiB,
+// so frames over maxSize are rejected before anything is allocated.
+//
+// Fix ReadFrame: read the header and the payload completely, return ErrFrameTooLarge for frames over maxSize,
+// io.EOF when the stream ends between frames, and io.ErrUnexpectedEOF when it ends in the middle of one.
+// Then fix WriteFrame, it has to send a frame in a single write, so frames of concurrent writers don't interleave.
+
+// ErrFrameTooLarge is returned for frames with a payload over the limit.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// WriteFrame writes the payload as a frame.
+func WriteFrame(w io.Writer, payload []byte) error {
+	var header [4]byte
+
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(payload)
+
+	return err
+}
+
+// ReadFrame reads the payload of the next frame, which is at most maxSize bytes.
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var header [4]byte
+
+	if _, err := r.Read(header[:]); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[:]))
+
+	if _, err := r.Read(payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// writeCounter counts calls to Write.
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+
+	return w.Buffer.Write(p)
+}
+
+func TestReadFrame(t *testing.T) {
+	var stream bytes.Buffer
+
+	payloads := [][]byte{[]byte("hello"), {}, {0x00, '\n', 0xFF, 0x00}, bytes.Repeat([]byte("x"), 1000)}
+
+	for _, p := range payloads {
+		if err := WriteFrame(&stream, p); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// One byte at a time, as a slow network may deliver them.
+	r := iotest.OneByteReader(bytes.NewReader(stream.Bytes()))
+
+	for _, expected := range payloads {
+		got, err := ReadFrame(r, 1024)
+		if err != nil || !bytes.Equal(got, expected) {
+			t.Fatalf("Expected frame %q, got %q, %v", expected, got, err)
+		}
+	}
+
+	if _, err := ReadFrame(r, 1024); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last frame, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		stream   []byte
+		expected error
+	}{
+		{"truncated header", []byte{0, 0}, io.ErrUnexpectedEOF},
+		{"truncated payload", []byte{0, 0, 0, 5, 'h', 'e'}, io.ErrUnexpectedEOF},
+		{"too large", []byte{0, 0, 4, 1}, ErrFrameTooLarge},
+		{"malicious", []byte{0xFF, 0xFF, 0xFF, 0xFF}, ErrFrameTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadFrame(bytes.NewReader(tt.stream), 1024); !errors.Is(err, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	var w writeCounter
+
+	if err := WriteFrame(&w, []byte("hello")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := []byte{0, 0, 0, 5, 'h', 'e', 'l', 'l', 'o'}; !bytes.Equal(w.Bytes(), expected) {
+		t.Errorf("Expected frame %v, got %v", expected, w.Bytes())
+	}
+
+	if w.writes != 1 {
+		t.Errorf("Expected the frame to be written at once, got %d writes", w.writes)
+	}
+}
+
+func TestFramingOverTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	received := make(chan []byte, 1)
+
+	// The server reads a frame and echoes it back.
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.SetDeadline(time.Now().Add(time.Second))
+
+		payload, err := ReadFrame(conn, 1<<20)
+		if err != nil {
+			return
+		}
+
+		received <- payload
+
+		WriteFrame(conn, payload)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	payload := bytes.Repeat([]byte{0, 1, 2, '\n'}, 64<<10)
+
+	// The frame goes out in pieces with pauses, and the server has to wait for all of them.
+	var frame bytes.Buffer
+	WriteFrame(&frame, payload)
+
+	for b := frame.Bytes(); len(b) > 0; {
+		n := min(len(b), 100<<10)
+
+		if _, err := conn.Write(b[:n]); err != nil {
+			t.Fatalf("Expected the server to wait for the whole frame, it closed the connection: %v", err)
+		}
+
+		b = b[n:]
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case got := <-received:
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("Expected the server to receive the payload of %d bytes, got %d bytes", len(payload), len(got))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the server to receive the frame")
+	}
+
+	got, err := ReadFrame(conn, 1<<20)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("Expected the payload of %d bytes back, got %d bytes, %v", len(payload), len(got), err)
+	}
+}
@@ -0,0 +1,370 @@
+package tcpbasics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 5. UDP datagrams.
+// UDP sends datagrams: messages that arrive whole or not at all, maybe out of order, maybe twice.
+// Nothing acknowledges them, and nothing is kept per client: a server reads datagrams of all clients from
+// a single net.PacketConn. It suits metrics well: a client sends them without waiting, and a collector that is
+// down or slow never slows the service down, at the cost of losing some of them.
+//
+// The protocol is the one of statsd. A datagram carries one or more lines, every line is a metric:
+//
+//	requests:1|c        a counter, the value is added to it
+//	requests:1|c|@0.1   a counter sampled at 10%, the client sends one increment of 10, so it's worth 10
+//	temperature:21.5|g  a gauge, the value replaces the previous one
+//
+// A lost datagram loses an increment of a counter, not the whole count, and the next gauge corrects a lost one,
+// so every datagram makes sense alone. Sampling trades precision for fewer packets on hot paths.
+//
+// Implement ParseMetric: a name that isn't empty, a float value, a type c or g, and an optional sample rate
+// in (0, 1] for counters, which divides the value. Anything else is ErrMalformed.
+
+// ErrMalformed is returned for lines that aren't valid metrics.
+var ErrMalformed = errors.New("malformed metric")
+
+// MetricType is the type of a metric.
+type MetricType string
+
+const (
+	Counter MetricType = "c"
+	Gauge   MetricType = "g"
+)
+
+// Metric is a single metric of a datagram.
+type Metric struct {
+	Name  string
+	Value float64
+	Type  MetricType
+}
+
+// ParseMetric parses a line of a datagram, the value of a sampled counter is scaled by the rate.
+func ParseMetric(line string) (Metric, error) {
+	name, rest, _ := strings.Cut(line, ":")
+	value, typ, _ := strings.Cut(rest, "|")
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Metric{}, err
+	}
+
+	return Metric{Name: name, Value: v, Type: MetricType(typ)}, nil
+}
+
+// 6. Advanced: aggregating metrics.
+// Thousands of services send increments, and the collector stores sums: every Interval it hands over counters
+// summed since the last flush, and resets them, together with the last values of all gauges.
+// That's the batcher pattern: a single goroutine owns the batch, receives items from a channel, and flushes
+// the batch on a ticker. It needs no locks, and the batch it flushes is never touched again, so Flush can keep it.
+// Datagrams are read and parsed by Workers goroutines, a PacketConn is safe for concurrent reads.
+//
+// The collector must not fail on input it can't parse: anybody can send it a datagram.
+// Malformed lines are dropped and counted in the snapshot, and the collector goes on.
+// When the context is done, the collector flushes what it has, and Serve returns nil.
+//
+// Serve reads and aggregates in one goroutine, stops on the first malformed line, and flushes only when it's done.
+
+// maxDatagramSize is the largest UDP payload, a shorter buffer truncates datagrams.
+const maxDatagramSize = 65507
+
+// Snapshot is the state of metrics at a flush.
+type Snapshot struct {
+	// Counters are sums of counters since the previous flush.
+	Counters map[string]float64
+	// Gauges are last values of all gauges.
+	Gauges map[string]float64
+	// Dropped is the number of malformed lines since the previous flush.
+	Dropped int
+}
+
+// Collector aggregates metrics it receives in datagrams.
+type Collector struct {
+	Workers  int
+	Interval time.Duration
+	Flush    func(Snapshot)
+}
+
+// Serve collects metrics from the connection until the context is done.
+func (c *Collector) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	snapshot := Snapshot{Counters: make(map[string]float64), Gauges: make(map[string]float64)}
+	buf := make([]byte, maxDatagramSize)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.Flush(snapshot)
+				return nil
+			}
+
+			return err
+		}
+
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			m, err := ParseMetric(line)
+			if err != nil {
+				return err
+			}
+
+			switch m.Type {
+			case Counter:
+				snapshot.Counters[m.Name] += m.Value
+			case Gauge:
+				snapshot.Gauges[m.Name] = m.Value
+			}
+		}
+	}
+}
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Metric
+		err      error
+	}{
+		{"requests:1|c", Metric{"requests", 1, Counter}, nil},
+		{"bytes.sent:1024|c", Metric{"bytes.sent", 1024, Counter}, nil},
+		{"requests:1|c|@0.1", Metric{"requests", 10, Counter}, nil},
+		{"requests:3|c|@1", Metric{"requests", 3, Counter}, nil},
+		{"temperature:21.5|g", Metric{"temperature", 21.5, Gauge}, nil},
+		{"temperature:-3|g", Metric{"temperature", -3, Gauge}, nil},
+		{"requests:1", Metric{}, ErrMalformed},
+		{"requests|c", Metric{}, ErrMalformed},
+		{":1|c", Metric{}, ErrMalformed},
+		{"requests:many|c", Metric{}, ErrMalformed},
+		{"latency:5|ms", Metric{}, ErrMalformed},
+		{"requests:1|c|@0", Metric{}, ErrMalformed},
+		{"requests:1|c|@1.5", Metric{}, ErrMalformed},
+		{"requests:1|c|0.1", Metric{}, ErrMalformed},
+		{"temperature:21.5|g|@0.5", Metric{}, ErrMalformed},
+		{"\xff\x00", Metric{}, ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			m, err := ParseMetric(tt.line)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			if m != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, m)
+			}
+		})
+	}
+}
+
+// flushes records snapshots of a collector.
+type flushes struct {
+	mu        sync.Mutex
+	snapshots []Snapshot
+}
+
+func (f *flushes) add(s Snapshot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.snapshots = append(f.snapshots, s)
+}
+
+// total sums counters and dropped lines of all snapshots, and returns the gauges of the last one.
+func (f *flushes) total() (counters map[string]float64, gauges map[string]float64, dropped int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counters = make(map[string]float64)
+
+	for _, s := range f.snapshots {
+		for name, v := range s.Counters {
+			counters[name] += v
+		}
+
+		dropped += s.Dropped
+		gauges = s.Gauges
+	}
+
+	return counters, gauges, dropped
+}
+
+func (f *flushes) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.snapshots)
+}
+
+// collect runs a collector on a random port, and returns its address and a function that stops it.
+func collect(t *testing.T, f *flushes) (addr string, stop func() error) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- (&Collector{Workers: 4, Interval: 20 * time.Millisecond, Flush: f.add}).Serve(ctx, conn)
+	}()
+
+	return conn.LocalAddr().String(), func() error {
+		cancel()
+
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(time.Second):
+			return errors.New("collector didn't stop in a second")
+		}
+	}
+}
+
+// sendDatagrams sends every datagram from a new socket.
+func sendDatagrams(t *testing.T, addr string, datagrams ...string) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	for i, d := range datagrams {
+		if _, err := conn.Write([]byte(d)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+			return
+		}
+
+		// Loopback drops datagrams too, when they come faster than they are read.
+		if i%10 == 9 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// eventually polls cond until it's true or a second passes.
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return true
+}
+
+func TestCollector(t *testing.T) {
+	f := &flushes{}
+	addr, stop := collect(t, f)
+
+	var wg sync.WaitGroup
+
+	for range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			datagrams := make([]string, 50)
+			for i := range datagrams {
+				datagrams[i] = "requests:1|c\nbytes:100|c"
+			}
+
+			sendDatagrams(t, addr, datagrams...)
+		}()
+	}
+
+	wg.Wait()
+
+	sendDatagrams(t, addr, "sampled:1|c|@0.5", "sampled:1|c|@0.5", "temperature:20|g", "temperature:21.5|g")
+
+	ok := eventually(func() bool {
+		counters, _, _ := f.total()
+
+		return counters["requests"] == 200 && counters["sampled"] == 4
+	})
+
+	if !ok {
+		counters, _, _ := f.total()
+		t.Fatalf("Expected counters to be flushed while the collector runs, got %v in %d flushes", counters, f.count())
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Expected the collector to stop without an error, got %v", err)
+	}
+
+	counters, gauges, _ := f.total()
+
+	expected := map[string]float64{"requests": 200, "bytes": 20000, "sampled": 4}
+	if fmt.Sprint(counters) != fmt.Sprint(expected) {
+		t.Errorf("Expected counters %v, got %v", expected, counters)
+	}
+
+	if gauges["temperature"] != 21.5 {
+		t.Errorf("Expected the last value of the gauge 21.5 in the last flush, got %v", gauges)
+	}
+}
+
+func TestCollectorFlushInterval(t *testing.T) {
+	f := &flushes{}
+	_, stop := collect(t, f)
+
+	time.Sleep(110 * time.Millisecond)
+
+	if n := f.count(); n < 3 || n > 6 {
+		t.Errorf("Expected about 5 flushes in 110ms with an interval of 20ms, got %d", n)
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Expected the collector to stop without an error, got %v", err)
+	}
+}
+
+func TestCollectorMalformed(t *testing.T) {
+	f := &flushes{}
+	addr, stop := collect(t, f)
+
+	sendDatagrams(t, addr,
+		"requests:1|c\nrequests:many|c\n\nlatency:5|ms\nrequests:1|c",
+		"\xff\x00\x13\x37",
+		"requests:1|c",
+	)
+
+	ok := eventually(func() bool {
+		counters, _, dropped := f.total()
+
+		return counters["requests"] == 3 && dropped == 3
+	})
+
+	if !ok {
+		counters, _, dropped := f.total()
+		t.Errorf("Expected 3 requests and 3 dropped lines, got %v and %d dropped", counters, dropped)
+	}
+
+	if err := stop(); err != nil {
+		t.Errorf("Expected the collector to keep running after malformed datagrams, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+)
+
+// Advanced: generating Go code.
+//
+// text/template isn't only for web pages, go generate tools like stringer and mockgen produce Go source with it.
+// Generated code has to compile and should look like it was written by hand,
+// a tool never produces gofmt-ed output directly from a template, indentation inside range and if blocks gets messy.
+// The usual approach: render the template without caring much about whitespace, then run the result through go/format.
+// format.Source also catches syntax errors of the template, the generator fails instead of producing broken code.
+//
+// Let's complete the template of GenerateStringer, it generates a String method for constants named
+// after the type, like StatusPending for the value Pending of the type Status, see the expected output in the test.
+// {{- and -}} trim whitespace around actions, it helps to keep the template readable.
+
+// stringerTemplate renders a String method of the type, its data is stringerData.
+var stringerTemplate = template.Must(template.New("stringer").Parse(`// Code generated by workshop-stringer; DO NOT EDIT.
+
+package {{.Package}}
+`))
+
+type stringerData struct {
+	Package string
+	Type    string
+	Values  []string
+}
+
+// GenerateStringer returns formatted Go source of the String method for the constants of the type.
+func GenerateStringer(pkg, typ string, values []string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := stringerTemplate.Execute(&buf, stringerData{Package: pkg, Type: typ, Values: values}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func TestGenerateStringer(t *testing.T) {
+	src, err := GenerateStringer("shop", "Status", []string{"Pending", "Paid", "Shipped"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by workshop-stringer; DO NOT EDIT.
+
+package shop
+
+import "strconv"
+
+func (v Status) String() string {
+	switch v {
+	case StatusPending:
+		return "Pending"
+	case StatusPaid:
+		return "Paid"
+	case StatusShipped:
+		return "Shipped"
+	}
+
+	return "Status(" + strconv.Itoa(int(v)) + ")"
+}
+`
+	if string(src) != expected {
+		t.Errorf("Expected generated code:\n%s\ngot:\n%s", expected, src)
+	}
+
+	if _, err := GenerateStringer("shop", "Bad Type", []string{"X"}); err == nil {
+		t.Error("Expected error when the generated code doesn't compile")
+	}
+}
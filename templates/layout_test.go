@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"embed"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+)
+
+// A template can contain named templates. {{define "name"}}...{{end}} declares one,
+// {{template "name" .}} executes it, and {{block "name" .}}default{{end}} is a shortcut
+// for declaring a template with default content and executing it in place.
+//
+// That's how layouts are built: testdata/site/layout.tmpl declares blocks "title" and "content",
+// and every page in testdata/site/pages redefines some of them.
+//
+// All templates parsed into the same template share one namespace, a later define replaces an earlier one with the same name.
+// Site parses pages one by one into the layout, so every page ends up rendering the last parsed one.
+// Fix NewSite, so each page gets its own copy of the layout.
+// Hint: take a look at Template.Clone.
+
+//go:embed testdata/site
+var siteFS embed.FS
+
+// Site renders pages of a web site with a shared layout.
+type Site struct {
+	pages map[string]*template.Template
+}
+
+// NewSite parses layout.tmpl and pages/*.tmpl from fsys.
+func NewSite(fsys fs.FS) (*Site, error) {
+	layout, err := template.ParseFS(fsys, "layout.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := fs.Glob(fsys, "pages/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Site{pages: make(map[string]*template.Template)}
+
+	for _, file := range files {
+		page, err := layout.ParseFS(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		s.pages[strings.TrimSuffix(path.Base(file), ".tmpl")] = page
+	}
+
+	return s, nil
+}
+
+// Render renders the page with the layout.
+func (s *Site) Render(w io.Writer, page string, data any) error {
+	tmpl, ok := s.pages[page]
+	if !ok {
+		return fs.ErrNotExist
+	}
+
+	return tmpl.ExecuteTemplate(w, "layout.tmpl", data)
+}
+
+func TestSitePages(t *testing.T) {
+	root, err := fs.Sub(siteFS, "testdata/site")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	site, err := NewSite(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		page    string
+		title   string
+		content string
+	}{
+		{page: "home", title: "<title>Home | Go Workshops</title>", content: "<h1>Welcome, Gopher!</h1>"},
+		{page: "about", title: "<title>Go Workshops</title>", content: "<p>Workshops about Go for Gopher.</p>"},
+		{page: "contact", title: "<title>Contact | Go Workshops</title>", content: "<p>Nothing here yet.</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			var b strings.Builder
+			if err := site.Render(&b, tt.page, map[string]string{"User": "Gopher"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, s := range []string{tt.title, tt.content} {
+				if !strings.Contains(b.String(), s) {
+					t.Errorf("Expected page %s to contain %q, got:\n%s", tt.page, s, b.String())
+				}
+			}
+		})
+	}
+}
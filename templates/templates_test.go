@@ -0,0 +1,177 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// text/template and html/template share the same template language:
+//
+//	{{.Field}}                  prints a field of the data
+//	{{range .Items}}...{{end}}  iterates over a slice, map, or channel, the dot becomes the element
+//	{{if .Ok}}...{{else}}...{{end}}
+//	{{len .Items}}              calls a function, arguments are separated by spaces
+//	{{.Name | printf "%q"}}     pipes a value into the last argument of the next function
+//	{{$.Currency}}              $ is the data passed to Execute, it's handy inside range
+//
+// Templates are parsed once and executed many times, parsing errors are found only at run time.
+
+// template.Must panics if parsing fails. It's meant for templates known at compile time,
+// a typo in them should stop the program at start up, like a failing regexp.MustCompile:
+var greetingTemplate = template.Must(template.New("greeting").Parse("Hello, {{.}}!\n"))
+
+func Example_mustParse() {
+	greetingTemplate.Execute(os.Stdout, "Gopher")
+	// Output:
+	// Hello, Gopher!
+}
+
+// Greeter renders greetings with templates configured by users of our service.
+// A template written by a user is an input like any other, it can be broken, and it must not crash the service.
+// Besides that, a field missing in the data is printed as "<no value>" by default, which ends up in emails sent to customers.
+// Fix NewGreeter to return errors for invalid templates, and make Greet fail when a key is missing.
+// Hint: take a look at Template.Option.
+
+// Greeter renders a greeting for a customer.
+type Greeter struct {
+	tmpl *template.Template
+}
+
+// NewGreeter parses the greeting template.
+func NewGreeter(text string) (*Greeter, error) {
+	return &Greeter{tmpl: template.Must(template.New("greeter").Parse(text))}, nil
+}
+
+// Greet renders the greeting with the data.
+func (g *Greeter) Greet(data map[string]string) (string, error) {
+	var b strings.Builder
+	if err := g.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func TestNewGreeterInvalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("Expected NewGreeter to return error, got panic: %v", r)
+		}
+	}()
+
+	if _, err := NewGreeter("Hello, {{.Name}"); err == nil {
+		t.Error("Expected error for invalid template, got nil")
+	}
+}
+
+func TestGreeterMissingKey(t *testing.T) {
+	g, err := NewGreeter("Hello, {{.Name}}! Your code is {{.Code}}.")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := g.Greet(map[string]string{"Name": "Ann", "Code": "WELCOME"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := "Hello, Ann! Your code is WELCOME."; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got, err := g.Greet(map[string]string{"Name": "Ann"}); err == nil {
+		t.Errorf("Expected error for missing key, got %q", got)
+	}
+}
+
+// The template language is small on purpose. Formatting that doesn't belong to the data is done by functions.
+// Custom functions are registered with Template.Funcs in a template.FuncMap.
+// A function can return a single value, or a value and an error, in which case the error stops the execution.
+//
+// RenderReceipt fails with "function "money" not defined". Fix it.
+
+// Item is a line of a receipt.
+type Item struct {
+	Name  string
+	Qty   int
+	Price int64
+}
+
+// Receipt is an order summary sent to a customer.
+type Receipt struct {
+	ID       string
+	Customer string
+	Currency string
+	Items    []Item
+}
+
+// Total returns the total price of the receipt in cents, methods can be called from templates like fields.
+func (r Receipt) Total() int64 {
+	var total int64
+	for _, it := range r.Items {
+		total += int64(it.Qty) * it.Price
+	}
+
+	return total
+}
+
+const receiptText = `Order {{.ID}} for {{.Customer}}
+{{range .Items}}- {{.Name}} x{{.Qty}}: {{money .Price $.Currency}}
+{{end}}Total for {{plural (len .Items) "item"}}: {{money .Total .Currency}}
+`
+
+var receiptFuncs = template.FuncMap{
+	"money": func(cents int64, currency string) string {
+		return fmt.Sprintf("%d.%02d %s", cents/100, cents%100, currency)
+	},
+	"plural": func(n int, word string) string {
+		if n == 1 {
+			return "1 " + word
+		}
+
+		return fmt.Sprintf("%d %ss", n, word)
+	},
+}
+
+// RenderReceipt renders the receipt as plain text.
+func RenderReceipt(r Receipt) (string, error) {
+	tmpl, err := template.New("receipt").Parse(receiptText)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl = tmpl.Funcs(receiptFuncs)
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func ExampleRenderReceipt() {
+	out, err := RenderReceipt(Receipt{
+		ID:       "1001",
+		Customer: "Ann",
+		Currency: "EUR",
+		Items: []Item{
+			{Name: "Gopher plush", Qty: 2, Price: 1250},
+			{Name: "Sticker pack", Qty: 1, Price: 399},
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Print(out)
+	// Output:
+	// Order 1001 for Ann
+	// - Gopher plush x2: 12.50 EUR
+	// - Sticker pack x1: 3.99 EUR
+	// Total for 2 items: 28.99 EUR
+}
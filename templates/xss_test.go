@@ -0,0 +1,77 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// text/template prints values as they are. That's fine for emails and config files,
+// but in a web page any text written by a user can become markup, a script, or a link:
+// it's cross-site scripting (XSS).
+//
+// html/template has the same API, but it understands HTML, CSS, JavaScript, and URLs,
+// and escapes every value according to the context where it's printed:
+// - in text and attribute values, < > & ' " are replaced with entities,
+// - in URL attributes, values are percent-encoded, and unsafe schemes like javascript: are replaced with #ZgotmplZ,
+// - inside <script>, values are encoded as JavaScript literals.
+//
+// Types like template.HTML and template.URL turn escaping off, use them only for values you trust.
+//
+// Fix RenderComment, so comments can't inject anything into the page.
+
+// Comment is a comment left by a visitor.
+type Comment struct {
+	Author  string
+	Website string
+	Body    string
+}
+
+var commentTemplate = template.Must(template.New("comment").Parse(
+	`<div class="comment"><a href="{{.Website}}" title="{{.Author}}">{{.Author}}</a><p>{{.Body}}</p></div>`,
+))
+
+// RenderComment renders the comment as HTML.
+func RenderComment(c Comment) (string, error) {
+	var b strings.Builder
+	if err := commentTemplate.Execute(&b, c); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func TestRenderCommentEscaping(t *testing.T) {
+	got, err := RenderComment(Comment{
+		Author:  `Eve" onmouseover="alert(1)`,
+		Website: "javascript:alert(2)",
+		Body:    "<script>alert(3)</script>",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, s := range []string{"<script>", `" onmouseover="`, `href="javascript:`} {
+		if strings.Contains(got, s) {
+			t.Errorf("Expected comment not to contain %s, got:\n%s", s, got)
+		}
+	}
+
+	for _, s := range []string{"&lt;script&gt;alert(3)&lt;/script&gt;", `href="#ZgotmplZ"`} {
+		if !strings.Contains(got, s) {
+			t.Errorf("Expected comment to contain %s, got:\n%s", s, got)
+		}
+	}
+}
+
+func TestRenderCommentPlain(t *testing.T) {
+	got, err := RenderComment(Comment{Author: "Ann", Website: "https://example.com/?a=1&b=2", Body: "Great workshop!"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `<div class="comment"><a href="https://example.com/?a=1&amp;b=2" title="Ann">Ann</a><p>Great workshop!</p></div>`
+	if got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,155 @@
+package testingtechniques
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+// 3. Advanced: writing a property.
+// Cursors of a paginated API encode the position of the last item of a page: its creation time and its ID.
+// EncodeCursor and DecodeCursor pass the example test, because example IDs look like order-1.
+//
+// Write cursorRoundTrip: the property that every cursor decodes back to itself. Draw the time and the ID from
+// generators wide enough to reach inputs the example doesn't: any int64, any string. TestCursorProperty
+// checks that your property catches broken codecs, then TestCursorRoundTrip runs it on the real one.
+// Fix the bug it finds.
+
+// ErrInvalidCursor is returned for cursors that can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a list sorted by creation time and ID.
+type Cursor struct {
+	CreatedAt int64
+	ID        string
+}
+
+// EncodeCursor returns the cursor as an opaque string for URLs.
+func EncodeCursor(c Cursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.CreatedAt, c.ID)))
+}
+
+// DecodeCursor returns the cursor encoded by EncodeCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 2 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	createdAt, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
+
+// cursorRoundTrip returns the property that cursors encoded with encode are decoded back by decode.
+func cursorRoundTrip(encode func(Cursor) string, decode func(string) (Cursor, error)) func(*rapid.T) {
+	return func(t *rapid.T) {
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := Cursor{CreatedAt: 1712345678, ID: "order-1"}
+
+	got, err := DecodeCursor(EncodeCursor(c))
+	if err != nil || got != c {
+		t.Errorf("Expected %v back, got %v, %v", c, got, err)
+	}
+
+	if _, err := DecodeCursor("not a cursor!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+// recorder is a rapid.TB that records failures instead of failing the test.
+type recorder struct {
+	rapid.TB
+	failed bool
+}
+
+func (r *recorder) Logf(string, ...any)   {}
+func (r *recorder) Log(...any)            {}
+func (r *recorder) Errorf(string, ...any) { r.failed = true }
+func (r *recorder) Error(...any)          { r.failed = true }
+func (r *recorder) Fatalf(string, ...any) { r.failed = true }
+func (r *recorder) Fatal(...any)          { r.failed = true }
+func (r *recorder) FailNow()              { r.failed = true }
+func (r *recorder) Fail()                 { r.failed = true }
+func (r *recorder) Failed() bool          { return r.failed }
+
+// falsified reports whether rapid finds an input that breaks the property.
+func falsified(t *testing.T, prop func(*rapid.T)) bool {
+	// Counterexamples of broken codecs aren't worth saving.
+	flag.Set("rapid.nofailfile", "true")
+	t.Cleanup(func() { flag.Set("rapid.nofailfile", "false") })
+
+	r := &recorder{TB: t}
+	rapid.Check(r, prop)
+
+	return r.failed
+}
+
+func TestCursorProperty(t *testing.T) {
+	encode := func(c Cursor) string {
+		return strconv.FormatInt(c.CreatedAt, 10) + "|" + c.ID
+	}
+
+	decode := func(s string) (Cursor, error) {
+		createdAt, id, _ := strings.Cut(s, "|")
+		n, err := strconv.ParseInt(createdAt, 10, 64)
+
+		return Cursor{CreatedAt: n, ID: id}, err
+	}
+
+	if falsified(t, cursorRoundTrip(encode, decode)) {
+		t.Fatal("Expected the property to hold for a correct codec")
+	}
+
+	broken := map[string]struct {
+		encode func(Cursor) string
+		decode func(string) (Cursor, error)
+	}{
+		"negative times": {encode, func(s string) (Cursor, error) {
+			c, err := decode(s)
+			if c.CreatedAt < 0 {
+				c.CreatedAt = 0
+			}
+
+			return c, err
+		}},
+		"precise times":   {func(c Cursor) string { return encode(Cursor{c.CreatedAt / 1000 * 1000, c.ID}) }, decode},
+		"IDs with spaces": {func(c Cursor) string { return encode(Cursor{c.CreatedAt, strings.TrimSpace(c.ID)}) }, decode},
+		"long IDs":        {func(c Cursor) string { return encode(Cursor{c.CreatedAt, c.ID[:min(len(c.ID), 8)]}) }, decode},
+		"IDs with dashes": {encode, func(s string) (Cursor, error) {
+			c, err := decode(s)
+			if strings.ContainsAny(c.ID, "-_") {
+				return Cursor{}, ErrInvalidCursor
+			}
+
+			return c, err
+		}},
+	}
+
+	for name, codec := range broken {
+		if !falsified(t, cursorRoundTrip(codec.encode, codec.decode)) {
+			t.Errorf("Expected the property to catch a codec that breaks %s", name)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	rapid.Check(t, cursorRoundTrip(EncodeCursor, DecodeCursor))
+}
@@ -0,0 +1,90 @@
+package testingtechniques
+
+import (
+	"errors"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+// Example tests check the cases their author thought of, and bugs live in the cases nobody thought of.
+// A property-based test states what must hold for every input, and a library generates inputs to break it:
+// hundreds of random ones, biased towards edge cases like 0, -1, and empty strings. When an input breaks
+// the property, the library shrinks it to the smallest input that still does, so a failure reads like
+// an example test: Divide(-1, 2). testing/quick in the standard library generates inputs but can't shrink them,
+// pgregory.net/rapid can.
+//
+// Good properties don't repeat the implementation, they describe it from another side:
+// - invariants: the result of rounding is at most half a step away from the exact value,
+// - symmetries: negating the input negates the output, shuffling the input doesn't change the result,
+// - round trips: decoding what was encoded gives back the original,
+// - a slow but obviously correct implementation that gives the same results.
+//
+// rapid saves inputs that broke a property to testdata/rapid, and replays them before random ones on the next run.
+
+// 1. Properties of arithmetic.
+// Divide splits an amount into shares rounded half away from zero: 7/2 is 4, and -7/2 is -4.
+// Its example tests pass, TestDivideProperties doesn't. Read the counterexample it prints, and fix Divide.
+
+// ErrDivisionByZero is returned by Divide for a zero divisor.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Divide returns a/b rounded half away from zero.
+func Divide(a, b int) (int, error) {
+	if b == 0 {
+		return 0, ErrDivisionByZero
+	}
+
+	return (a + b/2) / b, nil
+}
+
+func TestDivide(t *testing.T) {
+	for _, tt := range []struct {
+		a, b, expected int
+	}{
+		{7, 2, 4},
+		{10, 3, 3},
+		{5, 5, 1},
+		{1, 3, 0},
+		{0, 7, 0},
+		{100, 8, 13},
+	} {
+		if got, err := Divide(tt.a, tt.b); err != nil || got != tt.expected {
+			t.Errorf("Expected %d/%d to be %d, got %d, %v", tt.a, tt.b, tt.expected, got, err)
+		}
+	}
+
+	if _, err := Divide(1, 0); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("Expected ErrDivisionByZero, got %v", err)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+func TestDivideProperties(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		a := rapid.IntRange(-1_000_000, 1_000_000).Draw(t, "a")
+		b := rapid.IntRange(-1000, 1000).Filter(func(b int) bool { return b != 0 }).Draw(t, "b")
+
+		q, err := Divide(a, b)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// The quotient is the nearest integer: it's at most half of the divisor away from a.
+		if r := abs(a - q*b); 2*r > abs(b) {
+			t.Fatalf("Expected Divide(%d, %d) to be the nearest integer, got %d, off by %d/%d", a, b, q, r, abs(b))
+		}
+
+		// Rounding away from zero is symmetric.
+		if neg, _ := Divide(-a, b); neg != -q {
+			t.Fatalf("Expected Divide(%d, %d) to be %d, the negated Divide(%d, %d), got %d", -a, b, -q, a, b, neg)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package testingtechniques
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 6. Test isolation: files.
+// A test that writes files to a fixed path shares them with every test that uses the path, with parallel
+// subtests of its own, and with its next run, since nothing removes them. t.TempDir creates a new directory
+// on every call and removes it when the test finishes, so each test starts from an empty directory.
+//
+// SaveDraft and Drafts keep drafts of documents as files of a directory. The subtests of TestDrafts share one
+// directory under os.TempDir and see drafts of each other. Give each of them a directory of its own.
+
+// SaveDraft writes the text of the draft to the directory as <name>.txt.
+func SaveDraft(dir, name, text string) error {
+	return os.WriteFile(filepath.Join(dir, name+".txt"), []byte(text), 0o644)
+}
+
+// Drafts returns names of drafts in the directory, sorted.
+func Drafts(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".txt"); ok && e.Type().IsRegular() {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+// draftsDir returns the directory the test keeps drafts in.
+func draftsDir(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(os.TempDir(), "testingtechniques-drafts")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create drafts directory: %v", err)
+	}
+
+	return dir
+}
+
+func TestDrafts(t *testing.T) {
+	t.Parallel()
+
+	for _, names := range [][]string{{"intro"}, {"intro", "outline"}, {"summary"}} {
+		t.Run(strings.Join(names, ","), func(t *testing.T) {
+			t.Parallel()
+
+			dir := draftsDir(t)
+
+			for _, name := range names {
+				if err := SaveDraft(dir, name, "draft of "+name); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			got, err := Drafts(dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, names) {
+				t.Errorf("Expected drafts %q, got %q", names, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package testingtechniques
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// 7. Test isolation: environment variables.
+// The environment belongs to the process: a test that sets a variable sets it for every test running at
+// the same time, and for every test after it. t.Setenv sets a variable and restores it when the test finishes.
+// Restoring isn't enough while other tests run, so t.Setenv panics in a test that called t.Parallel,
+// or whose parent did, and the panic takes down the rest of the tests.
+// Tests of code that reads the environment either give up t.Parallel, or don't touch the environment at all:
+// the code takes a lookup function, os.LookupEnv in the program, and a lookup in a map in tests.
+//
+// The tests of LoadSettings set variables with os.Setenv and never restore them. Make them independent,
+// either way works.
+
+// Settings configure a shop.
+type Settings struct {
+	Currency string
+	PageSize int
+}
+
+// LoadSettings reads settings from SHOP_CURRENCY and SHOP_PAGE_SIZE, they default to USD and 20.
+func LoadSettings() (Settings, error) {
+	s := Settings{Currency: "USD", PageSize: 20}
+
+	if v, ok := os.LookupEnv("SHOP_CURRENCY"); ok {
+		s.Currency = v
+	}
+
+	if v, ok := os.LookupEnv("SHOP_PAGE_SIZE"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return Settings{}, fmt.Errorf("invalid SHOP_PAGE_SIZE %q", v)
+		}
+
+		s.PageSize = n
+	}
+
+	return s, nil
+}
+
+func TestSettingsDefaults(t *testing.T) {
+	t.Parallel()
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := (Settings{Currency: "USD", PageSize: 20}); s != expected {
+		t.Errorf("Expected %+v, got %+v", expected, s)
+	}
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	t.Parallel()
+
+	os.Setenv("SHOP_CURRENCY", "EUR")
+	os.Setenv("SHOP_PAGE_SIZE", "50")
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if expected := (Settings{Currency: "EUR", PageSize: 50}); s != expected {
+		t.Errorf("Expected %+v, got %+v", expected, s)
+	}
+}
+
+func TestSettingsInvalidPageSize(t *testing.T) {
+	t.Parallel()
+
+	os.Setenv("SHOP_PAGE_SIZE", "many")
+
+	if _, err := LoadSettings(); err == nil {
+		t.Error("Expected an error for an invalid page size")
+	}
+}
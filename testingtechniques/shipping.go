@@ -0,0 +1,59 @@
+// Package testingtechniques is a workshop on testing techniques beyond example tests.
+// Most of its code lives in test files, code of the coverage exercise doesn't: go test measures coverage
+// of non-test files only.
+package testingtechniques
+
+import "errors"
+
+// ShippingCost is the code under test of section 4, it is correct, the exercise is its test in shipping_test.go.
+
+// Zone is a shipping zone.
+type Zone string
+
+const (
+	Domestic Zone = "domestic"
+	Europe   Zone = "europe"
+	World    Zone = "world"
+)
+
+const (
+	// MaxWeight is the heaviest parcel in grams.
+	MaxWeight = 30_000
+
+	// FreeShippingFrom is the order total in cents from which domestic shipping is free.
+	FreeShippingFrom = 50_00
+)
+
+var (
+	ErrWeight      = errors.New("weight out of range")
+	ErrUnknownZone = errors.New("unknown zone")
+)
+
+// ShippingCost returns the cost in cents of shipping a parcel of the weight in grams to the zone.
+// The first kilogram is included in the base price, every started kilogram after it is charged in full.
+func ShippingCost(zone Zone, grams int, totalCents int64) (int64, error) {
+	if grams <= 0 || grams > MaxWeight {
+		return 0, ErrWeight
+	}
+
+	var base, perKg int64
+
+	switch zone {
+	case Domestic:
+		if totalCents >= FreeShippingFrom {
+			return 0, nil
+		}
+
+		base, perKg = 4_90, 50
+	case Europe:
+		base, perKg = 9_90, 1_50
+	case World:
+		base, perKg = 19_90, 4_00
+	default:
+		return 0, ErrUnknownZone
+	}
+
+	kg := int64((grams + 999) / 1000)
+
+	return base + perKg*(kg-1), nil
+}
@@ -0,0 +1,43 @@
+package testingtechniques
+
+import (
+	"errors"
+	"testing"
+)
+
+// 4. Coverage.
+// Coverage shows code that no test ran: go test -cover prints the percentage of statements, -coverprofile
+// writes which blocks ran, and go tool cover -html colors them. It tells nothing about code that ran:
+// a test that calls a function and checks nothing covers it completely.
+// So coverage is a floor, not a goal. It finds cases nobody tested, workshop mutate finds checks nobody wrote.
+//
+// This exercise is about writing a test, not fixing code: ShippingCost in shipping.go is correct.
+// TestShippingCost passes, but workshop verify requires it to cover all of shipping.go and lists lines it never runs.
+// Add cases until nothing is left, and check results and errors of each of them.
+// Boundaries deserve their own cases: a parcel of exactly 1 kg, and an order of exactly FreeShippingFrom.
+
+func TestShippingCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		zone     Zone
+		grams    int
+		total    int64
+		expected int64
+		err      error
+	}{
+		{"domestic", Domestic, 1500, 20_00, 5_40, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShippingCost(tt.zone, tt.grams, tt.total)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			if got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package testingtechniques
+
+import (
+	"errors"
+	"testing"
+
+	"pgregory.net/rapid"
+)
+
+// 2. Invariants under permutation.
+// The order of items in a cart means nothing, so it must not change whether the cart is valid.
+// Validate passes its example tests, and the property shuffles carts to find where the order leaks in.
+// Generators compose: rapid.Custom builds an Item from drawn fields, rapid.SliceOf builds a cart from items,
+// and rapid.Permutation shuffles it. SKUs come from a short list, so duplicates are likely.
+//
+// Fix Validate. Which error a cart with several problems gets may depend on the order, whether it gets one may not.
+
+// MaxTotal is the largest total of a cart in cents.
+const MaxTotal = 1_000_000
+
+var (
+	ErrEmptyCart     = errors.New("cart is empty")
+	ErrDuplicateItem = errors.New("duplicate item")
+	ErrQuantity      = errors.New("quantity out of range")
+	ErrTotalTooHigh  = errors.New("total too high")
+)
+
+// Item is a line of a cart, discounts are items with negative prices.
+type Item struct {
+	SKU        string
+	Quantity   int
+	PriceCents int64
+}
+
+// Validate checks the cart: it isn't empty, every SKU is in it once, quantities are 1 to 99,
+// and the total is at most MaxTotal.
+func Validate(items []Item) error {
+	if len(items) == 0 {
+		return ErrEmptyCart
+	}
+
+	var total int64
+
+	for i, item := range items {
+		if i > 0 && item.SKU == items[i-1].SKU {
+			return ErrDuplicateItem
+		}
+
+		if item.Quantity < 1 || item.Quantity > 99 {
+			return ErrQuantity
+		}
+
+		total += int64(item.Quantity) * item.PriceCents
+		if total > MaxTotal {
+			return ErrTotalTooHigh
+		}
+	}
+
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		items    []Item
+		expected error
+	}{
+		{"valid", []Item{{"book", 2, 1500}, {"pen", 10, 120}}, nil},
+		{"discount", []Item{{"book", 1, 1500}, {"coupon", 1, -500}}, nil},
+		{"empty", nil, ErrEmptyCart},
+		{"duplicate", []Item{{"book", 1, 1500}, {"book", 2, 1500}}, ErrDuplicateItem},
+		{"zero quantity", []Item{{"book", 0, 1500}}, ErrQuantity},
+		{"too many", []Item{{"book", 100, 1500}}, ErrQuantity},
+		{"too expensive", []Item{{"laptop", 5, 250_000}}, ErrTotalTooHigh},
+	} {
+		if err := Validate(tt.items); !errors.Is(err, tt.expected) {
+			t.Errorf("Expected %s cart to get %v, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+var itemGen = rapid.Custom(func(t *rapid.T) Item {
+	return Item{
+		SKU:        rapid.SampledFrom([]string{"book", "pen", "mug", "coupon", "laptop"}).Draw(t, "sku"),
+		Quantity:   rapid.IntRange(0, 100).Draw(t, "quantity"),
+		PriceCents: rapid.Int64Range(-100_000, 400_000).Draw(t, "price"),
+	}
+})
+
+func TestValidateProperties(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		items := rapid.SliceOfN(itemGen, 1, 5).Draw(t, "items")
+		shuffled := rapid.Permutation(items).Draw(t, "shuffled")
+
+		err, shuffledErr := Validate(items), Validate(shuffled)
+		if (err == nil) != (shuffledErr == nil) {
+			t.Fatalf("Expected the same validity in any order, got %v for %v and %v for %v", err, items, shuffledErr, shuffled)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package testingtechniques
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// 5. Test isolation: package-level state.
+// t.Parallel runs a test at the same time as other parallel tests. It makes a suite faster, and it reveals tests
+// that pass only in the order they were written. What breaks them is state they share: package-level variables,
+// files, and environment variables. The runner checks the isolation exercises harshly: -count 2 runs every test
+// twice in one process, -shuffle on changes their order, and -parallel 8 runs up to 8 parallel tests at once.
+// Run them the same way while you work on them:
+//
+//	go test -run 'TestRecordView|TestTopPages' -count 2 -shuffle on -parallel 8 ./testingtechniques
+//
+// RecordView and TopPages count views of pages in DefaultViews, like http.Handle registers handlers in
+// http.DefaultServeMux. That's convenient for the program, and every test that calls them adds to the same counts.
+// The tests pass once, not twice. Give each test a ViewCounter of its own, and keep t.Parallel.
+
+// ViewCounter counts views of pages, the zero value is ready to use and it's safe for concurrent use.
+type ViewCounter struct {
+	mu    sync.Mutex
+	views map[string]int
+}
+
+// DefaultViews is the counter used by RecordView and TopPages.
+var DefaultViews = &ViewCounter{}
+
+// Record counts a view of the page and returns the number of its views.
+func (c *ViewCounter) Record(page string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.views == nil {
+		c.views = make(map[string]int)
+	}
+
+	c.views[page]++
+
+	return c.views[page]
+}
+
+// Top returns up to n most viewed pages, pages with the same number of views are sorted by name.
+func (c *ViewCounter) Top(n int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pages := slices.SortedFunc(maps.Keys(c.views), func(a, b string) int {
+		return cmp.Or(cmp.Compare(c.views[b], c.views[a]), cmp.Compare(a, b))
+	})
+
+	return pages[:min(n, len(pages))]
+}
+
+// RecordView counts a view of the page in DefaultViews.
+func RecordView(page string) int {
+	return DefaultViews.Record(page)
+}
+
+// TopPages returns up to n most viewed pages of DefaultViews.
+func TopPages(n int) []string {
+	return DefaultViews.Top(n)
+}
+
+func TestRecordView(t *testing.T) {
+	t.Parallel()
+
+	RecordView("home")
+
+	if got := RecordView("home"); got != 2 {
+		t.Errorf("Expected 2 views of home, got %d", got)
+	}
+}
+
+func TestTopPages(t *testing.T) {
+	t.Parallel()
+
+	RecordView("home")
+	RecordView("pricing")
+	RecordView("pricing")
+	RecordView("blog")
+
+	expected := []string{"pricing", "blog"}
+	if got := TopPages(2); !slices.Equal(got, expected) {
+		t.Errorf("Expected top pages %q, got %q", expected, got)
+	}
+}
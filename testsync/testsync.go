@@ -0,0 +1,225 @@
+// Package testsync contains primitives for deterministic tests of concurrent code.
+// Instead of sleeping and hoping the other goroutine got far enough, a test waits for the exact moment it needs:
+// goroutines meeting at a Barrier, a goroutine reaching a step of a StepController, or a goroutine blocking
+// inside a function, so pass or fail reflects the logic of the code, not the luck of the scheduler.
+package testsync
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ksysoev/go-workshops/testutil"
+)
+
+// DefaultTimeout is how long StepController waits for a goroutine to reach a step.
+// It only expires when the code under test is broken, so it's generous.
+const DefaultTimeout = time.Second
+
+const (
+	minPoll = 10 * time.Microsecond
+	maxPoll = 5 * time.Millisecond
+)
+
+// Barrier blocks goroutines until n of them are waiting, then releases all of them at once.
+// It's reusable: after it opens, the next n goroutines meet again.
+type Barrier struct {
+	n       int
+	mu      sync.Mutex
+	arrived int
+	open    chan struct{}
+}
+
+// NewBarrier creates a barrier for n goroutines.
+func NewBarrier(n int) *Barrier {
+	return &Barrier{n: n}
+}
+
+// Wait blocks until n goroutines are waiting, or until timeout expires. It reports whether the barrier opened.
+// A goroutine that gives up doesn't count anymore, so a barrier that never fills tells that fewer than n goroutines
+// could run at the same time.
+func (b *Barrier) Wait(timeout time.Duration) bool {
+	b.mu.Lock()
+
+	if b.open == nil {
+		b.open = make(chan struct{})
+	}
+
+	open := b.open
+
+	b.arrived++
+	if b.arrived == b.n {
+		close(open)
+		b.open, b.arrived = nil, 0
+		b.mu.Unlock()
+
+		return true
+	}
+
+	b.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-open:
+		return true
+	case <-timer.C:
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The barrier could open at the same moment.
+	select {
+	case <-open:
+		return true
+	default:
+		b.arrived--
+		return false
+	}
+}
+
+// StepController releases goroutines of the code under test in the order the test chooses.
+// The code calls Step at interesting points and blocks there, the test waits until a step is reached with Await
+// and lets the goroutine continue with Release:
+//
+//	steps := testsync.NewStepController(t)
+//	srv := NewServer(func(req string) string {
+//		steps.Step("handle")
+//		return req
+//	})
+//
+//	go srv.Call("first")
+//
+//	steps.Await("handle")
+//	// The server is busy with the first call now.
+//	steps.Release("handle")
+//
+// All steps are released when the test finishes, so a failed test doesn't leave goroutines blocked forever.
+type StepController struct {
+	t     testing.TB
+	mu    sync.Mutex
+	steps map[string]*step
+}
+
+type step struct {
+	reached     chan struct{}
+	released    chan struct{}
+	reachOnce   sync.Once
+	releaseOnce sync.Once
+}
+
+// NewStepController creates a controller for the test.
+func NewStepController(t testing.TB) *StepController {
+	c := &StepController{t: t, steps: make(map[string]*step)}
+
+	t.Cleanup(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for _, s := range c.steps {
+			s.release()
+		}
+	})
+
+	return c
+}
+
+// Step marks the step as reached and blocks until the test releases it. Many goroutines can wait at the same step,
+// releasing it lets all of them continue, and a released step doesn't block anymore.
+func (c *StepController) Step(name string) {
+	s := c.step(name)
+
+	s.reachOnce.Do(func() { close(s.reached) })
+	<-s.released
+}
+
+// Reached returns a channel that is closed when a goroutine reaches the step, it's handy in select statements.
+func (c *StepController) Reached(name string) <-chan struct{} {
+	return c.step(name).reached
+}
+
+// Await blocks until a goroutine reaches the step, it fails the test if none does within DefaultTimeout.
+// It must be called from the goroutine running the test.
+func (c *StepController) Await(name string) {
+	c.t.Helper()
+
+	timer := time.NewTimer(DefaultTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.Reached(name):
+	case <-timer.C:
+		c.t.Fatalf("Expected step %q to be reached in %s", name, DefaultTimeout)
+	}
+}
+
+// Release lets goroutines blocked at the step continue.
+func (c *StepController) Release(name string) {
+	c.step(name).release()
+}
+
+// Advance releases the step and waits until the next one is reached.
+func (c *StepController) Advance(name, next string) {
+	c.t.Helper()
+
+	c.Release(name)
+	c.Await(next)
+}
+
+func (c *StepController) step(name string) *step {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.steps[name]
+	if !ok {
+		s = &step{reached: make(chan struct{}), released: make(chan struct{})}
+		c.steps[name] = s
+	}
+
+	return s
+}
+
+func (s *step) release() {
+	s.releaseOnce.Do(func() { close(s.released) })
+}
+
+// WaitUntil polls cond until it returns true or timeout expires, and reports whether it returned true.
+// Polling starts fast and backs off, so waiting for a quick condition doesn't take long.
+func WaitUntil(cond func() bool, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	poll := minPoll
+
+	for {
+		if cond() {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(poll)
+		poll = min(2*poll, maxPoll)
+	}
+}
+
+// Blocked returns the number of goroutines blocked on a mutex or a channel inside the function,
+// named the way stack traces name it, like "(*Weighted).Acquire" or "(*Server[...]).Call".
+// Together with WaitUntil it tells that a goroutine is waiting, like a caller queued behind a busy server:
+//
+//	testsync.WaitUntil(func() bool { return testsync.Blocked("(*Weighted).Acquire") == 1 }, time.Second)
+func Blocked(fn string) int {
+	frame := "." + fn + "("
+	n := 0
+
+	for _, g := range testutil.ParseGoroutines(testutil.DumpStacks()) {
+		if g.Blocked() && strings.Contains(g.Stack, frame) {
+			n++
+		}
+	}
+
+	return n
+}
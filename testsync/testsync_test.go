@@ -0,0 +1,157 @@
+package testsync
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBarrier(t *testing.T) {
+	b := NewBarrier(3)
+	met := atomic.Int32{}
+	wg := sync.WaitGroup{}
+
+	// The barrier is reusable, two rounds of 3 goroutines meet at it.
+	for range 6 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if b.Wait(time.Second) {
+				met.Add(1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := met.Load(); n != 6 {
+		t.Errorf("Expected all 6 goroutines to meet, got %d", n)
+	}
+}
+
+func TestBarrierTimeout(t *testing.T) {
+	b := NewBarrier(2)
+
+	if b.Wait(time.Millisecond) {
+		t.Fatal("Expected a lonely goroutine to give up")
+	}
+
+	// The goroutine that gave up doesn't count.
+	opened := make(chan bool)
+
+	go func() {
+		opened <- b.Wait(time.Second)
+	}()
+
+	if !b.Wait(time.Second) || !<-opened {
+		t.Error("Expected two goroutines to meet after a timeout")
+	}
+}
+
+func TestStepController(t *testing.T) {
+	steps := NewStepController(t)
+	order := make(chan string, 2)
+
+	go func() {
+		steps.Step("first")
+		order <- "first"
+		steps.Step("second")
+		order <- "second"
+	}()
+
+	steps.Await("first")
+
+	select {
+	case s := <-order:
+		t.Fatalf("Expected the goroutine to wait at the first step, got %s", s)
+	default:
+	}
+
+	steps.Advance("first", "second")
+
+	if s := <-order; s != "first" {
+		t.Errorf("Expected first to be released, got %s", s)
+	}
+
+	steps.Release("second")
+
+	if s := <-order; s != "second" {
+		t.Errorf("Expected second to be released, got %s", s)
+	}
+
+	// A released step doesn't block anymore.
+	steps.Step("first")
+}
+
+func TestStepControllerCleanup(t *testing.T) {
+	done := make(chan struct{})
+
+	t.Run("unreleased", func(t *testing.T) {
+		steps := NewStepController(t)
+
+		go func() {
+			steps.Step("never")
+			close(done)
+		}()
+
+		steps.Await("never")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected steps to be released when the test finishes")
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	n := atomic.Int32{}
+
+	go func() {
+		for range 3 {
+			n.Add(1)
+		}
+	}()
+
+	if !WaitUntil(func() bool { return n.Load() == 3 }, time.Second) {
+		t.Error("Expected the condition to become true")
+	}
+
+	if WaitUntil(func() bool { return false }, time.Millisecond) {
+		t.Error("Expected WaitUntil to give up after the timeout")
+	}
+}
+
+type queue struct {
+	items chan int
+}
+
+func (q *queue) Pop() int {
+	return <-q.items
+}
+
+func TestBlocked(t *testing.T) {
+	q := &queue{items: make(chan int)}
+
+	for range 2 {
+		go q.Pop()
+	}
+
+	if !WaitUntil(func() bool { return Blocked("(*queue).Pop") == 2 }, time.Second) {
+		t.Fatalf("Expected 2 goroutines blocked in Pop, got %d", Blocked("(*queue).Pop"))
+	}
+
+	q.items <- 1
+	q.items <- 2
+
+	if !WaitUntil(func() bool { return Blocked("(*queue).Pop") == 0 }, time.Second) {
+		t.Errorf("Expected no goroutines blocked in Pop, got %d", Blocked("(*queue).Pop"))
+	}
+
+	if n := Blocked("(*queue).Push"); n != 0 {
+		t.Errorf("Expected no goroutines blocked in a function nobody calls, got %d", n)
+	}
+}
@@ -0,0 +1,175 @@
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// WithDeadlockReport starts a watchdog for the test and returns a channel that is closed when timeout expires.
+// Before closing the channel, the watchdog fails the test with a report of all goroutines
+// blocked on mutexes or channels, so it's easy to see the lock cycle.
+// The test is expected to stop waiting when the channel is closed, for example:
+//
+//	deadlock := testutil.WithDeadlockReport(t, time.Second)
+//
+//	select {
+//	case res := <-results:
+//	case <-deadlock:
+//		t.FailNow()
+//	}
+func WithDeadlockReport(t testing.TB, timeout time.Duration) <-chan struct{} {
+	t.Helper()
+
+	expired := make(chan struct{})
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+			t.Error(DeadlockReport(timeout, DumpStacks()))
+			close(expired)
+		}
+	}()
+
+	t.Cleanup(func() {
+		close(stop)
+		wg.Wait()
+	})
+
+	return expired
+}
+
+// Goroutine is a goroutine parsed from the stack dump.
+type Goroutine struct {
+	ID    string
+	State string
+	Stack string
+
+	// Location is the first frame outside of the standard library, where the goroutine got stuck.
+	Location string
+}
+
+// Blocked reports whether the goroutine is waiting on a mutex or a channel.
+func (g Goroutine) Blocked() bool {
+	for _, prefix := range []string{"chan ", "select", "sync.", "semacquire"} {
+		if strings.HasPrefix(g.State, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeadlockReport builds a report from the goroutine stack dump produced by runtime.Stack.
+func DeadlockReport(timeout time.Duration, stacks []byte) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Deadlock suspected: test did not finish in %s\n\nBlocked goroutines:\n", timeout)
+
+	blocked := 0
+
+	for _, g := range ParseGoroutines(stacks) {
+		if !g.Blocked() || g.Location == "" {
+			continue
+		}
+
+		blocked++
+
+		fmt.Fprintf(&b, "  goroutine %s [%s] at %s\n", g.ID, g.State, g.Location)
+	}
+
+	if blocked == 0 {
+		b.WriteString("  none found, the test could be just slow\n")
+	}
+
+	b.WriteString("\nAll goroutines:\n\n")
+	b.Write(stacks)
+
+	return b.String()
+}
+
+// ParseGoroutines splits the stack dump produced by runtime.Stack into goroutines.
+func ParseGoroutines(stacks []byte) []Goroutine {
+	var goroutines []Goroutine
+
+	for _, block := range bytes.Split(bytes.TrimSpace(stacks), []byte("\n\n")) {
+		lines := strings.Split(string(block), "\n")
+
+		header, ok := strings.CutPrefix(lines[0], "goroutine ")
+		if !ok {
+			continue
+		}
+
+		id, state, _ := strings.Cut(header, " ")
+		state = strings.TrimSuffix(strings.TrimPrefix(state, "["), "]:")
+		state, _, _ = strings.Cut(state, ",")
+
+		goroutines = append(goroutines, Goroutine{
+			ID:       id,
+			State:    state,
+			Stack:    string(block),
+			Location: location(lines[1:]),
+		})
+	}
+
+	return goroutines
+}
+
+// location returns the first function of the stack that doesn't belong to the runtime or the standard library.
+func location(lines []string) string {
+	for i := 0; i+1 < len(lines); i += 2 {
+		fn := lines[i]
+		file, _, _ := strings.Cut(strings.TrimSpace(lines[i+1]), " ")
+
+		if strings.HasPrefix(fn, "created by ") || isInternalFrame(fn) || strings.HasPrefix(file, "_testmain.go") {
+			continue
+		}
+
+		if idx := strings.LastIndex(fn, "("); idx > 0 {
+			fn = fn[:idx]
+		}
+
+		return fn + " (" + file + ")"
+	}
+
+	return ""
+}
+
+func isInternalFrame(fn string) bool {
+	for _, prefix := range []string{"runtime.", "sync.", "internal/", "testing.", "time."} {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DumpStacks returns stack traces of all goroutines, ParseGoroutines parses them.
+func DumpStacks() []byte {
+	buf := make([]byte, 1<<16)
+
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,138 @@
+package testutil
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const sampleStacks = `goroutine 21 [running]:
+github.com/ksysoev/go-workshops/testutil.DumpStacks()
+	/src/testutil/deadlock.go:150 +0x45
+created by github.com/ksysoev/go-workshops/testutil.WithDeadlockReport in goroutine 20
+	/src/testutil/deadlock.go:35 +0x11a
+
+goroutine 20 [select]:
+github.com/ksysoev/go-workshops/concurrency.TestDeadlock(0xc000007a00)
+	/src/concurrency/concurrency_test.go:145 +0x2c5
+testing.tRunner(0xc000007a00, 0x5d9c48)
+	/usr/local/go/src/testing/testing.go:1689 +0xfb
+
+goroutine 22 [sync.Mutex.Lock, 1 minutes]:
+sync.runtime_SemacquireMutex(0xc00001c0f4?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/sema.go:95 +0x25
+sync.(*Mutex).Lock(...)
+	/usr/local/go/src/sync/mutex.go:90
+github.com/ksysoev/go-workshops/concurrency.EatPasta(0xc000007a00, {0x5b2a41, 0x5}, 0xc00002e0c0, {0xc000012090, 0x2, 0x2})
+	/src/concurrency/concurrency_test.go:123 +0x10b
+created by github.com/ksysoev/go-workshops/concurrency.TestDeadlock in goroutine 20
+	/src/concurrency/concurrency_test.go:140 +0x1a5
+
+goroutine 23 [sleep]:
+time.Sleep(0x3b9aca00)
+	/usr/local/go/src/runtime/time.go:195 +0x125
+github.com/ksysoev/go-workshops/concurrency.worker()
+	/src/concurrency/concurrency_test.go:200 +0x1a
+`
+
+func TestParseGoroutines(t *testing.T) {
+	goroutines := ParseGoroutines([]byte(sampleStacks))
+
+	expected := []Goroutine{
+		{ID: "21", State: "running", Location: "github.com/ksysoev/go-workshops/testutil.DumpStacks (/src/testutil/deadlock.go:150)"},
+		{ID: "20", State: "select", Location: "github.com/ksysoev/go-workshops/concurrency.TestDeadlock (/src/concurrency/concurrency_test.go:145)"},
+		{ID: "22", State: "sync.Mutex.Lock", Location: "github.com/ksysoev/go-workshops/concurrency.EatPasta (/src/concurrency/concurrency_test.go:123)"},
+		{ID: "23", State: "sleep", Location: "github.com/ksysoev/go-workshops/concurrency.worker (/src/concurrency/concurrency_test.go:200)"},
+	}
+
+	if len(goroutines) != len(expected) {
+		t.Fatalf("Expected %d goroutines, got %d", len(expected), len(goroutines))
+	}
+
+	for i, g := range goroutines {
+		if g.ID != expected[i].ID || g.State != expected[i].State || g.Location != expected[i].Location {
+			t.Errorf("Expected goroutine %+v, got %+v", expected[i], Goroutine{ID: g.ID, State: g.State, Location: g.Location})
+		}
+	}
+
+	blocked := []bool{false, true, true, false}
+	for i, g := range goroutines {
+		if g.Blocked() != blocked[i] {
+			t.Errorf("Expected goroutine %s blocked to be %t", g.ID, blocked[i])
+		}
+	}
+}
+
+func TestDeadlockReport(t *testing.T) {
+	report := DeadlockReport(time.Second, []byte(sampleStacks))
+
+	for _, line := range []string{
+		"Deadlock suspected: test did not finish in 1s",
+		"goroutine 22 [sync.Mutex.Lock] at github.com/ksysoev/go-workshops/concurrency.EatPasta (/src/concurrency/concurrency_test.go:123)",
+		"goroutine 20 [select] at github.com/ksysoev/go-workshops/concurrency.TestDeadlock",
+		sampleStacks,
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("Expected report to contain %q, got:\n%s", line, report)
+		}
+	}
+
+	if strings.Contains(report, "goroutine 23 [sleep] at") {
+		t.Errorf("Expected sleeping goroutine not to be reported as blocked, got:\n%s", report)
+	}
+}
+
+func TestWithDeadlockReport(t *testing.T) {
+	tb := &fakeTB{}
+
+	a, b := &sync.Mutex{}, &sync.Mutex{}
+	locked := sync.WaitGroup{}
+	locked.Add(2)
+
+	lockBoth := func(first, second *sync.Mutex) {
+		first.Lock()
+		locked.Done()
+		locked.Wait()
+		second.Lock()
+	}
+
+	go lockBoth(a, b)
+	go lockBoth(b, a)
+
+	select {
+	case <-WithDeadlockReport(tb, 10*time.Millisecond):
+	case <-time.After(time.Second):
+		t.Fatal("Expected watchdog to expire")
+	}
+
+	tb.runCleanups()
+
+	errs := tb.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Expected watchdog to report a single error, got %d", len(errs))
+	}
+
+	if !strings.Contains(errs[0], "[sync.Mutex.Lock] at github.com/ksysoev/go-workshops/testutil.TestWithDeadlockReport.func1") {
+		t.Errorf("Expected report to point to the deadlocked goroutine, got:\n%s", errs[0])
+	}
+}
+
+func TestWithDeadlockReportStopped(t *testing.T) {
+	tb := &fakeTB{}
+
+	expired := WithDeadlockReport(tb, 10*time.Millisecond)
+	tb.runCleanups()
+
+	time.Sleep(20 * time.Millisecond)
+
+	select {
+	case <-expired:
+		t.Error("Expected watchdog to be stopped by cleanup")
+	default:
+	}
+
+	if len(tb.Errors()) != 0 {
+		t.Errorf("Expected no errors, got %v", tb.Errors())
+	}
+}
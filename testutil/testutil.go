@@ -0,0 +1,38 @@
+// Package testutil contains helpers shared by workshop exercises.
+package testutil
+
+import (
+	"os"
+	"testing"
+)
+
+// SkipRaceEnv is the environment variable that turns RequireRaceDetector failures into skips.
+// It's useful on platforms where the race detector is not supported.
+const SkipRaceEnv = "WORKSHOP_SKIP_RACE"
+
+// RequireRaceDetector fails the test if it's not running with the race detector enabled.
+// Some exercises only reveal bugs under -race, without it they could pass by accident.
+func RequireRaceDetector(t testing.TB) {
+	t.Helper()
+
+	if raceEnabled {
+		return
+	}
+
+	if os.Getenv(SkipRaceEnv) != "" {
+		t.Skip("Skipping test that requires the race detector, because " + SkipRaceEnv + " is set")
+	}
+
+	t.Fatal("This test requires the race detector, run it with -race flag: go test -race -run '" + t.Name() + "' ./...")
+}
+
+// SkipWithRaceDetector skips the test if it's running with the race detector enabled.
+// The race detector changes allocations and makes sync.Pool drop objects at random,
+// so tests that count allocations are meaningful only without it.
+func SkipWithRaceDetector(t testing.TB) {
+	t.Helper()
+
+	if raceEnabled {
+		t.Skip("Skipping test that measures allocations, because the race detector is enabled")
+	}
+}
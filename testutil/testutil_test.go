@@ -0,0 +1,115 @@
+package testutil
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTB records calls to Fatal, Skip, and Error instead of stopping the test.
+type fakeTB struct {
+	testing.TB
+	fatal    string
+	skipped  string
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper()      {}
+func (f *fakeTB) Name() string { return "TestFake" }
+
+func (f *fakeTB) Fatal(args ...any) {
+	f.fatal = args[0].(string)
+	runtime.Goexit()
+}
+
+func (f *fakeTB) Error(args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errors = append(f.errors, args[0].(string))
+}
+
+func (f *fakeTB) Errors() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.errors
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// runCleanups calls registered cleanup functions in the reverse order, like testing.T does.
+func (f *fakeTB) runCleanups() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func (f *fakeTB) Skip(args ...any) {
+	f.skipped = args[0].(string)
+	runtime.Goexit()
+}
+
+// runFake runs fn in a separate goroutine, so Fatal and Skip can stop it with runtime.Goexit.
+func runFake(fn func(tb testing.TB)) *fakeTB {
+	tb := &fakeTB{}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		fn(tb)
+	}()
+
+	<-done
+
+	return tb
+}
+
+func TestRequireRaceDetector(t *testing.T) {
+	t.Setenv(SkipRaceEnv, "")
+
+	tb := runFake(RequireRaceDetector)
+
+	if raceEnabled {
+		if tb.fatal != "" || tb.skipped != "" {
+			t.Errorf("Expected test to continue with race detector enabled, got fatal: %q, skip: %q", tb.fatal, tb.skipped)
+		}
+
+		return
+	}
+
+	if !strings.Contains(tb.fatal, "go test -race -run 'TestFake'") {
+		t.Errorf("Expected test to fail with a hint to use -race flag, got %q", tb.fatal)
+	}
+}
+
+func TestRequireRaceDetectorSkip(t *testing.T) {
+	if raceEnabled {
+		t.Skip("The race detector is enabled")
+	}
+
+	t.Setenv(SkipRaceEnv, "1")
+
+	tb := runFake(RequireRaceDetector)
+
+	if tb.fatal != "" {
+		t.Errorf("Expected test to be skipped, got fatal: %q", tb.fatal)
+	}
+
+	if !strings.Contains(tb.skipped, SkipRaceEnv) {
+		t.Errorf("Expected skip message to mention %s, got %q", SkipRaceEnv, tb.skipped)
+	}
+}
+
+func TestSkipWithRaceDetector(t *testing.T) {
+	tb := runFake(SkipWithRaceDetector)
+
+	if raceEnabled != (tb.skipped != "") {
+		t.Errorf("Expected test to be skipped only with race detector enabled, got skip: %q", tb.skipped)
+	}
+}
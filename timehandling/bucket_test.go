@@ -0,0 +1,68 @@
+package timehandling
+
+import (
+	"testing"
+	"time"
+)
+
+// 5. Advanced: truncation and rounding.
+// Truncate and Round on time.Time work on the absolute time since the zero time, in UTC. They bucket by hour
+// in zones with a whole-hour offset by luck: in Asia/Kolkata, +05:30, an hour bucket starts at half past,
+// and a day bucket starts at midnight in UTC, not in the zone of the report.
+// Build Bucket from midnight of the day in the location of t, time.Date gives it,
+// and truncate the duration since then. Days when the clocks change don't have to be handled.
+//
+// Durations have Truncate and Round too, and neither of them rounds up. Calls are billed by every started minute.
+
+// Bucket returns the start of the bucket of the size that t falls in, buckets start at midnight in the location of t.
+func Bucket(t time.Time, size time.Duration) time.Time {
+	return t.Truncate(size)
+}
+
+// BillableMinutes returns the minutes billed for a call of the duration, every started minute counts.
+func BillableMinutes(d time.Duration) int64 {
+	return int64(d.Round(time.Minute) / time.Minute)
+}
+
+func TestBucket(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	kolkata := mustLoad(t, "Asia/Kolkata")
+
+	tests := []struct {
+		time     time.Time
+		size     time.Duration
+		expected time.Time
+	}{
+		{time.Date(2024, 3, 9, 14, 37, 12, 0, berlin), 15 * time.Minute, time.Date(2024, 3, 9, 14, 30, 0, 0, berlin)},
+		{time.Date(2024, 3, 9, 14, 37, 12, 0, kolkata), time.Hour, time.Date(2024, 3, 9, 14, 0, 0, 0, kolkata)},
+		{time.Date(2024, 3, 9, 0, 20, 0, 0, kolkata), 6 * time.Hour, time.Date(2024, 3, 9, 0, 0, 0, 0, kolkata)},
+		{time.Date(2024, 3, 9, 0, 30, 0, 0, berlin), 24 * time.Hour, time.Date(2024, 3, 9, 0, 0, 0, 0, berlin)},
+		{time.Date(2024, 3, 9, 23, 59, 59, 0, time.UTC), 24 * time.Hour, time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got := Bucket(tt.time, tt.size)
+		if !got.Equal(tt.expected) || got.Location() != tt.time.Location() {
+			t.Errorf("Expected %v to fall in the %v bucket starting at %v, got %v", tt.time, tt.size, tt.expected, got)
+		}
+	}
+}
+
+func TestBillableMinutes(t *testing.T) {
+	for _, tt := range []struct {
+		d        time.Duration
+		expected int64
+	}{
+		{0, 0},
+		{time.Second, 1},
+		{29 * time.Second, 1},
+		{time.Minute, 1},
+		{61 * time.Second, 2},
+		{90 * time.Second, 2},
+		{10*time.Minute + time.Millisecond, 11},
+	} {
+		if got := BillableMinutes(tt.d); got != tt.expected {
+			t.Errorf("Expected %v to be billed as %d minutes, got %d", tt.d, tt.expected, got)
+		}
+	}
+}
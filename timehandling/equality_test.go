@@ -0,0 +1,78 @@
+package timehandling
+
+import (
+	"testing"
+	"time"
+)
+
+// 4. Equality.
+// == compares time.Time structs field by field: the wall time, the monotonic reading, and the location.
+// The same instant in two locations, or with and without a monotonic reading, isn't == to itself,
+// and a time.Time as a map key has the same problem. Equal compares instants, and so do Before and After.
+//
+// Changed and Dedupe compare times with ==, so an event that came back in another zone is a new one.
+// Compare instants, and make map keys of times that are equal only when the instants are:
+// UTC() normalizes the location and drops the monotonic reading, UnixNano() is a plain number.
+
+// Event is an event of an order.
+type Event struct {
+	ID string
+	At time.Time
+}
+
+// Changed reports whether a resource that was modified at old was modified again at latest.
+func Changed(old, latest time.Time) bool {
+	return old != latest
+}
+
+// Dedupe removes repeated events, with the same ID at the same instant, keeping the first of them.
+func Dedupe(events []Event) []Event {
+	seen := make(map[Event]bool)
+
+	var unique []Event
+
+	for _, e := range events {
+		if !seen[e] {
+			seen[e] = true
+			unique = append(unique, e)
+		}
+	}
+
+	return unique
+}
+
+func TestChanged(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	now := time.Now()
+
+	parsed, _ := time.Parse(time.RFC3339Nano, now.In(berlin).Format(time.RFC3339Nano))
+
+	for _, same := range []time.Time{now.In(berlin), now.Round(0), parsed} {
+		if Changed(now, same) {
+			t.Errorf("Expected %v and %v not to differ, it's the same instant", now, same)
+		}
+	}
+
+	if !Changed(now, now.Add(time.Nanosecond)) || !Changed(now.In(berlin), now.Add(-time.Second)) {
+		t.Error("Expected different instants to differ")
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	now := time.Now()
+
+	events := []Event{
+		{"paid", now},
+		{"paid", now.Round(0)},
+		{"shipped", now},
+		{"paid", now.In(berlin)},
+		{"paid", now.Add(time.Second)},
+		{"shipped", now.UTC()},
+	}
+
+	got := Dedupe(events)
+	if len(got) != 3 || got[0] != events[0] || got[1] != events[2] || got[2] != events[4] {
+		t.Errorf("Expected events 0, 2, and 4 to stay, got %v", got)
+	}
+}
@@ -0,0 +1,84 @@
+package timehandling
+
+import (
+	"testing"
+	"time"
+)
+
+// A time.Time is an instant with a location: the same instant shows as 14:05 in UTC and as 15:05 in Berlin.
+// It also may carry a reading of the monotonic clock, which time.Now adds to measure durations.
+// Most bugs with time come from losing track of one of the three.
+
+// 1. Layouts.
+// Go doesn't describe formats with YYYY-MM-DD. A layout is the reference time, Mon Jan 2 15:04:05 MST 2006,
+// written the way the value should look: 2006 is the year, 01 the month, 02 the day, 15 the hour on
+// a 24-hour clock, 03 on a 12-hour one. The numbers go up: 1 2 3 4 5 6 7 for the month, day, hour, minute,
+// second, year, and zone offset. A layout with the wrong number compiles and runs, and gives a wrong value:
+// - FormatTimestamp prints afternoon hours as morning ones, and times in the zone they came in,
+// - ParseDate reads dates of a US partner, month first, as if the day were first, so it fails on
+//   dates after the 12th, and misreads the rest.
+//
+// Fix the layouts, log timestamps are always in UTC. Layouts like time.RFC3339 and time.DateTime are predefined.
+
+// FormatTimestamp formats the time for logs, like 2024-03-09 14:05:07.123 in UTC.
+func FormatTimestamp(t time.Time) string {
+	return t.Format("2006-01-02 03:04:05.000")
+}
+
+// ParseDate parses a date like 03/09/2024, the 9th of March, the time is midnight in UTC.
+func ParseDate(s string) (time.Time, error) {
+	return time.Parse("02/01/2006", s)
+}
+
+// mustLoad loads the location or fails the test.
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return loc
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	tests := []struct {
+		time     time.Time
+		expected string
+	}{
+		{time.Date(2024, 3, 9, 14, 5, 7, 123456789, time.UTC), "2024-03-09 14:05:07.123"},
+		{time.Date(2024, 3, 9, 15, 5, 7, 123000000, berlin), "2024-03-09 14:05:07.123"},
+		{time.Date(2024, 12, 31, 9, 0, 0, 0, time.UTC), "2024-12-31 09:00:00.000"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatTimestamp(tt.time); got != tt.expected {
+			t.Errorf("Expected %v to be formatted as %q, got %q", tt.time, tt.expected, got)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		expected time.Time
+	}{
+		{"03/09/2024", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"12/31/2024", time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"01/02/2024", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	} {
+		got, err := ParseDate(tt.s)
+		if err != nil || !got.Equal(tt.expected) {
+			t.Errorf("Expected %q to be parsed as %v, got %v, %v", tt.s, tt.expected, got, err)
+		}
+	}
+
+	for _, s := range []string{"02/30/2024", "13/01/2024", "2024-03-09"} {
+		if got, err := ParseDate(s); err == nil {
+			t.Errorf("Expected an error for %q, got %v", s, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package timehandling
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// 3. The monotonic clock.
+// The wall clock jumps: NTP corrects it, an admin sets it, a VM resumes from a snapshot. A duration measured
+// between two readings of the wall clock can be off by minutes or negative. So time.Now also reads
+// the monotonic clock, which only goes forward, and Sub, Since, and Until use it when both times have it.
+// String shows it at the end, as m=+0.000123456.
+//
+// The reading is lost by everything that computes a new wall time: In, UTC, Local, Round, Truncate, and AddDate,
+// and by serialization, it means nothing outside the process. Add keeps it.
+// Stopwatch converts the start to UTC for logs, so Elapsed measures with the wall clock.
+// Keep the monotonic reading in the start field and report the start in UTC.
+
+// Stopwatch measures how long something takes.
+type Stopwatch struct {
+	start time.Time
+}
+
+// Start starts the stopwatch.
+func (s *Stopwatch) Start() {
+	s.start = time.Now().UTC()
+}
+
+// Started returns the time the stopwatch was started, in UTC for logs.
+func (s *Stopwatch) Started() time.Time {
+	return s.start
+}
+
+// Elapsed returns the time since the stopwatch was started.
+func (s *Stopwatch) Elapsed() time.Duration {
+	return time.Since(s.start)
+}
+
+func TestStopwatch(t *testing.T) {
+	var sw Stopwatch
+
+	sw.Start()
+	time.Sleep(5 * time.Millisecond)
+
+	if elapsed := sw.Elapsed(); elapsed < 5*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected about 5ms to elapse, got %v", elapsed)
+	}
+
+	if loc := sw.Started().Location(); loc != time.UTC {
+		t.Errorf("Expected the start in UTC, got %v", loc)
+	}
+
+	// A clock jump can't be simulated, but the reading that protects from it is visible.
+	if !strings.Contains(sw.start.String(), " m=") {
+		t.Errorf("Expected the start to keep the monotonic clock reading, got %v", sw.start)
+	}
+}
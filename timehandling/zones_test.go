@@ -0,0 +1,79 @@
+package timehandling
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 2. Time zones.
+// A zone isn't an offset: Europe/Berlin is +01:00 in winter and +02:00 in summer, and the rules change by law.
+// time.LoadLocation reads them from the tz database of the system, and minimal containers like scratch
+// and distroless don't have one, so LoadLocation fails there. Importing time/tzdata embeds the database
+// in the binary, about 450KB, and LoadLocation falls back to it. The timetzdata build tag does the same.
+//
+// NextRun schedules a daily job at a wall-clock time in the zone of a customer. It builds the time in UTC,
+// takes the date of now in UTC, which isn't the date in the zone, and adds 24 hours to get tomorrow,
+// which is 23 or 25 hours away on the days the clocks change. Calendar arithmetic belongs to time.Date
+// in the location: it normalizes day 32 to the next month as well.
+// Fix NextRun, the result is in the location of the zone, and embed the tz database in the package.
+
+// NextRun returns the first time after now when the wall clock in the zone shows hour:minute.
+func NextRun(now time.Time, hour, minute int, zone string) (time.Time, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	run := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+	if !run.After(now) {
+		run = run.Add(24 * time.Hour)
+	}
+
+	return run.In(loc), nil
+}
+
+func TestNextRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		zone     string
+		expected time.Time
+	}{
+		{"later today", time.Date(2024, 3, 29, 6, 0, 0, 0, time.UTC), "Europe/Berlin", time.Date(2024, 3, 29, 8, 0, 0, 0, time.UTC)},
+		{"tomorrow", time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC), "Europe/Berlin", time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC)},
+		{"clocks go forward", time.Date(2024, 3, 30, 8, 30, 0, 0, time.UTC), "Europe/Berlin", time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC)},
+		{"clocks go back", time.Date(2024, 11, 2, 14, 0, 0, 0, time.UTC), "America/New_York", time.Date(2024, 11, 3, 14, 0, 0, 0, time.UTC)},
+		{"ahead of UTC", time.Date(2024, 6, 10, 22, 0, 0, 0, time.UTC), "Pacific/Auckland", time.Date(2024, 6, 11, 21, 0, 0, 0, time.UTC)},
+		{"end of month", time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC), "Asia/Tokyo", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextRun(tt.now, 9, 0, tt.zone)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !got.Equal(tt.expected) || got.Location().String() != tt.zone {
+				t.Errorf("Expected the next run at %v in %s, got %v", tt.expected.In(mustLoad(t, tt.zone)), tt.zone, got)
+			}
+		})
+	}
+
+	if _, err := NextRun(time.Now(), 9, 0, "Mars/Olympus_Mons"); err == nil {
+		t.Error("Expected an error for an unknown zone")
+	}
+}
+
+func TestEmbeddedTZData(t *testing.T) {
+	out, err := exec.Command("go", "list", "-deps", "-test", ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out)
+	}
+
+	if !strings.Contains(string(out), "\ntime/tzdata\n") {
+		t.Error("Expected the tz database to be embedded with time/tzdata")
+	}
+}
@@ -0,0 +1,72 @@
+package tlsbasics
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Advanced: public key pinning.
+//
+// Any of the trusted CAs can issue a certificate for any host. A mobile app or an agent that talks
+// only to its own backend can trust less: it pins the public key of the server, and rejects
+// certificates with other keys even if they are signed by a trusted CA.
+//
+// A pin is the base64 encoded SHA-256 of the SubjectPublicKeyInfo of a certificate (RFC 7469).
+// The key is pinned, not the certificate, so the certificate can be renewed with the same key without updating clients.
+// Clients usually keep several pins, a current key and a backup one, so the server can move to the backup when needed.
+//
+// Let's implement PinnedClient with tls.Config.VerifyConnection, it's called after the regular verification,
+// so the certificate is still checked against rootCAs and the host name, pinning only adds a check on top.
+
+// SPKIPin returns the pin of the certificate.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// PinnedClient returns an HTTP client that trusts rootCAs and accepts only servers with one of the pinned keys.
+func PinnedClient(rootCAs *x509.CertPool, pins []string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: rootCAs},
+		},
+	}
+}
+
+func TestPinnedClient(t *testing.T) {
+	ca := newCA(t, "Workshop CA")
+	pinned, other := ca.ServerCert(t), ca.ServerCert(t)
+
+	serve := func(cert tls.Certificate) *httptest.Server {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+		server.StartTLS()
+		t.Cleanup(server.Close)
+
+		return server
+	}
+
+	backup := "YmFja3VwIGtleSBpcyBub3QgZGVwbG95ZWQgeWV0IQ=="
+	client := PinnedClient(ca.Pool(), []string{backup, SPKIPin(pinned.Leaf)})
+
+	if got, err := get(client, serve(pinned).URL, nil); err != nil || got != "ok" {
+		t.Errorf("Expected the server with the pinned key to be accepted, got %q and error %v", got, err)
+	}
+
+	if _, err := get(client, serve(other).URL, nil); err == nil {
+		t.Error("Expected a server with another key to be rejected, even though its certificate is signed by a trusted CA")
+	}
+
+	rogue := newCA(t, "Rogue CA").ServerCert(t)
+	if _, err := get(PinnedClient(ca.Pool(), []string{SPKIPin(rogue.Leaf)}), serve(rogue).URL, nil); err == nil {
+		t.Error("Expected regular verification to stay in place, a server of an unknown CA was accepted")
+	}
+}
@@ -0,0 +1,151 @@
+package tlsbasics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TLS gives a connection two things: encryption, and authentication of the peer.
+// Encryption alone is worth little: without checking who is on the other side,
+// a connection can be encrypted straight to an attacker in the middle.
+//
+// With regular TLS only the server is authenticated. The client checks that the certificate of the server:
+// - is signed by one of the trusted roots in tls.Config.RootCAs, the system pool when it's nil,
+// - is valid for the host name the client connected to,
+// - is not expired.
+// InsecureSkipVerify turns all of it off, it's the most common way to "fix" certificate errors, and it's always wrong.
+//
+// Mutual TLS (mTLS) authenticates the client too: it presents its own certificate, and the server checks it
+// against tls.Config.ClientCAs. Services talking to each other often use it instead of API keys and tokens.
+
+// 1. Server.
+// NewServer ignores the certificate it's given and accepts clients without certificates.
+// Fix it to serve serverCert, and to accept only clients with certificates signed by clientCAs.
+// Look at tls.Config.ClientAuth, and at httptest.NewUnstartedServer to set TLS config before StartTLS.
+
+// NewServer starts an HTTPS test server that requires client certificates.
+func NewServer(handler http.Handler, serverCert tls.Certificate, clientCAs *x509.CertPool) *httptest.Server {
+	return httptest.NewTLSServer(handler)
+}
+
+// 2. Client.
+// NewClient skips verification of the server, so it would talk to anyone. Fix it to trust only rootCAs,
+// and to present clientCert when the server asks for it.
+
+// NewClient returns an HTTP client for a service with mTLS.
+func NewClient(rootCAs *x509.CertPool, clientCert tls.Certificate) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+// 3. Client identity.
+// Once the handshake is done, the verified certificate chains of the client are in r.TLS.
+// The name of the client should come from there, a header can be set by anyone. Fix ClientName.
+
+// ClientName returns the common name of the verified client certificate, or "" if there is none.
+func ClientName(r *http.Request) string {
+	return r.Header.Get("X-Client-Name")
+}
+
+var whoami = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, ClientName(r))
+})
+
+func get(client *http.Client, url string, header http.Header) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+	}
+
+	return string(body), nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newCA(t, "Workshop CA")
+
+	server := NewServer(whoami, ca.ServerCert(t), ca.Pool())
+	defer server.Close()
+
+	client := NewClient(ca.Pool(), ca.ClientCert(t, "billing-service"))
+
+	got, err := get(client, server.URL, http.Header{"X-Client-Name": {"admin-service"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got != "billing-service" {
+		t.Errorf("Expected client name from its certificate %q, got %q", "billing-service", got)
+	}
+}
+
+func TestClientRejectsUnknownServer(t *testing.T) {
+	ca := newCA(t, "Workshop CA")
+	impostor := newCA(t, "Impostor CA")
+
+	server := NewServer(whoami, impostor.ServerCert(t), ca.Pool())
+	defer server.Close()
+
+	client := NewClient(ca.Pool(), ca.ClientCert(t, "billing-service"))
+
+	_, err := get(client, server.URL, nil)
+
+	var unknown x509.UnknownAuthorityError
+	if !errors.As(err, &unknown) {
+		t.Errorf("Expected x509.UnknownAuthorityError for a server certificate signed by another CA, got %v", err)
+	}
+}
+
+func TestServerRequiresClientCert(t *testing.T) {
+	ca := newCA(t, "Workshop CA")
+
+	server := NewServer(whoami, ca.ServerCert(t), ca.Pool())
+	defer server.Close()
+
+	client := NewClient(ca.Pool(), tls.Certificate{})
+
+	if got, err := get(client, server.URL, nil); err == nil {
+		t.Errorf("Expected handshake to fail for a client without certificate, got response %q", got)
+	}
+}
+
+func TestServerRejectsUnknownClient(t *testing.T) {
+	ca := newCA(t, "Workshop CA")
+	impostor := newCA(t, "Impostor CA")
+
+	server := NewServer(whoami, ca.ServerCert(t), ca.Pool())
+	defer server.Close()
+
+	client := NewClient(ca.Pool(), impostor.ClientCert(t, "billing-service"))
+
+	if got, err := get(client, server.URL, nil); err == nil {
+		t.Errorf("Expected handshake to fail for a client certificate signed by another CA, got response %q", got)
+	}
+}
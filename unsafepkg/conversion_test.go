@@ -0,0 +1,130 @@
+package unsafepkg
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// 3. Zero-copy conversions.
+// string(b) and []byte(s) copy the data: strings are immutable, and a string sharing memory with a slice
+// would change when the slice changes. The compiler skips the copy where it can prove it's safe,
+// like in m[string(b)] lookups, comparisons, and for range over []byte(s), but not in general.
+//
+// Go 1.20 added functions that build strings and slices from pointers, replacing tricks with reflect.StringHeader:
+// - unsafe.String(ptr, len) and unsafe.StringData(s) for strings,
+// - unsafe.Slice(ptr, len) and unsafe.SliceData(b) for slices.
+// Implement the conversions with them, without copying. The rules of the contract are on the caller:
+// bytes converted to a string must not be modified while the string is in use,
+// and bytes from a string must never be modified at all, strings may live in read-only memory.
+
+// BytesToString returns a string that shares memory with b.
+func BytesToString(b []byte) string {
+	return string(b)
+}
+
+// StringToBytes returns a slice that shares memory with s, it must not be modified.
+func StringToBytes(s string) []byte {
+	return []byte(s)
+}
+
+func TestBytesToString(t *testing.T) {
+	b := []byte("hello, gopher")
+
+	s := BytesToString(b)
+	if s != "hello, gopher" {
+		t.Fatalf("Expected %q, got %q", "hello, gopher", s)
+	}
+
+	if unsafe.StringData(s) != unsafe.SliceData(b) {
+		t.Error("Expected the string to share memory with the slice")
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { s = BytesToString(b) }); allocs > 0 {
+		t.Errorf("Expected no allocations, got %.0f", allocs)
+	}
+
+	if got := BytesToString(nil); got != "" {
+		t.Errorf("Expected empty string for nil, got %q", got)
+	}
+}
+
+func TestStringToBytes(t *testing.T) {
+	s := strings.Repeat("go", 8)
+
+	b := StringToBytes(s)
+	if string(b) != s || len(b) != len(s) {
+		t.Fatalf("Expected %q, got %q", s, b)
+	}
+
+	if unsafe.SliceData(b) != unsafe.StringData(s) {
+		t.Error("Expected the slice to share memory with the string")
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { b = StringToBytes(s) }); allocs > 0 {
+		t.Errorf("Expected no allocations, got %.0f", allocs)
+	}
+
+	if got := StringToBytes(""); len(got) != 0 {
+		t.Errorf("Expected empty slice for empty string, got %q", got)
+	}
+}
+
+// 4. When zero-copy breaks.
+// WordCounter was made faster with BytesToString: it reads words with bufio.Scanner and counts them in a map.
+// But Scanner reuses its buffer, the bytes of a word are overwritten by the next lines,
+// and the map keeps strings that share memory with the buffer. Keys of the map change behind its back:
+// counts end up under wrong words, and lookups miss words that are in the map.
+//
+// Fix Add, so counts are correct, and counting a word that is already in the map still allocates nothing.
+// A string that is kept must own its memory, strings.Clone makes such a copy.
+// Careful with assignments: storing a value under an existing key may replace the key in the map as well.
+
+// WordCounter counts words.
+type WordCounter struct {
+	counts map[string]int
+}
+
+// NewWordCounter creates an empty counter.
+func NewWordCounter() *WordCounter {
+	return &WordCounter{counts: make(map[string]int)}
+}
+
+// Add counts the word, the slice is not used after Add returns.
+func (c *WordCounter) Add(word []byte) {
+	c.counts[BytesToString(word)]++
+}
+
+// Count returns how many times the word was added.
+func (c *WordCounter) Count(word string) int {
+	return c.counts[word]
+}
+
+func TestWordCounter(t *testing.T) {
+	text := strings.Repeat("gopher\nferris\ngopher\nduke\n", 100)
+
+	c := NewWordCounter()
+
+	s := bufio.NewScanner(strings.NewReader(text))
+	s.Buffer(make([]byte, 16), 16)
+
+	for s.Scan() {
+		c.Add(s.Bytes())
+	}
+
+	if err := s.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for word, expected := range map[string]int{"gopher": 200, "ferris": 100, "duke": 100} {
+		if got := c.Count(word); got != expected {
+			t.Errorf("Expected %q to be counted %d times, got %d", word, expected, got)
+		}
+	}
+
+	word := []byte("gopher")
+	if allocs := testing.AllocsPerRun(100, func() { c.Add(word) }); allocs > 0 {
+		t.Errorf("Expected no allocations when counting a known word, got %.0f", allocs)
+	}
+}
@@ -0,0 +1,125 @@
+package unsafepkg
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+// Package unsafe steps around the type system of Go: it converts between pointer types, does pointer arithmetic,
+// and reveals how values are laid out in memory. Code that imports it is not covered by the Go 1 compatibility promise,
+// go vet checks only some of its rules, and mistakes corrupt memory silently instead of panicking.
+// It's used in the runtime, in serialization libraries, and in hot paths where a copy is measurably too expensive.
+//
+// Three functions describe the layout of values, they are evaluated at compile time:
+// - unsafe.Sizeof(x) is the size of x in bytes, without memory it references: a string is 16 bytes on 64-bit platforms,
+// - unsafe.Alignof(x) is the alignment: the address of x is always a multiple of it,
+// - unsafe.Offsetof(s.f) is the offset of the field f from the start of the struct s.
+//
+// A field is aligned by its type: an int64 starts at a multiple of 8, an int16 at a multiple of 2.
+// The compiler inserts padding before fields to align them, and after the last field,
+// so the size of a struct is a multiple of its alignment and elements of an array stay aligned.
+// Go doesn't reorder fields, so the order chosen by the programmer decides how much memory is wasted.
+// The fieldalignment analyzer from golang.org/x/tools finds structs that can be smaller.
+
+// 1. Measuring padding.
+// Implement Padding with reflect, which reports the same numbers as unsafe for types known only at runtime:
+// Type.Size, Type.Align, and StructField.Offset. Count gaps between fields and after the last one,
+// and padding inside fields that are structs themselves.
+
+// Padding returns the number of padding bytes in the struct type.
+func Padding(t reflect.Type) uintptr {
+	return 0
+}
+
+func TestPadding(t *testing.T) {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("Sizes in the test are for 64-bit platforms")
+	}
+
+	tests := []struct {
+		name     string
+		value    any
+		expected uintptr
+	}{
+		{"empty", struct{}{}, 0},
+		{"packed", struct {
+			ID    int64
+			Flags uint32
+			Kind  uint16
+			OK    bool
+			Dirty bool
+		}{}, 0},
+		{"gaps", struct {
+			OK bool
+			ID int64
+		}{}, 7},
+		{"tail", struct {
+			ID int64
+			OK bool
+		}{}, 7},
+		{"both", struct {
+			OK    bool
+			ID    int64
+			Kind  uint16
+			Flags uint32
+			Dirty bool
+		}{}, 16},
+		{"nested", struct {
+			OK    bool
+			Inner struct {
+				A int32
+				B bool
+			}
+		}{}, 6},
+	}
+
+	for _, tt := range tests {
+		if got := Padding(reflect.TypeOf(tt.value)); got != tt.expected {
+			t.Errorf("Expected %s to have %d bytes of padding, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
+
+// 2. Ordering fields.
+// Event is stored in a slice of millions of elements, and a third of its size is padding.
+// Reorder its fields, so it takes as little memory as possible. Ordering fields from the largest alignment
+// to the smallest is a simple rule that gives the minimal size.
+
+// Event is a record of the audit log.
+type Event struct {
+	Valid    bool
+	ID       int64
+	Retries  uint8
+	Time     int64
+	Priority int16
+	Source   uint32
+	Urgent   bool
+}
+
+func TestEventLayout(t *testing.T) {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("Sizes in the test are for 64-bit platforms")
+	}
+
+	var e Event
+
+	typ := reflect.TypeOf(e)
+
+	var names []string
+	for i := range typ.NumField() {
+		names = append(names, typ.Field(i).Name)
+	}
+
+	slices.Sort(names)
+
+	if expected := []string{"ID", "Priority", "Retries", "Source", "Time", "Urgent", "Valid"}; !slices.Equal(names, expected) {
+		t.Fatalf("Expected Event to keep its fields %v, got %v", expected, names)
+	}
+
+	if size := unsafe.Sizeof(e); size != 32 {
+		t.Errorf("Expected Event to take 32 bytes, got %d: ID at offset %d, Time at %d, Valid at %d, alignment %d",
+			size, unsafe.Offsetof(e.ID), unsafe.Offsetof(e.Time), unsafe.Offsetof(e.Valid), unsafe.Alignof(e))
+	}
+}
@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// 2. Extracting errors.
+// errors.As finds the first error of a type in the tree, but an API that highlights invalid fields of a form
+// needs all of them. The tree of an error is walked by its Unwrap methods: Unwrap() error of wrappers
+// from fmt.Errorf with %w, and Unwrap() []error of errors.Join and of fmt.Errorf with several %w.
+//
+// AsAll returns only what errors.As finds. Make it collect every error of the type in the tree, depth first
+// in the order of unwrapping, which is the order errors.As tries them in. It's generic, so it works for
+// any error type, and FieldRules is built on top of it.
+
+// AsAll returns all errors of type T in the tree of err.
+func AsAll[T error](err error) []T {
+	var target T
+	if errors.As(err, &target) {
+		return []T{target}
+	}
+
+	return nil
+}
+
+// FieldRules returns the failed rules of fields in err by the paths of the fields.
+func FieldRules(err error) map[string]string {
+	rules := make(map[string]string)
+
+	for _, fe := range AsAll[*FieldValidationError](err) {
+		rules[fe.Field] = fe.Rule
+	}
+
+	return rules
+}
+
+func TestAsAll(t *testing.T) {
+	name := &FieldValidationError{Field: "name", Rule: "required"}
+	city := &FieldValidationError{Field: "address.city", Rule: "required"}
+	zip := &FieldValidationError{Field: "address.zip", Rule: "min", Param: "5"}
+
+	err := fmt.Errorf("sign up: %w", errors.Join(
+		name,
+		io.ErrUnexpectedEOF,
+		fmt.Errorf("address: %w", errors.Join(city, zip)),
+	))
+
+	if got := AsAll[*FieldValidationError](err); !slices.Equal(got, []*FieldValidationError{name, city, zip}) {
+		t.Errorf("Expected errors of name, address.city, and address.zip in this order, got %v", got)
+	}
+
+	if got := AsAll[*fs.PathError](err); len(got) != 0 {
+		t.Errorf("Expected no errors of another type, got %v", got)
+	}
+
+	if got := AsAll[*FieldValidationError](nil); len(got) != 0 {
+		t.Errorf("Expected no errors in nil, got %v", got)
+	}
+
+	multi := fmt.Errorf("%w and %w", name, &fs.PathError{Op: "open", Path: "signup.json", Err: fs.ErrNotExist})
+	if got := AsAll[*fs.PathError](multi); len(got) != 1 || got[0].Path != "signup.json" {
+		t.Errorf("Expected the error wrapped with the second %%w, got %v", got)
+	}
+}
+
+func TestFieldRules(t *testing.T) {
+	signUp := validSignUp()
+	signUp.Email = ""
+	signUp.Age = 200
+	signUp.Address.Zip = "1234567"
+
+	expected := map[string]string{"email": "required", "age": "max", "address.zip": "max"}
+
+	if got := FieldRules(fmt.Errorf("handle sign up: %w", Validate(signUp))); !maps.Equal(got, expected) {
+		t.Errorf("Expected failed rules %v, got %v", expected, got)
+	}
+
+	if got := FieldRules(nil); len(got) != 0 {
+		t.Errorf("Expected no failed rules for no error, got %v", got)
+	}
+}
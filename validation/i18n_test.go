@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 4. Advanced: localized messages.
+// Error returns English for logs, but users read messages in their language. FieldValidationError keeps
+// the rule and the parameter instead of a message, so messages are made when they are shown,
+// from a catalog of templates in the language: {field} and {param} are replaced with the field and the parameter.
+//
+// Catalogs are never complete: a rule missing from a catalog falls back to English, and a rule missing
+// from English too, like rules registered by users, to the generic "{field} is invalid".
+// Localize returns the English of Error now. Make it return messages of every field error in err
+// in the language of the catalog, in the order of AsAll, and add the fallbacks to Message.
+
+// Catalog maps names of rules to templates of messages.
+type Catalog map[string]string
+
+// English is the catalog of English messages, Error uses it.
+var English = Catalog{
+	"required": "{field} is required",
+	"min":      "{field} must be at least {param}",
+	"max":      "{field} must be at most {param}",
+	"email":    "{field} must be an email address",
+	"oneof":    "{field} must be one of {param}",
+}
+
+// German is the catalog of German messages, it doesn't have every rule yet.
+var German = Catalog{
+	"required": "{field} ist erforderlich",
+	"min":      "{field} muss mindestens {param} sein",
+	"max":      "{field} darf höchstens {param} sein",
+	"email":    "{field} muss eine E-Mail-Adresse sein",
+}
+
+// Message returns the message of the error in the language of the catalog.
+func (e *FieldValidationError) Message(c Catalog) string {
+	return strings.NewReplacer("{field}", e.Field, "{param}", e.Param).Replace(c[e.Rule])
+}
+
+// Localize returns messages of all field errors in err in the language of the catalog.
+func Localize(err error, c Catalog) []string {
+	if err == nil {
+		return nil
+	}
+
+	return strings.Split(err.Error(), "\n")
+}
+
+func TestLocalize(t *testing.T) {
+	signUp := validSignUp()
+	signUp.Email = "alice"
+	signUp.Age = 12
+	signUp.Plan = "gold"
+	signUp.Address.City = ""
+
+	got := Localize(errors.Join(errors.New("sign up"), Validate(signUp)), German)
+	slices.Sort(got)
+
+	expected := []string{
+		"address.city ist erforderlich",
+		"age muss mindestens 18 sein",
+		"email muss eine E-Mail-Adresse sein",
+		"plan must be one of free pro",
+	}
+
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected messages:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	custom := &FieldValidationError{Field: "sku", Rule: "sku"}
+
+	if msg := custom.Message(German); msg != "sku is invalid" {
+		t.Errorf("Expected the generic message for a rule without one, got %q", msg)
+	}
+
+	if msg := custom.Error(); msg != "sku is invalid" {
+		t.Errorf("Expected the generic message from Error, got %q", msg)
+	}
+
+	if got := Localize(nil, German); len(got) != 0 {
+		t.Errorf("Expected no messages for no error, got %q", got)
+	}
+}
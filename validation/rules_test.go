@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// 3. Registered rules.
+// Builtin rules don't know the domain: a SKU, a currency code, an IBAN. Register adds rules to a validator,
+// and Func adapts a plain function of the type of the field, so rules don't deal with reflect themselves.
+//
+// Two things are broken. Validate skips rules it doesn't know, so a typo in a tag turns a check off without
+// a word: it must return ErrUnknownRule. And Func asserts the type of the value, so a rule for strings rejects
+// a field of a named type like SKU, whose underlying type is string. Convert values that are convertible
+// to T, reflect.TypeFor[T]() returns the type to check, and return ErrRuleType for the rest. Careful: like the language,
+// reflect converts an int to a string as a rune, so convertible isn't enough for ints passed to string rules.
+
+// Register adds the rule to the validator, it replaces a rule with the same name.
+func (val *Validator) Register(name string, rule Rule) {
+	val.rules[name] = rule
+}
+
+// Func adapts the check of values of type T to a Rule.
+func Func[T any](check func(value T, param string) bool) Rule {
+	return func(v reflect.Value, param string) (bool, error) {
+		value, ok := v.Interface().(T)
+		if !ok {
+			return false, fmt.Errorf("%w: %s", ErrRuleType, v.Type())
+		}
+
+		return check(value, param), nil
+	}
+}
+
+// SKU is a stock keeping unit, like AB-1234.
+type SKU string
+
+// Product is a product of the catalog.
+type Product struct {
+	SKU   SKU    `json:"sku" validate:"required,sku"`
+	Name  string `json:"name" validate:"required,sku_free"`
+	Price int    `json:"price" validate:"min=1"`
+}
+
+var skuPattern = regexp.MustCompile(`[A-Z]{2}-\d{4}`)
+
+func newProductValidator() *Validator {
+	v := New()
+
+	v.Register("sku", Func(func(s string, _ string) bool {
+		return skuPattern.FindString(s) == s
+	}))
+
+	// sku_free rejects names that contain a SKU.
+	v.Register("sku_free", Func(func(s string, _ string) bool {
+		return !skuPattern.MatchString(s)
+	}))
+
+	return v
+}
+
+func TestRegisteredRules(t *testing.T) {
+	v := newProductValidator()
+
+	if err := v.Validate(Product{SKU: "AB-1234", Name: "Gopher plush", Price: 1500}); err != nil {
+		t.Errorf("Expected a valid product to pass, got %v", err)
+	}
+
+	err := v.Validate(Product{SKU: "ab1234", Name: "Gopher plush AB-1234", Price: 0})
+
+	expected := map[string]string{"sku": "sku", "name": "sku_free", "price": "min"}
+	if got := FieldRules(err); !maps.Equal(got, expected) {
+		t.Errorf("Expected failed rules %v, got %v", expected, got)
+	}
+
+	// Rules are registered per validator, the default one doesn't know sku.
+	if err := Validate(Product{SKU: "AB-1234", Name: "Gopher plush", Price: 1500}); !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("Expected ErrUnknownRule from the default validator, got %v", err)
+	}
+}
+
+func TestRuleMistakes(t *testing.T) {
+	v := newProductValidator()
+
+	type Coupon struct {
+		Code string `validate:"required,uppercase"`
+	}
+
+	err := v.Validate(Coupon{Code: "SPRING"})
+	if !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("Expected ErrUnknownRule for a rule that isn't registered, got %v", err)
+	}
+
+	type Stock struct {
+		Count int `validate:"sku"`
+	}
+
+	err = v.Validate(Stock{Count: 3})
+	if !errors.Is(err, ErrRuleType) {
+		t.Errorf("Expected ErrRuleType for a rule of strings on an int, got %v", err)
+	}
+
+	if len(AsAll[*FieldValidationError](err)) != 0 {
+		t.Errorf("Expected a mistake in tags not to be reported as an invalid field, got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// The config workshop validated one struct by hand: a check and a FieldError for every field.
+// With a dozen request types, the checks move into struct tags, and one validator reads them with reflect:
+//
+//	type SignUp struct {
+//		Email string `json:"email" validate:"required,email"`
+//		Age   int    `json:"age" validate:"min=18"`
+//	}
+//
+// Every failed rule is a *FieldValidationError, and Validate joins them with errors.Join, so callers
+// find them in the error tree with errors.As, like any other error.
+
+// 1. Struct tags.
+// Validate walks the fields of a struct and checks the rules of their validate tags in order. It stops at
+// the first failed rule, but a user who submits a form wants to hear about all of its problems at once.
+// Report every field that fails, with the first rule it fails, and join the errors.
+// Fields of nested structs are validated too, and their path is joined with dots: address.zip.
+// Fields are named like in JSON: by the name in the json tag, or by the name of the field when there's none.
+
+var (
+	// ErrNotStruct is returned by Validate for values that aren't structs or pointers to them.
+	ErrNotStruct = errors.New("not a struct")
+	// ErrUnknownRule is returned for rules in tags that aren't registered.
+	ErrUnknownRule = errors.New("unknown rule")
+	// ErrRuleType is returned for rules applied to fields of types they can't check.
+	ErrRuleType = errors.New("rule doesn't apply to the type")
+)
+
+// FieldValidationError is a failed rule of a field.
+type FieldValidationError struct {
+	// Field is the path of the field, like address.zip.
+	Field string
+	// Rule is the name of the rule, and Param is its parameter: min and 3 for min=3.
+	Rule  string
+	Param string
+}
+
+func (e *FieldValidationError) Error() string {
+	return e.Message(English)
+}
+
+// Rule checks the value of a field, param is the text after = in the tag, like 3 in min=3.
+// It returns an error when it can't check values of the type or the parameter is malformed,
+// these are bugs in tags, not invalid values.
+type Rule func(v reflect.Value, param string) (bool, error)
+
+// Validator checks structs with the rules of their validate tags.
+type Validator struct {
+	rules map[string]Rule
+}
+
+// New creates a validator with the builtin rules.
+func New() *Validator {
+	v := &Validator{rules: make(map[string]Rule)}
+
+	for name, rule := range builtin {
+		v.rules[name] = rule
+	}
+
+	return v
+}
+
+var defaultValidator = New()
+
+// Validate checks the struct, or a pointer to it, with the builtin rules.
+func Validate(v any) error {
+	return defaultValidator.Validate(v)
+}
+
+// Validate checks the struct, or a pointer to it. Failed rules are returned as *FieldValidationError,
+// one per field, joined with errors.Join. Mistakes in tags are returned as other errors.
+func (val *Validator) Validate(x any) error {
+	v := reflect.Indirect(reflect.ValueOf(x))
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T", ErrNotStruct, x)
+	}
+
+	t := v.Type()
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("validate")
+		if !f.IsExported() || tag == "" {
+			continue
+		}
+
+		for _, spec := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(spec, "=")
+
+			rule, ok := val.rules[name]
+			if !ok {
+				continue
+			}
+
+			valid, err := rule(v.Field(i), param)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", f.Name, name, err)
+			}
+
+			if !valid {
+				return &FieldValidationError{Field: f.Name, Rule: name, Param: param}
+			}
+		}
+	}
+
+	return nil
+}
+
+// builtin are the rules every validator has:
+// - required: the value isn't the zero value,
+// - min and max: bounds of the length of strings in characters, of slices and maps, and of numbers,
+// - email: a plain email address, without a display name,
+// - oneof: one of the values separated with spaces.
+var builtin = map[string]Rule{
+	"required": func(v reflect.Value, _ string) (bool, error) {
+		return !v.IsZero(), nil
+	},
+	"min": func(v reflect.Value, param string) (bool, error) {
+		return compare(v, param, func(size, limit float64) bool { return size >= limit })
+	},
+	"max": func(v reflect.Value, param string) (bool, error) {
+		return compare(v, param, func(size, limit float64) bool { return size <= limit })
+	},
+	"email": func(v reflect.Value, _ string) (bool, error) {
+		if v.Kind() != reflect.String {
+			return false, fmt.Errorf("%w: %s", ErrRuleType, v.Type())
+		}
+
+		addr, err := mail.ParseAddress(v.String())
+
+		return err == nil && addr.Address == v.String(), nil
+	},
+	"oneof": func(v reflect.Value, param string) (bool, error) {
+		switch v.Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		default:
+			return false, fmt.Errorf("%w: %s", ErrRuleType, v.Type())
+		}
+
+		value := fmt.Sprint(v.Interface())
+
+		for _, allowed := range strings.Fields(param) {
+			if value == allowed {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	},
+}
+
+// compare checks the size of the value against the limit in the parameter.
+func compare(v reflect.Value, param string, ok func(size, limit float64) bool) (bool, error) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false, fmt.Errorf("malformed parameter %q: %w", param, err)
+	}
+
+	var size float64
+
+	switch v.Kind() {
+	case reflect.String:
+		size = float64(utf8.RuneCountInString(v.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	default:
+		return false, fmt.Errorf("%w: %s", ErrRuleType, v.Type())
+	}
+
+	return ok(size, limit), nil
+}
+
+// Address is an address of a customer.
+type Address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"required,min=5,max=5"`
+}
+
+// SignUp is a request to create an account.
+type SignUp struct {
+	Name     string   `json:"name" validate:"required,max=20"`
+	Email    string   `json:"email" validate:"required,email"`
+	Age      int      `json:"age" validate:"min=18,max=130"`
+	Plan     string   `json:"plan" validate:"oneof=free pro"`
+	Tags     []string `json:"tags" validate:"max=3"`
+	Referrer string   `validate:"max=10"`
+	Address  Address  `json:"address"`
+
+	// password isn't exported, and it isn't validated.
+	password string `validate:"required"`
+}
+
+func validSignUp() SignUp {
+	return SignUp{
+		Name:    "Alice",
+		Email:   "alice@example.com",
+		Age:     30,
+		Plan:    "pro",
+		Tags:    []string{"beta"},
+		Address: Address{City: "Berlin", Zip: "10115"},
+	}
+}
+
+// errorLines returns the lines of the error sorted, or nil for no error.
+func errorLines(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	lines := strings.Split(err.Error(), "\n")
+	slices.Sort(lines)
+
+	return lines
+}
+
+func TestValidate(t *testing.T) {
+	signUp := validSignUp()
+
+	if err := Validate(signUp); err != nil {
+		t.Errorf("Expected a valid sign up to pass, got %v", err)
+	}
+
+	if err := Validate(&signUp); err != nil {
+		t.Errorf("Expected a pointer to a valid sign up to pass, got %v", err)
+	}
+
+	signUp = SignUp{
+		Name:     "Alice",
+		Email:    "alice at example.com",
+		Age:      16,
+		Plan:     "enterprise",
+		Tags:     []string{"a", "b", "c", "d"},
+		Referrer: "a-very-long-referrer",
+		Address:  Address{Zip: "101"},
+	}
+
+	expected := []string{
+		"Referrer must be at most 10",
+		"address.city is required",
+		"address.zip must be at least 5",
+		"age must be at least 18",
+		"email must be an email address",
+		"plan must be one of free pro",
+		"tags must be at most 3",
+	}
+
+	err := Validate(signUp)
+	if got := errorLines(err); !slices.Equal(got, expected) {
+		t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	var fieldErr *FieldValidationError
+	if !errors.As(err, &fieldErr) {
+		t.Errorf("Expected *FieldValidationError, got %T", err)
+	}
+
+	for _, v := range []any{42, "signup", nil, (*SignUp)(nil)} {
+		if err := Validate(v); !errors.Is(err, ErrNotStruct) {
+			t.Errorf("Expected ErrNotStruct for %#v, got %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Usage: node harness.js <wasm_exec.js> <module.wasm> <calls.json>
+//
+// Runs a Go WebAssembly module, calls functions it exported as globalThis.textwasm,
+// and prints their results as JSON: {"value": ...}, {"error": "..."} for a returned Error,
+// or {"error": "...", "crashed": true} for an exception.
+"use strict";
+
+const fs = require("fs");
+
+const [execPath, wasmPath, callsPath] = process.argv.slice(2);
+
+globalThis.fs ??= fs;
+globalThis.crypto ??= require("crypto");
+
+require(execPath);
+
+(async () => {
+	const go = new Go();
+	const { instance } = await WebAssembly.instantiate(fs.readFileSync(wasmPath), go.importObject);
+
+	// run executes main until it returns or blocks, exported functions are registered by then.
+	go.run(instance);
+
+	const results = [];
+
+	for (const { fn, args } of JSON.parse(fs.readFileSync(callsPath, "utf8"))) {
+		try {
+			const api = globalThis.textwasm;
+			if (typeof api?.[fn] !== "function") {
+				throw new Error(`textwasm.${fn} is not exported`);
+			}
+
+			const value = api[fn](...(args ?? []));
+			results.push(value instanceof Error ? { error: value.message } : { value });
+		} catch (err) {
+			results.push({ error: String(err?.message ?? err), crashed: true });
+		}
+	}
+
+	console.log(JSON.stringify(results));
+	process.exit(0);
+})().catch((err) => {
+	console.error(err);
+	process.exit(1);
+});
@@ -0,0 +1,50 @@
+//go:build js && wasm
+
+// Command textwasm exposes text functions to JavaScript as globalThis.textwasm. Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o text.wasm ./wasm/textwasm
+//
+// and run it with wasm_exec.js from $(go env GOROOT)/lib/wasm, see testdata/harness.js.
+package main
+
+import (
+	"strings"
+	"syscall/js"
+	"unicode"
+)
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("wordCount", js.FuncOf(wordCount))
+
+	js.Global().Set("textwasm", api)
+}
+
+// wordCount is textwasm.wordCount(text), it returns the number of words in the text.
+func wordCount(this js.Value, args []js.Value) any {
+	return len(strings.Fields(args[0].String()))
+}
+
+// slugify turns a title into a part of a URL: "Hello, WASM World!" becomes "hello-wasm-world".
+func slugify(title string) string {
+	var b strings.Builder
+
+	dash := false
+
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(r)
+
+			dash = false
+		default:
+			dash = true
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package wasm
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Go compiles to WebAssembly with GOOS=js GOARCH=wasm for browsers and Node.js,
+// and with GOOS=wasip1 GOARCH=wasm for WASI runtimes like wasmtime.
+// A js/wasm module doesn't run by itself: wasm_exec.js from the Go distribution provides the imports it needs,
+// and the host starts it with new Go().run(instance).
+//
+// syscall/js is the bridge between the two worlds: js.Global() is globalThis, js.Value wraps any JavaScript value,
+// and js.FuncOf turns a Go function into a JavaScript one. Values are converted explicitly:
+// args[0].String(), args[0].Int(), and js.ValueOf for results.
+//
+// The program is textwasm/main.go, it's built only for js/wasm, so go build ./... on your machine skips it.
+// The tests build it, run it in Node.js with testdata/harness.js, call the exported functions, and check results.
+// Node.js must be installed, without it the tests are skipped.
+
+// 1. Keeping the module alive.
+// textwasm exports wordCount, but every call fails with "Go program has already exited".
+// When main returns, the Go program exits like any other, and functions created with js.FuncOf can't be called anymore.
+// A module that serves calls from JavaScript must block in main forever, or until JavaScript tells it to stop.
+
+func TestWordCount(t *testing.T) {
+	results := runModule(t,
+		call{"wordCount", []any{"Go compiles to WebAssembly"}},
+		call{"wordCount", []any{"  "}},
+	)
+
+	expectValue(t, results[0], 4.0)
+	expectValue(t, results[1], 0.0)
+}
+
+// 2. Exporting a function.
+// slugify is implemented in Go, export it as textwasm.slugify(title) like wordCount.
+
+func TestSlugify(t *testing.T) {
+	results := runModule(t,
+		call{"slugify", []any{"Hello, WASM World!"}},
+		call{"slugify", []any{"  Go 1.23 — What's New  "}},
+	)
+
+	expectValue(t, results[0], "hello-wasm-world")
+	expectValue(t, results[1], "go-1-23-what-s-new")
+}
+
+// 3. Advanced: errors at the boundary.
+// JavaScript can call an exported function with any arguments. wordCount() without arguments panics
+// with an index out of range, and a panic in a js.FuncOf callback crashes the whole Go program:
+// every later call fails. A number is worse: args[0].String() returns "<number: 42>" instead of failing.
+//
+// Validate arguments of both functions, and return a JavaScript Error, created with
+// js.Global().Get("Error").New(message), when the argument is missing or isn't a string.
+
+func TestInvalidArguments(t *testing.T) {
+	results := runModule(t,
+		call{"wordCount", nil},
+		call{"slugify", []any{42}},
+		call{"wordCount", []any{"still alive"}},
+	)
+
+	for i, res := range results[:2] {
+		if res.Error == "" || res.Crashed {
+			t.Errorf("Expected call %d to return an Error, got %+v", i+1, res)
+		}
+	}
+
+	expectValue(t, results[2], 2.0)
+}
+
+type call struct {
+	Fn   string `json:"fn"`
+	Args []any  `json:"args"`
+}
+
+type result struct {
+	Value   any    `json:"value"`
+	Error   string `json:"error"`
+	Crashed bool   `json:"crashed"`
+}
+
+func expectValue(t *testing.T, res result, expected any) {
+	t.Helper()
+
+	if res.Error != "" || res.Value != expected {
+		t.Errorf("Expected %v, got %+v", expected, res)
+	}
+}
+
+// runModule builds textwasm for js/wasm, runs it in Node.js, and returns the results of the calls.
+func runModule(t *testing.T, calls ...call) []result {
+	t.Helper()
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("Node.js is not installed")
+	}
+
+	dir := t.TempDir()
+	module := filepath.Join(dir, "text.wasm")
+
+	build := exec.Command("go", "build", "-o", module, "./textwasm")
+	build.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Build failed: %v\n%s", err, out)
+	}
+
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wasm_exec.js moved from misc/wasm to lib/wasm in Go 1.24.
+	execJS := filepath.Join(strings.TrimSpace(string(goroot)), "lib", "wasm", "wasm_exec.js")
+	if _, err := os.Stat(execJS); err != nil {
+		execJS = filepath.Join(strings.TrimSpace(string(goroot)), "misc", "wasm", "wasm_exec.js")
+	}
+
+	data, err := json.Marshal(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callsPath := filepath.Join(dir, "calls.json")
+	if err := os.WriteFile(callsPath, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(node, filepath.Join("testdata", "harness.js"), execJS, module, callsPath).Output()
+	if err != nil {
+		t.Fatalf("Node.js failed: %v\n%s", err, out)
+	}
+
+	var results []result
+	if err := json.Unmarshal(out, &results); err != nil || len(results) != len(calls) {
+		t.Fatalf("Expected %d results from the harness, got %q", len(calls), out)
+	}
+
+	return results
+}